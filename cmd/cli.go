@@ -0,0 +1,364 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"file-sharing-app/internal/aws"
+	"file-sharing-app/internal/manager"
+	"file-sharing-app/internal/models"
+	"file-sharing-app/internal/storage"
+	"file-sharing-app/pkg/logger"
+)
+
+// healthCheckerFactory creates bucket health checkers for a profile
+type healthCheckerFactory interface {
+	NewHealthChecker(profile *models.Profile) (aws.HealthChecker, error)
+}
+
+// runHealthCheck checks the bucket of the named profile, or the active profile if name is empty,
+// and writes the report to out. It returns the process exit code: 0 if every check passed.
+func runHealthCheck(profileName string, out io.Writer) int {
+	log := logger.New()
+
+	database, err := initializeDatabase(log)
+	if err != nil {
+		fmt.Fprintf(out, "Error: %v\n", err)
+		return 2
+	}
+	defer database.Close()
+
+	return checkBucketHealth(manager.NewProfileManager(database), &awsServiceFactory{log: log}, profileName, out)
+}
+
+// checkBucketHealth resolves the profile, runs the health check and prints the report
+func checkBucketHealth(profileManager manager.ProfileManager, factory healthCheckerFactory, profileName string, out io.Writer) int {
+	profile, err := loadProfile(profileManager, profileName)
+	if err != nil {
+		fmt.Fprintf(out, "Error: failed to load profile: %v\n", err)
+		return 2
+	}
+
+	checker, err := factory.NewHealthChecker(profile)
+	if err != nil {
+		fmt.Fprintf(out, "Error: profile %s is not configured: %v\n", profile.Name, err)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	report, err := checker.CheckBucketHealth(ctx)
+	if err != nil {
+		fmt.Fprintf(out, "Error: %v\n", err)
+		return 2
+	}
+
+	fmt.Fprintf(out, "Profile: %s\n%s\n", profile.Name, report.String())
+	if !report.Healthy() {
+		return 1
+	}
+	return 0
+}
+
+// loadProfile returns the named profile, or the active profile if name is empty
+func loadProfile(profileManager manager.ProfileManager, name string) (*models.Profile, error) {
+	if name == "" {
+		return profileManager.GetActiveProfile()
+	}
+	return profileManager.GetProfile(name)
+}
+
+// shareServiceFactory creates the services sharing a file needs for a profile
+type shareServiceFactory interface {
+	NewS3Service(profile *models.Profile) (aws.S3Service, error)
+	ShareLinkKey(profileName string) ([]byte, error)
+}
+
+// shareRequest holds the arguments of the share command
+type shareRequest struct {
+	ProfileName string // empty for the active profile
+	File        string // ID or name of one of the profile's files
+	Recipients  []string
+	Message     string
+	Options     models.ShareOptions
+}
+
+// parseRecipients splits a comma-separated list of email addresses
+func parseRecipients(list string) []string {
+	var recipients []string
+	for _, recipient := range strings.Split(list, ",") {
+		if recipient = strings.TrimSpace(recipient); recipient != "" {
+			recipients = append(recipients, recipient)
+		}
+	}
+	return recipients
+}
+
+// runShare shares a file of the named profile, or the active profile if name is empty, and writes
+// the email to send recipients to out. It returns the process exit code.
+func runShare(req shareRequest, out io.Writer) int {
+	log := logger.New()
+
+	database, err := initializeDatabase(log)
+	if err != nil {
+		fmt.Fprintf(out, "Error: %v\n", err)
+		return 2
+	}
+	defer database.Close()
+
+	return shareFile(database, manager.NewProfileManager(database), &awsServiceFactory{log: log}, req, out)
+}
+
+// shareFile shares the file like the app does, with a short link if the profile has them,
+// and prints the email for recipients
+func shareFile(database storage.Database, profileManager manager.ProfileManager, factory shareServiceFactory, req shareRequest, out io.Writer) int {
+	profile, err := loadProfile(profileManager, req.ProfileName)
+	if err != nil {
+		fmt.Fprintf(out, "Error: failed to load profile: %v\n", err)
+		return 2
+	}
+
+	file, err := findProfileFile(database, profile.Name, req.File)
+	if err != nil {
+		fmt.Fprintf(out, "Error: %v\n", err)
+		return 2
+	}
+
+	s3Service, err := factory.NewS3Service(profile)
+	if err != nil {
+		fmt.Fprintf(out, "Error: profile %s is not configured: %v\n", profile.Name, err)
+		return 2
+	}
+
+	shareManager := manager.NewShareManager(database, s3Service)
+	if profile.ShareLinkURL != "" {
+		key, err := factory.ShareLinkKey(profile.Name)
+		if err != nil {
+			// The app falls back the same way
+			fmt.Fprintf(out, "Warning: short links unavailable, sharing a presigned link: %v\n", err)
+		} else {
+			shareManager.SetShareLinks(profile.ShareLinkBase(), key)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	share, err := shareManager.ShareFileWithOptions(ctx, file.ID, req.Recipients, req.Message, req.Options)
+	if err != nil {
+		fmt.Fprintf(out, "Error: %v\n", err)
+		return 2
+	}
+
+	email := models.NewShareEmail(file, share)
+	fmt.Fprintf(out, "To: %s\nSubject: %s\n\n%s", strings.Join(email.Recipients, ", "), email.Subject, email.Body)
+	return 0
+}
+
+// findProfileFile finds one of the profile's files by ID, or by name if only one file has it
+func findProfileFile(database storage.Database, profile, idOrName string) (*models.FileMetadata, error) {
+	if idOrName == "" {
+		return nil, fmt.Errorf("no file given")
+	}
+
+	if file, err := database.GetFile(idOrName); err == nil {
+		if file.Profile != profile {
+			return nil, fmt.Errorf("file %s belongs to profile %s", idOrName, file.Profile)
+		}
+		return file, nil
+	}
+
+	files, err := database.ListFilesByProfile(profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	var matches []*models.FileMetadata
+	for _, file := range files {
+		if file.FileName == idOrName && file.Status == models.StatusActive {
+			matches = append(matches, file)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("profile %s has no available file with ID or name %q", profile, idOrName)
+	case 1:
+		return matches[0], nil
+	default:
+		ids := make([]string, len(matches))
+		for i, file := range matches {
+			ids[i] = file.ID
+		}
+		return nil, fmt.Errorf("several files are named %q; share one by ID: %s", idOrName, strings.Join(ids, ", "))
+	}
+}
+
+// databaseKeyStore holds the keys the local database's sensitive fields are encrypted with
+type databaseKeyStore interface {
+	Keys() (current, previous []byte, err error)
+	SetKeys(current, previous []byte) error
+	ClearKeys() error
+}
+
+// openDatabaseKeyStore opens the database keys in the OS keyring
+func openDatabaseKeyStore() (databaseKeyStore, error) {
+	return aws.NewDatabaseKeyStore()
+}
+
+// Actions of the database encryption commands
+const (
+	encryptDatabase   = "encrypt"
+	decryptDatabase   = "decrypt"
+	rotateDatabaseKey = "rotate"
+)
+
+// loadFieldCipher lets the database read and write its encrypted fields, if they are encrypted,
+// using the keys in the keyring. The keyring is only opened for an encrypted database.
+func loadFieldCipher(database *storage.SQLiteDatabase, openKeys func() (databaseKeyStore, error)) error {
+	enabled, err := database.FieldEncryptionEnabled()
+	if err != nil || !enabled {
+		return err
+	}
+
+	keys, err := openKeys()
+	if err != nil {
+		return fmt.Errorf("the database is encrypted, but the keyring can't be opened: %w", err)
+	}
+	current, previous, err := keys.Keys()
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return fmt.Errorf("the database is encrypted, but its key isn't in the keyring")
+	}
+
+	fieldCipher, err := storage.NewFieldCipher(current, previous)
+	if err != nil {
+		return err
+	}
+	database.SetFieldCipher(fieldCipher)
+	return nil
+}
+
+// runDatabaseEncryption turns encryption of the local database's sensitive fields on or off,
+// or rotates its key, and writes the outcome to out. It returns the process exit code.
+func runDatabaseEncryption(action string, out io.Writer) int {
+	log := logger.New()
+
+	database, err := initializeDatabase(log)
+	if err != nil {
+		fmt.Fprintf(out, "Error: %v\n", err)
+		return 2
+	}
+	defer database.Close()
+
+	keys, err := openDatabaseKeyStore()
+	if err != nil {
+		fmt.Fprintf(out, "Error: %v\n", err)
+		return 2
+	}
+
+	if err := changeDatabaseEncryption(database, keys, action, out); err != nil {
+		fmt.Fprintf(out, "Error: %v\n", err)
+		return 2
+	}
+	return 0
+}
+
+// changeDatabaseEncryption runs a database encryption action. The database must have been
+// opened with loadFieldCipher, so it can read the fields already encrypted.
+func changeDatabaseEncryption(database *storage.SQLiteDatabase, keys databaseKeyStore, action string, out io.Writer) error {
+	enabled, err := database.FieldEncryptionEnabled()
+	if err != nil {
+		return err
+	}
+
+	current, previous, err := keys.Keys()
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case encryptDatabase:
+		if enabled {
+			fmt.Fprintln(out, "The database is already encrypted")
+			return nil
+		}
+
+		// A key left by an earlier attempt is reused; nothing is encrypted with any other
+		if current == nil {
+			if current, err = aws.GenerateDatabaseKey(); err != nil {
+				return err
+			}
+			if err := keys.SetKeys(current, nil); err != nil {
+				return err
+			}
+		}
+		fieldCipher, err := storage.NewFieldCipher(current)
+		if err != nil {
+			return err
+		}
+		if err := database.ReencryptFields(fieldCipher); err != nil {
+			return fmt.Errorf("failed to encrypt the database: %w", err)
+		}
+		fmt.Fprintf(out, "Encrypted the database with key %s, stored in the keyring\n", fieldCipher.KeyID())
+
+	case rotateDatabaseKey:
+		if !enabled {
+			return fmt.Errorf("the database isn't encrypted; encrypt it first")
+		}
+
+		// Finish any earlier rotation, so nothing is left encrypted with the previous key
+		// before it is replaced in the keyring
+		currentCipher, err := storage.NewFieldCipher(current)
+		if err != nil {
+			return err
+		}
+		if previous != nil {
+			if err := database.ReencryptFields(currentCipher); err != nil {
+				return fmt.Errorf("failed to finish the previous key rotation: %w", err)
+			}
+		}
+
+		// The old key stays in the keyring, so the database can be read whenever this stops
+		next, err := aws.GenerateDatabaseKey()
+		if err != nil {
+			return err
+		}
+		if err := keys.SetKeys(next, current); err != nil {
+			return err
+		}
+		fieldCipher, err := storage.NewFieldCipher(next, current)
+		if err != nil {
+			return err
+		}
+		if err := database.ReencryptFields(fieldCipher); err != nil {
+			return fmt.Errorf("failed to re-encrypt the database with the new key: %w", err)
+		}
+		fmt.Fprintf(out, "Rotated the database key from %s to %s\n", currentCipher.KeyID(), fieldCipher.KeyID())
+
+	case decryptDatabase:
+		if !enabled {
+			fmt.Fprintln(out, "The database isn't encrypted")
+			return nil
+		}
+
+		if err := database.ReencryptFields(nil); err != nil {
+			return fmt.Errorf("failed to decrypt the database: %w", err)
+		}
+		if err := keys.ClearKeys(); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "Decrypted the database and removed its key from the keyring")
+
+	default:
+		return fmt.Errorf("unknown database encryption action: %s", action)
+	}
+
+	return nil
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"file-sharing-app/internal/aws"
+	"file-sharing-app/internal/manager"
+	"file-sharing-app/internal/models"
+	"file-sharing-app/internal/storage"
+)
+
+type stubHealthChecker struct {
+	report *models.HealthReport
+}
+
+func (s *stubHealthChecker) CheckBucketHealth(ctx context.Context) (*models.HealthReport, error) {
+	return s.report, nil
+}
+
+type stubHealthCheckerFactory struct {
+	checker aws.HealthChecker
+	profile string
+}
+
+func (f *stubHealthCheckerFactory) NewHealthChecker(profile *models.Profile) (aws.HealthChecker, error) {
+	f.profile = profile.Name
+	if f.checker == nil {
+		return nil, fmt.Errorf("no credentials stored")
+	}
+	return f.checker, nil
+}
+
+func TestCheckBucketHealth(t *testing.T) {
+	database, err := storage.NewSQLiteDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer database.Close()
+
+	profileManager := manager.NewProfileManager(database)
+	if err := profileManager.SaveProfile(&models.Profile{Name: "client-a", AWSRegion: "eu-west-1", S3Bucket: "client-a-bucket", DefaultExpiration: "1d", MaxFileSize: 1024}); err != nil {
+		t.Fatalf("Failed to save profile: %v", err)
+	}
+
+	report := &models.HealthReport{
+		Bucket: "client-a-bucket",
+		Region: "eu-west-1",
+		Checks: []models.HealthCheck{{Name: "Default encryption", Status: models.HealthCheckPass, Message: "Objects are encrypted"}},
+	}
+	factory := &stubHealthCheckerFactory{checker: &stubHealthChecker{report: report}}
+
+	var out bytes.Buffer
+	if code := checkBucketHealth(profileManager, factory, "client-a", &out); code != 0 {
+		t.Errorf("Expected exit code 0 for a healthy bucket, got %d", code)
+	}
+	if factory.profile != "client-a" {
+		t.Errorf("Expected profile client-a to be checked, got %s", factory.profile)
+	}
+	if !strings.Contains(out.String(), "[PASS] Default encryption") {
+		t.Errorf("Expected report in output, got %q", out.String())
+	}
+
+	report.Checks = append(report.Checks, models.HealthCheck{Name: "Versioning", Status: models.HealthCheckFail})
+	out.Reset()
+	if code := checkBucketHealth(profileManager, factory, "client-a", &out); code != 1 {
+		t.Errorf("Expected exit code 1 when a check fails, got %d", code)
+	}
+
+	// The active profile is used when none is named
+	out.Reset()
+	if code := checkBucketHealth(profileManager, &stubHealthCheckerFactory{}, "", &out); code != 2 {
+		t.Errorf("Expected exit code 2 without credentials, got %d", code)
+	}
+
+	out.Reset()
+	if code := checkBucketHealth(profileManager, factory, "missing", &out); code != 2 {
+		t.Errorf("Expected exit code 2 for an unknown profile, got %d", code)
+	}
+}
+
+// stubS3Service signs links for the share command; its other methods aren't used
+type stubS3Service struct {
+	aws.S3Service
+	shareLinks map[string]*aws.ShareLink
+}
+
+func (s *stubS3Service) GeneratePresignedURLAt(ctx context.Context, key string, signedAt time.Time, expiration time.Duration, overrides aws.ResponseOverrides) (string, error) {
+	return fmt.Sprintf("https://bucket.s3.amazonaws.com/%s?expires=%d", key, int64(expiration.Seconds())), nil
+}
+
+func (s *stubS3Service) PutShareLink(ctx context.Context, link *aws.ShareLink) error {
+	s.shareLinks[link.Token] = link
+	return nil
+}
+
+type stubShareServiceFactory struct {
+	s3Service *stubS3Service
+	linkKey   []byte
+}
+
+func (f *stubShareServiceFactory) NewS3Service(profile *models.Profile) (aws.S3Service, error) {
+	return f.s3Service, nil
+}
+
+func (f *stubShareServiceFactory) ShareLinkKey(profileName string) ([]byte, error) {
+	if f.linkKey == nil {
+		return nil, fmt.Errorf("keyring locked")
+	}
+	return f.linkKey, nil
+}
+
+func TestShareFile(t *testing.T) {
+	database, err := storage.NewSQLiteDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer database.Close()
+
+	profileManager := manager.NewProfileManager(database)
+	profile := &models.Profile{Name: "client-a", AWSRegion: "eu-west-1", S3Bucket: "client-a-bucket", DefaultExpiration: "1d", MaxFileSize: 1024}
+	if err := profileManager.SaveProfile(profile); err != nil {
+		t.Fatalf("Failed to save profile: %v", err)
+	}
+	for _, file := range []*storage.FileMetadata{
+		{ID: "file-1", FileName: "report.pdf", Profile: "client-a"},
+		{ID: "file-2", FileName: "notes.txt", Profile: "client-a"},
+		{ID: "file-3", FileName: "notes.txt", Profile: "client-a"},
+		{ID: "file-4", FileName: "other.pdf", Profile: "default"},
+	} {
+		file.FilePath, file.FileSize, file.UploadDate = "/tmp/"+file.FileName, 1024, time.Now()
+		file.ExpirationDate, file.S3Key, file.Status = time.Now().Add(30*24*time.Hour), "uploads/"+file.ID, storage.StatusActive
+		if err := database.SaveFile(file); err != nil {
+			t.Fatalf("Failed to save file: %v", err)
+		}
+	}
+
+	factory := &stubShareServiceFactory{s3Service: &stubS3Service{shareLinks: map[string]*aws.ShareLink{}}}
+	req := shareRequest{
+		ProfileName: "client-a",
+		File:        "report.pdf",
+		Recipients:  parseRecipients(" alice@example.com, ,bob@example.com"),
+		Options:     models.ShareOptions{LinkLifetime: 72 * time.Hour, MaxDownloads: 2},
+	}
+
+	var out bytes.Buffer
+	if code := shareFile(database, profileManager, factory, req, &out); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, out.String())
+	}
+	for _, expected := range []string{"To: alice@example.com, bob@example.com", "uploads/file-1?expires=259200", "no more than 2 times"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in output, got %q", expected, out.String())
+		}
+	}
+
+	shares, err := database.GetShareHistory("file-1")
+	if err != nil || len(shares) != 1 {
+		t.Fatalf("Expected the share to be recorded, got %v, %v", shares, err)
+	}
+	if shares[0].ShareOptions != req.Options {
+		t.Errorf("Expected options %+v to be recorded, got %+v", req.Options, shares[0].ShareOptions)
+	}
+
+	// Profiles with short links share them, and can outlast a presigned link
+	profile.ShareLinkURL = "https://links.example.com"
+	if err := profileManager.SaveProfile(profile); err != nil {
+		t.Fatalf("Failed to save profile: %v", err)
+	}
+	factory.linkKey = make([]byte, aws.ShareLinkKeySize)
+	req.File, req.Options = "file-2", models.ShareOptions{LinkLifetime: 14 * 24 * time.Hour}
+	out.Reset()
+	if code := shareFile(database, profileManager, factory, req, &out); code != 0 {
+		t.Fatalf("Expected exit code 0 with short links, got %d: %s", code, out.String())
+	}
+	if !strings.Contains(out.String(), "Download: https://links.example.com/") || len(factory.s3Service.shareLinks) != 1 {
+		t.Errorf("Expected a short link, got %q", out.String())
+	}
+
+	failures := map[string]shareRequest{
+		"ambiguous name":    {ProfileName: "client-a", File: "notes.txt", Recipients: req.Recipients},
+		"another profile":   {ProfileName: "client-a", File: "file-4", Recipients: req.Recipients},
+		"unknown file":      {ProfileName: "client-a", File: "missing.pdf", Recipients: req.Recipients},
+		"no recipients":     {ProfileName: "client-a", File: "file-1"},
+		"unknown profile":   {ProfileName: "missing", File: "file-1", Recipients: req.Recipients},
+		"invalid file name": {ProfileName: "client-a", File: "file-1", Recipients: req.Recipients, Options: models.ShareOptions{DownloadName: "../x"}},
+	}
+	for name, failing := range failures {
+		out.Reset()
+		if code := shareFile(database, profileManager, factory, failing, &out); code != 2 {
+			t.Errorf("%s: expected exit code 2, got %d: %s", name, code, out.String())
+		}
+	}
+}
+
+// memoryKeyStore is a databaseKeyStore kept in memory
+type memoryKeyStore struct {
+	current, previous []byte
+}
+
+func (s *memoryKeyStore) Keys() ([]byte, []byte, error) {
+	return s.current, s.previous, nil
+}
+
+func (s *memoryKeyStore) SetKeys(current, previous []byte) error {
+	s.current, s.previous = current, previous
+	return nil
+}
+
+func (s *memoryKeyStore) ClearKeys() error {
+	s.current, s.previous = nil, nil
+	return nil
+}
+
+func TestChangeDatabaseEncryption(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	database, err := storage.NewSQLiteDatabase(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+
+	if err := database.SaveFile(&storage.FileMetadata{ID: "file-1", FileName: "report.pdf", FilePath: "/tmp/report.pdf", FileSize: 1024, UploadDate: time.Now(), ExpirationDate: time.Now().Add(time.Hour), S3Key: "uploads/report.pdf", Status: storage.StatusActive}); err != nil {
+		t.Fatalf("Failed to save file: %v", err)
+	}
+	if err := database.SaveShare(&storage.ShareRecord{ID: "share-1", FileID: "file-1", Recipients: []string{"alice@example.com"}, Message: "Q3 numbers", SharedDate: time.Now(), SignedAt: time.Now(), URLExpiration: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("Failed to save share: %v", err)
+	}
+
+	keys := &memoryKeyStore{}
+	openKeys := func() (databaseKeyStore, error) { return keys, nil }
+
+	// reopen opens the database the way the app does at startup
+	reopen := func() *storage.SQLiteDatabase {
+		database.Close()
+		database, err = storage.NewSQLiteDatabase(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to reopen database: %v", err)
+		}
+		if err := loadFieldCipher(database, openKeys); err != nil {
+			t.Fatalf("Failed to load database key: %v", err)
+		}
+		return database
+	}
+
+	// checkShare fails unless the share can still be read
+	checkShare := func() {
+		shares, err := database.GetShareHistory("file-1")
+		if err != nil {
+			t.Fatalf("Failed to read shares: %v", err)
+		}
+		if len(shares) != 1 || shares[0].Message != "Q3 numbers" || shares[0].Recipients[0] != "alice@example.com" {
+			t.Errorf("Expected the share to be readable, got %+v", shares)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := changeDatabaseEncryption(database, keys, encryptDatabase, &out); err != nil {
+		t.Fatalf("Failed to encrypt database: %v", err)
+	}
+	if keys.current == nil || keys.previous != nil {
+		t.Fatalf("Expected only a current key in the keyring")
+	}
+	first := keys.current
+	database = reopen()
+	checkShare()
+
+	// Encrypting again keeps the key
+	out.Reset()
+	if err := changeDatabaseEncryption(database, keys, encryptDatabase, &out); err != nil {
+		t.Fatalf("Failed to encrypt database again: %v", err)
+	}
+	if !strings.Contains(out.String(), "already encrypted") || !bytes.Equal(keys.current, first) {
+		t.Errorf("Expected the database to be left as it was, got %q", out.String())
+	}
+
+	// Rotation keeps the old key as the previous one
+	out.Reset()
+	if err := changeDatabaseEncryption(database, keys, rotateDatabaseKey, &out); err != nil {
+		t.Fatalf("Failed to rotate key: %v", err)
+	}
+	if bytes.Equal(keys.current, first) || !bytes.Equal(keys.previous, first) {
+		t.Errorf("Expected a new current key and the old one as previous")
+	}
+	database = reopen()
+	checkShare()
+
+	// The old key can go once nothing uses it
+	keys.previous = nil
+	database = reopen()
+	checkShare()
+
+	// Without the key the encrypted database can't be opened
+	current := keys.current
+	keys.current = nil
+	database.Close()
+	database, err = storage.NewSQLiteDatabase(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	if err := loadFieldCipher(database, openKeys); err == nil {
+		t.Errorf("Expected an error when the key isn't in the keyring")
+	}
+	keys.current = current
+	database = reopen()
+
+	out.Reset()
+	if err := changeDatabaseEncryption(database, keys, decryptDatabase, &out); err != nil {
+		t.Fatalf("Failed to decrypt database: %v", err)
+	}
+	if keys.current != nil || keys.previous != nil {
+		t.Errorf("Expected the keys to be removed from the keyring")
+	}
+
+	// A plain text database doesn't open the keyring
+	database.Close()
+	database, err = storage.NewSQLiteDatabase(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer database.Close()
+	failingKeys := func() (databaseKeyStore, error) { return nil, fmt.Errorf("keyring locked") }
+	if err := loadFieldCipher(database, failingKeys); err != nil {
+		t.Errorf("Expected the keyring to be left alone, got %v", err)
+	}
+	checkShare()
+
+	if err := changeDatabaseEncryption(database, keys, rotateDatabaseKey, &out); err == nil {
+		t.Errorf("Expected rotation to fail on a plain text database")
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"file-sharing-app/internal/aws"
 	"file-sharing-app/internal/config"
 	"file-sharing-app/internal/manager"
+	"file-sharing-app/internal/models"
 	"file-sharing-app/internal/storage"
 	"file-sharing-app/internal/ui"
 	"file-sharing-app/pkg/logger"
@@ -30,6 +31,18 @@ func main() {
 	// Handle command line flags
 	var showVersion = flag.Bool("version", false, "Show version information")
 	var showHelp = flag.Bool("help", false, "Show help information")
+	var healthCheck = flag.Bool("health-check", false, "Check the bucket configuration and exit")
+	var profileName = flag.String("profile", "", "Profile to use with -health-check and -share (default: active profile)")
+	var shareName = flag.String("share", "", "Share the file with this ID or name, print the email for recipients and exit")
+	var shareTo = flag.String("to", "", "Comma-separated email addresses to share with")
+	var shareMessage = flag.String("message", "", "Message for the recipients of -share")
+	var linkLifetime = flag.Duration("link-lifetime", 0, "How long the -share link works, e.g. 72h (default: the app's default)")
+	var maxDownloads = flag.Int("max-downloads", 0, "Downloads the -share recipients are asked to keep to (default: no limit)")
+	var downloadName = flag.String("download-name", "", "File name the -share download is saved as (default: the file's own)")
+	var inlinePreview = flag.Bool("inline", false, "Open the -share link in the browser instead of downloading; PDFs and images only")
+	var encryptDB = flag.Bool("encrypt-database", false, "Encrypt share recipients and messages in the local database and exit")
+	var decryptDB = flag.Bool("decrypt-database", false, "Store the local database's encrypted fields in plain text again and exit")
+	var rotateDBKey = flag.Bool("rotate-database-key", false, "Re-encrypt the local database with a new key and exit")
 	flag.Parse()
 
 	if *showVersion {
@@ -46,13 +59,55 @@ func main() {
 		fmt.Println("  file-sharing-app [options]")
 		fmt.Println("")
 		fmt.Println("Options:")
-		fmt.Println("  -version    Show version information")
-		fmt.Println("  -help       Show this help message")
+		fmt.Println("  -version         Show version information")
+		fmt.Println("  -help            Show this help message")
+		fmt.Println("  -health-check    Check the bucket configuration and exit")
+		fmt.Println("  -profile NAME    Profile to check or share from (default: active profile)")
+		fmt.Println("  -share FILE      Share a file by ID or name, print the email for recipients and exit")
+		fmt.Println("    -to LIST              Comma-separated email addresses")
+		fmt.Println("    -message TEXT         Message for the recipients")
+		fmt.Println("    -link-lifetime D      How long the link works, e.g. 72h (at most 168h without short links)")
+		fmt.Println("    -max-downloads N      Downloads recipients are asked to keep to")
+		fmt.Println("    -download-name NAME   File name the download is saved as")
+		fmt.Println("    -inline               Open PDFs and images in the browser instead of downloading")
+		fmt.Println("  -encrypt-database     Encrypt share recipients and messages in the local database")
+		fmt.Println("  -decrypt-database     Store them in plain text again")
+		fmt.Println("  -rotate-database-key  Re-encrypt them with a new key")
+		fmt.Println("")
+		fmt.Println("Close the app before changing database encryption. The key is kept in the system keyring.")
 		fmt.Println("")
 		fmt.Println("For more information, visit: https://github.com/your-org/file-sharing-app")
 		return
 	}
 
+	if *healthCheck {
+		os.Exit(runHealthCheck(*profileName, os.Stdout))
+	}
+
+	if *shareName != "" {
+		os.Exit(runShare(shareRequest{
+			ProfileName: *profileName,
+			File:        *shareName,
+			Recipients:  parseRecipients(*shareTo),
+			Message:     *shareMessage,
+			Options: models.ShareOptions{
+				LinkLifetime:  *linkLifetime,
+				MaxDownloads:  *maxDownloads,
+				DownloadName:  *downloadName,
+				InlinePreview: *inlinePreview,
+			},
+		}, os.Stdout))
+	}
+
+	switch {
+	case *encryptDB:
+		os.Exit(runDatabaseEncryption(encryptDatabase, os.Stdout))
+	case *decryptDB:
+		os.Exit(runDatabaseEncryption(decryptDatabase, os.Stdout))
+	case *rotateDBKey:
+		os.Exit(runDatabaseEncryption(rotateDatabaseKey, os.Stdout))
+	}
+
 	// Initialize logging
 	log := logger.New()
 	log.Info(fmt.Sprintf("File Sharing App v%s starting...", version))
@@ -108,41 +163,28 @@ func initializeApplication(cfg *config.AppConfig, mainWindow *ui.MainWindow, log
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
 
-	// Initialize AWS services (with graceful fallback if credentials not configured)
-	s3Service, credentialsConfigured, err := initializeAWSServices(cfg, log)
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize AWS services: %w", err)
-	}
-
-	// Initialize business logic managers
-	fileManager := manager.NewFileManager(database, s3Service)
-	shareManager := manager.NewShareManager(database, s3Service)
+	// Initialize business logic managers. The S3 service is attached by the controller
+	// once the active profile is known, so the managers start out offline.
+	fileManager := manager.NewFileManager(database, nil)
+	shareManager := manager.NewShareManager(database, nil)
 	expirationManager := manager.NewExpirationManager(database)
 	settingsManager := manager.NewSettingsManager(database)
-	
-	// Initialize sync manager (handles offline capability)
-	var syncManager manager.SyncManager
-	if s3Service != nil {
-		syncManager = manager.NewSyncManager(database, s3Service)
-	} else {
-		// Create sync manager in offline mode if S3 service is not available
-		syncManager = manager.NewSyncManagerWithoutS3(database)
-	}
+	syncManager := manager.NewSyncManagerWithoutS3(database)
+	profileManager := manager.NewProfileManager(database)
+	outboxManager := manager.NewOutboxManager(database, fileManager, shareManager, expirationManager)
+	notificationManager := manager.NewNotificationManager(database, settingsManager)
 
 	// Create application controller
 	controller := app.NewController(fileManager, shareManager, expirationManager, settingsManager, syncManager, mainWindow)
-
-	// Update UI status based on AWS credentials configuration
-	if !credentialsConfigured {
-		mainWindow.SetStatus("AWS credentials not configured - some features will be limited")
-		log.Info("AWS credentials not configured - running in limited mode")
-	}
+	controller.EnableProfiles(profileManager, &awsServiceFactory{log: log})
+	controller.EnableOutbox(outboxManager)
+	controller.EnableNotifications(notificationManager)
 
 	return controller, nil
 }
 
-// initializeDatabase sets up the SQLite database
-func initializeDatabase(log *logger.Logger) (storage.Database, error) {
+// initializeDatabase sets up the SQLite database, with the keys to its encrypted fields if it has them
+func initializeDatabase(log *logger.Logger) (*storage.SQLiteDatabase, error) {
 	// Create data directory if it doesn't exist
 	dataDir := "data"
 	if err := os.MkdirAll(dataDir, 0755); err != nil {
@@ -156,35 +198,128 @@ func initializeDatabase(log *logger.Logger) (storage.Database, error) {
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
 
+	if err := loadFieldCipher(database, openDatabaseKeyStore); err != nil {
+		database.Close()
+		return nil, fmt.Errorf("failed to load database encryption key: %w", err)
+	}
+
 	log.Info(fmt.Sprintf("Database initialized: %s", dbPath))
 	return database, nil
 }
 
-// initializeAWSServices sets up AWS S3 service and credential provider
-func initializeAWSServices(cfg *config.AppConfig, log *logger.Logger) (aws.S3Service, bool, error) {
-	// Try to initialize credential provider
-	credProvider, err := aws.NewSecureCredentialProvider()
+// awsServiceFactory builds AWS services from the credentials stored for each profile
+type awsServiceFactory struct {
+	log *logger.Logger
+}
+
+// NewS3Service sets up the S3 service for a profile, failing if its credentials are not configured
+func (f *awsServiceFactory) NewS3Service(profile *models.Profile) (aws.S3Service, error) {
+	credProvider, err := f.credentialProvider(profile)
 	if err != nil {
-		log.Info(fmt.Sprintf("AWS credential provider initialization failed: %v", err))
-		// Return nil service but don't fail - app can run in limited mode
-		return nil, false, nil
+		return nil, err
 	}
 
-	// Validate credentials
 	if err := credProvider.ValidateCredentials(context.Background()); err != nil {
-		log.Info(fmt.Sprintf("AWS credentials validation failed: %v", err))
-		// Return nil service but don't fail - app can run in limited mode
-		return nil, false, nil
+		return nil, fmt.Errorf("credentials validation failed: %w", err)
+	}
+
+	s3Service, err := aws.NewS3Service(credProvider, profile.S3Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("S3 service initialization failed: %w", err)
+	}
+
+	encryption, err := f.encryptionConfig(profile, credProvider)
+	if err != nil {
+		return nil, err
+	}
+	if err := s3Service.SetEncryption(encryption); err != nil {
+		return nil, fmt.Errorf("encryption configuration failed: %w", err)
+	}
+
+	f.log.Info(fmt.Sprintf("AWS services initialized for profile %s", profile.Name))
+	return s3Service, nil
+}
+
+// encryptionConfig builds the upload encryption for a profile. SSE-C profiles get a key
+// generated and stored in the keyring the first time they are used.
+func (f *awsServiceFactory) encryptionConfig(profile *models.Profile, credProvider *aws.SecureCredentialProvider) (aws.EncryptionConfig, error) {
+	encryption := aws.EncryptionConfig{
+		Mode:     profile.GetEncryptionMode(),
+		KMSKeyID: profile.KMSKeyID,
 	}
 
-	// Initialize S3 service
-	s3Service, err := aws.NewS3Service(credProvider, cfg.S3Bucket)
+	if encryption.Mode == models.EncryptionSSEC {
+		key, err := credProvider.GetOrCreateSSECustomerKey()
+		if err != nil {
+			return aws.EncryptionConfig{}, fmt.Errorf("failed to load SSE-C key: %w", err)
+		}
+		encryption.CustomerKey = key
+	} else if key, err := credProvider.GetSSECustomerKey(); err == nil {
+		// Keep an existing key so files uploaded before the mode changed can still be downloaded
+		encryption.CustomerKey = key
+	}
+
+	return encryption, nil
+}
+
+// NewHealthChecker sets up a bucket health checker for a profile
+func (f *awsServiceFactory) NewHealthChecker(profile *models.Profile) (aws.HealthChecker, error) {
+	credProvider, err := f.credentialProvider(profile)
 	if err != nil {
-		log.Info(fmt.Sprintf("S3 service initialization failed: %v", err))
-		// Return nil service but don't fail - app can run in limited mode
-		return nil, false, nil
+		return nil, err
 	}
 
-	log.Info("AWS services initialized successfully")
-	return s3Service, true, nil
-}
\ No newline at end of file
+	return aws.NewBucketHealthChecker(credProvider, profile.S3Bucket)
+}
+
+// ShareLinkKey returns the key a profile's short share link tokens are derived from, creating it if needed
+func (f *awsServiceFactory) ShareLinkKey(profileName string) ([]byte, error) {
+	credProvider, err := aws.NewSecureCredentialProviderForProfile(profileName)
+	if err != nil {
+		return nil, fmt.Errorf("credential provider initialization failed: %w", err)
+	}
+
+	return credProvider.GetOrCreateShareLinkKey()
+}
+
+// credentialProvider returns the keyring credential provider for a profile
+func (f *awsServiceFactory) credentialProvider(profile *models.Profile) (*aws.SecureCredentialProvider, error) {
+	credProvider, err := aws.NewSecureCredentialProviderForProfile(profile.Name)
+	if err != nil {
+		return nil, fmt.Errorf("credential provider initialization failed: %w", err)
+	}
+
+	// The profile is the source of truth for the region; keep the keyring in step with it
+	if region, err := credProvider.GetRegion(); err == nil && profile.AWSRegion != "" && region != profile.AWSRegion {
+		if err := credProvider.SetRegion(profile.AWSRegion); err != nil {
+			return nil, fmt.Errorf("failed to update region for profile: %w", err)
+		}
+	}
+
+	return credProvider, nil
+}
+
+// StoreCredentials saves a profile's credentials in the system keyring
+func (f *awsServiceFactory) StoreCredentials(profileName, accessKey, secretKey, region string) error {
+	credProvider, err := aws.NewSecureCredentialProviderForProfile(profileName)
+	if err != nil {
+		return err
+	}
+
+	return credProvider.StoreCredentials(accessKey, secretKey, region)
+}
+
+// ClearCredentials removes a profile's credentials from the system keyring
+func (f *awsServiceFactory) ClearCredentials(profileName string) error {
+	credProvider, err := aws.NewSecureCredentialProviderForProfile(profileName)
+	if err != nil {
+		return err
+	}
+
+	return credProvider.ClearCredentials()
+}
+
+// NewProvisioner creates a CloudFormation provisioner from the administrator credentials in the request
+func (f *awsServiceFactory) NewProvisioner(req *models.ProvisionRequest) (aws.Provisioner, error) {
+	return aws.NewCloudFormationProvisioner(context.Background(), req)
+}
@@ -0,0 +1,27 @@
+// Package cloudformation embeds the CloudFormation template used to provision the
+// application's S3 bucket and IAM user, so the app can deploy it without the AWS CLI.
+package cloudformation
+
+import _ "embed"
+
+// Template is the contents of file-sharing-app.yaml
+//
+//go:embed file-sharing-app.yaml
+var Template string
+
+// Output keys defined by the template
+const (
+	OutputBucketName      = "BucketName"
+	OutputBucketRegion    = "BucketRegion"
+	OutputIAMUserName     = "IAMUserName"
+	OutputAccessKeyID     = "AccessKeyId"
+	OutputSecretAccessKey = "SecretAccessKey"
+	OutputShareLinkURL    = "ShareLinkURL"
+)
+
+// Parameter keys accepted by the template
+const (
+	ParameterBucketName  = "BucketName"
+	ParameterIAMUserName = "IAMUserName"
+	ParameterEnvironment = "Environment"
+)
@@ -3,12 +3,17 @@ package app
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"file-sharing-app/internal/aws"
+	"file-sharing-app/internal/connectivity"
 	"file-sharing-app/internal/manager"
 	"file-sharing-app/internal/models"
-	"file-sharing-app/internal/storage"
+	"file-sharing-app/internal/scheduler"
 	"file-sharing-app/pkg/errors"
 	"file-sharing-app/pkg/logger"
 )
@@ -18,15 +23,89 @@ type MainWindowInterface interface {
 	SetStatus(status string)
 	EnableActions(enabled bool)
 	UpdateFiles(files []models.FileMetadata)
+	ShowShareEmail(email *models.ShareEmail)
+	SetSyncSchedule(lastSynced, nextSync time.Time)
+	SendNotification(title, content string)
+	
+	// System tray
+	SetMinimizeToTray(enabled bool)
+	SetRecentShares(shares []models.RecentShare)
+	
+	// Upload speed
+	ShowBandwidthLimit(kbps int)
 	
 	// Callback setters
-	SetOnUploadFile(callback func(filePath string, expiration time.Duration) error)
-	SetOnShareFile(callback func(fileID string, recipients []string, message string) error)
+	SetOnUploadFile(callback func(filePath string, expiration time.Duration, opts models.UploadOptions) error)
+	SetOnChangeBandwidthLimit(callback func(kbps int) error)
+	SetOnChangeUploadBandwidthLimit(callback func(fileID string, kbps int) error)
+	SetOnUploadClipboard(callback func(text string) error)
+	SetOnShareFile(callback func(fileID string, recipients []string, message string, opts models.ShareOptions) error)
 	SetOnDeleteFile(callback func(fileID string) error)
+	SetOnRetryUpload(callback func(fileID string) error)
 	SetOnRefreshFiles(callback func() ([]models.FileMetadata, error))
+	SetOnFilterFiles(callback func(query models.FileQuery) ([]models.FileMetadata, error))
 	SetOnGeneratePresignedURL(callback func(fileID string, expiration time.Duration) (string, error))
+	SetOnCopyShareLink(callback func(shareID string) (string, error))
+	SetOnRevokeShare(callback func(shareID string) error)
+	SetOnDownloadFile(callback func(fileID string, destPath string) error)
 	SetOnSaveSettings(callback func(settings *models.ApplicationSettings) error)
 	SetOnLoadSettings(callback func() (*models.ApplicationSettings, error))
+	SetOnLoadNotifications(callback func() ([]*models.Notification, error))
+	SetOnClearNotifications(callback func() error)
+	
+	// Profile management
+	SetProfiles(names []string, active string)
+	SetOnSwitchProfile(callback func(name string) error)
+	SetOnLoadProfile(callback func(name string) (*models.Profile, error))
+	SetOnSaveProfile(callback func(profile *models.Profile, accessKey, secretKey string) error)
+	SetOnDeleteProfile(callback func(name string) error)
+	
+	// Infrastructure provisioning
+	SetOnProvisionBucket(callback func(req *models.ProvisionRequest) (*models.ProvisionResult, error))
+	SetOnCheckDrift(callback func(req *models.ProvisionRequest) (*models.StackDriftReport, error))
+	SetOnCheckBucketHealth(callback func() (*models.HealthReport, error))
+}
+
+// Background jobs run by the scheduler
+const (
+	jobExpirationCheck = "expiration-check"
+	jobSync            = "sync"
+	jobMetadataCleanup = "metadata-cleanup"
+	jobURLRenewal      = "url-renewal"
+	jobScheduledUpload = "scheduled-upload"
+)
+
+// scheduledUploadInterval is how often to look for scheduled uploads whose time has come
+const scheduledUploadInterval = time.Minute
+
+// urlRenewalMargin is how much longer than the renewal interval a share link must last to be left alone
+const urlRenewalMargin = time.Hour
+
+// notificationHistoryLimit is how many past notifications the history shows
+const notificationHistoryLimit = 100
+
+// recentSharesLimit is how many recent shares the tray menu offers links for
+const recentSharesLimit = 10
+
+// ServiceFactory builds the AWS services used by a profile
+type ServiceFactory interface {
+	// NewS3Service creates an S3 service using the profile's credentials, region and bucket
+	NewS3Service(profile *models.Profile) (aws.S3Service, error)
+	
+	// StoreCredentials saves the profile's AWS credentials in the system keyring
+	StoreCredentials(profileName, accessKey, secretKey, region string) error
+	
+	// ClearCredentials removes the profile's AWS credentials from the system keyring
+	ClearCredentials(profileName string) error
+	
+	// NewProvisioner creates a provisioner using the request's administrator credentials and endpoint
+	NewProvisioner(req *models.ProvisionRequest) (aws.Provisioner, error)
+	
+	// NewHealthChecker creates a bucket health checker using the profile's credentials
+	NewHealthChecker(profile *models.Profile) (aws.HealthChecker, error)
+	
+	// ShareLinkKey returns the key the profile's short share link tokens are derived from, creating it if needed
+	ShareLinkKey(profileName string) ([]byte, error)
 }
 
 // Controller coordinates between UI and business logic layers
@@ -37,6 +116,38 @@ type Controller struct {
 	expirationManager manager.ExpirationManager
 	settingsManager   manager.SettingsManager
 	syncManager       manager.SyncManager
+	profileManager    manager.ProfileManager
+	
+	// Queues operations made while offline; nil means they are refused
+	outbox manager.OutboxManager
+	
+	// Decides which desktop notifications to send; nil means none are sent
+	notificationManager manager.NotificationManager
+	
+	// Builds AWS services when switching profiles
+	serviceFactory ServiceFactory
+	
+	// Runs background jobs at the intervals in the settings
+	scheduler *scheduler.Scheduler
+	
+	// Where clipboard text is saved before it is uploaded as a file
+	clipboardDir string
+	
+	// Search, filters and sort order of the file list, kept across refreshes
+	fileQuery      models.FileQuery
+	fileQueryMutex sync.Mutex
+	
+	// Serializes file list refreshes, so a refresh started before a profile switch
+	// can't replace the list loaded after it
+	refreshMutex sync.Mutex
+	
+	// Speed limits of the uploads started from the UI, by file path, so they can be changed while they run
+	uploadLimits      map[string]*models.BandwidthLimit
+	uploadLimitsMutex sync.Mutex
+	
+	// Called once queued uploads have been sent or dropped, by file ID, to remove the files of clipboard uploads
+	queuedUploadsDone  map[string]func()
+	queuedUploadsMutex sync.Mutex
 	
 	// UI components
 	mainWindow MainWindowInterface
@@ -67,6 +178,11 @@ func NewController(
 		settingsManager:   settingsManager,
 		syncManager:       syncManager,
 		mainWindow:        mainWindow,
+		scheduler:         scheduler.NewScheduler(),
+		clipboardDir:      filepath.Join(os.TempDir(), "file-sharing-app", "clipboard"),
+		fileQuery:         models.FileQuery{Limit: models.DefaultFilePageSize},
+		uploadLimits:      make(map[string]*models.BandwidthLimit),
+		queuedUploadsDone: make(map[string]func()),
 		logger:            logger.New(),
 		ctx:               ctx,
 		cancel:            cancel,
@@ -75,19 +191,106 @@ func NewController(
 	// Connect UI callbacks to controller methods
 	controller.setupUICallbacks()
 	
+	// Background jobs only run once the controller is started
+	controller.registerJobs()
+	
 	return controller
 }
 
+// EnableProfiles turns on switchable profiles. Must be called before Start.
+func (c *Controller) EnableProfiles(profileManager manager.ProfileManager, serviceFactory ServiceFactory) {
+	c.profileManager = profileManager
+	c.serviceFactory = serviceFactory
+}
+
+// EnableOutbox queues uploads, shares, expiry changes and deletes made while offline
+// instead of refusing them, and lets uploads be scheduled for later. Must be called before Start.
+func (c *Controller) EnableOutbox(outbox manager.OutboxManager) {
+	c.outbox = outbox
+	
+	if err := c.scheduler.Register(jobScheduledUpload, scheduledUploadInterval, c.runScheduledUploads); err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to register background job %s: %v", jobScheduledUpload, err))
+	}
+}
+
+// EnableNotifications sends desktop notifications for uploads, expiring files and sync problems,
+// as configured in the settings. Must be called before Start.
+func (c *Controller) EnableNotifications(notificationManager manager.NotificationManager) {
+	c.notificationManager = notificationManager
+}
+
+// notify records a notification and shows it on the desktop unless it is turned off or was already sent
+func (c *Controller) notify(event models.NotificationEvent, fileID, title, content string) {
+	if c.notificationManager == nil {
+		return
+	}
+	
+	show, err := c.notificationManager.Record(&models.Notification{
+		Event:   event,
+		FileID:  fileID,
+		Title:   title,
+		Content: content,
+	})
+	if err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to record notification: %v", err))
+		return
+	}
+	
+	if show {
+		c.mainWindow.SendNotification(title, content)
+	}
+}
+
+// notifyMissingFiles tells the user when a sync found files that are gone from S3
+func (c *Controller) notifyMissingFiles(result *manager.SyncResult) {
+	if result == nil || result.MissingFiles == 0 {
+		return
+	}
+	
+	c.notify(models.NotifyMissingFiles, "", "Files missing from S3",
+		fmt.Sprintf("%d files are no longer in S3 and were marked as deleted", result.MissingFiles))
+}
+
+// queueWhileOffline reports whether operations should go to the outbox rather than S3
+func (c *Controller) queueWhileOffline() bool {
+	return c.outbox != nil && c.syncManager.IsOfflineMode()
+}
+
 // Start initializes the controller and starts background operations
 func (c *Controller) Start() error {
 	c.logger.Info("Starting application controller")
 	
+	// Connect the managers to the active profile before anything talks to S3
+	if c.profileManager != nil {
+		profile, err := c.profileManager.GetActiveProfile()
+		if err != nil {
+			c.logger.Error(fmt.Sprintf("Failed to load active profile: %v", err))
+			return fmt.Errorf("failed to load active profile: %w", err)
+		}
+		if !c.activateProfile(profile) {
+			c.logger.Info(fmt.Sprintf("AWS not configured for profile %s - running in limited mode", profile.Name))
+		}
+	}
+	
 	// Perform initial synchronization with S3
 	c.mainWindow.SetStatus("Synchronizing with S3...")
 	go c.performInitialSync()
 	
-	// Start background expiration checker
-	go c.startExpirationChecker()
+	// Start background jobs at the intervals in the settings
+	settings, err := c.settingsManager.LoadSettings()
+	if err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to load settings, using default job intervals: %v", err))
+		settings = models.DefaultApplicationSettings()
+	}
+	c.applyJobSettings(settings)
+	c.publishSyncSchedule()
+	c.mainWindow.SetMinimizeToTray(settings.MinimizeToTray)
+	c.fileManager.SetUploadSettings(settings)
+	c.mainWindow.ShowBandwidthLimit(settings.UploadBandwidthLimitKBps)
+	go c.scheduler.Run(c.ctx)
+	
+	// Leave offline mode by itself once S3 can be reached again
+	go c.startConnectivityMonitor()
 	
 	// Load initial file list (this will work even if sync fails)
 	if err := c.refreshFiles(); err != nil {
@@ -116,18 +319,69 @@ func (c *Controller) Stop() {
 // setupUICallbacks connects UI callbacks to controller methods
 func (c *Controller) setupUICallbacks() {
 	c.mainWindow.SetOnUploadFile(c.handleUploadFile)
+	c.mainWindow.SetOnChangeBandwidthLimit(c.handleChangeBandwidthLimit)
+	c.mainWindow.SetOnChangeUploadBandwidthLimit(c.handleChangeUploadBandwidthLimit)
+	c.mainWindow.SetOnUploadClipboard(c.handleUploadClipboard)
 	c.mainWindow.SetOnShareFile(c.handleShareFile)
 	c.mainWindow.SetOnDeleteFile(c.handleDeleteFile)
+	c.mainWindow.SetOnRetryUpload(c.handleRetryUpload)
 	c.mainWindow.SetOnRefreshFiles(c.handleRefreshFiles)
+	c.mainWindow.SetOnFilterFiles(c.handleFilterFiles)
 	c.mainWindow.SetOnGeneratePresignedURL(c.GeneratePresignedURL)
+	c.mainWindow.SetOnCopyShareLink(c.handleCopyShareLink)
+	c.mainWindow.SetOnRevokeShare(c.handleRevokeShare)
+	c.mainWindow.SetOnDownloadFile(c.handleDownloadFile)
 	c.mainWindow.SetOnSaveSettings(c.handleSaveSettings)
 	c.mainWindow.SetOnLoadSettings(c.handleLoadSettings)
+	c.mainWindow.SetOnLoadNotifications(c.handleLoadNotifications)
+	c.mainWindow.SetOnClearNotifications(c.handleClearNotifications)
+	c.mainWindow.SetOnSwitchProfile(c.SwitchProfile)
+	c.mainWindow.SetOnLoadProfile(c.handleLoadProfile)
+	c.mainWindow.SetOnSaveProfile(c.handleSaveProfile)
+	c.mainWindow.SetOnDeleteProfile(c.handleDeleteProfile)
+	c.mainWindow.SetOnProvisionBucket(c.handleProvisionBucket)
+	c.mainWindow.SetOnCheckDrift(c.handleCheckDrift)
+	c.mainWindow.SetOnCheckBucketHealth(c.CheckBucketHealth)
 }
 
-// handleUploadFile handles file upload requests from UI
-func (c *Controller) handleUploadFile(filePath string, expiration time.Duration) error {
+// handleUploadFile handles file upload requests from UI. Uploads scheduled for later wait in the outbox
+// until their time; the upload's own bandwidth limit applies on top of the one in the settings.
+func (c *Controller) handleUploadFile(filePath string, expiration time.Duration, opts models.UploadOptions) error {
+	return c.uploadFile(filePath, expiration, opts, nil)
+}
+
+// uploadFile starts uploading a file, or queues it. done, if set, is called once the upload has
+// finished or failed, which for a queued upload is after the replay that sends or drops it.
+// It isn't called when an error is returned, as nothing was started or queued.
+func (c *Controller) uploadFile(filePath string, expiration time.Duration, opts models.UploadOptions, done func()) error {
 	c.logger.Info(fmt.Sprintf("Starting file upload: %s", filePath))
 	
+	if err := opts.Validate(); err != nil {
+		c.mainWindow.SetStatus("Upload failed: " + err.Error())
+		return fmt.Errorf("invalid upload options: %w", err)
+	}
+	
+	if opts.ScheduledAfter(time.Now()) {
+		return c.scheduleUpload(filePath, expiration, opts, done)
+	}
+	
+	if c.queueWhileOffline() {
+		file, err := c.outbox.QueueUploadWithOptions(filePath, expiration, opts)
+		if err != nil {
+			c.logger.Error(fmt.Sprintf("Failed to queue upload: %v", err))
+			c.mainWindow.SetStatus("Upload failed: " + err.Error())
+			return fmt.Errorf("failed to queue upload: %w", err)
+		}
+		
+		c.whenQueuedUploadDone(file.ID, done)
+		c.logger.Info(fmt.Sprintf("Queued upload of %s while offline", file.ID))
+		c.mainWindow.SetStatus("Offline - upload queued until S3 is reachable")
+		if err := c.refreshFiles(); err != nil {
+			c.logger.Error(fmt.Sprintf("Failed to refresh files after queueing upload: %v", err))
+		}
+		return nil
+	}
+	
 	// Check if we're in offline mode
 	if c.syncManager.IsOfflineMode() {
 		c.logger.Error("Cannot upload files in offline mode")
@@ -142,18 +396,32 @@ func (c *Controller) handleUploadFile(filePath string, expiration time.Duration)
 	// Create progress channel for upload progress updates
 	progressCh := make(chan aws.UploadProgress, 10)
 	
+	// Every upload gets a limit of its own, unlimited if none was chosen, so it can be slowed down while it runs
+	limit := opts.BandwidthLimit
+	if limit == nil {
+		limit = models.NewBandwidthLimit(0)
+	}
+	c.trackUploadLimit(filePath, limit)
+	
 	// Start upload in background goroutine
 	go func() {
 		defer close(progressCh)
+		defer c.untrackUploadLimit(filePath, limit)
+		if done != nil {
+			defer done()
+		}
 		defer func() {
 			// Re-enable UI actions when upload completes
 			c.mainWindow.EnableActions(true)
 		}()
 		
-		// Perform upload
-		fileMetadata, err := c.fileManager.UploadFile(c.ctx, filePath, expiration, progressCh)
+		// Perform upload, within its own bandwidth limit and the one in the settings
+		ctx := aws.WithBandwidthLimiter(c.ctx, aws.NewBandwidthLimiter(limit))
+		fileMetadata, err := c.fileManager.UploadFile(ctx, filePath, expiration, progressCh)
 		if err != nil {
 			c.logger.Error(fmt.Sprintf("File upload failed: %v", err))
+			c.notify(models.NotifyUploadFailed, "", "Upload failed",
+				fmt.Sprintf("%s could not be uploaded: %v", filepath.Base(filePath), err))
 			
 			// Check if error is due to network issues and enter offline mode
 			if isNetworkError(err) {
@@ -168,6 +436,8 @@ func (c *Controller) handleUploadFile(filePath string, expiration time.Duration)
 		
 		c.logger.Info(fmt.Sprintf("File upload completed: %s", fileMetadata.ID))
 		c.mainWindow.SetStatus("Upload completed successfully")
+		c.notify(models.NotifyUploadComplete, fileMetadata.ID, "Upload complete",
+			fmt.Sprintf("%s was uploaded and expires %s", fileMetadata.FileName, fileMetadata.ExpirationDate.Format("Jan 2 15:04")))
 		
 		// Refresh file list to show new file
 		if err := c.refreshFiles(); err != nil {
@@ -187,10 +457,189 @@ func (c *Controller) handleUploadFile(filePath string, expiration time.Duration)
 	return nil
 }
 
+// scheduleUpload queues an upload to start at the time in opts, offline or not
+func (c *Controller) scheduleUpload(filePath string, expiration time.Duration, opts models.UploadOptions, done func()) error {
+	if c.outbox == nil {
+		c.mainWindow.SetStatus("Upload failed: Uploads can't be scheduled")
+		return fmt.Errorf("uploads can't be scheduled without the upload queue")
+	}
+	
+	file, err := c.outbox.QueueUploadWithOptions(filePath, expiration, opts)
+	if err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to schedule upload: %v", err))
+		c.mainWindow.SetStatus("Upload failed: " + err.Error())
+		return fmt.Errorf("failed to schedule upload: %w", err)
+	}
+	
+	c.whenQueuedUploadDone(file.ID, done)
+	c.logger.Info(fmt.Sprintf("Scheduled upload of %s for %s", file.ID, opts.StartAt.Format(time.RFC3339)))
+	c.mainWindow.SetStatus(fmt.Sprintf("Upload of %s scheduled for %s", file.FileName, opts.StartAt.Format("Jan 2 15:04")))
+	if err := c.refreshFiles(); err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to refresh files after scheduling upload: %v", err))
+	}
+	return nil
+}
+
+// whenQueuedUploadDone arranges for done to be called once a queued upload has been sent or dropped
+func (c *Controller) whenQueuedUploadDone(fileID string, done func()) {
+	if done == nil {
+		return
+	}
+	
+	c.queuedUploadsMutex.Lock()
+	defer c.queuedUploadsMutex.Unlock()
+	
+	c.queuedUploadsDone[fileID] = done
+}
+
+// finishQueuedUploads calls done for the queued uploads no longer waiting to be sent
+func (c *Controller) finishQueuedUploads() {
+	c.queuedUploadsMutex.Lock()
+	defer c.queuedUploadsMutex.Unlock()
+	
+	for fileID, done := range c.queuedUploadsDone {
+		if file, err := c.fileManager.GetFile(fileID); err == nil && file.Status == models.StatusPending {
+			continue
+		}
+		done()
+		delete(c.queuedUploadsDone, fileID)
+	}
+}
+
+// handleChangeBandwidthLimit saves the upload bandwidth limit shared by all uploads and applies it
+// to the uploads already running
+func (c *Controller) handleChangeBandwidthLimit(kbps int) error {
+	if err := models.ValidateBandwidthLimit("upload_bandwidth_limit_kbps", kbps); err != nil {
+		return err
+	}
+	
+	settings, err := c.settingsManager.LoadSettings()
+	if err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to load settings: %v", err))
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+	
+	settings.UploadBandwidthLimitKBps = kbps
+	if err := c.settingsManager.SaveSettings(settings); err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to save upload bandwidth limit: %v", err))
+		return fmt.Errorf("failed to save settings: %w", err)
+	}
+	
+	c.fileManager.SetUploadSettings(settings)
+	c.mainWindow.ShowBandwidthLimit(kbps)
+	if kbps == 0 {
+		c.mainWindow.SetStatus("Upload speed unlimited")
+	} else {
+		c.mainWindow.SetStatus("Upload speed limited to " + models.FormatBandwidth(kbps))
+	}
+	return nil
+}
+
+// handleChangeUploadBandwidthLimit changes the speed limit of an upload started from the UI while it runs
+func (c *Controller) handleChangeUploadBandwidthLimit(fileID string, kbps int) error {
+	if err := models.ValidateBandwidthLimit("bandwidth_limit", kbps); err != nil {
+		return err
+	}
+	
+	file, err := c.fileManager.GetFile(fileID)
+	if err != nil {
+		return fmt.Errorf("failed to get file: %w", err)
+	}
+	
+	c.uploadLimitsMutex.Lock()
+	limit, ok := c.uploadLimits[file.FilePath]
+	c.uploadLimitsMutex.Unlock()
+	if !ok || file.Status != models.StatusUploading {
+		// Uploads started by the queue, such as scheduled ones, only follow the limit in the toolbar
+		return fmt.Errorf("%s has no speed limit of its own to change; use the upload speed in the toolbar", file.FileName)
+	}
+	
+	limit.Set(kbps)
+	if kbps == 0 {
+		c.mainWindow.SetStatus(fmt.Sprintf("Upload of %s unlimited", file.FileName))
+	} else {
+		c.mainWindow.SetStatus(fmt.Sprintf("Upload of %s limited to %s", file.FileName, models.FormatBandwidth(kbps)))
+	}
+	return nil
+}
+
+// trackUploadLimit records the speed limit of an upload starting, so it can be changed while it runs
+func (c *Controller) trackUploadLimit(filePath string, limit *models.BandwidthLimit) {
+	c.uploadLimitsMutex.Lock()
+	defer c.uploadLimitsMutex.Unlock()
+	
+	c.uploadLimits[filePath] = limit
+}
+
+// untrackUploadLimit forgets the speed limit of a finished upload, unless the file is being uploaded again
+func (c *Controller) untrackUploadLimit(filePath string, limit *models.BandwidthLimit) {
+	c.uploadLimitsMutex.Lock()
+	defer c.uploadLimitsMutex.Unlock()
+	
+	if c.uploadLimits[filePath] == limit {
+		delete(c.uploadLimits, filePath)
+	}
+}
+
+// handleUploadClipboard uploads text from the clipboard as a text file, with the default expiration
+func (c *Controller) handleUploadClipboard(text string) error {
+	if strings.TrimSpace(text) == "" {
+		c.mainWindow.SetStatus("Upload failed: Clipboard is empty")
+		return fmt.Errorf("clipboard is empty")
+	}
+	
+	settings, err := c.settingsManager.LoadSettings()
+	if err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to load settings, using default expiration: %v", err))
+		settings = models.DefaultApplicationSettings()
+	}
+	
+	// The file is removed once it has been uploaded, or the upload has failed
+	if err := os.MkdirAll(c.clipboardDir, 0700); err != nil {
+		return fmt.Errorf("failed to create clipboard directory: %w", err)
+	}
+	file, err := os.CreateTemp(c.clipboardDir, "clipboard-"+time.Now().Format("20060102-150405")+"-*.txt")
+	if err != nil {
+		return fmt.Errorf("failed to save clipboard: %w", err)
+	}
+	if _, err := file.WriteString(text); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to save clipboard: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to save clipboard: %w", err)
+	}
+	
+	removeFile := func() {
+		if err := os.Remove(file.Name()); err != nil && !os.IsNotExist(err) {
+			c.logger.Error(fmt.Sprintf("Failed to remove clipboard file: %v", err))
+		}
+	}
+	if err := c.uploadFile(file.Name(), settings.GetExpirationDuration(), models.UploadOptions{}, removeFile); err != nil {
+		removeFile()
+		return err
+	}
+	return nil
+}
+
 // handleShareFile handles file sharing requests from UI
-func (c *Controller) handleShareFile(fileID string, recipients []string, message string) error {
+func (c *Controller) handleShareFile(fileID string, recipients []string, message string, opts models.ShareOptions) error {
 	c.logger.Info(fmt.Sprintf("Starting file share: %s with %d recipients", fileID, len(recipients)))
 	
+	if c.queueWhileOffline() {
+		if err := c.outbox.QueueShare(fileID, recipients, message, opts); err != nil {
+			c.logger.Error(fmt.Sprintf("Failed to queue share: %v", err))
+			c.mainWindow.SetStatus("Sharing failed: " + err.Error())
+			return fmt.Errorf("failed to queue share: %w", err)
+		}
+		
+		c.mainWindow.SetStatus("Offline - share queued until S3 is reachable")
+		if err := c.refreshFiles(); err != nil {
+			c.logger.Error(fmt.Sprintf("Failed to refresh files after queueing share: %v", err))
+		}
+		return nil
+	}
+	
 	// Check if we're in offline mode
 	if c.syncManager.IsOfflineMode() {
 		c.logger.Error("Cannot share files in offline mode")
@@ -203,7 +652,7 @@ func (c *Controller) handleShareFile(fileID string, recipients []string, message
 	
 	// Perform sharing in background goroutine
 	go func() {
-		shareRecord, err := c.shareManager.ShareFile(c.ctx, fileID, recipients, message)
+		shareRecord, err := c.shareManager.ShareFileWithOptions(c.ctx, fileID, recipients, message, opts)
 		if err != nil {
 			c.logger.Error(fmt.Sprintf("File sharing failed: %v", err))
 			
@@ -221,6 +670,8 @@ func (c *Controller) handleShareFile(fileID string, recipients []string, message
 		c.logger.Info(fmt.Sprintf("File shared successfully: %s", shareRecord.ID))
 		c.mainWindow.SetStatus("File shared successfully")
 		
+		c.showShareEmail(shareRecord)
+		
 		// Refresh file list to update sharing status
 		if err := c.refreshFiles(); err != nil {
 			c.logger.Error(fmt.Sprintf("Failed to refresh files after sharing: %v", err))
@@ -230,10 +681,61 @@ func (c *Controller) handleShareFile(fileID string, recipients []string, message
 	return nil
 }
 
+// showShareEmail hands the user an email with the link and checksum to send to recipients
+func (c *Controller) showShareEmail(shareRecord *models.ShareRecord) {
+	file, err := c.fileManager.GetFile(shareRecord.FileID)
+	if err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to compose share email: %v", err))
+		return
+	}
+	
+	c.mainWindow.ShowShareEmail(models.NewShareEmail(file, &models.ShareRecord{
+		ID:            shareRecord.ID,
+		FileID:        shareRecord.FileID,
+		Recipients:    shareRecord.Recipients,
+		Message:       shareRecord.Message,
+		SharedDate:    shareRecord.SharedDate,
+		PresignedURL:  shareRecord.PresignedURL,
+		URLExpiration: shareRecord.URLExpiration,
+		ShareOptions:  shareRecord.ShareOptions,
+	}))
+}
+
 // handleDeleteFile handles file deletion requests from UI
 func (c *Controller) handleDeleteFile(fileID string) error {
 	c.logger.Info(fmt.Sprintf("Starting file deletion: %s", fileID))
 	
+	if c.queueWhileOffline() {
+		if err := c.outbox.QueueDelete(fileID); err != nil {
+			c.logger.Error(fmt.Sprintf("Failed to queue deletion: %v", err))
+			c.mainWindow.SetStatus("Deletion failed: " + err.Error())
+			return fmt.Errorf("failed to queue deletion: %w", err)
+		}
+		
+		c.mainWindow.SetStatus("Offline - deletion queued until S3 is reachable")
+		if err := c.refreshFiles(); err != nil {
+			c.logger.Error(fmt.Sprintf("Failed to refresh files after queueing deletion: %v", err))
+		}
+		return nil
+	}
+	
+	// A file still waiting to be uploaded, such as a scheduled upload, is just dropped from the queue
+	if c.outbox != nil {
+		if file, err := c.fileManager.GetFile(fileID); err == nil && file.Status == models.StatusPending {
+			if err := c.outbox.QueueDelete(fileID); err != nil {
+				c.logger.Error(fmt.Sprintf("Failed to cancel queued upload: %v", err))
+				c.mainWindow.SetStatus("Deletion failed: " + err.Error())
+				return fmt.Errorf("failed to cancel queued upload: %w", err)
+			}
+			
+			c.mainWindow.SetStatus("Queued upload cancelled")
+			if err := c.refreshFiles(); err != nil {
+				c.logger.Error(fmt.Sprintf("Failed to refresh files after cancelling upload: %v", err))
+			}
+			return nil
+		}
+	}
+	
 	// Update UI to show deletion in progress
 	c.mainWindow.SetStatus("Deleting file...")
 	c.mainWindow.EnableActions(false)
@@ -245,7 +747,7 @@ func (c *Controller) handleDeleteFile(fileID string) error {
 			c.mainWindow.EnableActions(true)
 		}()
 		
-		err := c.fileManager.DeleteFile(fileID)
+		err := c.fileManager.RemoveFile(c.ctx, fileID)
 		if err != nil {
 			c.logger.Error(fmt.Sprintf("File deletion failed: %v", err))
 			c.mainWindow.SetStatus("Deletion failed: " + err.Error())
@@ -264,6 +766,38 @@ func (c *Controller) handleDeleteFile(fileID string) error {
 	return nil
 }
 
+// handleRetryUpload uploads a file again after its upload failed
+func (c *Controller) handleRetryUpload(fileID string) error {
+	c.logger.Info(fmt.Sprintf("Retrying upload: %s", fileID))
+	
+	if c.syncManager.IsOfflineMode() {
+		c.mainWindow.SetStatus("Retry failed: Application is in offline mode")
+		return fmt.Errorf("cannot retry uploads in offline mode")
+	}
+	
+	c.mainWindow.SetStatus("Retrying upload...")
+	
+	go func() {
+		fileMetadata, err := c.fileManager.RetryUpload(c.ctx, fileID, nil)
+		if err != nil {
+			c.logger.Error(fmt.Sprintf("Upload retry failed: %v", err))
+			c.mainWindow.SetStatus("Retry failed: " + err.Error())
+		} else {
+			c.logger.Info(fmt.Sprintf("Upload retry completed: %s", fileMetadata.ID))
+			c.mainWindow.SetStatus("Upload completed successfully")
+			c.notify(models.NotifyUploadComplete, fileMetadata.ID, "Upload complete",
+				fmt.Sprintf("%s was uploaded and expires %s", fileMetadata.FileName, fileMetadata.ExpirationDate.Format("Jan 2 15:04")))
+		}
+		
+		// Show the new status, or the error status the file kept
+		if err := c.refreshFiles(); err != nil {
+			c.logger.Error(fmt.Sprintf("Failed to refresh files after retrying upload: %v", err))
+		}
+	}()
+	
+	return nil
+}
+
 // handleRefreshFiles handles file list refresh requests from UI
 func (c *Controller) handleRefreshFiles() ([]models.FileMetadata, error) {
 	err := c.refreshFiles()
@@ -272,80 +806,388 @@ func (c *Controller) handleRefreshFiles() ([]models.FileMetadata, error) {
 	}
 	
 	// Return the current file list
-	files, err := c.fileManager.ListFiles()
+	return c.listFiles()
+}
+
+// handleFilterFiles applies the search, filters and sort order chosen in the UI to the file list.
+// The query is kept, so later refreshes show the same files.
+func (c *Controller) handleFilterFiles(query models.FileQuery) ([]models.FileMetadata, error) {
+	c.fileQueryMutex.Lock()
+	previous := c.fileQuery
+	c.fileQuery = query
+	c.fileQueryMutex.Unlock()
+	
+	files, err := c.listFiles()
 	if err != nil {
-		return nil, fmt.Errorf("failed to list files: %w", err)
+		// Keep showing the files matching the previous query
+		c.fileQueryMutex.Lock()
+		c.fileQuery = previous
+		c.fileQueryMutex.Unlock()
+		return nil, err
 	}
 	
-	// Convert []*models.FileMetadata to []models.FileMetadata for UI
-	fileList := make([]models.FileMetadata, len(files))
-	for i, file := range files {
-		fileList[i] = *file
+	return files, nil
+}
+
+// currentFileQuery returns the search, filters and sort order of the file list
+func (c *Controller) currentFileQuery() models.FileQuery {
+	c.fileQueryMutex.Lock()
+	defer c.fileQueryMutex.Unlock()
+	return c.fileQuery
+}
+
+// refreshFiles loads the current file list and recent shares and updates the UI
+func (c *Controller) refreshFiles() error {
+	c.refreshMutex.Lock()
+	defer c.refreshMutex.Unlock()
+	
+	fileList, err := c.listFiles()
+	if err != nil {
+		return err
 	}
 	
-	return fileList, nil
+	// Update UI with file list
+	c.mainWindow.UpdateFiles(fileList)
+	c.publishRecentShares()
+	
+	c.logger.Info(fmt.Sprintf("Refreshed file list: %d files", len(fileList)))
+	return nil
 }
 
-// refreshFiles loads the current file list and updates the UI
-func (c *Controller) refreshFiles() error {
-	files, err := c.fileManager.ListFiles()
+// publishRecentShares updates the recent shares offered in the tray menu
+func (c *Controller) publishRecentShares() {
+	shares, err := c.shareManager.ListRecentShares(c.fileManager.GetProfile(), recentSharesLimit)
+	if err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to list recent shares: %v", err))
+		return
+	}
+	
+	recent := make([]models.RecentShare, len(shares))
+	for i, share := range shares {
+		recent[i] = *share
+	}
+	c.mainWindow.SetRecentShares(recent)
+}
+
+// listFiles returns the file list for the UI matching the current query, marking files with queued operations
+func (c *Controller) listFiles() ([]models.FileMetadata, error) {
+	files, err := c.fileManager.QueryFiles(c.currentFileQuery())
 	if err != nil {
-		return fmt.Errorf("failed to list files: %w", err)
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+	
+	var pending map[string]models.OutboxOperation
+	var scheduled map[string]time.Time
+	if c.outbox != nil {
+		if pending, err = c.outbox.PendingOperations(); err != nil {
+			c.logger.Error(fmt.Sprintf("Failed to load queued operations: %v", err))
+		}
+		if scheduled, err = c.outbox.ScheduledUploads(); err != nil {
+			c.logger.Error(fmt.Sprintf("Failed to load scheduled uploads: %v", err))
+		}
 	}
 	
 	// Convert []*models.FileMetadata to []models.FileMetadata for UI
 	fileList := make([]models.FileMetadata, len(files))
 	for i, file := range files {
 		fileList[i] = *file
+		fileList[i].PendingOperation = pending[file.ID]
+		fileList[i].ScheduledAt = scheduled[file.ID]
 	}
 	
-	// Update UI with file list
-	c.mainWindow.UpdateFiles(fileList)
-	
-	c.logger.Info(fmt.Sprintf("Refreshed file list: %d files", len(files)))
-	return nil
+	return fileList, nil
 }
 
-// startExpirationChecker runs a background process to check for expired files
-func (c *Controller) startExpirationChecker() {
-	c.logger.Info("Starting background expiration checker")
+// startConnectivityMonitor probes S3 in the background while offline and synchronizes once it is reachable
+func (c *Controller) startConnectivityMonitor() {
+	c.logger.Info("Starting background connectivity monitor")
 	
-	ticker := time.NewTicker(5 * time.Minute) // Check every 5 minutes
-	defer ticker.Stop()
+	monitor := connectivity.NewMonitor(c.syncManager.TestConnection, c.syncManager.IsOfflineMode,
+		c.handleConnectivityRestored, connectivity.DefaultConfig())
 	
-	for {
-		select {
-		case <-c.ctx.Done():
-			c.logger.Info("Stopping expiration checker")
-			return
-		case <-ticker.C:
-			c.checkAndCleanupExpiredFiles()
-		}
+	// Network changes trigger an immediate check where the platform reports them
+	if changes, err := connectivity.WatchNetworkChanges(c.ctx); err != nil {
+		c.logger.Warn(fmt.Sprintf("Network change notifications unavailable: %v", err))
+	} else {
+		monitor.SetNetworkChanges(changes)
 	}
+	
+	monitor.Run(c.ctx)
+	c.logger.Info("Stopping connectivity monitor")
 }
 
-// checkAndCleanupExpiredFiles checks for expired files and updates their status
-func (c *Controller) checkAndCleanupExpiredFiles() {
-	c.logger.Info("Checking for expired files")
+// handleConnectivityRestored leaves offline mode and synchronizes with S3, which also
+// re-enables actions and sends anything queued while offline
+func (c *Controller) handleConnectivityRestored() {
+	c.logger.Info("S3 reachable again, leaving offline mode")
 	
-	err := c.expirationManager.CleanupExpiredFiles()
-	if err != nil {
-		c.logger.Error(fmt.Sprintf("Failed to cleanup expired files: %v", err))
-		return
-	}
+	c.syncManager.SetOfflineMode(false)
+	c.mainWindow.SetStatus("Back online, synchronizing with S3...")
 	
-	// Refresh file list to update UI with any status changes
-	if err := c.refreshFiles(); err != nil {
-		c.logger.Error(fmt.Sprintf("Failed to refresh files after expiration cleanup: %v", err))
+	if _, err := c.SyncWithS3(); err != nil {
+		c.logger.Error(fmt.Sprintf("Sync after reconnecting failed: %v", err))
 	}
 }
 
-// GeneratePresignedURL generates a presigned URL for a file (used by UI for copy link functionality)
-func (c *Controller) GeneratePresignedURL(fileID string, expiration time.Duration) (string, error) {
-	c.logger.Info(fmt.Sprintf("Generating presigned URL for file: %s", fileID))
+// registerJobs registers the background jobs with default intervals; Start applies the saved ones
+func (c *Controller) registerJobs() {
+	defaults := models.DefaultApplicationSettings()
+	
+	jobs := []struct {
+		name     string
+		interval time.Duration
+		run      scheduler.JobFunc
+	}{
+		{jobExpirationCheck, defaults.GetExpirationCheckInterval(), func(ctx context.Context) error {
+			return c.checkAndCleanupExpiredFiles()
+		}},
+		{jobSync, defaults.GetSyncInterval(), c.runScheduledSync},
+		{jobMetadataCleanup, defaults.GetMetadataCleanupInterval(), c.runMetadataCleanup},
+		{jobURLRenewal, defaults.GetURLRenewalInterval(), c.runURLRenewal},
+	}
 	
-	// Check if we're in offline mode
-	if c.syncManager.IsOfflineMode() {
+	for _, job := range jobs {
+		if err := c.scheduler.Register(job.name, job.interval, job.run); err != nil {
+			c.logger.Error(fmt.Sprintf("Failed to register background job %s: %v", job.name, err))
+		}
+	}
+	
+	c.scheduler.SetOnChange(func(status scheduler.JobStatus) {
+		if status.Name == jobSync {
+			c.publishSyncSchedule()
+		}
+	})
+}
+
+// applyJobSettings sets the background job intervals from the settings. Automatic sync only runs with AutoRefresh on.
+func (c *Controller) applyJobSettings(settings *models.ApplicationSettings) {
+	syncInterval := settings.GetSyncInterval()
+	if !settings.AutoRefresh {
+		syncInterval = 0
+	}
+	
+	intervals := map[string]time.Duration{
+		jobExpirationCheck: settings.GetExpirationCheckInterval(),
+		jobSync:            syncInterval,
+		jobMetadataCleanup: settings.GetMetadataCleanupInterval(),
+		jobURLRenewal:      settings.GetURLRenewalInterval(),
+	}
+	
+	for name, interval := range intervals {
+		if err := c.scheduler.SetInterval(name, interval); err != nil {
+			c.logger.Error(fmt.Sprintf("Failed to set interval of background job %s: %v", name, err))
+		}
+	}
+}
+
+// publishSyncSchedule shows the last successful sync and the next automatic one in the UI
+func (c *Controller) publishSyncSchedule() {
+	status, err := c.scheduler.Status(jobSync)
+	if err != nil {
+		return
+	}
+	
+	// The last successful sync is kept in the database, so it survives restarts
+	lastSynced, err := c.syncManager.GetLastSyncTime()
+	if err != nil {
+		lastSynced = time.Time{}
+	}
+	
+	c.mainWindow.SetSyncSchedule(lastSynced, status.NextRun)
+}
+
+// markSynced pushes the next automatic sync back after a sync started some other way
+func (c *Controller) markSynced() {
+	if err := c.scheduler.MarkRun(jobSync); err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to record sync: %v", err))
+	}
+}
+
+// checkAndCleanupExpiredFiles checks for expired files and updates their status
+func (c *Controller) checkAndCleanupExpiredFiles() error {
+	c.logger.Info("Checking for expired files")
+	
+	// Find the newly expired files first, cleanup only changes their status
+	expiredFiles, err := c.expirationManager.CheckExpirations()
+	if err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to check expirations: %v", err))
+	}
+	
+	err = c.expirationManager.CleanupExpiredFiles()
+	if err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to cleanup expired files: %v", err))
+		return fmt.Errorf("failed to cleanup expired files: %w", err)
+	}
+	
+	for _, file := range expiredFiles {
+		c.notify(models.NotifyFileExpired, file.ID, "File expired",
+			fmt.Sprintf("%s has expired and can no longer be shared", file.FileName))
+	}
+	c.notifyExpiringFiles()
+	
+	// Refresh file list to update UI with any status changes
+	if err := c.refreshFiles(); err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to refresh files after expiration cleanup: %v", err))
+	}
+	
+	return nil
+}
+
+// notifyExpiringFiles warns about active files that expire within the configured warning window
+func (c *Controller) notifyExpiringFiles() {
+	if c.notificationManager == nil {
+		return
+	}
+	
+	now := time.Now()
+	files, err := c.fileManager.QueryFiles(models.FileQuery{
+		Statuses:      []models.FileStatus{models.StatusActive},
+		ExpiresAfter:  now,
+		ExpiresBefore: now.Add(c.notificationManager.ExpiryWarningWindow()),
+		SortBy:        models.SortByExpiration,
+		Ascending:     true,
+	})
+	if err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to list files for expiry warnings: %v", err))
+		return
+	}
+	
+	for _, file := range files {
+		left := file.ExpirationDate.Sub(now)
+		c.notify(models.NotifyExpiringSoon, file.ID, "File expiring soon",
+			fmt.Sprintf("%s expires in %s", file.FileName, formatTimeLeft(left)))
+	}
+}
+
+// formatTimeLeft formats the time until a file expires, rounded to a readable unit
+func formatTimeLeft(left time.Duration) string {
+	switch {
+	case left < time.Hour:
+		minutes := int(left.Round(time.Minute) / time.Minute)
+		if minutes <= 1 {
+			return "1 minute"
+		}
+		return fmt.Sprintf("%d minutes", minutes)
+	case left < 48*time.Hour:
+		hours := int(left.Round(time.Hour) / time.Hour)
+		if hours == 1 {
+			return "1 hour"
+		}
+		return fmt.Sprintf("%d hours", hours)
+	default:
+		return fmt.Sprintf("%d days", int(left.Round(24*time.Hour)/(24*time.Hour)))
+	}
+}
+
+// runScheduledSync reconciles local metadata with S3 in the background.
+// Unlike a manual sync it leaves the actions enabled and only reports problems in the status bar.
+func (c *Controller) runScheduledSync(ctx context.Context) error {
+	// While offline the connectivity monitor decides when to sync again
+	if c.syncManager.IsOfflineMode() {
+		return nil
+	}
+	
+	syncCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+	
+	result, err := c.syncManager.SyncWithS3(syncCtx)
+	if err != nil {
+		if result != nil && result.OfflineMode {
+			c.mainWindow.SetStatus("Offline Mode - S3 unavailable")
+		}
+		return fmt.Errorf("scheduled sync failed: %w", err)
+	}
+	
+	c.logger.Info(fmt.Sprintf("Scheduled sync completed: %d total, %d verified, %d missing, %d errors in %v",
+		result.TotalFiles, result.VerifiedFiles, result.MissingFiles, result.ErrorFiles, result.SyncDuration))
+	
+	if result.MismatchedFiles > 0 {
+		c.mainWindow.SetStatus(fmt.Sprintf("%d files changed in S3 since upload", result.MismatchedFiles))
+	} else if result.ErrorFiles > 0 || result.MissingFiles > 0 {
+		c.mainWindow.SetStatus(fmt.Sprintf("Sync found %d issues", result.ErrorFiles+result.MissingFiles))
+	}
+	c.notifyMissingFiles(result)
+	
+	if !result.OfflineMode {
+		c.replayOutbox()
+	}
+	
+	if err := c.refreshFiles(); err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to refresh files after scheduled sync: %v", err))
+	}
+	
+	return nil
+}
+
+// runMetadataCleanup removes the local records of files that expired long ago
+func (c *Controller) runMetadataCleanup(ctx context.Context) error {
+	if err := c.expirationManager.CleanupExpiredMetadata(); err != nil {
+		return fmt.Errorf("failed to cleanup expired metadata: %w", err)
+	}
+	
+	if err := c.refreshFiles(); err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to refresh files after metadata cleanup: %v", err))
+	}
+	
+	return nil
+}
+
+// runURLRenewal re-signs share links that would expire before the next renewal run
+func (c *Controller) runURLRenewal(ctx context.Context) error {
+	if c.syncManager.IsOfflineMode() {
+		return nil
+	}
+	
+	window := urlRenewalMargin
+	if status, err := c.scheduler.Status(jobURLRenewal); err == nil {
+		window += status.Interval
+	}
+	
+	renewed, err := c.shareManager.RenewExpiringURLs(ctx, c.fileManager.GetProfile(), window)
+	if renewed > 0 {
+		c.logger.Info(fmt.Sprintf("Renewed %d share links", renewed))
+		c.publishRecentShares()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to renew share links: %w", err)
+	}
+	
+	return nil
+}
+
+// runScheduledUploads starts the scheduled uploads whose time has come, with anything queued
+// for their files after them. While offline they wait for the replay once S3 is reachable.
+func (c *Controller) runScheduledUploads(ctx context.Context) error {
+	if c.outbox == nil || c.syncManager.IsOfflineMode() {
+		return nil
+	}
+	
+	pending, err := c.outbox.PendingOperations()
+	if err != nil {
+		return fmt.Errorf("failed to load queued operations: %w", err)
+	}
+	scheduled, err := c.outbox.ScheduledUploads()
+	if err != nil {
+		return fmt.Errorf("failed to load scheduled uploads: %w", err)
+	}
+	if len(pending) == len(scheduled) {
+		// Nothing is due yet
+		return nil
+	}
+	
+	c.replayOutbox()
+	if err := c.refreshFiles(); err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to refresh files after scheduled uploads: %v", err))
+	}
+	return nil
+}
+
+// GeneratePresignedURL generates a presigned URL for a file (used by UI for copy link functionality)
+func (c *Controller) GeneratePresignedURL(fileID string, expiration time.Duration) (string, error) {
+	c.logger.Info(fmt.Sprintf("Generating presigned URL for file: %s", fileID))
+	
+	// Check if we're in offline mode
+	if c.syncManager.IsOfflineMode() {
 		c.logger.Error("Cannot generate presigned URLs in offline mode")
 		return "", fmt.Errorf("cannot generate presigned URLs in offline mode")
 	}
@@ -368,11 +1210,87 @@ func (c *Controller) GeneratePresignedURL(fileID string, expiration time.Duratio
 	return url, nil
 }
 
+// handleCopyShareLink rebuilds a recent share's link so it can be copied from the tray.
+// Neither signing nor deriving a short link needs network access, so it works offline too.
+func (c *Controller) handleCopyShareLink(shareID string) (string, error) {
+	url, err := c.shareManager.ShareURL(c.ctx, c.fileManager.GetProfile(), shareID)
+	if err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to sign share link: %v", err))
+		return "", err
+	}
+	
+	return url, nil
+}
+
+// handleRevokeShare stops a recent share's short link from working and drops it from the tray
+func (c *Controller) handleRevokeShare(shareID string) error {
+	if err := c.shareManager.RevokeShare(c.ctx, c.fileManager.GetProfile(), shareID); err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to revoke share %s: %v", shareID, err))
+		return err
+	}
+	
+	c.logger.Info(fmt.Sprintf("Revoked share %s", shareID))
+	c.publishRecentShares()
+	return nil
+}
+
+// handleDownloadFile downloads a file to destPath. It blocks until the download finishes,
+// so the UI calls it off the main thread.
+func (c *Controller) handleDownloadFile(fileID string, destPath string) error {
+	c.logger.Info(fmt.Sprintf("Starting file download: %s", fileID))
+	
+	// Check if we're in offline mode
+	if c.syncManager.IsOfflineMode() {
+		c.logger.Error("Cannot download files in offline mode")
+		c.mainWindow.SetStatus("Download failed: Application is in offline mode")
+		return fmt.Errorf("cannot download files in offline mode")
+	}
+	
+	c.mainWindow.SetStatus("Downloading file...")
+	
+	err := c.fileManager.DownloadFile(c.ctx, fileID, destPath)
+	if err != nil {
+		c.logger.Error(fmt.Sprintf("File download failed: %v", err))
+		c.mainWindow.SetStatus("Download failed: " + err.Error())
+		
+		// Check if error is due to network issues and enter offline mode
+		if isNetworkError(err) {
+			c.logger.Info("Network error detected, entering offline mode")
+			c.syncManager.SetOfflineMode(true)
+			return fmt.Errorf("network error - entered offline mode: %w", err)
+		}
+		
+		return err
+	}
+	
+	c.logger.Info(fmt.Sprintf("File downloaded successfully: %s", fileID))
+	c.mainWindow.SetStatus("File downloaded successfully")
+	return nil
+}
+
 // GetShareHistory retrieves sharing history for a file
-func (c *Controller) GetShareHistory(fileID string) ([]*storage.ShareRecord, error) {
+func (c *Controller) GetShareHistory(fileID string) ([]*models.ShareRecord, error) {
 	return c.shareManager.GetShareHistory(fileID)
 }
 
+// SetFileExpiration changes a file's expiration to duration from now, queueing the change while offline
+func (c *Controller) SetFileExpiration(fileID string, duration time.Duration) error {
+	if c.queueWhileOffline() {
+		if err := c.outbox.QueueSetExpiration(fileID, duration); err != nil {
+			return fmt.Errorf("failed to queue expiration change: %w", err)
+		}
+		c.mainWindow.SetStatus("Offline - expiration change queued until S3 is reachable")
+	} else if err := c.expirationManager.SetExpiration(fileID, duration); err != nil {
+		return fmt.Errorf("failed to set expiration: %w", err)
+	}
+	
+	if err := c.refreshFiles(); err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to refresh files after changing expiration: %v", err))
+	}
+	
+	return nil
+}
+
 // handleSaveSettings handles settings save requests from UI
 func (c *Controller) handleSaveSettings(settings *models.ApplicationSettings) error {
 	c.logger.Info("Saving application settings")
@@ -383,6 +1301,20 @@ func (c *Controller) handleSaveSettings(settings *models.ApplicationSettings) er
 		return fmt.Errorf("failed to save settings: %w", err)
 	}
 	
+	// Keep the active profile in step with the settings dialog
+	if c.profileManager != nil {
+		if err := c.updateActiveProfile(settings); err != nil {
+			c.logger.Error(fmt.Sprintf("Failed to update active profile: %v", err))
+			return fmt.Errorf("failed to update active profile: %w", err)
+		}
+	}
+	
+	// Pick up changed job intervals and AutoRefresh without a restart
+	c.applyJobSettings(settings)
+	c.mainWindow.SetMinimizeToTray(settings.MinimizeToTray)
+	c.fileManager.SetUploadSettings(settings)
+	c.mainWindow.ShowBandwidthLimit(settings.UploadBandwidthLimitKBps)
+	
 	c.logger.Info("Application settings saved successfully")
 	return nil
 }
@@ -401,6 +1333,356 @@ func (c *Controller) handleLoadSettings() (*models.ApplicationSettings, error) {
 	return settings, nil
 }
 
+// handleLoadNotifications returns the notification history for the UI, newest first
+func (c *Controller) handleLoadNotifications() ([]*models.Notification, error) {
+	if c.notificationManager == nil {
+		return nil, nil
+	}
+	
+	notifications, err := c.notificationManager.History(notificationHistoryLimit)
+	if err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to load notification history: %v", err))
+		return nil, fmt.Errorf("failed to load notification history: %w", err)
+	}
+	
+	return notifications, nil
+}
+
+// handleClearNotifications clears the notification history
+func (c *Controller) handleClearNotifications() error {
+	if c.notificationManager == nil {
+		return nil
+	}
+	
+	if err := c.notificationManager.ClearHistory(); err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to clear notification history: %v", err))
+		return fmt.Errorf("failed to clear notification history: %w", err)
+	}
+	
+	return nil
+}
+
+// SwitchProfile makes the named profile active, rebuilding the S3 service and reloading its files
+func (c *Controller) SwitchProfile(name string) error {
+	if c.profileManager == nil {
+		return fmt.Errorf("profiles are not enabled")
+	}
+	
+	c.logger.Info(fmt.Sprintf("Switching to profile: %s", name))
+	
+	profile, err := c.profileManager.GetProfile(name)
+	if err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to load profile %s: %v", name, err))
+		return fmt.Errorf("failed to load profile: %w", err)
+	}
+	
+	if err := c.profileManager.SetActiveProfile(name); err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to activate profile %s: %v", name, err))
+		return fmt.Errorf("failed to activate profile: %w", err)
+	}
+	
+	// Mirror the profile into the application settings so the settings dialog shows it
+	settings, err := c.settingsManager.LoadSettings()
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+	profile.ApplyTo(settings)
+	if err := c.settingsManager.SaveSettings(settings); err != nil {
+		return fmt.Errorf("failed to save settings for profile: %w", err)
+	}
+	
+	connected := c.activateProfile(profile)
+	
+	if err := c.refreshFiles(); err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to refresh files after switching profile: %v", err))
+	}
+	
+	if connected {
+		c.mainWindow.SetStatus(fmt.Sprintf("Switched to profile %s, synchronizing with S3...", name))
+		go c.performInitialSync()
+	} else {
+		c.mainWindow.SetStatus(fmt.Sprintf("Switched to profile %s (Offline Mode - AWS not configured)", name))
+	}
+	
+	return nil
+}
+
+// activateProfile rebuilds the S3 service for a profile and hands it to the managers.
+// Returns false if no S3 service could be created, in which case the managers run offline.
+func (c *Controller) activateProfile(profile *models.Profile) bool {
+	var s3Service aws.S3Service
+	if c.serviceFactory != nil {
+		service, err := c.serviceFactory.NewS3Service(profile)
+		if err != nil {
+			c.logger.Info(fmt.Sprintf("S3 service unavailable for profile %s: %v", profile.Name, err))
+		} else {
+			s3Service = service
+		}
+	}
+	
+	c.fileManager.SetProfile(profile.Name, s3Service)
+	c.shareManager.SetS3Service(s3Service)
+	c.shareManager.SetShareLinks(c.shareLinks(profile))
+	c.syncManager.SetProfile(profile.Name, s3Service)
+	
+	c.publishProfiles()
+	
+	return s3Service != nil
+}
+
+// shareLinks returns where the profile's short share links are served and the key their tokens are
+// derived from. Both are empty when the profile has no redirect function, so shares get presigned links.
+func (c *Controller) shareLinks(profile *models.Profile) (string, []byte) {
+	if profile.ShareLinkURL == "" || c.serviceFactory == nil {
+		return "", nil
+	}
+	
+	key, err := c.serviceFactory.ShareLinkKey(profile.Name)
+	if err != nil {
+		c.logger.Error(fmt.Sprintf("Short share links unavailable for profile %s: %v", profile.Name, err))
+		return "", nil
+	}
+	
+	return profile.ShareLinkBase(), key
+}
+
+// publishProfiles sends the profile names and the active profile to the UI
+func (c *Controller) publishProfiles() {
+	profiles, err := c.profileManager.ListProfiles()
+	if err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to list profiles: %v", err))
+		return
+	}
+	
+	names := make([]string, len(profiles))
+	for i, profile := range profiles {
+		names[i] = profile.Name
+	}
+	
+	c.mainWindow.SetProfiles(names, c.fileManager.GetProfile())
+}
+
+// updateActiveProfile copies saved settings into the active profile, reconnecting if the bucket or region changed
+func (c *Controller) updateActiveProfile(settings *models.ApplicationSettings) error {
+	active, err := c.profileManager.GetActiveProfile()
+	if err != nil {
+		return err
+	}
+	
+	updated := *active
+	updated.UpdateFrom(settings)
+	if err := c.profileManager.SaveProfile(&updated); err != nil {
+		return err
+	}
+	
+	// The S3 service carries the bucket, region and upload encryption, so rebuild it if any changed
+	if updated.AWSRegion != active.AWSRegion || updated.S3Bucket != active.S3Bucket ||
+		updated.GetEncryptionMode() != active.GetEncryptionMode() || updated.KMSKeyID != active.KMSKeyID {
+		c.activateProfile(&updated)
+	}
+	
+	return nil
+}
+
+// handleLoadProfile handles profile load requests from UI
+func (c *Controller) handleLoadProfile(name string) (*models.Profile, error) {
+	if c.profileManager == nil {
+		return nil, fmt.Errorf("profiles are not enabled")
+	}
+	
+	return c.profileManager.GetProfile(name)
+}
+
+// handleSaveProfile handles profile save requests from UI. Credentials are only
+// stored when an access key is given so existing ones can be kept.
+func (c *Controller) handleSaveProfile(profile *models.Profile, accessKey, secretKey string) error {
+	if c.profileManager == nil {
+		return fmt.Errorf("profiles are not enabled")
+	}
+	
+	c.logger.Info(fmt.Sprintf("Saving profile: %s", profile.Name))
+	
+	if err := c.profileManager.SaveProfile(profile); err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to save profile: %v", err))
+		return fmt.Errorf("failed to save profile: %w", err)
+	}
+	
+	if accessKey != "" || secretKey != "" {
+		if c.serviceFactory == nil {
+			return fmt.Errorf("cannot store credentials: AWS services are not available")
+		}
+		if err := c.serviceFactory.StoreCredentials(profile.Name, accessKey, secretKey, profile.AWSRegion); err != nil {
+			c.logger.Error(fmt.Sprintf("Failed to store credentials for profile %s: %v", profile.Name, err))
+			return fmt.Errorf("failed to store credentials: %w", err)
+		}
+	}
+	
+	// Reconnect if the profile being edited is the one in use
+	if profile.Name == c.fileManager.GetProfile() {
+		return c.SwitchProfile(profile.Name)
+	}
+	
+	c.publishProfiles()
+	c.mainWindow.SetStatus(fmt.Sprintf("Profile %s saved", profile.Name))
+	return nil
+}
+
+// handleDeleteProfile handles profile deletion requests from UI
+func (c *Controller) handleDeleteProfile(name string) error {
+	if c.profileManager == nil {
+		return fmt.Errorf("profiles are not enabled")
+	}
+	
+	c.logger.Info(fmt.Sprintf("Deleting profile: %s", name))
+	
+	if err := c.profileManager.DeleteProfile(name); err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to delete profile: %v", err))
+		return fmt.Errorf("failed to delete profile: %w", err)
+	}
+	
+	if c.serviceFactory != nil {
+		if err := c.serviceFactory.ClearCredentials(name); err != nil {
+			// The profile is gone either way; stale keyring items are harmless
+			c.logger.Error(fmt.Sprintf("Failed to clear credentials for profile %s: %v", name, err))
+		}
+	}
+	
+	c.publishProfiles()
+	c.mainWindow.SetStatus(fmt.Sprintf("Profile %s deleted", name))
+	return nil
+}
+
+// handleProvisionBucket creates the stack for a new bucket and configures a profile from its outputs
+func (c *Controller) handleProvisionBucket(req *models.ProvisionRequest) (*models.ProvisionResult, error) {
+	if c.profileManager == nil || c.serviceFactory == nil {
+		return nil, fmt.Errorf("profiles are not enabled")
+	}
+	
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	
+	c.logger.Info(fmt.Sprintf("Provisioning stack %s for profile %s", req.StackName, req.ProfileName))
+	c.mainWindow.SetStatus("Provisioning bucket...")
+	
+	provisioner, err := c.serviceFactory.NewProvisioner(req)
+	if err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to create provisioner: %v", err))
+		c.mainWindow.SetStatus("Provisioning failed: " + err.Error())
+		return nil, fmt.Errorf("failed to create provisioner: %w", err)
+	}
+	
+	result, err := provisioner.Provision(c.ctx, req, func(status string) {
+		c.mainWindow.SetStatus("Provisioning bucket: " + status)
+	})
+	if err != nil {
+		c.logger.Error(fmt.Sprintf("Provisioning failed: %v", err))
+		c.mainWindow.SetStatus("Provisioning failed: " + err.Error())
+		return nil, fmt.Errorf("failed to provision bucket: %w", err)
+	}
+	
+	// Update the existing profile in place so its other settings survive re-provisioning
+	profile, err := c.profileManager.GetProfile(req.ProfileName)
+	if err != nil {
+		profile = models.NewProfile(req.ProfileName)
+	}
+	profile.S3Bucket = result.BucketName
+	profile.AWSRegion = result.Region
+	profile.StackName = result.StackName
+	profile.ShareLinkURL = result.ShareLinkURL
+	
+	if err := c.handleSaveProfile(profile, result.AccessKeyID, result.SecretAccessKey); err != nil {
+		return nil, err
+	}
+	
+	if profile.Name != c.fileManager.GetProfile() {
+		if err := c.SwitchProfile(profile.Name); err != nil {
+			return nil, err
+		}
+	}
+	
+	c.logger.Info(fmt.Sprintf("Provisioned bucket %s for profile %s", result.BucketName, profile.Name))
+	c.mainWindow.SetStatus(fmt.Sprintf("Bucket %s provisioned for profile %s", result.BucketName, profile.Name))
+	return result, nil
+}
+
+// handleCheckDrift reports whether the provisioned resources still match the template
+func (c *Controller) handleCheckDrift(req *models.ProvisionRequest) (*models.StackDriftReport, error) {
+	if c.serviceFactory == nil {
+		return nil, fmt.Errorf("AWS services are not available")
+	}
+	
+	if req.StackName == "" {
+		return nil, fmt.Errorf("stack name cannot be empty")
+	}
+	
+	c.logger.Info(fmt.Sprintf("Checking drift for stack %s", req.StackName))
+	c.mainWindow.SetStatus("Checking stack drift...")
+	
+	provisioner, err := c.serviceFactory.NewProvisioner(req)
+	if err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to create provisioner: %v", err))
+		c.mainWindow.SetStatus("Drift check failed: " + err.Error())
+		return nil, fmt.Errorf("failed to create provisioner: %w", err)
+	}
+	
+	report, err := provisioner.DetectDrift(c.ctx, req.StackName)
+	if err != nil {
+		c.logger.Error(fmt.Sprintf("Drift detection failed: %v", err))
+		c.mainWindow.SetStatus("Drift check failed: " + err.Error())
+		return nil, fmt.Errorf("failed to detect stack drift: %w", err)
+	}
+	
+	if report.IsDrifted() {
+		c.mainWindow.SetStatus(fmt.Sprintf("Stack %s has drifted (%d resources)", report.StackName, len(report.DriftedResources)))
+	} else {
+		c.mainWindow.SetStatus(fmt.Sprintf("Stack %s is in sync", report.StackName))
+	}
+	
+	return report, nil
+}
+
+// CheckBucketHealth diagnoses the configuration of the active profile's bucket
+func (c *Controller) CheckBucketHealth() (*models.HealthReport, error) {
+	if c.profileManager == nil || c.serviceFactory == nil {
+		return nil, fmt.Errorf("profiles are not enabled")
+	}
+	
+	profile, err := c.profileManager.GetActiveProfile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load active profile: %w", err)
+	}
+	
+	c.logger.Info(fmt.Sprintf("Checking bucket health for profile %s", profile.Name))
+	c.mainWindow.SetStatus("Checking bucket health...")
+	
+	checker, err := c.serviceFactory.NewHealthChecker(profile)
+	if err != nil {
+		c.logger.Error(fmt.Sprintf("Failed to create health checker: %v", err))
+		c.mainWindow.SetStatus("Health check failed: " + err.Error())
+		return nil, fmt.Errorf("failed to create health checker: %w", err)
+	}
+	
+	ctx, cancel := context.WithTimeout(c.ctx, time.Minute)
+	defer cancel()
+	
+	report, err := checker.CheckBucketHealth(ctx)
+	if err != nil {
+		c.logger.Error(fmt.Sprintf("Bucket health check failed: %v", err))
+		c.mainWindow.SetStatus("Health check failed: " + err.Error())
+		return nil, fmt.Errorf("failed to check bucket health: %w", err)
+	}
+	
+	failed := len(report.FailedChecks())
+	if failed > 0 {
+		c.mainWindow.SetStatus(fmt.Sprintf("Bucket health check: %d of %d checks failed", failed, len(report.Checks)))
+	} else {
+		c.mainWindow.SetStatus("Bucket health check: all checks passed")
+	}
+	
+	return report, nil
+}
+
 // performInitialSync performs initial synchronization with S3 on startup
 func (c *Controller) performInitialSync() {
 	c.logger.Info("Starting initial synchronization with S3")
@@ -427,11 +1709,21 @@ func (c *Controller) performInitialSync() {
 	// Update UI status based on sync results
 	if result.OfflineMode {
 		c.mainWindow.SetStatus("Ready (Offline Mode)")
+	} else if result.MismatchedFiles > 0 {
+		c.mainWindow.SetStatus(fmt.Sprintf("Ready (%d files changed in S3 since upload)", result.MismatchedFiles))
 	} else if result.ErrorFiles > 0 || result.MissingFiles > 0 {
 		c.mainWindow.SetStatus(fmt.Sprintf("Ready (Sync completed with %d issues)", result.ErrorFiles+result.MissingFiles))
 	} else {
 		c.mainWindow.SetStatus("Ready (Synced)")
 	}
+	c.notifyMissingFiles(result)
+	
+	// S3 is reachable, so finish interrupted uploads and send anything queued while offline
+	if !result.OfflineMode {
+		c.markSynced()
+		c.recoverInterruptedUploads()
+		c.replayOutbox()
+	}
 	
 	// Refresh file list to show any status updates
 	if err := c.refreshFiles(); err != nil {
@@ -472,11 +1764,21 @@ func (c *Controller) SyncWithS3() (*manager.SyncResult, error) {
 	// Update UI status based on sync results
 	if result.OfflineMode {
 		c.mainWindow.SetStatus("Ready (Offline Mode)")
+	} else if result.MismatchedFiles > 0 {
+		c.mainWindow.SetStatus(fmt.Sprintf("Sync completed: %d files changed in S3 since upload", result.MismatchedFiles))
 	} else if result.ErrorFiles > 0 || result.MissingFiles > 0 {
 		c.mainWindow.SetStatus(fmt.Sprintf("Sync completed with %d issues", result.ErrorFiles+result.MissingFiles))
 	} else {
 		c.mainWindow.SetStatus("Sync completed successfully")
 	}
+	c.notifyMissingFiles(result)
+	
+	// S3 is reachable, so finish interrupted uploads and send anything queued while offline
+	if !result.OfflineMode {
+		c.markSynced()
+		c.recoverInterruptedUploads()
+		c.replayOutbox()
+	}
 	
 	// Refresh file list to show any status updates
 	if err := c.refreshFiles(); err != nil {
@@ -486,6 +1788,49 @@ func (c *Controller) SyncWithS3() (*manager.SyncResult, error) {
 	return result, nil
 }
 
+// recoverInterruptedUploads finishes uploads left uploading when the app last stopped mid-upload
+func (c *Controller) recoverInterruptedUploads() {
+	result, err := c.fileManager.RecoverInterruptedUploads(c.ctx)
+	if err != nil {
+		c.logger.Error(fmt.Sprintf("Upload recovery stopped: %v", err))
+		if result.Remaining > 0 {
+			c.mainWindow.SetStatus(fmt.Sprintf("%d interrupted uploads still waiting for S3: %v", result.Remaining, err))
+		}
+		return
+	}
+	
+	if result.Failed > 0 {
+		c.mainWindow.SetStatus(fmt.Sprintf("%d interrupted uploads failed and can be retried: %s", result.Failed, result.Failures[0]))
+		c.notify(models.NotifyUploadFailed, "", "Interrupted uploads failed",
+			fmt.Sprintf("%d uploads interrupted when the app stopped could not be finished. Retry them from the file list.", result.Failed))
+	} else if recovered := result.Completed + result.Resumed; recovered > 0 {
+		c.mainWindow.SetStatus(fmt.Sprintf("Finished %d uploads interrupted when the app stopped", recovered))
+	}
+}
+
+// replayOutbox sends operations queued while offline, and scheduled uploads that are due, in order
+func (c *Controller) replayOutbox() {
+	if c.outbox == nil {
+		return
+	}
+	
+	result, err := c.outbox.Replay(c.ctx)
+	c.finishQueuedUploads()
+	
+	for _, shareRecord := range result.Shares {
+		c.showShareEmail(shareRecord)
+	}
+	
+	if err != nil {
+		c.logger.Error(fmt.Sprintf("Outbox replay stopped: %v", err))
+		c.mainWindow.SetStatus(fmt.Sprintf("%d queued operations still waiting for S3: %v", result.Remaining, err))
+	} else if result.Failed > 0 {
+		c.mainWindow.SetStatus(fmt.Sprintf("%d queued operations failed: %s", result.Failed, result.Failures[0]))
+	} else if result.Replayed > 0 {
+		c.mainWindow.SetStatus(fmt.Sprintf("Sent %d queued operations", result.Replayed))
+	}
+}
+
 // IsOfflineMode returns true if the application is in offline mode
 func (c *Controller) IsOfflineMode() bool {
 	return c.syncManager.IsOfflineMode()
@@ -507,6 +1852,11 @@ func isNetworkError(err error) bool {
 		return false
 	}
 	
+	// A virus scanner that can't be reached doesn't mean S3 can't be
+	if models.IsScanFailure(err) {
+		return false
+	}
+	
 	errStr := err.Error()
 	networkKeywords := []string{
 		"timeout",
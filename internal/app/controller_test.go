@@ -1,14 +1,22 @@
 package app
 
 import (
+	"context"
+	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"file-sharing-app/internal/aws"
 	"file-sharing-app/internal/manager"
 	"file-sharing-app/internal/models"
 	"file-sharing-app/internal/storage"
 
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -26,24 +34,71 @@ func createTempDatabase(t *testing.T) storage.Database {
 
 // MockMainWindow is a minimal mock for testing controller logic
 type MockMainWindow struct {
-	OnUploadFile           func(filePath string, expiration time.Duration) error
-	OnShareFile            func(fileID string, recipients []string, message string) error
+	OnUploadFile           func(filePath string, expiration time.Duration, opts models.UploadOptions) error
+	OnChangeBandwidthLimit func(kbps int) error
+	OnChangeUploadBandwidthLimit func(fileID string, kbps int) error
+	OnUploadClipboard      func(text string) error
+	OnShareFile            func(fileID string, recipients []string, message string, opts models.ShareOptions) error
 	OnDeleteFile           func(fileID string) error
+	OnRetryUpload          func(fileID string) error
 	OnRefreshFiles         func() ([]models.FileMetadata, error)
+	OnFilterFiles          func(query models.FileQuery) ([]models.FileMetadata, error)
 	OnGeneratePresignedURL func(fileID string, expiration time.Duration) (string, error)
+	OnCopyShareLink        func(shareID string) (string, error)
+	OnRevokeShare          func(shareID string) error
 	OnSaveSettings         func(settings *models.ApplicationSettings) error
 	OnLoadSettings         func() (*models.ApplicationSettings, error)
+	OnSwitchProfile        func(name string) error
+	OnLoadProfile          func(name string) (*models.Profile, error)
+	OnSaveProfile          func(profile *models.Profile, accessKey, secretKey string) error
+	OnDeleteProfile        func(name string) error
+	OnProvisionBucket      func(req *models.ProvisionRequest) (*models.ProvisionResult, error)
+	OnCheckDrift           func(req *models.ProvisionRequest) (*models.StackDriftReport, error)
+	OnCheckBucketHealth    func() (*models.HealthReport, error)
+	OnDownloadFile         func(fileID string, destPath string) error
+	OnLoadNotifications    func() ([]*models.Notification, error)
+	OnClearNotifications   func() error
 	
 	// Track UI updates for testing
 	LastStatus      string
 	ActionsEnabled  bool
 	LastFiles       []models.FileMetadata
+	ProfileNames    []string
+	ActiveProfile   string
+	LastShareEmail  *models.ShareEmail
+	LastSynced      time.Time
+	NextSync        time.Time
+	Notifications   []models.Notification
+	MinimizeToTray  bool
+	RecentShares    []models.RecentShare
+	BandwidthLimit  int
 }
 
 func (m *MockMainWindow) SetStatus(status string) {
 	m.LastStatus = status
 }
 
+func (m *MockMainWindow) SetSyncSchedule(lastSynced, nextSync time.Time) {
+	m.LastSynced = lastSynced
+	m.NextSync = nextSync
+}
+
+func (m *MockMainWindow) SendNotification(title, content string) {
+	m.Notifications = append(m.Notifications, models.Notification{Title: title, Content: content})
+}
+
+func (m *MockMainWindow) SetMinimizeToTray(enabled bool) {
+	m.MinimizeToTray = enabled
+}
+
+func (m *MockMainWindow) SetRecentShares(shares []models.RecentShare) {
+	m.RecentShares = shares
+}
+
+func (m *MockMainWindow) ShowBandwidthLimit(kbps int) {
+	m.BandwidthLimit = kbps
+}
+
 func (m *MockMainWindow) EnableActions(enabled bool) {
 	m.ActionsEnabled = enabled
 }
@@ -53,11 +108,23 @@ func (m *MockMainWindow) UpdateFiles(files []models.FileMetadata) {
 }
 
 // Callback setters for interface compliance
-func (m *MockMainWindow) SetOnUploadFile(callback func(filePath string, expiration time.Duration) error) {
+func (m *MockMainWindow) SetOnUploadFile(callback func(filePath string, expiration time.Duration, opts models.UploadOptions) error) {
 	m.OnUploadFile = callback
 }
 
-func (m *MockMainWindow) SetOnShareFile(callback func(fileID string, recipients []string, message string) error) {
+func (m *MockMainWindow) SetOnChangeBandwidthLimit(callback func(kbps int) error) {
+	m.OnChangeBandwidthLimit = callback
+}
+
+func (m *MockMainWindow) SetOnChangeUploadBandwidthLimit(callback func(fileID string, kbps int) error) {
+	m.OnChangeUploadBandwidthLimit = callback
+}
+
+func (m *MockMainWindow) SetOnUploadClipboard(callback func(text string) error) {
+	m.OnUploadClipboard = callback
+}
+
+func (m *MockMainWindow) SetOnShareFile(callback func(fileID string, recipients []string, message string, opts models.ShareOptions) error) {
 	m.OnShareFile = callback
 }
 
@@ -65,14 +132,30 @@ func (m *MockMainWindow) SetOnDeleteFile(callback func(fileID string) error) {
 	m.OnDeleteFile = callback
 }
 
+func (m *MockMainWindow) SetOnRetryUpload(callback func(fileID string) error) {
+	m.OnRetryUpload = callback
+}
+
 func (m *MockMainWindow) SetOnRefreshFiles(callback func() ([]models.FileMetadata, error)) {
 	m.OnRefreshFiles = callback
 }
 
+func (m *MockMainWindow) SetOnFilterFiles(callback func(query models.FileQuery) ([]models.FileMetadata, error)) {
+	m.OnFilterFiles = callback
+}
+
 func (m *MockMainWindow) SetOnGeneratePresignedURL(callback func(fileID string, expiration time.Duration) (string, error)) {
 	m.OnGeneratePresignedURL = callback
 }
 
+func (m *MockMainWindow) SetOnCopyShareLink(callback func(shareID string) (string, error)) {
+	m.OnCopyShareLink = callback
+}
+
+func (m *MockMainWindow) SetOnRevokeShare(callback func(shareID string) error) {
+	m.OnRevokeShare = callback
+}
+
 func (m *MockMainWindow) SetOnSaveSettings(callback func(settings *models.ApplicationSettings) error) {
 	m.OnSaveSettings = callback
 }
@@ -81,6 +164,55 @@ func (m *MockMainWindow) SetOnLoadSettings(callback func() (*models.ApplicationS
 	m.OnLoadSettings = callback
 }
 
+func (m *MockMainWindow) SetOnLoadNotifications(callback func() ([]*models.Notification, error)) {
+	m.OnLoadNotifications = callback
+}
+
+func (m *MockMainWindow) SetOnClearNotifications(callback func() error) {
+	m.OnClearNotifications = callback
+}
+
+func (m *MockMainWindow) SetProfiles(names []string, active string) {
+	m.ProfileNames = names
+	m.ActiveProfile = active
+}
+
+func (m *MockMainWindow) SetOnSwitchProfile(callback func(name string) error) {
+	m.OnSwitchProfile = callback
+}
+
+func (m *MockMainWindow) SetOnLoadProfile(callback func(name string) (*models.Profile, error)) {
+	m.OnLoadProfile = callback
+}
+
+func (m *MockMainWindow) SetOnSaveProfile(callback func(profile *models.Profile, accessKey, secretKey string) error) {
+	m.OnSaveProfile = callback
+}
+
+func (m *MockMainWindow) SetOnDeleteProfile(callback func(name string) error) {
+	m.OnDeleteProfile = callback
+}
+
+func (m *MockMainWindow) SetOnProvisionBucket(callback func(req *models.ProvisionRequest) (*models.ProvisionResult, error)) {
+	m.OnProvisionBucket = callback
+}
+
+func (m *MockMainWindow) SetOnCheckDrift(callback func(req *models.ProvisionRequest) (*models.StackDriftReport, error)) {
+	m.OnCheckDrift = callback
+}
+
+func (m *MockMainWindow) SetOnCheckBucketHealth(callback func() (*models.HealthReport, error)) {
+	m.OnCheckBucketHealth = callback
+}
+
+func (m *MockMainWindow) ShowShareEmail(email *models.ShareEmail) {
+	m.LastShareEmail = email
+}
+
+func (m *MockMainWindow) SetOnDownloadFile(callback func(fileID string, destPath string) error) {
+	m.OnDownloadFile = callback
+}
+
 func TestController_Creation(t *testing.T) {
 	// Create test database
 	db := createTempDatabase(t)
@@ -154,6 +286,73 @@ func TestController_RefreshFiles(t *testing.T) {
 	controller.Stop()
 }
 
+// blockingWindow holds its first file list update until released
+type blockingWindow struct {
+	*MockMainWindow
+	held     atomic.Bool
+	updating chan struct{}
+	release  chan struct{}
+}
+
+func (w *blockingWindow) UpdateFiles(files []models.FileMetadata) {
+	if w.held.CompareAndSwap(false, true) {
+		close(w.updating)
+		<-w.release
+	}
+	w.MockMainWindow.UpdateFiles(files)
+}
+
+func TestController_RefreshFiles_ProfileSwitched(t *testing.T) {
+	db := createTempDatabase(t)
+
+	fileManager := manager.NewFileManagerWithoutS3(db)
+	shareManager := manager.NewShareManager(db, nil)
+	expirationManager := manager.NewExpirationManager(db)
+	settingsManager := manager.NewSettingsManager(db)
+	syncManager := manager.NewSyncManagerWithoutS3(db)
+
+	mockWindow := &blockingWindow{MockMainWindow: &MockMainWindow{}, updating: make(chan struct{}), release: make(chan struct{})}
+	
+	controller := NewController(fileManager, shareManager, expirationManager, settingsManager, syncManager, mockWindow)
+	defer controller.Stop()
+
+	for _, profile := range []string{"alpha", "beta"} {
+		require.NoError(t, fileManager.SaveFile(&models.FileMetadata{
+			ID:             profile + "-file",
+			FileName:       profile + ".txt",
+			FilePath:       "/tmp/" + profile + ".txt",
+			FileSize:       1024,
+			UploadDate:     time.Now(),
+			ExpirationDate: time.Now().Add(24 * time.Hour),
+			S3Key:          "uploads/" + profile + ".txt",
+			Status:         models.StatusActive,
+			Profile:        profile,
+		}))
+	}
+
+	// A refresh loads alpha's files, then the profile is switched and refreshed again before the UI shows them
+	fileManager.SetProfile("alpha", nil)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, controller.refreshFiles())
+	}()
+	<-mockWindow.updating
+
+	fileManager.SetProfile("beta", nil)
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, controller.refreshFiles())
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(mockWindow.release)
+	wg.Wait()
+
+	require.Len(t, mockWindow.LastFiles, 1)
+	assert.Equal(t, "beta-file", mockWindow.LastFiles[0].ID)
+}
+
 func TestController_HandleUploadFile_WithoutS3(t *testing.T) {
 	// Create test database
 	db := createTempDatabase(t)
@@ -172,7 +371,7 @@ func TestController_HandleUploadFile_WithoutS3(t *testing.T) {
 	controller := NewController(fileManager, shareManager, expirationManager, settingsManager, syncManager, mockWindow)
 
 	// Test upload file (should fail in offline mode)
-	err := controller.handleUploadFile("/nonexistent/file.txt", 24*time.Hour)
+	err := controller.handleUploadFile("/nonexistent/file.txt", 24*time.Hour, models.UploadOptions{})
 	assert.Error(t, err) // Should fail immediately in offline mode
 	assert.Contains(t, err.Error(), "offline mode")
 
@@ -279,6 +478,158 @@ func TestController_BackgroundExpirationChecker(t *testing.T) {
 	controller.Stop()
 }
 
+func TestController_Notifications(t *testing.T) {
+	db := createTempDatabase(t)
+
+	fileManager := manager.NewFileManagerWithoutS3(db)
+	shareManager := manager.NewShareManager(db, nil)
+	expirationManager := manager.NewExpirationManager(db)
+	settingsManager := manager.NewSettingsManager(db)
+	syncManager := manager.NewSyncManagerWithoutS3(db)
+	mockWindow := &MockMainWindow{}
+
+	controller := NewController(fileManager, shareManager, expirationManager, settingsManager, syncManager, mockWindow)
+	controller.EnableNotifications(manager.NewNotificationManager(db, settingsManager))
+
+	files := []*models.FileMetadata{
+		{ID: "expired", FileName: "expired.txt", ExpirationDate: time.Now().Add(-time.Hour)},
+		{ID: "expiring", FileName: "expiring.txt", ExpirationDate: time.Now().Add(3 * time.Hour)},
+		{ID: "later", FileName: "later.txt", ExpirationDate: time.Now().Add(72 * time.Hour)},
+	}
+	for _, file := range files {
+		file.FilePath = "/tmp/" + file.FileName
+		file.UploadDate = time.Now().Add(-2 * time.Hour)
+		file.S3Key = "uploads/" + file.FileName
+		file.Status = models.StatusActive
+		require.NoError(t, fileManager.SaveFile(file))
+	}
+
+	require.NoError(t, controller.checkAndCleanupExpiredFiles())
+
+	require.Len(t, mockWindow.Notifications, 2)
+	assert.Equal(t, "File expired", mockWindow.Notifications[0].Title)
+	assert.Contains(t, mockWindow.Notifications[0].Content, "expired.txt")
+	assert.Equal(t, "File expiring soon", mockWindow.Notifications[1].Title)
+	assert.Equal(t, "expiring.txt expires in 3 hours", mockWindow.Notifications[1].Content)
+
+	// Later checks don't repeat the same notifications
+	require.NoError(t, controller.checkAndCleanupExpiredFiles())
+	assert.Len(t, mockWindow.Notifications, 2)
+
+	controller.notifyMissingFiles(&manager.SyncResult{MissingFiles: 2})
+	require.Len(t, mockWindow.Notifications, 3)
+	assert.Equal(t, "2 files are no longer in S3 and were marked as deleted", mockWindow.Notifications[2].Content)
+
+	// Turned off events are neither shown nor kept
+	settings, err := settingsManager.LoadSettings()
+	require.NoError(t, err)
+	settings.S3Bucket = "test-bucket"
+	settings.SetNotificationEnabled(models.NotifyMissingFiles, false)
+	require.NoError(t, settingsManager.SaveSettings(settings))
+
+	controller.notifyMissingFiles(&manager.SyncResult{MissingFiles: 1})
+	assert.Len(t, mockWindow.Notifications, 3)
+
+	require.NotNil(t, mockWindow.OnLoadNotifications)
+	history, err := mockWindow.OnLoadNotifications()
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, models.NotifyMissingFiles, history[0].Event)
+
+	require.NoError(t, mockWindow.OnClearNotifications())
+	history, err = mockWindow.OnLoadNotifications()
+	require.NoError(t, err)
+	assert.Empty(t, history)
+}
+
+func TestController_NotificationsDisabled(t *testing.T) {
+	db := createTempDatabase(t)
+	fileManager := manager.NewFileManagerWithoutS3(db)
+	settingsManager := manager.NewSettingsManager(db)
+	mockWindow := &MockMainWindow{}
+
+	controller := NewController(fileManager, manager.NewShareManager(db, nil), manager.NewExpirationManager(db),
+		settingsManager, manager.NewSyncManagerWithoutS3(db), mockWindow)
+
+	// Without a notification manager nothing is sent and the history is empty
+	controller.notifyMissingFiles(&manager.SyncResult{MissingFiles: 1})
+	assert.Empty(t, mockWindow.Notifications)
+
+	history, err := mockWindow.OnLoadNotifications()
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+	assert.NoError(t, mockWindow.OnClearNotifications())
+}
+
+func TestFormatTimeLeft(t *testing.T) {
+	tests := []struct {
+		left     time.Duration
+		expected string
+	}{
+		{30 * time.Second, "1 minute"},
+		{25 * time.Minute, "25 minutes"},
+		{time.Hour, "1 hour"},
+		{23*time.Hour + 40*time.Minute, "24 hours"},
+		{72 * time.Hour, "3 days"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, formatTimeLeft(tt.left), tt.left.String())
+	}
+}
+
+func TestController_BackgroundJobs(t *testing.T) {
+	db := createTempDatabase(t)
+
+	fileManager := manager.NewFileManagerWithoutS3(db)
+	shareManager := manager.NewShareManager(db, nil)
+	expirationManager := manager.NewExpirationManager(db)
+	settingsManager := manager.NewSettingsManager(db)
+	syncManager := manager.NewSyncManagerWithoutS3(db)
+
+	settings := models.DefaultApplicationSettings()
+	settings.S3Bucket = "test-bucket"
+	settings.SyncIntervalMinutes = 30
+	settings.URLRenewalIntervalMinutes = 10
+	require.NoError(t, settingsManager.SaveSettings(settings))
+
+	mockWindow := &MockMainWindow{}
+	
+	controller := NewController(fileManager, shareManager, expirationManager, settingsManager, syncManager, mockWindow)
+	require.NoError(t, controller.Start())
+	defer controller.Stop()
+
+	// Every background job is owned by the scheduler, at the saved intervals
+	statuses := controller.scheduler.Statuses()
+	require.Len(t, statuses, 4)
+	
+	intervals := map[string]time.Duration{}
+	for _, status := range statuses {
+		intervals[status.Name] = status.Interval
+	}
+	assert.Equal(t, 5*time.Minute, intervals[jobExpirationCheck])
+	assert.Equal(t, 30*time.Minute, intervals[jobSync])
+	assert.Equal(t, 24*time.Hour, intervals[jobMetadataCleanup])
+	assert.Equal(t, 10*time.Minute, intervals[jobURLRenewal])
+
+	// The UI shows when the next automatic sync runs
+	assert.True(t, mockWindow.LastSynced.IsZero())
+	assert.WithinDuration(t, time.Now().Add(30*time.Minute), mockWindow.NextSync, time.Minute)
+
+	// Turning AutoRefresh off stops automatic syncs without a restart
+	settings.AutoRefresh = false
+	require.NoError(t, controller.handleSaveSettings(settings))
+
+	status, err := controller.scheduler.Status(jobSync)
+	require.NoError(t, err)
+	assert.False(t, status.Enabled)
+	assert.True(t, mockWindow.NextSync.IsZero())
+
+	// Scheduled syncs and link renewals wait while offline
+	assert.NoError(t, controller.runScheduledSync(context.Background()))
+	assert.NoError(t, controller.runURLRenewal(context.Background()))
+}
+
 func TestController_GeneratePresignedURL_WithoutS3(t *testing.T) {
 	// Create test database
 	db := createTempDatabase(t)
@@ -306,6 +657,33 @@ func TestController_GeneratePresignedURL_WithoutS3(t *testing.T) {
 	controller.Stop()
 }
 
+func TestController_DownloadFile(t *testing.T) {
+	db := createTempDatabase(t)
+
+	fileManager := manager.NewFileManagerWithoutS3(db)
+	shareManager := manager.NewShareManager(db, nil)
+	expirationManager := manager.NewExpirationManager(db)
+	settingsManager := manager.NewSettingsManager(db)
+	syncManager := manager.NewSyncManagerWithoutS3(db)
+
+	mockWindow := &MockMainWindow{}
+	controller := NewController(fileManager, shareManager, expirationManager, settingsManager, syncManager, mockWindow)
+	defer controller.Stop()
+
+	require.NotNil(t, mockWindow.OnDownloadFile)
+
+	// Downloads need S3, so they are refused offline
+	syncManager.SetOfflineMode(true)
+	err := mockWindow.OnDownloadFile("file-id", filepath.Join(t.TempDir(), "out.txt"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "offline mode")
+
+	syncManager.SetOfflineMode(false)
+	err = mockWindow.OnDownloadFile("file-id", filepath.Join(t.TempDir(), "out.txt"))
+	assert.Error(t, err)
+	assert.Contains(t, mockWindow.LastStatus, "Download failed")
+}
+
 func TestController_UIIntegration(t *testing.T) {
 	// Create test database
 	db := createTempDatabase(t)
@@ -356,12 +734,12 @@ func TestController_OfflineMode(t *testing.T) {
 	assert.True(t, controller.IsOfflineMode())
 
 	// Test that upload fails in offline mode
-	err := controller.handleUploadFile("/tmp/test.txt", 24*time.Hour)
+	err := controller.handleUploadFile("/tmp/test.txt", 24*time.Hour, models.UploadOptions{})
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "offline mode")
 
 	// Test that sharing fails in offline mode
-	err = controller.handleShareFile("test-file", []string{"test@example.com"}, "test message")
+	err = controller.handleShareFile("test-file", []string{"test@example.com"}, "test message", models.ShareOptions{})
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "offline mode")
 
@@ -369,6 +747,374 @@ func TestController_OfflineMode(t *testing.T) {
 	controller.Stop()
 }
 
+func TestController_OfflineOutbox(t *testing.T) {
+	db := createTempDatabase(t)
+
+	fileManager := manager.NewFileManagerWithoutS3(db)
+	shareManager := manager.NewShareManager(db, nil)
+	expirationManager := manager.NewExpirationManager(db)
+	settingsManager := manager.NewSettingsManager(db)
+	syncManager := manager.NewSyncManagerWithoutS3(db)
+
+	mockWindow := &MockMainWindow{}
+	
+	controller := NewController(fileManager, shareManager, expirationManager, settingsManager, syncManager, mockWindow)
+	controller.EnableOutbox(manager.NewOutboxManager(db, fileManager, shareManager, expirationManager))
+	defer controller.Stop()
+
+	filePath := filepath.Join(t.TempDir(), "report.txt")
+	require.NoError(t, os.WriteFile(filePath, []byte("report"), 0600))
+
+	// Uploads are queued rather than refused while offline
+	err := controller.handleUploadFile(filePath, 24*time.Hour, models.UploadOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "Offline - upload queued until S3 is reachable", mockWindow.LastStatus)
+
+	require.Len(t, mockWindow.LastFiles, 1)
+	queued := mockWindow.LastFiles[0]
+	assert.Equal(t, models.StatusPending, queued.Status)
+	assert.Equal(t, models.OutboxUpload, queued.PendingOperation)
+
+	err = controller.handleShareFile(queued.ID, []string{"alice@example.com"}, "", models.ShareOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "Offline - share queued until S3 is reachable", mockWindow.LastStatus)
+	assert.Equal(t, models.OutboxShare, mockWindow.LastFiles[0].PendingOperation)
+
+	// Deleting a file that was never uploaded just drops it
+	err = controller.handleDeleteFile(queued.ID)
+	require.NoError(t, err)
+	assert.Empty(t, mockWindow.LastFiles)
+}
+
+func TestController_ScheduledUpload(t *testing.T) {
+	db := createTempDatabase(t)
+
+	fileManager := manager.NewFileManagerWithoutS3(db)
+	shareManager := manager.NewShareManager(db, nil)
+	expirationManager := manager.NewExpirationManager(db)
+	settingsManager := manager.NewSettingsManager(db)
+	syncManager := manager.NewSyncManagerWithoutS3(db)
+
+	mockWindow := &MockMainWindow{}
+	
+	controller := NewController(fileManager, shareManager, expirationManager, settingsManager, syncManager, mockWindow)
+	controller.EnableOutbox(manager.NewOutboxManager(db, fileManager, shareManager, expirationManager))
+	defer controller.Stop()
+
+	// The upload queue brings a job that starts scheduled uploads when they are due
+	status, err := controller.scheduler.Status(jobScheduledUpload)
+	require.NoError(t, err)
+	assert.Equal(t, scheduledUploadInterval, status.Interval)
+
+	filePath := filepath.Join(t.TempDir(), "backup.txt")
+	require.NoError(t, os.WriteFile(filePath, []byte("backup"), 0600))
+
+	err = controller.handleUploadFile(filePath, 24*time.Hour, models.UploadOptions{BandwidthLimit: models.NewBandwidthLimit(1)})
+	assert.Error(t, err)
+	assert.Empty(t, mockWindow.LastFiles)
+
+	startAt := time.Now().Add(8 * time.Hour)
+	err = controller.handleUploadFile(filePath, 24*time.Hour, models.UploadOptions{
+		BandwidthLimit: models.NewBandwidthLimit(512),
+		StartAt:        startAt,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Upload of backup.txt scheduled for "+startAt.Format("Jan 2 15:04"), mockWindow.LastStatus)
+
+	require.Len(t, mockWindow.LastFiles, 1)
+	scheduled := mockWindow.LastFiles[0]
+	assert.Equal(t, models.StatusPending, scheduled.Status)
+	assert.Equal(t, models.OutboxUpload, scheduled.PendingOperation)
+	assert.True(t, startAt.Equal(scheduled.ScheduledAt))
+
+	// While offline scheduled uploads wait for the replay once S3 is reachable
+	assert.NoError(t, controller.runScheduledUploads(context.Background()))
+	assert.Len(t, mockWindow.LastFiles, 1)
+
+	// Deleting the file cancels the upload
+	require.NoError(t, controller.handleDeleteFile(scheduled.ID))
+	assert.Empty(t, mockWindow.LastFiles)
+}
+
+func TestController_ChangeBandwidthLimit(t *testing.T) {
+	db := createTempDatabase(t)
+
+	fileManager := manager.NewFileManagerWithoutS3(db)
+	shareManager := manager.NewShareManager(db, nil)
+	expirationManager := manager.NewExpirationManager(db)
+	settingsManager := manager.NewSettingsManager(db)
+	syncManager := manager.NewSyncManagerWithoutS3(db)
+
+	settings := models.DefaultApplicationSettings()
+	settings.S3Bucket = "test-bucket"
+	require.NoError(t, settingsManager.SaveSettings(settings))
+
+	mockWindow := &MockMainWindow{}
+	
+	controller := NewController(fileManager, shareManager, expirationManager, settingsManager, syncManager, mockWindow)
+	defer controller.Stop()
+
+	require.NoError(t, mockWindow.OnChangeBandwidthLimit(2048))
+	assert.Equal(t, 2048, mockWindow.BandwidthLimit)
+	assert.Equal(t, "Upload speed limited to 2 MB/s", mockWindow.LastStatus)
+
+	saved, err := settingsManager.LoadSettings()
+	require.NoError(t, err)
+	assert.Equal(t, 2048, saved.UploadBandwidthLimitKBps)
+
+	assert.Error(t, mockWindow.OnChangeBandwidthLimit(1))
+
+	require.NoError(t, mockWindow.OnChangeBandwidthLimit(0))
+	assert.Equal(t, 0, mockWindow.BandwidthLimit)
+	assert.Equal(t, "Upload speed unlimited", mockWindow.LastStatus)
+}
+
+func TestController_ChangeUploadBandwidthLimit(t *testing.T) {
+	db := createTempDatabase(t)
+
+	fileManager := manager.NewFileManagerWithoutS3(db)
+	shareManager := manager.NewShareManager(db, nil)
+	expirationManager := manager.NewExpirationManager(db)
+	settingsManager := manager.NewSettingsManager(db)
+	syncManager := manager.NewSyncManagerWithoutS3(db)
+
+	mockWindow := &MockMainWindow{}
+	
+	controller := NewController(fileManager, shareManager, expirationManager, settingsManager, syncManager, mockWindow)
+	defer controller.Stop()
+
+	file, err := fileManager.CreateFileRecord("video.mp4", "/tmp/video.mp4", 1024, "files/video.mp4", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	// Only running uploads can be slowed down
+	assert.Error(t, mockWindow.OnChangeUploadBandwidthLimit(file.ID, 512))
+
+	limit := models.NewBandwidthLimit(0)
+	controller.trackUploadLimit(file.FilePath, limit)
+
+	require.NoError(t, mockWindow.OnChangeUploadBandwidthLimit(file.ID, 512))
+	assert.Equal(t, 512, limit.KBps())
+	assert.Equal(t, "Upload of video.mp4 limited to 512 KB/s", mockWindow.LastStatus)
+
+	assert.Error(t, mockWindow.OnChangeUploadBandwidthLimit(file.ID, 1))
+	assert.Equal(t, 512, limit.KBps())
+
+	// A later upload of the same file keeps its own limit when an earlier one finishes
+	later := models.NewBandwidthLimit(0)
+	controller.trackUploadLimit(file.FilePath, later)
+	controller.untrackUploadLimit(file.FilePath, limit)
+	require.NoError(t, mockWindow.OnChangeUploadBandwidthLimit(file.ID, 0))
+	assert.Equal(t, "Upload of video.mp4 unlimited", mockWindow.LastStatus)
+
+	controller.untrackUploadLimit(file.FilePath, later)
+	assert.Error(t, mockWindow.OnChangeUploadBandwidthLimit(file.ID, 512))
+}
+
+func TestController_UploadClipboard(t *testing.T) {
+	db := createTempDatabase(t)
+
+	fileManager := manager.NewFileManagerWithoutS3(db)
+	shareManager := manager.NewShareManager(db, nil)
+	expirationManager := manager.NewExpirationManager(db)
+	settingsManager := manager.NewSettingsManager(db)
+	syncManager := manager.NewSyncManagerWithoutS3(db)
+
+	mockWindow := &MockMainWindow{}
+
+	controller := NewController(fileManager, shareManager, expirationManager, settingsManager, syncManager, mockWindow)
+	controller.EnableOutbox(manager.NewOutboxManager(db, fileManager, shareManager, expirationManager))
+	controller.clipboardDir = t.TempDir()
+	defer controller.Stop()
+
+	err := mockWindow.OnUploadClipboard("  \n")
+	assert.Error(t, err)
+	assert.Equal(t, "Upload failed: Clipboard is empty", mockWindow.LastStatus)
+
+	// The text is saved as a file and uploaded with the default expiration
+	require.NoError(t, mockWindow.OnUploadClipboard("meeting notes"))
+	require.Len(t, mockWindow.LastFiles, 1)
+
+	uploaded := mockWindow.LastFiles[0]
+	assert.True(t, strings.HasPrefix(uploaded.FileName, "clipboard-"), uploaded.FileName)
+	assert.Equal(t, ".txt", filepath.Ext(uploaded.FileName))
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), uploaded.ExpirationDate, time.Minute)
+
+	content, err := os.ReadFile(uploaded.FilePath)
+	require.NoError(t, err)
+	assert.Equal(t, "meeting notes", string(content))
+
+	// The queued upload keeps the file until it is replayed, here failing as there is no S3 service
+	controller.replayOutbox()
+	file, err := fileManager.GetFile(uploaded.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusError, file.Status)
+	assert.NoFileExists(t, uploaded.FilePath)
+}
+
+// failingUploadS3Service is a reachable S3 service that refuses every upload
+type failingUploadS3Service struct {
+	interruptedS3Service
+}
+
+func (s *failingUploadS3Service) UploadFile(ctx context.Context, key string, filePath string, metadata map[string]string, progressCh chan<- aws.UploadProgress) (*aws.UploadResult, error) {
+	return nil, fmt.Errorf("AccessDenied: upload refused")
+}
+
+func TestController_UploadClipboard_RemovesFile(t *testing.T) {
+	for name, s3Service := range map[string]aws.S3Service{
+		"uploaded": &interruptedS3Service{objects: map[string]bool{}},
+		"failed":   &failingUploadS3Service{interruptedS3Service{objects: map[string]bool{}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			db := createTempDatabase(t)
+
+			fileManager := manager.NewFileManager(db, s3Service)
+			shareManager := manager.NewShareManager(db, nil)
+			expirationManager := manager.NewExpirationManager(db)
+			settingsManager := manager.NewSettingsManager(db)
+			syncManager := manager.NewSyncManager(db, s3Service)
+
+			mockWindow := &MockMainWindow{}
+
+			controller := NewController(fileManager, shareManager, expirationManager, settingsManager, syncManager, mockWindow)
+			controller.clipboardDir = t.TempDir()
+			defer controller.Stop()
+
+			require.NoError(t, mockWindow.OnUploadClipboard("meeting notes"))
+
+			// The file is removed once the upload has finished, whether it succeeded or not
+			assert.Eventually(t, func() bool {
+				entries, err := os.ReadDir(controller.clipboardDir)
+				return err == nil && len(entries) == 0
+			}, 5*time.Second, 10*time.Millisecond)
+		})
+	}
+}
+
+func TestController_TraySettings(t *testing.T) {
+	db := createTempDatabase(t)
+
+	fileManager := manager.NewFileManagerWithoutS3(db)
+	shareManager := manager.NewShareManager(db, nil)
+	expirationManager := manager.NewExpirationManager(db)
+	settingsManager := manager.NewSettingsManager(db)
+	syncManager := manager.NewSyncManagerWithoutS3(db)
+
+	mockWindow := &MockMainWindow{}
+
+	controller := NewController(fileManager, shareManager, expirationManager, settingsManager, syncManager, mockWindow)
+	defer controller.Stop()
+
+	file := &models.FileMetadata{
+		ID:             "shared-file",
+		FileName:       "report.pdf",
+		FilePath:       "/tmp/report.pdf",
+		FileSize:       1024,
+		UploadDate:     time.Now(),
+		ExpirationDate: time.Now().Add(24 * time.Hour),
+		S3Key:          "uploads/report.pdf",
+		Status:         models.StatusActive,
+	}
+	require.NoError(t, fileManager.SaveFile(file))
+	require.NoError(t, db.SaveShare(&storage.ShareRecord{
+		ID:            "share-1",
+		FileID:        file.ID,
+		Recipients:    []string{"alice@example.com"},
+		SharedDate:    time.Now(),
+		SignedAt:      time.Now(),
+		URLExpiration: time.Now().Add(time.Hour),
+	}))
+
+	require.NoError(t, controller.Start())
+	assert.True(t, mockWindow.MinimizeToTray)
+
+	// The tray menu offers the links of recent shares
+	require.Len(t, mockWindow.RecentShares, 1)
+	assert.Equal(t, "report.pdf", mockWindow.RecentShares[0].FileName)
+	assert.Equal(t, "share-1", mockWindow.RecentShares[0].ShareID)
+
+	// Links are signed when copied, which needs the bucket's credentials
+	require.NotNil(t, mockWindow.OnCopyShareLink)
+	_, err := mockWindow.OnCopyShareLink("share-1")
+	assert.Error(t, err)
+	
+	// Only short links can be revoked
+	require.NotNil(t, mockWindow.OnRevokeShare)
+	err = mockWindow.OnRevokeShare("share-1")
+	assert.Error(t, err)
+	assert.Len(t, mockWindow.RecentShares, 1)
+
+	settings := models.DefaultApplicationSettings()
+	settings.S3Bucket = "test-bucket"
+	settings.MinimizeToTray = false
+	require.NoError(t, controller.handleSaveSettings(settings))
+	assert.False(t, mockWindow.MinimizeToTray)
+}
+
+func TestController_FilterFiles(t *testing.T) {
+	db := createTempDatabase(t)
+
+	fileManager := manager.NewFileManagerWithoutS3(db)
+	shareManager := manager.NewShareManager(db, nil)
+	expirationManager := manager.NewExpirationManager(db)
+	settingsManager := manager.NewSettingsManager(db)
+	syncManager := manager.NewSyncManagerWithoutS3(db)
+
+	mockWindow := &MockMainWindow{}
+
+	controller := NewController(fileManager, shareManager, expirationManager, settingsManager, syncManager, mockWindow)
+	defer controller.Stop()
+
+	now := time.Now()
+	for i, name := range []string{"report.pdf", "photo.jpg", "report-draft.pdf"} {
+		status := models.StatusActive
+		if i == 2 {
+			status = models.StatusExpired
+		}
+		require.NoError(t, fileManager.SaveFile(&models.FileMetadata{
+			ID:             fmt.Sprintf("file-%d", i),
+			FileName:       name,
+			FilePath:       "/tmp/" + name,
+			FileSize:       int64(1024 * (i + 1)),
+			UploadDate:     now.Add(time.Duration(i) * time.Minute),
+			ExpirationDate: now.Add(24 * time.Hour),
+			S3Key:          "uploads/" + name,
+			Status:         status,
+		}))
+	}
+
+	require.NoError(t, controller.Start())
+	require.NotNil(t, mockWindow.OnFilterFiles)
+
+	files, err := mockWindow.OnFilterFiles(models.FileQuery{
+		Search:    "report",
+		Statuses:  []models.FileStatus{models.StatusActive},
+		SortBy:    models.SortByFileName,
+		Ascending: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "report.pdf", files[0].FileName)
+
+	// Refreshes keep the query
+	require.NoError(t, controller.refreshFiles())
+	require.Len(t, mockWindow.LastFiles, 1)
+	assert.Equal(t, "file-0", mockWindow.LastFiles[0].ID)
+
+	// An invalid query is refused and the previous one stays
+	_, err = mockWindow.OnFilterFiles(models.FileQuery{MinSize: 2048, MaxSize: 1024})
+	assert.Error(t, err)
+	files, err = mockWindow.OnRefreshFiles()
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	// Clearing the query shows every file, newest first
+	files, err = mockWindow.OnFilterFiles(models.FileQuery{})
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	assert.Equal(t, "file-2", files[0].ID)
+}
+
 func TestController_SyncWithS3(t *testing.T) {
 	// Create test database
 	db := createTempDatabase(t)
@@ -462,4 +1208,487 @@ func TestController_GetLastSyncTime(t *testing.T) {
 
 	// Cleanup
 	controller.Stop()
-}
\ No newline at end of file
+}
+// fakeServiceFactory records credential operations and never connects to AWS
+// reachableS3Service is an S3 service whose connection test always succeeds
+type reachableS3Service struct {
+	aws.S3Service
+}
+
+func (s *reachableS3Service) TestConnection(ctx context.Context) error {
+	return nil
+}
+
+func TestController_ConnectivityRestored(t *testing.T) {
+	db := createTempDatabase(t)
+
+	fileManager := manager.NewFileManagerWithoutS3(db)
+	shareManager := manager.NewShareManager(db, nil)
+	expirationManager := manager.NewExpirationManager(db)
+	settingsManager := manager.NewSettingsManager(db)
+	syncManager := manager.NewSyncManager(db, &reachableS3Service{})
+
+	mockWindow := &MockMainWindow{}
+	
+	controller := NewController(fileManager, shareManager, expirationManager, settingsManager, syncManager, mockWindow)
+	defer controller.Stop()
+
+	// A failed sync left the application offline with actions disabled
+	syncManager.SetOfflineMode(true)
+	mockWindow.EnableActions(false)
+
+	controller.handleConnectivityRestored()
+
+	assert.False(t, controller.IsOfflineMode())
+	assert.True(t, mockWindow.ActionsEnabled)
+	assert.Equal(t, "Sync completed successfully", mockWindow.LastStatus)
+}
+
+// interruptedS3Service is a reachable S3 service holding the objects it has been given or uploaded
+type interruptedS3Service struct {
+	reachableS3Service
+	mutex   sync.Mutex
+	objects map[string]bool
+}
+
+func (s *interruptedS3Service) EncryptionMode() string {
+	return models.EncryptionSSES3
+}
+
+func (s *interruptedS3Service) HeadObject(ctx context.Context, key string) (*s3.HeadObjectOutput, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	
+	if !s.objects[key] {
+		return nil, fmt.Errorf("NotFound: object %s not found", key)
+	}
+	etag := "\"etag\""
+	return &s3.HeadObjectOutput{ETag: &etag}, nil
+}
+
+func (s *interruptedS3Service) UploadFile(ctx context.Context, key string, filePath string, metadata map[string]string, progressCh chan<- aws.UploadProgress) (*aws.UploadResult, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	
+	s.objects[key] = true
+	return &aws.UploadResult{ETag: "etag"}, nil
+}
+
+func TestController_RecoverInterruptedUploads(t *testing.T) {
+	db := createTempDatabase(t)
+
+	s3Service := &interruptedS3Service{objects: map[string]bool{"uploads/finished": true}}
+	fileManager := manager.NewFileManager(db, s3Service)
+	shareManager := manager.NewShareManager(db, nil)
+	expirationManager := manager.NewExpirationManager(db)
+	settingsManager := manager.NewSettingsManager(db)
+	syncManager := manager.NewSyncManager(db, s3Service)
+
+	mockWindow := &MockMainWindow{}
+	
+	controller := NewController(fileManager, shareManager, expirationManager, settingsManager, syncManager, mockWindow)
+	defer controller.Stop()
+
+	// Uploads left behind when the app stopped: one reached S3, the other's file has since moved
+	movedPath := filepath.Join(t.TempDir(), "moved.txt")
+	for _, id := range []string{"finished", "moved"} {
+		require.NoError(t, fileManager.SaveFile(&models.FileMetadata{
+			ID:             id,
+			FileName:       id + ".txt",
+			FilePath:       movedPath,
+			FileSize:       1024,
+			UploadDate:     time.Now(),
+			ExpirationDate: time.Now().Add(24 * time.Hour),
+			S3Key:          "uploads/" + id,
+			Status:         models.StatusUploading,
+		}))
+	}
+
+	_, err := controller.SyncWithS3()
+	require.NoError(t, err)
+
+	finished, err := fileManager.GetFile("finished")
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusActive, finished.Status)
+	assert.Equal(t, "etag", finished.ETag)
+
+	moved, err := fileManager.GetFile("moved")
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusError, moved.Status)
+	assert.Contains(t, mockWindow.LastStatus, "1 interrupted uploads failed and can be retried")
+
+	// Once the file is back, the upload can be retried from the file list
+	require.NoError(t, os.WriteFile(movedPath, []byte("moved content"), 0600))
+	require.NotNil(t, mockWindow.OnRetryUpload)
+	require.NoError(t, mockWindow.OnRetryUpload("moved"))
+
+	assert.Eventually(t, func() bool {
+		file, err := fileManager.GetFile("moved")
+		return err == nil && file.Status == models.StatusActive
+	}, time.Second, 10*time.Millisecond)
+
+	// Retrying is refused while offline
+	syncManager.SetOfflineMode(true)
+	assert.Error(t, controller.handleRetryUpload("moved"))
+}
+
+type fakeServiceFactory struct {
+	storedCredentials  map[string]string
+	clearedCredentials []string
+	provisioner        aws.Provisioner
+	healthChecker      aws.HealthChecker
+	healthProfile      string
+	shareLinkProfiles  []string
+}
+
+func (f *fakeServiceFactory) ShareLinkKey(profileName string) ([]byte, error) {
+	f.shareLinkProfiles = append(f.shareLinkProfiles, profileName)
+	return []byte("0123456789abcdef0123456789abcdef"), nil
+}
+
+func (f *fakeServiceFactory) NewS3Service(profile *models.Profile) (aws.S3Service, error) {
+	return nil, fmt.Errorf("credentials not configured for profile %s", profile.Name)
+}
+
+func (f *fakeServiceFactory) StoreCredentials(profileName, accessKey, secretKey, region string) error {
+	if f.storedCredentials == nil {
+		f.storedCredentials = make(map[string]string)
+	}
+	f.storedCredentials[profileName] = accessKey
+	return nil
+}
+
+func (f *fakeServiceFactory) ClearCredentials(profileName string) error {
+	f.clearedCredentials = append(f.clearedCredentials, profileName)
+	return nil
+}
+
+func (f *fakeServiceFactory) NewProvisioner(req *models.ProvisionRequest) (aws.Provisioner, error) {
+	if f.provisioner == nil {
+		return nil, fmt.Errorf("provisioning not configured")
+	}
+	return f.provisioner, nil
+}
+
+func (f *fakeServiceFactory) NewHealthChecker(profile *models.Profile) (aws.HealthChecker, error) {
+	if f.healthChecker == nil {
+		return nil, fmt.Errorf("credentials not configured for profile %s", profile.Name)
+	}
+	f.healthProfile = profile.Name
+	return f.healthChecker, nil
+}
+
+// fakeHealthChecker returns a canned health report
+type fakeHealthChecker struct {
+	report *models.HealthReport
+}
+
+func (h *fakeHealthChecker) CheckBucketHealth(ctx context.Context) (*models.HealthReport, error) {
+	return h.report, nil
+}
+
+// fakeProvisioner returns canned provisioning and drift results
+type fakeProvisioner struct {
+	result *models.ProvisionResult
+	report *models.StackDriftReport
+	err    error
+}
+
+func (p *fakeProvisioner) Provision(ctx context.Context, req *models.ProvisionRequest, progress func(status string)) (*models.ProvisionResult, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	progress("CREATE_IN_PROGRESS")
+	return p.result, nil
+}
+
+func (p *fakeProvisioner) DetectDrift(ctx context.Context, stackName string) (*models.StackDriftReport, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	return p.report, nil
+}
+
+func TestController_SwitchProfile(t *testing.T) {
+	// Create test database
+	db := createTempDatabase(t)
+
+	// Create managers
+	fileManager := manager.NewFileManagerWithoutS3(db)
+	shareManager := manager.NewShareManager(db, nil)
+	expirationManager := manager.NewExpirationManager(db)
+	settingsManager := manager.NewSettingsManager(db)
+	syncManager := manager.NewSyncManagerWithoutS3(db)
+	profileManager := manager.NewProfileManager(db)
+
+	// Create a second profile with a file of its own
+	clientProfile := models.NewProfile("client-a")
+	clientProfile.S3Bucket = "client-a-bucket"
+	clientProfile.AWSRegion = "eu-west-1"
+	require.NoError(t, profileManager.SaveProfile(clientProfile))
+
+	for _, file := range []*storage.FileMetadata{
+		{ID: "default-file", FileName: "default.txt", FileSize: 1, S3Key: "uploads/default.txt", Status: storage.StatusActive, Profile: storage.DefaultProfile},
+		{ID: "client-file", FileName: "client.txt", FileSize: 1, S3Key: "uploads/client.txt", Status: storage.StatusActive, Profile: "client-a"},
+	} {
+		file.UploadDate = time.Now()
+		file.ExpirationDate = time.Now().Add(24 * time.Hour)
+		require.NoError(t, db.SaveFile(file))
+	}
+
+	// Create mock UI and controller with profiles enabled
+	mockWindow := &MockMainWindow{}
+	factory := &fakeServiceFactory{}
+	controller := NewController(fileManager, shareManager, expirationManager, settingsManager, syncManager, mockWindow)
+	controller.EnableProfiles(profileManager, factory)
+	defer controller.Stop()
+
+	require.NoError(t, controller.Start())
+	assert.ElementsMatch(t, []string{models.DefaultProfileName, "client-a"}, mockWindow.ProfileNames)
+	assert.Equal(t, models.DefaultProfileName, mockWindow.ActiveProfile)
+	require.Len(t, mockWindow.LastFiles, 1)
+	assert.Equal(t, "default-file", mockWindow.LastFiles[0].ID)
+
+	// Switch profiles through the UI callback
+	require.NotNil(t, mockWindow.OnSwitchProfile)
+	require.NoError(t, mockWindow.OnSwitchProfile("client-a"))
+	assert.Equal(t, "client-a", mockWindow.ActiveProfile)
+	require.Len(t, mockWindow.LastFiles, 1)
+	assert.Equal(t, "client-file", mockWindow.LastFiles[0].ID)
+	assert.True(t, syncManager.IsOfflineMode())
+
+	// The settings dialog reflects the active profile
+	settings, err := settingsManager.LoadSettings()
+	require.NoError(t, err)
+	assert.Equal(t, "client-a-bucket", settings.S3Bucket)
+	assert.Equal(t, "eu-west-1", settings.AWSRegion)
+
+	// Unknown profiles are rejected
+	assert.Error(t, controller.SwitchProfile("missing"))
+	assert.Equal(t, "client-a", mockWindow.ActiveProfile)
+}
+
+func TestController_SaveAndDeleteProfile(t *testing.T) {
+	// Create test database
+	db := createTempDatabase(t)
+
+	// Create managers
+	fileManager := manager.NewFileManagerWithoutS3(db)
+	shareManager := manager.NewShareManager(db, nil)
+	expirationManager := manager.NewExpirationManager(db)
+	settingsManager := manager.NewSettingsManager(db)
+	syncManager := manager.NewSyncManagerWithoutS3(db)
+	profileManager := manager.NewProfileManager(db)
+
+	mockWindow := &MockMainWindow{}
+	factory := &fakeServiceFactory{}
+	controller := NewController(fileManager, shareManager, expirationManager, settingsManager, syncManager, mockWindow)
+	controller.EnableProfiles(profileManager, factory)
+	defer controller.Stop()
+
+	// Save a new profile with credentials
+	profile := models.NewProfile("client-b")
+	profile.S3Bucket = "client-b-bucket"
+	require.NotNil(t, mockWindow.OnSaveProfile)
+	require.NoError(t, mockWindow.OnSaveProfile(profile, "AKIATEST", "secret"))
+	assert.Equal(t, "AKIATEST", factory.storedCredentials["client-b"])
+	assert.Contains(t, mockWindow.ProfileNames, "client-b")
+
+	// Saving without keys keeps the stored credentials
+	require.NoError(t, mockWindow.OnSaveProfile(profile, "", ""))
+	assert.Len(t, factory.storedCredentials, 1)
+
+	loaded, err := mockWindow.OnLoadProfile("client-b")
+	require.NoError(t, err)
+	assert.Equal(t, "client-b-bucket", loaded.S3Bucket)
+
+	// Delete the profile and its credentials
+	require.NotNil(t, mockWindow.OnDeleteProfile)
+	require.NoError(t, mockWindow.OnDeleteProfile("client-b"))
+	assert.Equal(t, []string{"client-b"}, factory.clearedCredentials)
+	assert.NotContains(t, mockWindow.ProfileNames, "client-b")
+
+	// The active profile cannot be deleted
+	assert.Error(t, mockWindow.OnDeleteProfile(models.DefaultProfileName))
+}
+
+func TestController_SaveSettings_KeepsShortLinks(t *testing.T) {
+	db := createTempDatabase(t)
+
+	fileManager := manager.NewFileManagerWithoutS3(db)
+	shareManager := manager.NewShareManager(db, nil)
+	expirationManager := manager.NewExpirationManager(db)
+	settingsManager := manager.NewSettingsManager(db)
+	syncManager := manager.NewSyncManagerWithoutS3(db)
+	profileManager := manager.NewProfileManager(db)
+
+	// A provisioned profile serving short links
+	provisioned := models.NewProfile("client-a")
+	provisioned.S3Bucket = "client-a-bucket"
+	provisioned.StackName = "client-a-stack"
+	provisioned.ShareLinkURL = "https://abc123.lambda-url.eu-west-1.on.aws/"
+	require.NoError(t, profileManager.SaveProfile(provisioned))
+
+	mockWindow := &MockMainWindow{}
+	factory := &fakeServiceFactory{}
+	controller := NewController(fileManager, shareManager, expirationManager, settingsManager, syncManager, mockWindow)
+	controller.EnableProfiles(profileManager, factory)
+	defer controller.Stop()
+
+	require.NoError(t, controller.Start())
+	require.NoError(t, controller.SwitchProfile("client-a"))
+
+	// Saving settings that move the profile to another bucket keeps its stack and short links
+	settings, err := settingsManager.LoadSettings()
+	require.NoError(t, err)
+	settings.S3Bucket = "client-a-archive"
+	factory.shareLinkProfiles = nil
+	require.NotNil(t, mockWindow.OnSaveSettings)
+	require.NoError(t, mockWindow.OnSaveSettings(settings))
+
+	profile, err := profileManager.GetProfile("client-a")
+	require.NoError(t, err)
+	assert.Equal(t, "client-a-archive", profile.S3Bucket)
+	assert.Equal(t, "client-a-stack", profile.StackName)
+	assert.Equal(t, "https://abc123.lambda-url.eu-west-1.on.aws/", profile.ShareLinkURL)
+	assert.Equal(t, []string{"client-a"}, factory.shareLinkProfiles)
+}
+
+func TestController_ProvisionBucket(t *testing.T) {
+	// Create test database
+	db := createTempDatabase(t)
+
+	// Create managers
+	fileManager := manager.NewFileManagerWithoutS3(db)
+	shareManager := manager.NewShareManager(db, nil)
+	expirationManager := manager.NewExpirationManager(db)
+	settingsManager := manager.NewSettingsManager(db)
+	syncManager := manager.NewSyncManagerWithoutS3(db)
+	profileManager := manager.NewProfileManager(db)
+
+	provisioner := &fakeProvisioner{
+		result: &models.ProvisionResult{
+			StackName:       "client-a-stack",
+			StackStatus:     "CREATE_COMPLETE",
+			BucketName:      "client-a-bucket",
+			Region:          "eu-west-1",
+			IAMUserName:     "client-a-user",
+			AccessKeyID:     "AKIAPROVISIONED",
+			SecretAccessKey: "secret",
+			ShareLinkURL:    "https://abc123.lambda-url.eu-west-1.on.aws/",
+		},
+		report: &models.StackDriftReport{
+			StackName:   "client-a-stack",
+			DriftStatus: "DRIFTED",
+			DriftedResources: []models.ResourceDrift{
+				{LogicalResourceID: "FileStorageBucket", ResourceType: "AWS::S3::Bucket", DriftStatus: "MODIFIED"},
+			},
+		},
+	}
+
+	mockWindow := &MockMainWindow{}
+	factory := &fakeServiceFactory{provisioner: provisioner}
+	controller := NewController(fileManager, shareManager, expirationManager, settingsManager, syncManager, mockWindow)
+	controller.EnableProfiles(profileManager, factory)
+	defer controller.Stop()
+
+	require.NoError(t, controller.Start())
+
+	req := &models.ProvisionRequest{
+		ProfileName: "client-a",
+		StackName:   "client-a-stack",
+		BucketName:  "client-a-bucket",
+		IAMUserName: "client-a-user",
+		Region:      "eu-west-1",
+	}
+
+	// Invalid requests never reach CloudFormation
+	require.NotNil(t, mockWindow.OnProvisionBucket)
+	_, err := mockWindow.OnProvisionBucket(&models.ProvisionRequest{ProfileName: "client-a"})
+	assert.Error(t, err)
+
+	result, err := mockWindow.OnProvisionBucket(req)
+	require.NoError(t, err)
+	assert.Equal(t, "client-a-bucket", result.BucketName)
+
+	// The profile is created from the stack outputs and becomes active
+	profile, err := profileManager.GetProfile("client-a")
+	require.NoError(t, err)
+	assert.Equal(t, "client-a-bucket", profile.S3Bucket)
+	assert.Equal(t, "eu-west-1", profile.AWSRegion)
+	assert.Equal(t, "client-a-stack", profile.StackName)
+	assert.Equal(t, "https://abc123.lambda-url.eu-west-1.on.aws/", profile.ShareLinkURL)
+	assert.Equal(t, "AKIAPROVISIONED", factory.storedCredentials["client-a"])
+	assert.Equal(t, "client-a", mockWindow.ActiveProfile)
+	
+	// The stack's redirect function serves the new profile's short links
+	assert.Contains(t, factory.shareLinkProfiles, "client-a")
+
+	settings, err := settingsManager.LoadSettings()
+	require.NoError(t, err)
+	assert.Equal(t, "client-a-bucket", settings.S3Bucket)
+
+	// Drift is reported for the stack
+	require.NotNil(t, mockWindow.OnCheckDrift)
+	report, err := mockWindow.OnCheckDrift(req)
+	require.NoError(t, err)
+	assert.True(t, report.IsDrifted())
+	assert.Contains(t, mockWindow.LastStatus, "has drifted")
+
+	// Provisioning failures are surfaced without touching the profile
+	provisioner.err = fmt.Errorf("stack rolled back")
+	_, err = mockWindow.OnProvisionBucket(req)
+	require.Error(t, err)
+	assert.Contains(t, mockWindow.LastStatus, "Provisioning failed")
+}
+
+func TestController_CheckBucketHealth(t *testing.T) {
+	// Create test database
+	db := createTempDatabase(t)
+
+	// Create managers
+	fileManager := manager.NewFileManagerWithoutS3(db)
+	shareManager := manager.NewShareManager(db, nil)
+	expirationManager := manager.NewExpirationManager(db)
+	settingsManager := manager.NewSettingsManager(db)
+	syncManager := manager.NewSyncManagerWithoutS3(db)
+	profileManager := manager.NewProfileManager(db)
+
+	mockWindow := &MockMainWindow{}
+	factory := &fakeServiceFactory{}
+	controller := NewController(fileManager, shareManager, expirationManager, settingsManager, syncManager, mockWindow)
+	controller.EnableProfiles(profileManager, factory)
+	defer controller.Stop()
+
+	require.NoError(t, controller.Start())
+	require.NotNil(t, mockWindow.OnCheckBucketHealth)
+
+	// Without credentials the check cannot run
+	_, err := mockWindow.OnCheckBucketHealth()
+	require.Error(t, err)
+	assert.Contains(t, mockWindow.LastStatus, "Health check failed")
+
+	factory.healthChecker = &fakeHealthChecker{report: &models.HealthReport{
+		Bucket: "test-bucket",
+		Checks: []models.HealthCheck{
+			{Name: "Default encryption", Status: models.HealthCheckPass},
+			{Name: "Public access block", Status: models.HealthCheckFail, Remediation: "Block public access"},
+		},
+	}}
+
+	report, err := mockWindow.OnCheckBucketHealth()
+	require.NoError(t, err)
+	assert.False(t, report.Healthy())
+	assert.Equal(t, models.DefaultProfileName, factory.healthProfile)
+	assert.Equal(t, "Bucket health check: 1 of 2 checks failed", mockWindow.LastStatus)
+}
+
+func TestIsNetworkError(t *testing.T) {
+	assert.True(t, isNetworkError(fmt.Errorf("dial tcp: connection refused")))
+	assert.False(t, isNetworkError(fmt.Errorf("file is empty")))
+	assert.False(t, isNetworkError(nil))
+
+	// A virus scanner that isn't running doesn't take the app offline
+	scanErr := &models.ScanFailedError{FileName: "report.pdf", Err: fmt.Errorf("failed to connect to clamd: connection refused")}
+	assert.False(t, isNetworkError(fmt.Errorf("upload failed: %w", scanErr)))
+}
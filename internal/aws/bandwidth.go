@@ -0,0 +1,130 @@
+package aws
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+
+	"file-sharing-app/internal/models"
+)
+
+// throttledReadSize is the most read at a time from a throttled upload, so waits stay short
+// and a changed limit applies quickly
+const throttledReadSize = 16 * 1024
+
+// throttledPartSize is the part size of throttled uploads. The uploader buffers a part before
+// sending it, so smaller parts keep the bursts that reach the network short.
+const throttledPartSize = manager.MinUploadPartSize
+
+// BandwidthLimiter paces the bytes read by uploads to the rate of a models.BandwidthLimit, which
+// can change while they run. It is safe for concurrent use, so one limiter can cap several
+// uploads together.
+type BandwidthLimiter struct {
+	limit *models.BandwidthLimit
+
+	mu   sync.Mutex
+	rate int64     // bytes per second the schedule was made at
+	next time.Time // when the bytes allowed so far will have been sent
+}
+
+// NewBandwidthLimiter returns a limiter following limit
+func NewBandwidthLimiter(limit *models.BandwidthLimit) *BandwidthLimiter {
+	return &BandwidthLimiter{limit: limit}
+}
+
+// Limit returns the limit the limiter follows
+func (l *BandwidthLimiter) Limit() *models.BandwidthLimit {
+	return l.limit
+}
+
+// Active reports whether the limiter is holding uploads back at the moment
+func (l *BandwidthLimiter) Active() bool {
+	return l != nil && l.limit.KBps() > 0
+}
+
+// WaitN waits until n more bytes can be sent without going over the limit. It returns at once
+// when the limit is off, and with the context's error if it is cancelled first.
+func (l *BandwidthLimiter) WaitN(ctx context.Context, n int) error {
+	wait := l.reserve(n, time.Now())
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// reserve schedules n bytes and returns how long to wait before sending them
+func (l *BandwidthLimiter) reserve(n int, now time.Time) time.Duration {
+	if l == nil {
+		return 0
+	}
+	rate := l.limit.BytesPerSecond()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if rate <= 0 {
+		l.rate = 0
+		return 0
+	}
+	// Start over when the limit changes, and don't let time spent idle build up into a burst
+	if rate != l.rate || l.next.Before(now) {
+		l.rate = rate
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(int64(n) * int64(time.Second) / rate))
+	return l.next.Sub(now)
+}
+
+type bandwidthLimitersKey struct{}
+
+// WithBandwidthLimiter returns a copy of ctx whose uploads are also paced by limiter. Limiters
+// add up, so an upload can be held to both a global and its own limit.
+func WithBandwidthLimiter(ctx context.Context, limiter *BandwidthLimiter) context.Context {
+	if limiter == nil {
+		return ctx
+	}
+	existing := bandwidthLimiters(ctx)
+	limiters := make([]*BandwidthLimiter, 0, len(existing)+1)
+	limiters = append(limiters, existing...)
+	limiters = append(limiters, limiter)
+	return context.WithValue(ctx, bandwidthLimitersKey{}, limiters)
+}
+
+// bandwidthLimiters returns the limiters uploads made with ctx are paced by
+func bandwidthLimiters(ctx context.Context) []*BandwidthLimiter {
+	if ctx == nil {
+		return nil
+	}
+	limiters, _ := ctx.Value(bandwidthLimitersKey{}).([]*BandwidthLimiter)
+	return limiters
+}
+
+// anyActive reports whether any of limiters is holding uploads back at the moment
+func anyActive(limiters []*BandwidthLimiter) bool {
+	for _, limiter := range limiters {
+		if limiter.Active() {
+			return true
+		}
+	}
+	return false
+}
+
+// throttledPartSizeFor returns the part size for a throttled upload of size bytes, staying
+// within the uploader's limit on the number of parts
+func throttledPartSizeFor(size int64) int64 {
+	partSize := throttledPartSize
+	if minimum := size/int64(manager.MaxUploadParts-1) + 1; minimum > partSize {
+		partSize = minimum
+	}
+	return partSize
+}
@@ -0,0 +1,129 @@
+package aws
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"file-sharing-app/internal/models"
+)
+
+func TestBandwidthLimiter_Reserve(t *testing.T) {
+	limit := models.NewBandwidthLimit(16) // 16384 bytes per second
+	limiter := NewBandwidthLimiter(limit)
+	now := time.Now()
+
+	assert.Equal(t, time.Second, limiter.reserve(16*1024, now))
+	assert.Equal(t, 2*time.Second, limiter.reserve(16*1024, now), "reservations queue up")
+	assert.Equal(t, 1500*time.Millisecond, limiter.reserve(8*1024, now.Add(time.Second)))
+
+	// Time spent idle doesn't build up into a burst
+	assert.Equal(t, time.Second, limiter.reserve(16*1024, now.Add(time.Minute)))
+
+	// A changed limit starts a new schedule at the new rate
+	limit.Set(32)
+	assert.Equal(t, 500*time.Millisecond, limiter.reserve(16*1024, now.Add(time.Minute)))
+
+	// Turning the limit off lets everything through
+	limit.Set(0)
+	assert.Zero(t, limiter.reserve(1024*1024, now.Add(time.Minute)))
+
+	var none *BandwidthLimiter
+	assert.Zero(t, none.reserve(1024*1024, now))
+}
+
+func TestBandwidthLimiter_WaitN_Cancelled(t *testing.T) {
+	limiter := NewBandwidthLimiter(models.NewBandwidthLimit(models.MinBandwidthLimitKBps))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// An hour's worth at the limit
+	err := limiter.WaitN(ctx, models.MinBandwidthLimitKBps*1024*3600)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestBandwidthLimiter_Active(t *testing.T) {
+	limit := models.NewBandwidthLimit(0)
+	limiter := NewBandwidthLimiter(limit)
+	assert.False(t, limiter.Active())
+	assert.False(t, anyActive([]*BandwidthLimiter{limiter, nil}))
+
+	limit.Set(512)
+	assert.True(t, limiter.Active())
+	assert.True(t, anyActive([]*BandwidthLimiter{nil, limiter}))
+}
+
+func TestWithBandwidthLimiter(t *testing.T) {
+	global := NewBandwidthLimiter(models.NewBandwidthLimit(1024))
+	own := NewBandwidthLimiter(models.NewBandwidthLimit(256))
+
+	ctx := context.Background()
+	assert.Empty(t, bandwidthLimiters(ctx))
+	assert.Equal(t, ctx, WithBandwidthLimiter(ctx, nil))
+
+	globalCtx := WithBandwidthLimiter(ctx, global)
+	bothCtx := WithBandwidthLimiter(globalCtx, own)
+	assert.Equal(t, []*BandwidthLimiter{global}, bandwidthLimiters(globalCtx))
+	assert.Equal(t, []*BandwidthLimiter{global, own}, bandwidthLimiters(bothCtx))
+}
+
+func TestThrottledPartSizeFor(t *testing.T) {
+	assert.Equal(t, manager.MinUploadPartSize, throttledPartSizeFor(100*1024*1024))
+
+	// Very large files use larger parts to stay within the part count limit
+	size := int64(100) * 1024 * 1024 * 1024
+	partSize := throttledPartSizeFor(size)
+	assert.Greater(t, partSize, manager.MinUploadPartSize)
+	assert.Less(t, (size+partSize-1)/partSize, int64(manager.MaxUploadParts))
+}
+
+func TestProgressReader_Throttled(t *testing.T) {
+	content := strings.Repeat("x", 3*throttledReadSize)
+	limit := models.NewBandwidthLimit(1024 * 1024) // fast enough not to slow the test
+	pr := &progressReader{
+		reader:     strings.NewReader(content),
+		totalBytes: int64(len(content)),
+		ctx:        context.Background(),
+		limiters:   []*BandwidthLimiter{NewBandwidthLimiter(limit)},
+	}
+
+	// Reads are split into small pieces so the limiter can pace them
+	buffer := make([]byte, len(content))
+	n, err := pr.Read(buffer)
+	require.NoError(t, err)
+	assert.Equal(t, throttledReadSize, n)
+
+	// Unless the limit is off
+	limit.Set(0)
+	m, err := pr.Read(buffer)
+	require.NoError(t, err)
+	assert.Equal(t, 2*throttledReadSize, m)
+	n += m
+	limit.Set(1024 * 1024)
+
+	rest, err := io.ReadAll(pr)
+	require.NoError(t, err)
+	assert.Equal(t, len(content), n+len(rest))
+}
+
+func TestProgressReader_ThrottledCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	pr := &progressReader{
+		reader:     strings.NewReader(strings.Repeat("x", 1024*1024)),
+		totalBytes: 1024 * 1024,
+		ctx:        ctx,
+		limiters:   []*BandwidthLimiter{NewBandwidthLimiter(models.NewBandwidthLimit(models.MinBandwidthLimitKBps))},
+	}
+
+	// The first piece takes a second at the limit, so the cancelled context stops the read
+	buffer := make([]byte, 1024*1024)
+	_, err := pr.Read(buffer)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,121 @@
+package aws
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+
+	"file-sharing-app/internal/models"
+)
+
+// CompressedFile is a compressed copy of a file, kept in a temporary file until it has been uploaded
+type CompressedFile struct {
+	Path             string // the temporary file; delete it with Remove
+	Encoding         string // models.CompressionGzip or models.CompressionZstd
+	Size             int64  // compressed size in bytes
+	OriginalSize     int64  // size of the file compressed, in bytes
+	OriginalChecksum string // hex SHA-256 of the file compressed
+}
+
+// CompressFile writes a copy of a file compressed with encoding to a temporary file. The original
+// content is hashed as it is read, so the checksum matches what was compressed.
+func CompressFile(filePath, encoding string) (*CompressedFile, error) {
+	src, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file for compression: %w", err)
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp("", "upload-*."+encoding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compressed file: %w", err)
+	}
+	compressed := &CompressedFile{Path: tmp.Name(), Encoding: encoding}
+
+	if err := compressed.write(tmp, src); err != nil {
+		tmp.Close()
+		compressed.Remove()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		compressed.Remove()
+		return nil, fmt.Errorf("failed to write compressed file: %w", err)
+	}
+
+	return compressed, nil
+}
+
+// write compresses src into dst, recording the sizes and the checksum of src
+func (c *CompressedFile) write(dst *os.File, src io.Reader) error {
+	encoder, err := newEncoder(dst, c.Encoding)
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.New()
+	c.OriginalSize, err = io.Copy(encoder, io.TeeReader(src, hash))
+	if err != nil {
+		encoder.Close()
+		return fmt.Errorf("failed to compress file: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to compress file: %w", err)
+	}
+
+	info, err := dst.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to get compressed file information: %w", err)
+	}
+	c.Size = info.Size()
+	c.OriginalChecksum = hex.EncodeToString(hash.Sum(nil))
+	return nil
+}
+
+// SavingsPercent returns how much smaller the compressed copy is, in percent of the original size
+func (c *CompressedFile) SavingsPercent() float64 {
+	if c.OriginalSize <= 0 {
+		return 0
+	}
+	return float64(c.OriginalSize-c.Size) * 100 / float64(c.OriginalSize)
+}
+
+// Remove deletes the temporary file
+func (c *CompressedFile) Remove() error {
+	return os.Remove(c.Path)
+}
+
+// newEncoder returns a writer compressing to w with encoding
+func newEncoder(w io.Writer, encoding string) (io.WriteCloser, error) {
+	switch encoding {
+	case models.CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case models.CompressionZstd:
+		return zstd.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("unsupported compression: %s", encoding)
+	}
+}
+
+// decodeContent returns a reader of the content of a body sent with the given Content-Encoding
+func decodeContent(body io.Reader, encoding string) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return io.NopCloser(body), nil
+	case models.CompressionGzip:
+		return gzip.NewReader(body)
+	case models.CompressionZstd:
+		decoder, err := zstd.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding: %s", encoding)
+	}
+}
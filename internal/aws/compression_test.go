@@ -0,0 +1,109 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"file-sharing-app/internal/models"
+)
+
+func TestCompressFile(t *testing.T) {
+	content := strings.Repeat("2024-03-01T12:00:00Z INFO request handled in 12ms\n", 1000)
+	filePath := createTestFile(t, content)
+	sum := sha256.Sum256([]byte(content))
+
+	for _, encoding := range models.CompressionAlgorithms {
+		t.Run(encoding, func(t *testing.T) {
+			compressed, err := CompressFile(filePath, encoding)
+			require.NoError(t, err)
+			defer compressed.Remove()
+
+			assert.Equal(t, encoding, compressed.Encoding)
+			assert.Equal(t, int64(len(content)), compressed.OriginalSize)
+			assert.Equal(t, hex.EncodeToString(sum[:]), compressed.OriginalChecksum)
+			assert.Less(t, compressed.Size, compressed.OriginalSize)
+			assert.Greater(t, compressed.SavingsPercent(), 90.0)
+
+			// The copy decompresses to the original
+			data, err := os.ReadFile(compressed.Path)
+			require.NoError(t, err)
+			assert.Equal(t, compressed.Size, int64(len(data)))
+			reader, err := decodeContent(bytes.NewReader(data), encoding)
+			require.NoError(t, err)
+			decoded, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			assert.Equal(t, content, string(decoded))
+
+			require.NoError(t, compressed.Remove())
+			assert.NoFileExists(t, compressed.Path)
+		})
+	}
+}
+
+func TestCompressFile_Errors(t *testing.T) {
+	_, err := CompressFile(filepath.Join(t.TempDir(), "missing.log"), models.CompressionGzip)
+	assert.Error(t, err)
+
+	_, err = CompressFile(createTestFile(t, "content"), "brotli")
+	assert.Error(t, err)
+}
+
+func TestDownloadPresigned_Compressed(t *testing.T) {
+	content := strings.Repeat("id,name,amount\n1,widget,9.99\n", 100)
+	compressed, err := CompressFile(createTestFile(t, content), models.CompressionZstd)
+	require.NoError(t, err)
+	defer compressed.Remove()
+	stored, err := os.ReadFile(compressed.Path)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", models.CompressionZstd)
+		w.Write(stored)
+	}))
+	defer server.Close()
+
+	// The download is saved decompressed
+	destPath := filepath.Join(t.TempDir(), "orders.csv")
+	require.NoError(t, DownloadPresigned(context.Background(), &PresignedDownload{URL: server.URL}, destPath))
+	data, err := os.ReadFile(destPath)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(data))
+}
+
+func TestS3ServiceImpl_UploadFile_ContentEncoding(t *testing.T) {
+	var encoding string
+	var metadata string
+	service := newShareLinkTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		encoding = r.Header.Get("Content-Encoding")
+		metadata = r.Header.Get("X-Amz-Meta-Content-Encoding")
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.Header().Set("ETag", `"abc"`)
+		w.WriteHeader(http.StatusOK)
+	})
+	service.uploader = manager.NewUploader(service.client)
+
+	result, err := service.UploadFile(context.Background(), "uploads/2024/03/01/0b9d.log", createTestFile(t, "compressed"),
+		map[string]string{"original-name": "server.log", "content-encoding": models.CompressionGzip}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.CompressionGzip, encoding)
+	assert.Empty(t, metadata)
+	assert.Equal(t, int64(len("compressed")), result.Size)
+
+	// Files uploaded as they are have no encoding
+	_, err = service.UploadFile(context.Background(), "uploads/2024/03/01/0b9e.log", createTestFile(t, "plain"), nil, nil)
+	require.NoError(t, err)
+	assert.Empty(t, encoding)
+}
@@ -0,0 +1,68 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ResponseOverrides are headers a presigned GET asks S3 to send in place of the object's own.
+// Empty fields leave the object's headers alone.
+type ResponseOverrides struct {
+	ContentDisposition string
+	ContentType        string
+}
+
+// AttachmentDisposition returns a Content-Disposition header that makes browsers save the
+// download as fileName
+func AttachmentDisposition(fileName string) string {
+	return contentDisposition("attachment", fileName)
+}
+
+// InlineDisposition returns a Content-Disposition header that lets browsers show the download
+// instead of saving it, under fileName if it is saved after all
+func InlineDisposition(fileName string) string {
+	return contentDisposition("inline", fileName)
+}
+
+// contentDisposition encodes the file name as RFC 5987 describes, with an ASCII fallback
+// for clients that don't understand filename*
+func contentDisposition(disposition, fileName string) string {
+	return fmt.Sprintf(`%s; filename="%s"; filename*=UTF-8''%s`, disposition, asciiFileName(fileName), encodeRFC5987(fileName))
+}
+
+// asciiFileName replaces the characters that can't appear in a quoted ASCII file name
+func asciiFileName(fileName string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, fileName)
+}
+
+// encodeRFC5987 percent-encodes the UTF-8 bytes of value that aren't RFC 5987 attr-chars
+func encodeRFC5987(value string) string {
+	const hex = "0123456789ABCDEF"
+
+	var encoded strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if isAttrChar(c) {
+			encoded.WriteByte(c)
+			continue
+		}
+		encoded.WriteByte('%')
+		encoded.WriteByte(hex[c>>4])
+		encoded.WriteByte(hex[c&0x0f])
+	}
+	return encoded.String()
+}
+
+// isAttrChar reports whether c can appear unencoded in an RFC 5987 value
+func isAttrChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", c) >= 0
+}
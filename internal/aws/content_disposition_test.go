@@ -0,0 +1,73 @@
+package aws
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttachmentDisposition(t *testing.T) {
+	assert.Equal(t,
+		`attachment; filename="report.pdf"; filename*=UTF-8''report.pdf`,
+		AttachmentDisposition("report.pdf"))
+
+	assert.Equal(t,
+		`attachment; filename="Q3 report (final).pdf"; filename*=UTF-8''Q3%20report%20%28final%29.pdf`,
+		AttachmentDisposition("Q3 report (final).pdf"))
+
+	// Non-ASCII names are percent-encoded as UTF-8, with a placeholder in the fallback
+	assert.Equal(t,
+		`attachment; filename="r_sum_.pdf"; filename*=UTF-8''r%C3%A9sum%C3%A9.pdf`,
+		AttachmentDisposition("résumé.pdf"))
+
+	// Quotes can't break out of the fallback name
+	assert.Equal(t,
+		`attachment; filename="a_b_.txt"; filename*=UTF-8''a%22b%5C.txt`,
+		AttachmentDisposition(`a"b\.txt`))
+
+	assert.Equal(t,
+		`inline; filename="scan.pdf"; filename*=UTF-8''scan.pdf`,
+		InlineDisposition("scan.pdf"))
+}
+
+func TestS3ServiceImpl_UploadFile_ContentDisposition(t *testing.T) {
+	var disposition string
+	service := newShareLinkTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		disposition = r.Header.Get("Content-Disposition")
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.Header().Set("ETag", `"abc"`)
+		w.WriteHeader(http.StatusOK)
+	})
+	service.uploader = manager.NewUploader(service.client)
+
+	// Downloads are named after the original file, not the generated key
+	_, err := service.UploadFile(context.Background(), "uploads/2024/03/01/0b9d.pdf", createTestFile(t, "report"),
+		map[string]string{"original-name": "Q3 résumé.pdf"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, AttachmentDisposition("Q3 résumé.pdf"), disposition)
+}
+
+func TestS3ServiceImpl_UploadFile_OriginalNameMetadata(t *testing.T) {
+	var header http.Header
+	service := newShareLinkTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.Header().Set("ETag", `"abc"`)
+		w.WriteHeader(http.StatusOK)
+	})
+	service.uploader = manager.NewUploader(service.client)
+
+	// Without an original name, the file's own name is recorded, once
+	filePath := createTestFile(t, "report")
+	_, err := service.UploadFile(context.Background(), "uploads/2024/03/01/0b9d.txt", filePath, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Base(filePath), header.Get("X-Amz-Meta-Original-Name"))
+	assert.Empty(t, header.Get("X-Amz-Meta-Original-Filename"))
+	assert.Equal(t, AttachmentDisposition(filepath.Base(filePath)), header.Get("Content-Disposition"))
+}
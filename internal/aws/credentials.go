@@ -18,6 +18,14 @@ const (
 	AccessKeyItem      = "aws-access-key"
 	SecretKeyItem      = "aws-secret-key"
 	RegionItem         = "aws-region"
+	SSECustomerKeyItem = "sse-customer-key"
+	ShareLinkKeyItem   = "share-link-key"
+
+	// DefaultProfileName is the profile whose credentials fall back to the legacy un-namespaced items
+	DefaultProfileName = "default"
+
+	// profileItemPrefix namespaces keyring items that belong to a named profile
+	profileItemPrefix = "profile."
 )
 
 // CredentialProvider defines the interface for managing AWS credentials
@@ -33,10 +41,70 @@ type CredentialProvider interface {
 // SecureCredentialProvider implements CredentialProvider using OS keychain
 type SecureCredentialProvider struct {
 	keyring keyring.Keyring
+	// profile selects namespaced keyring items; empty uses the legacy single-account items
+	profile string
 }
 
 // NewSecureCredentialProvider creates a new SecureCredentialProvider
 func NewSecureCredentialProvider() (*SecureCredentialProvider, error) {
+	ring, err := openKeyring()
+	if err != nil {
+		return nil, err
+	}
+
+	return &SecureCredentialProvider{
+		keyring: ring,
+	}, nil
+}
+
+// NewSecureCredentialProviderForProfile creates a SecureCredentialProvider whose credentials
+// are stored under keyring items namespaced by the given profile name
+func NewSecureCredentialProviderForProfile(profile string) (*SecureCredentialProvider, error) {
+	if profile == "" {
+		return nil, errors.New("profile name cannot be empty")
+	}
+
+	ring, err := openKeyring()
+	if err != nil {
+		return nil, err
+	}
+
+	return &SecureCredentialProvider{
+		keyring: ring,
+		profile: profile,
+	}, nil
+}
+
+// Profile returns the profile the provider reads credentials for
+func (p *SecureCredentialProvider) Profile() string {
+	return p.profile
+}
+
+// ProfileItemKey returns the keyring item name used to store item for a profile
+func ProfileItemKey(profile, item string) string {
+	if profile == "" {
+		return item
+	}
+	return profileItemPrefix + profile + "." + item
+}
+
+// itemKey returns the keyring item name for this provider's profile
+func (p *SecureCredentialProvider) itemKey(item string) string {
+	return ProfileItemKey(p.profile, item)
+}
+
+// getItem reads a keyring item for this provider's profile. The default profile falls back
+// to the legacy un-namespaced item so credentials stored by earlier versions keep working.
+func (p *SecureCredentialProvider) getItem(item string) (keyring.Item, error) {
+	value, err := p.keyring.Get(p.itemKey(item))
+	if err != nil && p.profile == DefaultProfileName && errors.Is(err, keyring.ErrKeyNotFound) {
+		return p.keyring.Get(item)
+	}
+	return value, err
+}
+
+// openKeyring opens the OS keyring used for all application secrets
+func openKeyring() (keyring.Keyring, error) {
 	ring, err := keyring.Open(keyring.Config{
 		ServiceName: KeyringServiceName,
 		// Allow fallback to file backend for testing
@@ -51,9 +119,7 @@ func NewSecureCredentialProvider() (*SecureCredentialProvider, error) {
 		return nil, fmt.Errorf("failed to open keyring: %w", err)
 	}
 
-	return &SecureCredentialProvider{
-		keyring: ring,
-	}, nil
+	return ring, nil
 }
 
 // StoreCredentials stores AWS credentials securely in the OS keychain
@@ -64,7 +130,7 @@ func (p *SecureCredentialProvider) StoreCredentials(accessKey, secretKey, region
 
 	// Store access key
 	if err := p.keyring.Set(keyring.Item{
-		Key:  AccessKeyItem,
+		Key:  p.itemKey(AccessKeyItem),
 		Data: []byte(accessKey),
 	}); err != nil {
 		return fmt.Errorf("failed to store access key: %w", err)
@@ -72,7 +138,7 @@ func (p *SecureCredentialProvider) StoreCredentials(accessKey, secretKey, region
 
 	// Store secret key
 	if err := p.keyring.Set(keyring.Item{
-		Key:  SecretKeyItem,
+		Key:  p.itemKey(SecretKeyItem),
 		Data: []byte(secretKey),
 	}); err != nil {
 		return fmt.Errorf("failed to store secret key: %w", err)
@@ -88,16 +154,20 @@ func (p *SecureCredentialProvider) StoreCredentials(accessKey, secretKey, region
 	return nil
 }
 
-// GetCredentials retrieves AWS credentials from the keychain
+// GetCredentials retrieves AWS credentials from the keychain. Only the default profile falls back to
+// the AWS credential chain, which may belong to another account than a named profile's bucket.
 func (p *SecureCredentialProvider) GetCredentials(ctx context.Context) (aws.Credentials, error) {
 	// Try to get credentials from keychain first
-	accessKeyItem, err := p.keyring.Get(AccessKeyItem)
+	accessKeyItem, err := p.getItem(AccessKeyItem)
 	if err != nil {
+		if p.profile != "" && p.profile != DefaultProfileName {
+			return aws.Credentials{}, fmt.Errorf("no credentials stored for profile %s: %w", p.profile, err)
+		}
 		// If not found in keychain, try AWS credential chain
 		return p.getCredentialsFromChain(ctx)
 	}
 
-	secretKeyItem, err := p.keyring.Get(SecretKeyItem)
+	secretKeyItem, err := p.getItem(SecretKeyItem)
 	if err != nil {
 		return aws.Credentials{}, fmt.Errorf("failed to retrieve secret key from keychain: %w", err)
 	}
@@ -163,20 +233,20 @@ func (p *SecureCredentialProvider) ValidateCredentials(ctx context.Context) erro
 // ClearCredentials removes all stored credentials from the keychain
 func (p *SecureCredentialProvider) ClearCredentials() error {
 	// Remove access key - ignore if not found
-	_ = p.keyring.Remove(AccessKeyItem)
+	_ = p.keyring.Remove(p.itemKey(AccessKeyItem))
 
 	// Remove secret key - ignore if not found
-	_ = p.keyring.Remove(SecretKeyItem)
+	_ = p.keyring.Remove(p.itemKey(SecretKeyItem))
 
 	// Remove region - ignore if not found
-	_ = p.keyring.Remove(RegionItem)
+	_ = p.keyring.Remove(p.itemKey(RegionItem))
 
 	return nil
 }
 
 // GetRegion retrieves the stored AWS region
 func (p *SecureCredentialProvider) GetRegion() (string, error) {
-	item, err := p.keyring.Get(RegionItem)
+	item, err := p.getItem(RegionItem)
 	if err != nil {
 		if errors.Is(err, keyring.ErrKeyNotFound) {
 			return "us-east-1", nil // Default region
@@ -194,7 +264,7 @@ func (p *SecureCredentialProvider) SetRegion(region string) error {
 	}
 
 	if err := p.keyring.Set(keyring.Item{
-		Key:  RegionItem,
+		Key:  p.itemKey(RegionItem),
 		Data: []byte(region),
 	}); err != nil {
 		return fmt.Errorf("failed to store region: %w", err)
@@ -203,6 +273,81 @@ func (p *SecureCredentialProvider) SetRegion(region string) error {
 	return nil
 }
 
+// GetSSECustomerKey retrieves the customer-provided SSE-C key stored for this profile
+func (p *SecureCredentialProvider) GetSSECustomerKey() ([]byte, error) {
+	item, err := p.getItem(SSECustomerKeyItem)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve SSE-C key: %w", err)
+	}
+
+	return item.Data, nil
+}
+
+// StoreSSECustomerKey stores the customer-provided SSE-C key for this profile.
+// ClearCredentials leaves it in place: objects uploaded with it cannot be read without it.
+func (p *SecureCredentialProvider) StoreSSECustomerKey(key []byte) error {
+	if len(key) != SSECustomerKeySize {
+		return fmt.Errorf("SSE-C key must be %d bytes, got %d", SSECustomerKeySize, len(key))
+	}
+
+	if err := p.keyring.Set(keyring.Item{
+		Key:  p.itemKey(SSECustomerKeyItem),
+		Data: key,
+	}); err != nil {
+		return fmt.Errorf("failed to store SSE-C key: %w", err)
+	}
+
+	return nil
+}
+
+// GetOrCreateSSECustomerKey returns the profile's SSE-C key, generating and storing one if none exists
+func (p *SecureCredentialProvider) GetOrCreateSSECustomerKey() ([]byte, error) {
+	key, err := p.GetSSECustomerKey()
+	if err == nil {
+		return key, nil
+	}
+	if !errors.Is(err, keyring.ErrKeyNotFound) {
+		return nil, err
+	}
+
+	key, err = GenerateSSECustomerKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.StoreSSECustomerKey(key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// GetOrCreateShareLinkKey returns the key this profile's short share link tokens are derived from,
+// generating and storing one if none exists. ClearCredentials leaves it in place, so links keep working.
+func (p *SecureCredentialProvider) GetOrCreateShareLinkKey() ([]byte, error) {
+	item, err := p.getItem(ShareLinkKeyItem)
+	if err == nil {
+		return item.Data, nil
+	}
+	if !errors.Is(err, keyring.ErrKeyNotFound) {
+		return nil, fmt.Errorf("failed to retrieve share link key: %w", err)
+	}
+
+	key, err := GenerateShareLinkKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.keyring.Set(keyring.Item{
+		Key:  p.itemKey(ShareLinkKeyItem),
+		Data: key,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to store share link key: %w", err)
+	}
+
+	return key, nil
+}
+
 // GetSetupGuidance provides user-friendly guidance for setting up AWS credentials
 func GetSetupGuidance() string {
 	return `AWS Credentials Setup Guide:
@@ -231,6 +231,114 @@ func TestClearCredentials(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestProfileCredentials_Namespaced(t *testing.T) {
+	ring := createTestKeyring(t)
+	clientA := &SecureCredentialProvider{keyring: ring, profile: "client-a"}
+	clientB := &SecureCredentialProvider{keyring: ring, profile: "client-b"}
+	ctx := context.Background()
+
+	require.NoError(t, clientA.StoreCredentials("AKIACLIENTA", "secret-a", "eu-west-1"))
+	require.NoError(t, clientB.StoreCredentials("AKIACLIENTB", "secret-b", "us-east-2"))
+
+	// Each profile's items live under their own namespaced keys
+	item, err := ring.Get(ProfileItemKey("client-a", AccessKeyItem))
+	require.NoError(t, err)
+	assert.Equal(t, "AKIACLIENTA", string(item.Data))
+
+	_, err = ring.Get(AccessKeyItem)
+	assert.Error(t, err, "profile credentials should not be written to the legacy item")
+
+	credsA, err := clientA.GetCredentials(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "AKIACLIENTA", credsA.AccessKeyID)
+
+	regionB, err := clientB.GetRegion()
+	require.NoError(t, err)
+	assert.Equal(t, "us-east-2", regionB)
+
+	// Clearing one profile leaves the other untouched
+	require.NoError(t, clientA.ClearCredentials())
+	_, err = ring.Get(ProfileItemKey("client-a", SecretKeyItem))
+	assert.Error(t, err)
+
+	credsB, err := clientB.GetCredentials(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "AKIACLIENTB", credsB.AccessKeyID)
+}
+
+func TestGetOrCreateShareLinkKey(t *testing.T) {
+	ring := createTestKeyring(t)
+	clientA := &SecureCredentialProvider{keyring: ring, profile: "client-a"}
+	clientB := &SecureCredentialProvider{keyring: ring, profile: "client-b"}
+
+	key, err := clientA.GetOrCreateShareLinkKey()
+	require.NoError(t, err)
+	assert.Len(t, key, ShareLinkKeySize)
+
+	// The key is kept, so existing links keep their tokens
+	again, err := clientA.GetOrCreateShareLinkKey()
+	require.NoError(t, err)
+	assert.Equal(t, key, again)
+
+	other, err := clientB.GetOrCreateShareLinkKey()
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other, "each profile has its own key")
+
+	// Clearing credentials must not break links that were already sent
+	require.NoError(t, clientA.ClearCredentials())
+	afterClear, err := clientA.GetOrCreateShareLinkKey()
+	require.NoError(t, err)
+	assert.Equal(t, key, afterClear)
+}
+
+func TestProfileCredentials_DefaultFallsBackToLegacyItems(t *testing.T) {
+	ring := createTestKeyring(t)
+	legacy := &SecureCredentialProvider{keyring: ring}
+	require.NoError(t, legacy.StoreCredentials("AKIALEGACY", "legacy-secret", "ap-southeast-2"))
+
+	defaultProfile := &SecureCredentialProvider{keyring: ring, profile: DefaultProfileName}
+
+	creds, err := defaultProfile.GetCredentials(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "AKIALEGACY", creds.AccessKeyID)
+
+	region, err := defaultProfile.GetRegion()
+	require.NoError(t, err)
+	assert.Equal(t, "ap-southeast-2", region)
+
+	// Other profiles never see the legacy items
+	other := &SecureCredentialProvider{keyring: ring, profile: "client-a"}
+	region, err = other.GetRegion()
+	require.NoError(t, err)
+	assert.Equal(t, "us-east-1", region)
+}
+
+func TestProfileCredentials_NamedProfileWithoutKeys(t *testing.T) {
+	ring := createTestKeyring(t)
+	legacy := &SecureCredentialProvider{keyring: ring}
+	require.NoError(t, legacy.StoreCredentials("AKIALEGACY", "legacy-secret", "ap-southeast-2"))
+
+	// A named profile never uses the legacy items or the AWS credential chain, which may be another account
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIAENVIRONMENT")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "environment-secret")
+	clientA := &SecureCredentialProvider{keyring: ring, profile: "client-a"}
+	_, err := clientA.GetCredentials(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no credentials stored for profile client-a")
+
+	// The default profile still falls back to the chain
+	require.NoError(t, legacy.ClearCredentials())
+	defaultProfile := &SecureCredentialProvider{keyring: ring, profile: DefaultProfileName}
+	creds, err := defaultProfile.GetCredentials(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "AKIAENVIRONMENT", creds.AccessKeyID)
+}
+
+func TestProfileItemKey(t *testing.T) {
+	assert.Equal(t, AccessKeyItem, ProfileItemKey("", AccessKeyItem))
+	assert.Equal(t, "profile.client-a.aws-region", ProfileItemKey("client-a", RegionItem))
+}
+
 func TestGetRegion(t *testing.T) {
 	provider := createTestCredentialProvider(t)
 
@@ -0,0 +1,119 @@
+package aws
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/99designs/keyring"
+)
+
+const (
+	// DatabaseKeyItem holds the key sensitive fields in the local database are encrypted with
+	DatabaseKeyItem = "database-encryption-key"
+
+	// PreviousDatabaseKeyItem holds the key replaced by the last rotation, so fields still
+	// encrypted with it can be read until they are re-encrypted
+	PreviousDatabaseKeyItem = "database-encryption-key.previous"
+
+	// DatabaseKeySize is the size of an AES-256 database key in bytes
+	DatabaseKeySize = 32
+)
+
+// DatabaseKeyStore keeps the local database encryption keys in the OS keyring.
+// The keys belong to the installation rather than to a profile.
+type DatabaseKeyStore struct {
+	keyring keyring.Keyring
+}
+
+// NewDatabaseKeyStore creates a DatabaseKeyStore using the OS keyring
+func NewDatabaseKeyStore() (*DatabaseKeyStore, error) {
+	ring, err := openKeyring()
+	if err != nil {
+		return nil, err
+	}
+
+	return &DatabaseKeyStore{keyring: ring}, nil
+}
+
+// GenerateDatabaseKey returns a new random AES-256 database key
+func GenerateDatabaseKey() ([]byte, error) {
+	key := make([]byte, DatabaseKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate database key: %w", err)
+	}
+	return key, nil
+}
+
+// Keys returns the current database key and the previous one, if any. The current key
+// is nil if database encryption has never been turned on.
+func (s *DatabaseKeyStore) Keys() (current, previous []byte, err error) {
+	current, err = s.getKey(DatabaseKeyItem)
+	if err != nil {
+		return nil, nil, err
+	}
+	previous, err = s.getKey(PreviousDatabaseKeyItem)
+	if err != nil {
+		return nil, nil, err
+	}
+	return current, previous, nil
+}
+
+// getKey reads a key from the keyring, returning nil if it isn't there
+func (s *DatabaseKeyStore) getKey(item string) ([]byte, error) {
+	value, err := s.keyring.Get(item)
+	if errors.Is(err, keyring.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve database key: %w", err)
+	}
+	if len(value.Data) != DatabaseKeySize {
+		return nil, fmt.Errorf("database key %s must be %d bytes, got %d", item, DatabaseKeySize, len(value.Data))
+	}
+	return value.Data, nil
+}
+
+// SetKeys stores the current database key and the previous one; a nil previous key removes it.
+// The previous key is stored first, so the key being replaced is never lost.
+func (s *DatabaseKeyStore) SetKeys(current, previous []byte) error {
+	if len(current) != DatabaseKeySize {
+		return fmt.Errorf("database key must be %d bytes, got %d", DatabaseKeySize, len(current))
+	}
+
+	if previous != nil {
+		if len(previous) != DatabaseKeySize {
+			return fmt.Errorf("database key must be %d bytes, got %d", DatabaseKeySize, len(previous))
+		}
+		if err := s.keyring.Set(keyring.Item{Key: PreviousDatabaseKeyItem, Data: previous}); err != nil {
+			return fmt.Errorf("failed to store previous database key: %w", err)
+		}
+	} else if err := s.removeKey(PreviousDatabaseKeyItem); err != nil {
+		return err
+	}
+
+	if err := s.keyring.Set(keyring.Item{Key: DatabaseKeyItem, Data: current}); err != nil {
+		return fmt.Errorf("failed to store database key: %w", err)
+	}
+	return nil
+}
+
+// ClearKeys removes the database keys once nothing is encrypted with them
+func (s *DatabaseKeyStore) ClearKeys() error {
+	if err := s.removeKey(DatabaseKeyItem); err != nil {
+		return err
+	}
+	return s.removeKey(PreviousDatabaseKeyItem)
+}
+
+// removeKey removes a key from the keyring, ignoring keys that aren't there.
+// Backends report removing a missing item differently, so it is looked up first.
+func (s *DatabaseKeyStore) removeKey(item string) error {
+	if _, err := s.keyring.Get(item); errors.Is(err, keyring.ErrKeyNotFound) {
+		return nil
+	}
+	if err := s.keyring.Remove(item); err != nil {
+		return fmt.Errorf("failed to remove database key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateDatabaseKey(t *testing.T) {
+	key, err := GenerateDatabaseKey()
+	require.NoError(t, err)
+	assert.Len(t, key, DatabaseKeySize)
+
+	other, err := GenerateDatabaseKey()
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+}
+
+func TestDatabaseKeyStore(t *testing.T) {
+	store := &DatabaseKeyStore{keyring: createTestKeyring(t)}
+
+	// No keys until encryption is turned on
+	current, previous, err := store.Keys()
+	require.NoError(t, err)
+	assert.Nil(t, current)
+	assert.Nil(t, previous)
+
+	first, err := GenerateDatabaseKey()
+	require.NoError(t, err)
+	require.NoError(t, store.SetKeys(first, nil))
+
+	current, previous, err = store.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, first, current)
+	assert.Nil(t, previous)
+
+	// Rotating keeps the replaced key
+	second, err := GenerateDatabaseKey()
+	require.NoError(t, err)
+	require.NoError(t, store.SetKeys(second, first))
+
+	current, previous, err = store.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, second, current)
+	assert.Equal(t, first, previous)
+
+	// Keys of the wrong size are refused
+	assert.Error(t, store.SetKeys([]byte("short"), nil))
+	assert.Error(t, store.SetKeys(second, []byte("short")))
+
+	require.NoError(t, store.ClearKeys())
+	current, previous, err = store.Keys()
+	require.NoError(t, err)
+	assert.Nil(t, current)
+	assert.Nil(t, previous)
+
+	// Clearing again is harmless
+	assert.NoError(t, store.ClearKeys())
+}
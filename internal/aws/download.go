@@ -0,0 +1,84 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"file-sharing-app/pkg/errors"
+)
+
+// DownloadPresigned fetches a presigned download into destPath, sending any headers the
+// download requires. The file is written to a temporary file first so a failed download
+// never leaves a partial file at destPath. Compressed uploads are decompressed, so destPath
+// gets the original content.
+func DownloadPresigned(ctx context.Context, download *PresignedDownload, destPath string) error {
+	return downloadPresigned(ctx, http.DefaultClient, download, destPath)
+}
+
+func downloadPresigned(ctx context.Context, client *http.Client, download *PresignedDownload, destPath string) error {
+	if download == nil || download.URL == "" {
+		return errors.NewAppError(errors.ErrInvalidInput, "download URL cannot be empty", nil)
+	}
+	if destPath == "" {
+		return errors.NewAppError(errors.ErrInvalidInput, "destination path cannot be empty", nil)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, download.URL, nil)
+	if err != nil {
+		return errors.NewAppError(errors.ErrInvalidInput, "invalid download URL", err)
+	}
+	for name, values := range download.Headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	// S3 sends compressed uploads as they are stored; they are decompressed below
+	req.Header.Set("Accept-Encoding", "identity")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.ClassifyError(err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusForbidden:
+		return errors.NewAppError(errors.ErrS3AccessDenied, "download was refused; the link may have expired", nil)
+	case resp.StatusCode == http.StatusNotFound:
+		return errors.NewAppError(errors.ErrS3ObjectNotFound, "file no longer exists in S3", nil)
+	case resp.StatusCode != http.StatusOK:
+		return errors.NewAppError(errors.ErrDownloadFailed, fmt.Sprintf("download failed with HTTP status %d", resp.StatusCode), nil)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(destPath), "."+filepath.Base(destPath)+".*.part")
+	if err != nil {
+		return errors.NewAppError(errors.ErrInvalidFilePath, "failed to create download file", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	content, err := decodeContent(resp.Body, resp.Header.Get("Content-Encoding"))
+	if err != nil {
+		tmp.Close()
+		return errors.NewAppError(errors.ErrDownloadFailed, "failed to decompress download", err)
+	}
+	defer content.Close()
+
+	if _, err := io.Copy(tmp, content); err != nil {
+		tmp.Close()
+		return errors.ClassifyError(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.NewAppError(errors.ErrDownloadFailed, "failed to write download file", err)
+	}
+
+	if err := os.Rename(tmpPath, destPath); err != nil {
+		return errors.NewAppError(errors.ErrDownloadFailed, "failed to save downloaded file", err)
+	}
+
+	return nil
+}
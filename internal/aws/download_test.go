@@ -0,0 +1,56 @@
+package aws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadPresigned(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(headerSSECustomerKey) != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("file contents"))
+	}))
+	defer server.Close()
+
+	destPath := filepath.Join(t.TempDir(), "file.txt")
+	headers := make(http.Header)
+	headers.Set(headerSSECustomerKey, "secret")
+
+	err := DownloadPresigned(context.Background(), &PresignedDownload{URL: server.URL, Headers: headers}, destPath)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(destPath)
+	require.NoError(t, err)
+	assert.Equal(t, "file contents", string(data))
+}
+
+func TestDownloadPresigned_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	destPath := filepath.Join(dir, "file.txt")
+
+	err := DownloadPresigned(context.Background(), &PresignedDownload{URL: server.URL}, destPath)
+	assert.Error(t, err)
+
+	// A failed download leaves nothing behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	assert.Error(t, DownloadPresigned(context.Background(), nil, destPath))
+	assert.Error(t, DownloadPresigned(context.Background(), &PresignedDownload{URL: server.URL}, ""))
+}
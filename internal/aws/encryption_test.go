@@ -0,0 +1,140 @@
+package aws
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"file-sharing-app/internal/models"
+)
+
+func testCustomerKey() []byte {
+	key := make([]byte, SSECustomerKeySize)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	return key
+}
+
+func TestEncryptionConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      EncryptionConfig
+		expectError bool
+	}{
+		{name: "SSE-S3", config: EncryptionConfig{Mode: models.EncryptionSSES3}},
+		{name: "SSE-KMS with key", config: EncryptionConfig{Mode: models.EncryptionSSEKMS, KMSKeyID: "alias/app"}},
+		{name: "SSE-KMS without key", config: EncryptionConfig{Mode: models.EncryptionSSEKMS}, expectError: true},
+		{name: "SSE-C with key", config: EncryptionConfig{Mode: models.EncryptionSSEC, CustomerKey: testCustomerKey()}},
+		{name: "SSE-C with short key", config: EncryptionConfig{Mode: models.EncryptionSSEC, CustomerKey: []byte("short")}, expectError: true},
+		{name: "unknown mode", config: EncryptionConfig{Mode: "ROT13"}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGenerateSSECustomerKey(t *testing.T) {
+	key1, err := GenerateSSECustomerKey()
+	require.NoError(t, err)
+	key2, err := GenerateSSECustomerKey()
+	require.NoError(t, err)
+
+	assert.Len(t, key1, SSECustomerKeySize)
+	assert.NotEqual(t, key1, key2)
+}
+
+func TestEncryptionConfig_ApplyUploadEncryption(t *testing.T) {
+	t.Run("SSE-S3", func(t *testing.T) {
+		input := &s3.PutObjectInput{}
+		DefaultEncryptionConfig().applyUploadEncryption(input)
+
+		assert.Equal(t, types.ServerSideEncryptionAes256, input.ServerSideEncryption)
+		assert.Nil(t, input.SSEKMSKeyId)
+		assert.Nil(t, input.SSECustomerKey)
+	})
+
+	t.Run("SSE-KMS", func(t *testing.T) {
+		input := &s3.PutObjectInput{}
+		EncryptionConfig{Mode: models.EncryptionSSEKMS, KMSKeyID: "alias/app"}.applyUploadEncryption(input)
+
+		assert.Equal(t, types.ServerSideEncryptionAwsKms, input.ServerSideEncryption)
+		require.NotNil(t, input.SSEKMSKeyId)
+		assert.Equal(t, "alias/app", *input.SSEKMSKeyId)
+	})
+
+	t.Run("SSE-C", func(t *testing.T) {
+		key := testCustomerKey()
+		input := &s3.PutObjectInput{}
+		EncryptionConfig{Mode: models.EncryptionSSEC, CustomerKey: key}.applyUploadEncryption(input)
+
+		// SSE-C must not also request S3-managed encryption
+		assert.Empty(t, input.ServerSideEncryption)
+		require.NotNil(t, input.SSECustomerKey)
+		require.NotNil(t, input.SSECustomerKeyMD5)
+		assert.Equal(t, "AES256", *input.SSECustomerAlgorithm)
+
+		decoded, err := base64.StdEncoding.DecodeString(*input.SSECustomerKey)
+		require.NoError(t, err)
+		assert.Equal(t, key, decoded)
+
+		digest := md5.Sum(key)
+		assert.Equal(t, base64.StdEncoding.EncodeToString(digest[:]), *input.SSECustomerKeyMD5)
+	})
+}
+
+func TestS3ServiceImpl_SetEncryption(t *testing.T) {
+	service, err := NewS3Service(createTestS3CredentialProvider(), "test-bucket")
+	require.NoError(t, err)
+
+	assert.Equal(t, models.EncryptionSSES3, service.EncryptionMode())
+
+	err = service.SetEncryption(EncryptionConfig{Mode: models.EncryptionSSEKMS})
+	assert.Error(t, err)
+	assert.Equal(t, models.EncryptionSSES3, service.EncryptionMode())
+
+	require.NoError(t, service.SetEncryption(EncryptionConfig{Mode: models.EncryptionSSEC, CustomerKey: testCustomerKey()}))
+	assert.Equal(t, models.EncryptionSSEC, service.EncryptionMode())
+}
+
+func TestS3ServiceImpl_GeneratePresignedDownload(t *testing.T) {
+	ctx := context.Background()
+	service, err := NewS3Service(createTestS3CredentialProvider(), "test-bucket")
+	require.NoError(t, err)
+
+	// Without a customer key SSE-C objects cannot be presigned
+	_, err = service.GeneratePresignedDownload(ctx, "uploads/file.txt", time.Hour, models.EncryptionSSEC)
+	assert.Error(t, err)
+
+	require.NoError(t, service.SetEncryption(EncryptionConfig{Mode: models.EncryptionSSEC, CustomerKey: testCustomerKey()}))
+
+	download, err := service.GeneratePresignedDownload(ctx, "uploads/file.txt", time.Hour, models.EncryptionSSES3)
+	require.NoError(t, err)
+	assert.Contains(t, download.URL, "uploads/file.txt")
+	assert.Empty(t, download.Headers)
+
+	download, err = service.GeneratePresignedDownload(ctx, "uploads/file.txt", time.Hour, models.EncryptionSSEC)
+	require.NoError(t, err)
+	assert.Equal(t, "AES256", download.Headers.Get(headerSSECustomerAlgorithm))
+	assert.NotEmpty(t, download.Headers.Get(headerSSECustomerKey))
+	assert.NotEmpty(t, download.Headers.Get(headerSSECustomerKeyMD5))
+
+	// The key headers are part of the signature, so the URL is useless without them
+	assert.Contains(t, strings.ToLower(download.URL), "x-amz-server-side-encryption-customer-algorithm")
+}
@@ -0,0 +1,504 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+
+	"file-sharing-app/internal/models"
+	"file-sharing-app/pkg/logger"
+)
+
+// Names of the checks in a bucket health report
+const (
+	HealthCheckEncryption        = "Default encryption"
+	HealthCheckPublicAccessBlock = "Public access block"
+	HealthCheckVersioning        = "Versioning"
+	HealthCheckIAMRequired       = "IAM required permissions"
+	HealthCheckIAMLeastPrivilege = "IAM least privilege"
+	healthCheckLifecyclePrefix   = "Lifecycle rule "
+	stackUpdateRemediation       = "Re-run Provision Bucket for this profile, or redeploy infrastructure/cloudformation/file-sharing-app.yaml."
+)
+
+// minExpirationDays is the shortest lifecycle expiration that still outlives a file with each
+// expiration tag. S3 lifecycle rules work in whole days, so one-hour files expire after a day.
+var minExpirationDays = map[string]int32{
+	ExpirationTagOneHour:  1,
+	ExpirationTagOneDay:   1,
+	ExpirationTagOneWeek:  7,
+	ExpirationTagOneMonth: 30,
+}
+
+// Permissions the app uses, by the resource they apply to
+var (
+	requiredObjectActions = []string{"s3:PutObject", "s3:PutObjectTagging", "s3:GetObject", "s3:DeleteObject"}
+	requiredBucketActions = []string{"s3:ListBucket"}
+)
+
+// Permissions the app never needs and that could defeat expiration or expose the bucket
+var (
+	excessObjectActions = []string{"s3:DeleteObjectVersion", "s3:PutObjectAcl", "s3:PutObjectVersionAcl"}
+	excessBucketActions = []string{
+		"s3:DeleteBucket",
+		"s3:PutBucketPolicy",
+		"s3:DeleteBucketPolicy",
+		"s3:PutBucketAcl",
+		"s3:PutLifecycleConfiguration",
+		"s3:PutEncryptionConfiguration",
+		"s3:PutBucketPublicAccessBlock",
+		"s3:PutBucketVersioning",
+	}
+	excessAccountActions = []string{"iam:CreateAccessKey", "iam:CreateUser", "iam:PutUserPolicy", "iam:AttachUserPolicy"}
+)
+
+// BucketConfigAPI is the subset of the S3 client used to read bucket configuration
+type BucketConfigAPI interface {
+	GetBucketLifecycleConfiguration(ctx context.Context, params *s3.GetBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLifecycleConfigurationOutput, error)
+	GetBucketEncryption(ctx context.Context, params *s3.GetBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error)
+	GetPublicAccessBlock(ctx context.Context, params *s3.GetPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.GetPublicAccessBlockOutput, error)
+	GetBucketVersioning(ctx context.Context, params *s3.GetBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error)
+}
+
+// PolicySimulatorAPI is the subset of the IAM client used to evaluate the identity's permissions
+type PolicySimulatorAPI interface {
+	SimulatePrincipalPolicy(ctx context.Context, params *iam.SimulatePrincipalPolicyInput, optFns ...func(*iam.Options)) (*iam.SimulatePrincipalPolicyOutput, error)
+}
+
+// CallerIdentityAPI is the subset of the STS client used to find the identity being checked
+type CallerIdentityAPI interface {
+	GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
+}
+
+// HealthChecker diagnoses the configuration of the application's bucket
+type HealthChecker interface {
+	// CheckBucketHealth runs every check and reports each one as passed or failed.
+	// An error is only returned if the checks could not be run at all.
+	CheckBucketHealth(ctx context.Context) (*models.HealthReport, error)
+}
+
+// BucketHealthChecker implements HealthChecker against the bucket and IAM identity of a profile
+type BucketHealthChecker struct {
+	s3Client  BucketConfigAPI
+	iamClient PolicySimulatorAPI
+	stsClient CallerIdentityAPI
+	bucket    string
+	region    string
+	logger    *logger.Logger
+}
+
+// NewBucketHealthChecker creates a health checker using the provider's credentials
+func NewBucketHealthChecker(credProvider CredentialProvider, bucket string) (*BucketHealthChecker, error) {
+	if bucket == "" {
+		return nil, fmt.Errorf("bucket name cannot be empty")
+	}
+
+	ctx := context.Background()
+
+	creds, err := credProvider.GetCredentials(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AWS credentials: %w", err)
+	}
+
+	region, err := credProvider.GetRegion()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AWS region: %w", err)
+	}
+
+	cfg := aws.Config{
+		Credentials: credentials.StaticCredentialsProvider{
+			Value: creds,
+		},
+		Region:           region,
+		RetryMode:        aws.RetryModeStandard,
+		RetryMaxAttempts: 3,
+	}
+
+	return NewBucketHealthCheckerWithClients(s3.NewFromConfig(cfg), iam.NewFromConfig(cfg), sts.NewFromConfig(cfg), bucket, region), nil
+}
+
+// NewBucketHealthCheckerWithClients creates a health checker around existing clients
+func NewBucketHealthCheckerWithClients(s3Client BucketConfigAPI, iamClient PolicySimulatorAPI, stsClient CallerIdentityAPI, bucket, region string) *BucketHealthChecker {
+	return &BucketHealthChecker{
+		s3Client:  s3Client,
+		iamClient: iamClient,
+		stsClient: stsClient,
+		bucket:    bucket,
+		region:    region,
+		logger:    logger.NewWithComponent("health_checker"),
+	}
+}
+
+// CheckBucketHealth checks lifecycle rules, encryption, public access, versioning and IAM permissions
+func (h *BucketHealthChecker) CheckBucketHealth(ctx context.Context) (*models.HealthReport, error) {
+	report := &models.HealthReport{
+		Bucket:    h.bucket,
+		Region:    h.region,
+		CheckedAt: time.Now(),
+	}
+
+	// The lifecycle rules are needed by both the lifecycle and versioning checks
+	rules, lifecycleErr := h.getLifecycleRules(ctx)
+
+	report.Checks = append(report.Checks, h.checkLifecycleRules(rules, lifecycleErr)...)
+	report.Checks = append(report.Checks, h.checkEncryption(ctx))
+	report.Checks = append(report.Checks, h.checkPublicAccessBlock(ctx))
+	report.Checks = append(report.Checks, h.checkVersioning(ctx, rules, lifecycleErr))
+
+	identity, iamChecks := h.checkIAMPermissions(ctx)
+	report.Identity = identity
+	report.Checks = append(report.Checks, iamChecks...)
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("bucket health check interrupted: %w", err)
+	}
+
+	h.logger.InfoWithFields("Bucket health check completed", map[string]interface{}{
+		"bucket": h.bucket,
+		"failed": len(report.FailedChecks()),
+		"total":  len(report.Checks),
+	})
+
+	return report, nil
+}
+
+// getLifecycleRules returns the bucket's lifecycle rules; a bucket without a configuration has none
+func (h *BucketHealthChecker) getLifecycleRules(ctx context.Context) ([]types.LifecycleRule, error) {
+	output, err := h.s3Client.GetBucketLifecycleConfiguration(ctx, &s3.GetBucketLifecycleConfigurationInput{
+		Bucket: aws.String(h.bucket),
+	})
+	if err != nil {
+		if hasErrorCode(err, "NoSuchLifecycleConfiguration") {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return output.Rules, nil
+}
+
+// checkLifecycleRules reports one check per expiration tag the app puts on uploads
+func (h *BucketHealthChecker) checkLifecycleRules(rules []types.LifecycleRule, lifecycleErr error) []models.HealthCheck {
+	checks := make([]models.HealthCheck, 0, len(ExpirationTags))
+
+	for _, tag := range ExpirationTags {
+		name := fmt.Sprintf("%s%s=%s", healthCheckLifecyclePrefix, ExpirationTagKey, tag)
+
+		if lifecycleErr != nil {
+			checks = append(checks, readFailure(name, "lifecycle configuration", "s3:GetLifecycleConfiguration", lifecycleErr))
+			continue
+		}
+
+		rule := findExpirationRule(rules, tag)
+		switch {
+		case rule == nil:
+			checks = append(checks, failCheck(name,
+				"No enabled lifecycle rule matches this tag, so files uploaded with it are never deleted from S3",
+				fmt.Sprintf("Add an enabled lifecycle rule filtered on tag %s=%s that expires objects after %d day(s). %s",
+					ExpirationTagKey, tag, minExpirationDays[tag], stackUpdateRemediation)))
+		case rule.Expiration == nil || aws.ToInt32(rule.Expiration.Days) == 0:
+			checks = append(checks, failCheck(name,
+				fmt.Sprintf("Rule %s does not expire current objects after a number of days", aws.ToString(rule.ID)),
+				fmt.Sprintf("Set the rule's expiration to %d day(s).", minExpirationDays[tag])))
+		case aws.ToInt32(rule.Expiration.Days) < minExpirationDays[tag]:
+			checks = append(checks, failCheck(name,
+				fmt.Sprintf("Rule %s expires objects after %d day(s), before their share links run out",
+					aws.ToString(rule.ID), aws.ToInt32(rule.Expiration.Days)),
+				fmt.Sprintf("Set the rule's expiration to at least %d day(s).", minExpirationDays[tag])))
+		default:
+			checks = append(checks, passCheck(name,
+				fmt.Sprintf("Rule %s expires objects after %d day(s)", aws.ToString(rule.ID), aws.ToInt32(rule.Expiration.Days))))
+		}
+	}
+
+	return checks
+}
+
+// checkEncryption verifies that objects are encrypted by default
+func (h *BucketHealthChecker) checkEncryption(ctx context.Context) models.HealthCheck {
+	output, err := h.s3Client.GetBucketEncryption(ctx, &s3.GetBucketEncryptionInput{
+		Bucket: aws.String(h.bucket),
+	})
+	if err != nil && !hasErrorCode(err, "ServerSideEncryptionConfigurationNotFoundError") {
+		return readFailure(HealthCheckEncryption, "encryption configuration", "s3:GetEncryptionConfiguration", err)
+	}
+
+	if err == nil && output.ServerSideEncryptionConfiguration != nil {
+		for _, rule := range output.ServerSideEncryptionConfiguration.Rules {
+			if rule.ApplyServerSideEncryptionByDefault != nil {
+				return passCheck(HealthCheckEncryption,
+					fmt.Sprintf("Objects are encrypted with %s by default", rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm))
+			}
+		}
+	}
+
+	return failCheck(HealthCheckEncryption,
+		"Default server-side encryption is not configured",
+		"Enable default encryption (SSE-S3 or SSE-KMS) in the bucket's properties. "+stackUpdateRemediation)
+}
+
+// checkPublicAccessBlock verifies that all four public access block settings are on
+func (h *BucketHealthChecker) checkPublicAccessBlock(ctx context.Context) models.HealthCheck {
+	output, err := h.s3Client.GetPublicAccessBlock(ctx, &s3.GetPublicAccessBlockInput{
+		Bucket: aws.String(h.bucket),
+	})
+	if err != nil && !hasErrorCode(err, "NoSuchPublicAccessBlockConfiguration") {
+		return readFailure(HealthCheckPublicAccessBlock, "public access block", "s3:GetBucketPublicAccessBlock", err)
+	}
+
+	var missing []string
+	var config *types.PublicAccessBlockConfiguration
+	if err == nil {
+		config = output.PublicAccessBlockConfiguration
+	}
+	if config == nil {
+		config = &types.PublicAccessBlockConfiguration{}
+	}
+	if !aws.ToBool(config.BlockPublicAcls) {
+		missing = append(missing, "BlockPublicAcls")
+	}
+	if !aws.ToBool(config.IgnorePublicAcls) {
+		missing = append(missing, "IgnorePublicAcls")
+	}
+	if !aws.ToBool(config.BlockPublicPolicy) {
+		missing = append(missing, "BlockPublicPolicy")
+	}
+	if !aws.ToBool(config.RestrictPublicBuckets) {
+		missing = append(missing, "RestrictPublicBuckets")
+	}
+
+	if len(missing) > 0 {
+		return failCheck(HealthCheckPublicAccessBlock,
+			fmt.Sprintf("Public access is not fully blocked: %s off", strings.Join(missing, ", ")),
+			"Turn on \"Block all public access\" in the bucket's permissions. Files are shared through presigned URLs and never need public access.")
+	}
+
+	return passCheck(HealthCheckPublicAccessBlock, "All public access is blocked")
+}
+
+// checkVersioning verifies that versioning cannot keep deleted or expired files alive.
+// On a versioned bucket, deleting an object only hides it behind a delete marker, so every
+// expiration rule must also expire noncurrent versions.
+func (h *BucketHealthChecker) checkVersioning(ctx context.Context, rules []types.LifecycleRule, lifecycleErr error) models.HealthCheck {
+	output, err := h.s3Client.GetBucketVersioning(ctx, &s3.GetBucketVersioningInput{
+		Bucket: aws.String(h.bucket),
+	})
+	if err != nil {
+		return readFailure(HealthCheckVersioning, "versioning status", "s3:GetBucketVersioning", err)
+	}
+
+	// Suspended versioning still keeps the versions written while it was enabled
+	if output.Status != types.BucketVersioningStatusEnabled && output.Status != types.BucketVersioningStatusSuspended {
+		return passCheck(HealthCheckVersioning, "Versioning is off, so deleted files are removed immediately")
+	}
+
+	if lifecycleErr != nil {
+		return readFailure(HealthCheckVersioning, "lifecycle configuration", "s3:GetLifecycleConfiguration", lifecycleErr)
+	}
+
+	var unprotected []string
+	for _, tag := range ExpirationTags {
+		rule := findExpirationRule(rules, tag)
+		if rule == nil || rule.NoncurrentVersionExpiration == nil || aws.ToInt32(rule.NoncurrentVersionExpiration.NoncurrentDays) == 0 {
+			unprotected = append(unprotected, tag)
+		}
+	}
+
+	if len(unprotected) > 0 {
+		return failCheck(HealthCheckVersioning,
+			fmt.Sprintf("Versioning is %s but old versions of files tagged %s are never expired, so deleted files stay in S3",
+				strings.ToLower(string(output.Status)), strings.Join(unprotected, ", ")),
+			"Add a noncurrent version expiration to each expiration lifecycle rule, or suspend versioning and remove old versions. "+stackUpdateRemediation)
+	}
+
+	return passCheck(HealthCheckVersioning,
+		fmt.Sprintf("Versioning is %s and noncurrent versions expire with their files", strings.ToLower(string(output.Status))))
+}
+
+// checkIAMPermissions simulates the identity's policies to confirm it has every permission the
+// app needs and none of the ones it should not have. It returns the ARN of the identity checked.
+func (h *BucketHealthChecker) checkIAMPermissions(ctx context.Context) (string, []models.HealthCheck) {
+	identity, err := h.stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		failure := readFailure(HealthCheckIAMRequired, "caller identity", "sts:GetCallerIdentity", err)
+		return "", []models.HealthCheck{failure, {
+			Name:        HealthCheckIAMLeastPrivilege,
+			Status:      models.HealthCheckFail,
+			Message:     "Could not determine the IAM identity to check",
+			Remediation: failure.Remediation,
+		}}
+	}
+
+	callerARN := aws.ToString(identity.Arn)
+	principalARN, err := principalARNForSimulation(callerARN)
+	if err != nil {
+		remediation := "Use the access key of the IAM user created for the app instead of root or federated credentials."
+		return callerARN, []models.HealthCheck{
+			failCheck(HealthCheckIAMRequired, err.Error(), remediation),
+			failCheck(HealthCheckIAMLeastPrivilege, err.Error(), remediation),
+		}
+	}
+
+	bucketARN := "arn:aws:s3:::" + h.bucket
+	allowed := make(map[string]bool)
+	for _, sim := range []struct {
+		resource string
+		actions  []string
+	}{
+		{resource: bucketARN + "/*", actions: append(append([]string{}, requiredObjectActions...), excessObjectActions...)},
+		{resource: bucketARN, actions: append(append([]string{}, requiredBucketActions...), excessBucketActions...)},
+		{resource: "*", actions: excessAccountActions},
+	} {
+		if err := h.simulate(ctx, principalARN, sim.resource, sim.actions, allowed); err != nil {
+			failure := readFailure(HealthCheckIAMRequired, "IAM policies", "iam:SimulatePrincipalPolicy", err)
+			return callerARN, []models.HealthCheck{failure, {
+				Name:        HealthCheckIAMLeastPrivilege,
+				Status:      models.HealthCheckFail,
+				Message:     "Could not simulate the identity's IAM policies",
+				Remediation: failure.Remediation,
+			}}
+		}
+	}
+
+	var missing, excess []string
+	for _, action := range append(append([]string{}, requiredObjectActions...), requiredBucketActions...) {
+		if !allowed[action] {
+			missing = append(missing, action)
+		}
+	}
+	for _, action := range append(append(append([]string{}, excessObjectActions...), excessBucketActions...), excessAccountActions...) {
+		if allowed[action] {
+			excess = append(excess, action)
+		}
+	}
+
+	checks := make([]models.HealthCheck, 0, 2)
+	if len(missing) > 0 {
+		checks = append(checks, failCheck(HealthCheckIAMRequired,
+			fmt.Sprintf("Missing %s", strings.Join(missing, ", ")),
+			fmt.Sprintf("Allow %s on %s in the user's policy. %s", strings.Join(missing, ", "), bucketARN, stackUpdateRemediation)))
+	} else {
+		checks = append(checks, passCheck(HealthCheckIAMRequired, "All permissions needed by the app are granted"))
+	}
+
+	if len(excess) > 0 {
+		checks = append(checks, failCheck(HealthCheckIAMLeastPrivilege,
+			fmt.Sprintf("Unneeded permissions granted: %s", strings.Join(excess, ", ")),
+			"Remove these actions from the policies attached to "+callerARN+"; the app never uses them and they let the credentials bypass expiration or expose the bucket."))
+	} else {
+		checks = append(checks, passCheck(HealthCheckIAMLeastPrivilege, "No unneeded bucket or IAM permissions are granted"))
+	}
+
+	return callerARN, checks
+}
+
+// simulate evaluates actions against resource for the principal and records which are allowed
+func (h *BucketHealthChecker) simulate(ctx context.Context, principalARN, resource string, actions []string, allowed map[string]bool) error {
+	input := &iam.SimulatePrincipalPolicyInput{
+		PolicySourceArn: aws.String(principalARN),
+		ActionNames:     actions,
+		ResourceArns:    []string{resource},
+	}
+
+	for {
+		output, err := h.iamClient.SimulatePrincipalPolicy(ctx, input)
+		if err != nil {
+			return err
+		}
+
+		for _, result := range output.EvaluationResults {
+			if result.EvalDecision == iamtypes.PolicyEvaluationDecisionTypeAllowed {
+				allowed[aws.ToString(result.EvalActionName)] = true
+			}
+		}
+
+		if !output.IsTruncated || output.Marker == nil {
+			return nil
+		}
+		input.Marker = output.Marker
+	}
+}
+
+// principalARNForSimulation converts a caller ARN into an ARN accepted by SimulatePrincipalPolicy.
+// Assumed-role sessions are simulated as their role; root and federated users cannot be simulated.
+func principalARNForSimulation(callerARN string) (string, error) {
+	// arn:partition:service::account:resource
+	parts := strings.SplitN(callerARN, ":", 6)
+	if len(parts) != 6 {
+		return "", fmt.Errorf("unrecognised identity ARN %q", callerARN)
+	}
+	resource := parts[5]
+
+	switch {
+	case parts[2] == "iam" && strings.HasPrefix(resource, "user/"):
+		return callerARN, nil
+	case parts[2] == "iam" && resource == "root":
+		return "", fmt.Errorf("the profile uses root account credentials, which have unrestricted access")
+	case parts[2] == "sts" && strings.HasPrefix(resource, "assumed-role/"):
+		segments := strings.Split(resource, "/")
+		if len(segments) < 2 {
+			return "", fmt.Errorf("unrecognised assumed role ARN %q", callerARN)
+		}
+		return fmt.Sprintf("arn:%s:iam::%s:role/%s", parts[1], parts[4], segments[1]), nil
+	default:
+		return "", fmt.Errorf("permissions of %s cannot be simulated", callerARN)
+	}
+}
+
+// findExpirationRule returns the enabled lifecycle rule that applies to objects with the expiration tag
+func findExpirationRule(rules []types.LifecycleRule, tag string) *types.LifecycleRule {
+	matches := func(t *types.Tag) bool {
+		return t != nil && aws.ToString(t.Key) == ExpirationTagKey && aws.ToString(t.Value) == tag
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Status != types.ExpirationStatusEnabled || rule.Filter == nil {
+			continue
+		}
+
+		if matches(rule.Filter.Tag) {
+			return rule
+		}
+
+		// Rules combining the tag with other conditions would skip some of the app's uploads
+		if and := rule.Filter.And; and != nil && aws.ToString(and.Prefix) == "" && len(and.Tags) == 1 && matches(&and.Tags[0]) {
+			return rule
+		}
+	}
+
+	return nil
+}
+
+// hasErrorCode reports whether err is an AWS API error with the given code
+func hasErrorCode(err error, code string) bool {
+	return err != nil && strings.Contains(err.Error(), code)
+}
+
+// readFailure describes a check that failed because the configuration could not be read
+func readFailure(name, what, permission string, err error) models.HealthCheck {
+	if hasErrorCode(err, "AccessDenied") {
+		return failCheck(name,
+			fmt.Sprintf("Could not read the %s: access denied", what),
+			fmt.Sprintf("Allow %s for the app's IAM identity so the configuration can be verified. %s", permission, stackUpdateRemediation))
+	}
+
+	return failCheck(name,
+		fmt.Sprintf("Could not read the %s: %v", what, err),
+		"Check the network connection, the bucket name and region in the profile, then run the check again.")
+}
+
+func passCheck(name, message string) models.HealthCheck {
+	return models.HealthCheck{Name: name, Status: models.HealthCheckPass, Message: message}
+}
+
+func failCheck(name, message, remediation string) models.HealthCheck {
+	return models.HealthCheck{Name: name, Status: models.HealthCheckFail, Message: message, Remediation: remediation}
+}
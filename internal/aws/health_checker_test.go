@@ -0,0 +1,303 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"file-sharing-app/internal/models"
+)
+
+// fakeBucketConfig serves a bucket configuration like the one file-sharing-app.yaml creates
+type fakeBucketConfig struct {
+	rules           []types.LifecycleRule
+	lifecycleErr    error
+	encryption      *types.ServerSideEncryptionConfiguration
+	publicAccess    *types.PublicAccessBlockConfiguration
+	publicAccessErr error
+	versioning      types.BucketVersioningStatus
+}
+
+func newHealthyBucketConfig() *fakeBucketConfig {
+	config := &fakeBucketConfig{
+		encryption: &types.ServerSideEncryptionConfiguration{
+			Rules: []types.ServerSideEncryptionRule{{
+				ApplyServerSideEncryptionByDefault: &types.ServerSideEncryptionByDefault{SSEAlgorithm: types.ServerSideEncryptionAes256},
+			}},
+		},
+		publicAccess: &types.PublicAccessBlockConfiguration{
+			BlockPublicAcls:       aws.Bool(true),
+			IgnorePublicAcls:      aws.Bool(true),
+			BlockPublicPolicy:     aws.Bool(true),
+			RestrictPublicBuckets: aws.Bool(true),
+		},
+		versioning: types.BucketVersioningStatusEnabled,
+	}
+
+	for _, tag := range ExpirationTags {
+		config.rules = append(config.rules, expirationRule(tag, minExpirationDays[tag], minExpirationDays[tag]))
+	}
+	return config
+}
+
+func expirationRule(tag string, days, noncurrentDays int32) types.LifecycleRule {
+	rule := types.LifecycleRule{
+		ID:     aws.String("Expire-" + tag),
+		Status: types.ExpirationStatusEnabled,
+		Filter: &types.LifecycleRuleFilter{
+			Tag: &types.Tag{Key: aws.String(ExpirationTagKey), Value: aws.String(tag)},
+		},
+		Expiration: &types.LifecycleExpiration{Days: aws.Int32(days)},
+	}
+	if noncurrentDays > 0 {
+		rule.NoncurrentVersionExpiration = &types.NoncurrentVersionExpiration{NoncurrentDays: aws.Int32(noncurrentDays)}
+	}
+	return rule
+}
+
+func (f *fakeBucketConfig) GetBucketLifecycleConfiguration(ctx context.Context, params *s3.GetBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+	if f.lifecycleErr != nil {
+		return nil, f.lifecycleErr
+	}
+	if f.rules == nil {
+		return nil, fmt.Errorf("api error NoSuchLifecycleConfiguration: The lifecycle configuration does not exist")
+	}
+	return &s3.GetBucketLifecycleConfigurationOutput{Rules: f.rules}, nil
+}
+
+func (f *fakeBucketConfig) GetBucketEncryption(ctx context.Context, params *s3.GetBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error) {
+	if f.encryption == nil {
+		return nil, fmt.Errorf("api error ServerSideEncryptionConfigurationNotFoundError: The server side encryption configuration was not found")
+	}
+	return &s3.GetBucketEncryptionOutput{ServerSideEncryptionConfiguration: f.encryption}, nil
+}
+
+func (f *fakeBucketConfig) GetPublicAccessBlock(ctx context.Context, params *s3.GetPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.GetPublicAccessBlockOutput, error) {
+	if f.publicAccessErr != nil {
+		return nil, f.publicAccessErr
+	}
+	if f.publicAccess == nil {
+		return nil, fmt.Errorf("api error NoSuchPublicAccessBlockConfiguration: The public access block configuration was not found")
+	}
+	return &s3.GetPublicAccessBlockOutput{PublicAccessBlockConfiguration: f.publicAccess}, nil
+}
+
+func (f *fakeBucketConfig) GetBucketVersioning(ctx context.Context, params *s3.GetBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error) {
+	return &s3.GetBucketVersioningOutput{Status: f.versioning}, nil
+}
+
+// fakePolicySimulator allows exactly the listed actions
+type fakePolicySimulator struct {
+	allowed  map[string]bool
+	err      error
+	policies []string
+}
+
+func newFakePolicySimulator(actions ...string) *fakePolicySimulator {
+	allowed := make(map[string]bool)
+	for _, action := range actions {
+		allowed[action] = true
+	}
+	return &fakePolicySimulator{allowed: allowed}
+}
+
+func (f *fakePolicySimulator) SimulatePrincipalPolicy(ctx context.Context, params *iam.SimulatePrincipalPolicyInput, optFns ...func(*iam.Options)) (*iam.SimulatePrincipalPolicyOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.policies = append(f.policies, aws.ToString(params.PolicySourceArn))
+
+	output := &iam.SimulatePrincipalPolicyOutput{}
+	for _, action := range params.ActionNames {
+		decision := iamtypes.PolicyEvaluationDecisionTypeImplicitDeny
+		if f.allowed[action] {
+			decision = iamtypes.PolicyEvaluationDecisionTypeAllowed
+		}
+		output.EvaluationResults = append(output.EvaluationResults, iamtypes.EvaluationResult{
+			EvalActionName: aws.String(action),
+			EvalDecision:   decision,
+		})
+	}
+	return output, nil
+}
+
+type fakeCallerIdentity struct {
+	arn string
+}
+
+func (f *fakeCallerIdentity) GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	return &sts.GetCallerIdentityOutput{Arn: aws.String(f.arn)}, nil
+}
+
+func appUserActions() []string {
+	return append(append([]string{}, requiredObjectActions...), requiredBucketActions...)
+}
+
+func findCheck(t *testing.T, report *models.HealthReport, name string) models.HealthCheck {
+	t.Helper()
+	for _, check := range report.Checks {
+		if check.Name == name {
+			return check
+		}
+	}
+	t.Fatalf("check %q not in report", name)
+	return models.HealthCheck{}
+}
+
+func TestBucketHealthChecker_Healthy(t *testing.T) {
+	simulator := newFakePolicySimulator(appUserActions()...)
+	checker := NewBucketHealthCheckerWithClients(newHealthyBucketConfig(), simulator,
+		&fakeCallerIdentity{arn: "arn:aws:iam::123456789012:user/file-app-user"}, "test-bucket", "us-west-2")
+
+	report, err := checker.CheckBucketHealth(context.Background())
+	require.NoError(t, err)
+
+	assert.True(t, report.Healthy(), report.String())
+	assert.Len(t, report.Checks, len(ExpirationTags)+5)
+	assert.Equal(t, "arn:aws:iam::123456789012:user/file-app-user", report.Identity)
+	assert.Contains(t, report.String(), fmt.Sprintf("%d of %d checks passed", len(report.Checks), len(report.Checks)))
+	for _, policy := range simulator.policies {
+		assert.Equal(t, "arn:aws:iam::123456789012:user/file-app-user", policy)
+	}
+}
+
+func TestBucketHealthChecker_Misconfigured(t *testing.T) {
+	config := newHealthyBucketConfig()
+	config.rules = []types.LifecycleRule{
+		expirationRule(ExpirationTagOneHour, 1, 1),
+		expirationRule(ExpirationTagOneDay, 1, 0),  // keeps noncurrent versions forever
+		expirationRule(ExpirationTagOneWeek, 3, 3), // expires files before their links
+	}
+	config.encryption = nil
+	config.publicAccess.RestrictPublicBuckets = aws.Bool(false)
+
+	// Missing tagging, plus a permission that lets deleted versions be purged
+	simulator := newFakePolicySimulator("s3:PutObject", "s3:GetObject", "s3:DeleteObject", "s3:ListBucket", "s3:DeleteObjectVersion")
+	checker := NewBucketHealthCheckerWithClients(config, simulator,
+		&fakeCallerIdentity{arn: "arn:aws:iam::123456789012:user/file-app-user"}, "test-bucket", "us-west-2")
+
+	report, err := checker.CheckBucketHealth(context.Background())
+	require.NoError(t, err)
+	assert.False(t, report.Healthy())
+
+	assert.True(t, findCheck(t, report, healthCheckLifecyclePrefix+"expiration=1hour").Passed())
+	assert.True(t, findCheck(t, report, healthCheckLifecyclePrefix+"expiration=1day").Passed())
+
+	week := findCheck(t, report, healthCheckLifecyclePrefix+"expiration=1week")
+	assert.False(t, week.Passed())
+	assert.Contains(t, week.Remediation, "at least 7 day(s)")
+
+	month := findCheck(t, report, healthCheckLifecyclePrefix+"expiration=1month")
+	assert.False(t, month.Passed())
+	assert.Contains(t, month.Message, "never deleted")
+
+	encryption := findCheck(t, report, HealthCheckEncryption)
+	assert.False(t, encryption.Passed())
+	assert.NotEmpty(t, encryption.Remediation)
+
+	publicAccess := findCheck(t, report, HealthCheckPublicAccessBlock)
+	assert.False(t, publicAccess.Passed())
+	assert.Contains(t, publicAccess.Message, "RestrictPublicBuckets")
+
+	versioning := findCheck(t, report, HealthCheckVersioning)
+	assert.False(t, versioning.Passed())
+	assert.Contains(t, versioning.Message, "1day, 1month")
+
+	required := findCheck(t, report, HealthCheckIAMRequired)
+	assert.False(t, required.Passed())
+	assert.Equal(t, "Missing s3:PutObjectTagging", required.Message)
+
+	leastPrivilege := findCheck(t, report, HealthCheckIAMLeastPrivilege)
+	assert.False(t, leastPrivilege.Passed())
+	assert.Contains(t, leastPrivilege.Message, "s3:DeleteObjectVersion")
+}
+
+func TestBucketHealthChecker_UnversionedBucket(t *testing.T) {
+	config := newHealthyBucketConfig()
+	config.versioning = ""
+	config.rules = nil
+
+	checker := NewBucketHealthCheckerWithClients(config, newFakePolicySimulator(appUserActions()...),
+		&fakeCallerIdentity{arn: "arn:aws:iam::123456789012:user/file-app-user"}, "test-bucket", "us-west-2")
+
+	report, err := checker.CheckBucketHealth(context.Background())
+	require.NoError(t, err)
+
+	// Without any lifecycle configuration, versioning is not to blame but every rule is missing
+	assert.True(t, findCheck(t, report, HealthCheckVersioning).Passed())
+	for _, tag := range ExpirationTags {
+		assert.False(t, findCheck(t, report, healthCheckLifecyclePrefix+"expiration="+tag).Passed())
+	}
+}
+
+func TestBucketHealthChecker_AccessDenied(t *testing.T) {
+	config := newHealthyBucketConfig()
+	config.lifecycleErr = fmt.Errorf("api error AccessDenied: Access Denied")
+	config.publicAccessErr = fmt.Errorf("api error AccessDenied: Access Denied")
+
+	simulator := newFakePolicySimulator()
+	simulator.err = fmt.Errorf("api error AccessDenied: not authorized to perform iam:SimulatePrincipalPolicy")
+
+	checker := NewBucketHealthCheckerWithClients(config, simulator,
+		&fakeCallerIdentity{arn: "arn:aws:iam::123456789012:user/file-app-user"}, "test-bucket", "us-west-2")
+
+	report, err := checker.CheckBucketHealth(context.Background())
+	require.NoError(t, err)
+
+	lifecycle := findCheck(t, report, healthCheckLifecyclePrefix+"expiration=1day")
+	assert.False(t, lifecycle.Passed())
+	assert.Contains(t, lifecycle.Remediation, "s3:GetLifecycleConfiguration")
+
+	assert.Contains(t, findCheck(t, report, HealthCheckPublicAccessBlock).Remediation, "s3:GetBucketPublicAccessBlock")
+	assert.Contains(t, findCheck(t, report, HealthCheckIAMRequired).Remediation, "iam:SimulatePrincipalPolicy")
+	assert.False(t, findCheck(t, report, HealthCheckIAMLeastPrivilege).Passed())
+	assert.True(t, findCheck(t, report, HealthCheckEncryption).Passed())
+}
+
+func TestBucketHealthChecker_Canceled(t *testing.T) {
+	checker := NewBucketHealthCheckerWithClients(newHealthyBucketConfig(), newFakePolicySimulator(),
+		&fakeCallerIdentity{arn: "arn:aws:iam::123456789012:user/file-app-user"}, "test-bucket", "us-west-2")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := checker.CheckBucketHealth(ctx)
+	assert.Error(t, err)
+}
+
+func TestPrincipalARNForSimulation(t *testing.T) {
+	tests := []struct {
+		name     string
+		arn      string
+		expected string
+		wantErr  bool
+	}{
+		{name: "IAM user", arn: "arn:aws:iam::123456789012:user/file-app-user", expected: "arn:aws:iam::123456789012:user/file-app-user"},
+		{name: "IAM user with path", arn: "arn:aws:iam::123456789012:user/apps/file-app-user", expected: "arn:aws:iam::123456789012:user/apps/file-app-user"},
+		{name: "assumed role", arn: "arn:aws:sts::123456789012:assumed-role/FileAppRole/session", expected: "arn:aws:iam::123456789012:role/FileAppRole"},
+		{name: "root", arn: "arn:aws:iam::123456789012:root", wantErr: true},
+		{name: "federated user", arn: "arn:aws:sts::123456789012:federated-user/bob", wantErr: true},
+		{name: "malformed", arn: "not-an-arn", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := principalARNForSimulation(tt.arn)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, principal)
+		})
+	}
+}
@@ -0,0 +1,396 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+
+	cfntemplate "file-sharing-app/infrastructure/cloudformation"
+	"file-sharing-app/internal/models"
+	"file-sharing-app/pkg/errors"
+	"file-sharing-app/pkg/logger"
+)
+
+// defaultStackPollInterval is how often stack and drift status is polled
+const defaultStackPollInterval = 5 * time.Second
+
+// CloudFormationAPI is the subset of the CloudFormation client used by the provisioner.
+// It allows the provisioner to be tested against a fake or a local stand-in.
+type CloudFormationAPI interface {
+	CreateStack(ctx context.Context, params *cloudformation.CreateStackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.CreateStackOutput, error)
+	UpdateStack(ctx context.Context, params *cloudformation.UpdateStackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.UpdateStackOutput, error)
+	DescribeStacks(ctx context.Context, params *cloudformation.DescribeStacksInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStacksOutput, error)
+	DetectStackDrift(ctx context.Context, params *cloudformation.DetectStackDriftInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DetectStackDriftOutput, error)
+	DescribeStackDriftDetectionStatus(ctx context.Context, params *cloudformation.DescribeStackDriftDetectionStatusInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackDriftDetectionStatusOutput, error)
+	DescribeStackResourceDrifts(ctx context.Context, params *cloudformation.DescribeStackResourceDriftsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackResourceDriftsOutput, error)
+}
+
+// Provisioner creates and inspects the application's AWS infrastructure
+type Provisioner interface {
+	// Provision creates or updates the stack and returns its outputs. progress, if not nil,
+	// is called with each stack status while waiting for the operation to finish.
+	Provision(ctx context.Context, req *models.ProvisionRequest, progress func(status string)) (*models.ProvisionResult, error)
+
+	// DetectDrift runs drift detection on the stack and reports resources that no longer match the template
+	DetectDrift(ctx context.Context, stackName string) (*models.StackDriftReport, error)
+}
+
+// CloudFormationProvisioner implements Provisioner by deploying the embedded file-sharing-app.yaml template
+type CloudFormationProvisioner struct {
+	client       CloudFormationAPI
+	region       string
+	pollInterval time.Duration
+	logger       *logger.Logger
+}
+
+// NewCloudFormationProvisioner creates a provisioner using the request's administrator credentials,
+// falling back to the default AWS credential chain. req.Endpoint, if set, overrides the service endpoint.
+func NewCloudFormationProvisioner(ctx context.Context, req *models.ProvisionRequest) (*CloudFormationProvisioner, error) {
+	if req == nil || req.Region == "" {
+		return nil, fmt.Errorf("region cannot be empty")
+	}
+
+	var cfg aws.Config
+	if req.AdminAccessKey != "" {
+		cfg = aws.Config{
+			Credentials: credentials.NewStaticCredentialsProvider(req.AdminAccessKey, req.AdminSecretKey, ""),
+			Region:      req.Region,
+			RetryMode:   aws.RetryModeStandard,
+		}
+	} else {
+		loaded, err := config.LoadDefaultConfig(ctx, config.WithRegion(req.Region))
+		if err != nil {
+			return nil, errors.WrapError(err, errors.ErrInvalidCredentials, "failed to load AWS credentials for provisioning")
+		}
+		cfg = loaded
+	}
+
+	client := cloudformation.NewFromConfig(cfg, func(o *cloudformation.Options) {
+		if req.Endpoint != "" {
+			o.BaseEndpoint = aws.String(req.Endpoint)
+		}
+	})
+
+	return NewCloudFormationProvisionerWithClient(client, req.Region), nil
+}
+
+// NewCloudFormationProvisionerWithClient creates a provisioner around an existing CloudFormation client
+func NewCloudFormationProvisionerWithClient(client CloudFormationAPI, region string) *CloudFormationProvisioner {
+	return &CloudFormationProvisioner{
+		client:       client,
+		region:       region,
+		pollInterval: defaultStackPollInterval,
+		logger:       logger.NewWithComponent("provisioner"),
+	}
+}
+
+// SetPollInterval changes how often stack status is polled
+func (p *CloudFormationProvisioner) SetPollInterval(interval time.Duration) {
+	p.pollInterval = interval
+}
+
+// Provision creates the stack, or updates it if it already exists, and waits for completion
+func (p *CloudFormationProvisioner) Provision(ctx context.Context, req *models.ProvisionRequest, progress func(status string)) (*models.ProvisionResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, errors.WrapError(err, errors.ErrValidationFailed, "invalid provision request")
+	}
+
+	if progress == nil {
+		progress = func(string) {}
+	}
+
+	exists, err := p.stackExists(ctx, req.StackName)
+	if err != nil {
+		return nil, err
+	}
+
+	parameters := []types.Parameter{
+		{ParameterKey: aws.String(cfntemplate.ParameterBucketName), ParameterValue: aws.String(req.BucketName)},
+		{ParameterKey: aws.String(cfntemplate.ParameterIAMUserName), ParameterValue: aws.String(req.IAMUserName)},
+		{ParameterKey: aws.String(cfntemplate.ParameterEnvironment), ParameterValue: aws.String("production")},
+	}
+	// The template names its IAM user explicitly, which requires CAPABILITY_NAMED_IAM
+	capabilities := []types.Capability{types.CapabilityCapabilityNamedIam}
+	tags := []types.Tag{
+		{Key: aws.String("Application"), Value: aws.String("file-sharing-app")},
+		{Key: aws.String("Environment"), Value: aws.String("production")},
+	}
+
+	p.logger.InfoWithFields("Provisioning stack", map[string]interface{}{
+		"stack":  req.StackName,
+		"bucket": req.BucketName,
+		"region": p.region,
+		"update": exists,
+	})
+
+	if exists {
+		progress("Updating stack " + req.StackName)
+		_, err = p.client.UpdateStack(ctx, &cloudformation.UpdateStackInput{
+			StackName:    aws.String(req.StackName),
+			TemplateBody: aws.String(cfntemplate.Template),
+			Parameters:   parameters,
+			Capabilities: capabilities,
+			Tags:         tags,
+		})
+		if err != nil && !isNoUpdatesError(err) {
+			return nil, p.handleCloudFormationError("update stack", req.StackName, err)
+		}
+	} else {
+		progress("Creating stack " + req.StackName)
+		_, err = p.client.CreateStack(ctx, &cloudformation.CreateStackInput{
+			StackName:    aws.String(req.StackName),
+			TemplateBody: aws.String(cfntemplate.Template),
+			Parameters:   parameters,
+			Capabilities: capabilities,
+			Tags:         tags,
+			OnFailure:    types.OnFailureRollback,
+		})
+		if err != nil {
+			return nil, p.handleCloudFormationError("create stack", req.StackName, err)
+		}
+	}
+
+	stack, err := p.waitForStack(ctx, req.StackName, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ProvisionResult{
+		StackName:   req.StackName,
+		StackStatus: string(stack.StackStatus),
+		Region:      p.region,
+	}
+	for _, output := range stack.Outputs {
+		value := aws.ToString(output.OutputValue)
+		switch aws.ToString(output.OutputKey) {
+		case cfntemplate.OutputBucketName:
+			result.BucketName = value
+		case cfntemplate.OutputBucketRegion:
+			result.Region = value
+		case cfntemplate.OutputIAMUserName:
+			result.IAMUserName = value
+		case cfntemplate.OutputAccessKeyID:
+			result.AccessKeyID = value
+		case cfntemplate.OutputSecretAccessKey:
+			result.SecretAccessKey = value
+		case cfntemplate.OutputShareLinkURL:
+			result.ShareLinkURL = value
+		}
+	}
+
+	if result.BucketName == "" || result.AccessKeyID == "" || result.SecretAccessKey == "" {
+		return nil, errors.NewAppErrorWithContext(
+			errors.ErrAWSServiceError,
+			"stack completed but did not report the bucket name and access key outputs",
+			nil,
+			map[string]interface{}{"stack": req.StackName},
+		)
+	}
+
+	p.logger.InfoWithFields("Stack provisioned", map[string]interface{}{
+		"stack":  req.StackName,
+		"status": result.StackStatus,
+		"bucket": result.BucketName,
+	})
+
+	return result, nil
+}
+
+// DetectDrift runs drift detection on the stack and reports resources that no longer match the template
+func (p *CloudFormationProvisioner) DetectDrift(ctx context.Context, stackName string) (*models.StackDriftReport, error) {
+	if stackName == "" {
+		return nil, fmt.Errorf("stack name cannot be empty")
+	}
+
+	detection, err := p.client.DetectStackDrift(ctx, &cloudformation.DetectStackDriftInput{
+		StackName: aws.String(stackName),
+	})
+	if err != nil {
+		return nil, p.handleCloudFormationError("detect stack drift", stackName, err)
+	}
+
+	var status *cloudformation.DescribeStackDriftDetectionStatusOutput
+	for {
+		status, err = p.client.DescribeStackDriftDetectionStatus(ctx, &cloudformation.DescribeStackDriftDetectionStatusInput{
+			StackDriftDetectionId: detection.StackDriftDetectionId,
+		})
+		if err != nil {
+			return nil, p.handleCloudFormationError("describe drift detection status", stackName, err)
+		}
+
+		if status.DetectionStatus != types.StackDriftDetectionStatusDetectionInProgress {
+			break
+		}
+
+		if err := p.sleep(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if status.DetectionStatus == types.StackDriftDetectionStatusDetectionFailed {
+		return nil, errors.NewAppErrorWithContext(
+			errors.ErrAWSServiceError,
+			fmt.Sprintf("drift detection failed: %s", aws.ToString(status.DetectionStatusReason)),
+			nil,
+			map[string]interface{}{"stack": stackName},
+		)
+	}
+
+	report := &models.StackDriftReport{
+		StackName:        stackName,
+		DriftStatus:      string(status.StackDriftStatus),
+		DriftedResources: []models.ResourceDrift{},
+		CheckedAt:        time.Now(),
+	}
+
+	input := &cloudformation.DescribeStackResourceDriftsInput{
+		StackName: aws.String(stackName),
+		StackResourceDriftStatusFilters: []types.StackResourceDriftStatus{
+			types.StackResourceDriftStatusModified,
+			types.StackResourceDriftStatusDeleted,
+		},
+	}
+	for {
+		drifts, err := p.client.DescribeStackResourceDrifts(ctx, input)
+		if err != nil {
+			return nil, p.handleCloudFormationError("describe resource drifts", stackName, err)
+		}
+
+		for _, drift := range drifts.StackResourceDrifts {
+			report.DriftedResources = append(report.DriftedResources, models.ResourceDrift{
+				LogicalResourceID: aws.ToString(drift.LogicalResourceId),
+				ResourceType:      aws.ToString(drift.ResourceType),
+				DriftStatus:       string(drift.StackResourceDriftStatus),
+			})
+		}
+
+		if drifts.NextToken == nil {
+			break
+		}
+		input.NextToken = drifts.NextToken
+	}
+
+	p.logger.InfoWithFields("Stack drift detection completed", map[string]interface{}{
+		"stack":             stackName,
+		"drift_status":      report.DriftStatus,
+		"drifted_resources": len(report.DriftedResources),
+	})
+
+	return report, nil
+}
+
+// stackExists reports whether a stack with the given name exists
+func (p *CloudFormationProvisioner) stackExists(ctx context.Context, stackName string) (bool, error) {
+	output, err := p.client.DescribeStacks(ctx, &cloudformation.DescribeStacksInput{
+		StackName: aws.String(stackName),
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "does not exist") {
+			return false, nil
+		}
+		return false, p.handleCloudFormationError("describe stack", stackName, err)
+	}
+
+	for _, stack := range output.Stacks {
+		// A stack whose creation rolled back cannot be updated and must be recreated
+		if stack.StackStatus == types.StackStatusRollbackComplete {
+			return false, errors.NewAppErrorWithContext(
+				errors.ErrInvalidState,
+				fmt.Sprintf("stack %s failed to create previously; delete it before provisioning again", stackName),
+				nil,
+				map[string]interface{}{"stack": stackName},
+			)
+		}
+		if stack.StackStatus != types.StackStatusDeleteComplete {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// waitForStack polls the stack until it reaches a terminal status
+func (p *CloudFormationProvisioner) waitForStack(ctx context.Context, stackName string, progress func(status string)) (*types.Stack, error) {
+	lastStatus := types.StackStatus("")
+
+	for {
+		output, err := p.client.DescribeStacks(ctx, &cloudformation.DescribeStacksInput{
+			StackName: aws.String(stackName),
+		})
+		if err != nil {
+			return nil, p.handleCloudFormationError("describe stack", stackName, err)
+		}
+		if len(output.Stacks) == 0 {
+			return nil, fmt.Errorf("stack %s not found", stackName)
+		}
+
+		stack := output.Stacks[0]
+		if stack.StackStatus != lastStatus {
+			lastStatus = stack.StackStatus
+			progress(fmt.Sprintf("Stack %s: %s", stackName, lastStatus))
+		}
+
+		switch stack.StackStatus {
+		case types.StackStatusCreateComplete, types.StackStatusUpdateComplete:
+			return &stack, nil
+		case types.StackStatusCreateFailed, types.StackStatusRollbackComplete, types.StackStatusRollbackFailed,
+			types.StackStatusUpdateRollbackComplete, types.StackStatusUpdateRollbackFailed, types.StackStatusUpdateFailed,
+			types.StackStatusDeleteComplete, types.StackStatusDeleteFailed:
+			return nil, errors.NewAppErrorWithContext(
+				errors.ErrAWSServiceError,
+				fmt.Sprintf("stack %s ended in %s: %s", stackName, stack.StackStatus, aws.ToString(stack.StackStatusReason)),
+				nil,
+				map[string]interface{}{"stack": stackName, "status": string(stack.StackStatus)},
+			)
+		}
+
+		if err := p.sleep(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sleep waits for the poll interval or until the context is done
+func (p *CloudFormationProvisioner) sleep(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return errors.WrapError(ctx.Err(), errors.ErrConnectionTimeout, "stopped waiting for CloudFormation")
+	case <-time.After(p.pollInterval):
+		return nil
+	}
+}
+
+// handleCloudFormationError converts CloudFormation errors to AppErrors
+func (p *CloudFormationProvisioner) handleCloudFormationError(operation, stackName string, err error) error {
+	p.logger.ErrorWithFields("CloudFormation operation failed", map[string]interface{}{
+		"operation": operation,
+		"stack":     stackName,
+	})
+
+	appErr := errors.ClassifyError(err)
+	if appErr.Code != errors.ErrUnknownError {
+		return appErr
+	}
+
+	return errors.NewAppErrorWithContext(
+		errors.ErrAWSServiceError,
+		fmt.Sprintf("CloudFormation operation failed: %s", operation),
+		err,
+		map[string]interface{}{
+			"stack":     stackName,
+			"operation": operation,
+		},
+	)
+}
+
+// isNoUpdatesError reports whether UpdateStack failed only because the stack is already up to date
+func isNoUpdatesError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "No updates are to be performed")
+}
@@ -0,0 +1,320 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cfntemplate "file-sharing-app/infrastructure/cloudformation"
+	"file-sharing-app/internal/models"
+)
+
+// fakeCloudFormation is an in-memory stand-in for the CloudFormation API
+type fakeCloudFormation struct {
+	stackStatuses []types.StackStatus // statuses returned by successive DescribeStacks calls
+	outputs       []types.Output
+	exists        bool
+	updateErr     error
+
+	createInput *cloudformation.CreateStackInput
+	updateInput *cloudformation.UpdateStackInput
+
+	driftPolls     int
+	driftStatus    types.StackDriftStatus
+	resourceDrifts [][]types.StackResourceDrift // pages
+}
+
+func (f *fakeCloudFormation) CreateStack(ctx context.Context, params *cloudformation.CreateStackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.CreateStackOutput, error) {
+	f.createInput = params
+	f.exists = true
+	return &cloudformation.CreateStackOutput{StackId: aws.String("stack-id")}, nil
+}
+
+func (f *fakeCloudFormation) UpdateStack(ctx context.Context, params *cloudformation.UpdateStackInput, optFns ...func(*cloudformation.Options)) (*cloudformation.UpdateStackOutput, error) {
+	f.updateInput = params
+	if f.updateErr != nil {
+		return nil, f.updateErr
+	}
+	return &cloudformation.UpdateStackOutput{StackId: aws.String("stack-id")}, nil
+}
+
+func (f *fakeCloudFormation) DescribeStacks(ctx context.Context, params *cloudformation.DescribeStacksInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStacksOutput, error) {
+	if !f.exists {
+		return nil, fmt.Errorf("api error ValidationError: Stack with id %s does not exist", aws.ToString(params.StackName))
+	}
+
+	status := f.stackStatuses[0]
+	if len(f.stackStatuses) > 1 {
+		f.stackStatuses = f.stackStatuses[1:]
+	}
+
+	return &cloudformation.DescribeStacksOutput{
+		Stacks: []types.Stack{{
+			StackName:         params.StackName,
+			StackStatus:       status,
+			StackStatusReason: aws.String("test reason"),
+			Outputs:           f.outputs,
+		}},
+	}, nil
+}
+
+func (f *fakeCloudFormation) DetectStackDrift(ctx context.Context, params *cloudformation.DetectStackDriftInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DetectStackDriftOutput, error) {
+	return &cloudformation.DetectStackDriftOutput{StackDriftDetectionId: aws.String("detection-id")}, nil
+}
+
+func (f *fakeCloudFormation) DescribeStackDriftDetectionStatus(ctx context.Context, params *cloudformation.DescribeStackDriftDetectionStatusInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackDriftDetectionStatusOutput, error) {
+	f.driftPolls++
+	if f.driftPolls < 2 {
+		return &cloudformation.DescribeStackDriftDetectionStatusOutput{
+			DetectionStatus: types.StackDriftDetectionStatusDetectionInProgress,
+		}, nil
+	}
+	return &cloudformation.DescribeStackDriftDetectionStatusOutput{
+		DetectionStatus:  types.StackDriftDetectionStatusDetectionComplete,
+		StackDriftStatus: f.driftStatus,
+	}, nil
+}
+
+func (f *fakeCloudFormation) DescribeStackResourceDrifts(ctx context.Context, params *cloudformation.DescribeStackResourceDriftsInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackResourceDriftsOutput, error) {
+	page := 0
+	if params.NextToken != nil {
+		fmt.Sscanf(aws.ToString(params.NextToken), "%d", &page)
+	}
+
+	output := &cloudformation.DescribeStackResourceDriftsOutput{}
+	if page < len(f.resourceDrifts) {
+		output.StackResourceDrifts = f.resourceDrifts[page]
+	}
+	if page+1 < len(f.resourceDrifts) {
+		output.NextToken = aws.String(fmt.Sprintf("%d", page+1))
+	}
+	return output, nil
+}
+
+func testStackOutputs() []types.Output {
+	return []types.Output{
+		{OutputKey: aws.String(cfntemplate.OutputBucketName), OutputValue: aws.String("client-a-bucket")},
+		{OutputKey: aws.String(cfntemplate.OutputBucketRegion), OutputValue: aws.String("eu-west-1")},
+		{OutputKey: aws.String(cfntemplate.OutputIAMUserName), OutputValue: aws.String("client-a-user")},
+		{OutputKey: aws.String(cfntemplate.OutputAccessKeyID), OutputValue: aws.String("AKIATEST")},
+		{OutputKey: aws.String(cfntemplate.OutputSecretAccessKey), OutputValue: aws.String("secret")},
+		{OutputKey: aws.String(cfntemplate.OutputShareLinkURL), OutputValue: aws.String("https://abc123.lambda-url.eu-west-1.on.aws/")},
+	}
+}
+
+func testProvisionRequest() *models.ProvisionRequest {
+	return &models.ProvisionRequest{
+		ProfileName: "client-a",
+		StackName:   "client-a-stack",
+		BucketName:  "client-a-bucket",
+		IAMUserName: "client-a-user",
+		Region:      "eu-west-1",
+	}
+}
+
+func TestCloudFormationProvisioner_ProvisionCreatesStack(t *testing.T) {
+	fake := &fakeCloudFormation{
+		stackStatuses: []types.StackStatus{types.StackStatusCreateInProgress, types.StackStatusCreateComplete},
+		outputs:       testStackOutputs(),
+	}
+	provisioner := NewCloudFormationProvisionerWithClient(fake, "eu-west-1")
+	provisioner.SetPollInterval(time.Millisecond)
+
+	var progress []string
+	result, err := provisioner.Provision(context.Background(), testProvisionRequest(), func(status string) {
+		progress = append(progress, status)
+	})
+	require.NoError(t, err)
+
+	// The embedded template is deployed with the request parameters
+	require.NotNil(t, fake.createInput)
+	assert.Equal(t, cfntemplate.Template, aws.ToString(fake.createInput.TemplateBody))
+	assert.Contains(t, fake.createInput.Capabilities, types.CapabilityCapabilityNamedIam)
+	parameters := map[string]string{}
+	for _, p := range fake.createInput.Parameters {
+		parameters[aws.ToString(p.ParameterKey)] = aws.ToString(p.ParameterValue)
+	}
+	assert.Equal(t, "client-a-bucket", parameters[cfntemplate.ParameterBucketName])
+	assert.Equal(t, "client-a-user", parameters[cfntemplate.ParameterIAMUserName])
+
+	assert.Equal(t, "client-a-bucket", result.BucketName)
+	assert.Equal(t, "eu-west-1", result.Region)
+	assert.Equal(t, "AKIATEST", result.AccessKeyID)
+	assert.Equal(t, "secret", result.SecretAccessKey)
+	assert.Equal(t, "https://abc123.lambda-url.eu-west-1.on.aws/", result.ShareLinkURL)
+	assert.Equal(t, string(types.StackStatusCreateComplete), result.StackStatus)
+
+	assert.Contains(t, progress, "Creating stack client-a-stack")
+	assert.Contains(t, progress, "Stack client-a-stack: CREATE_COMPLETE")
+}
+
+func TestCloudFormationProvisioner_ProvisionUpdatesExistingStack(t *testing.T) {
+	fake := &fakeCloudFormation{
+		exists:        true,
+		stackStatuses: []types.StackStatus{types.StackStatusCreateComplete, types.StackStatusUpdateComplete},
+		outputs:       testStackOutputs(),
+		updateErr:     fmt.Errorf("api error ValidationError: No updates are to be performed."),
+	}
+	provisioner := NewCloudFormationProvisionerWithClient(fake, "eu-west-1")
+	provisioner.SetPollInterval(time.Millisecond)
+
+	result, err := provisioner.Provision(context.Background(), testProvisionRequest(), nil)
+	require.NoError(t, err)
+
+	assert.Nil(t, fake.createInput)
+	require.NotNil(t, fake.updateInput)
+	assert.Equal(t, "AKIATEST", result.AccessKeyID)
+}
+
+func TestCloudFormationProvisioner_ProvisionFailure(t *testing.T) {
+	fake := &fakeCloudFormation{
+		stackStatuses: []types.StackStatus{types.StackStatusCreateInProgress, types.StackStatusRollbackInProgress, types.StackStatusRollbackComplete},
+	}
+	provisioner := NewCloudFormationProvisionerWithClient(fake, "eu-west-1")
+	provisioner.SetPollInterval(time.Millisecond)
+
+	_, err := provisioner.Provision(context.Background(), testProvisionRequest(), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ROLLBACK_COMPLETE")
+	assert.Contains(t, err.Error(), "test reason")
+}
+
+func TestCloudFormationProvisioner_ProvisionInvalidRequest(t *testing.T) {
+	provisioner := NewCloudFormationProvisionerWithClient(&fakeCloudFormation{}, "eu-west-1")
+
+	req := testProvisionRequest()
+	req.BucketName = "Invalid_Bucket"
+
+	_, err := provisioner.Provision(context.Background(), req, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid provision request")
+}
+
+func TestCloudFormationProvisioner_ProvisionCanceled(t *testing.T) {
+	fake := &fakeCloudFormation{
+		stackStatuses: []types.StackStatus{types.StackStatusCreateInProgress},
+	}
+	provisioner := NewCloudFormationProvisionerWithClient(fake, "eu-west-1")
+	provisioner.SetPollInterval(time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := provisioner.Provision(ctx, testProvisionRequest(), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stopped waiting")
+}
+
+func TestCloudFormationProvisioner_DetectDrift(t *testing.T) {
+	fake := &fakeCloudFormation{
+		driftStatus: types.StackDriftStatusDrifted,
+		resourceDrifts: [][]types.StackResourceDrift{
+			{{
+				LogicalResourceId:        aws.String("FileStorageBucket"),
+				ResourceType:             aws.String("AWS::S3::Bucket"),
+				StackResourceDriftStatus: types.StackResourceDriftStatusModified,
+			}},
+			{{
+				LogicalResourceId:        aws.String("FileAppUserPolicy"),
+				ResourceType:             aws.String("AWS::IAM::Policy"),
+				StackResourceDriftStatus: types.StackResourceDriftStatusDeleted,
+			}},
+		},
+	}
+	provisioner := NewCloudFormationProvisionerWithClient(fake, "eu-west-1")
+	provisioner.SetPollInterval(time.Millisecond)
+
+	report, err := provisioner.DetectDrift(context.Background(), "client-a-stack")
+	require.NoError(t, err)
+
+	assert.True(t, report.IsDrifted())
+	assert.Equal(t, "DRIFTED", report.DriftStatus)
+	require.Len(t, report.DriftedResources, 2)
+	assert.Equal(t, "FileStorageBucket", report.DriftedResources[0].LogicalResourceID)
+	assert.Equal(t, "MODIFIED", report.DriftedResources[0].DriftStatus)
+	assert.Equal(t, "FileAppUserPolicy", report.DriftedResources[1].LogicalResourceID)
+	assert.Equal(t, 2, fake.driftPolls)
+}
+
+func TestCloudFormationProvisioner_DetectDriftInSync(t *testing.T) {
+	fake := &fakeCloudFormation{driftStatus: types.StackDriftStatusInSync}
+	provisioner := NewCloudFormationProvisionerWithClient(fake, "eu-west-1")
+	provisioner.SetPollInterval(time.Millisecond)
+
+	report, err := provisioner.DetectDrift(context.Background(), "client-a-stack")
+	require.NoError(t, err)
+	assert.False(t, report.IsDrifted())
+	assert.Empty(t, report.DriftedResources)
+}
+
+// TestCloudFormationProvisioner_LocalEndpoint runs a provision against a local HTTP
+// stand-in speaking the CloudFormation query protocol, as LocalStack would.
+func TestCloudFormationProvisioner_LocalEndpoint(t *testing.T) {
+	created := false
+	var actions []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		action := r.Form.Get("Action")
+		actions = append(actions, action)
+
+		w.Header().Set("Content-Type", "text/xml")
+		switch {
+		case action == "DescribeStacks" && !created:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `<ErrorResponse><Error><Type>Sender</Type><Code>ValidationError</Code><Message>Stack with id local-stack does not exist</Message></Error><RequestId>1</RequestId></ErrorResponse>`)
+		case action == "CreateStack":
+			assert.Equal(t, "local-stack", r.Form.Get("StackName"))
+			assert.True(t, strings.Contains(r.Form.Get("TemplateBody"), "AWS::S3::Bucket"))
+			created = true
+			fmt.Fprint(w, `<CreateStackResponse><CreateStackResult><StackId>local-stack-id</StackId></CreateStackResult><ResponseMetadata><RequestId>2</RequestId></ResponseMetadata></CreateStackResponse>`)
+		case action == "DescribeStacks":
+			fmt.Fprint(w, `<DescribeStacksResponse><DescribeStacksResult><Stacks><member>
+<StackName>local-stack</StackName><StackStatus>CREATE_COMPLETE</StackStatus><CreationTime>2025-01-01T00:00:00Z</CreationTime>
+<Outputs>
+<member><OutputKey>BucketName</OutputKey><OutputValue>local-bucket</OutputValue></member>
+<member><OutputKey>BucketRegion</OutputKey><OutputValue>us-east-1</OutputValue></member>
+<member><OutputKey>AccessKeyId</OutputKey><OutputValue>AKIALOCAL</OutputValue></member>
+<member><OutputKey>SecretAccessKey</OutputKey><OutputValue>local-secret</OutputValue></member>
+</Outputs>
+</member></Stacks></DescribeStacksResult><ResponseMetadata><RequestId>3</RequestId></ResponseMetadata></DescribeStacksResponse>`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `<ErrorResponse><Error><Code>InvalidAction</Code><Message>unexpected action %s</Message></Error></ErrorResponse>`, action)
+		}
+	}))
+	defer server.Close()
+
+	req := &models.ProvisionRequest{
+		ProfileName:    "local",
+		StackName:      "local-stack",
+		BucketName:     "local-bucket",
+		IAMUserName:    "local-user",
+		Region:         "us-east-1",
+		AdminAccessKey: "test",
+		AdminSecretKey: "test",
+		Endpoint:       server.URL,
+	}
+
+	provisioner, err := NewCloudFormationProvisioner(context.Background(), req)
+	require.NoError(t, err)
+	provisioner.SetPollInterval(time.Millisecond)
+
+	result, err := provisioner.Provision(context.Background(), req, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"DescribeStacks", "CreateStack", "DescribeStacks"}, actions)
+	assert.Equal(t, "local-bucket", result.BucketName)
+	assert.Equal(t, "AKIALOCAL", result.AccessKeyID)
+	assert.Equal(t, "local-secret", result.SecretAccessKey)
+}
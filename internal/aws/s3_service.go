@@ -1,24 +1,52 @@
 package aws
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"hash"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 
+	"file-sharing-app/internal/models"
 	"file-sharing-app/pkg/errors"
 	"file-sharing-app/pkg/logger"
 )
 
+// Object tag read by the bucket lifecycle rules in file-sharing-app.yaml
+const ExpirationTagKey = "expiration"
+
+// Expiration tag values, one per lifecycle rule
+const (
+	ExpirationTagOneHour  = "1hour"
+	ExpirationTagOneDay   = "1day"
+	ExpirationTagOneWeek  = "1week"
+	ExpirationTagOneMonth = "1month"
+)
+
+// ExpirationTags lists every expiration tag value the app can put on an object
+var ExpirationTags = []string{
+	ExpirationTagOneHour,
+	ExpirationTagOneDay,
+	ExpirationTagOneWeek,
+	ExpirationTagOneMonth,
+}
+
 // UploadProgress represents the progress of a file upload
 type UploadProgress struct {
 	BytesUploaded int64   `json:"bytes_uploaded"`
@@ -26,17 +54,52 @@ type UploadProgress struct {
 	Percentage    float64 `json:"percentage"`
 }
 
+// UploadResult describes an uploaded object
+type UploadResult struct {
+	ETag           string `json:"etag"`            // ETag returned by S3, without quotes
+	ChecksumSHA256 string `json:"checksum_sha256"` // hex SHA-256 of the uploaded content
+	Size           int64  `json:"size"`            // bytes uploaded
+}
+
+// PresignedDownload is a presigned GET request together with the headers that must be sent with it.
+// Headers is empty unless the object was encrypted with a customer-provided key.
+type PresignedDownload struct {
+	URL     string      `json:"url"`
+	Headers http.Header `json:"-"`
+}
+
 // S3Service defines the interface for S3 operations
 type S3Service interface {
-	// UploadFile uploads a file to S3 with optional progress tracking
-	UploadFile(ctx context.Context, key string, filePath string, metadata map[string]string, progressCh chan<- UploadProgress) error
+	// UploadFile uploads a file to S3 with optional progress tracking and returns its ETag and SHA-256 checksum
+	UploadFile(ctx context.Context, key string, filePath string, metadata map[string]string, progressCh chan<- UploadProgress) (*UploadResult, error)
 	
 	// GeneratePresignedURL generates a presigned URL for downloading a file
 	GeneratePresignedURL(ctx context.Context, key string, expiration time.Duration) (string, error)
 	
+	// GeneratePresignedURLAt generates a presigned URL signed as of signedAt. The same arguments and
+	// credentials always produce the same URL, so a link can be reproduced instead of stored.
+	// The overrides are signed into the URL.
+	GeneratePresignedURLAt(ctx context.Context, key string, signedAt time.Time, expiration time.Duration, overrides ResponseOverrides) (string, error)
+	
+	// GeneratePresignedDownload generates a presigned download for an object uploaded with the given encryption mode
+	GeneratePresignedDownload(ctx context.Context, key string, expiration time.Duration, encryptionMode string) (*PresignedDownload, error)
+	
+	// EncryptionMode returns the server-side encryption mode applied to uploads
+	EncryptionMode() string
+	
 	// DeleteObject deletes an object from S3
 	DeleteObject(ctx context.Context, key string) error
 	
+	// ExtendExpiration retags an object so the bucket lifecycle keeps it at least as long as
+	// the expiration tag says; objects tagged to live longer already are left alone
+	ExtendExpiration(ctx context.Context, key string, expirationTag string) error
+	
+	// PutShareLink stores the record the redirect function reads to resolve a short share link
+	PutShareLink(ctx context.Context, link *ShareLink) error
+	
+	// DeleteShareLink removes a short share link's record, so the link stops working
+	DeleteShareLink(ctx context.Context, token string) error
+	
 	// HeadObject retrieves metadata about an object without downloading it
 	HeadObject(ctx context.Context, key string) (*s3.HeadObjectOutput, error)
 	
@@ -46,12 +109,13 @@ type S3Service interface {
 
 // S3ServiceImpl implements S3Service using AWS SDK v2
 type S3ServiceImpl struct {
-	client    *s3.Client
-	presigner *s3.PresignClient
-	uploader  *manager.Uploader
-	bucket    string
-	region    string
-	logger    *logger.Logger
+	client     *s3.Client
+	presigner  *s3.PresignClient
+	uploader   *manager.Uploader
+	bucket     string
+	region     string
+	encryption EncryptionConfig
+	logger     *logger.Logger
 }
 
 // NewS3Service creates a new S3Service instance
@@ -101,18 +165,44 @@ func NewS3Service(credProvider CredentialProvider, bucket string) (*S3ServiceImp
 	})
 
 	return &S3ServiceImpl{
-		client:    client,
-		presigner: presigner,
-		uploader:  uploader,
-		bucket:    bucket,
-		region:    region,
-		logger:    logger.NewWithComponent("s3_service"),
+		client:     client,
+		presigner:  presigner,
+		uploader:   uploader,
+		bucket:     bucket,
+		region:     region,
+		encryption: DefaultEncryptionConfig(),
+		logger:     logger.NewWithComponent("s3_service"),
 	}, nil
 }
 
-// UploadFile uploads a file to S3 with progress tracking and chunking for large files
-func (s *S3ServiceImpl) UploadFile(ctx context.Context, key string, filePath string, metadata map[string]string, progressCh chan<- UploadProgress) error {
-	return s.logger.LogOperation("upload_file", func() error {
+// SetEncryption sets the server-side encryption applied to uploads and SSE-C reads
+func (s *S3ServiceImpl) SetEncryption(cfg EncryptionConfig) error {
+	if cfg.Mode == "" {
+		cfg.Mode = DefaultEncryptionConfig().Mode
+	}
+	if err := cfg.Validate(); err != nil {
+		return errors.NewAppError(errors.ErrInvalidInput, "invalid encryption configuration", err)
+	}
+	s.encryption = cfg
+	return nil
+}
+
+// EncryptionMode returns the server-side encryption mode applied to uploads
+func (s *S3ServiceImpl) EncryptionMode() string {
+	return s.encryption.Mode
+}
+
+// UploadFile uploads a file to S3 with progress tracking and chunking for large files.
+// Reads of the file are paced by the bandwidth limiters added to ctx with WithBandwidthLimiter;
+// uploads limited when they start are sent in small parts, so the limit holds on the network.
+// The SHA-256 of the content is computed while it streams and checked against the
+// checksum S3 validated on receipt, so a returned result is known to match the file.
+// The object is stored with the content type in the "content-type" metadata entry, if
+// there is one, and otherwise with the type detected from the file's name and content.
+// A file already compressed is marked with its compression in the "content-encoding" entry.
+func (s *S3ServiceImpl) UploadFile(ctx context.Context, key string, filePath string, metadata map[string]string, progressCh chan<- UploadProgress) (*UploadResult, error) {
+	var result *UploadResult
+	err := s.logger.LogOperation("upload_file", func() error {
 		if key == "" {
 			return errors.NewAppError(errors.ErrInvalidInput, "S3 object key cannot be empty", nil)
 		}
@@ -145,22 +235,31 @@ func (s *S3ServiceImpl) UploadFile(ctx context.Context, key string, filePath str
 			return errors.NewAppError(errors.ErrFileEmpty, "file is empty", nil)
 		}
 
+		// Use the content type the caller detected, or detect it from the name and content
+		contentType := metadata["content-type"]
+		delete(metadata, "content-type")
+		if contentType == "" {
+			contentType, err = detectContentType(file, filePath)
+			if err != nil {
+				return errors.WrapError(err, errors.ErrInvalidFilePath, "failed to read file content")
+			}
+		}
+
 		s.logger.InfoWithFields("File validated for upload", map[string]interface{}{
 			"file_size_bytes": fileSize,
-			"content_type":    getContentType(filePath),
+			"content_type":    contentType,
 		})
 
-		// Determine content type based on file extension
-		contentType := getContentType(filePath)
-
 		// Create progress reader if progress channel is provided
-		var reader io.Reader = file
-		if progressCh != nil {
-			reader = &progressReader{
-				reader:     file,
-				totalBytes: fileSize,
-				progressCh: progressCh,
-			}
+		// Always read through progressReader so the content is hashed exactly once as it streams,
+		// and paced by any bandwidth limiters in the context
+		reader := &progressReader{
+			reader:     file,
+			totalBytes: fileSize,
+			progressCh: progressCh,
+			hash:       sha256.New(),
+			ctx:        ctx,
+			limiters:   bandwidthLimiters(ctx),
 		}
 
 		// Prepare metadata
@@ -170,7 +269,17 @@ func (s *S3ServiceImpl) UploadFile(ctx context.Context, key string, filePath str
 		
 		// Add upload timestamp to metadata
 		metadata["upload-timestamp"] = time.Now().UTC().Format(time.RFC3339)
-		metadata["original-filename"] = filepath.Base(filePath)
+
+		// Objects are stored under generated keys, so downloads are named after the original file
+		downloadName := metadata["original-name"]
+		if downloadName == "" {
+			downloadName = filepath.Base(filePath)
+			metadata["original-name"] = downloadName
+		}
+
+		// Compressed uploads are decompressed by browsers as they download
+		contentEncoding := metadata["content-encoding"]
+		delete(metadata, "content-encoding")
 
 		// Prepare tags for S3 lifecycle policies
 		var tags []types.Tag
@@ -178,7 +287,7 @@ func (s *S3ServiceImpl) UploadFile(ctx context.Context, key string, filePath str
 		// Add expiration tag if provided in metadata
 		if expirationTag, exists := metadata["expiration-tag"]; exists {
 			tags = append(tags, types.Tag{
-				Key:   aws.String("expiration"),
+				Key:   aws.String(ExpirationTagKey),
 				Value: aws.String(expirationTag),
 			})
 			// Remove from metadata since it's now a tag
@@ -193,18 +302,34 @@ func (s *S3ServiceImpl) UploadFile(ctx context.Context, key string, filePath str
 
 		// Create upload input
 		input := &s3.PutObjectInput{
-			Bucket:      aws.String(s.bucket),
-			Key:         aws.String(key),
-			Body:        reader,
-			ContentType: aws.String(contentType),
-			Metadata:    metadata,
-			Tagging:     aws.String(formatTagsForUpload(tags)),
-			// Enable server-side encryption
-			ServerSideEncryption: types.ServerSideEncryptionAes256,
+			Bucket:             aws.String(s.bucket),
+			Key:                aws.String(key),
+			Body:               reader,
+			ContentType:        aws.String(contentType),
+			ContentDisposition: aws.String(AttachmentDisposition(downloadName)),
+			Metadata:           metadata,
+			Tagging:            aws.String(formatTagsForUpload(tags)),
+			// Have S3 verify a SHA-256 of the body on receipt
+			ChecksumAlgorithm: types.ChecksumAlgorithmSha256,
 		}
+		
+		if contentEncoding != "" {
+			input.ContentEncoding = aws.String(contentEncoding)
+		}
+		
+		// Apply the configured server-side encryption
+		s.encryption.applyUploadEncryption(input)
 
 		// Use uploader for chunking (automatically handles multipart uploads for files >5MB)
-		_, err = s.uploader.Upload(ctx, input)
+		var uploadOptions []func(*manager.Uploader)
+		if anyActive(reader.limiters) {
+			// Parts are buffered before they are sent, so throttled uploads use small ones
+			partSize := throttledPartSizeFor(fileSize)
+			uploadOptions = append(uploadOptions, func(u *manager.Uploader) {
+				u.PartSize = partSize
+			})
+		}
+		output, err := s.uploader.Upload(ctx, input, uploadOptions...)
 		if err != nil {
 			s.logger.ErrorWithFields("Upload failed", map[string]interface{}{
 				"s3_key": key,
@@ -213,6 +338,22 @@ func (s *S3ServiceImpl) UploadFile(ctx context.Context, key string, filePath str
 			return s.handleS3Error("upload file", err)
 		}
 
+		checksum := hex.EncodeToString(reader.hash.Sum(nil))
+		if remote := checksumFromS3(output.ChecksumSHA256); remote != "" && remote != checksum {
+			s.logger.ErrorWithFields("Upload checksum mismatch", map[string]interface{}{
+				"s3_key":          key,
+				"local_checksum":  checksum,
+				"remote_checksum": remote,
+			})
+			return errors.NewAppError(errors.ErrUploadFailed, "uploaded content does not match the local file checksum", nil)
+		}
+
+		result = &UploadResult{
+			ETag:           NormalizeETag(aws.ToString(output.ETag)),
+			ChecksumSHA256: checksum,
+			Size:           fileSize,
+		}
+
 		// Send final progress update
 		if progressCh != nil {
 			select {
@@ -229,67 +370,156 @@ func (s *S3ServiceImpl) UploadFile(ctx context.Context, key string, filePath str
 		s.logger.InfoWithFields("File upload completed successfully", map[string]interface{}{
 			"s3_key":          key,
 			"file_size_bytes": fileSize,
+			"checksum_sha256": checksum,
 		})
 
 		return nil
 	})
+
+	return result, err
 }
 
 // GeneratePresignedURL generates a presigned URL for downloading a file
 func (s *S3ServiceImpl) GeneratePresignedURL(ctx context.Context, key string, expiration time.Duration) (string, error) {
 	var result string
 	err := s.logger.LogOperation("generate_presigned_url", func() error {
-		if key == "" {
-			return errors.NewAppError(errors.ErrInvalidInput, "S3 object key cannot be empty", nil)
+		download, err := s.presignGetObject(ctx, key, expiration, false, time.Time{}, ResponseOverrides{})
+		if err != nil {
+			return err
 		}
+		result = download.URL
+		return nil
+	})
+
+	return result, err
+}
 
-		if expiration <= 0 {
-			return errors.NewAppError(errors.ErrInvalidInput, "expiration duration must be positive", nil)
+// GeneratePresignedURLAt generates a presigned URL signed as of signedAt. The URL expires
+// the given duration after signedAt, so it may already have expired. S3 answers the download
+// with the overridden response headers.
+func (s *S3ServiceImpl) GeneratePresignedURLAt(ctx context.Context, key string, signedAt time.Time, expiration time.Duration, overrides ResponseOverrides) (string, error) {
+	if signedAt.IsZero() {
+		return "", errors.NewAppError(errors.ErrInvalidInput, "signing time cannot be empty", nil)
+	}
+
+	var result string
+	err := s.logger.LogOperation("generate_presigned_url", func() error {
+		download, err := s.presignGetObject(ctx, key, expiration, false, signedAt, overrides)
+		if err != nil {
+			return err
 		}
+		result = download.URL
+		return nil
+	})
 
-		// Limit maximum expiration to 7 days for security (as per requirement 8.5)
-		maxExpiration := 7 * 24 * time.Hour
-		originalExpiration := expiration
-		if expiration > maxExpiration {
-			expiration = maxExpiration
-			s.logger.WarnWithFields("Presigned URL expiration capped for security", map[string]interface{}{
-				"requested_duration_hours": originalExpiration.Hours(),
-				"capped_duration_hours":    expiration.Hours(),
-				"s3_key":                   key,
-			})
+	return result, err
+}
+
+// GeneratePresignedDownload generates a presigned download for an object uploaded with the given
+// encryption mode. SSE-C objects can only be read by sending the customer key headers returned
+// alongside the URL, so the URL alone is not enough to share them.
+func (s *S3ServiceImpl) GeneratePresignedDownload(ctx context.Context, key string, expiration time.Duration, encryptionMode string) (*PresignedDownload, error) {
+	var result *PresignedDownload
+	err := s.logger.LogOperation("generate_presigned_download", func() error {
+		withCustomerKey := encryptionMode == models.EncryptionSSEC
+		if withCustomerKey && len(s.encryption.CustomerKey) == 0 {
+			return errors.NewAppError(errors.ErrMissingConfig, "object is encrypted with SSE-C but no customer key is configured", nil)
 		}
 
-		s.logger.InfoWithFields("Generating presigned URL", map[string]interface{}{
-			"s3_key":           key,
-			"expiration_hours": expiration.Hours(),
-		})
+		download, err := s.presignGetObject(ctx, key, expiration, withCustomerKey, time.Time{}, ResponseOverrides{})
+		if err != nil {
+			return err
+		}
+		result = download
+		return nil
+	})
 
-		// Create presigned request
-		request, err := s.presigner.PresignGetObject(ctx, &s3.GetObjectInput{
-			Bucket: aws.String(s.bucket),
-			Key:    aws.String(key),
-		}, func(opts *s3.PresignOptions) {
-			opts.Expires = expiration
+	return result, err
+}
+
+// presignGetObject presigns a GET request, optionally signing the SSE-C headers into it.
+// It is signed as of signedAt, or now if signedAt is zero.
+func (s *S3ServiceImpl) presignGetObject(ctx context.Context, key string, expiration time.Duration, withCustomerKey bool, signedAt time.Time, overrides ResponseOverrides) (*PresignedDownload, error) {
+	if key == "" {
+		return nil, errors.NewAppError(errors.ErrInvalidInput, "S3 object key cannot be empty", nil)
+	}
+
+	if expiration <= 0 {
+		return nil, errors.NewAppError(errors.ErrInvalidInput, "expiration duration must be positive", nil)
+	}
+
+	// Limit maximum expiration to 7 days for security (as per requirement 8.5)
+	maxExpiration := 7 * 24 * time.Hour
+	originalExpiration := expiration
+	if expiration > maxExpiration {
+		expiration = maxExpiration
+		s.logger.WarnWithFields("Presigned URL expiration capped for security", map[string]interface{}{
+			"requested_duration_hours": originalExpiration.Hours(),
+			"capped_duration_hours":    expiration.Hours(),
+			"s3_key":                   key,
 		})
+	}
 
-		if err != nil {
-			s.logger.ErrorWithFields("Failed to generate presigned URL", map[string]interface{}{
-				"s3_key": key,
-				"bucket": s.bucket,
-			})
-			return s.handleS3Error("generate presigned URL", err)
+	s.logger.InfoWithFields("Generating presigned URL", map[string]interface{}{
+		"s3_key":           key,
+		"expiration_hours": expiration.Hours(),
+	})
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}
+	if withCustomerKey {
+		s.encryption.applyGetEncryption(input)
+	}
+	if overrides.ContentDisposition != "" {
+		input.ResponseContentDisposition = aws.String(overrides.ContentDisposition)
+	}
+	if overrides.ContentType != "" {
+		input.ResponseContentType = aws.String(overrides.ContentType)
+	}
+
+	// Create presigned request
+	request, err := s.presigner.PresignGetObject(ctx, input, func(opts *s3.PresignOptions) {
+		opts.Expires = expiration
+		if !signedAt.IsZero() {
+			opts.Presigner = fixedTimePresigner{presigner: opts.Presigner, signingTime: signedAt}
 		}
+	})
 
-		result = request.URL
-		s.logger.InfoWithFields("Presigned URL generated successfully", map[string]interface{}{
-			"s3_key":           key,
-			"expiration_hours": expiration.Hours(),
+	if err != nil {
+		s.logger.ErrorWithFields("Failed to generate presigned URL", map[string]interface{}{
+			"s3_key": key,
+			"bucket": s.bucket,
 		})
+		return nil, s.handleS3Error("generate presigned URL", err)
+	}
 
-		return nil
+	download := &PresignedDownload{URL: request.URL, Headers: make(http.Header)}
+	if withCustomerKey {
+		download.Headers = s.encryption.customerKeyHeaders()
+	}
+
+	s.logger.InfoWithFields("Presigned URL generated successfully", map[string]interface{}{
+		"s3_key":           key,
+		"expiration_hours": expiration.Hours(),
+		"customer_key":     withCustomerKey,
 	})
 
-	return result, err
+	return download, nil
+}
+
+// fixedTimePresigner signs requests as of a fixed time instead of now
+type fixedTimePresigner struct {
+	presigner   s3.HTTPPresignerV4
+	signingTime time.Time
+}
+
+// PresignHTTP presigns the request, ignoring the signing time it is given
+func (p fixedTimePresigner) PresignHTTP(ctx context.Context, credentials aws.Credentials, r *http.Request,
+	payloadHash string, service string, region string, signingTime time.Time,
+	optFns ...func(*v4.SignerOptions)) (string, http.Header, error) {
+	return p.presigner.PresignHTTP(ctx, credentials, r, payloadHash, service, region, p.signingTime, optFns...)
 }
 
 // DeleteObject deletes an object from S3
@@ -326,6 +556,128 @@ func (s *S3ServiceImpl) DeleteObject(ctx context.Context, key string) error {
 	})
 }
 
+// ExtendExpiration retags an object so the bucket lifecycle keeps it at least as long as the
+// expiration tag says. Lifecycle rules count from when the object was uploaded, so this can't
+// keep an object longer than the longest rule. Objects tagged to live longer already are left alone.
+func (s *S3ServiceImpl) ExtendExpiration(ctx context.Context, key string, expirationTag string) error {
+	return s.logger.LogOperation("extend_expiration", func() error {
+		if key == "" {
+			return errors.NewAppError(errors.ErrInvalidInput, "S3 object key cannot be empty", nil)
+		}
+		if expirationTagRank(expirationTag) < 0 {
+			return errors.NewAppError(errors.ErrInvalidInput, fmt.Sprintf("unknown expiration tag: %s", expirationTag), nil)
+		}
+
+		output, err := s.client.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return s.handleS3Error("get object tags", err)
+		}
+
+		// The other tags are kept, as putting tags replaces them all
+		tags := make([]types.Tag, 0, len(output.TagSet)+1)
+		for _, tag := range output.TagSet {
+			if aws.ToString(tag.Key) != ExpirationTagKey {
+				tags = append(tags, tag)
+				continue
+			}
+			if expirationTagRank(aws.ToString(tag.Value)) >= expirationTagRank(expirationTag) {
+				return nil
+			}
+		}
+		tags = append(tags, types.Tag{Key: aws.String(ExpirationTagKey), Value: aws.String(expirationTag)})
+
+		_, err = s.client.PutObjectTagging(ctx, &s3.PutObjectTaggingInput{
+			Bucket:  aws.String(s.bucket),
+			Key:     aws.String(key),
+			Tagging: &types.Tagging{TagSet: tags},
+		})
+		if err != nil {
+			s.logger.ErrorWithFields("Failed to extend S3 object expiration", map[string]interface{}{
+				"s3_key": key,
+				"bucket": s.bucket,
+			})
+			return s.handleS3Error("put object tags", err)
+		}
+
+		s.logger.InfoWithFields("Extended S3 object expiration", map[string]interface{}{
+			"s3_key":         key,
+			"expiration_tag": expirationTag,
+		})
+		return nil
+	})
+}
+
+// expirationTagRank orders expiration tags from the shortest lifetime, or returns -1 for unknown tags
+func expirationTagRank(tag string) int {
+	for i, known := range ExpirationTags {
+		if tag == known {
+			return i
+		}
+	}
+	return -1
+}
+
+// PutShareLink stores the record the redirect function reads to resolve a short share link.
+// The record is left to the bucket's default encryption, since the function must be able to read it.
+func (s *S3ServiceImpl) PutShareLink(ctx context.Context, link *ShareLink) error {
+	return s.logger.LogOperation("put_share_link", func() error {
+		if link == nil || link.Token == "" || link.Key == "" {
+			return errors.NewAppError(errors.ErrInvalidInput, "share link needs a token and an S3 object key", nil)
+		}
+
+		// Whole seconds in UTC, which the function's ISO 8601 parser reads as-is
+		body, err := json.Marshal(ShareLink{
+			Key:                link.Key,
+			ExpiresAt:          link.ExpiresAt.UTC().Truncate(time.Second),
+			ContentDisposition: link.ContentDisposition,
+			ContentType:        link.ContentType,
+		})
+		if err != nil {
+			return errors.WrapError(err, errors.ErrInvalidInput, "failed to encode share link")
+		}
+
+		_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(s.bucket),
+			Key:         aws.String(shareLinkRecordKey(link.Token)),
+			Body:        bytes.NewReader(body),
+			ContentType: aws.String("application/json"),
+		})
+		if err != nil {
+			s.logger.ErrorWithFields("Failed to store share link", map[string]interface{}{
+				"bucket": s.bucket,
+			})
+			return s.handleS3Error("store share link", err)
+		}
+
+		return nil
+	})
+}
+
+// DeleteShareLink removes a short share link's record, so the link stops working
+func (s *S3ServiceImpl) DeleteShareLink(ctx context.Context, token string) error {
+	return s.logger.LogOperation("delete_share_link", func() error {
+		if token == "" {
+			return errors.NewAppError(errors.ErrInvalidInput, "share link token cannot be empty", nil)
+		}
+
+		_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(shareLinkRecordKey(token)),
+		})
+		if err != nil {
+			s.logger.ErrorWithFields("Failed to delete share link", map[string]interface{}{
+				"bucket": s.bucket,
+			})
+			return s.handleS3Error("delete share link", err)
+		}
+
+		return nil
+	})
+}
+
 // HeadObject retrieves metadata about an object without downloading it
 func (s *S3ServiceImpl) HeadObject(ctx context.Context, key string) (*s3.HeadObjectOutput, error) {
 	var result *s3.HeadObjectOutput
@@ -342,9 +694,16 @@ func (s *S3ServiceImpl) HeadObject(ctx context.Context, key string) (*s3.HeadObj
 		input := &s3.HeadObjectInput{
 			Bucket: aws.String(s.bucket),
 			Key:    aws.String(key),
+			// Include the stored checksum so sync can verify content integrity
+			ChecksumMode: types.ChecksumModeEnabled,
 		}
 
 		output, err := s.client.HeadObject(ctx, input)
+		if err != nil && len(s.encryption.CustomerKey) > 0 {
+			// SSE-C objects reject metadata reads without the key; retry with it
+			s.encryption.applyHeadEncryption(input)
+			output, err = s.client.HeadObject(ctx, input)
+		}
 		if err != nil {
 			s.logger.ErrorWithFields("Failed to get S3 object metadata", map[string]interface{}{
 				"s3_key": key,
@@ -451,43 +810,18 @@ func (s *S3ServiceImpl) handleS3Error(operation string, err error) error {
 	)
 }
 
-// getContentType determines the content type based on file extension
-func getContentType(filePath string) string {
-	ext := filepath.Ext(filePath)
-	switch ext {
-	case ".txt":
-		return "text/plain"
-	case ".pdf":
-		return "application/pdf"
-	case ".jpg", ".jpeg":
-		return "image/jpeg"
-	case ".png":
-		return "image/png"
-	case ".gif":
-		return "image/gif"
-	case ".zip":
-		return "application/zip"
-	case ".json":
-		return "application/json"
-	case ".xml":
-		return "application/xml"
-	case ".csv":
-		return "text/csv"
-	case ".doc":
-		return "application/msword"
-	case ".docx":
-		return "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
-	case ".xls":
-		return "application/vnd.ms-excel"
-	case ".xlsx":
-		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
-	case ".ppt":
-		return "application/vnd.ms-powerpoint"
-	case ".pptx":
-		return "application/vnd.openxmlformats-officedocument.presentationml.presentation"
-	default:
-		return "application/octet-stream"
+// detectContentType determines the content type from the file's extension and the start of its
+// content, and leaves the file at its start for uploading
+func detectContentType(file io.ReadSeeker, filePath string) (string, error) {
+	head := make([]byte, models.ContentSniffLength)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
 	}
+	return models.DetectContentType(filepath.Base(filePath), head[:n], nil), nil
 }
 
 // formatTagsForUpload formats tags for S3 upload (key1=value1&key2=value2)
@@ -512,13 +846,29 @@ type progressReader struct {
 	totalBytes   int64
 	bytesRead    int64
 	progressCh   chan<- UploadProgress
+	hash         hash.Hash
+	ctx          context.Context     // cancels waits for the limiters
+	limiters     []*BandwidthLimiter // pace the reads; all of them apply
 }
 
-// Read implements io.Reader and sends progress updates
+// Read implements io.Reader, hashing the content, pacing it to the bandwidth limiters and
+// sending progress updates
 func (pr *progressReader) Read(p []byte) (int, error) {
+	if len(p) > throttledReadSize && anyActive(pr.limiters) {
+		p = p[:throttledReadSize]
+	}
 	n, err := pr.reader.Read(p)
 	if n > 0 {
+		if pr.hash != nil {
+			pr.hash.Write(p[:n])
+		}
+		if waitErr := pr.wait(n); waitErr != nil {
+			return n, waitErr
+		}
 		pr.bytesRead += int64(n)
+		if pr.progressCh == nil {
+			return n, err
+		}
 		percentage := float64(pr.bytesRead) / float64(pr.totalBytes) * 100.0
 		
 		// Send progress update (non-blocking)
@@ -533,4 +883,86 @@ func (pr *progressReader) Read(p []byte) (int, error) {
 		}
 	}
 	return n, err
-}
\ No newline at end of file
+}
+
+// wait holds a read of n bytes back until every limiter allows it
+func (pr *progressReader) wait(n int) error {
+	ctx := pr.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	for _, limiter := range pr.limiters {
+		if err := limiter.WaitN(ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NormalizeETag strips the quotes S3 puts around ETag values
+func NormalizeETag(etag string) string {
+	return strings.Trim(etag, "\"")
+}
+
+// ETagFromHead returns an object's ETag without quotes, or "" if it has none
+func ETagFromHead(output *s3.HeadObjectOutput) string {
+	if output == nil {
+		return ""
+	}
+	return NormalizeETag(aws.ToString(output.ETag))
+}
+
+// ContentChecksumFromHead returns the hex SHA-256 of an object's content before any compression,
+// or "" if it isn't known
+func ContentChecksumFromHead(output *s3.HeadObjectOutput) string {
+	if ContentEncodingFromHead(output) == "" {
+		return ChecksumFromHead(output)
+	}
+	return output.Metadata["original-sha256"]
+}
+
+// ContentLengthFromHead returns the size of an object in bytes, or zero if it isn't known
+func ContentLengthFromHead(output *s3.HeadObjectOutput) int64 {
+	if output == nil {
+		return 0
+	}
+	return aws.ToInt64(output.ContentLength)
+}
+
+// ContentEncodingFromHead returns the compression an object is stored with, or "" if it isn't compressed
+func ContentEncodingFromHead(output *s3.HeadObjectOutput) string {
+	if output == nil {
+		return ""
+	}
+	return aws.ToString(output.ContentEncoding)
+}
+
+// ContentTypeFromHead returns the content type an object is stored with, or "" if it has none
+func ContentTypeFromHead(output *s3.HeadObjectOutput) string {
+	if output == nil {
+		return ""
+	}
+	return aws.ToString(output.ContentType)
+}
+
+// ChecksumFromHead returns the hex SHA-256 S3 stored for an object, or "" if it has none.
+// Multipart uploads report a checksum of part checksums, which can't be compared to a file hash.
+func ChecksumFromHead(output *s3.HeadObjectOutput) string {
+	if output == nil {
+		return ""
+	}
+	return checksumFromS3(output.ChecksumSHA256)
+}
+
+// checksumFromS3 converts a base64 full-object SHA-256 returned by S3 to hex
+func checksumFromS3(value *string) string {
+	encoded := aws.ToString(value)
+	if encoded == "" || strings.Contains(encoded, "-") {
+		return ""
+	}
+	digest, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(digest) != sha256.Size {
+		return ""
+	}
+	return hex.EncodeToString(digest)
+}
@@ -2,13 +2,19 @@ package aws
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -218,7 +224,7 @@ func TestS3ServiceImpl_UploadFile(t *testing.T) {
 			progressCh := make(chan UploadProgress, 10)
 			
 			// Upload file
-			err := service.UploadFile(ctx, tt.key, filePath, tt.metadata, progressCh)
+			_, err := service.UploadFile(ctx, tt.key, filePath, tt.metadata, progressCh)
 			
 			if tt.expectError {
 				assert.Error(t, err)
@@ -304,6 +310,44 @@ func TestS3ServiceImpl_GeneratePresignedURL(t *testing.T) {
 	}
 }
 
+func TestS3ServiceImpl_GeneratePresignedURLAt(t *testing.T) {
+	service, err := NewS3Service(createTestS3CredentialProvider(), "test-bucket")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	signedAt := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+
+	url, err := service.GeneratePresignedURLAt(ctx, "uploads/report.pdf", signedAt, 24*time.Hour, ResponseOverrides{})
+	require.NoError(t, err)
+	assert.Contains(t, url, "X-Amz-Date=20240301T093000Z")
+	assert.Contains(t, url, "X-Amz-Expires=86400")
+
+	// The same share is signed to the same link every time
+	again, err := service.GeneratePresignedURLAt(ctx, "uploads/report.pdf", signedAt, 24*time.Hour, ResponseOverrides{})
+	require.NoError(t, err)
+	assert.Equal(t, url, again)
+
+	later, err := service.GeneratePresignedURLAt(ctx, "uploads/report.pdf", signedAt.Add(time.Second), 24*time.Hour, ResponseOverrides{})
+	require.NoError(t, err)
+	assert.NotEqual(t, url, later)
+
+	// Response header overrides are signed into the link
+	renamed, err := service.GeneratePresignedURLAt(ctx, "uploads/report.pdf", signedAt, 24*time.Hour,
+		ResponseOverrides{ContentDisposition: AttachmentDisposition("Q3 report.pdf")})
+	require.NoError(t, err)
+	assert.Contains(t, renamed, "response-content-disposition=attachment%3B%20filename%3D%22Q3%20report.pdf%22")
+	assert.NotEqual(t, url, renamed)
+
+	preview, err := service.GeneratePresignedURLAt(ctx, "uploads/report.pdf", signedAt, 24*time.Hour,
+		ResponseOverrides{ContentDisposition: InlineDisposition("report.pdf"), ContentType: "application/pdf"})
+	require.NoError(t, err)
+	assert.Contains(t, preview, "response-content-disposition=inline%3B")
+	assert.Contains(t, preview, "response-content-type=application%2Fpdf")
+
+	_, err = service.GeneratePresignedURLAt(ctx, "uploads/report.pdf", time.Time{}, 24*time.Hour, ResponseOverrides{})
+	assert.Error(t, err)
+}
+
 func TestS3ServiceImpl_DeleteObject(t *testing.T) {
 	credProvider := createTestS3CredentialProvider()
 	service, err := NewS3Service(credProvider, "test-bucket")
@@ -403,7 +447,7 @@ func TestS3ServiceImpl_TestConnection(t *testing.T) {
 	assert.Contains(t, err.Error(), "failed to test connection")
 }
 
-func TestGetContentType(t *testing.T) {
+func TestDetectContentType(t *testing.T) {
 	tests := []struct {
 		filePath    string
 		expectedType string
@@ -430,8 +474,41 @@ func TestGetContentType(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.filePath, func(t *testing.T) {
-			contentType := getContentType(tt.filePath)
+			contentType, err := detectContentType(strings.NewReader(""), tt.filePath)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedType, contentType)
+		})
+	}
+}
+
+func TestDetectContentType_Content(t *testing.T) {
+	tests := []struct {
+		filePath     string
+		content      string
+		expectedType string
+	}{
+		{"movie.mp4", "", "video/mp4"},
+		{"notes.md", "# Notes", "text/markdown"},
+		{"main.go", "package main", "text/x-go"},
+		{"page.html", "<html></html>", "text/html"},
+		// Unknown extensions are identified by their content
+		{"notes", "plain text notes", "text/plain; charset=utf-8"},
+		{"scan.bin", "%PDF-1.7", "application/pdf"},
+		// HTML is stored as HTML whatever it is called
+		{"report.txt", "<!DOCTYPE html><script>alert(1)</script>", "text/html; charset=utf-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filePath, func(t *testing.T) {
+			reader := strings.NewReader(tt.content + strings.Repeat(" ", 1024))
+			contentType, err := detectContentType(reader, tt.filePath)
+			assert.NoError(t, err)
 			assert.Equal(t, tt.expectedType, contentType)
+
+			// The file is left at its start for uploading
+			offset, err := reader.Seek(0, io.SeekCurrent)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(0), offset)
 		})
 	}
 }
@@ -484,6 +561,96 @@ func TestProgressReader(t *testing.T) {
 	}
 }
 
+func TestProgressReader_Checksum(t *testing.T) {
+	content := "test"
+	
+	// Without a progress channel the content is still hashed
+	pr := &progressReader{
+		reader:     strings.NewReader(content),
+		totalBytes: int64(len(content)),
+		hash:       sha256.New(),
+	}
+
+	data, err := io.ReadAll(pr)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(data))
+	assert.Equal(t, "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", hex.EncodeToString(pr.hash.Sum(nil)))
+}
+
+func TestChecksumFromHead(t *testing.T) {
+	digest := sha256.Sum256([]byte("test"))
+	encoded := base64.StdEncoding.EncodeToString(digest[:])
+
+	tests := []struct {
+		name     string
+		output   *s3.HeadObjectOutput
+		expected string
+	}{
+		{
+			name:     "nil output",
+			output:   nil,
+			expected: "",
+		},
+		{
+			name:     "no checksum",
+			output:   &s3.HeadObjectOutput{},
+			expected: "",
+		},
+		{
+			name:     "full object checksum",
+			output:   &s3.HeadObjectOutput{ChecksumSHA256: aws.String(encoded)},
+			expected: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		},
+		{
+			name:     "multipart composite checksum",
+			output:   &s3.HeadObjectOutput{ChecksumSHA256: aws.String(encoded + "-3")},
+			expected: "",
+		},
+		{
+			name:     "invalid base64",
+			output:   &s3.HeadObjectOutput{ChecksumSHA256: aws.String("not base64!")},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ChecksumFromHead(tt.output))
+		})
+	}
+}
+
+func TestETagFromHead(t *testing.T) {
+	assert.Equal(t, "", ETagFromHead(nil))
+	assert.Equal(t, "", ETagFromHead(&s3.HeadObjectOutput{}))
+	assert.Equal(t, "098f6bcd4621d373cade4e832627b4f6", ETagFromHead(&s3.HeadObjectOutput{ETag: aws.String("\"098f6bcd4621d373cade4e832627b4f6\"")}))
+	assert.Equal(t, "abc", NormalizeETag("abc"))
+}
+
+func TestContentChecksumFromHead(t *testing.T) {
+	digest := sha256.Sum256([]byte("test"))
+	encoded := base64.StdEncoding.EncodeToString(digest[:])
+	original := "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+
+	assert.Equal(t, "", ContentChecksumFromHead(nil))
+
+	// Objects stored as they are have the checksum S3 stored
+	plain := &s3.HeadObjectOutput{ChecksumSHA256: aws.String(encoded), ContentLength: aws.Int64(4)}
+	assert.Equal(t, "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", ContentChecksumFromHead(plain))
+	assert.Equal(t, "", ContentEncodingFromHead(plain))
+	assert.Equal(t, int64(4), ContentLengthFromHead(plain))
+
+	// Compressed objects have the checksum of the original content, recorded when they were uploaded
+	compressed := &s3.HeadObjectOutput{
+		ChecksumSHA256:  aws.String(encoded),
+		ContentEncoding: aws.String("gzip"),
+		Metadata:        map[string]string{"original-sha256": original},
+	}
+	assert.Equal(t, original, ContentChecksumFromHead(compressed))
+	assert.Equal(t, "gzip", ContentEncodingFromHead(compressed))
+	assert.Equal(t, int64(0), ContentLengthFromHead(compressed))
+}
+
 func TestS3ServiceImpl_handleS3Error(t *testing.T) {
 	credProvider := createTestS3CredentialProvider()
 	service, err := NewS3Service(credProvider, "test-bucket")
@@ -566,7 +733,7 @@ func TestS3ServiceIntegration(t *testing.T) {
 	
 	// Upload file
 	progressCh := make(chan UploadProgress, 10)
-	err = service.UploadFile(ctx, "integration-test-key", testFile, nil, progressCh)
+	_, err = service.UploadFile(ctx, "integration-test-key", testFile, nil, progressCh)
 	assert.NoError(t, err)
 	close(progressCh)
 
@@ -594,7 +761,7 @@ func BenchmarkGetContentType(b *testing.B) {
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		for _, file := range testFiles {
-			getContentType(file)
+			detectContentType(strings.NewReader(""), file)
 		}
 	}
 }
@@ -695,4 +862,40 @@ func TestFormatTagsForUpload(t *testing.T) {
 			assert.Equal(t, tt.expected, result)
 		})
 	}
-}
\ No newline at end of file
+}
+func TestS3ServiceImpl_ExtendExpiration(t *testing.T) {
+	currentTag := ExpirationTagOneDay
+	var putBody string
+	var puts int
+	service := newShareLinkTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.URL.Query()["tagging"]; !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.Method == http.MethodPut {
+			body, _ := io.ReadAll(r.Body)
+			putBody = string(body)
+			puts++
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		fmt.Fprintf(w, `<Tagging><TagSet><Tag><Key>upload-date</Key><Value>2024-03-01</Value></Tag><Tag><Key>expiration</Key><Value>%s</Value></Tag></TagSet></Tagging>`, currentTag)
+	})
+	ctx := context.Background()
+
+	// A longer tag replaces the expiration tag and keeps the others
+	require.NoError(t, service.ExtendExpiration(ctx, "uploads/2024/03/01/0b9d.log", ExpirationTagOneWeek))
+	assert.Equal(t, 1, puts)
+	assert.Contains(t, putBody, "<Key>expiration</Key><Value>1week</Value>")
+	assert.Contains(t, putBody, "<Key>upload-date</Key><Value>2024-03-01</Value>")
+	assert.NotContains(t, putBody, "1day")
+
+	// Objects tagged to live as long or longer are left alone
+	require.NoError(t, service.ExtendExpiration(ctx, "uploads/2024/03/01/0b9d.log", ExpirationTagOneDay))
+	require.NoError(t, service.ExtendExpiration(ctx, "uploads/2024/03/01/0b9d.log", ExpirationTagOneHour))
+	assert.Equal(t, 1, puts)
+
+	assert.Error(t, service.ExtendExpiration(ctx, "uploads/2024/03/01/0b9d.log", "1year"))
+	assert.Error(t, service.ExtendExpiration(ctx, "", ExpirationTagOneWeek))
+}
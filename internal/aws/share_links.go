@@ -0,0 +1,55 @@
+package aws
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// ShareLinkPrefix is where short share link records are kept in the bucket. The redirect function
+// in file-sharing-app.yaml reads them, and a lifecycle rule cleans them up.
+const ShareLinkPrefix = "share-links/"
+
+// ShareLinkKeySize is the length in bytes of the key that share link tokens are derived from
+const ShareLinkKeySize = 32
+
+// shareLinkTokenLength is the number of base64url characters in a link token (132 bits).
+// The redirect function only accepts tokens of exactly this length.
+const shareLinkTokenLength = 22
+
+// ShareLink is the record the redirect function reads to resolve a short share link
+type ShareLink struct {
+	Token              string    `json:"-"`                             // never stored; a hash of it names the record
+	Key                string    `json:"key"`                           // S3 key of the shared file
+	ExpiresAt          time.Time `json:"expires_at"`                    // the link stops working after this time
+	ContentDisposition string    `json:"content_disposition,omitempty"` // overrides the object's Content-Disposition
+	ContentType        string    `json:"content_type,omitempty"`        // overrides the object's Content-Type
+}
+
+// ShareLinkToken derives the token of a share's short link from the profile's link key,
+// so the link can be rebuilt whenever it is needed instead of being stored
+func ShareLinkToken(linkKey []byte, shareID string) string {
+	mac := hmac.New(sha256.New, linkKey)
+	mac.Write([]byte(shareID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))[:shareLinkTokenLength]
+}
+
+// shareLinkRecordKey returns the S3 key of a link's record. It is named by a hash of the token,
+// so reading the bucket listing doesn't reveal working links.
+func shareLinkRecordKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return ShareLinkPrefix + hex.EncodeToString(sum[:]) + ".json"
+}
+
+// GenerateShareLinkKey creates a random key for deriving share link tokens
+func GenerateShareLinkKey() ([]byte, error) {
+	key := make([]byte, ShareLinkKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate share link key: %w", err)
+	}
+	return key, nil
+}
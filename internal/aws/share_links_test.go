@@ -0,0 +1,114 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShareLinkToken(t *testing.T) {
+	key := []byte(strings.Repeat("k", ShareLinkKeySize))
+
+	token := ShareLinkToken(key, "share-1")
+	assert.Regexp(t, regexp.MustCompile(`^[A-Za-z0-9_-]{22}$`), token, "the redirect function only accepts this format")
+
+	// The same share always gets the same link, and other shares and keys get other links
+	assert.Equal(t, token, ShareLinkToken(key, "share-1"))
+	assert.NotEqual(t, token, ShareLinkToken(key, "share-2"))
+	assert.NotEqual(t, token, ShareLinkToken([]byte(strings.Repeat("x", ShareLinkKeySize)), "share-1"))
+}
+
+func TestShareLinkRecordKey(t *testing.T) {
+	key := shareLinkRecordKey("abcdefghijklmnopqrstuv")
+
+	// Matches the redirect function: the hex SHA-256 of the token under the link prefix
+	assert.Equal(t, "share-links/f69f9b70d1c9a5442258ca76f8b0a7a45fcb4e31c36141b6357ec591328b0624.json", key)
+	assert.NotContains(t, key, "abcdefghijklmnopqrstuv")
+}
+
+func TestGenerateShareLinkKey(t *testing.T) {
+	key1, err := GenerateShareLinkKey()
+	require.NoError(t, err)
+	key2, err := GenerateShareLinkKey()
+	require.NoError(t, err)
+
+	assert.Len(t, key1, ShareLinkKeySize)
+	assert.NotEqual(t, key1, key2)
+}
+
+// newShareLinkTestService creates an S3 service that talks to a test server instead of AWS
+func newShareLinkTestService(t *testing.T, handler http.HandlerFunc) *S3ServiceImpl {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	service, err := NewS3Service(createTestS3CredentialProvider(), "test-bucket")
+	require.NoError(t, err)
+
+	service.client = s3.New(s3.Options{
+		Region:       "us-east-1",
+		Credentials:  aws.NewCredentialsCache(aws.CredentialsProviderFunc(createTestS3CredentialProvider().GetCredentials)),
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+	})
+	return service
+}
+
+func TestS3ServiceImpl_PutShareLink(t *testing.T) {
+	var method, path string
+	var record map[string]string
+	service := newShareLinkTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &record)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	expiresAt := time.Date(2024, 3, 31, 9, 30, 0, 500, time.FixedZone("CET", 3600))
+	err := service.PutShareLink(context.Background(), &ShareLink{Token: "abcdefghijklmnopqrstuv", Key: "uploads/report.pdf", ExpiresAt: expiresAt})
+	require.NoError(t, err)
+
+	assert.Equal(t, http.MethodPut, method)
+	assert.Equal(t, "/test-bucket/"+shareLinkRecordKey("abcdefghijklmnopqrstuv"), path)
+	assert.Equal(t, map[string]string{"key": "uploads/report.pdf", "expires_at": "2024-03-31T08:30:00Z"}, record)
+
+	// The redirect function passes the response overrides on to S3
+	record = nil
+	err = service.PutShareLink(context.Background(), &ShareLink{
+		Token:              "abcdefghijklmnopqrstuv",
+		Key:                "uploads/report.pdf",
+		ExpiresAt:          expiresAt,
+		ContentDisposition: InlineDisposition("Q3 report.pdf"),
+		ContentType:        "application/pdf",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, InlineDisposition("Q3 report.pdf"), record["content_disposition"])
+	assert.Equal(t, "application/pdf", record["content_type"])
+
+	// A record without a token would never be found
+	assert.Error(t, service.PutShareLink(context.Background(), &ShareLink{Key: "uploads/report.pdf", ExpiresAt: expiresAt}))
+}
+
+func TestS3ServiceImpl_DeleteShareLink(t *testing.T) {
+	var method, path string
+	service := newShareLinkTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	require.NoError(t, service.DeleteShareLink(context.Background(), "abcdefghijklmnopqrstuv"))
+	assert.Equal(t, http.MethodDelete, method)
+	assert.Equal(t, "/test-bucket/"+shareLinkRecordKey("abcdefghijklmnopqrstuv"), path)
+
+	assert.Error(t, service.DeleteShareLink(context.Background(), ""))
+}
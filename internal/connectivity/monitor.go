@@ -0,0 +1,133 @@
+package connectivity
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"file-sharing-app/pkg/logger"
+)
+
+// Prober checks whether the remote service can be reached
+type Prober func(ctx context.Context) error
+
+// Config controls how often the monitor probes while offline
+type Config struct {
+	MinDelay    time.Duration // delay before the first probe after going offline
+	MaxDelay    time.Duration // longest delay between probes
+	SettleDelay time.Duration // delay after a network change before probing, to let it come up
+}
+
+// DefaultConfig returns the default monitor configuration
+func DefaultConfig() Config {
+	return Config{
+		MinDelay:    5 * time.Second,
+		MaxDelay:    5 * time.Minute,
+		SettleDelay: 2 * time.Second,
+	}
+}
+
+// Monitor probes a remote service while the application is offline and reports when it can be reached again.
+// Probes back off exponentially with jitter, and a network change triggers a probe straight away.
+type Monitor struct {
+	probe          Prober
+	isOffline      func() bool
+	onOnline       func()
+	config         Config
+	networkChanges <-chan struct{}
+	logger         *logger.Logger
+}
+
+// NewMonitor creates a monitor that calls probe while isOffline reports true, and onOnline once a probe succeeds
+func NewMonitor(probe Prober, isOffline func() bool, onOnline func(), config Config) *Monitor {
+	return &Monitor{
+		probe:     probe,
+		isOffline: isOffline,
+		onOnline:  onOnline,
+		config:    config,
+		logger:    logger.NewWithComponent("connectivity"),
+	}
+}
+
+// SetNetworkChanges sets a channel of network change events that trigger a probe. Must be called before Run.
+func (m *Monitor) SetNetworkChanges(changes <-chan struct{}) {
+	m.networkChanges = changes
+}
+
+// Run monitors connectivity until ctx is canceled
+func (m *Monitor) Run(ctx context.Context) {
+	delay := m.config.MinDelay
+	wait := jitter(delay)
+	networkChanges := m.networkChanges
+
+	for {
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+
+		case _, ok := <-networkChanges:
+			timer.Stop()
+			if !ok {
+				networkChanges = nil
+				continue
+			}
+			// Start the backoff over, probing once the new network has had a moment to come up
+			m.logger.Info("Network change detected")
+			delay = m.config.MinDelay
+			wait = m.config.SettleDelay
+			continue
+
+		case <-timer.C:
+		}
+
+		if !m.isOffline() {
+			delay = m.config.MinDelay
+			wait = jitter(delay)
+			continue
+		}
+
+		if err := m.probe(ctx); err != nil {
+			delay = nextDelay(delay, m.config.MaxDelay)
+			wait = jitter(delay)
+			m.logger.Info(fmt.Sprintf("Still offline, next check in %v: %v", wait.Round(time.Second), err))
+			continue
+		}
+
+		m.logger.Info("Connectivity restored")
+		m.onOnline()
+
+		delay = m.config.MinDelay
+		wait = jitter(delay)
+	}
+}
+
+// nextDelay doubles a backoff delay up to max
+func nextDelay(delay, max time.Duration) time.Duration {
+	delay *= 2
+	if delay > max {
+		return max
+	}
+	return delay
+}
+
+// jitter returns a random delay between half and all of delay, so clients that
+// went offline together don't all probe at once
+func jitter(delay time.Duration) time.Duration {
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// notify sends a change event without blocking, coalescing bursts of changes
+func notify(changes chan<- struct{}) {
+	select {
+	case changes <- struct{}{}:
+	default:
+	}
+}
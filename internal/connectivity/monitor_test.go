@@ -0,0 +1,188 @@
+package connectivity
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConnection simulates a service that becomes reachable after a number of failed probes
+type fakeConnection struct {
+	mutex         sync.Mutex
+	offline       bool
+	failuresLeft  int
+	probes        int
+	onlineSignals chan struct{}
+}
+
+func newFakeConnection(failures int) *fakeConnection {
+	return &fakeConnection{
+		offline:       true,
+		failuresLeft:  failures,
+		onlineSignals: make(chan struct{}, 10),
+	}
+}
+
+func (f *fakeConnection) probe(ctx context.Context) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.probes++
+	if f.failuresLeft > 0 {
+		f.failuresLeft--
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func (f *fakeConnection) isOffline() bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.offline
+}
+
+func (f *fakeConnection) onOnline() {
+	f.mutex.Lock()
+	f.offline = false
+	f.mutex.Unlock()
+	f.onlineSignals <- struct{}{}
+}
+
+func (f *fakeConnection) probeCount() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.probes
+}
+
+func fastConfig() Config {
+	return Config{
+		MinDelay:    5 * time.Millisecond,
+		MaxDelay:    20 * time.Millisecond,
+		SettleDelay: time.Millisecond,
+	}
+}
+
+func TestMonitor_RestoresAfterFailedProbes(t *testing.T) {
+	conn := newFakeConnection(3)
+	monitor := NewMonitor(conn.probe, conn.isOffline, conn.onOnline, fastConfig())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go monitor.Run(ctx)
+
+	select {
+	case <-conn.onlineSignals:
+	case <-time.After(5 * time.Second):
+		t.Fatal("monitor never reported connectivity restored")
+	}
+
+	assert.Equal(t, 4, conn.probeCount())
+	assert.False(t, conn.isOffline())
+}
+
+func TestMonitor_DoesNotProbeWhileOnline(t *testing.T) {
+	conn := newFakeConnection(0)
+	conn.offline = false
+	monitor := NewMonitor(conn.probe, conn.isOffline, conn.onOnline, fastConfig())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	monitor.Run(ctx)
+
+	assert.Equal(t, 0, conn.probeCount())
+}
+
+func TestMonitor_NetworkChangeTriggersProbe(t *testing.T) {
+	conn := newFakeConnection(0)
+	config := fastConfig()
+	config.MinDelay = time.Hour
+	config.MaxDelay = time.Hour
+	monitor := NewMonitor(conn.probe, conn.isOffline, conn.onOnline, config)
+
+	changes := make(chan struct{}, 1)
+	monitor.SetNetworkChanges(changes)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go monitor.Run(ctx)
+
+	// Without a network change the next probe is an hour away
+	changes <- struct{}{}
+
+	select {
+	case <-conn.onlineSignals:
+	case <-time.After(5 * time.Second):
+		t.Fatal("network change did not trigger a probe")
+	}
+	assert.Equal(t, 1, conn.probeCount())
+}
+
+func TestMonitor_StopsOnCancel(t *testing.T) {
+	conn := newFakeConnection(0)
+	monitor := NewMonitor(conn.probe, conn.isOffline, conn.onOnline, DefaultConfig())
+
+	changes := make(chan struct{})
+	close(changes)
+	monitor.SetNetworkChanges(changes)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		monitor.Run(ctx)
+		close(done)
+	}()
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("monitor did not stop when its context was canceled")
+	}
+	assert.Equal(t, 0, conn.probeCount())
+}
+
+func TestNextDelay(t *testing.T) {
+	assert.Equal(t, 10*time.Second, nextDelay(5*time.Second, time.Minute))
+	assert.Equal(t, time.Minute, nextDelay(45*time.Second, time.Minute))
+}
+
+func TestJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		wait := jitter(10 * time.Second)
+		assert.GreaterOrEqual(t, wait, 5*time.Second)
+		assert.LessOrEqual(t, wait, 10*time.Second)
+	}
+
+	assert.Equal(t, time.Duration(0), jitter(0))
+}
+
+func TestWatchNetworkChanges_StopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	changes, err := WatchNetworkChanges(ctx)
+	if err != nil {
+		cancel()
+		t.Skipf("network change notifications unavailable: %v", err)
+	}
+	require.NotNil(t, changes)
+
+	cancel()
+
+	deadline := time.After(10 * time.Second)
+	for {
+		select {
+		case _, ok := <-changes:
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatal("watcher did not stop when its context was canceled")
+		}
+	}
+}
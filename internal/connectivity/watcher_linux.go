@@ -0,0 +1,65 @@
+//go:build linux
+
+package connectivity
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+)
+
+// Netlink multicast groups from linux/rtnetlink.h, which the syscall package doesn't define
+const (
+	rtmgrpLink       = 0x1
+	rtmgrpIPv4IfAddr = 0x10
+	rtmgrpIPv4Route  = 0x40
+	rtmgrpIPv6IfAddr = 0x100
+)
+
+// WatchNetworkChanges reports changes to network links, addresses and routes using a netlink socket.
+// The channel is closed when ctx is canceled.
+func WatchNetworkChanges(ctx context.Context) (<-chan struct{}, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_ROUTE)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open netlink socket: %w", err)
+	}
+
+	addr := &syscall.SockaddrNetlink{
+		Family: syscall.AF_NETLINK,
+		Groups: rtmgrpLink | rtmgrpIPv4IfAddr | rtmgrpIPv6IfAddr | rtmgrpIPv4Route,
+	}
+	if err := syscall.Bind(fd, addr); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("failed to subscribe to network changes: %w", err)
+	}
+
+	// Wake up regularly so the watcher notices ctx being canceled
+	timeout := syscall.Timeval{Sec: 1}
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &timeout); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("failed to set netlink socket timeout: %w", err)
+	}
+
+	changes := make(chan struct{}, 1)
+
+	go func() {
+		defer close(changes)
+		defer syscall.Close(fd)
+
+		buf := make([]byte, 8192)
+		for ctx.Err() == nil {
+			n, _, err := syscall.Recvfrom(fd, buf, 0)
+			if err != nil {
+				if err == syscall.EAGAIN || err == syscall.EINTR {
+					continue
+				}
+				return
+			}
+			if n > 0 {
+				notify(changes)
+			}
+		}
+	}()
+
+	return changes, nil
+}
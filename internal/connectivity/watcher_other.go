@@ -0,0 +1,77 @@
+//go:build !linux
+
+package connectivity
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// addressPollInterval is how often interface addresses are compared on platforms without notifications
+const addressPollInterval = 5 * time.Second
+
+// WatchNetworkChanges reports changes to the machine's network addresses. The standard library has no
+// change notifications on this platform, so the addresses are polled. The channel is closed when ctx is canceled.
+func WatchNetworkChanges(ctx context.Context) (<-chan struct{}, error) {
+	last, err := interfaceAddresses()
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(chan struct{}, 1)
+
+	go func() {
+		defer close(changes)
+
+		ticker := time.NewTicker(addressPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			current, err := interfaceAddresses()
+			if err != nil {
+				continue
+			}
+			if current != last {
+				last = current
+				notify(changes)
+			}
+		}
+	}()
+
+	return changes, nil
+}
+
+// interfaceAddresses returns the addresses of all interfaces that are up, in a comparable form
+func interfaceAddresses() (string, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return "", fmt.Errorf("failed to list network interfaces: %w", err)
+	}
+
+	var addresses []string
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			addresses = append(addresses, iface.Name+"="+addr.String())
+		}
+	}
+
+	sort.Strings(addresses)
+	return strings.Join(addresses, ","), nil
+}
@@ -79,36 +79,17 @@ func (em *ExpirationManagerImpl) SetExpiration(fileID string, duration time.Dura
 
 // CheckExpirations checks for files that have expired and returns them
 func (em *ExpirationManagerImpl) CheckExpirations() ([]*models.FileMetadata, error) {
-	// Get all files from database
-	storageFiles, err := em.db.ListFiles()
+	// Files of every profile that are past their expiration but not yet marked as expired or deleted
+	expiredFiles, err := em.db.QueryFiles(models.FileQuery{
+		ExpiresBefore:    time.Now(),
+		ExcludedStatuses: []models.FileStatus{models.StatusExpired, models.StatusDeleted},
+		SortBy:           models.SortByExpiration,
+		Ascending:        true,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list files: %w", err)
 	}
 	
-	now := time.Now()
-	var expiredFiles []*models.FileMetadata
-	
-	// Check each file for expiration
-	for _, storageFile := range storageFiles {
-		// Only check files that are not already marked as expired or deleted
-		if storageFile.Status != storage.StatusExpired && storageFile.Status != storage.StatusDeleted {
-			if storageFile.ExpirationDate.Before(now) {
-				// Convert storage.FileMetadata to models.FileMetadata
-				expiredFile := &models.FileMetadata{
-					ID:             storageFile.ID,
-					FileName:       storageFile.FileName,
-					FilePath:       storageFile.FilePath,
-					FileSize:       storageFile.FileSize,
-					UploadDate:     storageFile.UploadDate,
-					ExpirationDate: storageFile.ExpirationDate,
-					S3Key:          storageFile.S3Key,
-					Status:         models.FileStatus(storageFile.Status),
-				}
-				expiredFiles = append(expiredFiles, expiredFile)
-			}
-		}
-	}
-	
 	em.logger.Info(fmt.Sprintf("Found %d expired files", len(expiredFiles)))
 	
 	return expiredFiles, nil
@@ -132,7 +113,7 @@ func (em *ExpirationManagerImpl) CleanupExpiredFiles() error {
 	cleanedCount := 0
 	
 	for _, file := range expiredFiles {
-		err := em.db.UpdateFileStatus(file.ID, storage.StatusExpired)
+		err := em.db.UpdateFileStatus(file.ID, models.StatusExpired)
 		if err != nil {
 			cleanupErrors = append(cleanupErrors, fmt.Sprintf("failed to update status for file %s: %v", file.ID, err))
 			em.logger.Error(fmt.Sprintf("Failed to update status for expired file %s: %v", file.ID, err))
@@ -164,14 +145,14 @@ func (em *ExpirationManagerImpl) IsFileExpired(fileID string) (bool, error) {
 	}
 	
 	// Get file from database
-	storageFile, err := em.db.GetFile(fileID)
+	file, err := em.db.GetFile(fileID)
 	if err != nil {
 		return false, fmt.Errorf("failed to get file: %w", err)
 	}
 	
 	// Check if file has expired
 	now := time.Now()
-	isExpired := storageFile.ExpirationDate.Before(now)
+	isExpired := file.ExpirationDate.Before(now)
 	
 	return isExpired, nil
 }
@@ -183,14 +164,14 @@ func (em *ExpirationManagerImpl) GetTimeUntilExpiration(fileID string) (time.Dur
 	}
 	
 	// Get file from database
-	storageFile, err := em.db.GetFile(fileID)
+	file, err := em.db.GetFile(fileID)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get file: %w", err)
 	}
 	
 	// Calculate time until expiration
 	now := time.Now()
-	timeUntilExpiration := storageFile.ExpirationDate.Sub(now)
+	timeUntilExpiration := file.ExpirationDate.Sub(now)
 	
 	// If already expired, return 0
 	if timeUntilExpiration < 0 {
@@ -202,27 +183,21 @@ func (em *ExpirationManagerImpl) GetTimeUntilExpiration(fileID string) (time.Dur
 
 // CleanupExpiredMetadata removes local metadata for files that have been expired for more than 30 days
 func (em *ExpirationManagerImpl) CleanupExpiredMetadata() error {
-	// Get all files from database
-	storageFiles, err := em.db.ListFiles()
-	if err != nil {
-		return fmt.Errorf("failed to list files: %w", err)
-	}
-	
-	now := time.Now()
 	const metadataRetentionDays = 30
 	metadataRetentionDuration := time.Duration(metadataRetentionDays) * 24 * time.Hour
 	
-	var filesToDelete []string
-	
 	// Find files that have been expired for more than 30 days
-	for _, storageFile := range storageFiles {
-		if storageFile.Status == storage.StatusExpired {
-			// Check if the file has been expired for more than 30 days
-			timeSinceExpiration := now.Sub(storageFile.ExpirationDate)
-			if timeSinceExpiration > metadataRetentionDuration {
-				filesToDelete = append(filesToDelete, storageFile.ID)
-			}
-		}
+	expiredFiles, err := em.db.QueryFiles(models.FileQuery{
+		Statuses:      []models.FileStatus{models.StatusExpired},
+		ExpiresBefore: time.Now().Add(-metadataRetentionDuration),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list files: %w", err)
+	}
+	
+	filesToDelete := make([]string, len(expiredFiles))
+	for i, file := range expiredFiles {
+		filesToDelete[i] = file.ID
 	}
 	
 	if len(filesToDelete) == 0 {
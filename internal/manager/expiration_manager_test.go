@@ -256,6 +256,28 @@ func TestExpirationManager_CheckExpirations(t *testing.T) {
 	}
 }
 
+func TestExpirationManager_CheckExpirations_AllProfiles(t *testing.T) {
+	db, _ := createTempDatabaseForExpiration(t)
+	defer db.Close()
+	
+	em := NewExpirationManager(db)
+	
+	now := time.Now()
+	older := createTestFileMetadata("work-expired", "work.txt", now.Add(-48*time.Hour), storage.StatusActive)
+	older.Profile = "work"
+	newer := createTestFileMetadata("default-expired", "default.txt", now.Add(-time.Hour), storage.StatusActive)
+	require.NoError(t, db.SaveFile(older))
+	require.NoError(t, db.SaveFile(newer))
+	
+	// Files of every profile are checked, the longest expired first
+	expiredFiles, err := em.CheckExpirations()
+	require.NoError(t, err)
+	require.Len(t, expiredFiles, 2)
+	assert.Equal(t, "work-expired", expiredFiles[0].ID)
+	assert.Equal(t, "work", expiredFiles[0].Profile)
+	assert.Equal(t, "default-expired", expiredFiles[1].ID)
+}
+
 func TestExpirationManager_CheckExpirations_EmptyDatabase(t *testing.T) {
 	db, _ := createTempDatabaseForExpiration(t)
 	defer db.Close()
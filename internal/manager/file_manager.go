@@ -2,15 +2,21 @@ package manager
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/google/uuid"
 
 	"file-sharing-app/internal/aws"
 	"file-sharing-app/internal/models"
+	"file-sharing-app/internal/scanner"
 	"file-sharing-app/internal/storage"
 	"file-sharing-app/pkg/logger"
 )
@@ -35,24 +41,83 @@ type FileManager interface {
 	// CreateFileRecord creates a new file metadata record with generated ID
 	CreateFileRecord(fileName, filePath string, fileSize int64, s3Key string, expirationDate time.Time) (*models.FileMetadata, error)
 	
+	// QueryFiles retrieves the files matching a search and filters, in the query's sort order
+	QueryFiles(query models.FileQuery) ([]*models.FileMetadata, error)
+	
 	// GetFilesByStatus retrieves files filtered by status
 	GetFilesByStatus(status models.FileStatus) ([]*models.FileMetadata, error)
 	
 	// GetExpiredFiles retrieves files that have passed their expiration date
 	GetExpiredFiles() ([]*models.FileMetadata, error)
 	
-	// UploadFile uploads a file to S3 and stores metadata locally
+	// UploadFile uploads a file to S3 and stores metadata locally. A file identical to one stored already
+	// shares its object instead of being uploaded again. When virus scanning is on, files the scanner
+	// finds something in are not uploaded.
 	UploadFile(ctx context.Context, filePath string, expiration time.Duration, progressCh chan<- aws.UploadProgress) (*models.FileMetadata, error)
 	
+	// CreatePendingUpload records a file to be uploaded later, e.g. while offline
+	CreatePendingUpload(filePath string, expiration time.Duration) (*models.FileMetadata, error)
+	
+	// UploadPendingFile uploads a file recorded by CreatePendingUpload
+	UploadPendingFile(ctx context.Context, fileID string, progressCh chan<- aws.UploadProgress) (*models.FileMetadata, error)
+	
+	// RetryUpload uploads a file again after its upload failed
+	RetryUpload(ctx context.Context, fileID string, progressCh chan<- aws.UploadProgress) (*models.FileMetadata, error)
+	
+	// RecoverInterruptedUploads finishes the uploads left behind when the app stopped mid-upload
+	RecoverInterruptedUploads(ctx context.Context) (*RecoveryResult, error)
+	
+	// RemoveFile deletes a file's object from S3, unless other files are stored in it, and then its local record
+	RemoveFile(ctx context.Context, fileID string) error
+	
 	// GeneratePresignedURL generates a presigned URL for file sharing
 	GeneratePresignedURL(ctx context.Context, fileID string, expiration time.Duration) (string, error)
+	
+	// DownloadFile downloads a file from S3 to destPath, supplying the SSE-C key if the file needs it
+	DownloadFile(ctx context.Context, fileID string, destPath string) error
+	
+	// SetProfile switches the profile new records are tagged with and the S3 service used for it
+	SetProfile(profile string, s3Service aws.S3Service)
+	
+	// GetProfile returns the profile currently in use
+	GetProfile() string
+	
+	// SetUploadSettings applies the settings for how uploads are checked, stored and sent: virus scanning,
+	// content types by extension, compression and the bandwidth limit shared by all uploads, which also
+	// slows down uploads already running
+	SetUploadSettings(settings *models.ApplicationSettings)
+}
+
+// RecoveryResult contains the results of recovering interrupted uploads
+type RecoveryResult struct {
+	Completed int      `json:"completed"` // found in S3 and marked active
+	Resumed   int      `json:"resumed"`   // uploaded again from the local file
+	Failed    int      `json:"failed"`    // marked as errors, to be retried or deleted
+	Remaining int      `json:"remaining"` // left uploading as S3 couldn't be checked
+	Failures  []string `json:"failures"`
 }
 
 // FileManagerImpl implements the FileManager interface
 type FileManagerImpl struct {
 	db        storage.Database
 	s3Service aws.S3Service
+	profile   string
 	logger    *logger.Logger
+	mutex     sync.RWMutex
+	
+	// Upload settings: content types by extension, and the compression applied when it saves enough
+	contentTypeOverrides  map[string]string
+	compression           string
+	compressionMinSavings int
+	
+	// Files this process is uploading; recovery leaves them alone
+	uploading map[string]bool
+	
+	// Paces all uploads together to the upload bandwidth limit in the settings
+	bandwidth *aws.BandwidthLimiter
+	
+	// Scans files before they are uploaded; nil when scanning is off
+	scanner scanner.Scanner
 }
 
 // NewFileManager creates a new FileManager instance
@@ -60,16 +125,168 @@ func NewFileManager(db storage.Database, s3Service aws.S3Service) FileManager {
 	return &FileManagerImpl{
 		db:        db,
 		s3Service: s3Service,
+		profile:   storage.DefaultProfile,
 		logger:    logger.New(),
+		bandwidth: aws.NewBandwidthLimiter(models.NewBandwidthLimit(0)),
 	}
 }
 
 // NewFileManagerWithoutS3 creates a new FileManager instance without S3 service (for testing)
 func NewFileManagerWithoutS3(db storage.Database) FileManager {
 	return &FileManagerImpl{
-		db:     db,
-		logger: logger.New(),
+		db:        db,
+		profile:   storage.DefaultProfile,
+		logger:    logger.New(),
+		bandwidth: aws.NewBandwidthLimiter(models.NewBandwidthLimit(0)),
+	}
+}
+
+// SetProfile switches the profile new records are tagged with and the S3 service used for it
+func (fm *FileManagerImpl) SetProfile(profile string, s3Service aws.S3Service) {
+	if profile == "" {
+		profile = storage.DefaultProfile
+	}
+	
+	fm.mutex.Lock()
+	defer fm.mutex.Unlock()
+	
+	fm.profile = profile
+	fm.s3Service = s3Service
+}
+
+// GetProfile returns the profile currently in use
+func (fm *FileManagerImpl) GetProfile() string {
+	fm.mutex.RLock()
+	defer fm.mutex.RUnlock()
+	
+	return fm.profile
+}
+
+// SetUploadSettings applies the settings for how uploads are checked and stored: virus scanning, content types
+// by extension and compression. The upload bandwidth limit applies to uploads already running too.
+func (fm *FileManagerImpl) SetUploadSettings(settings *models.ApplicationSettings) {
+	fm.mutex.Lock()
+	defer fm.mutex.Unlock()
+	
+	fm.scanner = scanner.New(settings)
+	fm.contentTypeOverrides = settings.ContentTypeOverrides
+	fm.compression = settings.UploadCompression
+	fm.compressionMinSavings = settings.GetCompressionMinSavings()
+	fm.bandwidth.Limit().Set(settings.UploadBandwidthLimitKBps)
+}
+
+// detectContentType returns the content type a file is uploaded with, from its name, the start of
+// its content and the content types set in the settings
+func (fm *FileManagerImpl) detectContentType(fileName, filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	
+	head := make([]byte, models.ContentSniffLength)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	
+	fm.mutex.RLock()
+	defer fm.mutex.RUnlock()
+	
+	return models.DetectContentType(fileName, head[:n], fm.contentTypeOverrides), nil
+}
+
+// compress returns a compressed copy of a file to upload instead of it, or nil if compression is
+// off, the content is compressed already, or compressing doesn't save enough
+func (fm *FileManagerImpl) compress(filePath, contentType string) *aws.CompressedFile {
+	fm.mutex.RLock()
+	compression, minSavings := fm.compression, fm.compressionMinSavings
+	fm.mutex.RUnlock()
+	
+	if compression == "" || !models.IsCompressible(contentType) {
+		return nil
+	}
+	
+	compressed, err := aws.CompressFile(filePath, compression)
+	if err != nil {
+		// The file is uploaded as it is; if it can't be read, the upload reports why
+		fm.logger.Warn(fmt.Sprintf("Failed to compress %s, uploading it uncompressed: %v", filepath.Base(filePath), err))
+		return nil
+	}
+	if compressed.SavingsPercent() <= float64(minSavings) {
+		compressed.Remove()
+		return nil
+	}
+	return compressed
+}
+
+// scanFile scans a file for viruses and malware before it is uploaded. It returns nil when scanning
+// is off, the result along with an *models.InfectedFileError when the scanner finds something, and
+// an *models.ScanFailedError when it can't scan the file.
+func (fm *FileManagerImpl) scanFile(ctx context.Context, fileName, filePath string) (*models.ScanResult, error) {
+	fm.mutex.RLock()
+	fileScanner := fm.scanner
+	fm.mutex.RUnlock()
+	
+	if fileScanner == nil {
+		return nil, nil
+	}
+	
+	result, err := fileScanner.Scan(ctx, filePath)
+	if err != nil {
+		return nil, &models.ScanFailedError{FileName: fileName, Err: err}
+	}
+	if result.Infected() {
+		fm.logger.Warn(fmt.Sprintf("Blocked the upload of %s: %s found %s", fileName, result.Engine, result.Signature))
+		return result, &models.InfectedFileError{FileName: fileName, Result: result}
+	}
+	return result, nil
+}
+
+// recordInfected records the verdict of a scan that found something in a file, and marks the file
+// as an error, as it can't be uploaded
+func (fm *FileManagerImpl) recordInfected(fileID string, scan *models.ScanResult) error {
+	return fm.db.WithTransaction(func(tx storage.Database) error {
+		if err := tx.UpdateFileScan(fileID, scan.Verdict, scan.Engine); err != nil {
+			return fmt.Errorf("failed to save scan verdict: %w", err)
+		}
+		if err := tx.UpdateFileStatus(fileID, models.StatusError); err != nil {
+			return fmt.Errorf("failed to update status: %w", err)
+		}
+		return nil
+	})
+}
+
+// getS3Service returns the S3 service for the current profile
+func (fm *FileManagerImpl) getS3Service() aws.S3Service {
+	fm.mutex.RLock()
+	defer fm.mutex.RUnlock()
+	
+	return fm.s3Service
+}
+
+// beginUpload registers a file as being uploaded by this process, so recovery leaves it alone.
+// It returns false if the file is already being uploaded.
+func (fm *FileManagerImpl) beginUpload(fileID string) bool {
+	fm.mutex.Lock()
+	defer fm.mutex.Unlock()
+	
+	if fm.uploading == nil {
+		fm.uploading = make(map[string]bool)
+	}
+	if fm.uploading[fileID] {
+		return false
 	}
+	fm.uploading[fileID] = true
+	return true
+}
+
+// endUpload unregisters a file registered by beginUpload
+func (fm *FileManagerImpl) endUpload(fileID string) {
+	fm.mutex.Lock()
+	defer fm.mutex.Unlock()
+	
+	delete(fm.uploading, fileID)
 }
 
 // SaveFile saves file metadata to local storage
@@ -90,19 +307,11 @@ func (fm *FileManagerImpl) SaveFile(file *models.FileMetadata) error {
 		return fmt.Errorf("S3 key cannot be empty")
 	}
 	
-	// Convert models.FileMetadata to storage.FileMetadata
-	storageFile := &storage.FileMetadata{
-		ID:             file.ID,
-		FileName:       file.FileName,
-		FilePath:       file.FilePath,
-		FileSize:       file.FileSize,
-		UploadDate:     file.UploadDate,
-		ExpirationDate: file.ExpirationDate,
-		S3Key:          file.S3Key,
-		Status:         storage.FileStatus(file.Status),
+	if file.Profile == "" {
+		file.Profile = fm.GetProfile()
 	}
 	
-	return fm.db.SaveFile(storageFile)
+	return fm.db.SaveFile(file)
 }
 
 // GetFile retrieves file metadata by ID
@@ -111,46 +320,31 @@ func (fm *FileManagerImpl) GetFile(fileID string) (*models.FileMetadata, error)
 		return nil, fmt.Errorf("file ID cannot be empty")
 	}
 	
-	storageFile, err := fm.db.GetFile(fileID)
-	if err != nil {
-		return nil, err
-	}
-	
-	// Convert storage.FileMetadata to models.FileMetadata
-	return &models.FileMetadata{
-		ID:             storageFile.ID,
-		FileName:       storageFile.FileName,
-		FilePath:       storageFile.FilePath,
-		FileSize:       storageFile.FileSize,
-		UploadDate:     storageFile.UploadDate,
-		ExpirationDate: storageFile.ExpirationDate,
-		S3Key:          storageFile.S3Key,
-		Status:         models.FileStatus(storageFile.Status),
-	}, nil
+	return fm.db.GetFile(fileID)
 }
 
-// ListFiles retrieves all file metadata records
+// ListFiles retrieves all file metadata records belonging to the current profile
 func (fm *FileManagerImpl) ListFiles() ([]*models.FileMetadata, error) {
-	storageFiles, err := fm.db.ListFiles()
-	if err != nil {
-		return nil, err
+	return fm.db.ListFilesByProfile(fm.GetProfile())
+}
+
+// QueryFiles retrieves the current profile's files matching a search and filters, in the query's sort order
+func (fm *FileManagerImpl) QueryFiles(query models.FileQuery) ([]*models.FileMetadata, error) {
+	if query.MaxSize > 0 && query.MinSize > query.MaxSize {
+		return nil, fmt.Errorf("minimum size cannot be larger than maximum size")
 	}
-	
-	files := make([]*models.FileMetadata, len(storageFiles))
-	for i, storageFile := range storageFiles {
-		files[i] = &models.FileMetadata{
-			ID:             storageFile.ID,
-			FileName:       storageFile.FileName,
-			FilePath:       storageFile.FilePath,
-			FileSize:       storageFile.FileSize,
-			UploadDate:     storageFile.UploadDate,
-			ExpirationDate: storageFile.ExpirationDate,
-			S3Key:          storageFile.S3Key,
-			Status:         models.FileStatus(storageFile.Status),
-		}
+	if !query.UploadedAfter.IsZero() && !query.UploadedBefore.IsZero() && !query.UploadedAfter.Before(query.UploadedBefore) {
+		return nil, fmt.Errorf("upload date range is empty")
+	}
+	if !query.ExpiresAfter.IsZero() && !query.ExpiresBefore.IsZero() && !query.ExpiresAfter.Before(query.ExpiresBefore) {
+		return nil, fmt.Errorf("expiration date range is empty")
+	}
+	if query.Limit < 0 || query.Offset < 0 {
+		return nil, fmt.Errorf("limit and offset cannot be negative")
 	}
 	
-	return files, nil
+	query.Profile = fm.GetProfile()
+	return fm.db.QueryFiles(query)
 }
 
 // UpdateFileStatus updates the status of a file
@@ -159,7 +353,7 @@ func (fm *FileManagerImpl) UpdateFileStatus(fileID string, status models.FileSta
 		return fmt.Errorf("file ID cannot be empty")
 	}
 	
-	return fm.db.UpdateFileStatus(fileID, storage.FileStatus(status))
+	return fm.db.UpdateFileStatus(fileID, status)
 }
 
 // DeleteFile removes file metadata from local storage
@@ -173,6 +367,11 @@ func (fm *FileManagerImpl) DeleteFile(fileID string) error {
 
 // CreateFileRecord creates a new file metadata record with generated ID
 func (fm *FileManagerImpl) CreateFileRecord(fileName, filePath string, fileSize int64, s3Key string, expirationDate time.Time) (*models.FileMetadata, error) {
+	return fm.createFileRecord(fileName, filePath, fileSize, s3Key, expirationDate, models.EncryptionSSES3, models.StatusUploading)
+}
+
+// createFileRecord creates a new file metadata record recording the encryption mode used for its upload
+func (fm *FileManagerImpl) createFileRecord(fileName, filePath string, fileSize int64, s3Key string, expirationDate time.Time, encryptionMode string, status models.FileStatus) (*models.FileMetadata, error) {
 	if fileName == "" {
 		return nil, fmt.Errorf("file name cannot be empty")
 	}
@@ -201,11 +400,19 @@ func (fm *FileManagerImpl) CreateFileRecord(fileName, filePath string, fileSize
 		UploadDate:     time.Now(),
 		ExpirationDate: expirationDate,
 		S3Key:          s3Key,
-		Status:         models.StatusUploading,
+		Status:         status,
+		Profile:        fm.GetProfile(),
+		EncryptionMode: encryptionMode,
+	}
+	
+	// Register the upload before its record exists, so recovery never sees it as interrupted
+	if status == models.StatusUploading {
+		fm.beginUpload(file.ID)
 	}
 	
 	err := fm.SaveFile(file)
 	if err != nil {
+		fm.endUpload(file.ID)
 		return nil, fmt.Errorf("failed to save file record: %w", err)
 	}
 	
@@ -214,108 +421,390 @@ func (fm *FileManagerImpl) CreateFileRecord(fileName, filePath string, fileSize
 
 // GetFilesByStatus retrieves files filtered by status
 func (fm *FileManagerImpl) GetFilesByStatus(status models.FileStatus) ([]*models.FileMetadata, error) {
-	allFiles, err := fm.ListFiles()
+	return fm.QueryFiles(models.FileQuery{Statuses: []models.FileStatus{status}})
+}
+
+// GetExpiredFiles retrieves files that have passed their expiration date
+func (fm *FileManagerImpl) GetExpiredFiles() ([]*models.FileMetadata, error) {
+	return fm.QueryFiles(models.FileQuery{
+		ExpiresBefore:    time.Now(),
+		ExcludedStatuses: []models.FileStatus{models.StatusExpired, models.StatusDeleted},
+		SortBy:           models.SortByExpiration,
+		Ascending:        true,
+	})
+}
+
+// UploadFile uploads a file to S3 and stores metadata locally. If an active file of the profile has
+// the same name and content, and its object can be kept until the new file expires, the new file
+// is recorded as stored in that object instead of being uploaded again. When virus scanning is on,
+// the file is scanned first; if the scanner finds something, or can't scan it, nothing is recorded
+// or uploaded.
+func (fm *FileManagerImpl) UploadFile(ctx context.Context, filePath string, expiration time.Duration, progressCh chan<- aws.UploadProgress) (*models.FileMetadata, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("file path cannot be empty")
+	}
+	
+	s3Service := fm.getS3Service()
+	if s3Service == nil {
+		return nil, fmt.Errorf("S3 service not configured")
+	}
+	
+	fileSize, err := validateUploadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
 	
-	var filteredFiles []*models.FileMetadata
-	for _, file := range allFiles {
-		if file.Status == status {
-			filteredFiles = append(filteredFiles, file)
-		}
+	fileName := filepath.Base(filePath)
+	
+	// Files the virus scanner finds something in are not uploaded
+	scan, err := fm.scanFile(ctx, fileName, filePath)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Calculate expiration date
+	expirationDate := time.Now().Add(expiration)
+	
+	// An identical file stored already is shared instead of uploaded again
+	if stored := fm.findStoredCopy(ctx, s3Service, filePath, fileName, expirationDate); stored != nil {
+		return fm.reuseStoredCopy(stored, filePath, fileSize, expirationDate, scan)
+	}
+	
+	// Generate UUID-based S3 key with timestamp prefix
+	s3Key := generateS3Key(fileName)
+	
+	// Create file record in database with uploading status
+	fileRecord, err := fm.createFileRecord(fileName, filePath, fileSize, s3Key, expirationDate, s3Service.EncryptionMode(), models.StatusUploading)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file record: %w", err)
 	}
 	
-	return filteredFiles, nil
+	return fm.uploadRecord(ctx, s3Service, fileRecord, expiration, models.StatusError, scan, progressCh)
 }
 
-// GetExpiredFiles retrieves files that have passed their expiration date
-func (fm *FileManagerImpl) GetExpiredFiles() ([]*models.FileMetadata, error) {
-	allFiles, err := fm.ListFiles()
+// findStoredCopy returns an active file of the current profile with the same name, content and
+// encryption as the file at filePath, whose object S3 can keep until expirationDate, or nil if
+// there is none. Anything that stops a copy from being reused just means the file is uploaded again.
+func (fm *FileManagerImpl) findStoredCopy(ctx context.Context, s3Service aws.S3Service, filePath, fileName string, expirationDate time.Time) *models.FileMetadata {
+	checksum, err := fileChecksum(filePath)
 	if err != nil {
-		return nil, err
+		fm.logger.Warn(fmt.Sprintf("Failed to hash %s, uploading it without looking for a stored copy: %v", fileName, err))
+		return nil
+	}
+	
+	candidates, err := fm.QueryFiles(models.FileQuery{
+		Checksum:     checksum,
+		Statuses:     []models.FileStatus{models.StatusActive},
+		ExpiresAfter: time.Now(),
+	})
+	if err != nil {
+		fm.logger.Warn(fmt.Sprintf("Failed to look for a stored copy of %s: %v", fileName, err))
+		return nil
+	}
+	
+	// Downloads are named after the file the object was uploaded for, so only the same name is reused
+	encryptionMode := s3Service.EncryptionMode()
+	checked := make(map[string]bool)
+	for _, candidate := range candidates {
+		if candidate.FileName != fileName || candidate.EncryptionMode != encryptionMode || checked[candidate.S3Key] {
+			continue
+		}
+		checked[candidate.S3Key] = true
+		
+		if fm.keepStoredCopy(ctx, s3Service, candidate, checksum, expirationDate) {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// keepStoredCopy checks a file's object is still in S3 with the given content, and extends its
+// lifecycle tag so S3 keeps it until expirationDate. It returns false if the object can't be reused.
+func (fm *FileManagerImpl) keepStoredCopy(ctx context.Context, s3Service aws.S3Service, file *models.FileMetadata, checksum string, expirationDate time.Time) bool {
+	headCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	head, err := s3Service.HeadObject(headCtx, file.S3Key)
+	cancel()
+	if err != nil {
+		fm.logger.Info(fmt.Sprintf("Not reusing the stored copy of %s (S3 key: %s): %v", file.FileName, file.S3Key, err))
+		return false
+	}
+	if stored := aws.ContentChecksumFromHead(head); stored != "" && stored != checksum {
+		return false
 	}
 	
-	now := time.Now()
-	var expiredFiles []*models.FileMetadata
+	// Lifecycle rules count from the object's upload, so they can't keep it past the longest rule
+	if head.LastModified == nil {
+		return false
+	}
+	lifetime := expirationDate.Sub(*head.LastModified)
+	if lifetime > maxObjectLifetime {
+		return false
+	}
+	
+	if err := s3Service.ExtendExpiration(ctx, file.S3Key, getExpirationTag(lifetime)); err != nil {
+		fm.logger.Warn(fmt.Sprintf("Failed to extend the stored copy of %s (S3 key: %s): %v", file.FileName, file.S3Key, err))
+		return false
+	}
+	return true
+}
+
+// reuseStoredCopy records the file at filePath as stored in the object of an identical file,
+// without uploading it again. Without a scan of its own, the file takes the stored copy's verdict.
+func (fm *FileManagerImpl) reuseStoredCopy(stored *models.FileMetadata, filePath string, fileSize int64, expirationDate time.Time, scan *models.ScanResult) (*models.FileMetadata, error) {
+	fileRecord, err := fm.createFileRecord(stored.FileName, filePath, fileSize, stored.S3Key, expirationDate, stored.EncryptionMode, models.StatusUploading)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file record: %w", err)
+	}
+	defer fm.endUpload(fileRecord.ID)
 	
-	for _, file := range allFiles {
-		if file.ExpirationDate.Before(now) && file.Status != models.StatusExpired && file.Status != models.StatusDeleted {
-			expiredFiles = append(expiredFiles, file)
+	object := uploadedObject{
+		checksum:    stored.Checksum,
+		etag:        stored.ETag,
+		contentType: stored.ContentType,
+		compression: stored.Compression,
+		size:        stored.StoredSize,
+		scan:        scan,
+	}
+	if scan == nil && stored.ScanVerdict != "" {
+		object.scan = &models.ScanResult{Verdict: stored.ScanVerdict, Engine: stored.ScanEngine}
+	}
+	if err := fm.markUploaded(fileRecord.ID, object); err != nil {
+		// Nothing was uploaded for the record, so there is nothing to retry
+		if deleteErr := fm.DeleteFile(fileRecord.ID); deleteErr != nil {
+			return nil, fmt.Errorf("failed to record stored copy: %w, and failed to remove the record: %w", err, deleteErr)
 		}
+		return nil, fmt.Errorf("failed to record stored copy: %w", err)
 	}
 	
-	return expiredFiles, nil
+	fm.logger.Info(fmt.Sprintf("Reused the stored copy of %s (S3 key: %s) instead of uploading it again", stored.FileName, stored.S3Key))
+	
+	return fm.GetFile(fileRecord.ID)
 }
 
-// UploadFile uploads a file to S3 and stores metadata locally
-func (fm *FileManagerImpl) UploadFile(ctx context.Context, filePath string, expiration time.Duration, progressCh chan<- aws.UploadProgress) (*models.FileMetadata, error) {
+// fileChecksum returns the hex SHA-256 of a file's content
+func fileChecksum(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// CreatePendingUpload records a file to be uploaded later, e.g. while offline
+func (fm *FileManagerImpl) CreatePendingUpload(filePath string, expiration time.Duration) (*models.FileMetadata, error) {
 	if filePath == "" {
 		return nil, fmt.Errorf("file path cannot be empty")
 	}
 	
-	if fm.s3Service == nil {
+	if expiration <= 0 {
+		return nil, fmt.Errorf("expiration duration must be positive")
+	}
+	
+	fileSize, err := validateUploadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	
+	// The encryption mode is confirmed against the S3 service when the upload runs
+	encryptionMode := models.EncryptionSSES3
+	if s3Service := fm.getS3Service(); s3Service != nil {
+		encryptionMode = s3Service.EncryptionMode()
+	}
+	
+	fileName := filepath.Base(filePath)
+	fileRecord, err := fm.createFileRecord(fileName, filePath, fileSize, generateS3Key(fileName), time.Now().Add(expiration), encryptionMode, models.StatusPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file record: %w", err)
+	}
+	
+	return fileRecord, nil
+}
+
+// UploadPendingFile uploads a file recorded by CreatePendingUpload. If the upload fails the
+// file stays pending so it can be retried.
+func (fm *FileManagerImpl) UploadPendingFile(ctx context.Context, fileID string, progressCh chan<- aws.UploadProgress) (*models.FileMetadata, error) {
+	return fm.uploadExisting(ctx, fileID, models.StatusPending, progressCh)
+}
+
+// RetryUpload uploads a file again after its upload failed, from the same local file and to the same
+// S3 key. If it fails again the file keeps its error status.
+func (fm *FileManagerImpl) RetryUpload(ctx context.Context, fileID string, progressCh chan<- aws.UploadProgress) (*models.FileMetadata, error) {
+	return fm.uploadExisting(ctx, fileID, models.StatusError, progressCh)
+}
+
+// uploadExisting uploads the file behind a record with the given status, which it returns to if the upload fails
+func (fm *FileManagerImpl) uploadExisting(ctx context.Context, fileID string, status models.FileStatus, progressCh chan<- aws.UploadProgress) (*models.FileMetadata, error) {
+	if fileID == "" {
+		return nil, fmt.Errorf("file ID cannot be empty")
+	}
+	
+	s3Service := fm.getS3Service()
+	if s3Service == nil {
 		return nil, fmt.Errorf("S3 service not configured")
 	}
 	
+	fileRecord, err := fm.GetFile(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file metadata: %w", err)
+	}
+	
+	if fileRecord.Status != status {
+		if status == models.StatusPending {
+			return nil, fmt.Errorf("file is not waiting to be uploaded (status: %s)", fileRecord.Status)
+		}
+		return nil, fmt.Errorf("only failed uploads can be retried (status: %s)", fileRecord.Status)
+	}
+	
+	expiration := time.Until(fileRecord.ExpirationDate)
+	if expiration <= 0 {
+		return nil, fmt.Errorf("file expired before it could be uploaded")
+	}
+	
+	// The file may have changed or been removed since it was recorded
+	if _, err := validateUploadFile(fileRecord.FilePath); err != nil {
+		return nil, fmt.Errorf("file can no longer be uploaded: %w", err)
+	}
+	
+	if !fm.beginUpload(fileID) {
+		return nil, fmt.Errorf("file is already being uploaded")
+	}
+	
+	// Files the virus scanner finds something in are not uploaded. If it can't scan the file, it
+	// keeps its status so it can be tried again.
+	scan, err := fm.scanFile(ctx, fileRecord.FileName, fileRecord.FilePath)
+	if err != nil {
+		fm.endUpload(fileID)
+		if scan.Infected() {
+			if recordErr := fm.recordInfected(fileID, scan); recordErr != nil {
+				return nil, fmt.Errorf("%w, and failed to record it: %w", err, recordErr)
+			}
+		}
+		return nil, err
+	}
+	
+	// Record the encryption actually used, in case the settings changed since the record was created
+	mode := s3Service.EncryptionMode()
+	err = fm.db.WithTransaction(func(tx storage.Database) error {
+		if mode != fileRecord.EncryptionMode {
+			if err := tx.UpdateFileEncryptionMode(fileID, mode); err != nil {
+				return fmt.Errorf("failed to update encryption mode: %w", err)
+			}
+		}
+		if err := tx.UpdateFileStatus(fileID, models.StatusUploading); err != nil {
+			return fmt.Errorf("failed to update file status: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		fm.endUpload(fileID)
+		return nil, err
+	}
+	fileRecord.EncryptionMode = mode
+	
+	return fm.uploadRecord(ctx, s3Service, fileRecord, expiration, status, scan, progressCh)
+}
+
+// validateUploadFile checks a file can be uploaded and returns its size
+func validateUploadFile(filePath string) (int64, error) {
 	// Check if file exists and get file info
 	fileInfo, err := os.Stat(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get file info: %w", err)
+		return 0, fmt.Errorf("failed to get file info: %w", err)
 	}
 	
 	if fileInfo.IsDir() {
-		return nil, fmt.Errorf("path is a directory, not a file")
+		return 0, fmt.Errorf("path is a directory, not a file")
 	}
 	
 	fileSize := fileInfo.Size()
 	if fileSize == 0 {
-		return nil, fmt.Errorf("file is empty")
+		return 0, fmt.Errorf("file is empty")
 	}
 	
 	// Check file size limit (100MB as per requirement 2.5)
 	const maxFileSize = 100 * 1024 * 1024 // 100MB
 	if fileSize > maxFileSize {
-		return nil, fmt.Errorf("file size (%d bytes) exceeds maximum allowed size (%d bytes)", fileSize, maxFileSize)
+		return 0, fmt.Errorf("file size (%d bytes) exceeds maximum allowed size (%d bytes)", fileSize, maxFileSize)
 	}
 	
-	fileName := filepath.Base(filePath)
-	
-	// Generate UUID-based S3 key with timestamp prefix
-	s3Key := generateS3Key(fileName)
-	
-	// Calculate expiration date
-	expirationDate := time.Now().Add(expiration)
+	return fileSize, nil
+}
+
+// uploadRecord uploads the file behind a record to S3 and marks it active, recording the verdict
+// of its virus scan, if it was scanned. If the upload fails the record is set to failStatus. The
+// upload must have been registered with beginUpload, and is unregistered once it has finished.
+func (fm *FileManagerImpl) uploadRecord(ctx context.Context, s3Service aws.S3Service, fileRecord *models.FileMetadata, expiration time.Duration, failStatus models.FileStatus, scan *models.ScanResult, progressCh chan<- aws.UploadProgress) (*models.FileMetadata, error) {
+	defer fm.endUpload(fileRecord.ID)
 	
-	// Create file record in database with uploading status
-	fileRecord, err := fm.CreateFileRecord(fileName, filePath, fileSize, s3Key, expirationDate)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create file record: %w", err)
+	// The verdict is recorded before anything is sent, so it is kept if the upload is interrupted
+	if scan != nil {
+		if err := fm.db.UpdateFileScan(fileRecord.ID, scan.Verdict, scan.Engine); err != nil {
+			if updateErr := fm.UpdateFileStatus(fileRecord.ID, failStatus); updateErr != nil {
+				return nil, fmt.Errorf("failed to save scan verdict: %w, and failed to update status: %w", err, updateErr)
+			}
+			return nil, fmt.Errorf("failed to save scan verdict: %w", err)
+		}
 	}
 	
 	// Prepare metadata for S3
 	metadata := map[string]string{
 		"file-id":         fileRecord.ID,
-		"original-name":   fileName,
-		"expiration-date": expirationDate.UTC().Format(time.RFC3339),
+		"original-name":   fileRecord.FileName,
+		"expiration-date": fileRecord.ExpirationDate.UTC().Format(time.RFC3339),
 		"expiration-tag":  getExpirationTag(expiration),
 	}
 	
-	// Upload file to S3
-	err = fm.s3Service.UploadFile(ctx, s3Key, filePath, metadata, progressCh)
+	// If the file can't be read, the upload fails below and reports why
+	contentType, err := fm.detectContentType(fileRecord.FileName, fileRecord.FilePath)
+	if err == nil {
+		metadata["content-type"] = contentType
+	}
+	
+	// Upload a compressed copy instead when it saves enough
+	uploadPath := fileRecord.FilePath
+	compressed := fm.compress(fileRecord.FilePath, contentType)
+	if compressed != nil {
+		defer compressed.Remove()
+		uploadPath = compressed.Path
+		metadata["content-encoding"] = compressed.Encoding
+		metadata["original-sha256"] = compressed.OriginalChecksum
+	}
+	
+	// Upload file to S3, within the bandwidth limit shared by all uploads
+	ctx = aws.WithBandwidthLimiter(ctx, fm.bandwidth)
+	uploadResult, err := s3Service.UploadFile(ctx, fileRecord.S3Key, uploadPath, metadata, progressCh)
 	if err != nil {
 		// Update file status to error
-		updateErr := fm.UpdateFileStatus(fileRecord.ID, models.StatusError)
+		updateErr := fm.UpdateFileStatus(fileRecord.ID, failStatus)
 		if updateErr != nil {
 			return nil, fmt.Errorf("upload failed: %w, and failed to update status: %w", err, updateErr)
 		}
 		return nil, fmt.Errorf("failed to upload file to S3: %w", err)
 	}
 	
-	// Update file status to active after successful upload
-	err = fm.UpdateFileStatus(fileRecord.ID, models.StatusActive)
-	if err != nil {
-		return nil, fmt.Errorf("file uploaded successfully but failed to update status: %w", err)
+	// Record the checksum so recipients and later syncs can verify the content
+	if uploadResult == nil {
+		uploadResult = &aws.UploadResult{}
+	}
+	object := uploadedObject{
+		checksum:    uploadResult.ChecksumSHA256,
+		etag:        uploadResult.ETag,
+		contentType: contentType,
+		size:        uploadResult.Size,
+	}
+	if compressed != nil {
+		// Recipients' browsers decompress the download, so they get the original content
+		object.checksum = compressed.OriginalChecksum
+		object.compression = compressed.Encoding
+	}
+	if err := fm.markUploaded(fileRecord.ID, object); err != nil {
+		return nil, fmt.Errorf("file uploaded successfully but %w", err)
 	}
 	
 	// Return updated file record
@@ -327,6 +816,205 @@ func (fm *FileManagerImpl) UploadFile(ctx context.Context, filePath string, expi
 	return updatedFile, nil
 }
 
+// uploadedObject describes the object an upload stored in S3
+type uploadedObject struct {
+	checksum    string // hex SHA-256 of the original content
+	etag        string
+	contentType string
+	compression string             // empty if stored as it is
+	size        int64              // bytes stored; zero if unknown
+	scan        *models.ScanResult // verdict of the content's virus scan, if it was scanned and not recorded yet
+}
+
+// uploadedObjectFromHead describes an object found in S3
+func uploadedObjectFromHead(head *s3.HeadObjectOutput) uploadedObject {
+	return uploadedObject{
+		checksum:    aws.ContentChecksumFromHead(head),
+		etag:        aws.ETagFromHead(head),
+		contentType: aws.ContentTypeFromHead(head),
+		compression: aws.ContentEncodingFromHead(head),
+		size:        aws.ContentLengthFromHead(head),
+	}
+}
+
+// markUploaded records what an upload stored and marks the file active in one transaction,
+// so a file is never active without the checksum of what was uploaded
+func (fm *FileManagerImpl) markUploaded(fileID string, object uploadedObject) error {
+	return fm.db.WithTransaction(func(tx storage.Database) error {
+		if err := tx.UpdateFileChecksum(fileID, object.checksum, object.etag); err != nil {
+			return fmt.Errorf("failed to save checksum: %w", err)
+		}
+		if object.contentType != "" {
+			if err := tx.UpdateFileContentType(fileID, object.contentType); err != nil {
+				return fmt.Errorf("failed to save content type: %w", err)
+			}
+		}
+		if err := tx.UpdateFileStorage(fileID, object.compression, object.size); err != nil {
+			return fmt.Errorf("failed to save stored size: %w", err)
+		}
+		if object.scan != nil {
+			if err := tx.UpdateFileScan(fileID, object.scan.Verdict, object.scan.Engine); err != nil {
+				return fmt.Errorf("failed to save scan verdict: %w", err)
+			}
+		}
+		if err := tx.UpdateFileStatus(fileID, models.StatusActive); err != nil {
+			return fmt.Errorf("failed to update status: %w", err)
+		}
+		return nil
+	})
+}
+
+// RecoverInterruptedUploads finishes the uploads of the current profile that were left uploading when
+// the app stopped mid-upload. Each is looked up in S3: objects already there are marked active, and
+// missing ones are uploaded again from the local file, or marked as errors if it can no longer be
+// uploaded. Recovery stops at the first file that can't be looked up, leaving the rest for next time.
+func (fm *FileManagerImpl) RecoverInterruptedUploads(ctx context.Context) (*RecoveryResult, error) {
+	result := &RecoveryResult{Failures: []string{}}
+	
+	s3Service := fm.getS3Service()
+	if s3Service == nil {
+		return result, fmt.Errorf("S3 service not configured")
+	}
+	
+	files, err := fm.GetFilesByStatus(models.StatusUploading)
+	if err != nil {
+		return result, fmt.Errorf("failed to get uploading files: %w", err)
+	}
+	
+	for i, file := range files {
+		// Uploads still running in this process aren't interrupted
+		if !fm.beginUpload(file.ID) {
+			continue
+		}
+		
+		err := fm.recoverUpload(ctx, s3Service, file, result)
+		fm.endUpload(file.ID)
+		if err != nil {
+			result.Remaining = len(files) - i
+			return result, err
+		}
+	}
+	
+	if result.Completed+result.Resumed+result.Failed > 0 {
+		fm.logger.Info(fmt.Sprintf("Recovered interrupted uploads: %d completed, %d resumed, %d failed",
+			result.Completed, result.Resumed, result.Failed))
+	}
+	
+	return result, nil
+}
+
+// recoverUpload recovers one interrupted upload, adding the outcome to result.
+// It returns an error only if S3 couldn't be asked whether the object exists.
+func (fm *FileManagerImpl) recoverUpload(ctx context.Context, s3Service aws.S3Service, file *models.FileMetadata, result *RecoveryResult) error {
+	headCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	head, err := s3Service.HeadObject(headCtx, file.S3Key)
+	cancel()
+	
+	if err == nil {
+		// The upload finished, but the app stopped before recording it
+		if err := fm.markUploaded(file.ID, uploadedObjectFromHead(head)); err != nil {
+			result.Failed++
+			result.Failures = append(result.Failures, fmt.Sprintf("%s: %v", file.FileName, err))
+			return nil
+		}
+		result.Completed++
+		return nil
+	}
+	
+	if !isNotFoundError(err) {
+		return fmt.Errorf("failed to look up %s in S3: %w", file.FileName, err)
+	}
+	
+	// S3 has no partial objects to continue from, so the upload starts again
+	expiration := time.Until(file.ExpirationDate)
+	_, validateErr := validateUploadFile(file.FilePath)
+	if expiration <= 0 {
+		validateErr = fmt.Errorf("file expired before it could be uploaded")
+	}
+	
+	// The file may have changed since it was scanned, so it is scanned again
+	var scan *models.ScanResult
+	if validateErr == nil {
+		scan, validateErr = fm.scanFile(ctx, file.FileName, file.FilePath)
+	}
+	
+	if validateErr != nil {
+		result.Failed++
+		result.Failures = append(result.Failures, fmt.Sprintf("%s: %v", file.FileName, validateErr))
+		var err error
+		if scan.Infected() {
+			err = fm.recordInfected(file.ID, scan)
+		} else {
+			err = fm.UpdateFileStatus(file.ID, models.StatusError)
+		}
+		if err != nil {
+			fm.logger.Error(fmt.Sprintf("Failed to mark interrupted upload %s as failed: %v", file.ID, err))
+		}
+		return nil
+	}
+	
+	if _, err := fm.uploadRecord(ctx, s3Service, file, expiration, models.StatusError, scan, nil); err != nil {
+		result.Failed++
+		result.Failures = append(result.Failures, fmt.Sprintf("%s: %v", file.FileName, err))
+		return nil
+	}
+	result.Resumed++
+	return nil
+}
+
+// RemoveFile deletes a file's object from S3, if there is one and no other file is stored in it,
+// and then its local record. Without an S3 service only the local record is removed.
+func (fm *FileManagerImpl) RemoveFile(ctx context.Context, fileID string) error {
+	if fileID == "" {
+		return fmt.Errorf("file ID cannot be empty")
+	}
+	
+	file, err := fm.GetFile(fileID)
+	if err != nil {
+		return fmt.Errorf("failed to get file metadata: %w", err)
+	}
+	
+	// Pending files were never uploaded
+	if s3Service := fm.getS3Service(); s3Service != nil && file.Status != models.StatusPending {
+		shared, err := fm.objectShared(file)
+		if err != nil {
+			return fmt.Errorf("failed to check whether other files are stored in the same object: %w", err)
+		}
+		if shared {
+			fm.logger.Info(fmt.Sprintf("Kept S3 object %s, as other files are stored in it", file.S3Key))
+		} else if err := s3Service.DeleteObject(ctx, file.S3Key); err != nil {
+			return fmt.Errorf("failed to delete file from S3: %w", err)
+		}
+	}
+	
+	if err := fm.DeleteFile(fileID); err != nil {
+		return fmt.Errorf("failed to delete file record: %w", err)
+	}
+	
+	fm.logger.Info(fmt.Sprintf("Removed file %s (S3 key: %s)", fileID, file.S3Key))
+	
+	return nil
+}
+
+// objectShared reports whether any other file that still needs it is stored in a file's S3 object.
+// Expired and deleted files don't, and neither do pending ones, which get an object of their own.
+func (fm *FileManagerImpl) objectShared(file *models.FileMetadata) (bool, error) {
+	files, err := fm.db.QueryFiles(models.FileQuery{
+		S3Key:            file.S3Key,
+		ExcludedStatuses: []models.FileStatus{models.StatusExpired, models.StatusDeleted, models.StatusPending},
+	})
+	if err != nil {
+		return false, err
+	}
+	
+	for _, other := range files {
+		if other.ID != file.ID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // generateS3Key generates a UUID-based S3 key with timestamp prefix
 func generateS3Key(fileName string) string {
 	// Create timestamp prefix (YYYY/MM/DD format for organization)
@@ -345,6 +1033,9 @@ func generateS3Key(fileName string) string {
 	return s3Key
 }
 
+// maxObjectLifetime is how long the longest lifecycle rule, ExpirationTagOneMonth, keeps an object
+const maxObjectLifetime = 30 * 24 * time.Hour
+
 // getExpirationTag returns the appropriate S3 lifecycle tag based on expiration duration
 func getExpirationTag(expiration time.Duration) string {
 	hours := expiration.Hours()
@@ -352,16 +1043,16 @@ func getExpirationTag(expiration time.Duration) string {
 	// Map expiration duration to lifecycle policy tags
 	switch {
 	case hours <= 1:
-		return "1hour"
+		return aws.ExpirationTagOneHour
 	case hours <= 24:
-		return "1day"
+		return aws.ExpirationTagOneDay
 	case hours <= 24*7:
-		return "1week"
+		return aws.ExpirationTagOneWeek
 	case hours <= 24*30:
-		return "1month"
+		return aws.ExpirationTagOneMonth
 	default:
 		// For longer durations, default to 1 month
-		return "1month"
+		return aws.ExpirationTagOneMonth
 	}
 }
 
@@ -375,7 +1066,8 @@ func (fm *FileManagerImpl) GeneratePresignedURL(ctx context.Context, fileID stri
 		return "", fmt.Errorf("expiration duration must be positive")
 	}
 	
-	if fm.s3Service == nil {
+	s3Service := fm.getS3Service()
+	if s3Service == nil {
 		return "", fmt.Errorf("S3 service not configured")
 	}
 	
@@ -395,6 +1087,11 @@ func (fm *FileManagerImpl) GeneratePresignedURL(ctx context.Context, fileID stri
 		return "", fmt.Errorf("file has expired and cannot be shared")
 	}
 	
+	// SSE-C objects can only be read with the customer key, which must never leave this machine
+	if file.EncryptionMode == models.EncryptionSSEC {
+		return "", fmt.Errorf("file is encrypted with a customer-provided key (SSE-C) and cannot be shared by link; use Download instead")
+	}
+	
 	// Ensure presigned URL expiration doesn't exceed file expiration (requirement 3.4)
 	timeUntilFileExpires := time.Until(file.ExpirationDate)
 	if expiration > timeUntilFileExpires {
@@ -402,8 +1099,10 @@ func (fm *FileManagerImpl) GeneratePresignedURL(ctx context.Context, fileID stri
 		fm.logger.Info(fmt.Sprintf("Adjusted presigned URL expiration to match file expiration for file %s", fileID))
 	}
 	
-	// Generate presigned URL using S3 service
-	presignedURL, err := fm.s3Service.GeneratePresignedURL(ctx, file.S3Key, expiration)
+	// Generate presigned URL using S3 service, signed as of a recorded time so the share
+	// history can sign the same link again instead of storing it
+	signedAt := time.Now().Truncate(time.Second)
+	presignedURL, err := s3Service.GeneratePresignedURLAt(ctx, file.S3Key, signedAt, expiration, aws.ResponseOverrides{})
 	if err != nil {
 		fm.logger.Error(fmt.Sprintf("Failed to generate presigned URL for file %s: %v", fileID, err))
 		return "", fmt.Errorf("failed to generate presigned URL: %w", err)
@@ -413,29 +1112,18 @@ func (fm *FileManagerImpl) GeneratePresignedURL(ctx context.Context, fileID stri
 	fm.logger.Info(fmt.Sprintf("Generated presigned URL for file %s (S3 key: %s) with expiration: %v", 
 		fileID, file.S3Key, expiration))
 	
-	// Create share record to store presigned URL metadata in database
+	// Create share record to store presigned URL metadata in database; the URL itself isn't stored
 	shareRecord := &models.ShareRecord{
 		ID:            uuid.New().String(),
 		FileID:        fileID,
 		Recipients:    []string{}, // Empty for now, will be populated when sharing with specific recipients
 		Message:       "",         // Empty for now, will be populated when sharing with custom message
 		SharedDate:    time.Now(),
-		PresignedURL:  presignedURL,
-		URLExpiration: time.Now().Add(expiration),
+		SignedAt:      signedAt,
+		URLExpiration: signedAt.Add(expiration),
 	}
 	
-	// Convert models.ShareRecord to storage.ShareRecord and save to database
-	storageShareRecord := &storage.ShareRecord{
-		ID:            shareRecord.ID,
-		FileID:        shareRecord.FileID,
-		Recipients:    shareRecord.Recipients,
-		Message:       shareRecord.Message,
-		SharedDate:    shareRecord.SharedDate,
-		PresignedURL:  shareRecord.PresignedURL,
-		URLExpiration: shareRecord.URLExpiration,
-	}
-	
-	err = fm.db.SaveShare(storageShareRecord)
+	err = fm.db.SaveShare(shareRecord)
 	if err != nil {
 		fm.logger.Error(fmt.Sprintf("Failed to save share record for file %s: %v", fileID, err))
 		// Don't fail the operation if we can't save the share record, just log the error
@@ -445,4 +1133,47 @@ func (fm *FileManagerImpl) GeneratePresignedURL(ctx context.Context, fileID stri
 	}
 	
 	return presignedURL, nil
-}
\ No newline at end of file
+}
+
+// DownloadFile downloads a file from S3 to destPath, supplying the SSE-C key if the file needs it
+func (fm *FileManagerImpl) DownloadFile(ctx context.Context, fileID string, destPath string) error {
+	if fileID == "" {
+		return fmt.Errorf("file ID cannot be empty")
+	}
+	
+	if destPath == "" {
+		return fmt.Errorf("destination path cannot be empty")
+	}
+	
+	s3Service := fm.getS3Service()
+	if s3Service == nil {
+		return fmt.Errorf("S3 service not configured")
+	}
+	
+	file, err := fm.GetFile(fileID)
+	if err != nil {
+		return fmt.Errorf("failed to get file metadata: %w", err)
+	}
+	
+	if file.Status != models.StatusActive {
+		return fmt.Errorf("cannot download file with status: %s", file.Status)
+	}
+	
+	// A short-lived URL is enough since it is used immediately
+	download, err := s3Service.GeneratePresignedDownload(ctx, file.S3Key, 15*time.Minute, file.EncryptionMode)
+	if err != nil {
+		return fmt.Errorf("failed to prepare download: %w", err)
+	}
+	
+	if err := downloadPresigned(ctx, download, destPath); err != nil {
+		fm.logger.Error(fmt.Sprintf("Failed to download file %s: %v", fileID, err))
+		return fmt.Errorf("failed to download file: %w", err)
+	}
+	
+	fm.logger.Info(fmt.Sprintf("Downloaded file %s (S3 key: %s) to %s", fileID, file.S3Key, destPath))
+	
+	return nil
+}
+
+// downloadPresigned fetches a presigned download; replaced in tests
+var downloadPresigned = aws.DownloadPresigned
@@ -2,7 +2,10 @@ package manager
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -34,17 +37,26 @@ type mockS3Service struct {
 	shouldError bool
 	errorMsg    string
 	uploadedFiles map[string]bool
+	contentTypes  map[string]string // the content type each key was uploaded with
+	encodings     map[string]string // the content encoding each key was uploaded with
+	uploadedAt    map[string]time.Time
+	expirationTags map[string]string // the lifecycle tag of each key
+	encryptionMode string
 }
 
 func newMockS3Service() *mockS3Service {
 	return &mockS3Service{
 		uploadedFiles: make(map[string]bool),
+		contentTypes:  make(map[string]string),
+		encodings:     make(map[string]string),
+		uploadedAt:    make(map[string]time.Time),
+		expirationTags: make(map[string]string),
 	}
 }
 
-func (m *mockS3Service) UploadFile(ctx context.Context, key string, filePath string, metadata map[string]string, progressCh chan<- aws.UploadProgress) error {
+func (m *mockS3Service) UploadFile(ctx context.Context, key string, filePath string, metadata map[string]string, progressCh chan<- aws.UploadProgress) (*aws.UploadResult, error) {
 	if m.shouldError {
-		return fmt.Errorf(m.errorMsg)
+		return nil, fmt.Errorf(m.errorMsg)
 	}
 	
 	// Simulate progress updates
@@ -52,20 +64,31 @@ func (m *mockS3Service) UploadFile(ctx context.Context, key string, filePath str
 		select {
 		case progressCh <- aws.UploadProgress{BytesUploaded: 50, TotalBytes: 100, Percentage: 50.0}:
 		case <-ctx.Done():
-			return ctx.Err()
+			return nil, ctx.Err()
 		}
 		
 		select {
 		case progressCh <- aws.UploadProgress{BytesUploaded: 100, TotalBytes: 100, Percentage: 100.0}:
 		case <-ctx.Done():
-			return ctx.Err()
+			return nil, ctx.Err()
 		}
 	}
 	
 	m.uploadedFiles[key] = true
-	return nil
+	m.contentTypes[key] = metadata["content-type"]
+	m.encodings[key] = metadata["content-encoding"]
+	m.uploadedAt[key] = time.Now()
+	m.expirationTags[key] = metadata["expiration-tag"]
+	result := &aws.UploadResult{ETag: "etag-" + key, ChecksumSHA256: testChecksum}
+	if info, err := os.Stat(filePath); err == nil {
+		result.Size = info.Size()
+	}
+	return result, nil
 }
 
+// testChecksum is the checksum reported by mockS3Service for every upload
+const testChecksum = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
 func (m *mockS3Service) GeneratePresignedURL(ctx context.Context, key string, expiration time.Duration) (string, error) {
 	if m.shouldError {
 		return "", fmt.Errorf(m.errorMsg)
@@ -73,6 +96,34 @@ func (m *mockS3Service) GeneratePresignedURL(ctx context.Context, key string, ex
 	return fmt.Sprintf("https://test-bucket.s3.amazonaws.com/%s?expires=%d", key, int64(expiration.Seconds())), nil
 }
 
+func (m *mockS3Service) GeneratePresignedURLAt(ctx context.Context, key string, signedAt time.Time, expiration time.Duration, overrides aws.ResponseOverrides) (string, error) {
+	if m.shouldError {
+		return "", fmt.Errorf(m.errorMsg)
+	}
+	return fmt.Sprintf("https://test-bucket.s3.amazonaws.com/%s?date=%s&expires=%d", key, signedAt.UTC().Format("20060102T150405Z"), int64(expiration.Seconds())), nil
+}
+
+func (m *mockS3Service) GeneratePresignedDownload(ctx context.Context, key string, expiration time.Duration, encryptionMode string) (*aws.PresignedDownload, error) {
+	if m.shouldError {
+		return nil, fmt.Errorf(m.errorMsg)
+	}
+	download := &aws.PresignedDownload{
+		URL:     fmt.Sprintf("https://test-bucket.s3.amazonaws.com/%s?expires=%d", key, int64(expiration.Seconds())),
+		Headers: make(http.Header),
+	}
+	if encryptionMode == models.EncryptionSSEC {
+		download.Headers.Set("X-Amz-Server-Side-Encryption-Customer-Algorithm", "AES256")
+	}
+	return download, nil
+}
+
+func (m *mockS3Service) EncryptionMode() string {
+	if m.encryptionMode == "" {
+		return models.EncryptionSSES3
+	}
+	return m.encryptionMode
+}
+
 func (m *mockS3Service) DeleteObject(ctx context.Context, key string) error {
 	if m.shouldError {
 		return fmt.Errorf(m.errorMsg)
@@ -81,11 +132,38 @@ func (m *mockS3Service) DeleteObject(ctx context.Context, key string) error {
 	return nil
 }
 
+func (m *mockS3Service) PutShareLink(ctx context.Context, link *aws.ShareLink) error {
+	if m.shouldError {
+		return fmt.Errorf(m.errorMsg)
+	}
+	return nil
+}
+
+func (m *mockS3Service) DeleteShareLink(ctx context.Context, token string) error {
+	if m.shouldError {
+		return fmt.Errorf(m.errorMsg)
+	}
+	return nil
+}
+
 func (m *mockS3Service) HeadObject(ctx context.Context, key string) (*s3.HeadObjectOutput, error) {
 	if m.shouldError {
 		return nil, fmt.Errorf(m.errorMsg)
 	}
-	return nil, nil
+	if !m.uploadedFiles[key] {
+		return nil, fmt.Errorf("NotFound: object %s not found", key)
+	}
+	etag := "\"etag-" + key + "\""
+	lastModified := m.uploadedAt[key]
+	return &s3.HeadObjectOutput{ETag: &etag, LastModified: &lastModified}, nil
+}
+
+func (m *mockS3Service) ExtendExpiration(ctx context.Context, key string, expirationTag string) error {
+	if m.shouldError {
+		return fmt.Errorf(m.errorMsg)
+	}
+	m.expirationTags[key] = expirationTag
+	return nil
 }
 
 func (m *mockS3Service) TestConnection(ctx context.Context) error {
@@ -668,6 +746,96 @@ func TestFileManager_GetFilesByStatus(t *testing.T) {
 	}
 }
 
+func TestFileManager_QueryFiles(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+	
+	fm := NewFileManagerWithoutS3(db)
+	
+	now := time.Now()
+	for _, file := range []*models.FileMetadata{
+		{ID: "small", FileName: "notes.txt", FileSize: 512, UploadDate: now.Add(-time.Hour), Status: models.StatusActive},
+		{ID: "large", FileName: "video.mp4", FileSize: 500 * 1024 * 1024, UploadDate: now.Add(-2 * time.Hour), Status: models.StatusExpired},
+		{ID: "other-profile", FileName: "notes-work.txt", FileSize: 512, UploadDate: now, Status: models.StatusActive, Profile: "work"},
+	} {
+		file.FilePath = "/tmp/" + file.FileName
+		file.ExpirationDate = now.Add(24 * time.Hour)
+		file.S3Key = "uploads/" + file.ID
+		require.NoError(t, fm.SaveFile(file))
+	}
+	
+	files, err := fm.QueryFiles(models.FileQuery{Search: "notes"})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "small", files[0].ID)
+	assert.Equal(t, models.StatusActive, files[0].Status)
+	
+	files, err = fm.QueryFiles(models.FileQuery{SortBy: models.SortByFileSize, Ascending: true})
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, "small", files[0].ID)
+	assert.Equal(t, "large", files[1].ID)
+	
+	files, err = fm.QueryFiles(models.FileQuery{Statuses: []models.FileStatus{models.StatusExpired}, MinSize: 1024})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "large", files[0].ID)
+	
+	_, err = fm.QueryFiles(models.FileQuery{MinSize: 2048, MaxSize: 1024})
+	assert.Error(t, err)
+	
+	_, err = fm.QueryFiles(models.FileQuery{UploadedAfter: now, UploadedBefore: now.Add(-time.Hour)})
+	assert.Error(t, err)
+	
+	_, err = fm.QueryFiles(models.FileQuery{ExpiresAfter: now, ExpiresBefore: now})
+	assert.Error(t, err)
+	
+	_, err = fm.QueryFiles(models.FileQuery{Limit: -1})
+	assert.Error(t, err)
+}
+
+func TestFileManager_QueryFiles_Paging(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+	
+	fm := NewFileManagerWithoutS3(db)
+	
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, fm.SaveFile(&models.FileMetadata{
+			ID:             fmt.Sprintf("file-%d", i),
+			FileName:       fmt.Sprintf("Report-%d.pdf", i),
+			FilePath:       "/tmp/report.pdf",
+			FileSize:       1024,
+			UploadDate:     now.Add(time.Duration(i) * time.Minute),
+			ExpirationDate: now.Add(time.Duration(i+1) * time.Hour),
+			S3Key:          fmt.Sprintf("uploads/file-%d", i),
+			Status:         models.StatusActive,
+		}))
+	}
+	
+	ids := func(query models.FileQuery) []string {
+		files, err := fm.QueryFiles(query)
+		require.NoError(t, err)
+		ids := []string{}
+		for _, file := range files {
+			ids = append(ids, file.ID)
+		}
+		return ids
+	}
+	
+	assert.Equal(t, []string{"file-4", "file-3"}, ids(models.FileQuery{Limit: 2}))
+	assert.Equal(t, []string{"file-2", "file-1"}, ids(models.FileQuery{Limit: 2, Offset: 2}))
+	assert.Equal(t, []string{"file-0"}, ids(models.FileQuery{Limit: 2, Offset: 4}))
+	
+	assert.Equal(t, []string{"file-0", "file-1"}, ids(models.FileQuery{
+		NamePrefix:    "report",
+		ExpiresBefore: now.Add(150 * time.Minute),
+		SortBy:        models.SortByExpiration,
+		Ascending:     true,
+	}))
+}
+
 func TestFileManager_GetExpiredFiles(t *testing.T) {
 	db, _ := createTempDatabase(t)
 	defer db.Close()
@@ -925,6 +1093,12 @@ func TestFileManager_UploadFile(t *testing.T) {
 				// Verify file was uploaded to S3
 				assert.True(t, mockS3.uploadedFiles[fileRecord.S3Key])
 				
+				// Verify the integrity details returned by S3 were stored
+				stored, err := fm.GetFile(fileRecord.ID)
+				require.NoError(t, err)
+				assert.Equal(t, testChecksum, stored.Checksum)
+				assert.Equal(t, "etag-"+fileRecord.S3Key, stored.ETag)
+				
 				// Check progress updates
 				close(progressCh)
 				progressUpdates := make([]aws.UploadProgress, 0)
@@ -1267,7 +1441,8 @@ func TestFileManager_GeneratePresignedURL(t *testing.T) {
 				
 				assert.NotNil(t, latestShare)
 				assert.Equal(t, tt.fileID, latestShare.FileID)
-				assert.Equal(t, url, latestShare.PresignedURL)
+				assert.Empty(t, latestShare.PresignedURL) // The link is signed again from the signing time, not stored
+				assert.Contains(t, url, latestShare.SignedAt.UTC().Format("20060102T150405Z"))
 				assert.Empty(t, latestShare.Recipients) // Should be empty for basic URL generation
 				assert.Empty(t, latestShare.Message)    // Should be empty for basic URL generation
 				assert.False(t, latestShare.SharedDate.IsZero())
@@ -1391,7 +1566,8 @@ func TestFileManager_GeneratePresignedURL_Integration(t *testing.T) {
 	
 	share := shareHistory[0]
 	assert.Equal(t, fileRecord.ID, share.FileID)
-	assert.Equal(t, url, share.PresignedURL)
+	assert.Empty(t, share.PresignedURL)
+	assert.Contains(t, url, share.SignedAt.UTC().Format("20060102T150405Z"))
 	assert.Empty(t, share.Recipients)
 	assert.Empty(t, share.Message)
 	assert.False(t, share.SharedDate.IsZero())
@@ -1409,6 +1585,438 @@ func TestFileManager_GeneratePresignedURL_Integration(t *testing.T) {
 	assert.Len(t, shareHistory, 2)
 }
 
+func TestFileManager_UploadFile_RecordsEncryptionMode(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+	
+	mockS3 := newMockS3Service()
+	mockS3.encryptionMode = models.EncryptionSSEKMS
+	fm := NewFileManager(db, mockS3)
+	
+	filePath := createTestFile(t, "encrypted content")
+	
+	file, err := fm.UploadFile(context.Background(), filePath, 24*time.Hour, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.EncryptionSSEKMS, file.EncryptionMode)
+}
+
+func TestFileManager_UploadFile_RecordsContentType(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+	
+	mockS3 := newMockS3Service()
+	fm := NewFileManager(db, mockS3)
+	ctx := context.Background()
+	
+	// Detected from the extension
+	file, err := fm.UploadFile(ctx, createTestFile(t, "plain notes"), 24*time.Hour, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "text/plain", file.ContentType)
+	assert.Equal(t, "text/plain", mockS3.contentTypes[file.S3Key])
+	
+	// HTML is uploaded as HTML whatever it is called
+	file, err = fm.UploadFile(ctx, createTestFile(t, "<!DOCTYPE html><script>alert(1)</script>"), 24*time.Hour, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "text/html; charset=utf-8", file.ContentType)
+	assert.Equal(t, file.ContentType, mockS3.contentTypes[file.S3Key])
+	
+	// Types set in the settings come first
+	settings := models.DefaultApplicationSettings()
+	settings.ContentTypeOverrides = map[string]string{".txt": "text/x-notes"}
+	fm.SetUploadSettings(settings)
+	file, err = fm.UploadFile(ctx, createTestFile(t, "plain notes"), 24*time.Hour, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "text/x-notes", file.ContentType)
+	assert.Equal(t, "text/x-notes", mockS3.contentTypes[file.S3Key])
+}
+
+func TestFileManager_UploadFile_Compression(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+	
+	mockS3 := newMockS3Service()
+	fm := NewFileManager(db, mockS3)
+	ctx := context.Background()
+	
+	logLines := strings.Repeat("2024-01-01 12:00:00 INFO request served in 12ms\n", 200)
+	logSum := sha256.Sum256([]byte(logLines))
+	
+	// Compression is off by default
+	file, err := fm.UploadFile(ctx, createTestFile(t, logLines), 24*time.Hour, nil)
+	require.NoError(t, err)
+	assert.Empty(t, file.Compression)
+	assert.Empty(t, mockS3.encodings[file.S3Key])
+	assert.Equal(t, int64(len(logLines)), file.StoredSize)
+	
+	for _, algorithm := range models.CompressionAlgorithms {
+		t.Run(algorithm, func(t *testing.T) {
+			settings := models.DefaultApplicationSettings()
+			settings.UploadCompression = algorithm
+			fm.SetUploadSettings(settings)
+			
+			file, err := fm.UploadFile(ctx, createTestFile(t, logLines), 24*time.Hour, nil)
+			require.NoError(t, err)
+			assert.Equal(t, algorithm, file.Compression)
+			assert.Equal(t, algorithm, mockS3.encodings[file.S3Key])
+			assert.Equal(t, int64(len(logLines)), file.FileSize)
+			assert.Greater(t, file.StoredSize, int64(0))
+			assert.Less(t, file.CompressionRatio(), 0.5)
+			// The checksum is of the content recipients get once their browser decompresses it
+			assert.Equal(t, hex.EncodeToString(logSum[:]), file.Checksum)
+			assert.Equal(t, "text/plain", file.ContentType)
+		})
+	}
+	
+	settings := models.DefaultApplicationSettings()
+	settings.UploadCompression = models.CompressionGzip
+	fm.SetUploadSettings(settings)
+	
+	// Content that doesn't shrink enough is uploaded as it is
+	file, err = fm.UploadFile(ctx, createTestFile(t, "short note"), 24*time.Hour, nil)
+	require.NoError(t, err)
+	assert.Empty(t, file.Compression)
+	assert.Empty(t, mockS3.encodings[file.S3Key])
+	assert.Equal(t, testChecksum, file.Checksum)
+	
+	// Content compressed already isn't compressed again
+	settings.ContentTypeOverrides = map[string]string{".txt": "application/zip"}
+	fm.SetUploadSettings(settings)
+	file, err = fm.UploadFile(ctx, createTestFile(t, logLines), 24*time.Hour, nil)
+	require.NoError(t, err)
+	assert.Empty(t, file.Compression)
+	assert.Equal(t, int64(len(logLines)), file.StoredSize)
+}
+
+func TestFileManager_SetUploadSettings_BandwidthLimit(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+	
+	fm := NewFileManager(db, newMockS3Service()).(*FileManagerImpl)
+	limit := fm.bandwidth.Limit()
+	assert.Zero(t, limit.KBps(), "uploads are unlimited by default")
+	
+	// The limit is changed in place, so uploads already running follow it
+	settings := models.DefaultApplicationSettings()
+	settings.UploadBandwidthLimitKBps = 512
+	fm.SetUploadSettings(settings)
+	assert.Same(t, limit, fm.bandwidth.Limit())
+	assert.Equal(t, 512, limit.KBps())
+	
+	settings.UploadBandwidthLimitKBps = 0
+	fm.SetUploadSettings(settings)
+	assert.Zero(t, limit.KBps())
+}
+
+func TestFileManager_UploadFile_ReusesStoredCopy(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+	
+	mockS3 := newMockS3Service()
+	fm := NewFileManager(db, mockS3)
+	ctx := context.Background()
+	
+	// The mock reports the checksum of "test" for every upload
+	filePath := createTestFile(t, "test")
+	first, err := fm.UploadFile(ctx, filePath, time.Hour, nil)
+	require.NoError(t, err)
+	assert.Equal(t, aws.ExpirationTagOneHour, mockS3.expirationTags[first.S3Key])
+	
+	// Uploading it again shares the object, and extends it to the new file's expiration
+	second, err := fm.UploadFile(ctx, filePath, 3*24*time.Hour, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, second.ID)
+	assert.Equal(t, first.S3Key, second.S3Key)
+	assert.Equal(t, models.StatusActive, second.Status)
+	assert.Equal(t, first.Checksum, second.Checksum)
+	assert.Equal(t, first.ETag, second.ETag)
+	assert.Equal(t, first.ContentType, second.ContentType)
+	assert.WithinDuration(t, time.Now().Add(3*24*time.Hour), second.ExpirationDate, time.Minute)
+	assert.Equal(t, aws.ExpirationTagOneWeek, mockS3.expirationTags[first.S3Key])
+	assert.Len(t, mockS3.uploadedFiles, 1)
+	
+	// A copy under another name gets its own object, as downloads are named after the stored file
+	renamed := filepath.Join(t.TempDir(), "renamed.txt")
+	require.NoError(t, os.WriteFile(renamed, []byte("test"), 0600))
+	third, err := fm.UploadFile(ctx, renamed, time.Hour, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.S3Key, third.S3Key)
+	
+	// So does a file S3 can't keep the object for, as lifecycle rules count from its upload
+	mockS3.uploadedAt[first.S3Key] = time.Now().Add(-29 * 24 * time.Hour)
+	fourth, err := fm.UploadFile(ctx, filePath, 2*24*time.Hour, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.S3Key, fourth.S3Key)
+	assert.Equal(t, aws.ExpirationTagOneWeek, mockS3.expirationTags[first.S3Key])
+	
+	// Removing a file keeps the object while another file is stored in it
+	require.NoError(t, fm.RemoveFile(ctx, first.ID))
+	assert.True(t, mockS3.uploadedFiles[second.S3Key])
+	require.NoError(t, fm.RemoveFile(ctx, second.ID))
+	assert.False(t, mockS3.uploadedFiles[second.S3Key])
+}
+
+func TestFileManager_UploadFile_StoredCopyGone(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+	
+	mockS3 := newMockS3Service()
+	fm := NewFileManager(db, mockS3)
+	ctx := context.Background()
+	
+	filePath := createTestFile(t, "test")
+	first, err := fm.UploadFile(ctx, filePath, time.Hour, nil)
+	require.NoError(t, err)
+	
+	// The object was removed from S3 behind the app's back, so the file is uploaded again
+	delete(mockS3.uploadedFiles, first.S3Key)
+	second, err := fm.UploadFile(ctx, filePath, time.Hour, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.S3Key, second.S3Key)
+	assert.True(t, mockS3.uploadedFiles[second.S3Key])
+	
+	// Expired files aren't reused
+	require.NoError(t, fm.UpdateFileStatus(second.ID, models.StatusExpired))
+	third, err := fm.UploadFile(ctx, filePath, time.Hour, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, second.S3Key, third.S3Key)
+	
+	// Removing an expired file's record still deletes its object, as nothing else needs it
+	require.NoError(t, fm.RemoveFile(ctx, second.ID))
+	assert.False(t, mockS3.uploadedFiles[second.S3Key])
+}
+
+// mockScanner finds "EICAR" in files, and fails to scan anything while err is set
+type mockScanner struct {
+	err     error
+	scanned int
+}
+
+func (m *mockScanner) Scan(ctx context.Context, filePath string) (*models.ScanResult, error) {
+	m.scanned++
+	if m.err != nil {
+		return nil, m.err
+	}
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(string(content), "EICAR") {
+		return &models.ScanResult{Verdict: models.ScanInfected, Signature: "Eicar-Test-Signature", Engine: "MockScan 1.0"}, nil
+	}
+	return &models.ScanResult{Verdict: models.ScanClean, Engine: "MockScan 1.0"}, nil
+}
+
+func TestFileManager_UploadFile_VirusScan(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+	
+	mockS3 := newMockS3Service()
+	fm := NewFileManager(db, mockS3)
+	ctx := context.Background()
+	
+	// Files aren't scanned unless scanning is on
+	file, err := fm.UploadFile(ctx, createTestFile(t, "unscanned"), 24*time.Hour, nil)
+	require.NoError(t, err)
+	assert.Empty(t, file.ScanVerdict)
+	
+	scanner := &mockScanner{}
+	fm.(*FileManagerImpl).scanner = scanner
+	
+	file, err = fm.UploadFile(ctx, createTestFile(t, "quarterly figures"), 24*time.Hour, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusActive, file.Status)
+	assert.Equal(t, models.ScanClean, file.ScanVerdict)
+	assert.Equal(t, "MockScan 1.0", file.ScanEngine)
+	
+	// Infected files are neither recorded nor uploaded
+	uploads := len(mockS3.uploadedFiles)
+	_, err = fm.UploadFile(ctx, createTestFile(t, "EICAR test"), 24*time.Hour, nil)
+	require.Error(t, err)
+	var infected *models.InfectedFileError
+	require.ErrorAs(t, err, &infected)
+	assert.Equal(t, "Eicar-Test-Signature", infected.Result.Signature)
+	assert.Contains(t, err.Error(), "Eicar-Test-Signature")
+	assert.Len(t, mockS3.uploadedFiles, uploads)
+	
+	// Nor are files the scanner can't scan
+	scanner.err = fmt.Errorf("connection refused")
+	_, err = fm.UploadFile(ctx, createTestFile(t, "more figures"), 24*time.Hour, nil)
+	require.Error(t, err)
+	assert.True(t, models.IsScanFailure(err))
+	assert.Len(t, mockS3.uploadedFiles, uploads)
+	
+	files, err := fm.ListFiles()
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+}
+
+func TestFileManager_UploadFile_VirusScanStoredCopy(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+	
+	fm := NewFileManager(db, newMockS3Service())
+	ctx := context.Background()
+	
+	// The mock reports the checksum of "test" for every upload
+	filePath := createTestFile(t, "test")
+	scanner := &mockScanner{}
+	fm.(*FileManagerImpl).scanner = scanner
+	first, err := fm.UploadFile(ctx, filePath, time.Hour, nil)
+	require.NoError(t, err)
+	
+	// An identical file is scanned too before it shares the stored object
+	second, err := fm.UploadFile(ctx, filePath, time.Hour, nil)
+	require.NoError(t, err)
+	assert.Equal(t, first.S3Key, second.S3Key)
+	assert.Equal(t, 2, scanner.scanned)
+	assert.Equal(t, models.ScanClean, second.ScanVerdict)
+	
+	// With scanning off, it takes the stored copy's verdict
+	fm.(*FileManagerImpl).scanner = nil
+	third, err := fm.UploadFile(ctx, filePath, time.Hour, nil)
+	require.NoError(t, err)
+	assert.Equal(t, first.S3Key, third.S3Key)
+	assert.Equal(t, models.ScanClean, third.ScanVerdict)
+	assert.Equal(t, "MockScan 1.0", third.ScanEngine)
+}
+
+func TestFileManager_UploadPendingFile_VirusScan(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+	
+	mockS3 := newMockS3Service()
+	fm := NewFileManager(db, mockS3)
+	ctx := context.Background()
+	
+	scanner := &mockScanner{err: fmt.Errorf("connection refused")}
+	fm.(*FileManagerImpl).scanner = scanner
+	
+	pendingFile, err := fm.CreatePendingUpload(createTestFile(t, "EICAR test"), 24*time.Hour)
+	require.NoError(t, err)
+	
+	// A file that couldn't be scanned stays pending, to be tried again
+	_, err = fm.UploadPendingFile(ctx, pendingFile.ID, nil)
+	assert.True(t, models.IsScanFailure(err))
+	file, err := fm.GetFile(pendingFile.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusPending, file.Status)
+	
+	// An infected one fails for good, with the verdict recorded
+	scanner.err = nil
+	_, err = fm.UploadPendingFile(ctx, pendingFile.ID, nil)
+	var infected *models.InfectedFileError
+	require.ErrorAs(t, err, &infected)
+	file, err = fm.GetFile(pendingFile.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusError, file.Status)
+	assert.Equal(t, models.ScanInfected, file.ScanVerdict)
+	assert.Equal(t, "MockScan 1.0", file.ScanEngine)
+	assert.False(t, mockS3.uploadedFiles[file.S3Key])
+	
+	// Retrying scans it again
+	_, err = fm.RetryUpload(ctx, pendingFile.ID, nil)
+	require.ErrorAs(t, err, &infected)
+	assert.Equal(t, 3, scanner.scanned)
+}
+
+func TestFileManager_RecoverInterruptedUploads_VirusScan(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+	
+	mockS3 := newMockS3Service()
+	fm := NewFileManager(db, mockS3)
+	fm.(*FileManagerImpl).scanner = &mockScanner{}
+	
+	clean := saveInterruptedUpload(t, fm, "clean", createTestFile(t, "interrupted content"), 24*time.Hour)
+	infected := saveInterruptedUpload(t, fm, "infected", createTestFile(t, "EICAR content"), 24*time.Hour)
+	
+	result, err := fm.RecoverInterruptedUploads(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Resumed)
+	assert.Equal(t, 1, result.Failed)
+	
+	file, err := fm.GetFile(clean.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusActive, file.Status)
+	assert.Equal(t, models.ScanClean, file.ScanVerdict)
+	
+	file, err = fm.GetFile(infected.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusError, file.Status)
+	assert.Equal(t, models.ScanInfected, file.ScanVerdict)
+	assert.False(t, mockS3.uploadedFiles[infected.S3Key])
+}
+
+func TestFileManager_SetUploadSettings_VirusScanner(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+	
+	fm := NewFileManager(db, newMockS3Service()).(*FileManagerImpl)
+	assert.Nil(t, fm.scanner, "scanning is off by default")
+	
+	settings := models.DefaultApplicationSettings()
+	settings.VirusScanner = models.ScannerCommand
+	settings.ScanCommand = "clamscan --no-summary"
+	fm.SetUploadSettings(settings)
+	assert.NotNil(t, fm.scanner)
+	
+	settings.VirusScanner = ""
+	fm.SetUploadSettings(settings)
+	assert.Nil(t, fm.scanner)
+}
+
+func TestFileManager_SSECFiles(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+	
+	mockS3 := newMockS3Service()
+	fm := NewFileManager(db, mockS3)
+	
+	ctx := context.Background()
+	now := time.Now()
+	
+	testFile := &models.FileMetadata{
+		ID:             "sse-c-file",
+		FileName:       "secret.txt",
+		FilePath:       "/tmp/secret.txt",
+		FileSize:       1024,
+		UploadDate:     now,
+		ExpirationDate: now.Add(24 * time.Hour),
+		S3Key:          "uploads/secret.txt",
+		Status:         models.StatusActive,
+		EncryptionMode: models.EncryptionSSEC,
+	}
+	require.NoError(t, fm.SaveFile(testFile))
+	
+	// A bare URL is useless without the customer key, so links are refused
+	url, err := fm.GeneratePresignedURL(ctx, testFile.ID, time.Hour)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "SSE-C")
+	assert.Empty(t, url)
+	
+	// Downloads go through the helper with the key headers attached
+	var got *aws.PresignedDownload
+	var gotPath string
+	original := downloadPresigned
+	downloadPresigned = func(ctx context.Context, download *aws.PresignedDownload, destPath string) error {
+		got = download
+		gotPath = destPath
+		return nil
+	}
+	defer func() { downloadPresigned = original }()
+	
+	err = fm.DownloadFile(ctx, testFile.ID, "/tmp/out.txt")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Contains(t, got.URL, testFile.S3Key)
+	assert.Equal(t, "AES256", got.Headers.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm"))
+	assert.Equal(t, "/tmp/out.txt", gotPath)
+	
+	// Inactive files cannot be downloaded
+	require.NoError(t, fm.UpdateFileStatus(testFile.ID, models.StatusExpired))
+	assert.Error(t, fm.DownloadFile(ctx, testFile.ID, "/tmp/out.txt"))
+}
+
 func TestGetExpirationTag(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -1473,4 +2081,301 @@ func TestGetExpirationTag(t *testing.T) {
 			assert.Equal(t, tt.expected, result)
 		})
 	}
-}
\ No newline at end of file
+}
+func TestFileManager_SetProfile(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+	
+	defaultS3 := newMockS3Service()
+	fm := NewFileManager(db, defaultS3)
+	assert.Equal(t, storage.DefaultProfile, fm.GetProfile())
+	
+	expiration := time.Now().Add(24 * time.Hour)
+	defaultFile, err := fm.CreateFileRecord("default.txt", "/tmp/default.txt", 100, "uploads/default.txt", expiration)
+	require.NoError(t, err)
+	assert.Equal(t, storage.DefaultProfile, defaultFile.Profile)
+	
+	// Switch to another profile with its own S3 service
+	clientS3 := newMockS3Service()
+	fm.SetProfile("client-a", clientS3)
+	assert.Equal(t, "client-a", fm.GetProfile())
+	
+	clientFile, err := fm.CreateFileRecord("client.txt", "/tmp/client.txt", 100, "uploads/client.txt", expiration)
+	require.NoError(t, err)
+	assert.Equal(t, "client-a", clientFile.Profile)
+	
+	// Only the active profile's files are listed
+	files, err := fm.ListFiles()
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, clientFile.ID, files[0].ID)
+	
+	// Uploads go to the active profile's S3 service
+	testFile := createTestFile(t, "client content")
+	uploaded, err := fm.UploadFile(context.Background(), testFile, time.Hour, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "client-a", uploaded.Profile)
+	assert.True(t, clientS3.uploadedFiles[uploaded.S3Key])
+	assert.Empty(t, defaultS3.uploadedFiles)
+	
+	// Switching back shows the default profile's files again
+	fm.SetProfile("", defaultS3)
+	files, err = fm.ListFiles()
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, defaultFile.ID, files[0].ID)
+}
+
+func TestFileManager_UploadPendingFile(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+	
+	mockS3 := newMockS3Service()
+	fm := NewFileManager(db, mockS3)
+	
+	ctx := context.Background()
+	
+	pendingFile, err := fm.CreatePendingUpload(createTestFile(t, "queued content"), 24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusPending, pendingFile.Status)
+	assert.False(t, mockS3.uploadedFiles[pendingFile.S3Key])
+	
+	// A failed upload leaves the file pending so it can be retried
+	mockS3.shouldError = true
+	mockS3.errorMsg = "connection reset"
+	_, err = fm.UploadPendingFile(ctx, pendingFile.ID, nil)
+	assert.Error(t, err)
+	
+	stillPending, err := fm.GetFile(pendingFile.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusPending, stillPending.Status)
+	
+	// Encryption settings changed while the upload was queued
+	mockS3.shouldError = false
+	mockS3.encryptionMode = models.EncryptionSSEKMS
+	uploaded, err := fm.UploadPendingFile(ctx, pendingFile.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusActive, uploaded.Status)
+	assert.Equal(t, models.EncryptionSSEKMS, uploaded.EncryptionMode)
+	assert.True(t, mockS3.uploadedFiles[pendingFile.S3Key])
+	
+	// Only pending files can be uploaded this way
+	_, err = fm.UploadPendingFile(ctx, pendingFile.ID, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not waiting to be uploaded")
+}
+
+func TestFileManager_RemoveFile(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+	
+	mockS3 := newMockS3Service()
+	fm := NewFileManager(db, mockS3)
+	
+	ctx := context.Background()
+	
+	uploaded, err := fm.UploadFile(ctx, createTestFile(t, "uploaded content"), 24*time.Hour, nil)
+	require.NoError(t, err)
+	
+	// The record is kept if the object can't be deleted
+	mockS3.shouldError = true
+	mockS3.errorMsg = "AccessDenied"
+	err = fm.RemoveFile(ctx, uploaded.ID)
+	assert.Error(t, err)
+	_, err = fm.GetFile(uploaded.ID)
+	assert.NoError(t, err)
+	
+	mockS3.shouldError = false
+	err = fm.RemoveFile(ctx, uploaded.ID)
+	require.NoError(t, err)
+	assert.False(t, mockS3.uploadedFiles[uploaded.S3Key])
+	_, err = fm.GetFile(uploaded.ID)
+	assert.Error(t, err)
+	
+	err = fm.RemoveFile(ctx, "non-existent-id")
+	assert.Error(t, err)
+}
+
+func TestFileManager_SaveFile_RecordsStoredFields(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+	
+	fm := NewFileManagerWithoutS3(db)
+	
+	file := &models.FileMetadata{
+		ID:             "file-1",
+		FileName:       "report.pdf",
+		FilePath:       "/tmp/report.pdf",
+		FileSize:       1024,
+		UploadDate:     time.Now(),
+		ExpirationDate: time.Now().Add(time.Hour),
+		S3Key:          "uploads/report.pdf",
+		Status:         models.StatusActive,
+	}
+	require.NoError(t, fm.SaveFile(file))
+	
+	// The database's defaults and timestamps are set on the saved model
+	assert.Equal(t, fm.GetProfile(), file.Profile)
+	assert.Equal(t, storage.DefaultEncryptionMode, file.EncryptionMode)
+	assert.False(t, file.CreatedAt.IsZero())
+	
+	stored, err := fm.GetFile("file-1")
+	require.NoError(t, err)
+	assert.WithinDuration(t, file.CreatedAt, stored.CreatedAt, time.Second)
+	assert.WithinDuration(t, file.UpdatedAt, stored.UpdatedAt, time.Second)
+}
+
+// saveInterruptedUpload records a file as the app would have left it after stopping mid-upload
+func saveInterruptedUpload(t *testing.T, fm FileManager, id, filePath string, expiration time.Duration) *models.FileMetadata {
+	file := &models.FileMetadata{
+		ID:             id,
+		FileName:       filepath.Base(filePath),
+		FilePath:       filePath,
+		FileSize:       int64(len("interrupted content")),
+		UploadDate:     time.Now().Add(-time.Hour),
+		ExpirationDate: time.Now().Add(expiration),
+		S3Key:          "uploads/" + id,
+		Status:         models.StatusUploading,
+	}
+	require.NoError(t, fm.SaveFile(file))
+	return file
+}
+
+func TestFileManager_RecoverInterruptedUploads(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+	
+	mockS3 := newMockS3Service()
+	fm := NewFileManager(db, mockS3)
+	
+	ctx := context.Background()
+	
+	// Uploaded before the app stopped, but never marked active
+	finished := saveInterruptedUpload(t, fm, "finished", createTestFile(t, "interrupted content"), 24*time.Hour)
+	mockS3.uploadedFiles[finished.S3Key] = true
+	
+	// Not in S3 yet, and the local file is still there
+	resumable := saveInterruptedUpload(t, fm, "resumable", createTestFile(t, "interrupted content"), 24*time.Hour)
+	
+	// Not in S3, and the local file has gone or the file has expired
+	missing := saveInterruptedUpload(t, fm, "missing", filepath.Join(t.TempDir(), "moved.txt"), 24*time.Hour)
+	expired := saveInterruptedUpload(t, fm, "expired", createTestFile(t, "interrupted content"), -time.Minute)
+	
+	// Still being uploaded by this process
+	running := saveInterruptedUpload(t, fm, "running", createTestFile(t, "interrupted content"), 24*time.Hour)
+	fm.(*FileManagerImpl).beginUpload(running.ID)
+	
+	result, err := fm.RecoverInterruptedUploads(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Completed)
+	assert.Equal(t, 1, result.Resumed)
+	assert.Equal(t, 2, result.Failed)
+	assert.Len(t, result.Failures, 2)
+	
+	file, err := fm.GetFile(finished.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusActive, file.Status)
+	assert.Equal(t, "etag-"+finished.S3Key, file.ETag)
+	
+	file, err = fm.GetFile(resumable.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusActive, file.Status)
+	assert.Equal(t, testChecksum, file.Checksum)
+	assert.True(t, mockS3.uploadedFiles[resumable.S3Key])
+	
+	for _, id := range []string{missing.ID, expired.ID} {
+		file, err = fm.GetFile(id)
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusError, file.Status)
+	}
+	
+	file, err = fm.GetFile(running.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusUploading, file.Status)
+	
+	// Once the running upload is no longer registered, it is treated as interrupted
+	fm.(*FileManagerImpl).endUpload(running.ID)
+	result, err = fm.RecoverInterruptedUploads(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Resumed)
+}
+
+func TestFileManager_RecoverInterruptedUploads_Offline(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+	
+	mockS3 := newMockS3Service()
+	fm := NewFileManager(db, mockS3)
+	
+	first := saveInterruptedUpload(t, fm, "first", createTestFile(t, "interrupted content"), 24*time.Hour)
+	saveInterruptedUpload(t, fm, "second", createTestFile(t, "interrupted content"), 24*time.Hour)
+	
+	// Files that can't be looked up are left for the next recovery
+	mockS3.shouldError = true
+	mockS3.errorMsg = "dial tcp: connection refused"
+	result, err := fm.RecoverInterruptedUploads(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, 2, result.Remaining)
+	
+	file, err := fm.GetFile(first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusUploading, file.Status)
+	
+	mockS3.shouldError = false
+	result, err = fm.RecoverInterruptedUploads(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Resumed)
+	assert.Zero(t, result.Remaining)
+}
+
+func TestFileManager_RetryUpload(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+	
+	mockS3 := newMockS3Service()
+	fm := NewFileManager(db, mockS3)
+	
+	ctx := context.Background()
+	
+	mockS3.shouldError = true
+	mockS3.errorMsg = "connection reset"
+	_, err := fm.UploadFile(ctx, createTestFile(t, "retried content"), 24*time.Hour, nil)
+	require.Error(t, err)
+	
+	failed, err := fm.GetFilesByStatus(models.StatusError)
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+	
+	// A retry that fails again keeps the error status
+	_, err = fm.RetryUpload(ctx, failed[0].ID, nil)
+	require.Error(t, err)
+	file, err := fm.GetFile(failed[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusError, file.Status)
+	
+	mockS3.shouldError = false
+	uploaded, err := fm.RetryUpload(ctx, failed[0].ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusActive, uploaded.Status)
+	assert.Equal(t, testChecksum, uploaded.Checksum)
+	assert.True(t, mockS3.uploadedFiles[uploaded.S3Key])
+	
+	// Only failed uploads can be retried
+	_, err = fm.RetryUpload(ctx, uploaded.ID, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "only failed uploads")
+	
+	// Nor can files whose local copy has gone
+	mockS3.shouldError = true
+	_, err = fm.UploadFile(ctx, createTestFile(t, "removed content"), 24*time.Hour, nil)
+	require.Error(t, err)
+	failed, err = fm.GetFilesByStatus(models.StatusError)
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+	require.NoError(t, os.Remove(failed[0].FilePath))
+	
+	mockS3.shouldError = false
+	_, err = fm.RetryUpload(ctx, failed[0].ID, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "can no longer be uploaded")
+}
@@ -0,0 +1,125 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"file-sharing-app/internal/models"
+	"file-sharing-app/internal/storage"
+	"file-sharing-app/pkg/logger"
+)
+
+// NotificationManager decides which desktop notifications to send and keeps their history
+type NotificationManager interface {
+	// Record checks a notification against the settings and adds it to the history.
+	// Returns false if it should not be shown: its event is turned off, or the file was already notified about for it.
+	Record(notification *models.Notification) (bool, error)
+
+	// History returns the most recent notifications, newest first
+	History(limit int) ([]*models.Notification, error)
+
+	// ClearHistory removes all notification history
+	ClearHistory() error
+
+	// ExpiryWarningWindow returns how long before a file expires to warn about it
+	ExpiryWarningWindow() time.Duration
+}
+
+// NotificationManagerImpl implements the NotificationManager interface
+type NotificationManagerImpl struct {
+	db              storage.Database
+	settingsManager SettingsManager
+	logger          *logger.Logger
+}
+
+// NewNotificationManager creates a new NotificationManager instance
+func NewNotificationManager(db storage.Database, settingsManager SettingsManager) *NotificationManagerImpl {
+	return &NotificationManagerImpl{
+		db:              db,
+		settingsManager: settingsManager,
+		logger:          logger.New(),
+	}
+}
+
+// Record checks a notification against the settings and adds it to the history
+func (nm *NotificationManagerImpl) Record(notification *models.Notification) (bool, error) {
+	if notification == nil || notification.Title == "" {
+		return false, fmt.Errorf("notification must have a title")
+	}
+
+	settings, err := nm.settingsManager.LoadSettings()
+	if err != nil {
+		return false, fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	if !settings.NotificationEnabled(notification.Event) {
+		return false, nil
+	}
+
+	// Each file is notified about at most once per event, so periodic checks don't repeat themselves
+	if notification.FileID != "" {
+		sent, err := nm.db.HasNotification(string(notification.Event), notification.FileID)
+		if err != nil {
+			return false, err
+		}
+		if sent {
+			return false, nil
+		}
+	}
+
+	record := &storage.NotificationRecord{
+		Event:   string(notification.Event),
+		FileID:  notification.FileID,
+		Title:   notification.Title,
+		Content: notification.Content,
+	}
+	if err := nm.db.SaveNotification(record); err != nil {
+		return false, err
+	}
+
+	notification.ID = record.ID
+	notification.CreatedAt = record.CreatedAt
+
+	nm.logger.Info(fmt.Sprintf("Notification: %s - %s", notification.Title, notification.Content))
+	return true, nil
+}
+
+// History returns the most recent notifications, newest first
+func (nm *NotificationManagerImpl) History(limit int) ([]*models.Notification, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive")
+	}
+
+	records, err := nm.db.ListNotifications(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	notifications := make([]*models.Notification, len(records))
+	for i, record := range records {
+		notifications[i] = &models.Notification{
+			ID:        record.ID,
+			Event:     models.NotificationEvent(record.Event),
+			FileID:    record.FileID,
+			Title:     record.Title,
+			Content:   record.Content,
+			CreatedAt: record.CreatedAt,
+		}
+	}
+
+	return notifications, nil
+}
+
+// ClearHistory removes all notification history
+func (nm *NotificationManagerImpl) ClearHistory() error {
+	return nm.db.ClearNotifications()
+}
+
+// ExpiryWarningWindow returns how long before a file expires to warn about it
+func (nm *NotificationManagerImpl) ExpiryWarningWindow() time.Duration {
+	settings, err := nm.settingsManager.LoadSettings()
+	if err != nil {
+		return models.DefaultApplicationSettings().GetExpiryWarningWindow()
+	}
+	return settings.GetExpiryWarningWindow()
+}
@@ -0,0 +1,139 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"file-sharing-app/internal/models"
+)
+
+// newTestNotificationManager creates a notification manager over a temporary database with the given settings
+func newTestNotificationManager(t *testing.T, settings *models.ApplicationSettings) *NotificationManagerImpl {
+	db, _ := createTempDatabase(t)
+	t.Cleanup(func() { db.Close() })
+
+	settingsManager := NewSettingsManager(db)
+	if settings != nil {
+		settings.S3Bucket = "test-bucket"
+		require.NoError(t, settingsManager.SaveSettings(settings))
+	}
+
+	return NewNotificationManager(db, settingsManager)
+}
+
+func TestNotificationManager_Record(t *testing.T) {
+	nm := newTestNotificationManager(t, nil)
+
+	notification := &models.Notification{
+		Event:   models.NotifyUploadComplete,
+		FileID:  "file-1",
+		Title:   "Upload complete",
+		Content: "report.pdf was uploaded",
+	}
+
+	show, err := nm.Record(notification)
+	require.NoError(t, err)
+	assert.True(t, show)
+	assert.NotZero(t, notification.ID)
+	assert.False(t, notification.CreatedAt.IsZero())
+
+	history, err := nm.History(10)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, models.NotifyUploadComplete, history[0].Event)
+	assert.Equal(t, "file-1", history[0].FileID)
+	assert.Equal(t, "report.pdf was uploaded", history[0].Content)
+
+	require.NoError(t, nm.ClearHistory())
+	history, err = nm.History(10)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+}
+
+func TestNotificationManager_Record_OncePerFile(t *testing.T) {
+	nm := newTestNotificationManager(t, nil)
+
+	warning := func(fileID string) *models.Notification {
+		return &models.Notification{Event: models.NotifyExpiringSoon, FileID: fileID, Title: "File expiring soon"}
+	}
+
+	show, err := nm.Record(warning("file-1"))
+	require.NoError(t, err)
+	assert.True(t, show)
+
+	// The periodic expiration check doesn't warn about the same file again
+	show, err = nm.Record(warning("file-1"))
+	require.NoError(t, err)
+	assert.False(t, show)
+
+	show, err = nm.Record(warning("file-2"))
+	require.NoError(t, err)
+	assert.True(t, show)
+
+	// Notifications that aren't about a single file always show
+	for i := 0; i < 2; i++ {
+		show, err = nm.Record(&models.Notification{Event: models.NotifyMissingFiles, Title: "Files missing from S3"})
+		require.NoError(t, err)
+		assert.True(t, show)
+	}
+
+	history, err := nm.History(10)
+	require.NoError(t, err)
+	assert.Len(t, history, 4)
+}
+
+func TestNotificationManager_Record_Disabled(t *testing.T) {
+	settings := models.DefaultApplicationSettings()
+	settings.SetNotificationEnabled(models.NotifyUploadComplete, false)
+	nm := newTestNotificationManager(t, settings)
+
+	show, err := nm.Record(&models.Notification{Event: models.NotifyUploadComplete, Title: "Upload complete"})
+	require.NoError(t, err)
+	assert.False(t, show)
+
+	show, err = nm.Record(&models.Notification{Event: models.NotifyUploadFailed, Title: "Upload failed"})
+	require.NoError(t, err)
+	assert.True(t, show)
+
+	// Only notifications that were shown are kept
+	history, err := nm.History(10)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, models.NotifyUploadFailed, history[0].Event)
+}
+
+func TestNotificationManager_Record_AllDisabled(t *testing.T) {
+	settings := models.DefaultApplicationSettings()
+	settings.ShowNotifications = false
+	nm := newTestNotificationManager(t, settings)
+
+	for _, event := range models.NotificationEvents {
+		show, err := nm.Record(&models.Notification{Event: event, Title: "Title"})
+		require.NoError(t, err)
+		assert.False(t, show, event)
+	}
+}
+
+func TestNotificationManager_Validation(t *testing.T) {
+	nm := newTestNotificationManager(t, nil)
+
+	_, err := nm.Record(nil)
+	assert.Error(t, err)
+
+	_, err = nm.Record(&models.Notification{Event: models.NotifyUploadFailed})
+	assert.Error(t, err)
+
+	_, err = nm.History(0)
+	assert.Error(t, err)
+}
+
+func TestNotificationManager_ExpiryWarningWindow(t *testing.T) {
+	assert.Equal(t, 24*time.Hour, newTestNotificationManager(t, nil).ExpiryWarningWindow())
+
+	settings := models.DefaultApplicationSettings()
+	settings.ExpiryWarningHours = 6
+	assert.Equal(t, 6*time.Hour, newTestNotificationManager(t, settings).ExpiryWarningWindow())
+}
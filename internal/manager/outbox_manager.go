@@ -0,0 +1,455 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"file-sharing-app/internal/aws"
+	"file-sharing-app/internal/models"
+	"file-sharing-app/internal/storage"
+	"file-sharing-app/pkg/errors"
+	"file-sharing-app/pkg/logger"
+)
+
+// OutboxManager queues operations made while offline and replays them once S3 is reachable
+type OutboxManager interface {
+	// QueueUpload records a file as pending and queues its upload
+	QueueUpload(filePath string, expiration time.Duration) (*models.FileMetadata, error)
+
+	// QueueUploadWithOptions queues an upload like QueueUpload, with a bandwidth limit of its own
+	// and a time to start it at, which the replay waits for
+	QueueUploadWithOptions(filePath string, expiration time.Duration, opts models.UploadOptions) (*models.FileMetadata, error)
+
+	// QueueShare queues sharing a file with recipients and the options chosen for the share
+	QueueShare(fileID string, recipients []string, message string, opts models.ShareOptions) error
+
+	// QueueSetExpiration queues changing a file's expiration to duration from now
+	QueueSetExpiration(fileID string, duration time.Duration) error
+
+	// QueueDelete queues deleting a file. A file whose upload is still queued is removed straight away.
+	QueueDelete(fileID string) error
+
+	// PendingOperations returns the latest queued operation for each file with one
+	PendingOperations() (map[string]models.OutboxOperation, error)
+
+	// ScheduledUploads returns when each queued upload scheduled for later starts
+	ScheduledUploads() (map[string]time.Time, error)
+
+	// Replay runs queued operations in the order they were queued
+	Replay(ctx context.Context) (*ReplayResult, error)
+}
+
+// ReplayResult summarizes an outbox replay
+type ReplayResult struct {
+	Replayed  int                    `json:"replayed"`
+	Failed    int                    `json:"failed"`
+	Remaining int                    `json:"remaining"`
+	Failures  []string               `json:"failures"`
+	Shares    []*models.ShareRecord `json:"shares"`
+}
+
+// OutboxManagerImpl implements OutboxManager using the outbox table
+type OutboxManagerImpl struct {
+	db                storage.Database
+	fileManager       FileManager
+	shareManager      ShareManager
+	expirationManager ExpirationManager
+	retryConfig       errors.RetryConfig
+	logger            *logger.Logger
+	replayMutex       sync.Mutex
+}
+
+// sharePayload holds the arguments of a queued share
+type sharePayload struct {
+	Recipients []string            `json:"recipients"`
+	Message    string              `json:"message"`
+	Options    models.ShareOptions `json:"options"` // zero in shares queued before options existed
+}
+
+// uploadPayload holds the options of a queued upload; uploads queued without options have none
+type uploadPayload struct {
+	StartAt            time.Time `json:"start_at,omitempty"`
+	BandwidthLimitKBps int       `json:"bandwidth_limit_kbps,omitempty"`
+}
+
+// expirationPayload holds the expiration date a queued change sets
+type expirationPayload struct {
+	ExpirationDate time.Time `json:"expiration_date"`
+}
+
+// NewOutboxManager creates a new OutboxManager instance
+func NewOutboxManager(db storage.Database, fileManager FileManager, shareManager ShareManager, expirationManager ExpirationManager) *OutboxManagerImpl {
+	return &OutboxManagerImpl{
+		db:                db,
+		fileManager:       fileManager,
+		shareManager:      shareManager,
+		expirationManager: expirationManager,
+		retryConfig:       errors.DefaultRetryConfig(),
+		logger:            logger.NewWithComponent("outbox"),
+	}
+}
+
+// QueueUpload records a file as pending and queues its upload
+func (om *OutboxManagerImpl) QueueUpload(filePath string, expiration time.Duration) (*models.FileMetadata, error) {
+	return om.QueueUploadWithOptions(filePath, expiration, models.UploadOptions{})
+}
+
+// QueueUploadWithOptions queues an upload like QueueUpload, with a bandwidth limit of its own
+// and a time to start it at, which the replay waits for. The limit queued is the one set now.
+func (om *OutboxManagerImpl) QueueUploadWithOptions(filePath string, expiration time.Duration, opts models.UploadOptions) (*models.FileMetadata, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	file, err := om.fileManager.CreatePendingUpload(filePath, expiration)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload interface{}
+	if !opts.StartAt.IsZero() || opts.BandwidthLimit.KBps() > 0 {
+		payload = &uploadPayload{StartAt: opts.StartAt, BandwidthLimitKBps: opts.BandwidthLimit.KBps()}
+	}
+
+	if err := om.enqueue(models.OutboxUpload, file.ID, payload); err != nil {
+		// Don't leave a pending file behind that nothing will upload
+		if deleteErr := om.fileManager.DeleteFile(file.ID); deleteErr != nil {
+			om.logger.Error(fmt.Sprintf("Failed to remove pending file %s: %v", file.ID, deleteErr))
+		}
+		return nil, err
+	}
+
+	file.PendingOperation = models.OutboxUpload
+	if opts.ScheduledAfter(time.Now()) {
+		file.ScheduledAt = opts.StartAt
+	}
+	return file, nil
+}
+
+// QueueShare queues sharing a file with recipients and the options chosen for the share
+func (om *OutboxManagerImpl) QueueShare(fileID string, recipients []string, message string, opts models.ShareOptions) error {
+	if len(recipients) == 0 {
+		return fmt.Errorf("at least one recipient must be specified")
+	}
+
+	for _, recipient := range recipients {
+		if err := validateEmail(recipient); err != nil {
+			return fmt.Errorf("invalid recipient email '%s': %w", recipient, err)
+		}
+	}
+
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	file, err := om.getFile(fileID)
+	if err != nil {
+		return err
+	}
+
+	if file.Status != models.StatusActive && file.Status != models.StatusPending {
+		return fmt.Errorf("cannot share file with status: %s", file.Status)
+	}
+
+	if file.EncryptionMode == models.EncryptionSSEC {
+		return fmt.Errorf("cannot share a file encrypted with a customer-provided key (SSE-C) by link")
+	}
+
+	if _, ok := file.InlineContentType(); opts.InlinePreview && !ok {
+		return fmt.Errorf("only PDFs and images can open in the browser")
+	}
+
+	return om.enqueue(models.OutboxShare, fileID, &sharePayload{Recipients: recipients, Message: message, Options: opts})
+}
+
+// QueueSetExpiration queues changing a file's expiration to duration from now
+func (om *OutboxManagerImpl) QueueSetExpiration(fileID string, duration time.Duration) error {
+	if duration <= 0 {
+		return fmt.Errorf("expiration duration must be positive")
+	}
+
+	if _, err := om.getFile(fileID); err != nil {
+		return err
+	}
+
+	// Store the date rather than the duration so the replay sets what the user asked for
+	return om.enqueue(models.OutboxSetExpiration, fileID, &expirationPayload{ExpirationDate: time.Now().Add(duration)})
+}
+
+// QueueDelete queues deleting a file. A file whose upload is still queued is removed straight away.
+func (om *OutboxManagerImpl) QueueDelete(fileID string) error {
+	file, err := om.getFile(fileID)
+	if err != nil {
+		return err
+	}
+
+	if file.Status == models.StatusPending {
+		return om.discardPendingFile(fileID)
+	}
+
+	return om.enqueue(models.OutboxDelete, fileID, nil)
+}
+
+// PendingOperations returns the latest queued operation for each file with one
+func (om *OutboxManagerImpl) PendingOperations() (map[string]models.OutboxOperation, error) {
+	entries, err := om.db.ListOutbox()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list outbox: %w", err)
+	}
+
+	pending := make(map[string]models.OutboxOperation)
+	for _, entry := range entries {
+		pending[entry.FileID] = models.OutboxOperation(entry.Operation)
+	}
+
+	return pending, nil
+}
+
+// ScheduledUploads returns when each queued upload scheduled for later starts
+func (om *OutboxManagerImpl) ScheduledUploads() (map[string]time.Time, error) {
+	entries, err := om.db.ListOutbox()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list outbox: %w", err)
+	}
+
+	now := time.Now()
+	scheduled := make(map[string]time.Time)
+	for _, entry := range entries {
+		if startAt, ok := scheduledStart(entry, now); ok {
+			scheduled[entry.FileID] = startAt
+		}
+	}
+
+	return scheduled, nil
+}
+
+// scheduledStart returns when a queued upload is scheduled to start, and false if it isn't
+// an upload or can start now
+func scheduledStart(entry *storage.OutboxEntry, now time.Time) (time.Time, bool) {
+	if models.OutboxOperation(entry.Operation) != models.OutboxUpload || entry.Payload == "" {
+		return time.Time{}, false
+	}
+	var payload uploadPayload
+	if err := json.Unmarshal([]byte(entry.Payload), &payload); err != nil || !payload.StartAt.After(now) {
+		return time.Time{}, false
+	}
+	return payload.StartAt, true
+}
+
+// Replay runs queued operations in the order they were queued, retrying each with backoff.
+// If an operation still fails because S3 is unreachable, the replay stops and it and everything
+// after it stay queued. Operations that can never succeed are dropped and reported as failures.
+// Operations on files of another profile wait until that profile is active, and uploads
+// scheduled for later wait until their time, along with everything queued after them for the file.
+func (om *OutboxManagerImpl) Replay(ctx context.Context) (*ReplayResult, error) {
+	om.replayMutex.Lock()
+	defer om.replayMutex.Unlock()
+
+	result := &ReplayResult{
+		Failures: []string{},
+		Shares:   []*models.ShareRecord{},
+	}
+
+	entries, err := om.db.ListOutbox()
+	if err != nil {
+		return result, fmt.Errorf("failed to list outbox: %w", err)
+	}
+
+	if len(entries) == 0 {
+		return result, nil
+	}
+
+	om.logger.Info(fmt.Sprintf("Replaying %d queued operations", len(entries)))
+	profile := om.fileManager.GetProfile()
+	now := time.Now()
+	scheduled := make(map[string]bool)
+
+	for i, entry := range entries {
+		if _, ok := scheduledStart(entry, now); ok || scheduled[entry.FileID] {
+			scheduled[entry.FileID] = true
+			result.Remaining++
+			continue
+		}
+
+		file, err := om.fileManager.GetFile(entry.FileID)
+		if err != nil {
+			// The file was removed locally, so there is nothing left to do
+			om.logger.Info(fmt.Sprintf("Dropping queued %s for missing file %s", entry.Operation, entry.FileID))
+			om.removeEntry(entry)
+			continue
+		}
+
+		if file.Profile != profile {
+			result.Remaining++
+			continue
+		}
+
+		attempts := 0
+		var lastErr error
+		err = errors.RetryWithBackoff(ctx, func() error {
+			attempts++
+			lastErr = om.apply(ctx, entry, result)
+			return lastErr
+		}, om.retryConfig)
+
+		if err == nil {
+			result.Replayed++
+			om.removeEntry(entry)
+			continue
+		}
+
+		if recordErr := om.db.RecordOutboxAttempt(entry.ID, lastErr.Error()); recordErr != nil {
+			om.logger.Error(fmt.Sprintf("Failed to record outbox attempt %d: %v", entry.ID, recordErr))
+		}
+
+		// RetryWithBackoff only uses every attempt on errors it considers temporary
+		if ctx.Err() != nil || attempts >= om.retryConfig.MaxAttempts {
+			result.Remaining += len(entries) - i
+			om.logger.Warn(fmt.Sprintf("Outbox replay stopped at %s of file %s: %v", entry.Operation, entry.FileID, lastErr))
+			return result, fmt.Errorf("failed to replay queued %s: %w", entry.Operation, lastErr)
+		}
+
+		om.fail(entry, file, lastErr, result)
+	}
+
+	om.logger.Info(fmt.Sprintf("Outbox replay completed: %d replayed, %d failed, %d remaining",
+		result.Replayed, result.Failed, result.Remaining))
+
+	return result, nil
+}
+
+// apply runs a single queued operation
+func (om *OutboxManagerImpl) apply(ctx context.Context, entry *storage.OutboxEntry, result *ReplayResult) error {
+	switch models.OutboxOperation(entry.Operation) {
+	case models.OutboxUpload:
+		if entry.Payload != "" {
+			var payload uploadPayload
+			if err := json.Unmarshal([]byte(entry.Payload), &payload); err != nil {
+				return fmt.Errorf("invalid queued upload: %w", err)
+			}
+			if payload.BandwidthLimitKBps > 0 {
+				ctx = aws.WithBandwidthLimiter(ctx, aws.NewBandwidthLimiter(models.NewBandwidthLimit(payload.BandwidthLimitKBps)))
+			}
+		}
+		_, err := om.fileManager.UploadPendingFile(ctx, entry.FileID, nil)
+		return err
+
+	case models.OutboxShare:
+		var payload sharePayload
+		if err := json.Unmarshal([]byte(entry.Payload), &payload); err != nil {
+			return fmt.Errorf("invalid queued share: %w", err)
+		}
+		shareRecord, err := om.shareManager.ShareFileWithOptions(ctx, entry.FileID, payload.Recipients, payload.Message, payload.Options)
+		if err != nil {
+			return err
+		}
+		result.Shares = append(result.Shares, shareRecord)
+		return nil
+
+	case models.OutboxSetExpiration:
+		var payload expirationPayload
+		if err := json.Unmarshal([]byte(entry.Payload), &payload); err != nil {
+			return fmt.Errorf("invalid queued expiration change: %w", err)
+		}
+		duration := time.Until(payload.ExpirationDate)
+		if duration <= 0 {
+			return fmt.Errorf("requested expiration date has already passed")
+		}
+		return om.expirationManager.SetExpiration(entry.FileID, duration)
+
+	case models.OutboxDelete:
+		return om.fileManager.RemoveFile(ctx, entry.FileID)
+
+	default:
+		return fmt.Errorf("unknown queued operation: %s", entry.Operation)
+	}
+}
+
+// fail drops an operation that can't succeed, marking a file whose upload failed as an error
+func (om *OutboxManagerImpl) fail(entry *storage.OutboxEntry, file *models.FileMetadata, err error, result *ReplayResult) {
+	om.logger.Error(fmt.Sprintf("Queued %s of file %s failed: %v", entry.Operation, entry.FileID, err))
+
+	result.Failed++
+	result.Failures = append(result.Failures, fmt.Sprintf("%s %s: %v", entry.Operation, file.FileName, err))
+
+	if models.OutboxOperation(entry.Operation) == models.OutboxUpload {
+		if updateErr := om.fileManager.UpdateFileStatus(entry.FileID, models.StatusError); updateErr != nil {
+			om.logger.Error(fmt.Sprintf("Failed to update status for file %s: %v", entry.FileID, updateErr))
+		}
+	}
+
+	om.removeEntry(entry)
+}
+
+// discardPendingFile drops a never-uploaded file and everything queued for it
+func (om *OutboxManagerImpl) discardPendingFile(fileID string) error {
+	entries, err := om.db.ListOutbox()
+	if err != nil {
+		return fmt.Errorf("failed to list outbox: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.FileID == fileID {
+			if err := om.db.DeleteOutboxEntry(entry.ID); err != nil {
+				return fmt.Errorf("failed to remove queued %s: %w", entry.Operation, err)
+			}
+		}
+	}
+
+	if err := om.fileManager.DeleteFile(fileID); err != nil {
+		return fmt.Errorf("failed to delete file record: %w", err)
+	}
+
+	return nil
+}
+
+// enqueue adds an operation with an optional JSON payload to the outbox
+func (om *OutboxManagerImpl) enqueue(operation models.OutboxOperation, fileID string, payload interface{}) error {
+	entry := &storage.OutboxEntry{
+		Operation: string(operation),
+		FileID:    fileID,
+	}
+
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to encode queued %s: %w", operation, err)
+		}
+		entry.Payload = string(data)
+	}
+
+	if err := om.db.EnqueueOutbox(entry); err != nil {
+		return fmt.Errorf("failed to queue %s: %w", operation, err)
+	}
+
+	om.logger.InfoWithFields("Queued operation for when S3 is reachable", map[string]interface{}{
+		"operation": string(operation),
+		"file_id":   fileID,
+	})
+
+	return nil
+}
+
+// removeEntry deletes a finished or dropped operation from the outbox
+func (om *OutboxManagerImpl) removeEntry(entry *storage.OutboxEntry) {
+	if err := om.db.DeleteOutboxEntry(entry.ID); err != nil {
+		om.logger.Error(fmt.Sprintf("Failed to remove outbox entry %d: %v", entry.ID, err))
+	}
+}
+
+// getFile retrieves the metadata of a file an operation is queued for
+func (om *OutboxManagerImpl) getFile(fileID string) (*models.FileMetadata, error) {
+	if fileID == "" {
+		return nil, fmt.Errorf("file ID cannot be empty")
+	}
+
+	file, err := om.fileManager.GetFile(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file metadata: %w", err)
+	}
+
+	return file, nil
+}
@@ -0,0 +1,349 @@
+package manager
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"file-sharing-app/internal/models"
+	"file-sharing-app/internal/storage"
+	"file-sharing-app/pkg/errors"
+)
+
+// newTestOutboxManager creates an outbox manager over a temporary database and mock S3 service
+func newTestOutboxManager(t *testing.T) (*OutboxManagerImpl, FileManager, *mockS3Service, *storage.SQLiteDatabase) {
+	db, _ := createTempDatabase(t)
+	t.Cleanup(func() { db.Close() })
+
+	mockS3 := newMockS3Service()
+	fileManager := NewFileManager(db, mockS3)
+	om := NewOutboxManager(db, fileManager, NewShareManager(db, mockS3), NewExpirationManager(db))
+
+	// Keep retries fast
+	om.retryConfig = errors.RetryConfig{
+		MaxAttempts: 2,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    time.Millisecond,
+		Multiplier:  1.0,
+	}
+
+	return om, fileManager, mockS3, db
+}
+
+func TestOutboxManager_QueueUploadAndShare(t *testing.T) {
+	om, fileManager, mockS3, db := newTestOutboxManager(t)
+
+	file, err := om.QueueUpload(createTestFile(t, "queued content"), 24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusPending, file.Status)
+	assert.Equal(t, models.OutboxUpload, file.PendingOperation)
+
+	require.NoError(t, om.QueueShare(file.ID, []string{"alice@example.com"}, "Here you go", models.ShareOptions{
+		LinkLifetime: time.Hour,
+		MaxDownloads: 1,
+		DownloadName: "Q3 report.txt",
+	}))
+
+	pending, err := om.PendingOperations()
+	require.NoError(t, err)
+	assert.Equal(t, models.OutboxShare, pending[file.ID])
+
+	// Nothing reaches S3 until the replay
+	assert.False(t, mockS3.uploadedFiles[file.S3Key])
+
+	result, err := om.Replay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Replayed)
+	assert.Equal(t, 0, result.Failed)
+	assert.Equal(t, 0, result.Remaining)
+
+	// The upload ran before the share that depends on it
+	assert.True(t, mockS3.uploadedFiles[file.S3Key])
+	require.Len(t, result.Shares, 1)
+	assert.Equal(t, []string{"alice@example.com"}, result.Shares[0].Recipients)
+	assert.Equal(t, "Here you go", result.Shares[0].Message)
+	assert.Equal(t, 1, result.Shares[0].MaxDownloads)
+	assert.Equal(t, "Q3 report.txt", result.Shares[0].DownloadName)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), result.Shares[0].URLExpiration, time.Minute)
+
+	uploaded, err := fileManager.GetFile(file.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusActive, uploaded.Status)
+	assert.Equal(t, testChecksum, uploaded.Checksum)
+
+	entries, err := db.ListOutbox()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestOutboxManager_QueueUploadWithOptions_Scheduled(t *testing.T) {
+	om, fileManager, mockS3, db := newTestOutboxManager(t)
+
+	startAt := time.Now().Add(time.Hour)
+	file, err := om.QueueUploadWithOptions(createTestFile(t, "queued content"), 24*time.Hour, models.UploadOptions{
+		BandwidthLimit: models.NewBandwidthLimit(512),
+		StartAt:        startAt,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusPending, file.Status)
+	assert.Equal(t, startAt, file.ScheduledAt)
+	require.NoError(t, om.QueueShare(file.ID, []string{"alice@example.com"}, "", models.ShareOptions{}))
+
+	other, err := om.QueueUpload(createTestFile(t, "other content"), 24*time.Hour)
+	require.NoError(t, err)
+	assert.True(t, other.ScheduledAt.IsZero())
+
+	scheduled, err := om.ScheduledUploads()
+	require.NoError(t, err)
+	require.Len(t, scheduled, 1)
+	assert.True(t, startAt.Equal(scheduled[file.ID]))
+
+	// The scheduled upload, and the share waiting for it, stay queued; the other upload goes ahead
+	result, err := om.Replay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Replayed)
+	assert.Equal(t, 2, result.Remaining)
+	assert.False(t, mockS3.uploadedFiles[file.S3Key])
+	assert.True(t, mockS3.uploadedFiles[other.S3Key])
+
+	queued, err := fileManager.GetFile(file.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusPending, queued.Status)
+
+	entries, err := db.ListOutbox()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Zero(t, entries[0].Attempts)
+
+	// Once its time has come it is held back no longer
+	_, ok := scheduledStart(entries[0], startAt.Add(time.Second))
+	assert.False(t, ok)
+}
+
+func TestOutboxManager_QueueUploadWithOptions_Due(t *testing.T) {
+	om, fileManager, mockS3, _ := newTestOutboxManager(t)
+
+	// A start time already passed, e.g. while the app was closed, starts the upload at the next replay
+	file, err := om.QueueUploadWithOptions(createTestFile(t, "queued content"), 24*time.Hour, models.UploadOptions{
+		BandwidthLimit: models.NewBandwidthLimit(1024 * 1024),
+		StartAt:        time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+	assert.True(t, file.ScheduledAt.IsZero())
+
+	scheduled, err := om.ScheduledUploads()
+	require.NoError(t, err)
+	assert.Empty(t, scheduled)
+
+	result, err := om.Replay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Replayed)
+	assert.True(t, mockS3.uploadedFiles[file.S3Key])
+
+	uploaded, err := fileManager.GetFile(file.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusActive, uploaded.Status)
+}
+
+func TestOutboxManager_QueueUploadWithOptions_InvalidLimit(t *testing.T) {
+	om, _, _, db := newTestOutboxManager(t)
+
+	_, err := om.QueueUploadWithOptions(createTestFile(t, "queued content"), 24*time.Hour, models.UploadOptions{
+		BandwidthLimit: models.NewBandwidthLimit(1),
+	})
+	assert.Error(t, err)
+
+	entries, err := db.ListOutbox()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestOutboxManager_Replay_StillOffline(t *testing.T) {
+	om, fileManager, mockS3, db := newTestOutboxManager(t)
+
+	file, err := om.QueueUpload(createTestFile(t, "queued content"), 24*time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, om.QueueShare(file.ID, []string{"alice@example.com"}, "", models.ShareOptions{}))
+
+	mockS3.shouldError = true
+	mockS3.errorMsg = "dial tcp: connection refused"
+
+	result, err := om.Replay(context.Background())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "connection refused")
+	assert.Equal(t, 0, result.Replayed)
+	assert.Equal(t, 2, result.Remaining)
+
+	// Everything stays queued, with the failed attempts recorded
+	entries, err := db.ListOutbox()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, 1, entries[0].Attempts)
+	assert.Contains(t, entries[0].LastError, "connection refused")
+
+	queued, err := fileManager.GetFile(file.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusPending, queued.Status)
+
+	// Once S3 is reachable the queue drains
+	mockS3.shouldError = false
+
+	result, err = om.Replay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Replayed)
+	assert.True(t, mockS3.uploadedFiles[file.S3Key])
+}
+
+func TestOutboxManager_Replay_PermanentFailure(t *testing.T) {
+	om, fileManager, _, db := newTestOutboxManager(t)
+
+	filePath := createTestFile(t, "queued content")
+	file, err := om.QueueUpload(filePath, 24*time.Hour)
+	require.NoError(t, err)
+
+	other, err := om.QueueUpload(createTestFile(t, "other content"), 24*time.Hour)
+	require.NoError(t, err)
+
+	// The file is removed from disk before S3 becomes reachable
+	require.NoError(t, os.Remove(filePath))
+
+	result, err := om.Replay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Replayed)
+	assert.Equal(t, 1, result.Failed)
+	require.Len(t, result.Failures, 1)
+	assert.Contains(t, result.Failures[0], "upload "+file.FileName)
+
+	failed, err := fileManager.GetFile(file.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusError, failed.Status)
+
+	uploaded, err := fileManager.GetFile(other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusActive, uploaded.Status)
+
+	entries, err := db.ListOutbox()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestOutboxManager_QueueDelete(t *testing.T) {
+	om, fileManager, mockS3, _ := newTestOutboxManager(t)
+
+	uploaded, err := fileManager.UploadFile(context.Background(), createTestFile(t, "uploaded content"), 24*time.Hour, nil)
+	require.NoError(t, err)
+	require.True(t, mockS3.uploadedFiles[uploaded.S3Key])
+
+	require.NoError(t, om.QueueDelete(uploaded.ID))
+
+	// The file stays listed until the deletion is sent
+	pending, err := om.PendingOperations()
+	require.NoError(t, err)
+	assert.Equal(t, models.OutboxDelete, pending[uploaded.ID])
+
+	result, err := om.Replay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Replayed)
+
+	assert.False(t, mockS3.uploadedFiles[uploaded.S3Key])
+	_, err = fileManager.GetFile(uploaded.ID)
+	assert.Error(t, err)
+}
+
+func TestOutboxManager_QueueDelete_PendingUpload(t *testing.T) {
+	om, fileManager, mockS3, db := newTestOutboxManager(t)
+
+	file, err := om.QueueUpload(createTestFile(t, "queued content"), 24*time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, om.QueueShare(file.ID, []string{"alice@example.com"}, "", models.ShareOptions{}))
+
+	// Deleting a file that was never uploaded cancels everything queued for it
+	require.NoError(t, om.QueueDelete(file.ID))
+
+	_, err = fileManager.GetFile(file.ID)
+	assert.Error(t, err)
+
+	entries, err := db.ListOutbox()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	result, err := om.Replay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Replayed)
+	assert.Empty(t, mockS3.uploadedFiles)
+}
+
+func TestOutboxManager_QueueSetExpiration(t *testing.T) {
+	om, fileManager, _, _ := newTestOutboxManager(t)
+
+	uploaded, err := fileManager.UploadFile(context.Background(), createTestFile(t, "uploaded content"), time.Hour, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, om.QueueSetExpiration(uploaded.ID, 7*24*time.Hour))
+
+	result, err := om.Replay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Replayed)
+
+	updated, err := fileManager.GetFile(uploaded.ID)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(7*24*time.Hour), updated.ExpirationDate, time.Minute)
+}
+
+func TestOutboxManager_QueueValidation(t *testing.T) {
+	om, fileManager, _, _ := newTestOutboxManager(t)
+
+	_, err := om.QueueUpload("", time.Hour)
+	assert.Error(t, err)
+
+	_, err = om.QueueUpload("/non/existent/file.txt", time.Hour)
+	assert.Error(t, err)
+
+	err = om.QueueShare("missing-file", []string{"alice@example.com"}, "", models.ShareOptions{})
+	assert.Error(t, err)
+
+	uploaded, err := fileManager.UploadFile(context.Background(), createTestFile(t, "uploaded content"), time.Hour, nil)
+	require.NoError(t, err)
+
+	err = om.QueueShare(uploaded.ID, []string{}, "", models.ShareOptions{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "at least one recipient")
+
+	err = om.QueueShare(uploaded.ID, []string{"not-an-email"}, "", models.ShareOptions{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid recipient email")
+
+	err = om.QueueShare(uploaded.ID, []string{"alice@example.com"}, "", models.ShareOptions{DownloadName: "../report.txt"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "download name")
+
+	err = om.QueueSetExpiration(uploaded.ID, 0)
+	assert.Error(t, err)
+
+	err = om.QueueDelete("")
+	assert.Error(t, err)
+}
+
+func TestOutboxManager_Replay_OtherProfile(t *testing.T) {
+	om, fileManager, mockS3, db := newTestOutboxManager(t)
+
+	file, err := om.QueueUpload(createTestFile(t, "queued content"), 24*time.Hour)
+	require.NoError(t, err)
+
+	// Operations wait for the profile they were queued under
+	fileManager.SetProfile("work", mockS3)
+
+	result, err := om.Replay(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Replayed)
+	assert.Equal(t, 1, result.Remaining)
+	assert.False(t, mockS3.uploadedFiles[file.S3Key])
+
+	entries, err := db.ListOutbox()
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
@@ -0,0 +1,195 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"file-sharing-app/internal/models"
+	"file-sharing-app/internal/storage"
+)
+
+const (
+	profilesConfigKey      = "profiles"
+	activeProfileConfigKey = "active_profile"
+)
+
+// ProfileManager interface defines the contract for managing named account/bucket profiles
+type ProfileManager interface {
+	// ListProfiles returns all configured profiles
+	ListProfiles() ([]*models.Profile, error)
+
+	// GetProfile returns the profile with the given name
+	GetProfile(name string) (*models.Profile, error)
+
+	// SaveProfile creates or updates a profile
+	SaveProfile(profile *models.Profile) error
+
+	// DeleteProfile removes a profile; the active profile cannot be deleted
+	DeleteProfile(name string) error
+
+	// GetActiveProfile returns the profile currently in use
+	GetActiveProfile() (*models.Profile, error)
+
+	// SetActiveProfile switches the profile currently in use
+	SetActiveProfile(name string) error
+}
+
+// ProfileManagerImpl implements the ProfileManager interface
+type ProfileManagerImpl struct {
+	db storage.Database
+}
+
+// NewProfileManager creates a new profile manager
+func NewProfileManager(db storage.Database) *ProfileManagerImpl {
+	return &ProfileManagerImpl{
+		db: db,
+	}
+}
+
+// ListProfiles returns all configured profiles. If none have been saved yet, a default
+// profile is derived from the existing application settings.
+func (pm *ProfileManagerImpl) ListProfiles() ([]*models.Profile, error) {
+	profilesJSON, err := pm.db.GetConfig(profilesConfigKey)
+	if err != nil {
+		// No profiles saved yet - derive the default profile from the single-bucket settings
+		settings, loadErr := NewSettingsManager(pm.db).LoadSettings()
+		if loadErr != nil {
+			return nil, fmt.Errorf("failed to load settings for default profile: %w", loadErr)
+		}
+		return []*models.Profile{models.ProfileFromSettings(models.DefaultProfileName, settings)}, nil
+	}
+
+	profiles, err := models.ProfilesFromJSON(profilesJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse profiles from database: %w", err)
+	}
+
+	return profiles, nil
+}
+
+// GetProfile returns the profile with the given name
+func (pm *ProfileManagerImpl) GetProfile(name string) (*models.Profile, error) {
+	if name == "" {
+		return nil, fmt.Errorf("profile name cannot be empty")
+	}
+
+	profiles, err := pm.ListProfiles()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, profile := range profiles {
+		if profile.Name == name {
+			return profile, nil
+		}
+	}
+
+	return nil, fmt.Errorf("profile not found: %s", name)
+}
+
+// SaveProfile creates or updates a profile
+func (pm *ProfileManagerImpl) SaveProfile(profile *models.Profile) error {
+	if err := profile.Validate(); err != nil {
+		return fmt.Errorf("profile validation failed: %w", err)
+	}
+
+	profiles, err := pm.ListProfiles()
+	if err != nil {
+		return err
+	}
+
+	profile.LastUpdated = time.Now()
+
+	replaced := false
+	for i, existing := range profiles {
+		if existing.Name == profile.Name {
+			profiles[i] = profile
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		profiles = append(profiles, profile)
+	}
+
+	return pm.saveProfiles(profiles)
+}
+
+// DeleteProfile removes a profile; the active profile cannot be deleted
+func (pm *ProfileManagerImpl) DeleteProfile(name string) error {
+	if name == "" {
+		return fmt.Errorf("profile name cannot be empty")
+	}
+
+	active, err := pm.activeProfileName()
+	if err != nil {
+		return err
+	}
+	if active == name {
+		return fmt.Errorf("cannot delete the active profile: %s", name)
+	}
+
+	profiles, err := pm.ListProfiles()
+	if err != nil {
+		return err
+	}
+
+	remaining := make([]*models.Profile, 0, len(profiles))
+	for _, profile := range profiles {
+		if profile.Name != name {
+			remaining = append(remaining, profile)
+		}
+	}
+
+	if len(remaining) == len(profiles) {
+		return fmt.Errorf("profile not found: %s", name)
+	}
+
+	return pm.saveProfiles(remaining)
+}
+
+// GetActiveProfile returns the profile currently in use
+func (pm *ProfileManagerImpl) GetActiveProfile() (*models.Profile, error) {
+	name, err := pm.activeProfileName()
+	if err != nil {
+		return nil, err
+	}
+
+	return pm.GetProfile(name)
+}
+
+// SetActiveProfile switches the profile currently in use
+func (pm *ProfileManagerImpl) SetActiveProfile(name string) error {
+	if _, err := pm.GetProfile(name); err != nil {
+		return err
+	}
+
+	if err := pm.db.SaveConfig(activeProfileConfigKey, name); err != nil {
+		return fmt.Errorf("failed to save active profile: %w", err)
+	}
+
+	return nil
+}
+
+// activeProfileName returns the name of the active profile, defaulting to the default profile
+func (pm *ProfileManagerImpl) activeProfileName() (string, error) {
+	name, err := pm.db.GetConfig(activeProfileConfigKey)
+	if err != nil || name == "" {
+		return models.DefaultProfileName, nil
+	}
+	return name, nil
+}
+
+// saveProfiles persists the full list of profiles
+func (pm *ProfileManagerImpl) saveProfiles(profiles []*models.Profile) error {
+	profilesJSON, err := models.ProfilesToJSON(profiles)
+	if err != nil {
+		return fmt.Errorf("failed to serialize profiles: %w", err)
+	}
+
+	if err := pm.db.SaveConfig(profilesConfigKey, profilesJSON); err != nil {
+		return fmt.Errorf("failed to save profiles to database: %w", err)
+	}
+
+	return nil
+}
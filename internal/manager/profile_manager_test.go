@@ -0,0 +1,117 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"file-sharing-app/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileManager_DefaultProfileFromSettings(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+
+	// Existing single-bucket settings become the default profile
+	settings := models.DefaultApplicationSettings()
+	settings.S3Bucket = "legacy-bucket"
+	settings.AWSRegion = "eu-west-1"
+	require.NoError(t, NewSettingsManager(db).SaveSettings(settings))
+
+	pm := NewProfileManager(db)
+
+	profiles, err := pm.ListProfiles()
+	require.NoError(t, err)
+	require.Len(t, profiles, 1)
+	assert.Equal(t, models.DefaultProfileName, profiles[0].Name)
+	assert.Equal(t, "legacy-bucket", profiles[0].S3Bucket)
+	assert.Equal(t, "eu-west-1", profiles[0].AWSRegion)
+
+	active, err := pm.GetActiveProfile()
+	require.NoError(t, err)
+	assert.Equal(t, models.DefaultProfileName, active.Name)
+}
+
+func TestProfileManager_SaveAndSwitch(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+	pm := NewProfileManager(db)
+
+	client := models.NewProfile("client-a")
+	client.S3Bucket = "client-a-bucket"
+	client.AWSRegion = "us-west-2"
+	require.NoError(t, pm.SaveProfile(client))
+
+	profiles, err := pm.ListProfiles()
+	require.NoError(t, err)
+	assert.Len(t, profiles, 2)
+
+	require.NoError(t, pm.SetActiveProfile("client-a"))
+
+	active, err := pm.GetActiveProfile()
+	require.NoError(t, err)
+	assert.Equal(t, "client-a", active.Name)
+	assert.Equal(t, "client-a-bucket", active.S3Bucket)
+
+	// Updating keeps a single entry per name
+	client.S3Bucket = "client-a-bucket-2"
+	require.NoError(t, pm.SaveProfile(client))
+
+	profiles, err = pm.ListProfiles()
+	require.NoError(t, err)
+	assert.Len(t, profiles, 2)
+
+	updated, err := pm.GetProfile("client-a")
+	require.NoError(t, err)
+	assert.Equal(t, "client-a-bucket-2", updated.S3Bucket)
+	assert.WithinDuration(t, time.Now(), updated.LastUpdated, time.Minute)
+}
+
+func TestProfileManager_SaveProfile_Invalid(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+	pm := NewProfileManager(db)
+
+	invalid := models.NewProfile("bad name!")
+	invalid.S3Bucket = "bucket"
+	assert.Error(t, pm.SaveProfile(invalid))
+
+	noBucket := models.NewProfile("client-b")
+	assert.Error(t, pm.SaveProfile(noBucket))
+}
+
+func TestProfileManager_SetActiveProfile_Unknown(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+	pm := NewProfileManager(db)
+
+	err := pm.SetActiveProfile("missing")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "profile not found")
+}
+
+func TestProfileManager_DeleteProfile(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+	pm := NewProfileManager(db)
+
+	client := models.NewProfile("client-a")
+	client.S3Bucket = "client-a-bucket"
+	require.NoError(t, pm.SaveProfile(client))
+
+	// The active profile cannot be deleted
+	err := pm.DeleteProfile(models.DefaultProfileName)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "active profile")
+
+	require.NoError(t, pm.DeleteProfile("client-a"))
+
+	_, err = pm.GetProfile("client-a")
+	assert.Error(t, err)
+
+	err = pm.DeleteProfile("client-a")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "profile not found")
+}
@@ -3,33 +3,62 @@ package manager
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 
 	"file-sharing-app/internal/aws"
+	"file-sharing-app/internal/models"
 	"file-sharing-app/internal/storage"
 )
 
 // ShareManager defines the interface for managing file shares
 type ShareManager interface {
 	// ShareFile creates a new share record for a file with recipients and custom message
-	ShareFile(ctx context.Context, fileID string, recipients []string, message string) (*storage.ShareRecord, error)
+	ShareFile(ctx context.Context, fileID string, recipients []string, message string) (*models.ShareRecord, error)
+	
+	// ShareFileWithOptions creates a new share record like ShareFile, with the link lifetime,
+	// download limit and download name chosen for this share
+	ShareFileWithOptions(ctx context.Context, fileID string, recipients []string, message string, opts models.ShareOptions) (*models.ShareRecord, error)
 	
 	// GetShareHistory retrieves all share records for a file
-	GetShareHistory(fileID string) ([]*storage.ShareRecord, error)
+	GetShareHistory(fileID string) ([]*models.ShareRecord, error)
 	
-	// RevokeShare revokes a share by updating its status (not implemented in simplified version)
-	RevokeShare(shareID string) error
+	// RevokeShare stops a share's short link from working, for a share of one of the profile's files.
+	// Presigned links can't be revoked; they work until they expire.
+	RevokeShare(ctx context.Context, profile string, shareID string) error
 	
 	// GeneratePresignedURL generates a presigned URL for a file with specified expiration
 	GeneratePresignedURL(ctx context.Context, fileID string, expiration time.Duration) (string, error)
+	
+	// ShareURL rebuilds a share's link, for a share of one of the profile's files. Links aren't stored:
+	// short links are derived from the share, and presigned links are signed again identically until renewed.
+	ShareURL(ctx context.Context, profile string, shareID string) (string, error)
+	
+	// ListRecentShares returns the profile's most recent shares whose links still work, newest first
+	ListRecentShares(profile string, limit int) ([]*models.RecentShare, error)
+	
+	// RenewExpiringURLs re-signs the links of the profile's shares that expire within the given window
+	RenewExpiringURLs(ctx context.Context, profile string, within time.Duration) (int, error)
+	
+	// SetS3Service replaces the S3 service, e.g. after switching profiles
+	SetS3Service(s3Service aws.S3Service)
+	
+	// SetShareLinks sets up short links for new shares, served from baseURL by the profile's redirect
+	// function, with tokens derived from linkKey. An empty baseURL shares presigned links instead.
+	SetShareLinks(baseURL string, linkKey []byte)
 }
 
 // ShareManagerImpl implements ShareManager interface
 type ShareManagerImpl struct {
 	db        storage.Database
 	s3Service aws.S3Service
+	mutex     sync.RWMutex
+	
+	// Short link settings of the active profile; shareLinkBase is empty when it has none
+	shareLinkBase string
+	shareLinkKey  []byte
 }
 
 // NewShareManager creates a new ShareManager instance
@@ -40,8 +69,53 @@ func NewShareManager(db storage.Database, s3Service aws.S3Service) *ShareManager
 	}
 }
 
+// SetS3Service replaces the S3 service, e.g. after switching profiles
+func (sm *ShareManagerImpl) SetS3Service(s3Service aws.S3Service) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	
+	sm.s3Service = s3Service
+}
+
+// SetShareLinks sets up short links for new shares, served from baseURL by the profile's redirect
+// function, with tokens derived from linkKey. An empty baseURL shares presigned links instead.
+func (sm *ShareManagerImpl) SetShareLinks(baseURL string, linkKey []byte) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	
+	sm.shareLinkBase = baseURL
+	sm.shareLinkKey = linkKey
+}
+
+// getShareLinks returns the short link settings currently in use
+func (sm *ShareManagerImpl) getShareLinks() (string, []byte) {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+	
+	return sm.shareLinkBase, sm.shareLinkKey
+}
+
+// getS3Service returns the S3 service currently in use
+func (sm *ShareManagerImpl) getS3Service() aws.S3Service {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+	
+	return sm.s3Service
+}
+
+// maxPresignedLinkLifetime is the longest a presigned link can work; S3 rejects longer ones
+const maxPresignedLinkLifetime = 7 * 24 * time.Hour
+
 // ShareFile creates a new share record for a file with recipients and custom message
-func (sm *ShareManagerImpl) ShareFile(ctx context.Context, fileID string, recipients []string, message string) (*storage.ShareRecord, error) {
+func (sm *ShareManagerImpl) ShareFile(ctx context.Context, fileID string, recipients []string, message string) (*models.ShareRecord, error) {
+	return sm.ShareFileWithOptions(ctx, fileID, recipients, message, models.ShareOptions{})
+}
+
+// ShareFileWithOptions creates a new share record with the options chosen for this share. A link
+// lifetime ends the link early, but never after the file expires; presigned links can't last
+// more than 7 days. The download name and inline preview are signed into the link, or kept with
+// the short link.
+func (sm *ShareManagerImpl) ShareFileWithOptions(ctx context.Context, fileID string, recipients []string, message string, opts models.ShareOptions) (*models.ShareRecord, error) {
 	if fileID == "" {
 		return nil, fmt.Errorf("file ID cannot be empty")
 	}
@@ -57,6 +131,10 @@ func (sm *ShareManagerImpl) ShareFile(ctx context.Context, fileID string, recipi
 		}
 	}
 
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
 	// Get file metadata to ensure file exists and get S3 key
 	file, err := sm.db.GetFile(fileID)
 	if err != nil {
@@ -64,7 +142,7 @@ func (sm *ShareManagerImpl) ShareFile(ctx context.Context, fileID string, recipi
 	}
 
 	// Check if file is active (not expired or deleted)
-	if file.Status != storage.StatusActive {
+	if file.Status != models.StatusActive {
 		return nil, fmt.Errorf("cannot share file with status: %s", file.Status)
 	}
 
@@ -73,36 +151,106 @@ func (sm *ShareManagerImpl) ShareFile(ctx context.Context, fileID string, recipi
 		return nil, fmt.Errorf("cannot share expired file")
 	}
 
-	// Calculate URL expiration (should not exceed file expiration)
-	urlExpiration := calculateURLExpiration(file.ExpirationDate)
-	
-	// Generate presigned URL
-	presignedURL, err := sm.s3Service.GeneratePresignedURL(ctx, file.S3Key, time.Until(urlExpiration))
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate presigned URL: %w", err)
+	// SSE-C objects can only be read with the customer key, which must never leave this machine
+	if file.EncryptionMode == models.EncryptionSSEC {
+		return nil, fmt.Errorf("cannot share a file encrypted with a customer-provided key (SSE-C) by link")
 	}
 
+	if _, ok := file.InlineContentType(); opts.InlinePreview && !ok {
+		return nil, fmt.Errorf("only PDFs and images can open in the browser")
+	}
+
+	// Calculate URL expiration (should not exceed file expiration)
+	signedAt := time.Now().Truncate(time.Second)
+	urlExpiration := calculateURLExpiration(file.ExpirationDate).Truncate(time.Second)
+
 	// Create share record
-	shareRecord := &storage.ShareRecord{
+	shareRecord := &models.ShareRecord{
 		ID:            uuid.New().String(),
 		FileID:        fileID,
 		Recipients:    recipients,
 		Message:       message,
 		SharedDate:    time.Now(),
-		PresignedURL:  presignedURL,
+		SignedAt:      signedAt,
 		URLExpiration: urlExpiration,
+		ShareOptions:  opts,
+	}
+
+	s3Service := sm.getS3Service()
+	baseURL, linkKey := sm.getShareLinks()
+
+	// The redirect function's role can't decrypt objects under a customer-managed KMS key, so those
+	// files are shared by presigned links, which are signed with the profile's own credentials
+	if file.EncryptionMode == models.EncryptionSSEKMS && baseURL != "" {
+		if opts.LinkLifetime > maxPresignedLinkLifetime {
+			return nil, fmt.Errorf("links to files encrypted with a KMS key (SSE-KMS) can work for at most 7 days")
+		}
+		baseURL, linkKey = "", nil
+	}
+
+	if baseURL == "" && opts.LinkLifetime > maxPresignedLinkLifetime {
+		return nil, fmt.Errorf("links can work for at most 7 days unless short links are set up for the profile")
+	}
+
+	if baseURL != "" {
+		// The redirect function signs each download itself, so a short link can last as long as the file
+		shareRecord.ShortLink = true
+		shareRecord.URLExpiration = file.ExpirationDate.Truncate(time.Second)
+	}
+	if opts.LinkLifetime > 0 {
+		// The chosen lifetime replaces the default, and the link isn't renewed past it
+		shareRecord.URLExpiration = linkExpiration(signedAt, opts.LinkLifetime, file.ExpirationDate)
+	}
+
+	if shareRecord.ShortLink {
+		shareRecord.PresignedURL, err = createShortLink(ctx, s3Service, baseURL, linkKey, file, shareRecord)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create short link: %w", err)
+		}
+	} else {
+		// Generate presigned URL, which is returned with the record but not stored
+		shareRecord.PresignedURL, err = signShare(ctx, s3Service, file, shareRecord)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate presigned URL: %w", err)
+		}
 	}
 
 	// Save share record to database
 	if err := sm.db.SaveShare(shareRecord); err != nil {
+		if shareRecord.ShortLink {
+			// Nobody has the link yet, so don't leave it working
+			_ = s3Service.DeleteShareLink(ctx, aws.ShareLinkToken(linkKey, shareRecord.ID))
+		}
 		return nil, fmt.Errorf("failed to save share record: %w", err)
 	}
 
 	return shareRecord, nil
 }
 
+// createShortLink stores the record the redirect function resolves a share's short link with,
+// and returns the link
+func createShortLink(ctx context.Context, s3Service aws.S3Service, baseURL string, linkKey []byte, file *models.FileMetadata, share *models.ShareRecord) (string, error) {
+	if s3Service == nil {
+		return "", fmt.Errorf("S3 service not available")
+	}
+
+	token := aws.ShareLinkToken(linkKey, share.ID)
+	overrides := shareOverrides(file, share)
+	if err := s3Service.PutShareLink(ctx, &aws.ShareLink{
+		Token:     token,
+		Key:                file.S3Key,
+		ExpiresAt:          share.URLExpiration,
+		ContentDisposition: overrides.ContentDisposition,
+		ContentType:        overrides.ContentType,
+	}); err != nil {
+		return "", err
+	}
+
+	return baseURL + token, nil
+}
+
 // GetShareHistory retrieves all share records for a file
-func (sm *ShareManagerImpl) GetShareHistory(fileID string) ([]*storage.ShareRecord, error) {
+func (sm *ShareManagerImpl) GetShareHistory(fileID string) ([]*models.ShareRecord, error) {
 	if fileID == "" {
 		return nil, fmt.Errorf("file ID cannot be empty")
 	}
@@ -115,19 +263,53 @@ func (sm *ShareManagerImpl) GetShareHistory(fileID string) ([]*storage.ShareReco
 	return shares, nil
 }
 
-// RevokeShare revokes a share by updating its status
-// Note: In the simplified version, we don't implement actual revocation
-// This would require additional database schema changes
-func (sm *ShareManagerImpl) RevokeShare(shareID string) error {
+// RevokeShare stops a share's short link from working by deleting the record the redirect function
+// resolves it with. Presigned links can't be revoked; S3 accepts them until they expire.
+func (sm *ShareManagerImpl) RevokeShare(ctx context.Context, profile string, shareID string) error {
 	if shareID == "" {
 		return fmt.Errorf("share ID cannot be empty")
 	}
 
-	// In a full implementation, this would:
-	// 1. Update share record status to "revoked"
-	// 2. Potentially invalidate the presigned URL (not possible with S3 presigned URLs)
-	// For now, return not implemented error
-	return fmt.Errorf("share revocation not implemented in simplified version")
+	share, err := sm.db.GetShare(shareID)
+	if err != nil {
+		return fmt.Errorf("failed to get share: %w", err)
+	}
+
+	file, err := sm.db.GetFile(share.FileID)
+	if err != nil {
+		return fmt.Errorf("failed to get file metadata: %w", err)
+	}
+
+	// The link record lives in the active profile's bucket
+	if file.Profile != profile {
+		return fmt.Errorf("share belongs to profile %s", file.Profile)
+	}
+	if share.IsRevoked() {
+		return fmt.Errorf("share link was already revoked at %s", share.RevokedAt.Format(time.RFC1123))
+	}
+	if !share.ShortLink {
+		return fmt.Errorf("presigned share links can't be revoked; this one stops working at %s", share.URLExpiration.Format(time.RFC1123))
+	}
+
+	s3Service := sm.getS3Service()
+	if s3Service == nil {
+		return fmt.Errorf("S3 service not available")
+	}
+
+	_, linkKey := sm.getShareLinks()
+	if linkKey == nil {
+		return fmt.Errorf("short links are not set up for profile %s", profile)
+	}
+
+	if err := s3Service.DeleteShareLink(ctx, aws.ShareLinkToken(linkKey, share.ID)); err != nil {
+		return fmt.Errorf("failed to delete short link: %w", err)
+	}
+
+	if err := sm.db.RevokeShare(share.ID, time.Now()); err != nil {
+		return fmt.Errorf("failed to record revocation: %w", err)
+	}
+
+	return nil
 }
 
 // GeneratePresignedURL generates a presigned URL for a file with specified expiration
@@ -147,7 +329,7 @@ func (sm *ShareManagerImpl) GeneratePresignedURL(ctx context.Context, fileID str
 	}
 
 	// Check if file is active
-	if file.Status != storage.StatusActive {
+	if file.Status != models.StatusActive {
 		return "", fmt.Errorf("cannot generate URL for file with status: %s", file.Status)
 	}
 
@@ -156,6 +338,10 @@ func (sm *ShareManagerImpl) GeneratePresignedURL(ctx context.Context, fileID str
 		return "", fmt.Errorf("cannot generate URL for expired file")
 	}
 
+	if file.EncryptionMode == models.EncryptionSSEC {
+		return "", fmt.Errorf("cannot generate URL for a file encrypted with a customer-provided key (SSE-C)")
+	}
+
 	// Ensure expiration doesn't exceed file expiration
 	maxExpiration := time.Until(file.ExpirationDate)
 	if expiration > maxExpiration {
@@ -163,7 +349,7 @@ func (sm *ShareManagerImpl) GeneratePresignedURL(ctx context.Context, fileID str
 	}
 
 	// Generate presigned URL
-	presignedURL, err := sm.s3Service.GeneratePresignedURL(ctx, file.S3Key, expiration)
+	presignedURL, err := sm.getS3Service().GeneratePresignedURL(ctx, file.S3Key, expiration)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate presigned URL: %w", err)
 	}
@@ -171,6 +357,181 @@ func (sm *ShareManagerImpl) GeneratePresignedURL(ctx context.Context, fileID str
 	return presignedURL, nil
 }
 
+// ShareURL rebuilds a share's link, for a share of one of the profile's files. A short link is derived
+// from the share ID, and a presigned link is signed as of the time recorded with the share, so either
+// way it is the same link every time.
+func (sm *ShareManagerImpl) ShareURL(ctx context.Context, profile string, shareID string) (string, error) {
+	if shareID == "" {
+		return "", fmt.Errorf("share ID cannot be empty")
+	}
+
+	s3Service := sm.getS3Service()
+	if s3Service == nil {
+		return "", fmt.Errorf("S3 service not available")
+	}
+
+	share, err := sm.db.GetShare(shareID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get share: %w", err)
+	}
+
+	file, err := sm.db.GetFile(share.FileID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get file metadata: %w", err)
+	}
+
+	// Links can only be signed for the active profile's bucket, and only while the file is available
+	if file.Profile != profile {
+		return "", fmt.Errorf("share belongs to profile %s", file.Profile)
+	}
+	if file.Status != models.StatusActive {
+		return "", fmt.Errorf("cannot copy link for file with status: %s", file.Status)
+	}
+	if share.IsRevoked() {
+		return "", fmt.Errorf("share link was revoked at %s", share.RevokedAt.Format(time.RFC1123))
+	}
+	if !share.URLExpiration.After(time.Now()) {
+		return "", fmt.Errorf("share link expired at %s", share.URLExpiration.Format(time.RFC1123))
+	}
+
+	if share.ShortLink {
+		baseURL, linkKey := sm.getShareLinks()
+		if baseURL == "" {
+			return "", fmt.Errorf("short links are not set up for profile %s", profile)
+		}
+		return baseURL + aws.ShareLinkToken(linkKey, share.ID), nil
+	}
+
+	presignedURL, err := signShare(ctx, s3Service, file, share)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate presigned URL: %w", err)
+	}
+
+	return presignedURL, nil
+}
+
+// signShare signs a share's link as of its signing time, so it is the same link every time
+func signShare(ctx context.Context, s3Service aws.S3Service, file *models.FileMetadata, share *models.ShareRecord) (string, error) {
+	if s3Service == nil {
+		return "", fmt.Errorf("S3 service not available")
+	}
+
+	return s3Service.GeneratePresignedURLAt(ctx, file.S3Key, share.SignedAt, share.URLExpiration.Sub(share.SignedAt), shareOverrides(file, share))
+}
+
+// shareOverrides returns the response headers a share's downloads are served with. Shares
+// without options get none, so the object's own headers name the download.
+func shareOverrides(file *models.FileMetadata, share *models.ShareRecord) aws.ResponseOverrides {
+	var overrides aws.ResponseOverrides
+
+	if share.InlinePreview {
+		// Older objects were stored without a content type browsers can show
+		fileName := file.FileName
+		if share.DownloadName != "" {
+			fileName = share.DownloadName
+		}
+		overrides.ContentDisposition = aws.InlineDisposition(fileName)
+		overrides.ContentType, _ = file.InlineContentType()
+	} else if share.DownloadName != "" {
+		overrides.ContentDisposition = aws.AttachmentDisposition(share.DownloadName)
+	}
+
+	return overrides
+}
+
+// RenewExpiringURLs extends the links of the profile's shares that expire within the given window,
+// so share history keeps a working link for as long as the file itself is available. A renewed
+// share is signed as of now, so its link changes. Returns the number of links renewed.
+func (sm *ShareManagerImpl) RenewExpiringURLs(ctx context.Context, profile string, within time.Duration) (int, error) {
+	s3Service := sm.getS3Service()
+	if s3Service == nil {
+		return 0, fmt.Errorf("S3 service not available")
+	}
+
+	shares, err := sm.db.ListSharesExpiringBefore(time.Now().Add(within))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expiring shares: %w", err)
+	}
+
+	var renewErrors []string
+	renewed := 0
+
+	for _, share := range shares {
+		// Short links already last as long as the file, links given a lifetime end when it is up,
+		// and revoked links stay revoked
+		if share.ShortLink || share.LinkLifetime > 0 || share.IsRevoked() {
+			continue
+		}
+
+		file, err := sm.db.GetFile(share.FileID)
+		if err != nil {
+			continue
+		}
+
+		// Links can only be signed for the active profile's bucket, and only while the file is available
+		if file.Profile != profile || file.Status != models.StatusActive || file.EncryptionMode == models.EncryptionSSEC {
+			continue
+		}
+
+		// Nothing to gain once the link already lasts as long as the file
+		signedAt := time.Now().Truncate(time.Second)
+		urlExpiration := calculateURLExpiration(file.ExpirationDate).Truncate(time.Second)
+		if !urlExpiration.After(share.URLExpiration) {
+			continue
+		}
+
+		if err := sm.db.UpdateShareSigning(share.ID, signedAt, urlExpiration); err != nil {
+			renewErrors = append(renewErrors, fmt.Sprintf("share %s: %v", share.ID, err))
+			continue
+		}
+
+		renewed++
+	}
+
+	if len(renewErrors) > 0 {
+		return renewed, fmt.Errorf("failed to renew %d share links: %v", len(renewErrors), renewErrors)
+	}
+
+	return renewed, nil
+}
+
+// ListRecentShares returns the profile's most recent shares whose links still work, newest first
+func (sm *ShareManagerImpl) ListRecentShares(profile string, limit int) ([]*models.RecentShare, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive")
+	}
+
+	shares, err := sm.db.ListRecentShares(profile, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var recent []*models.RecentShare
+	for _, share := range shares {
+		if !share.URLExpiration.After(now) || share.IsRevoked() {
+			continue
+		}
+
+		file, err := sm.db.GetFile(share.FileID)
+		if err != nil {
+			continue
+		}
+
+		recent = append(recent, &models.RecentShare{
+			ShareID:       share.ID,
+			FileID:        share.FileID,
+			FileName:      file.FileName,
+			Recipients:    share.Recipients,
+			SharedDate:    share.SharedDate,
+			URLExpiration: share.URLExpiration,
+			ShortLink:     share.ShortLink,
+		})
+	}
+
+	return recent, nil
+}
+
 // validateEmail performs basic email format validation
 func validateEmail(email string) error {
 	if email == "" {
@@ -231,4 +592,13 @@ func calculateURLExpiration(fileExpiration time.Time) time.Time {
 	}
 	
 	return defaultExpiration
+}
+
+// linkExpiration returns when a link given the lifetime expires, which is never after the file does
+func linkExpiration(signedAt time.Time, lifetime time.Duration, fileExpiration time.Time) time.Time {
+	expiration := signedAt.Add(lifetime)
+	if fileExpiration.Before(expiration) {
+		expiration = fileExpiration
+	}
+	return expiration.Truncate(time.Second)
 }
\ No newline at end of file
@@ -3,6 +3,7 @@ package manager
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"testing"
@@ -14,16 +15,19 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"file-sharing-app/internal/aws"
+	"file-sharing-app/internal/models"
 	"file-sharing-app/internal/storage"
 )
 
 // MockS3Service implements aws.S3Service for testing
 type MockS3Service struct {
 	generatePresignedURLFunc func(ctx context.Context, key string, expiration time.Duration) (string, error)
-	uploadFileFunc           func(ctx context.Context, key string, filePath string, metadata map[string]string, progressCh chan<- aws.UploadProgress) error
+	uploadFileFunc           func(ctx context.Context, key string, filePath string, metadata map[string]string, progressCh chan<- aws.UploadProgress) (*aws.UploadResult, error)
 	deleteObjectFunc         func(ctx context.Context, key string) error
 	headObjectFunc           func(ctx context.Context, key string) (*s3.HeadObjectOutput, error)
 	testConnectionFunc       func(ctx context.Context) error
+	shareLinks               map[string]*aws.ShareLink // link records by token
+	shareLinkErr             error
 }
 
 func (m *MockS3Service) GeneratePresignedURL(ctx context.Context, key string, expiration time.Duration) (string, error) {
@@ -33,11 +37,37 @@ func (m *MockS3Service) GeneratePresignedURL(ctx context.Context, key string, ex
 	return fmt.Sprintf("https://test-bucket.s3.amazonaws.com/%s?expires=%d", key, int64(expiration.Seconds())), nil
 }
 
-func (m *MockS3Service) UploadFile(ctx context.Context, key string, filePath string, metadata map[string]string, progressCh chan<- aws.UploadProgress) error {
+func (m *MockS3Service) GeneratePresignedURLAt(ctx context.Context, key string, signedAt time.Time, expiration time.Duration, overrides aws.ResponseOverrides) (string, error) {
+	if m.generatePresignedURLFunc != nil {
+		return m.generatePresignedURLFunc(ctx, key, expiration)
+	}
+	presignedURL := fmt.Sprintf("https://test-bucket.s3.amazonaws.com/%s?date=%s&expires=%d", key, signedAt.UTC().Format("20060102T150405Z"), int64(expiration.Seconds()))
+	if overrides.ContentDisposition != "" {
+		presignedURL += "&response-content-disposition=" + url.QueryEscape(overrides.ContentDisposition)
+	}
+	if overrides.ContentType != "" {
+		presignedURL += "&response-content-type=" + url.QueryEscape(overrides.ContentType)
+	}
+	return presignedURL, nil
+}
+
+func (m *MockS3Service) GeneratePresignedDownload(ctx context.Context, key string, expiration time.Duration, encryptionMode string) (*aws.PresignedDownload, error) {
+	url, err := m.GeneratePresignedURL(ctx, key, expiration)
+	if err != nil {
+		return nil, err
+	}
+	return &aws.PresignedDownload{URL: url}, nil
+}
+
+func (m *MockS3Service) EncryptionMode() string {
+	return "SSE-S3"
+}
+
+func (m *MockS3Service) UploadFile(ctx context.Context, key string, filePath string, metadata map[string]string, progressCh chan<- aws.UploadProgress) (*aws.UploadResult, error) {
 	if m.uploadFileFunc != nil {
 		return m.uploadFileFunc(ctx, key, filePath, metadata, progressCh)
 	}
-	return nil
+	return &aws.UploadResult{}, nil
 }
 
 func (m *MockS3Service) DeleteObject(ctx context.Context, key string) error {
@@ -47,6 +77,29 @@ func (m *MockS3Service) DeleteObject(ctx context.Context, key string) error {
 	return nil
 }
 
+func (m *MockS3Service) ExtendExpiration(ctx context.Context, key string, expirationTag string) error {
+	return nil
+}
+
+func (m *MockS3Service) PutShareLink(ctx context.Context, link *aws.ShareLink) error {
+	if m.shareLinkErr != nil {
+		return m.shareLinkErr
+	}
+	if m.shareLinks == nil {
+		m.shareLinks = make(map[string]*aws.ShareLink)
+	}
+	m.shareLinks[link.Token] = link
+	return nil
+}
+
+func (m *MockS3Service) DeleteShareLink(ctx context.Context, token string) error {
+	if m.shareLinkErr != nil {
+		return m.shareLinkErr
+	}
+	delete(m.shareLinks, token)
+	return nil
+}
+
 func (m *MockS3Service) HeadObject(ctx context.Context, key string) (*s3.HeadObjectOutput, error) {
 	if m.headObjectFunc != nil {
 		return m.headObjectFunc(ctx, key)
@@ -132,11 +185,50 @@ func TestShareManager_ShareFile_Success(t *testing.T) {
 	assert.True(t, shareRecord.SharedDate.Before(time.Now().Add(1*time.Second)))
 	assert.True(t, shareRecord.URLExpiration.After(time.Now()))
 	
-	// Verify share record was saved to database
+	// Verify share record was saved to database, without the link
 	shares, err := db.GetShareHistory(file.ID)
 	assert.NoError(t, err)
 	assert.Len(t, shares, 1)
 	assert.Equal(t, shareRecord.ID, shares[0].ID)
+	assert.Empty(t, shares[0].PresignedURL)
+	assert.True(t, shareRecord.SignedAt.Equal(shares[0].SignedAt))
+}
+
+func TestShareManager_ShareURL(t *testing.T) {
+	db := createShareTestDatabase(t)
+	sm := NewShareManager(db, &MockS3Service{})
+	ctx := context.Background()
+	
+	file := createTestFileRecord(t, db, storage.StatusActive, time.Now().Add(7*24*time.Hour))
+	shareRecord, err := sm.ShareFile(ctx, file.ID, []string{"test@example.com"}, "")
+	require.NoError(t, err)
+	
+	// The share is signed to the link that was sent
+	url, err := sm.ShareURL(ctx, storage.DefaultProfile, shareRecord.ID)
+	require.NoError(t, err)
+	assert.Equal(t, shareRecord.PresignedURL, url)
+	
+	// Only the active profile's bucket can be signed for
+	_, err = sm.ShareURL(ctx, "work", shareRecord.ID)
+	assert.Error(t, err)
+	_, err = NewShareManager(db, nil).ShareURL(ctx, storage.DefaultProfile, shareRecord.ID)
+	assert.Error(t, err)
+	
+	_, err = sm.ShareURL(ctx, storage.DefaultProfile, "missing-share")
+	assert.Error(t, err)
+	
+	// Expired links aren't signed again
+	require.NoError(t, db.SaveShare(&storage.ShareRecord{
+		ID:            "lapsed",
+		FileID:        file.ID,
+		Recipients:    []string{"test@example.com"},
+		SharedDate:    time.Now().Add(-2 * time.Hour),
+		SignedAt:      time.Now().Add(-2 * time.Hour),
+		URLExpiration: time.Now().Add(-time.Hour),
+	}))
+	_, err = sm.ShareURL(ctx, storage.DefaultProfile, "lapsed")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "expired")
 }
 
 func TestShareManager_ShareFile_EmptyFileID(t *testing.T) {
@@ -319,15 +411,276 @@ func TestShareManager_GetShareHistory_NoShares(t *testing.T) {
 	assert.Empty(t, shares)
 }
 
+// testShareLinkKey is the key short link tokens are derived from in tests
+var testShareLinkKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestShareManager_ShareFile_ShortLink(t *testing.T) {
+	db := createShareTestDatabase(t)
+	s3Service := &MockS3Service{}
+	sm := NewShareManager(db, s3Service)
+	sm.SetShareLinks("https://links.example.com/", testShareLinkKey)
+	ctx := context.Background()
+	
+	// A month-long file gets a link for the whole month, past the 7-day presign limit
+	expirationDate := time.Now().Add(30 * 24 * time.Hour)
+	file := createTestFileRecord(t, db, storage.StatusActive, expirationDate)
+	
+	shareRecord, err := sm.ShareFile(ctx, file.ID, []string{"test@example.com"}, "")
+	require.NoError(t, err)
+	
+	token := aws.ShareLinkToken(testShareLinkKey, shareRecord.ID)
+	assert.True(t, shareRecord.ShortLink)
+	assert.Equal(t, "https://links.example.com/"+token, shareRecord.PresignedURL)
+	assert.True(t, expirationDate.Truncate(time.Second).Equal(shareRecord.URLExpiration))
+	
+	// The redirect function gets the file and the link's expiry
+	require.Contains(t, s3Service.shareLinks, token)
+	assert.Equal(t, file.S3Key, s3Service.shareLinks[token].Key)
+	assert.True(t, shareRecord.URLExpiration.Equal(s3Service.shareLinks[token].ExpiresAt))
+	
+	// The link is rebuilt, not stored
+	share, err := db.GetShare(shareRecord.ID)
+	require.NoError(t, err)
+	assert.True(t, share.ShortLink)
+	assert.Empty(t, share.PresignedURL)
+	
+	url, err := sm.ShareURL(ctx, storage.DefaultProfile, shareRecord.ID)
+	require.NoError(t, err)
+	assert.Equal(t, shareRecord.PresignedURL, url)
+	
+	// Short links never need renewing
+	renewed, err := sm.RenewExpiringURLs(ctx, storage.DefaultProfile, 60*24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 0, renewed)
+	
+	// Without short links set up, the link can't be rebuilt
+	sm.SetShareLinks("", nil)
+	_, err = sm.ShareURL(ctx, storage.DefaultProfile, shareRecord.ID)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "short links are not set up")
+	
+	// ...and new shares get presigned links again
+	presigned, err := sm.ShareFile(ctx, file.ID, []string{"test@example.com"}, "")
+	require.NoError(t, err)
+	assert.False(t, presigned.ShortLink)
+	assert.Contains(t, presigned.PresignedURL, "test-bucket.s3.amazonaws.com")
+}
+
+func TestShareManager_ShareFile_KMSFileGetsPresignedLink(t *testing.T) {
+	db := createShareTestDatabase(t)
+	s3Service := &MockS3Service{}
+	sm := NewShareManager(db, s3Service)
+	sm.SetShareLinks("https://links.example.com/", testShareLinkKey)
+	ctx := context.Background()
+	
+	file := &storage.FileMetadata{
+		ID:             uuid.New().String(),
+		FileName:       "test.txt",
+		FilePath:       "/tmp/test.txt",
+		FileSize:       1024,
+		UploadDate:     time.Now(),
+		ExpirationDate: time.Now().Add(30 * 24 * time.Hour),
+		S3Key:          "test-key-" + uuid.New().String(),
+		Status:         storage.StatusActive,
+		EncryptionMode: models.EncryptionSSEKMS,
+	}
+	require.NoError(t, db.SaveFile(file))
+	
+	// The redirect function can't read the file, so no short link is created for it
+	shareRecord, err := sm.ShareFile(ctx, file.ID, []string{"test@example.com"}, "")
+	require.NoError(t, err)
+	assert.False(t, shareRecord.ShortLink)
+	assert.Contains(t, shareRecord.PresignedURL, "test-bucket.s3.amazonaws.com")
+	assert.Empty(t, s3Service.shareLinks)
+	
+	// ...and links past the presign limit are refused
+	_, err = sm.ShareFileWithOptions(ctx, file.ID, []string{"test@example.com"}, "", models.ShareOptions{LinkLifetime: 14 * 24 * time.Hour})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "SSE-KMS")
+}
+
+func TestShareManager_ShareFileWithOptions(t *testing.T) {
+	db := createShareTestDatabase(t)
+	sm := NewShareManager(db, &MockS3Service{})
+	ctx := context.Background()
+	
+	file := createTestFileRecord(t, db, storage.StatusActive, time.Now().Add(30*24*time.Hour))
+	opts := models.ShareOptions{LinkLifetime: 3 * 24 * time.Hour, MaxDownloads: 2, DownloadName: "Q3 report.pdf"}
+	
+	shareRecord, err := sm.ShareFileWithOptions(ctx, file.ID, []string{"test@example.com"}, "", opts)
+	require.NoError(t, err)
+	assert.Equal(t, opts, shareRecord.ShareOptions)
+	assert.True(t, shareRecord.SignedAt.Add(opts.LinkLifetime).Equal(shareRecord.URLExpiration))
+	assert.Contains(t, shareRecord.PresignedURL, "expires=259200")
+	assert.Contains(t, shareRecord.PresignedURL, "response-content-disposition="+url.QueryEscape(aws.AttachmentDisposition("Q3 report.pdf")))
+	
+	// The options are kept, so the same link is signed again
+	share, err := db.GetShare(shareRecord.ID)
+	require.NoError(t, err)
+	assert.Equal(t, opts, share.ShareOptions)
+	
+	copied, err := sm.ShareURL(ctx, storage.DefaultProfile, shareRecord.ID)
+	require.NoError(t, err)
+	assert.Equal(t, shareRecord.PresignedURL, copied)
+	
+	// A link given a lifetime isn't renewed past it
+	renewed, err := sm.RenewExpiringURLs(ctx, storage.DefaultProfile, 4*24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 0, renewed)
+	
+	// Links never outlive the file
+	endingSoon := createTestFileRecord(t, db, storage.StatusActive, time.Now().Add(time.Hour))
+	shareRecord, err = sm.ShareFileWithOptions(ctx, endingSoon.ID, []string{"test@example.com"}, "", models.ShareOptions{LinkLifetime: 24 * time.Hour})
+	require.NoError(t, err)
+	assert.True(t, endingSoon.ExpirationDate.Truncate(time.Second).Equal(shareRecord.URLExpiration))
+	
+	// Presigned links can't last longer than S3 allows
+	_, err = sm.ShareFileWithOptions(ctx, file.ID, []string{"test@example.com"}, "", models.ShareOptions{LinkLifetime: 14 * 24 * time.Hour})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "at most 7 days")
+	
+	_, err = sm.ShareFileWithOptions(ctx, file.ID, []string{"test@example.com"}, "", models.ShareOptions{DownloadName: "reports/q3.pdf"})
+	assert.Error(t, err)
+	
+	// Short links can last as long as the file, or as long as the share asks
+	s3Service := &MockS3Service{}
+	sm = NewShareManager(db, s3Service)
+	sm.SetShareLinks("https://links.example.com/", testShareLinkKey)
+	
+	shareRecord, err = sm.ShareFileWithOptions(ctx, file.ID, []string{"test@example.com"}, "", models.ShareOptions{
+		LinkLifetime: 14 * 24 * time.Hour,
+		DownloadName: "Q3 report.pdf",
+	})
+	require.NoError(t, err)
+	assert.True(t, shareRecord.ShortLink)
+	assert.True(t, shareRecord.SignedAt.Add(14*24*time.Hour).Equal(shareRecord.URLExpiration))
+	
+	token := aws.ShareLinkToken(testShareLinkKey, shareRecord.ID)
+	require.Contains(t, s3Service.shareLinks, token)
+	assert.True(t, shareRecord.URLExpiration.Equal(s3Service.shareLinks[token].ExpiresAt))
+	assert.Equal(t, aws.AttachmentDisposition("Q3 report.pdf"), s3Service.shareLinks[token].ContentDisposition)
+}
+
+func TestShareManager_ShareFileWithOptions_InlinePreview(t *testing.T) {
+	db := createShareTestDatabase(t)
+	s3Service := &MockS3Service{}
+	sm := NewShareManager(db, s3Service)
+	ctx := context.Background()
+	
+	scan := &storage.FileMetadata{
+		ID:             uuid.New().String(),
+		FileName:       "scan.pdf",
+		FilePath:       "/tmp/scan.pdf",
+		FileSize:       1024,
+		UploadDate:     time.Now(),
+		ExpirationDate: time.Now().Add(7 * 24 * time.Hour),
+		S3Key:          "uploads/2024/03/01/0b9d.pdf",
+		Status:         storage.StatusActive,
+	}
+	require.NoError(t, db.SaveFile(scan))
+	
+	// PDFs and images open in the browser, as the type browsers can show
+	shareRecord, err := sm.ShareFileWithOptions(ctx, scan.ID, []string{"test@example.com"}, "", models.ShareOptions{InlinePreview: true})
+	require.NoError(t, err)
+	assert.Contains(t, shareRecord.PresignedURL, "response-content-disposition="+url.QueryEscape(aws.InlineDisposition("scan.pdf")))
+	assert.Contains(t, shareRecord.PresignedURL, "response-content-type=application%2Fpdf")
+	
+	share, err := db.GetShare(shareRecord.ID)
+	require.NoError(t, err)
+	assert.True(t, share.InlinePreview)
+	copied, err := sm.ShareURL(ctx, storage.DefaultProfile, shareRecord.ID)
+	require.NoError(t, err)
+	assert.Equal(t, shareRecord.PresignedURL, copied)
+	
+	// Short links keep the overrides for the redirect function
+	sm.SetShareLinks("https://links.example.com/", testShareLinkKey)
+	shareRecord, err = sm.ShareFileWithOptions(ctx, scan.ID, []string{"test@example.com"}, "", models.ShareOptions{InlinePreview: true, DownloadName: "Signed contract.pdf"})
+	require.NoError(t, err)
+	link := s3Service.shareLinks[aws.ShareLinkToken(testShareLinkKey, shareRecord.ID)]
+	require.NotNil(t, link)
+	assert.Equal(t, aws.InlineDisposition("Signed contract.pdf"), link.ContentDisposition)
+	assert.Equal(t, "application/pdf", link.ContentType)
+	
+	// Other files can only be downloaded
+	text := createTestFileRecord(t, db, storage.StatusActive, time.Now().Add(24*time.Hour))
+	_, err = sm.ShareFileWithOptions(ctx, text.ID, []string{"test@example.com"}, "", models.ShareOptions{InlinePreview: true})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "only PDFs and images")
+	
+	// So are files whose content isn't what their name says
+	disguised := *scan
+	disguised.ID = uuid.New().String()
+	disguised.ContentType = "text/html; charset=utf-8"
+	require.NoError(t, db.SaveFile(&disguised))
+	_, err = sm.ShareFileWithOptions(ctx, disguised.ID, []string{"test@example.com"}, "", models.ShareOptions{InlinePreview: true})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "only PDFs and images")
+}
+
+func TestShareManager_ShareFile_ShortLinkError(t *testing.T) {
+	db := createShareTestDatabase(t)
+	sm := NewShareManager(db, &MockS3Service{shareLinkErr: fmt.Errorf("access denied")})
+	sm.SetShareLinks("https://links.example.com/", testShareLinkKey)
+	
+	file := createTestFileRecord(t, db, storage.StatusActive, time.Now().Add(24*time.Hour))
+	_, err := sm.ShareFile(context.Background(), file.ID, []string{"test@example.com"}, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to create short link")
+	
+	shares, err := db.GetShareHistory(file.ID)
+	require.NoError(t, err)
+	assert.Empty(t, shares)
+}
+
 func TestShareManager_RevokeShare(t *testing.T) {
 	db := createShareTestDatabase(t)
 	s3Service := &MockS3Service{}
 	sm := NewShareManager(db, s3Service)
+	ctx := context.Background()
+	
+	file := createTestFileRecord(t, db, storage.StatusActive, time.Now().Add(7*24*time.Hour))
+	
+	// Presigned links work until they expire
+	presigned, err := sm.ShareFile(ctx, file.ID, []string{"test@example.com"}, "")
+	require.NoError(t, err)
+	err = sm.RevokeShare(ctx, storage.DefaultProfile, presigned.ID)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "can't be revoked")
+	
+	sm.SetShareLinks("https://links.example.com/", testShareLinkKey)
+	short, err := sm.ShareFile(ctx, file.ID, []string{"test@example.com"}, "")
+	require.NoError(t, err)
+	token := aws.ShareLinkToken(testShareLinkKey, short.ID)
+	
+	// The link record lives in the active profile's bucket
+	err = sm.RevokeShare(ctx, "work", short.ID)
+	assert.Error(t, err)
+	assert.Contains(t, s3Service.shareLinks, token)
+	
+	require.NoError(t, sm.RevokeShare(ctx, storage.DefaultProfile, short.ID))
+	assert.NotContains(t, s3Service.shareLinks, token, "the redirect function should no longer find the link")
+	
+	share, err := db.GetShare(short.ID)
+	require.NoError(t, err)
+	assert.True(t, share.IsRevoked())
+	
+	// A revoked link can't be copied again
+	_, err = sm.ShareURL(ctx, storage.DefaultProfile, short.ID)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "revoked")
 	
-	err := sm.RevokeShare("test-share-id")
+	recent, err := sm.ListRecentShares(storage.DefaultProfile, 10)
+	require.NoError(t, err)
+	require.Len(t, recent, 1)
+	assert.Equal(t, presigned.ID, recent[0].ShareID)
+	assert.False(t, recent[0].ShortLink)
+	
+	err = sm.RevokeShare(ctx, storage.DefaultProfile, short.ID)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "already revoked")
 	
+	err = sm.RevokeShare(ctx, storage.DefaultProfile, "missing-share")
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "share revocation not implemented")
 }
 
 func TestShareManager_RevokeShare_EmptyShareID(t *testing.T) {
@@ -335,7 +688,7 @@ func TestShareManager_RevokeShare_EmptyShareID(t *testing.T) {
 	s3Service := &MockS3Service{}
 	sm := NewShareManager(db, s3Service)
 	
-	err := sm.RevokeShare("")
+	err := sm.RevokeShare(context.Background(), storage.DefaultProfile, "")
 	
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "share ID cannot be empty")
@@ -469,6 +822,127 @@ func TestShareManager_GeneratePresignedURL_ExpirationLimitedByFile(t *testing.T)
 
 // Test helper functions
 
+func TestShareManager_RenewExpiringURLs(t *testing.T) {
+	db := createShareTestDatabase(t)
+	sm := NewShareManager(db, &MockS3Service{})
+	
+	// saveShare records a share of file whose link expires after expiresIn
+	saveShare := func(file *storage.FileMetadata, expiresIn time.Duration) *storage.ShareRecord {
+		share := &storage.ShareRecord{
+			ID:            uuid.New().String(),
+			FileID:        file.ID,
+			Recipients:    []string{"test@example.com"},
+			SharedDate:    time.Now(),
+			SignedAt:      time.Now().Add(-time.Hour).Truncate(time.Second),
+			URLExpiration: time.Now().Add(expiresIn),
+		}
+		require.NoError(t, db.SaveShare(share))
+		return share
+	}
+	
+	active := createTestFileRecord(t, db, storage.StatusActive, time.Now().Add(7*24*time.Hour))
+	expiring := saveShare(active, 30*time.Minute)
+	fresh := saveShare(active, 20*time.Hour)
+	
+	// Links can't outlive their file, so there is nothing to renew
+	endingSoon := createTestFileRecord(t, db, storage.StatusActive, time.Now().Add(30*time.Minute))
+	saveShare(endingSoon, 30*time.Minute)
+	
+	expired := createTestFileRecord(t, db, storage.StatusExpired, time.Now().Add(-time.Hour))
+	saveShare(expired, -2*time.Hour)
+	
+	renewed, err := sm.RenewExpiringURLs(context.Background(), storage.DefaultProfile, 2*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 1, renewed)
+	
+	shares, err := db.GetShareHistory(active.ID)
+	require.NoError(t, err)
+	for _, share := range shares {
+		switch share.ID {
+		case expiring.ID:
+			assert.WithinDuration(t, time.Now(), share.SignedAt, time.Minute)
+			assert.WithinDuration(t, time.Now().Add(24*time.Hour), share.URLExpiration, time.Minute)
+		case fresh.ID:
+			assert.True(t, fresh.SignedAt.Equal(share.SignedAt))
+		}
+	}
+	
+	// Shares of another profile's files are left for that profile
+	renewed, err = sm.RenewExpiringURLs(context.Background(), "work", 48*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 0, renewed)
+}
+
+func TestShareManager_RenewExpiringURLs_Errors(t *testing.T) {
+	db := createShareTestDatabase(t)
+	
+	_, err := NewShareManager(db, nil).RenewExpiringURLs(context.Background(), storage.DefaultProfile, time.Hour)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "S3 service not available")
+	
+	// Renewing only records a new signing time; links are signed when they are copied
+	s3Service := &MockS3Service{
+		generatePresignedURLFunc: func(ctx context.Context, key string, expiration time.Duration) (string, error) {
+			return "", fmt.Errorf("access denied")
+		},
+	}
+	sm := NewShareManager(db, s3Service)
+	
+	file := createTestFileRecord(t, db, storage.StatusActive, time.Now().Add(7*24*time.Hour))
+	share := &storage.ShareRecord{
+		ID:            uuid.New().String(),
+		FileID:        file.ID,
+		Recipients:    []string{"test@example.com"},
+		SharedDate:    time.Now(),
+		SignedAt:      time.Now(),
+		URLExpiration: time.Now().Add(time.Minute),
+	}
+	require.NoError(t, db.SaveShare(share))
+	
+	renewed, err := sm.RenewExpiringURLs(context.Background(), storage.DefaultProfile, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 1, renewed)
+	
+	_, err = sm.ShareURL(context.Background(), storage.DefaultProfile, share.ID)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "access denied")
+}
+
+func TestShareManager_ListRecentShares(t *testing.T) {
+	db := createShareTestDatabase(t)
+	sm := NewShareManager(db, nil)
+	
+	file := createTestFileRecord(t, db, storage.StatusActive, time.Now().Add(7*24*time.Hour))
+	for id, linkExpiresIn := range map[string]time.Duration{
+		"working": time.Hour,
+		"lapsed":  -time.Hour,
+	} {
+		require.NoError(t, db.SaveShare(&storage.ShareRecord{
+			ID:            id,
+			FileID:        file.ID,
+			Recipients:    []string{"test@example.com"},
+			SharedDate:    time.Now(),
+			SignedAt:      time.Now(),
+			URLExpiration: time.Now().Add(linkExpiresIn),
+		}))
+	}
+	
+	// Only links that still work can be copied
+	shares, err := sm.ListRecentShares(storage.DefaultProfile, 10)
+	require.NoError(t, err)
+	require.Len(t, shares, 1)
+	assert.Equal(t, "working", shares[0].ShareID)
+	assert.Equal(t, file.ID, shares[0].FileID)
+	assert.Equal(t, "test.txt", shares[0].FileName)
+	
+	shares, err = sm.ListRecentShares("work", 10)
+	require.NoError(t, err)
+	assert.Empty(t, shares)
+	
+	_, err = sm.ListRecentShares(storage.DefaultProfile, 0)
+	assert.Error(t, err)
+}
+
 func TestValidateEmail_Valid(t *testing.T) {
 	validEmails := []string{
 		"test@example.com",
@@ -3,8 +3,12 @@ package manager
 import (
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
 	"file-sharing-app/internal/aws"
 	"file-sharing-app/internal/models"
 	"file-sharing-app/internal/storage"
@@ -27,19 +31,27 @@ type SyncManager interface {
 	
 	// GetLastSyncTime returns the timestamp of the last successful sync
 	GetLastSyncTime() (time.Time, error)
+	
+	// SetProfile switches the profile whose files are synchronized and the S3 service used for it
+	SetProfile(profile string, s3Service aws.S3Service)
+	
+	// TestConnection checks whether S3 can be reached, without changing the offline mode state
+	TestConnection(ctx context.Context) error
 }
 
 // SyncResult contains the results of a synchronization operation
 type SyncResult struct {
-	TotalFiles      int                        `json:"total_files"`
-	VerifiedFiles   int                        `json:"verified_files"`
-	MissingFiles    int                        `json:"missing_files"`
-	ErrorFiles      int                        `json:"error_files"`
-	UpdatedFiles    []string                   `json:"updated_files"`
-	MissingFileIDs  []string                   `json:"missing_file_ids"`
-	Errors          []FileVerificationError    `json:"errors"`
-	SyncDuration    time.Duration              `json:"sync_duration"`
-	OfflineMode     bool                       `json:"offline_mode"`
+	TotalFiles        int                     `json:"total_files"`
+	VerifiedFiles     int                     `json:"verified_files"`
+	MissingFiles      int                     `json:"missing_files"`
+	ErrorFiles        int                     `json:"error_files"`
+	MismatchedFiles   int                     `json:"mismatched_files"`
+	UpdatedFiles      []string                `json:"updated_files"`
+	MissingFileIDs    []string                `json:"missing_file_ids"`
+	MismatchedFileIDs []string                `json:"mismatched_file_ids"`
+	Errors            []FileVerificationError `json:"errors"`
+	SyncDuration      time.Duration           `json:"sync_duration"`
+	OfflineMode       bool                    `json:"offline_mode"`
 }
 
 // FileVerificationResult contains the result of verifying a single file
@@ -48,6 +60,7 @@ type FileVerificationResult struct {
 	Exists      bool                     `json:"exists"`
 	OldStatus   models.FileStatus        `json:"old_status"`
 	NewStatus   models.FileStatus        `json:"new_status"`
+	Mismatch    string                   `json:"mismatch,omitempty"` // why the S3 object no longer matches the upload
 	Error       *FileVerificationError   `json:"error,omitempty"`
 }
 
@@ -62,28 +75,32 @@ type FileVerificationError struct {
 type SyncManagerImpl struct {
 	db          storage.Database
 	s3Service   aws.S3Service
+	profile     string
 	logger      *logger.Logger
-	offlineMode bool
+	mutex       sync.RWMutex // guards profile and s3Service, which change on a profile switch
+	offlineMode atomic.Bool  // read by the connectivity monitor while a sync may be running
 }
 
 // NewSyncManager creates a new SyncManager instance
 func NewSyncManager(db storage.Database, s3Service aws.S3Service) SyncManager {
 	return &SyncManagerImpl{
-		db:          db,
-		s3Service:   s3Service,
-		logger:      logger.New(),
-		offlineMode: false,
+		db:        db,
+		s3Service: s3Service,
+		profile:   storage.DefaultProfile,
+		logger:    logger.New(),
 	}
 }
 
 // NewSyncManagerWithoutS3 creates a new SyncManager instance without S3 service (for testing or offline mode)
 func NewSyncManagerWithoutS3(db storage.Database) SyncManager {
-	return &SyncManagerImpl{
-		db:          db,
-		s3Service:   nil,
-		logger:      logger.New(),
-		offlineMode: true,
+	sm := &SyncManagerImpl{
+		db:        db,
+		s3Service: nil,
+		profile:   storage.DefaultProfile,
+		logger:    logger.New(),
 	}
+	sm.offlineMode.Store(true)
+	return sm
 }
 
 // SyncWithS3 synchronizes local file metadata with S3 state
@@ -91,38 +108,43 @@ func (sm *SyncManagerImpl) SyncWithS3(ctx context.Context) (*SyncResult, error)
 	startTime := time.Now()
 	
 	result := &SyncResult{
-		UpdatedFiles:   []string{},
-		MissingFileIDs: []string{},
-		Errors:         []FileVerificationError{},
-		OfflineMode:    sm.offlineMode,
+		UpdatedFiles:      []string{},
+		MissingFileIDs:    []string{},
+		MismatchedFileIDs: []string{},
+		Errors:            []FileVerificationError{},
+		OfflineMode:       sm.offlineMode.Load(),
 	}
 	
 	sm.logger.Info("Starting synchronization with S3")
 	
+	// Sync the profile current now against its own S3 service, even if the profile is switched
+	// while the sync runs; checking its files in another profile's bucket would mark them deleted
+	profile, s3Service := sm.current()
+	
 	// If S3 service is not available, enter offline mode
-	if sm.s3Service == nil {
+	if s3Service == nil {
 		sm.logger.Info("S3 service not available, entering offline mode")
-		sm.offlineMode = true
+		sm.offlineMode.Store(true)
 		result.OfflineMode = true
 		result.SyncDuration = time.Since(startTime)
 		return result, nil
 	}
 	
 	// Test S3 connection first
-	if err := sm.testS3Connection(ctx); err != nil {
+	if err := testConnection(ctx, s3Service); err != nil {
 		sm.logger.Error(fmt.Sprintf("S3 connection test failed: %v", err))
-		sm.offlineMode = true
+		sm.offlineMode.Store(true)
 		result.OfflineMode = true
 		result.SyncDuration = time.Since(startTime)
 		return result, fmt.Errorf("S3 connection failed, entering offline mode: %w", err)
 	}
 	
 	// S3 is available, exit offline mode
-	sm.offlineMode = false
+	sm.offlineMode.Store(false)
 	result.OfflineMode = false
 	
 	// Get all files from local database
-	files, err := sm.getFilesFromDatabase()
+	files, err := sm.getFilesFromDatabase(profile)
 	if err != nil {
 		result.SyncDuration = time.Since(startTime)
 		return result, fmt.Errorf("failed to get files from database: %w", err)
@@ -133,12 +155,14 @@ func (sm *SyncManagerImpl) SyncWithS3(ctx context.Context) (*SyncResult, error)
 	
 	// Verify each file in S3
 	for _, file := range files {
-		// Skip files that are already marked as deleted or error
-		if file.Status == storage.StatusDeleted || file.Status == storage.StatusError {
+		// Skip files that are already marked as deleted or error, and queued uploads not in S3 yet.
+		// Uploads in progress, or interrupted, are left to the file manager's upload recovery.
+		if file.Status == models.StatusDeleted || file.Status == models.StatusError || file.Status == models.StatusPending ||
+			file.Status == models.StatusUploading {
 			continue
 		}
 		
-		verificationResult, err := sm.verifyFileInS3(ctx, file)
+		verificationResult, err := sm.verifyFileInS3(ctx, s3Service, file)
 		if err != nil {
 			result.ErrorFiles++
 			result.Errors = append(result.Errors, FileVerificationError{
@@ -149,7 +173,11 @@ func (sm *SyncManagerImpl) SyncWithS3(ctx context.Context) (*SyncResult, error)
 			continue
 		}
 		
-		if verificationResult.Exists {
+		if verificationResult.Mismatch != "" {
+			result.MismatchedFiles++
+			result.MismatchedFileIDs = append(result.MismatchedFileIDs, file.ID)
+			sm.logger.Warn(fmt.Sprintf("File %s no longer matches its upload: %s", file.ID, verificationResult.Mismatch))
+		} else if verificationResult.Exists {
 			result.VerifiedFiles++
 		} else {
 			result.MissingFiles++
@@ -194,33 +222,35 @@ func (sm *SyncManagerImpl) VerifyFileExists(ctx context.Context, fileID string)
 		return nil, fmt.Errorf("file ID cannot be empty")
 	}
 	
+	profile, s3Service := sm.current()
+	
 	// Get file metadata from database
 	file, err := sm.getFileFromDatabase(fileID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get file from database: %w", err)
 	}
 	
-	// If in offline mode, return current status without verification
-	if sm.offlineMode || sm.s3Service == nil {
+	// If in offline mode, or the file belongs to another profile's bucket, return current status without verification
+	if sm.offlineMode.Load() || s3Service == nil || file.Profile != profile {
 		return &FileVerificationResult{
 			FileID:    fileID,
-			Exists:    file.Status == storage.StatusActive, // Assume active files exist in offline mode
-			OldStatus: models.FileStatus(file.Status),
-			NewStatus: models.FileStatus(file.Status),
+			Exists:    file.Status == models.StatusActive, // Assume active files exist in offline mode
+			OldStatus: file.Status,
+			NewStatus: file.Status,
 		}, nil
 	}
 	
-	return sm.verifyFileInS3(ctx, file)
+	return sm.verifyFileInS3(ctx, s3Service, file)
 }
 
 // IsOfflineMode returns true if the application is in offline mode
 func (sm *SyncManagerImpl) IsOfflineMode() bool {
-	return sm.offlineMode
+	return sm.offlineMode.Load()
 }
 
 // SetOfflineMode sets the offline mode state
 func (sm *SyncManagerImpl) SetOfflineMode(offline bool) {
-	sm.offlineMode = offline
+	sm.offlineMode.Store(offline)
 	if offline {
 		sm.logger.Info("Entered offline mode")
 	} else {
@@ -238,9 +268,38 @@ func (sm *SyncManagerImpl) GetLastSyncTime() (time.Time, error) {
 	return time.Parse(time.RFC3339, value)
 }
 
-// testS3Connection tests the S3 connection
-func (sm *SyncManagerImpl) testS3Connection(ctx context.Context) error {
-	if sm.s3Service == nil {
+// SetProfile switches the profile whose files are synchronized and the S3 service used for it
+func (sm *SyncManagerImpl) SetProfile(profile string, s3Service aws.S3Service) {
+	if profile == "" {
+		profile = storage.DefaultProfile
+	}
+	
+	sm.mutex.Lock()
+	sm.profile = profile
+	sm.s3Service = s3Service
+	sm.mutex.Unlock()
+	
+	sm.offlineMode.Store(s3Service == nil)
+	sm.logger.Info(fmt.Sprintf("Switched sync profile to %s", profile))
+}
+
+// TestConnection checks whether S3 can be reached, without changing the offline mode state
+func (sm *SyncManagerImpl) TestConnection(ctx context.Context) error {
+	_, s3Service := sm.current()
+	return testConnection(ctx, s3Service)
+}
+
+// current returns the profile being synchronized and its S3 service
+func (sm *SyncManagerImpl) current() (string, aws.S3Service) {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+	
+	return sm.profile, sm.s3Service
+}
+
+// testConnection checks whether an S3 service can be reached
+func testConnection(ctx context.Context, s3Service aws.S3Service) error {
+	if s3Service == nil {
 		return fmt.Errorf("S3 service not available")
 	}
 	
@@ -248,25 +307,25 @@ func (sm *SyncManagerImpl) testS3Connection(ctx context.Context) error {
 	testCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 	
-	return sm.s3Service.TestConnection(testCtx)
+	return s3Service.TestConnection(testCtx)
 }
 
-// getFilesFromDatabase retrieves all files from the local database
-func (sm *SyncManagerImpl) getFilesFromDatabase() ([]*storage.FileMetadata, error) {
-	return sm.db.ListFiles()
+// getFilesFromDatabase retrieves all files of a profile from the local database
+func (sm *SyncManagerImpl) getFilesFromDatabase(profile string) ([]*models.FileMetadata, error) {
+	return sm.db.ListFilesByProfile(profile)
 }
 
 // getFileFromDatabase retrieves a specific file from the local database
-func (sm *SyncManagerImpl) getFileFromDatabase(fileID string) (*storage.FileMetadata, error) {
+func (sm *SyncManagerImpl) getFileFromDatabase(fileID string) (*models.FileMetadata, error) {
 	return sm.db.GetFile(fileID)
 }
 
-// verifyFileInS3 checks if a file exists in S3 and determines its correct status
-func (sm *SyncManagerImpl) verifyFileInS3(ctx context.Context, file *storage.FileMetadata) (*FileVerificationResult, error) {
+// verifyFileInS3 checks if a file exists in the S3 service of its profile and determines its correct status
+func (sm *SyncManagerImpl) verifyFileInS3(ctx context.Context, s3Service aws.S3Service, file *models.FileMetadata) (*FileVerificationResult, error) {
 	result := &FileVerificationResult{
 		FileID:    file.ID,
-		OldStatus: models.FileStatus(file.Status),
-		NewStatus: models.FileStatus(file.Status),
+		OldStatus: file.Status,
+		NewStatus: file.Status,
 	}
 	
 	// Use a timeout for S3 operations
@@ -274,13 +333,13 @@ func (sm *SyncManagerImpl) verifyFileInS3(ctx context.Context, file *storage.Fil
 	defer cancel()
 	
 	// Try to get object metadata from S3
-	_, err := sm.s3Service.HeadObject(verifyCtx, file.S3Key)
+	head, err := s3Service.HeadObject(verifyCtx, file.S3Key)
 	if err != nil {
 		// Check if it's a "not found" error
 		if isNotFoundError(err) {
 			result.Exists = false
 			// If file was active but doesn't exist in S3, mark as deleted
-			if file.Status == storage.StatusActive {
+			if file.Status == models.StatusActive {
 				result.NewStatus = models.StatusDeleted
 			}
 		} else {
@@ -295,10 +354,14 @@ func (sm *SyncManagerImpl) verifyFileInS3(ctx context.Context, file *storage.Fil
 	} else {
 		result.Exists = true
 		
-		// Check if file has expired based on local expiration date
-		if time.Now().After(file.ExpirationDate) && file.Status != storage.StatusExpired {
+		// Flag objects that were replaced or altered since they were uploaded
+		if mismatch := integrityMismatch(file, head); mismatch != "" {
+			result.Mismatch = mismatch
+			result.NewStatus = models.StatusError
+		} else if time.Now().After(file.ExpirationDate) && file.Status != models.StatusExpired {
+			// The file has expired based on local expiration date
 			result.NewStatus = models.StatusExpired
-		} else if file.Status == storage.StatusUploading {
+		} else if file.Status == models.StatusUploading {
 			// If file exists in S3 but local status is still uploading, mark as active
 			result.NewStatus = models.StatusActive
 		}
@@ -307,9 +370,31 @@ func (sm *SyncManagerImpl) verifyFileInS3(ctx context.Context, file *storage.Fil
 	return result, nil
 }
 
+// integrityMismatch compares an object's ETag and checksum with those recorded at upload.
+// It returns why they differ, or "" if they match or nothing was recorded to compare.
+func integrityMismatch(file *models.FileMetadata, head *s3.HeadObjectOutput) string {
+	if head == nil {
+		return ""
+	}
+	
+	if file.ETag != "" {
+		if etag := aws.ETagFromHead(head); etag != "" && etag != file.ETag {
+			return fmt.Sprintf("ETag changed from %s to %s", file.ETag, etag)
+		}
+	}
+	
+	if file.Checksum != "" {
+		if checksum := aws.ContentChecksumFromHead(head); checksum != "" && checksum != file.Checksum {
+			return fmt.Sprintf("SHA-256 changed from %s to %s", file.Checksum, checksum)
+		}
+	}
+	
+	return ""
+}
+
 // updateFileStatus updates the status of a file in the database
 func (sm *SyncManagerImpl) updateFileStatus(fileID string, status models.FileStatus) error {
-	return sm.db.UpdateFileStatus(fileID, storage.FileStatus(status))
+	return sm.db.UpdateFileStatus(fileID, status)
 }
 
 // saveLastSyncTime saves the timestamp of the last successful sync
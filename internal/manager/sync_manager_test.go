@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -21,9 +22,12 @@ type MockS3ServiceSync struct {
 	mock.Mock
 }
 
-func (m *MockS3ServiceSync) UploadFile(ctx context.Context, key string, filePath string, metadata map[string]string, progressCh chan<- aws.UploadProgress) error {
+func (m *MockS3ServiceSync) UploadFile(ctx context.Context, key string, filePath string, metadata map[string]string, progressCh chan<- aws.UploadProgress) (*aws.UploadResult, error) {
 	args := m.Called(ctx, key, filePath, metadata, progressCh)
-	return args.Error(0)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*aws.UploadResult), args.Error(1)
 }
 
 func (m *MockS3ServiceSync) GeneratePresignedURL(ctx context.Context, key string, expiration time.Duration) (string, error) {
@@ -31,11 +35,43 @@ func (m *MockS3ServiceSync) GeneratePresignedURL(ctx context.Context, key string
 	return args.String(0), args.Error(1)
 }
 
+func (m *MockS3ServiceSync) GeneratePresignedURLAt(ctx context.Context, key string, signedAt time.Time, expiration time.Duration, overrides aws.ResponseOverrides) (string, error) {
+	args := m.Called(ctx, key, signedAt, expiration, overrides)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockS3ServiceSync) GeneratePresignedDownload(ctx context.Context, key string, expiration time.Duration, encryptionMode string) (*aws.PresignedDownload, error) {
+	args := m.Called(ctx, key, expiration, encryptionMode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*aws.PresignedDownload), args.Error(1)
+}
+
+func (m *MockS3ServiceSync) EncryptionMode() string {
+	return models.EncryptionSSES3
+}
+
 func (m *MockS3ServiceSync) DeleteObject(ctx context.Context, key string) error {
 	args := m.Called(ctx, key)
 	return args.Error(0)
 }
 
+func (m *MockS3ServiceSync) ExtendExpiration(ctx context.Context, key string, expirationTag string) error {
+	args := m.Called(ctx, key, expirationTag)
+	return args.Error(0)
+}
+
+func (m *MockS3ServiceSync) PutShareLink(ctx context.Context, link *aws.ShareLink) error {
+	args := m.Called(ctx, link)
+	return args.Error(0)
+}
+
+func (m *MockS3ServiceSync) DeleteShareLink(ctx context.Context, token string) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
 func (m *MockS3ServiceSync) HeadObject(ctx context.Context, key string) (*s3.HeadObjectOutput, error) {
 	args := m.Called(ctx, key)
 	if args.Get(0) == nil {
@@ -213,6 +249,73 @@ func TestSyncManager_SyncWithS3_Success(t *testing.T) {
 	mockS3.AssertExpectations(t)
 }
 
+func TestSyncManager_SyncWithS3_IntegrityMismatch(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "test.db")
+	db, err := storage.NewSQLiteDatabase(dbPath)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// One file whose object was replaced in S3, one that is unchanged
+	changedFile := &storage.FileMetadata{
+		ID:             "test-file-changed",
+		FileName:       "changed.txt",
+		FilePath:       "/tmp/changed.txt",
+		FileSize:       100,
+		UploadDate:     time.Now().Add(-1 * time.Hour),
+		ExpirationDate: time.Now().Add(1 * time.Hour),
+		S3Key:          "uploads/2024/01/01/changed.txt",
+		Status:         storage.StatusActive,
+		Checksum:       "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		ETag:           "098f6bcd4621d373cade4e832627b4f6",
+	}
+
+	unchangedFile := &storage.FileMetadata{
+		ID:             "test-file-unchanged",
+		FileName:       "unchanged.txt",
+		FilePath:       "/tmp/unchanged.txt",
+		FileSize:       100,
+		UploadDate:     time.Now().Add(-1 * time.Hour),
+		ExpirationDate: time.Now().Add(1 * time.Hour),
+		S3Key:          "uploads/2024/01/01/unchanged.txt",
+		Status:         storage.StatusActive,
+		ETag:           "5d41402abc4b2a76b9719d911017c592",
+	}
+
+	assert.NoError(t, db.SaveFile(changedFile))
+	assert.NoError(t, db.SaveFile(unchangedFile))
+
+	// S3 returns ETags quoted
+	replacedETag := "\"d8e8fca2dc0f896fd7cb4cb0031ba249\""
+	sameETag := "\"5d41402abc4b2a76b9719d911017c592\""
+
+	mockS3 := &MockS3ServiceSync{}
+	mockS3.On("TestConnection", mock.Anything).Return(nil)
+	mockS3.On("HeadObject", mock.Anything, "uploads/2024/01/01/changed.txt").Return(&s3.HeadObjectOutput{ETag: &replacedETag}, nil)
+	mockS3.On("HeadObject", mock.Anything, "uploads/2024/01/01/unchanged.txt").Return(&s3.HeadObjectOutput{ETag: &sameETag}, nil)
+
+	syncManager := NewSyncManager(db, mockS3)
+
+	result, err := syncManager.SyncWithS3(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.TotalFiles)
+	assert.Equal(t, 1, result.VerifiedFiles)
+	assert.Equal(t, 1, result.MismatchedFiles)
+	assert.Equal(t, []string{"test-file-changed"}, result.MismatchedFileIDs)
+	assert.Contains(t, result.UpdatedFiles, "test-file-changed")
+
+	updatedFile, err := db.GetFile("test-file-changed")
+	assert.NoError(t, err)
+	assert.Equal(t, storage.StatusError, updatedFile.Status)
+
+	unchanged, err := db.GetFile("test-file-unchanged")
+	assert.NoError(t, err)
+	assert.Equal(t, storage.StatusActive, unchanged.Status)
+
+	mockS3.AssertExpectations(t)
+}
+
 func TestSyncManager_SyncWithS3_MissingFiles(t *testing.T) {
 	// Create temporary database
 	tempDir := t.TempDir()
@@ -266,6 +369,71 @@ func TestSyncManager_SyncWithS3_MissingFiles(t *testing.T) {
 	mockS3.AssertExpectations(t)
 }
 
+func TestSyncManager_SyncWithS3_ProfileSwitchedDuringSync(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "test.db")
+	db, err := storage.NewSQLiteDatabase(dbPath)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	for _, id := range []string{"alpha-1", "alpha-2"} {
+		err = db.SaveFile(&storage.FileMetadata{
+			ID:             id,
+			FileName:       id + ".txt",
+			FilePath:       "/tmp/" + id + ".txt",
+			FileSize:       100,
+			UploadDate:     time.Now().Add(-1 * time.Hour),
+			ExpirationDate: time.Now().Add(1 * time.Hour),
+			S3Key:          "uploads/2024/01/01/" + id + ".txt",
+			Status:         storage.StatusActive,
+			Profile:        "alpha",
+		})
+		assert.NoError(t, err)
+	}
+
+	// The alpha bucket holds both files; its first lookup waits until the profile has been switched
+	started := make(chan struct{})
+	switched := make(chan struct{})
+	var once sync.Once
+	alphaS3 := &MockS3ServiceSync{}
+	alphaS3.On("TestConnection", mock.Anything).Return(nil)
+	alphaS3.On("HeadObject", mock.Anything, mock.Anything).Run(func(mock.Arguments) {
+		once.Do(func() {
+			close(started)
+			<-switched
+		})
+	}).Return(&s3.HeadObjectOutput{}, nil)
+
+	// The beta bucket has none of alpha's files
+	betaS3 := &MockS3ServiceSync{}
+	betaS3.On("HeadObject", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("NotFound: not found")).Maybe()
+
+	syncManager := NewSyncManager(db, alphaS3)
+	syncManager.SetProfile("alpha", alphaS3)
+
+	done := make(chan *SyncResult)
+	go func() {
+		result, err := syncManager.SyncWithS3(context.Background())
+		assert.NoError(t, err)
+		done <- result
+	}()
+
+	<-started
+	syncManager.SetProfile("beta", betaS3)
+	close(switched)
+	result := <-done
+
+	assert.Equal(t, 2, result.TotalFiles)
+	assert.Equal(t, 2, result.VerifiedFiles)
+	assert.Empty(t, result.UpdatedFiles)
+	for _, id := range []string{"alpha-1", "alpha-2"} {
+		file, err := db.GetFile(id)
+		assert.NoError(t, err)
+		assert.Equal(t, storage.StatusActive, file.Status)
+	}
+	betaS3.AssertNotCalled(t, "HeadObject", mock.Anything, mock.Anything)
+}
+
 func TestSyncManager_VerifyFileExists_OfflineMode(t *testing.T) {
 	// Create temporary database
 	tempDir := t.TempDir()
@@ -347,6 +515,37 @@ func TestSyncManager_VerifyFileExists_Online(t *testing.T) {
 	mockS3.AssertExpectations(t)
 }
 
+func TestSyncManager_VerifyFileExists_OtherProfile(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "test.db")
+	db, err := storage.NewSQLiteDatabase(dbPath)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	err = db.SaveFile(&storage.FileMetadata{
+		ID:             "alpha-file",
+		FileName:       "alpha.txt",
+		FilePath:       "/tmp/alpha.txt",
+		FileSize:       100,
+		UploadDate:     time.Now().Add(-1 * time.Hour),
+		ExpirationDate: time.Now().Add(1 * time.Hour),
+		S3Key:          "uploads/2024/01/01/alpha.txt",
+		Status:         storage.StatusActive,
+		Profile:        "alpha",
+	})
+	assert.NoError(t, err)
+
+	// The file isn't looked up in the beta bucket, where it would be missing
+	betaS3 := &MockS3ServiceSync{}
+	syncManager := NewSyncManager(db, nil)
+	syncManager.SetProfile("beta", betaS3)
+
+	result, err := syncManager.VerifyFileExists(context.Background(), "alpha-file")
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusActive, result.NewStatus)
+	betaS3.AssertNotCalled(t, "HeadObject", mock.Anything, mock.Anything)
+}
+
 func TestSyncManager_GetLastSyncTime(t *testing.T) {
 	// Create temporary database
 	tempDir := t.TempDir()
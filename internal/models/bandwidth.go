@@ -0,0 +1,83 @@
+package models
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Bounds for upload bandwidth limits, in KB/s; zero means unlimited
+const (
+	MinBandwidthLimitKBps = 16
+	MaxBandwidthLimitKBps = 1024 * 1024 // 1 GB/s
+)
+
+// BandwidthLimit is an upload speed cap in KB/s that can be changed while uploads run.
+// A nil limit, or a limit of zero, is unlimited.
+type BandwidthLimit struct {
+	kbps atomic.Int64
+}
+
+// NewBandwidthLimit returns a limit of kbps KB/s
+func NewBandwidthLimit(kbps int) *BandwidthLimit {
+	limit := &BandwidthLimit{}
+	limit.Set(kbps)
+	return limit
+}
+
+// Set changes the limit; uploads running under it slow down or speed up on their next read
+func (l *BandwidthLimit) Set(kbps int) {
+	if kbps < 0 {
+		kbps = 0
+	}
+	l.kbps.Store(int64(kbps))
+}
+
+// KBps returns the limit in KB/s, or zero if unlimited
+func (l *BandwidthLimit) KBps() int {
+	if l == nil {
+		return 0
+	}
+	return int(l.kbps.Load())
+}
+
+// BytesPerSecond returns the limit in bytes per second, or zero if unlimited
+func (l *BandwidthLimit) BytesPerSecond() int64 {
+	return int64(l.KBps()) * 1024
+}
+
+// ValidateBandwidthLimit checks an upload bandwidth limit in KB/s; zero means unlimited
+func ValidateBandwidthLimit(field string, kbps int) error {
+	if kbps != 0 && (kbps < MinBandwidthLimitKBps || kbps > MaxBandwidthLimitKBps) {
+		return &ValidationError{Field: field, Message: fmt.Sprintf("Bandwidth limit must be between %d and %d KB/s", MinBandwidthLimitKBps, MaxBandwidthLimitKBps)}
+	}
+	return nil
+}
+
+// FormatBandwidth returns a limit in KB/s as a short label, such as "512 KB/s" or "2 MB/s"
+func FormatBandwidth(kbps int) string {
+	switch {
+	case kbps <= 0:
+		return "Unlimited"
+	case kbps%1024 == 0:
+		return fmt.Sprintf("%d MB/s", kbps/1024)
+	default:
+		return fmt.Sprintf("%d KB/s", kbps)
+	}
+}
+
+// UploadOptions are the choices made for a single upload. Zero values keep the defaults.
+type UploadOptions struct {
+	BandwidthLimit *BandwidthLimit // caps this upload on top of the global limit; it can be changed while the upload runs
+	StartAt        time.Time       // when to start the upload; zero or a time already passed starts it now
+}
+
+// ScheduledAfter reports whether the upload is to start later than now
+func (o UploadOptions) ScheduledAfter(now time.Time) bool {
+	return o.StartAt.After(now)
+}
+
+// Validate checks the options can be applied to an upload
+func (o UploadOptions) Validate() error {
+	return ValidateBandwidthLimit("bandwidth_limit", o.BandwidthLimit.KBps())
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBandwidthLimit(t *testing.T) {
+	var none *BandwidthLimit
+	assert.Zero(t, none.KBps())
+	assert.Zero(t, none.BytesPerSecond())
+
+	limit := NewBandwidthLimit(512)
+	assert.Equal(t, 512, limit.KBps())
+	assert.Equal(t, int64(512*1024), limit.BytesPerSecond())
+
+	limit.Set(0)
+	assert.Zero(t, limit.BytesPerSecond())
+
+	limit.Set(-5)
+	assert.Zero(t, limit.KBps())
+}
+
+func TestValidateBandwidthLimit(t *testing.T) {
+	assert.NoError(t, ValidateBandwidthLimit("limit", 0))
+	assert.NoError(t, ValidateBandwidthLimit("limit", MinBandwidthLimitKBps))
+	assert.NoError(t, ValidateBandwidthLimit("limit", MaxBandwidthLimitKBps))
+
+	for _, kbps := range []int{-1, MinBandwidthLimitKBps - 1, MaxBandwidthLimitKBps + 1} {
+		err := ValidateBandwidthLimit("limit", kbps)
+		require.Error(t, err, "%d KB/s", kbps)
+		assert.Equal(t, "limit", err.(*ValidationError).Field)
+	}
+}
+
+func TestFormatBandwidth(t *testing.T) {
+	assert.Equal(t, "Unlimited", FormatBandwidth(0))
+	assert.Equal(t, "256 KB/s", FormatBandwidth(256))
+	assert.Equal(t, "1 MB/s", FormatBandwidth(1024))
+	assert.Equal(t, "10 MB/s", FormatBandwidth(10*1024))
+	assert.Equal(t, "1500 KB/s", FormatBandwidth(1500))
+}
+
+func TestUploadOptions(t *testing.T) {
+	now := time.Now()
+
+	assert.NoError(t, UploadOptions{}.Validate())
+	assert.NoError(t, UploadOptions{BandwidthLimit: NewBandwidthLimit(256)}.Validate())
+	assert.Error(t, UploadOptions{BandwidthLimit: NewBandwidthLimit(1)}.Validate())
+
+	assert.False(t, UploadOptions{}.ScheduledAfter(now))
+	assert.False(t, UploadOptions{StartAt: now.Add(-time.Minute)}.ScheduledAfter(now))
+	assert.True(t, UploadOptions{StartAt: now.Add(time.Minute)}.ScheduledAfter(now))
+}
+
+func TestApplicationSettings_UploadBandwidthLimit(t *testing.T) {
+	settings := DefaultApplicationSettings()
+	assert.Zero(t, settings.UploadBandwidthLimitKBps)
+
+	settings.UploadBandwidthLimitKBps = 2048
+	assert.NoError(t, settings.Validate())
+
+	settings.UploadBandwidthLimitKBps = 1
+	err := settings.Validate()
+	require.Error(t, err)
+	assert.Equal(t, "upload_bandwidth_limit_kbps", err.(*ValidationError).Field)
+}
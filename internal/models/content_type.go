@@ -0,0 +1,288 @@
+package models
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// ContentSniffLength is how many bytes from the start of a file are read to detect its type
+const ContentSniffLength = 512
+
+// DefaultContentType is the type of files that can't be identified
+const DefaultContentType = "application/octet-stream"
+
+// contentTypes are the content types of files, by extension. Text types are sent without a
+// charset, as the file may not be UTF-8.
+var contentTypes = map[string]string{
+	// Documents
+	".pdf":     "application/pdf",
+	".txt":     "text/plain",
+	".text":    "text/plain",
+	".log":     "text/plain",
+	".md":      "text/markdown",
+	".rtf":     "application/rtf",
+	".csv":     "text/csv",
+	".tsv":     "text/tab-separated-values",
+	".doc":     "application/msword",
+	".docx":    "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xls":     "application/vnd.ms-excel",
+	".xlsx":    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".ppt":     "application/vnd.ms-powerpoint",
+	".pptx":    "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".odt":     "application/vnd.oasis.opendocument.text",
+	".ods":     "application/vnd.oasis.opendocument.spreadsheet",
+	".odp":     "application/vnd.oasis.opendocument.presentation",
+	".pages":   "application/vnd.apple.pages",
+	".numbers": "application/vnd.apple.numbers",
+	".key":     "application/vnd.apple.keynote",
+	".epub":    "application/epub+zip",
+	".ics":     "text/calendar",
+	".vcf":     "text/vcard",
+	".eml":     "message/rfc822",
+
+	// Web
+	".html":  "text/html",
+	".htm":   "text/html",
+	".xhtml": "application/xhtml+xml",
+	".css":   "text/css",
+	".js":    "text/javascript",
+	".mjs":   "text/javascript",
+	".json":  "application/json",
+	".xml":   "application/xml",
+	".wasm":  "application/wasm",
+
+	// Source code and configuration
+	".go":    "text/x-go",
+	".c":     "text/x-c",
+	".h":     "text/x-c",
+	".cpp":   "text/x-c++",
+	".cc":    "text/x-c++",
+	".hpp":   "text/x-c++",
+	".cs":    "text/x-csharp",
+	".java":  "text/x-java",
+	".kt":    "text/x-kotlin",
+	".py":    "text/x-python",
+	".rb":    "text/x-ruby",
+	".rs":    "text/x-rust",
+	".swift": "text/x-swift",
+	".php":   "text/x-php",
+	".ts":    "text/x-typescript",
+	".tsx":   "text/x-typescript",
+	".jsx":   "text/javascript",
+	".sql":   "application/sql",
+	".sh":    "application/x-sh",
+	".ps1":   "text/x-powershell",
+	".yaml":  "application/yaml",
+	".yml":   "application/yaml",
+	".toml":  "application/toml",
+	".ini":   "text/plain",
+	".diff":  "text/x-diff",
+	".patch": "text/x-diff",
+
+	// Images
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".bmp":  "image/bmp",
+	".tif":  "image/tiff",
+	".tiff": "image/tiff",
+	".ico":  "image/vnd.microsoft.icon",
+	".svg":  "image/svg+xml",
+	".heic": "image/heic",
+	".heif": "image/heif",
+	".avif": "image/avif",
+	".psd":  "image/vnd.adobe.photoshop",
+
+	// Audio
+	".mp3":  "audio/mpeg",
+	".wav":  "audio/wav",
+	".ogg":  "audio/ogg",
+	".oga":  "audio/ogg",
+	".opus": "audio/ogg",
+	".flac": "audio/flac",
+	".aac":  "audio/aac",
+	".m4a":  "audio/mp4",
+	".aif":  "audio/aiff",
+	".aiff": "audio/aiff",
+	".mid":  "audio/midi",
+	".midi": "audio/midi",
+	".weba": "audio/webm",
+
+	// Video
+	".mp4":  "video/mp4",
+	".m4v":  "video/mp4",
+	".mov":  "video/quicktime",
+	".webm": "video/webm",
+	".mkv":  "video/x-matroska",
+	".avi":  "video/x-msvideo",
+	".wmv":  "video/x-ms-wmv",
+	".mpeg": "video/mpeg",
+	".mpg":  "video/mpeg",
+	".ogv":  "video/ogg",
+	".3gp":  "video/3gpp",
+
+	// Fonts
+	".ttf":   "font/ttf",
+	".otf":   "font/otf",
+	".woff":  "font/woff",
+	".woff2": "font/woff2",
+
+	// Archives
+	".zip": "application/zip",
+	".gz":  "application/gzip",
+	".tgz": "application/gzip",
+	".tar": "application/x-tar",
+	".bz2": "application/x-bzip2",
+	".xz":  "application/x-xz",
+	".7z":  "application/x-7z-compressed",
+	".rar": "application/vnd.rar",
+	".zst": "application/zstd",
+
+	// Programs and installers
+	".exe": "application/vnd.microsoft.portable-executable",
+	".dll": "application/vnd.microsoft.portable-executable",
+	".msi": "application/x-msi",
+	".dmg": "application/x-apple-diskimage",
+	".deb": "application/vnd.debian.binary-package",
+	".rpm": "application/x-rpm",
+	".apk": "application/vnd.android.package-archive",
+	".jar": "application/java-archive",
+	".iso": "application/x-iso9660-image",
+	".bat": "application/x-bat",
+	".cmd": "application/x-bat",
+}
+
+// riskyExtensions are files that run as programs or scripts when a recipient opens them
+var riskyExtensions = map[string]bool{
+	".exe": true, ".dll": true, ".msi": true, ".com": true, ".scr": true, ".pif": true,
+	".bat": true, ".cmd": true, ".ps1": true, ".vbs": true, ".vbe": true, ".wsf": true,
+	".hta": true, ".lnk": true, ".reg": true, ".jar": true, ".apk": true, ".app": true,
+	".dmg": true, ".pkg": true, ".sh": true, ".command": true,
+	".js": true, ".mjs": true, ".html": true, ".htm": true, ".xhtml": true, ".svg": true,
+}
+
+// activeContentTypes are the types browsers run scripts in when they open them. Served inline from
+// the bucket's domain, they could read or send anything else opened from it.
+var activeContentTypes = map[string]bool{
+	"text/html":                true,
+	"application/xhtml+xml":    true,
+	"image/svg+xml":            true,
+	"text/xml":                 true,
+	"application/xml":          true,
+	"text/javascript":          true,
+	"application/javascript":   true,
+	"application/x-javascript": true,
+}
+
+// incompressibleContentTypes are types whose content is compressed already, beyond the media types
+var incompressibleContentTypes = map[string]bool{
+	"application/pdf":                         true,
+	"application/zip":                         true,
+	"application/gzip":                        true,
+	"application/x-bzip2":                     true,
+	"application/x-xz":                        true,
+	"application/x-7z-compressed":             true,
+	"application/vnd.rar":                     true,
+	"application/zstd":                        true,
+	"application/epub+zip":                    true,
+	"application/java-archive":                true,
+	"application/x-apple-diskimage":           true,
+	"application/vnd.android.package-archive": true,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   true,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         true,
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": true,
+	"application/vnd.oasis.opendocument.text":                                   true,
+	"application/vnd.oasis.opendocument.spreadsheet":                            true,
+	"application/vnd.oasis.opendocument.presentation":                           true,
+	"font/woff":  true,
+	"font/woff2": true,
+}
+
+// uncompressedImageTypes are the image types worth compressing
+var uncompressedImageTypes = map[string]bool{
+	"image/bmp":                 true,
+	"image/tiff":                true,
+	"image/svg+xml":             true,
+	"image/vnd.microsoft.icon":  true,
+	"image/vnd.adobe.photoshop": true,
+}
+
+// IsCompressible reports whether content of this type is worth trying to compress. Audio, video,
+// most images, archives and zip-based documents are compressed already.
+func IsCompressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "audio/"), strings.HasPrefix(mediaType, "video/"):
+		return false
+	case strings.HasPrefix(mediaType, "image/"):
+		return uncompressedImageTypes[mediaType]
+	default:
+		return !incompressibleContentTypes[mediaType]
+	}
+}
+
+// DetectContentType returns the content type a file is uploaded with, from its name and head,
+// the first ContentSniffLength bytes of its content. A type set for the extension in overrides
+// comes first, then the extension table; content that browsers would run is always stored as
+// such, whatever the file is called. Files with unknown extensions are identified by their content.
+func DetectContentType(fileName string, head []byte, overrides map[string]string) string {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	if contentType, ok := overrides[ext]; ok && ext != "" {
+		return contentType
+	}
+
+	sniffed := ""
+	if len(head) > 0 {
+		sniffed = http.DetectContentType(head)
+	}
+
+	if contentType, ok := contentTypes[ext]; ok {
+		if IsActiveContentType(sniffed) && !IsActiveContentType(contentType) {
+			return sniffed
+		}
+		return contentType
+	}
+
+	if sniffed != "" {
+		return sniffed
+	}
+	return DefaultContentType
+}
+
+// IsActiveContentType reports whether browsers run scripts in content of this type
+func IsActiveContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return activeContentTypes[mediaType]
+}
+
+// IsRiskyFile reports whether a file could run code on a recipient's computer or in their browser,
+// judged by its name and, when known, its content type
+func IsRiskyFile(fileName, contentType string) bool {
+	return riskyExtensions[strings.ToLower(filepath.Ext(fileName))] || IsActiveContentType(contentType)
+}
+
+// ValidateContentTypeOverride checks a content type set for a file extension in the settings
+func ValidateContentTypeOverride(ext, contentType string) error {
+	if len(ext) < 2 || ext[0] != '.' || strings.ContainsAny(ext[1:], "./\\ ") {
+		return fmt.Errorf("%q is not a file extension, such as .md", ext)
+	}
+	if ext != strings.ToLower(ext) {
+		return fmt.Errorf("file extension %q must be lower case", ext)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.Contains(mediaType, "/") {
+		return fmt.Errorf("%q is not a content type, such as text/markdown", contentType)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package models
+
+import "testing"
+
+func TestDetectContentType(t *testing.T) {
+	overrides := map[string]string{".md": "text/plain", ".log": "text/x-log"}
+
+	tests := []struct {
+		fileName    string
+		head        string
+		contentType string
+	}{
+		// By extension, ignoring case
+		{"movie.MP4", "", "video/mp4"},
+		{"song.flac", "", "audio/flac"},
+		{"main.go", "package main", "text/x-go"},
+		{"page.html", "<html>", "text/html"},
+		{"archive.tar", "", "application/x-tar"},
+		// Overrides come first
+		{"notes.md", "# Notes", "text/plain"},
+		{"server.log", "<html>", "text/x-log"},
+		// Unknown extensions are identified by their content
+		{"scan", "%PDF-1.7", "application/pdf"},
+		{"picture.raw", "\x89PNG\x0D\x0A\x1A\x0A", "image/png"},
+		{"data.unknown", "", DefaultContentType},
+		// Content browsers would run is stored as such, whatever the file is called
+		{"invoice.pdf", "<!DOCTYPE html><script>", "text/html; charset=utf-8"},
+		{"photo.jpg", "<?xml version=\"1.0\"?><svg>", "text/xml; charset=utf-8"},
+		{"feed.xml", "<?xml version=\"1.0\"?>", "application/xml"},
+	}
+
+	for _, test := range tests {
+		contentType := DetectContentType(test.fileName, []byte(test.head), overrides)
+		if contentType != test.contentType {
+			t.Errorf("DetectContentType(%s, %q) = %q, expected %q", test.fileName, test.head, contentType, test.contentType)
+		}
+	}
+}
+
+func TestIsRiskyFile(t *testing.T) {
+	tests := []struct {
+		fileName    string
+		contentType string
+		risky       bool
+	}{
+		{"setup.exe", "", true},
+		{"Install.MSI", "", true},
+		{"page.html", "text/html", true},
+		{"logo.svg", "image/svg+xml", true},
+		{"invoice.pdf", "text/html; charset=utf-8", true},
+		{"invoice.pdf", "application/pdf", false},
+		{"notes.txt", "text/plain; charset=utf-8", false},
+		{"photo.jpg", "", false},
+	}
+
+	for _, test := range tests {
+		if risky := IsRiskyFile(test.fileName, test.contentType); risky != test.risky {
+			t.Errorf("IsRiskyFile(%s, %q) = %v, expected %v", test.fileName, test.contentType, risky, test.risky)
+		}
+	}
+}
+
+func TestIsCompressible(t *testing.T) {
+	tests := map[string]bool{
+		"text/plain":                true,
+		"text/csv":                  true,
+		"application/json":          true,
+		"text/plain; charset=utf-8": true,
+		"image/bmp":                 true,
+		"image/svg+xml":             true,
+		DefaultContentType:          true,
+		"image/jpeg":                false,
+		"video/mp4":                 false,
+		"audio/mpeg":                false,
+		"application/zip":           false,
+		"application/pdf":           false,
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.document": false,
+		"not a type": false,
+	}
+
+	for contentType, compressible := range tests {
+		if IsCompressible(contentType) != compressible {
+			t.Errorf("IsCompressible(%q) = %v, expected %v", contentType, !compressible, compressible)
+		}
+	}
+}
+
+func TestValidateContentTypeOverride(t *testing.T) {
+	valid := map[string]string{
+		".md":   "text/markdown",
+		".heic": "image/heic",
+		".txt":  "text/plain; charset=utf-8",
+	}
+	for ext, contentType := range valid {
+		if err := ValidateContentTypeOverride(ext, contentType); err != nil {
+			t.Errorf("Expected %s %s to be valid: %v", ext, contentType, err)
+		}
+	}
+
+	invalid := []struct{ ext, contentType string }{
+		{"md", "text/markdown"},
+		{".", "text/markdown"},
+		{".MD", "text/markdown"},
+		{".tar.gz", "application/gzip"},
+		{".md", "markdown"},
+		{".md", ""},
+	}
+	for _, test := range invalid {
+		if err := ValidateContentTypeOverride(test.ext, test.contentType); err == nil {
+			t.Errorf("Expected %s %s to be invalid", test.ext, test.contentType)
+		}
+	}
+}
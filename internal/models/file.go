@@ -1,6 +1,14 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
 
 // FileStatus represents the current status of a file
 type FileStatus string
@@ -11,18 +19,67 @@ const (
 	StatusExpired   FileStatus = "expired"
 	StatusDeleted   FileStatus = "deleted"
 	StatusError     FileStatus = "error"
+	StatusPending   FileStatus = "pending" // queued for upload while offline
 )
 
 // FileMetadata represents file information stored locally
 type FileMetadata struct {
-	ID             string    `json:"id"`
-	FileName       string    `json:"filename"`
-	FilePath       string    `json:"filepath"`
-	FileSize       int64     `json:"filesize"`
-	UploadDate     time.Time `json:"upload_date"`
-	ExpirationDate time.Time `json:"expiration_date"`
-	S3Key          string    `json:"s3_key"`
-	Status         FileStatus `json:"status"`
+	ID               string          `json:"id"`
+	FileName         string          `json:"filename"`
+	FilePath         string          `json:"filepath"`
+	FileSize         int64           `json:"filesize"`
+	UploadDate       time.Time       `json:"upload_date"`
+	ExpirationDate   time.Time       `json:"expiration_date"`
+	S3Key            string          `json:"s3_key"`
+	Status           FileStatus      `json:"status"`
+	Profile          string          `json:"profile"`
+	EncryptionMode   string          `json:"encryption_mode"`             // "SSE-S3", "SSE-KMS", "SSE-C"
+	Checksum         string          `json:"checksum_sha256,omitempty"`   // hex SHA-256 of the uploaded content, before any compression
+	ETag             string          `json:"etag,omitempty"`              // S3 ETag returned by the upload
+	ContentType      string          `json:"content_type,omitempty"`      // detected on upload; empty for files uploaded before it was
+	Compression      string          `json:"compression,omitempty"`       // "gzip" or "zstd" if stored compressed
+	StoredSize       int64           `json:"stored_size,omitempty"`       // bytes stored in S3; zero if not recorded
+	ScanVerdict      string          `json:"scan_verdict,omitempty"`      // "clean" or "infected"; empty if not scanned
+	ScanEngine       string          `json:"scan_engine,omitempty"`       // the scanner and version that gave the verdict
+	CreatedAt        time.Time       `json:"created_at"`                  // set by the database
+	UpdatedAt        time.Time       `json:"updated_at"`                  // set by the database
+	PendingOperation OutboxOperation `json:"pending_operation,omitempty"` // latest queued operation, if any; not stored
+	ScheduledAt      time.Time       `json:"scheduled_at,omitempty"`      // when a scheduled upload starts, if it is one; not stored
+}
+
+// FileSortField is a column the file list can be sorted by
+type FileSortField string
+
+const (
+	SortByUploadDate FileSortField = "upload_date"
+	SortByFileName   FileSortField = "filename"
+	SortByFileSize   FileSortField = "filesize"
+	SortByExpiration FileSortField = "expiration_date"
+	SortByStatus     FileSortField = "status"
+)
+
+// DefaultFilePageSize is how many files the file list shows at first, and adds each time more are loaded
+const DefaultFilePageSize = 200
+
+// FileQuery filters, sorts and pages the file list. Zero-valued filters match everything.
+type FileQuery struct {
+	Profile          string        `json:"profile,omitempty"`           // the profile owning the files; empty matches every profile
+	Search           string        `json:"search,omitempty"`            // matched against the file name and the recipients and messages of its shares
+	NamePrefix       string        `json:"name_prefix,omitempty"`       // file names starting with this, ignoring case
+	Checksum         string        `json:"checksum,omitempty"`          // files whose content has this hex SHA-256
+	S3Key            string        `json:"s3_key,omitempty"`            // files stored in this S3 object
+	Statuses         []FileStatus  `json:"statuses,omitempty"`          // any of these statuses
+	ExcludedStatuses []FileStatus  `json:"excluded_statuses,omitempty"` // none of these statuses
+	MinSize          int64         `json:"min_size,omitempty"`          // in bytes
+	MaxSize          int64         `json:"max_size,omitempty"`          // in bytes; zero means no limit
+	UploadedAfter    time.Time     `json:"uploaded_after,omitempty"`
+	UploadedBefore   time.Time     `json:"uploaded_before,omitempty"`
+	ExpiresAfter     time.Time     `json:"expires_after,omitempty"`
+	ExpiresBefore    time.Time     `json:"expires_before,omitempty"`
+	SortBy           FileSortField `json:"sort_by,omitempty"`   // defaults to the upload date
+	Ascending        bool          `json:"ascending,omitempty"` // defaults to newest, largest or last first
+	Limit            int           `json:"limit,omitempty"`     // zero means no limit
+	Offset           int           `json:"offset,omitempty"`
 }
 
 // ShareRecord represents a file sharing record
@@ -32,6 +89,120 @@ type ShareRecord struct {
 	Recipients    []string  `json:"recipients"`
 	Message       string    `json:"message"`
 	SharedDate    time.Time `json:"shared_date"`
-	PresignedURL  string    `json:"presigned_url"`
+	SignedAt      time.Time `json:"signed_at"`               // the link is signed as of this time, so it can be signed again identically
+	PresignedURL  string    `json:"presigned_url,omitempty"` // the link sent to recipients; built when needed and never stored
+	URLExpiration time.Time `json:"url_expiration"`
+	ShortLink     bool      `json:"short_link"`              // served by the redirect function, which signs each download itself
+	RevokedAt     time.Time `json:"revoked_at"`              // zero unless the short link was revoked
+	ShareOptions
+	CreatedAt     time.Time `json:"created_at"` // set by the database
+}
+
+// ShareOptions are the choices made for a single share. Zero values keep the defaults.
+type ShareOptions struct {
+	LinkLifetime  time.Duration `json:"link_lifetime"`  // how long the link works, at most until the file expires; zero keeps the default
+	MaxDownloads  int           `json:"max_downloads"`  // how many downloads recipients are asked to keep to; not enforced
+	DownloadName  string        `json:"download_name"`  // file name browsers save the download as, instead of the file's own
+	InlinePreview bool          `json:"inline_preview"` // opens in the browser instead of downloading; PDFs and images only
+}
+
+// previewContentTypes are the content types of the files browsers can show inline, by extension.
+// SVG is left out, as it can run scripts.
+var previewContentTypes = map[string]string{
+	".pdf":  "application/pdf",
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+// PreviewContentType returns the content type a file is shown inline with, and false if
+// browsers can't show it inline
+func PreviewContentType(fileName string) (string, bool) {
+	contentType, ok := previewContentTypes[strings.ToLower(filepath.Ext(fileName))]
+	return contentType, ok
+}
+
+// CompressionRatio returns the stored size as a fraction of the original size, or zero if the
+// stored size wasn't recorded
+func (f *FileMetadata) CompressionRatio() float64 {
+	if f.StoredSize <= 0 || f.FileSize <= 0 {
+		return 0
+	}
+	return float64(f.StoredSize) / float64(f.FileSize)
+}
+
+// InlineContentType returns the content type the file is shown inline with, and false if browsers
+// can't show it inline. Files whose content isn't what their name says, such as HTML named .pdf,
+// are never shown inline.
+func (f *FileMetadata) InlineContentType() (string, bool) {
+	contentType, ok := PreviewContentType(f.FileName)
+	if !ok || f.ContentType == "" {
+		return contentType, ok
+	}
+	mediaType, _, err := mime.ParseMediaType(f.ContentType)
+	if err != nil || mediaType != contentType {
+		return "", false
+	}
+	return contentType, true
+}
+
+// maxDownloadNameLength is the longest file name most file systems accept, in bytes
+const maxDownloadNameLength = 255
+
+// Validate checks the options can be applied to a share
+func (o ShareOptions) Validate() error {
+	if o.LinkLifetime < 0 {
+		return fmt.Errorf("link lifetime cannot be negative")
+	}
+	if o.LinkLifetime > 0 && o.LinkLifetime < time.Minute {
+		return fmt.Errorf("link lifetime must be at least a minute")
+	}
+	if o.MaxDownloads < 0 {
+		return fmt.Errorf("maximum downloads cannot be negative")
+	}
+	return ValidateDownloadName(o.DownloadName)
+}
+
+// ValidateDownloadName checks a file name to save downloads as. An empty name keeps the file's own.
+func ValidateDownloadName(name string) error {
+	if name == "" {
+		return nil
+	}
+	if strings.TrimSpace(name) != name {
+		return fmt.Errorf("download name cannot start or end with spaces")
+	}
+	if len(name) > maxDownloadNameLength {
+		return fmt.Errorf("download name cannot be longer than %d bytes", maxDownloadNameLength)
+	}
+	if !utf8.ValidString(name) {
+		return fmt.Errorf("download name must be valid UTF-8")
+	}
+	if name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("download name must be a file name, not a path")
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("download name cannot contain control characters")
+		}
+	}
+	return nil
+}
+
+// IsRevoked reports whether the share's link was revoked
+func (s *ShareRecord) IsRevoked() bool {
+	return !s.RevokedAt.IsZero()
+}
+
+// RecentShare is a share whose link can still be copied, with the name of the shared file.
+// The link itself is signed again when it is copied.
+type RecentShare struct {
+	ShareID       string    `json:"share_id"`
+	FileID        string    `json:"file_id"`
+	FileName      string    `json:"file_name"`
+	Recipients    []string  `json:"recipients"`
+	SharedDate    time.Time `json:"shared_date"`
 	URLExpiration time.Time `json:"url_expiration"`
+	ShortLink     bool      `json:"short_link"` // only short links can be revoked
 }
\ No newline at end of file
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -71,4 +72,95 @@ func TestShareRecord(t *testing.T) {
 	if share.Recipients[0] != "user@example.com" {
 		t.Errorf("Expected recipient to be 'user@example.com', got %s", share.Recipients[0])
 	}
-}
\ No newline at end of file
+}
+func TestShareOptions_Validate(t *testing.T) {
+	valid := []ShareOptions{
+		{},
+		{LinkLifetime: time.Hour, MaxDownloads: 3, DownloadName: "Quarterly report.pdf"},
+		{DownloadName: "résumé.pdf"},
+	}
+	for _, opts := range valid {
+		if err := opts.Validate(); err != nil {
+			t.Errorf("Expected %+v to be valid, got %v", opts, err)
+		}
+	}
+
+	invalid := []ShareOptions{
+		{LinkLifetime: -time.Hour},
+		{LinkLifetime: time.Second},
+		{MaxDownloads: -1},
+		{DownloadName: "reports/q3.pdf"},
+		{DownloadName: `reports\q3.pdf`},
+		{DownloadName: ".."},
+		{DownloadName: " report.pdf"},
+		{DownloadName: "report\n.pdf"},
+		{DownloadName: "report\xff.pdf"},
+		{DownloadName: strings.Repeat("a", 256)},
+	}
+	for _, opts := range invalid {
+		if err := opts.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", opts)
+		}
+	}
+}
+
+func TestPreviewContentType(t *testing.T) {
+	tests := []struct {
+		fileName    string
+		contentType string
+		ok          bool
+	}{
+		{"report.pdf", "application/pdf", true},
+		{"Photo.JPG", "image/jpeg", true},
+		{"diagram.png", "image/png", true},
+		{"logo.svg", "", false},
+		{"notes.txt", "", false},
+		{"README", "", false},
+	}
+
+	for _, test := range tests {
+		contentType, ok := PreviewContentType(test.fileName)
+		if contentType != test.contentType || ok != test.ok {
+			t.Errorf("PreviewContentType(%s) = %q, %v, expected %q, %v", test.fileName, contentType, ok, test.contentType, test.ok)
+		}
+	}
+}
+
+func TestFileMetadata_CompressionRatio(t *testing.T) {
+	file := &FileMetadata{FileSize: 1000}
+	if ratio := file.CompressionRatio(); ratio != 0 {
+		t.Errorf("Expected no ratio before the stored size is recorded, got %v", ratio)
+	}
+
+	file.Compression = "gzip"
+	file.StoredSize = 250
+	if ratio := file.CompressionRatio(); ratio != 0.25 {
+		t.Errorf("Expected ratio 0.25, got %v", ratio)
+	}
+}
+
+func TestFileMetadata_InlineContentType(t *testing.T) {
+	tests := []struct {
+		fileName    string
+		uploadedAs  string
+		contentType string
+		ok          bool
+	}{
+		{"report.pdf", "application/pdf", "application/pdf", true},
+		{"photo.png", "image/png", "image/png", true},
+		// Files uploaded before content types were recorded go by their name
+		{"report.pdf", "", "application/pdf", true},
+		// Content that isn't what the name says is never shown
+		{"report.pdf", "text/html; charset=utf-8", "", false},
+		{"photo.png", "image/jpeg", "", false},
+		{"notes.txt", "text/plain", "", false},
+	}
+
+	for _, test := range tests {
+		file := &FileMetadata{FileName: test.fileName, ContentType: test.uploadedAs}
+		contentType, ok := file.InlineContentType()
+		if contentType != test.contentType || ok != test.ok {
+			t.Errorf("InlineContentType(%s as %q) = %q, %v, expected %q, %v", test.fileName, test.uploadedAs, contentType, ok, test.contentType, test.ok)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// HealthCheckStatus represents the outcome of a single bucket health check
+type HealthCheckStatus string
+
+const (
+	HealthCheckPass HealthCheckStatus = "pass"
+	HealthCheckFail HealthCheckStatus = "fail"
+)
+
+// HealthCheck is the result of one bucket configuration check
+type HealthCheck struct {
+	Name        string            `json:"name"`
+	Status      HealthCheckStatus `json:"status"`
+	Message     string            `json:"message"`
+	Remediation string            `json:"remediation,omitempty"` // only set when the check fails
+}
+
+// Passed reports whether the check passed
+func (c HealthCheck) Passed() bool {
+	return c.Status == HealthCheckPass
+}
+
+// HealthReport contains the results of a bucket configuration health check
+type HealthReport struct {
+	Bucket    string        `json:"bucket"`
+	Region    string        `json:"region"`
+	Identity  string        `json:"identity"` // ARN of the IAM identity the checks ran as
+	Checks    []HealthCheck `json:"checks"`
+	CheckedAt time.Time     `json:"checked_at"`
+}
+
+// Healthy reports whether every check passed
+func (r *HealthReport) Healthy() bool {
+	return len(r.FailedChecks()) == 0
+}
+
+// FailedChecks returns the checks that did not pass
+func (r *HealthReport) FailedChecks() []HealthCheck {
+	var failed []HealthCheck
+	for _, check := range r.Checks {
+		if !check.Passed() {
+			failed = append(failed, check)
+		}
+	}
+	return failed
+}
+
+// String formats the report for display, one line per check with remediation hints for failures
+func (r *HealthReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Bucket %s (%s)", r.Bucket, r.Region)
+	if r.Identity != "" {
+		fmt.Fprintf(&b, " checked as %s", r.Identity)
+	}
+	b.WriteString("\n")
+
+	for _, check := range r.Checks {
+		fmt.Fprintf(&b, "[%s] %s: %s\n", strings.ToUpper(string(check.Status)), check.Name, check.Message)
+		if !check.Passed() && check.Remediation != "" {
+			fmt.Fprintf(&b, "       Fix: %s\n", check.Remediation)
+		}
+	}
+
+	fmt.Fprintf(&b, "%d of %d checks passed", len(r.Checks)-len(r.FailedChecks()), len(r.Checks))
+	return b.String()
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthReport(t *testing.T) {
+	report := &HealthReport{
+		Bucket:   "test-bucket",
+		Region:   "us-west-2",
+		Identity: "arn:aws:iam::123456789012:user/file-app-user",
+		Checks: []HealthCheck{
+			{Name: "Default encryption", Status: HealthCheckPass, Message: "Objects are encrypted with AES256 by default"},
+			{Name: "Public access block", Status: HealthCheckFail, Message: "Public access is not fully blocked", Remediation: "Turn on \"Block all public access\""},
+		},
+	}
+
+	assert.False(t, report.Healthy())
+	failed := report.FailedChecks()
+	assert.Len(t, failed, 1)
+	assert.Equal(t, "Public access block", failed[0].Name)
+
+	text := report.String()
+	assert.Contains(t, text, "Bucket test-bucket (us-west-2) checked as arn:aws:iam::123456789012:user/file-app-user")
+	assert.Contains(t, text, "[PASS] Default encryption: Objects are encrypted with AES256 by default")
+	assert.Contains(t, text, "[FAIL] Public access block: Public access is not fully blocked")
+	assert.Contains(t, text, "Fix: Turn on \"Block all public access\"")
+	assert.Contains(t, text, "1 of 2 checks passed")
+
+	report.Checks[1].Status = HealthCheckPass
+	assert.True(t, report.Healthy())
+	assert.Empty(t, report.FailedChecks())
+}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// NotificationEvent identifies what a desktop notification is about
+type NotificationEvent string
+
+const (
+	NotifyUploadComplete NotificationEvent = "upload_complete"
+	NotifyUploadFailed   NotificationEvent = "upload_failed"
+	NotifyFileExpired    NotificationEvent = "file_expired"
+	NotifyExpiringSoon   NotificationEvent = "expiring_soon"
+	NotifyMissingFiles   NotificationEvent = "missing_files"
+)
+
+// NotificationEvents lists the events that can be notified, in the order they are shown in settings
+var NotificationEvents = []NotificationEvent{
+	NotifyUploadComplete,
+	NotifyUploadFailed,
+	NotifyFileExpired,
+	NotifyExpiringSoon,
+	NotifyMissingFiles,
+}
+
+// Description returns a short label for the event, as shown in settings
+func (e NotificationEvent) Description() string {
+	switch e {
+	case NotifyUploadComplete:
+		return "Upload completed"
+	case NotifyUploadFailed:
+		return "Upload failed"
+	case NotifyFileExpired:
+		return "File expired"
+	case NotifyExpiringSoon:
+		return "File about to expire"
+	case NotifyMissingFiles:
+		return "Files missing from S3"
+	default:
+		return string(e)
+	}
+}
+
+// Notification is a desktop notification sent by the app, kept as history
+type Notification struct {
+	ID        int64             `json:"id"`
+	Event     NotificationEvent `json:"event"`
+	FileID    string            `json:"file_id,omitempty"` // the file it is about, if any
+	Title     string            `json:"title"`
+	Content   string            `json:"content"`
+	CreatedAt time.Time         `json:"created_at"`
+}
@@ -0,0 +1,27 @@
+package models
+
+// OutboxOperation identifies an operation queued while offline
+type OutboxOperation string
+
+const (
+	OutboxUpload        OutboxOperation = "upload"
+	OutboxShare         OutboxOperation = "share"
+	OutboxSetExpiration OutboxOperation = "set_expiration"
+	OutboxDelete        OutboxOperation = "delete"
+)
+
+// Description returns a short label for showing the operation in the file list
+func (op OutboxOperation) Description() string {
+	switch op {
+	case OutboxUpload:
+		return "Upload pending"
+	case OutboxShare:
+		return "Share pending"
+	case OutboxSetExpiration:
+		return "Expiry change pending"
+	case OutboxDelete:
+		return "Delete pending"
+	default:
+		return string(op) + " pending"
+	}
+}
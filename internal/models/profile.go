@@ -0,0 +1,152 @@
+package models
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultProfileName is the profile used when no other profile has been created
+const DefaultProfileName = "default"
+
+// profileNamePattern restricts profile names to characters that are safe in keyring item names
+var profileNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,32}$`)
+
+// Profile represents a named AWS account/bucket combination the user can switch between
+type Profile struct {
+	Name              string `json:"name"`
+	AWSRegion         string `json:"aws_region"`
+	S3Bucket          string `json:"s3_bucket"`
+	DefaultExpiration string `json:"default_expiration"` // "1h", "1d", "1w", "1m"
+	MaxFileSize       int64  `json:"max_file_size"`      // in bytes
+	EncryptionMode    string `json:"encryption_mode"`    // "SSE-S3", "SSE-KMS", "SSE-C"
+	KMSKeyID          string `json:"kms_key_id,omitempty"`
+
+	// CloudFormation stack that provisioned the bucket, if it was created from the app
+	StackName string `json:"stack_name,omitempty"`
+
+	// Base address of the short share link redirect function; empty shares presigned links instead
+	ShareLinkURL string `json:"share_link_url,omitempty"`
+
+	// Internal tracking
+	LastUpdated time.Time `json:"last_updated"`
+}
+
+// NewProfile creates a profile with the given name and default settings
+func NewProfile(name string) *Profile {
+	defaults := DefaultApplicationSettings()
+	return &Profile{
+		Name:              name,
+		AWSRegion:         defaults.AWSRegion,
+		S3Bucket:          defaults.S3Bucket,
+		DefaultExpiration: defaults.DefaultExpiration,
+		MaxFileSize:       defaults.MaxFileSize,
+		EncryptionMode:    defaults.EncryptionMode,
+		LastUpdated:       time.Now(),
+	}
+}
+
+// ProfileFromSettings creates a profile from the AWS and default settings in ApplicationSettings
+func ProfileFromSettings(name string, settings *ApplicationSettings) *Profile {
+	profile := &Profile{Name: name}
+	profile.UpdateFrom(settings)
+	return profile
+}
+
+// UpdateFrom copies the AWS and default settings into the profile, keeping what settings don't hold,
+// such as the profile's stack and short link address
+func (p *Profile) UpdateFrom(settings *ApplicationSettings) {
+	p.AWSRegion = settings.AWSRegion
+	p.S3Bucket = settings.S3Bucket
+	p.DefaultExpiration = settings.DefaultExpiration
+	p.MaxFileSize = settings.MaxFileSize
+	p.EncryptionMode = settings.GetEncryptionMode()
+	p.KMSKeyID = settings.KMSKeyID
+	p.LastUpdated = time.Now()
+}
+
+// ApplyTo copies the profile's AWS and default settings into ApplicationSettings
+func (p *Profile) ApplyTo(settings *ApplicationSettings) {
+	settings.AWSRegion = p.AWSRegion
+	settings.S3Bucket = p.S3Bucket
+	settings.DefaultExpiration = p.DefaultExpiration
+	settings.MaxFileSize = p.MaxFileSize
+	settings.EncryptionMode = p.GetEncryptionMode()
+	settings.KMSKeyID = p.KMSKeyID
+}
+
+// GetEncryptionMode returns the encryption mode, treating profiles saved before it existed as SSE-S3
+func (p *Profile) GetEncryptionMode() string {
+	if p.EncryptionMode == "" {
+		return EncryptionSSES3
+	}
+	return p.EncryptionMode
+}
+
+// Validate checks if the profile is valid for saving
+func (p *Profile) Validate() error {
+	if p == nil {
+		return &ValidationError{Field: "profile", Message: "profile cannot be nil"}
+	}
+
+	if !IsValidProfileName(p.Name) {
+		return &ValidationError{Field: "name", Message: "Profile name must be 1-32 letters, digits, '-' or '_'"}
+	}
+
+	if err := ValidateShareLinkURL(p.ShareLinkURL); err != nil {
+		return err
+	}
+
+	// Reuse the settings validation rules for the shared fields
+	settings := DefaultApplicationSettings()
+	p.ApplyTo(settings)
+	return settings.ValidateForSave()
+}
+
+// ValidateShareLinkURL checks that a short share link address is empty or an HTTPS URL without a query,
+// since link tokens are appended to it
+func ValidateShareLinkURL(link string) error {
+	if link == "" {
+		return nil
+	}
+
+	parsed, err := url.Parse(link)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" || parsed.RawQuery != "" || parsed.Fragment != "" {
+		return &ValidationError{Field: "share_link_url", Message: "Short link address must be an https:// URL without a query"}
+	}
+
+	return nil
+}
+
+// ShareLinkBase returns the short share link address ending in a slash, ready for a link token
+func (p *Profile) ShareLinkBase() string {
+	if p.ShareLinkURL == "" {
+		return ""
+	}
+	return strings.TrimSuffix(p.ShareLinkURL, "/") + "/"
+}
+
+// IsValidProfileName reports whether name can be used as a profile name
+func IsValidProfileName(name string) bool {
+	return profileNamePattern.MatchString(name)
+}
+
+// ProfilesToJSON converts a list of profiles to JSON string for database storage
+func ProfilesToJSON(profiles []*Profile) (string, error) {
+	data, err := json.Marshal(profiles)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ProfilesFromJSON loads a list of profiles from JSON string
+func ProfilesFromJSON(jsonStr string) ([]*Profile, error) {
+	var profiles []*Profile
+	if err := json.Unmarshal([]byte(jsonStr), &profiles); err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
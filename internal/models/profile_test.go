@@ -0,0 +1,143 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProfile(t *testing.T) {
+	profile := NewProfile("client-a")
+
+	assert.Equal(t, "client-a", profile.Name)
+	assert.Equal(t, "us-west-2", profile.AWSRegion)
+	assert.Equal(t, "", profile.S3Bucket)
+	assert.Equal(t, "1d", profile.DefaultExpiration)
+	assert.Equal(t, int64(100*1024*1024), profile.MaxFileSize)
+	assert.False(t, profile.LastUpdated.IsZero())
+}
+
+func TestProfile_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		modify      func(p *Profile)
+		expectError bool
+		field       string
+	}{
+		{
+			name:   "valid profile",
+			modify: func(p *Profile) {},
+		},
+		{
+			name:        "empty name",
+			modify:      func(p *Profile) { p.Name = "" },
+			expectError: true,
+			field:       "name",
+		},
+		{
+			name:        "name with path separator",
+			modify:      func(p *Profile) { p.Name = "client/a" },
+			expectError: true,
+			field:       "name",
+		},
+		{
+			name:        "empty bucket",
+			modify:      func(p *Profile) { p.S3Bucket = "" },
+			expectError: true,
+			field:       "s3_bucket",
+		},
+		{
+			name:        "invalid expiration",
+			modify:      func(p *Profile) { p.DefaultExpiration = "2d" },
+			expectError: true,
+			field:       "default_expiration",
+		},
+		{
+			name:        "SSE-KMS without key",
+			modify:      func(p *Profile) { p.EncryptionMode = EncryptionSSEKMS },
+			expectError: true,
+			field:       "kms_key_id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := NewProfile("client-a")
+			profile.S3Bucket = "client-a-bucket"
+			tt.modify(profile)
+
+			err := profile.Validate()
+			if tt.expectError {
+				require.Error(t, err)
+				var validationErr *ValidationError
+				require.ErrorAs(t, err, &validationErr)
+				assert.Equal(t, tt.field, validationErr.Field)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestProfile_ApplyToAndFromSettings(t *testing.T) {
+	settings := DefaultApplicationSettings()
+	settings.AWSRegion = "eu-central-1"
+	settings.S3Bucket = "shared-bucket"
+	settings.DefaultExpiration = "1w"
+	settings.EncryptionMode = EncryptionSSEKMS
+	settings.KMSKeyID = "alias/shared"
+
+	profile := ProfileFromSettings(DefaultProfileName, settings)
+	assert.Equal(t, DefaultProfileName, profile.Name)
+	assert.Equal(t, "eu-central-1", profile.AWSRegion)
+	assert.Equal(t, "shared-bucket", profile.S3Bucket)
+	assert.Equal(t, EncryptionSSEKMS, profile.EncryptionMode)
+	assert.Equal(t, "alias/shared", profile.KMSKeyID)
+
+	profile.S3Bucket = "client-b-bucket"
+	profile.ApplyTo(settings)
+	assert.Equal(t, "client-b-bucket", settings.S3Bucket)
+	assert.Equal(t, "1w", settings.DefaultExpiration)
+}
+
+func TestProfilesJSONRoundTrip(t *testing.T) {
+	profiles := []*Profile{NewProfile("a"), NewProfile("b")}
+	profiles[1].S3Bucket = "bucket-b"
+
+	jsonStr, err := ProfilesToJSON(profiles)
+	require.NoError(t, err)
+
+	loaded, err := ProfilesFromJSON(jsonStr)
+	require.NoError(t, err)
+	require.Len(t, loaded, 2)
+	assert.Equal(t, "a", loaded[0].Name)
+	assert.Equal(t, "bucket-b", loaded[1].S3Bucket)
+}
+
+func TestValidateShareLinkURL(t *testing.T) {
+	valid := []string{"", "https://abc123.lambda-url.eu-west-1.on.aws/", "https://links.example.com/s"}
+	for _, link := range valid {
+		assert.NoError(t, ValidateShareLinkURL(link), link)
+	}
+
+	invalid := []string{"http://links.example.com/", "links.example.com", "https://", "https://links.example.com/?t=", "https://links.example.com/#s"}
+	for _, link := range invalid {
+		assert.Error(t, ValidateShareLinkURL(link), link)
+	}
+
+	profile := NewProfile("client-a")
+	profile.ShareLinkURL = "http://links.example.com/"
+	assert.Error(t, profile.Validate())
+}
+
+func TestProfile_ShareLinkBase(t *testing.T) {
+	profile := NewProfile("client-a")
+	assert.Empty(t, profile.ShareLinkBase())
+
+	// Tokens are appended to the address, with or without a trailing slash
+	profile.ShareLinkURL = "https://links.example.com/s"
+	assert.Equal(t, "https://links.example.com/s/", profile.ShareLinkBase())
+	profile.ShareLinkURL = "https://abc123.lambda-url.eu-west-1.on.aws/"
+	assert.Equal(t, "https://abc123.lambda-url.eu-west-1.on.aws/", profile.ShareLinkBase())
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"regexp"
+	"time"
+)
+
+// DefaultStackName is the CloudFormation stack name used by infrastructure/scripts/deploy.sh
+const DefaultStackName = "file-sharing-app"
+
+var (
+	// Patterns mirror the parameter constraints in file-sharing-app.yaml
+	stackNamePattern   = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9-]{0,127}$`)
+	bucketNamePattern  = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,61}[a-z0-9]$`)
+	iamUserNamePattern = regexp.MustCompile(`^[a-zA-Z0-9+=,.@_-]{1,64}$`)
+)
+
+// ProvisionRequest describes the infrastructure to create for a profile
+type ProvisionRequest struct {
+	ProfileName string `json:"profile_name"`
+	StackName   string `json:"stack_name"`
+	BucketName  string `json:"bucket_name"`
+	IAMUserName string `json:"iam_user_name"`
+	Region      string `json:"region"`
+
+	// Administrator credentials used only for provisioning; never stored.
+	// When empty, the default AWS credential chain is used.
+	AdminAccessKey string `json:"-"`
+	AdminSecretKey string `json:"-"`
+
+	// Endpoint overrides the CloudFormation endpoint, e.g. for a local stand-in such as LocalStack
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// Validate checks if the request can be submitted to CloudFormation
+func (r *ProvisionRequest) Validate() error {
+	if r == nil {
+		return &ValidationError{Field: "request", Message: "provision request cannot be nil"}
+	}
+
+	if !IsValidProfileName(r.ProfileName) {
+		return &ValidationError{Field: "profile_name", Message: "Profile name must be 1-32 letters, digits, '-' or '_'"}
+	}
+
+	if !stackNamePattern.MatchString(r.StackName) {
+		return &ValidationError{Field: "stack_name", Message: "Stack name must start with a letter and contain only letters, digits and '-'"}
+	}
+
+	if !bucketNamePattern.MatchString(r.BucketName) {
+		return &ValidationError{Field: "bucket_name", Message: "Bucket name must be 3-63 lowercase letters, digits or '-'"}
+	}
+
+	if !iamUserNamePattern.MatchString(r.IAMUserName) {
+		return &ValidationError{Field: "iam_user_name", Message: "IAM user name must be 1-64 letters, digits or +=,.@_-"}
+	}
+
+	if r.Region == "" {
+		return &ValidationError{Field: "region", Message: "AWS region cannot be empty"}
+	}
+
+	if (r.AdminAccessKey == "") != (r.AdminSecretKey == "") {
+		return &ValidationError{Field: "admin_credentials", Message: "Access key ID and secret access key must be provided together"}
+	}
+
+	return nil
+}
+
+// ProvisionResult contains the stack outputs needed to configure a profile
+type ProvisionResult struct {
+	StackName       string `json:"stack_name"`
+	StackStatus     string `json:"stack_status"`
+	BucketName      string `json:"bucket_name"`
+	Region          string `json:"region"`
+	IAMUserName     string `json:"iam_user_name"`
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"-"`
+	ShareLinkURL    string `json:"share_link_url"` // base address of short share links
+}
+
+// ResourceDrift describes a stack resource whose configuration differs from the template
+type ResourceDrift struct {
+	LogicalResourceID string `json:"logical_resource_id"`
+	ResourceType      string `json:"resource_type"`
+	DriftStatus       string `json:"drift_status"` // "MODIFIED", "DELETED"
+}
+
+// StackDriftReport contains the result of a drift detection run
+type StackDriftReport struct {
+	StackName        string          `json:"stack_name"`
+	DriftStatus      string          `json:"drift_status"` // "IN_SYNC", "DRIFTED", "UNKNOWN"
+	DriftedResources []ResourceDrift `json:"drifted_resources"`
+	CheckedAt        time.Time       `json:"checked_at"`
+}
+
+// IsDrifted reports whether any stack resource has drifted
+func (r *StackDriftReport) IsDrifted() bool {
+	return r.DriftStatus == "DRIFTED" || len(r.DriftedResources) > 0
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvisionRequest_Validate(t *testing.T) {
+	validRequest := func() *ProvisionRequest {
+		return &ProvisionRequest{
+			ProfileName: "client-a",
+			StackName:   DefaultStackName,
+			BucketName:  "client-a-bucket",
+			IAMUserName: "client-a-user",
+			Region:      "eu-west-1",
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(r *ProvisionRequest)
+		field  string
+	}{
+		{name: "valid request", modify: func(r *ProvisionRequest) {}},
+		{name: "valid request with admin credentials", modify: func(r *ProvisionRequest) {
+			r.AdminAccessKey = "AKIATEST"
+			r.AdminSecretKey = "secret"
+		}},
+		{name: "invalid profile name", modify: func(r *ProvisionRequest) { r.ProfileName = "client a" }, field: "profile_name"},
+		{name: "stack name starting with digit", modify: func(r *ProvisionRequest) { r.StackName = "1stack" }, field: "stack_name"},
+		{name: "uppercase bucket name", modify: func(r *ProvisionRequest) { r.BucketName = "Client-Bucket" }, field: "bucket_name"},
+		{name: "bucket name too short", modify: func(r *ProvisionRequest) { r.BucketName = "ab" }, field: "bucket_name"},
+		{name: "bucket name ending with hyphen", modify: func(r *ProvisionRequest) { r.BucketName = "bucket-" }, field: "bucket_name"},
+		{name: "invalid IAM user name", modify: func(r *ProvisionRequest) { r.IAMUserName = "user name" }, field: "iam_user_name"},
+		{name: "empty region", modify: func(r *ProvisionRequest) { r.Region = "" }, field: "region"},
+		{name: "access key without secret", modify: func(r *ProvisionRequest) { r.AdminAccessKey = "AKIATEST" }, field: "admin_credentials"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := validRequest()
+			tt.modify(req)
+
+			err := req.Validate()
+			if tt.field == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var validationErr *ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			assert.Equal(t, tt.field, validationErr.Field)
+		})
+	}
+
+	var nilRequest *ProvisionRequest
+	assert.Error(t, nilRequest.Validate())
+}
+
+func TestStackDriftReport_IsDrifted(t *testing.T) {
+	assert.False(t, (&StackDriftReport{DriftStatus: "IN_SYNC"}).IsDrifted())
+	assert.True(t, (&StackDriftReport{DriftStatus: "DRIFTED"}).IsDrifted())
+	assert.True(t, (&StackDriftReport{
+		DriftStatus:      "UNKNOWN",
+		DriftedResources: []ResourceDrift{{LogicalResourceID: "FileStorageBucket", DriftStatus: "MODIFIED"}},
+	}).IsDrifted())
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Virus scanners that can check files before they are uploaded
+const (
+	ScannerClamd   = "clamd"   // a ClamAV daemon, over its local socket or TCP
+	ScannerCommand = "command" // any command that exits 0 for clean files and 1 for infected ones
+)
+
+// VirusScanners lists the supported virus scanners
+var VirusScanners = []string{ScannerClamd, ScannerCommand}
+
+// DefaultClamdAddress is where clamd listens when no address is set, as packaged on Debian and Ubuntu
+const DefaultClamdAddress = "/var/run/clamav/clamd.ctl"
+
+// ScanFilePlaceholder is replaced by the path of the file to scan in a scan command; without it the
+// path is added to the end
+const ScanFilePlaceholder = "{file}"
+
+// Scan verdicts recorded on files; files uploaded without a scan have none
+const (
+	ScanClean    = "clean"
+	ScanInfected = "infected"
+)
+
+// ScanResult is the outcome of scanning a file for viruses and malware
+type ScanResult struct {
+	Verdict   string `json:"verdict"`             // ScanClean or ScanInfected
+	Signature string `json:"signature,omitempty"` // what was found in an infected file
+	Engine    string `json:"engine"`              // the scanner and its version, such as "ClamAV 1.0.3/27100"
+}
+
+// Infected reports whether the scan found something
+func (r *ScanResult) Infected() bool {
+	return r != nil && r.Verdict == ScanInfected
+}
+
+// InfectedFileError is returned for a file that is not uploaded because a scan found something in it
+type InfectedFileError struct {
+	FileName string
+	Result   *ScanResult
+}
+
+func (e *InfectedFileError) Error() string {
+	return fmt.Sprintf("%s is infected: %s found %s", e.FileName, e.Result.Engine, e.Result.Signature)
+}
+
+// ScanFailedError is returned for a file that is not uploaded because it couldn't be scanned,
+// such as when the scanner isn't running
+type ScanFailedError struct {
+	FileName string
+	Err      error
+}
+
+func (e *ScanFailedError) Error() string {
+	return fmt.Sprintf("%s couldn't be scanned for viruses: %v", e.FileName, e.Err)
+}
+
+func (e *ScanFailedError) Unwrap() error {
+	return e.Err
+}
+
+// IsScanFailure reports whether err is, or wraps, a ScanFailedError
+func IsScanFailure(err error) bool {
+	var scanErr *ScanFailedError
+	return errors.As(err, &scanErr)
+}
+
+// GetClamdAddress returns the socket path or host:port of the ClamAV daemon
+func (s *ApplicationSettings) GetClamdAddress() string {
+	if s.ClamdAddress == "" {
+		return DefaultClamdAddress
+	}
+	return s.ClamdAddress
+}
+
+// ValidateVirusScanner checks that a virus scanner is supported and has what it needs to run;
+// an empty scanner turns scanning off
+func ValidateVirusScanner(scanner, clamdAddress, command string) error {
+	switch scanner {
+	case "":
+		return nil
+	case ScannerClamd:
+		if clamdAddress != "" && !filepath.IsAbs(clamdAddress) && !strings.Contains(clamdAddress, ":") {
+			return &ValidationError{Field: "clamd_address", Message: "clamd address must be a socket path or host:port"}
+		}
+		return nil
+	case ScannerCommand:
+		if strings.TrimSpace(command) == "" {
+			return &ValidationError{Field: "scan_command", Message: "Scan command is required to scan with a command"}
+		}
+		return nil
+	default:
+		return &ValidationError{Field: "virus_scanner", Message: "Invalid virus scanner"}
+	}
+}
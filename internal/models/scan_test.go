@@ -0,0 +1,72 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanResult_Infected(t *testing.T) {
+	var none *ScanResult
+	assert.False(t, none.Infected())
+	assert.False(t, (&ScanResult{Verdict: ScanClean}).Infected())
+	assert.True(t, (&ScanResult{Verdict: ScanInfected}).Infected())
+}
+
+func TestInfectedFileError(t *testing.T) {
+	err := &InfectedFileError{
+		FileName: "invoice.exe",
+		Result:   &ScanResult{Verdict: ScanInfected, Signature: "Win.Trojan.Agent-1", Engine: "ClamAV 1.0.3/27100"},
+	}
+	assert.Equal(t, "invoice.exe is infected: ClamAV 1.0.3/27100 found Win.Trojan.Agent-1", err.Error())
+}
+
+func TestScanFailedError(t *testing.T) {
+	cause := fmt.Errorf("connection refused")
+	err := fmt.Errorf("upload failed: %w", &ScanFailedError{FileName: "report.pdf", Err: cause})
+
+	assert.True(t, IsScanFailure(err))
+	assert.ErrorIs(t, err, cause)
+	assert.Contains(t, err.Error(), "report.pdf couldn't be scanned for viruses: connection refused")
+	assert.False(t, IsScanFailure(cause))
+}
+
+func TestValidateVirusScanner(t *testing.T) {
+	assert.NoError(t, ValidateVirusScanner("", "", ""))
+	assert.NoError(t, ValidateVirusScanner(ScannerClamd, "", ""))
+	assert.NoError(t, ValidateVirusScanner(ScannerClamd, "/run/clamav/clamd.sock", ""))
+	assert.NoError(t, ValidateVirusScanner(ScannerClamd, "localhost:3310", ""))
+	assert.NoError(t, ValidateVirusScanner(ScannerCommand, "", "clamscan --no-summary {file}"))
+
+	tests := []struct {
+		scanner, address, command string
+		field                     string
+	}{
+		{"norton", "", "", "virus_scanner"},
+		{ScannerClamd, "clamd.sock", "", "clamd_address"},
+		{ScannerCommand, "", "  ", "scan_command"},
+	}
+	for _, tt := range tests {
+		err := ValidateVirusScanner(tt.scanner, tt.address, tt.command)
+		require.Error(t, err, tt.scanner)
+		assert.Equal(t, tt.field, err.(*ValidationError).Field)
+	}
+}
+
+func TestApplicationSettings_VirusScanner(t *testing.T) {
+	settings := DefaultApplicationSettings()
+	assert.Empty(t, settings.VirusScanner, "scanning is off by default")
+	assert.Equal(t, DefaultClamdAddress, settings.GetClamdAddress())
+
+	settings.VirusScanner = ScannerClamd
+	settings.ClamdAddress = "localhost:3310"
+	assert.NoError(t, settings.Validate())
+	assert.Equal(t, "localhost:3310", settings.GetClamdAddress())
+
+	settings.VirusScanner = ScannerCommand
+	err := settings.Validate()
+	require.Error(t, err)
+	assert.Equal(t, "scan_command", err.(*ValidationError).Field)
+}
@@ -2,9 +2,52 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
 )
 
+// Server-side encryption modes for uploaded objects
+const (
+	EncryptionSSES3  = "SSE-S3"  // S3-managed keys (AES256)
+	EncryptionSSEKMS = "SSE-KMS" // customer-managed AWS KMS key
+	EncryptionSSEC   = "SSE-C"   // customer-provided key, never stored by AWS
+)
+
+// EncryptionModes lists the supported encryption modes
+var EncryptionModes = []string{EncryptionSSES3, EncryptionSSEKMS, EncryptionSSEC}
+
+// Compression algorithms for uploads, named as in the Content-Encoding header
+const (
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// CompressionAlgorithms lists the supported compression algorithms
+var CompressionAlgorithms = []string{CompressionGzip, CompressionZstd}
+
+// DefaultCompressionMinSavingsPercent is how much smaller compression must make a file for it to be stored compressed
+const DefaultCompressionMinSavingsPercent = 10
+
+// MaxCompressionMinSavingsPercent is the largest savings threshold that can be set
+const MaxCompressionMinSavingsPercent = 90
+
+// Default background job intervals, in minutes
+const (
+	DefaultSyncIntervalMinutes            = 15
+	DefaultExpirationCheckIntervalMinutes = 5
+	DefaultMetadataCleanupIntervalMinutes = 24 * 60
+	DefaultURLRenewalIntervalMinutes      = 60
+)
+
+// MaxJobIntervalMinutes is the longest allowed background job interval (one week)
+const MaxJobIntervalMinutes = 7 * 24 * 60
+
+// DefaultExpiryWarningHours is how long before a file expires to warn about it
+const DefaultExpiryWarningHours = 24
+
+// MaxExpiryWarningHours is the longest allowed expiry warning (30 days)
+const MaxExpiryWarningHours = 30 * 24
+
 // ApplicationSettings represents user preferences stored locally
 type ApplicationSettings struct {
 	// AWS Configuration
@@ -15,12 +58,42 @@ type ApplicationSettings struct {
 	DefaultExpiration string `json:"default_expiration"` // "1h", "1d", "1w", "1m"
 	MaxFileSize       int64  `json:"max_file_size"`      // in bytes
 	
+	// Encryption Settings
+	EncryptionMode string `json:"encryption_mode"`      // "SSE-S3", "SSE-KMS", "SSE-C"
+	KMSKeyID       string `json:"kms_key_id,omitempty"` // key ID, ARN or alias, for SSE-KMS
+	
+	// Content types uploads are stored with, by lower-case extension such as ".md", instead of the detected ones
+	ContentTypeOverrides map[string]string `json:"content_type_overrides,omitempty"`
+	
+	// Upload compression; files are only stored compressed when it saves enough
+	UploadCompression            string `json:"upload_compression,omitempty"`      // "gzip" or "zstd"; empty turns compression off
+	CompressionMinSavingsPercent int    `json:"compression_min_savings_percent"` // zero means the default
+	
+	// Upload speed cap shared by all uploads, in KB/s; zero means unlimited
+	UploadBandwidthLimitKBps int `json:"upload_bandwidth_limit_kbps,omitempty"`
+	
+	// Virus scanning before upload; files the scanner finds something in are not uploaded
+	VirusScanner string `json:"virus_scanner,omitempty"` // "clamd" or "command"; empty turns scanning off
+	ClamdAddress string `json:"clamd_address,omitempty"` // clamd's socket path or host:port; empty means the default socket
+	ScanCommand  string `json:"scan_command,omitempty"`  // command run on each file, with {file} standing for its path
+	
 	// UI Settings
 	UITheme           string `json:"ui_theme"`           // "light", "dark", "auto"
 	
 	// Application Settings
 	AutoRefresh       bool   `json:"auto_refresh"`       // auto refresh file list
 	ShowNotifications bool   `json:"show_notifications"` // show system notifications
+	MinimizeToTray    bool   `json:"minimize_to_tray"`   // closing the window keeps the app running in the system tray
+	
+	// Notification Settings; ShowNotifications turns them all off
+	DisabledNotifications []NotificationEvent `json:"disabled_notifications,omitempty"` // events not notified
+	ExpiryWarningHours    int                 `json:"expiry_warning_hours"`             // warn this long before expiry; zero means the default
+	
+	// Background job intervals in minutes; zero means the default
+	SyncIntervalMinutes            int `json:"sync_interval_minutes"`             // S3 sync, when AutoRefresh is on
+	ExpirationCheckIntervalMinutes int `json:"expiration_check_interval_minutes"` // marking expired files
+	MetadataCleanupIntervalMinutes int `json:"metadata_cleanup_interval_minutes"` // removing long-expired records
+	URLRenewalIntervalMinutes      int `json:"url_renewal_interval_minutes"`      // renewing share links
 	
 	// Internal tracking
 	LastUpdated       time.Time `json:"last_updated"`
@@ -33,9 +106,17 @@ func DefaultApplicationSettings() *ApplicationSettings {
 		S3Bucket:          "",
 		DefaultExpiration: "1d",
 		MaxFileSize:       100 * 1024 * 1024, // 100MB
+		EncryptionMode:    EncryptionSSES3,
 		UITheme:           "auto",
 		AutoRefresh:       true,
 		ShowNotifications: true,
+		MinimizeToTray:    true,
+		SyncIntervalMinutes:            DefaultSyncIntervalMinutes,
+		ExpirationCheckIntervalMinutes: DefaultExpirationCheckIntervalMinutes,
+		MetadataCleanupIntervalMinutes: DefaultMetadataCleanupIntervalMinutes,
+		URLRenewalIntervalMinutes:      DefaultURLRenewalIntervalMinutes,
+		ExpiryWarningHours:             DefaultExpiryWarningHours,
+		CompressionMinSavingsPercent:   DefaultCompressionMinSavingsPercent,
 		LastUpdated:       time.Now(),
 	}
 }
@@ -70,6 +151,125 @@ func (s *ApplicationSettings) GetExpirationDuration() time.Duration {
 	}
 }
 
+// GetEncryptionMode returns the encryption mode, treating settings saved before it existed as SSE-S3
+func (s *ApplicationSettings) GetEncryptionMode() string {
+	if s.EncryptionMode == "" {
+		return EncryptionSSES3
+	}
+	return s.EncryptionMode
+}
+
+// GetCompressionMinSavings returns how much smaller, in percent, compression must make a file for it to be stored compressed
+func (s *ApplicationSettings) GetCompressionMinSavings() int {
+	if s.CompressionMinSavingsPercent <= 0 {
+		return DefaultCompressionMinSavingsPercent
+	}
+	return s.CompressionMinSavingsPercent
+}
+
+// ValidateCompression checks that an upload compression algorithm is supported and its threshold is in range;
+// an empty algorithm turns compression off, and a zero threshold means the default
+func ValidateCompression(algorithm string, minSavingsPercent int) error {
+	switch algorithm {
+	case "", CompressionGzip, CompressionZstd:
+	default:
+		return &ValidationError{Field: "upload_compression", Message: "Invalid compression algorithm"}
+	}
+	if minSavingsPercent < 0 || minSavingsPercent > MaxCompressionMinSavingsPercent {
+		return &ValidationError{Field: "compression_min_savings_percent", Message: fmt.Sprintf("Compression savings threshold must be between 1 and %d percent", MaxCompressionMinSavingsPercent)}
+	}
+	return nil
+}
+
+// GetSyncInterval returns how often to sync with S3 when AutoRefresh is on
+func (s *ApplicationSettings) GetSyncInterval() time.Duration {
+	return intervalOrDefault(s.SyncIntervalMinutes, DefaultSyncIntervalMinutes)
+}
+
+// GetExpirationCheckInterval returns how often to mark expired files
+func (s *ApplicationSettings) GetExpirationCheckInterval() time.Duration {
+	return intervalOrDefault(s.ExpirationCheckIntervalMinutes, DefaultExpirationCheckIntervalMinutes)
+}
+
+// GetMetadataCleanupInterval returns how often to remove records of long-expired files
+func (s *ApplicationSettings) GetMetadataCleanupInterval() time.Duration {
+	return intervalOrDefault(s.MetadataCleanupIntervalMinutes, DefaultMetadataCleanupIntervalMinutes)
+}
+
+// GetURLRenewalInterval returns how often to renew share links that are about to expire
+func (s *ApplicationSettings) GetURLRenewalInterval() time.Duration {
+	return intervalOrDefault(s.URLRenewalIntervalMinutes, DefaultURLRenewalIntervalMinutes)
+}
+
+// intervalOrDefault converts an interval in minutes to a duration, treating settings saved before it existed as the default
+func intervalOrDefault(minutes, defaultMinutes int) time.Duration {
+	if minutes <= 0 {
+		minutes = defaultMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// ValidateJobInterval checks that a background job interval is within range; zero means the default
+func ValidateJobInterval(field string, minutes int) error {
+	if minutes < 0 || minutes > MaxJobIntervalMinutes {
+		return &ValidationError{Field: field, Message: fmt.Sprintf("Interval must be between 1 and %d minutes", MaxJobIntervalMinutes)}
+	}
+	return nil
+}
+
+// NotificationEnabled reports whether desktop notifications are sent for an event
+func (s *ApplicationSettings) NotificationEnabled(event NotificationEvent) bool {
+	return s.ShowNotifications && !s.NotificationDisabled(event)
+}
+
+// NotificationDisabled reports whether notifications for an event were turned off, regardless of ShowNotifications
+func (s *ApplicationSettings) NotificationDisabled(event NotificationEvent) bool {
+	for _, disabled := range s.DisabledNotifications {
+		if disabled == event {
+			return true
+		}
+	}
+	return false
+}
+
+// SetNotificationEnabled turns notifications for a single event on or off
+func (s *ApplicationSettings) SetNotificationEnabled(event NotificationEvent, enabled bool) {
+	var disabled []NotificationEvent
+	for _, existing := range s.DisabledNotifications {
+		if existing != event {
+			disabled = append(disabled, existing)
+		}
+	}
+	if !enabled {
+		disabled = append(disabled, event)
+	}
+	s.DisabledNotifications = disabled
+}
+
+// GetExpiryWarningWindow returns how long before a file expires to warn about it
+func (s *ApplicationSettings) GetExpiryWarningWindow() time.Duration {
+	hours := s.ExpiryWarningHours
+	if hours <= 0 {
+		hours = DefaultExpiryWarningHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// ValidateEncryption checks that mode is supported and that SSE-KMS has a key
+func ValidateEncryption(mode, kmsKeyID string) error {
+	switch mode {
+	case "", EncryptionSSES3, EncryptionSSEC:
+		return nil
+	case EncryptionSSEKMS:
+		if kmsKeyID == "" {
+			return &ValidationError{Field: "kms_key_id", Message: "KMS key ID is required for SSE-KMS encryption"}
+		}
+		return nil
+	default:
+		return &ValidationError{Field: "encryption_mode", Message: "Invalid encryption mode"}
+	}
+}
+
 // Validate checks if the settings are valid
 func (s *ApplicationSettings) Validate() error {
 	// Validate AWS region format (basic check)
@@ -98,6 +298,54 @@ func (s *ApplicationSettings) Validate() error {
 		return &ValidationError{Field: "ui_theme", Message: "Invalid UI theme"}
 	}
 	
+	// Validate encryption
+	if err := ValidateEncryption(s.EncryptionMode, s.KMSKeyID); err != nil {
+		return err
+	}
+	
+	// Validate compression
+	if err := ValidateCompression(s.UploadCompression, s.CompressionMinSavingsPercent); err != nil {
+		return err
+	}
+	
+	// Validate upload bandwidth limit
+	if err := ValidateBandwidthLimit("upload_bandwidth_limit_kbps", s.UploadBandwidthLimitKBps); err != nil {
+		return err
+	}
+	
+	// Validate virus scanning
+	if err := ValidateVirusScanner(s.VirusScanner, s.ClamdAddress, s.ScanCommand); err != nil {
+		return err
+	}
+	
+	// Validate content type overrides
+	for ext, contentType := range s.ContentTypeOverrides {
+		if err := ValidateContentTypeOverride(ext, contentType); err != nil {
+			return &ValidationError{Field: "content_type_overrides", Message: err.Error()}
+		}
+	}
+	
+	// Validate expiry warning
+	if s.ExpiryWarningHours < 0 || s.ExpiryWarningHours > MaxExpiryWarningHours {
+		return &ValidationError{Field: "expiry_warning_hours", Message: fmt.Sprintf("Expiry warning must be between 1 and %d hours", MaxExpiryWarningHours)}
+	}
+	
+	// Validate background job intervals
+	intervals := []struct {
+		field   string
+		minutes int
+	}{
+		{"sync_interval_minutes", s.SyncIntervalMinutes},
+		{"expiration_check_interval_minutes", s.ExpirationCheckIntervalMinutes},
+		{"metadata_cleanup_interval_minutes", s.MetadataCleanupIntervalMinutes},
+		{"url_renewal_interval_minutes", s.URLRenewalIntervalMinutes},
+	}
+	for _, interval := range intervals {
+		if err := ValidateJobInterval(interval.field, interval.minutes); err != nil {
+			return err
+		}
+	}
+	
 	return nil
 }
 
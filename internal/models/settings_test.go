@@ -19,6 +19,7 @@ func TestDefaultApplicationSettings(t *testing.T) {
 	assert.Equal(t, "auto", settings.UITheme)
 	assert.True(t, settings.AutoRefresh)
 	assert.True(t, settings.ShowNotifications)
+	assert.True(t, settings.MinimizeToTray)
 	assert.False(t, settings.LastUpdated.IsZero())
 }
 
@@ -124,6 +125,30 @@ func TestApplicationSettings_Validate(t *testing.T) {
 			},
 			expectError: false, // Basic validation allows empty S3 bucket
 		},
+		{
+			name: "negative sync interval",
+			settings: &ApplicationSettings{
+				AWSRegion:           "us-west-2",
+				DefaultExpiration:   "1d",
+				MaxFileSize:         100 * 1024 * 1024,
+				UITheme:             "light",
+				SyncIntervalMinutes: -1,
+			},
+			expectError: true,
+			errorField:  "sync_interval_minutes",
+		},
+		{
+			name: "URL renewal interval over a week",
+			settings: &ApplicationSettings{
+				AWSRegion:                 "us-west-2",
+				DefaultExpiration:         "1d",
+				MaxFileSize:               100 * 1024 * 1024,
+				UITheme:                   "light",
+				URLRenewalIntervalMinutes: MaxJobIntervalMinutes + 1,
+			},
+			expectError: true,
+			errorField:  "url_renewal_interval_minutes",
+		},
 		{
 			name: "invalid expiration format",
 			settings: &ApplicationSettings{
@@ -192,6 +217,174 @@ func TestApplicationSettings_Validate(t *testing.T) {
 	}
 }
 
+func TestValidateEncryption(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       string
+		kmsKeyID   string
+		errorField string
+	}{
+		{name: "unset defaults to SSE-S3", mode: ""},
+		{name: "SSE-S3", mode: EncryptionSSES3},
+		{name: "SSE-C", mode: EncryptionSSEC},
+		{name: "SSE-KMS with key", mode: EncryptionSSEKMS, kmsKeyID: "alias/file-sharing"},
+		{name: "SSE-KMS without key", mode: EncryptionSSEKMS, errorField: "kms_key_id"},
+		{name: "unknown mode", mode: "AES128", errorField: "encryption_mode"},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateEncryption(tt.mode, tt.kmsKeyID)
+			if tt.errorField == "" {
+				assert.NoError(t, err)
+				return
+			}
+			validationErr, ok := err.(*ValidationError)
+			require.True(t, ok, "Expected ValidationError")
+			assert.Equal(t, tt.errorField, validationErr.Field)
+		})
+	}
+}
+
+func TestApplicationSettings_GetEncryptionMode(t *testing.T) {
+	settings := &ApplicationSettings{}
+	assert.Equal(t, EncryptionSSES3, settings.GetEncryptionMode())
+	
+	settings.EncryptionMode = EncryptionSSEC
+	assert.Equal(t, EncryptionSSEC, settings.GetEncryptionMode())
+}
+
+func TestApplicationSettings_JobIntervals(t *testing.T) {
+	// Settings saved before the intervals existed use the defaults
+	settings := &ApplicationSettings{}
+	assert.Equal(t, 15*time.Minute, settings.GetSyncInterval())
+	assert.Equal(t, 5*time.Minute, settings.GetExpirationCheckInterval())
+	assert.Equal(t, 24*time.Hour, settings.GetMetadataCleanupInterval())
+	assert.Equal(t, time.Hour, settings.GetURLRenewalInterval())
+	
+	settings.SyncIntervalMinutes = 30
+	settings.ExpirationCheckIntervalMinutes = 1
+	settings.MetadataCleanupIntervalMinutes = 120
+	settings.URLRenewalIntervalMinutes = 10
+	assert.Equal(t, 30*time.Minute, settings.GetSyncInterval())
+	assert.Equal(t, time.Minute, settings.GetExpirationCheckInterval())
+	assert.Equal(t, 2*time.Hour, settings.GetMetadataCleanupInterval())
+	assert.Equal(t, 10*time.Minute, settings.GetURLRenewalInterval())
+	
+	defaults := DefaultApplicationSettings()
+	assert.Equal(t, DefaultSyncIntervalMinutes, defaults.SyncIntervalMinutes)
+	assert.Equal(t, DefaultURLRenewalIntervalMinutes, defaults.URLRenewalIntervalMinutes)
+}
+
+func TestApplicationSettings_Notifications(t *testing.T) {
+	settings := DefaultApplicationSettings()
+	for _, event := range NotificationEvents {
+		assert.True(t, settings.NotificationEnabled(event), event)
+	}
+	
+	settings.SetNotificationEnabled(NotifyUploadComplete, false)
+	settings.SetNotificationEnabled(NotifyUploadComplete, false)
+	assert.Equal(t, []NotificationEvent{NotifyUploadComplete}, settings.DisabledNotifications)
+	assert.False(t, settings.NotificationEnabled(NotifyUploadComplete))
+	assert.True(t, settings.NotificationEnabled(NotifyUploadFailed))
+	
+	settings.SetNotificationEnabled(NotifyUploadComplete, true)
+	assert.Empty(t, settings.DisabledNotifications)
+	assert.True(t, settings.NotificationEnabled(NotifyUploadComplete))
+	
+	// ShowNotifications turns them all off
+	settings.ShowNotifications = false
+	assert.False(t, settings.NotificationEnabled(NotifyUploadFailed))
+	assert.False(t, settings.NotificationDisabled(NotifyUploadFailed))
+	
+	// Settings saved before the expiry warning existed use the default
+	settings.ExpiryWarningHours = 0
+	assert.Equal(t, 24*time.Hour, settings.GetExpiryWarningWindow())
+	settings.ExpiryWarningHours = 2
+	assert.Equal(t, 2*time.Hour, settings.GetExpiryWarningWindow())
+	
+	settings.ExpiryWarningHours = MaxExpiryWarningHours + 1
+	err := settings.Validate()
+	require.Error(t, err)
+	assert.Equal(t, "expiry_warning_hours", err.(*ValidationError).Field)
+}
+
+func TestApplicationSettings_ContentTypeOverrides(t *testing.T) {
+	settings := DefaultApplicationSettings()
+	settings.ContentTypeOverrides = map[string]string{".md": "text/markdown"}
+	assert.NoError(t, settings.Validate())
+	
+	// Overrides survive saving and loading
+	jsonStr, err := settings.ToJSON()
+	require.NoError(t, err)
+	loaded := &ApplicationSettings{}
+	require.NoError(t, loaded.FromJSON(jsonStr))
+	assert.Equal(t, settings.ContentTypeOverrides, loaded.ContentTypeOverrides)
+	
+	settings.ContentTypeOverrides[".txt"] = "plain"
+	err = settings.Validate()
+	require.Error(t, err)
+	assert.Equal(t, "content_type_overrides", err.(*ValidationError).Field)
+}
+
+func TestValidateCompression(t *testing.T) {
+	tests := []struct {
+		name       string
+		algorithm  string
+		minSavings int
+		errorField string
+	}{
+		{name: "off", algorithm: ""},
+		{name: "gzip", algorithm: CompressionGzip, minSavings: 10},
+		{name: "zstd with default threshold", algorithm: CompressionZstd},
+		{name: "unknown algorithm", algorithm: "brotli", errorField: "upload_compression"},
+		{name: "negative threshold", algorithm: CompressionGzip, minSavings: -1, errorField: "compression_min_savings_percent"},
+		{name: "threshold too high", algorithm: CompressionGzip, minSavings: MaxCompressionMinSavingsPercent + 1, errorField: "compression_min_savings_percent"},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCompression(tt.algorithm, tt.minSavings)
+			if tt.errorField == "" {
+				assert.NoError(t, err)
+				return
+			}
+			validationErr, ok := err.(*ValidationError)
+			require.True(t, ok, "Expected ValidationError")
+			assert.Equal(t, tt.errorField, validationErr.Field)
+		})
+	}
+}
+
+func TestApplicationSettings_Compression(t *testing.T) {
+	settings := DefaultApplicationSettings()
+	assert.Empty(t, settings.UploadCompression)
+	assert.Equal(t, DefaultCompressionMinSavingsPercent, settings.GetCompressionMinSavings())
+	
+	settings.UploadCompression = CompressionZstd
+	settings.CompressionMinSavingsPercent = 25
+	assert.NoError(t, settings.Validate())
+	assert.Equal(t, 25, settings.GetCompressionMinSavings())
+	
+	// Settings saved before compression existed use the default threshold
+	loaded := &ApplicationSettings{}
+	require.NoError(t, loaded.FromJSON(`{"s3_bucket":"bucket"}`))
+	assert.Empty(t, loaded.UploadCompression)
+	assert.Equal(t, DefaultCompressionMinSavingsPercent, loaded.GetCompressionMinSavings())
+	
+	settings.UploadCompression = "lz4"
+	err := settings.Validate()
+	require.Error(t, err)
+	assert.Equal(t, "upload_compression", err.(*ValidationError).Field)
+}
+
+func TestNotificationEvent_Description(t *testing.T) {
+	for _, event := range NotificationEvents {
+		assert.NotEqual(t, string(event), event.Description(), event)
+	}
+	assert.Equal(t, "custom", NotificationEvent("custom").Description())
+}
+
 func TestValidationError_Error(t *testing.T) {
 	err := &ValidationError{
 		Field:   "test_field",
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ShareEmail is a ready-to-send message telling recipients about a shared file
+type ShareEmail struct {
+	Recipients []string `json:"recipients"`
+	Subject    string   `json:"subject"`
+	Body       string   `json:"body"`
+}
+
+// NewShareEmail composes the email for a share. When the file has a checksum the body
+// includes it, with the commands recipients can use to verify their download, and when
+// it was scanned for viruses the body says what scanned it.
+func NewShareEmail(file *FileMetadata, share *ShareRecord) *ShareEmail {
+	var body strings.Builder
+
+	fmt.Fprintf(&body, "Hi,\n\nI've shared %q with you.\n\n", file.FileName)
+
+	if message := strings.TrimSpace(share.Message); message != "" {
+		fmt.Fprintf(&body, "%s\n\n", message)
+	}
+
+	fmt.Fprintf(&body, "Download: %s\n", share.PresignedURL)
+	fmt.Fprintf(&body, "The link expires on %s.\n", share.URLExpiration.Local().Format(time.RFC1123))
+
+	switch share.MaxDownloads {
+	case 0:
+	case 1:
+		body.WriteString("Please download it only once.\n")
+	default:
+		fmt.Fprintf(&body, "Please download it no more than %d times.\n", share.MaxDownloads)
+	}
+
+	if file.ScanVerdict == ScanClean {
+		fmt.Fprintf(&body, "It was scanned for viruses and malware with %s, which found nothing.\n", file.ScanEngine)
+	}
+
+	if file.Checksum != "" {
+		// The commands name the file the download is saved as
+		fileName := file.FileName
+		if share.DownloadName != "" {
+			fileName = share.DownloadName
+		}
+
+		fmt.Fprintf(&body, "\nSHA-256: %s\n", file.Checksum)
+		body.WriteString("To check the download is intact, compare this with the output of:\n")
+		fmt.Fprintf(&body, "  Linux:   sha256sum %q\n", fileName)
+		fmt.Fprintf(&body, "  macOS:   shasum -a 256 %q\n", fileName)
+		fmt.Fprintf(&body, "  Windows: certutil -hashfile %q SHA256\n", fileName)
+	}
+
+	return &ShareEmail{
+		Recipients: share.Recipients,
+		Subject:    fmt.Sprintf("File shared with you: %s", file.FileName),
+		Body:       body.String(),
+	}
+}
+
+// MailtoURL returns a mailto: link that opens the email in the default mail client
+func (e *ShareEmail) MailtoURL() string {
+	query := url.Values{}
+	query.Set("subject", e.Subject)
+	query.Set("body", e.Body)
+
+	// Mail clients expect %20 rather than + for spaces
+	return "mailto:" + strings.Join(e.Recipients, ",") + "?" + strings.ReplaceAll(query.Encode(), "+", "%20")
+}
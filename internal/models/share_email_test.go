@@ -0,0 +1,106 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewShareEmail(t *testing.T) {
+	file := &FileMetadata{
+		ID:       "file-1",
+		FileName: "report.pdf",
+		Checksum: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+	}
+	share := &ShareRecord{
+		FileID:        "file-1",
+		Recipients:    []string{"alice@example.com", "bob@example.com"},
+		Message:       "Here is the quarterly report.",
+		PresignedURL:  "https://bucket.s3.amazonaws.com/uploads/report.pdf?X-Amz-Signature=abc",
+		URLExpiration: time.Now().Add(24 * time.Hour),
+	}
+
+	email := NewShareEmail(file, share)
+
+	assert.Equal(t, share.Recipients, email.Recipients)
+	assert.Equal(t, "File shared with you: report.pdf", email.Subject)
+	assert.Contains(t, email.Body, "Here is the quarterly report.")
+	assert.Contains(t, email.Body, "Download: "+share.PresignedURL)
+	assert.Contains(t, email.Body, "SHA-256: "+file.Checksum)
+	assert.Contains(t, email.Body, `sha256sum "report.pdf"`)
+	assert.Contains(t, email.Body, `shasum -a 256 "report.pdf"`)
+	assert.Contains(t, email.Body, `certutil -hashfile "report.pdf" SHA256`)
+	assert.NotContains(t, email.Body, "Please download")
+}
+
+func TestNewShareEmail_ShareOptions(t *testing.T) {
+	file := &FileMetadata{
+		ID:       "file-1",
+		FileName: "report-final-v3.pdf",
+		Checksum: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+	}
+	share := &ShareRecord{
+		Recipients:    []string{"alice@example.com"},
+		PresignedURL:  "https://example.com/report.pdf",
+		URLExpiration: time.Now().Add(time.Hour),
+		ShareOptions:  ShareOptions{MaxDownloads: 3, DownloadName: "Q3 report.pdf"},
+	}
+
+	email := NewShareEmail(file, share)
+
+	assert.Equal(t, "File shared with you: report-final-v3.pdf", email.Subject)
+	assert.Contains(t, email.Body, "Please download it no more than 3 times.")
+	assert.Contains(t, email.Body, `sha256sum "Q3 report.pdf"`)
+	assert.NotContains(t, email.Body, `sha256sum "report-final-v3.pdf"`)
+
+	share.MaxDownloads = 1
+	assert.Contains(t, NewShareEmail(file, share).Body, "Please download it only once.")
+}
+
+func TestNewShareEmail_NoChecksum(t *testing.T) {
+	file := &FileMetadata{ID: "file-1", FileName: "old.txt"}
+	share := &ShareRecord{
+		Recipients:    []string{"alice@example.com"},
+		PresignedURL:  "https://example.com/old.txt",
+		URLExpiration: time.Now().Add(time.Hour),
+	}
+
+	email := NewShareEmail(file, share)
+
+	assert.Contains(t, email.Body, "Download: https://example.com/old.txt")
+	assert.NotContains(t, email.Body, "SHA-256")
+	assert.NotContains(t, email.Body, "sha256sum")
+}
+
+func TestNewShareEmail_ScanVerdict(t *testing.T) {
+	file := &FileMetadata{ID: "file-1", FileName: "report.pdf", ScanVerdict: ScanClean, ScanEngine: "ClamAV 1.0.3/27100"}
+	share := &ShareRecord{
+		Recipients:    []string{"alice@example.com"},
+		PresignedURL:  "https://example.com/report.pdf",
+		URLExpiration: time.Now().Add(time.Hour),
+	}
+
+	email := NewShareEmail(file, share)
+	assert.Contains(t, email.Body, "It was scanned for viruses and malware with ClamAV 1.0.3/27100, which found nothing.")
+
+	// Files uploaded without a scan don't mention one
+	file.ScanVerdict, file.ScanEngine = "", ""
+	assert.NotContains(t, NewShareEmail(file, share).Body, "scanned")
+}
+
+func TestShareEmail_MailtoURL(t *testing.T) {
+	email := &ShareEmail{
+		Recipients: []string{"alice@example.com", "bob@example.com"},
+		Subject:    "File shared with you: a+b.txt",
+		Body:       "Hi there",
+	}
+
+	mailto := email.MailtoURL()
+
+	assert.True(t, strings.HasPrefix(mailto, "mailto:alice@example.com,bob@example.com?"))
+	assert.Contains(t, mailto, "body=Hi%20there")
+	assert.Contains(t, mailto, "subject=File%20shared%20with%20you%3A%20a%2Bb.txt")
+	assert.NotContains(t, mailto, "+")
+}
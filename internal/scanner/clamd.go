@@ -0,0 +1,167 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"file-sharing-app/internal/models"
+)
+
+// clamdChunkSize is how much of a file is sent to clamd at a time
+const clamdChunkSize = 64 * 1024
+
+// DefaultClamdTimeout is how long a clamd command may take before it is abandoned. Large files can
+// take a while to scan.
+const DefaultClamdTimeout = 5 * time.Minute
+
+// ClamdScanner scans files with a ClamAV daemon. Files are streamed to it, so it doesn't need
+// access to them, and it can run on another machine.
+type ClamdScanner struct {
+	network string // "unix" or "tcp"
+	address string
+	timeout time.Duration
+}
+
+// NewClamdScanner returns a scanner using the clamd listening at address, a socket path or host:port
+func NewClamdScanner(address string) *ClamdScanner {
+	return NewClamdScannerWithTimeout(address, DefaultClamdTimeout)
+}
+
+// NewClamdScannerWithTimeout returns a scanner using the clamd listening at address, abandoning
+// commands that take longer than timeout
+func NewClamdScannerWithTimeout(address string, timeout time.Duration) *ClamdScanner {
+	network := "tcp"
+	if filepath.IsAbs(address) {
+		network = "unix"
+	}
+	return &ClamdScanner{network: network, address: address, timeout: timeout}
+}
+
+// Scan streams a file to clamd and returns its verdict, with the ClamAV version and signature
+// database that gave it
+func (s *ClamdScanner) Scan(ctx context.Context, filePath string) (*models.ScanResult, error) {
+	engine, err := s.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file to scan: %w", err)
+	}
+	defer file.Close()
+
+	reply, err := s.command(ctx, "INSTREAM", file)
+	if err != nil {
+		return nil, err
+	}
+
+	// Replies are "stream: OK", "stream: <signature> FOUND" or "<message> ERROR"
+	reply = strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case reply == "OK":
+		return &models.ScanResult{Verdict: models.ScanClean, Engine: engine}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		signature := strings.TrimSuffix(reply, " FOUND")
+		return &models.ScanResult{Verdict: models.ScanInfected, Signature: signature, Engine: engine}, nil
+	default:
+		return nil, fmt.Errorf("clamd could not scan the file: %s", strings.TrimSuffix(reply, " ERROR"))
+	}
+}
+
+// Version returns the ClamAV version and the version of its signature database, such as
+// "ClamAV 1.0.3/27100"
+func (s *ClamdScanner) Version(ctx context.Context) (string, error) {
+	reply, err := s.command(ctx, "VERSION", nil)
+	if err != nil {
+		return "", err
+	}
+
+	// The reply ends with the date of the signature database, which changes with its version anyway
+	parts := strings.SplitN(reply, "/", 3)
+	if len(parts) > 2 {
+		parts = parts[:2]
+	}
+	return strings.Join(parts, "/"), nil
+}
+
+// command sends a command to clamd, followed by stream if it isn't nil, and returns the reply
+func (s *ClamdScanner) command(ctx context.Context, name string, stream io.Reader) (string, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to clamd at %s: %w", s.address, err)
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(s.timeout))
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	// The z prefix has clamd end the command and its reply with a null byte
+	_, err = conn.Write([]byte("z" + name + "\x00"))
+	if err == nil && stream != nil {
+		err = writeChunks(conn, stream)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		// clamd stops reading when a stream is too large, and says so before closing the connection
+		if reply, readErr := readReply(conn); readErr == nil && reply != "" {
+			return reply, nil
+		}
+		return "", fmt.Errorf("failed to send %s to clamd: %w", name, err)
+	}
+
+	reply, err := readReply(conn)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("failed to read clamd's reply to %s: %w", name, err)
+	}
+	return reply, nil
+}
+
+// writeChunks sends a stream to clamd as chunks, each preceded by its length, ending with an empty chunk
+func writeChunks(w io.Writer, stream io.Reader) error {
+	buffer := make([]byte, 4+clamdChunkSize)
+	for {
+		n, err := stream.Read(buffer[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buffer, uint32(n))
+			if _, writeErr := w.Write(buffer[:4+n]); writeErr != nil {
+				return writeErr
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read file to scan: %w", err)
+		}
+	}
+
+	_, err := w.Write([]byte{0, 0, 0, 0})
+	return err
+}
+
+// readReply reads a reply from clamd, up to the null byte that ends it
+func readReply(r io.Reader) (string, error) {
+	reply, err := bufio.NewReader(r).ReadString(0)
+	if err != nil && (err != io.EOF || reply == "") {
+		return "", err
+	}
+	return strings.TrimSpace(strings.TrimSuffix(reply, "\x00")), nil
+}
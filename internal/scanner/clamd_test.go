@@ -0,0 +1,191 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"file-sharing-app/internal/models"
+)
+
+// eicar is the standard antivirus test file, which scanners detect as if it were a virus
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// fakeClamd stands in for a ClamAV daemon on a local socket, finding the EICAR test file
+type fakeClamd struct {
+	address  string
+	maxBytes int // stream size clamd refuses beyond; zero means no limit
+
+	mu       sync.Mutex
+	received []byte   // the last stream scanned
+	commands []string // the commands received
+}
+
+func startFakeClamd(t *testing.T) *fakeClamd {
+	// Socket paths are limited to about 100 bytes, which test temp dirs can exceed
+	dir, err := os.MkdirTemp("", "clamd")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	clamd := &fakeClamd{address: filepath.Join(dir, "clamd.sock")}
+	listener, err := net.Listen("unix", clamd.address)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			clamd.serve(conn)
+		}
+	}()
+	return clamd
+}
+
+func (c *fakeClamd) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	command, err := reader.ReadString(0)
+	if err != nil {
+		return
+	}
+	command = strings.TrimSuffix(command, "\x00")
+	c.mu.Lock()
+	c.commands = append(c.commands, command)
+	c.mu.Unlock()
+
+	switch command {
+	case "zVERSION":
+		conn.Write([]byte("ClamAV 1.0.3/27100/Mon Oct 16 07:25:32 2023\x00"))
+	case "zINSTREAM":
+		var received bytes.Buffer
+		for {
+			var length uint32
+			if err := binary.Read(reader, binary.BigEndian, &length); err != nil {
+				return
+			}
+			if length == 0 {
+				break
+			}
+			if _, err := io.CopyN(&received, reader, int64(length)); err != nil {
+				return
+			}
+			if c.maxBytes > 0 && received.Len() > c.maxBytes {
+				conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+				return
+			}
+		}
+		c.mu.Lock()
+		c.received = received.Bytes()
+		c.mu.Unlock()
+
+		if bytes.Contains(received.Bytes(), []byte(eicar)) {
+			conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+		} else {
+			conn.Write([]byte("stream: OK\x00"))
+		}
+	default:
+		conn.Write([]byte("UNKNOWN COMMAND\x00"))
+	}
+}
+
+func writeTestFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestClamdScanner_Clean(t *testing.T) {
+	clamd := startFakeClamd(t)
+	scanner := NewClamdScanner(clamd.address)
+
+	// Larger than a chunk, so it is sent in several
+	content := strings.Repeat("quarterly figures\n", 10000)
+	result, err := scanner.Scan(context.Background(), writeTestFile(t, "report.txt", content))
+	require.NoError(t, err)
+
+	assert.Equal(t, models.ScanClean, result.Verdict)
+	assert.False(t, result.Infected())
+	assert.Empty(t, result.Signature)
+	assert.Equal(t, "ClamAV 1.0.3/27100", result.Engine)
+	clamd.mu.Lock()
+	defer clamd.mu.Unlock()
+	assert.Equal(t, content, string(clamd.received))
+	assert.Equal(t, []string{"zVERSION", "zINSTREAM"}, clamd.commands)
+}
+
+func TestClamdScanner_Infected(t *testing.T) {
+	clamd := startFakeClamd(t)
+	scanner := NewClamdScanner(clamd.address)
+
+	result, err := scanner.Scan(context.Background(), writeTestFile(t, "eicar.com", eicar))
+	require.NoError(t, err)
+
+	assert.True(t, result.Infected())
+	assert.Equal(t, "Eicar-Test-Signature", result.Signature)
+	assert.Equal(t, "ClamAV 1.0.3/27100", result.Engine)
+}
+
+func TestClamdScanner_SizeLimit(t *testing.T) {
+	clamd := startFakeClamd(t)
+	clamd.maxBytes = 1024
+	scanner := NewClamdScanner(clamd.address)
+
+	_, err := scanner.Scan(context.Background(), writeTestFile(t, "big.bin", strings.Repeat("x", 4*clamdChunkSize)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "size limit exceeded")
+}
+
+func TestClamdScanner_Unreachable(t *testing.T) {
+	scanner := NewClamdScanner(filepath.Join(t.TempDir(), "missing.sock"))
+
+	_, err := scanner.Scan(context.Background(), writeTestFile(t, "report.txt", "figures"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to connect to clamd")
+}
+
+func TestClamdScanner_Cancelled(t *testing.T) {
+	// A clamd that accepts connections but never replies
+	dir, err := os.MkdirTemp("", "clamd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	address := filepath.Join(dir, "clamd.sock")
+	listener, err := net.Listen("unix", address)
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err = NewClamdScanner(address).Version(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestNewClamdScanner_Network(t *testing.T) {
+	assert.Equal(t, "unix", NewClamdScanner("/var/run/clamav/clamd.ctl").network)
+	assert.Equal(t, "tcp", NewClamdScanner("localhost:3310").network)
+}
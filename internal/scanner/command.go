@@ -0,0 +1,94 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"file-sharing-app/internal/models"
+)
+
+// maxSignatureLength is the most of a scan command's output kept as the signature of what it found
+const maxSignatureLength = 200
+
+// CommandScanner scans files by running a command on each, such as clamscan or another vendor's
+// scanner. Like clamscan, the command must exit with 0 for clean files and 1 for infected ones;
+// any other exit status means the file couldn't be scanned.
+type CommandScanner struct {
+	args []string
+}
+
+// NewCommandScanner returns a scanner running command, split on spaces. {file} in it stands for the
+// path of the file to scan; without it the path is added to the end.
+func NewCommandScanner(command string) *CommandScanner {
+	return &CommandScanner{args: strings.Fields(command)}
+}
+
+// Scan runs the command on a file. The command's name is given as the engine, and what it prints
+// about an infected file as the signature.
+func (s *CommandScanner) Scan(ctx context.Context, filePath string) (*models.ScanResult, error) {
+	if len(s.args) == 0 {
+		return nil, fmt.Errorf("no scan command set")
+	}
+
+	args := make([]string, 0, len(s.args)+1)
+	placed := false
+	for _, arg := range s.args[1:] {
+		if strings.Contains(arg, models.ScanFilePlaceholder) {
+			arg = strings.ReplaceAll(arg, models.ScanFilePlaceholder, filePath)
+			placed = true
+		}
+		args = append(args, arg)
+	}
+	if !placed {
+		args = append(args, filePath)
+	}
+
+	engine := filepath.Base(s.args[0])
+	output, err := exec.CommandContext(ctx, s.args[0], args...).CombinedOutput()
+	if err == nil {
+		return &models.ScanResult{Verdict: models.ScanClean, Engine: engine}, nil
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return &models.ScanResult{Verdict: models.ScanInfected, Signature: commandSignature(string(output), filePath), Engine: engine}, nil
+	}
+	return nil, fmt.Errorf("scan command %s failed: %w: %s", engine, err, lastLine(string(output)))
+}
+
+// commandSignature picks what a scan command found out of its output: the line reporting it,
+// without the file's path, as clamscan prints "<path>: <signature> FOUND"
+func commandSignature(output, filePath string) string {
+	signature := ""
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); strings.HasSuffix(line, " FOUND") {
+			signature = strings.TrimSuffix(line, " FOUND")
+			break
+		}
+	}
+	if signature == "" {
+		signature = lastLine(output)
+	}
+	signature = strings.TrimSpace(strings.TrimPrefix(signature, filePath+":"))
+
+	if signature == "" {
+		return "a threat"
+	}
+	if len(signature) > maxSignatureLength {
+		signature = signature[:maxSignatureLength]
+	}
+	return signature
+}
+
+// lastLine returns the last line of output that isn't blank
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,102 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"file-sharing-app/internal/models"
+)
+
+// writeScanScript writes a shell script standing in for a scan command
+func writeScanScript(t *testing.T, script string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("scan command tests use a shell script")
+	}
+	path := filepath.Join(t.TempDir(), "fakescan")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755))
+	return path
+}
+
+// clamscanScript behaves like clamscan, finding the EICAR test file in the last argument
+const clamscanScript = `for file; do :; done
+if grep -q EICAR-STANDARD-ANTIVIRUS-TEST-FILE "$file"; then
+	echo "$file: Eicar-Test-Signature FOUND"
+	echo
+	echo "----------- SCAN SUMMARY -----------"
+	exit 1
+fi
+echo "$file: OK"
+`
+
+func TestCommandScanner_Clean(t *testing.T) {
+	script := writeScanScript(t, clamscanScript)
+
+	result, err := NewCommandScanner(script+" --no-summary").Scan(context.Background(), writeTestFile(t, "report.txt", "figures"))
+	require.NoError(t, err)
+
+	assert.Equal(t, models.ScanClean, result.Verdict)
+	assert.Equal(t, "fakescan", result.Engine)
+}
+
+func TestCommandScanner_Infected(t *testing.T) {
+	script := writeScanScript(t, clamscanScript)
+
+	result, err := NewCommandScanner(script).Scan(context.Background(), writeTestFile(t, "eicar.com", eicar))
+	require.NoError(t, err)
+
+	assert.True(t, result.Infected())
+	assert.Equal(t, "Eicar-Test-Signature", result.Signature)
+	assert.Equal(t, "fakescan", result.Engine)
+}
+
+func TestCommandScanner_FilePlaceholder(t *testing.T) {
+	// The script only finds the file if it is given as --file=<path>, not last
+	script := writeScanScript(t, `case "$1" in
+--file=*) [ -f "${1#--file=}" ] && [ "$2" = "--verbose" ] && exit 0 ;;
+esac
+exit 2
+`)
+
+	result, err := NewCommandScanner(script+" --file={file} --verbose").Scan(context.Background(), writeTestFile(t, "report.txt", "figures"))
+	require.NoError(t, err)
+	assert.Equal(t, models.ScanClean, result.Verdict)
+}
+
+func TestCommandScanner_Failed(t *testing.T) {
+	script := writeScanScript(t, "echo 'cannot load signatures' >&2\nexit 2\n")
+
+	_, err := NewCommandScanner(script).Scan(context.Background(), writeTestFile(t, "report.txt", "figures"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot load signatures")
+
+	_, err = NewCommandScanner("").Scan(context.Background(), writeTestFile(t, "report.txt", "figures"))
+	assert.Error(t, err)
+}
+
+func TestCommandSignature(t *testing.T) {
+	assert.Equal(t, "Win.Trojan.Agent-1", commandSignature("/tmp/a.exe: Win.Trojan.Agent-1 FOUND\n\nsummary\n", "/tmp/a.exe"))
+	assert.Equal(t, "threat detected: Trojan", commandSignature("scanning...\nthreat detected: Trojan\n", "/tmp/a.exe"))
+	assert.Equal(t, "a threat", commandSignature("", "/tmp/a.exe"))
+}
+
+func TestNew(t *testing.T) {
+	settings := models.DefaultApplicationSettings()
+	assert.Nil(t, New(settings))
+
+	settings.VirusScanner = models.ScannerClamd
+	clamd, ok := New(settings).(*ClamdScanner)
+	require.True(t, ok)
+	assert.Equal(t, models.DefaultClamdAddress, clamd.address)
+
+	settings.VirusScanner = models.ScannerCommand
+	settings.ScanCommand = "clamscan --no-summary"
+	command, ok := New(settings).(*CommandScanner)
+	require.True(t, ok)
+	assert.Equal(t, []string{"clamscan", "--no-summary"}, command.args)
+}
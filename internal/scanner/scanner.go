@@ -0,0 +1,26 @@
+package scanner
+
+import (
+	"context"
+
+	"file-sharing-app/internal/models"
+)
+
+// Scanner scans files for viruses and malware
+type Scanner interface {
+	// Scan scans the file at filePath. Finding something is not an error: it is reported in the
+	// result. An error means the file couldn't be scanned.
+	Scan(ctx context.Context, filePath string) (*models.ScanResult, error)
+}
+
+// New returns the scanner chosen in the settings, or nil if scanning is off
+func New(settings *models.ApplicationSettings) Scanner {
+	switch settings.VirusScanner {
+	case models.ScannerClamd:
+		return NewClamdScanner(settings.GetClamdAddress())
+	case models.ScannerCommand:
+		return NewCommandScanner(settings.ScanCommand)
+	default:
+		return nil
+	}
+}
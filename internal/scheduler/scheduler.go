@@ -0,0 +1,333 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"file-sharing-app/pkg/logger"
+)
+
+// idleWait is how long the scheduler sleeps when no job is enabled
+const idleWait = time.Hour
+
+// JobFunc performs one run of a background job
+type JobFunc func(ctx context.Context) error
+
+// JobStatus reports when a job last ran and when it runs next
+type JobStatus struct {
+	Name      string
+	Interval  time.Duration
+	Enabled   bool
+	Running   bool
+	LastRun   time.Time // zero if the job hasn't run yet
+	LastError error
+	NextRun   time.Time // zero if the job is disabled
+}
+
+// job is a registered job and its schedule
+type job struct {
+	name      string
+	interval  time.Duration
+	run       JobFunc
+	running   bool
+	lastRun   time.Time
+	lastError error
+	nextRun   time.Time
+}
+
+// status returns a snapshot of the job's schedule
+func (j *job) status() JobStatus {
+	return JobStatus{
+		Name:      j.name,
+		Interval:  j.interval,
+		Enabled:   j.interval > 0,
+		Running:   j.running,
+		LastRun:   j.lastRun,
+		LastError: j.lastError,
+		NextRun:   j.nextRun,
+	}
+}
+
+// reschedule sets the next run one interval after the last, or after now if the job hasn't run
+func (j *job) reschedule(now time.Time) {
+	if j.interval <= 0 {
+		j.nextRun = time.Time{}
+		return
+	}
+
+	from := j.lastRun
+	if from.IsZero() {
+		from = now
+	}
+	j.nextRun = from.Add(j.interval)
+}
+
+// Scheduler runs background jobs at configurable intervals.
+// Jobs run one at a time, so a slow job delays the others rather than overlapping them.
+type Scheduler struct {
+	mutex    sync.Mutex
+	jobs     []*job
+	wake     chan struct{}
+	onChange func(status JobStatus)
+	logger   *logger.Logger
+}
+
+// NewScheduler creates a scheduler with no jobs
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		wake:   make(chan struct{}, 1),
+		logger: logger.NewWithComponent("scheduler"),
+	}
+}
+
+// SetOnChange sets a callback invoked whenever a job starts, finishes or is rescheduled.
+// It is called from the scheduler's goroutine or the caller's, never with the scheduler locked.
+func (s *Scheduler) SetOnChange(onChange func(status JobStatus)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.onChange = onChange
+}
+
+// Register adds a job that first runs one interval from now. A zero interval registers it disabled.
+func (s *Scheduler) Register(name string, interval time.Duration, run JobFunc) error {
+	if name == "" {
+		return fmt.Errorf("job name cannot be empty")
+	}
+	if run == nil {
+		return fmt.Errorf("job %s has no function to run", name)
+	}
+
+	s.mutex.Lock()
+	if s.find(name) != nil {
+		s.mutex.Unlock()
+		return fmt.Errorf("job already registered: %s", name)
+	}
+
+	j := &job{name: name, interval: interval, run: run}
+	j.reschedule(time.Now())
+	s.jobs = append(s.jobs, j)
+	status := j.status()
+	s.mutex.Unlock()
+
+	s.notifyChange(status)
+	s.signal()
+	return nil
+}
+
+// SetInterval changes how often a job runs, counting from its last run. A zero interval disables it.
+func (s *Scheduler) SetInterval(name string, interval time.Duration) error {
+	s.mutex.Lock()
+	j := s.find(name)
+	if j == nil {
+		s.mutex.Unlock()
+		return fmt.Errorf("job not found: %s", name)
+	}
+
+	if j.interval == interval {
+		s.mutex.Unlock()
+		return nil
+	}
+
+	j.interval = interval
+	j.reschedule(time.Now())
+	status := j.status()
+	s.mutex.Unlock()
+
+	s.logger.Info(fmt.Sprintf("Job %s now runs every %v", name, interval))
+	s.notifyChange(status)
+	s.signal()
+	return nil
+}
+
+// RunNow schedules a job to run as soon as the scheduler is free, even if it is disabled
+func (s *Scheduler) RunNow(name string) error {
+	s.mutex.Lock()
+	j := s.find(name)
+	if j == nil {
+		s.mutex.Unlock()
+		return fmt.Errorf("job not found: %s", name)
+	}
+
+	j.nextRun = time.Now()
+	status := j.status()
+	s.mutex.Unlock()
+
+	s.notifyChange(status)
+	s.signal()
+	return nil
+}
+
+// MarkRun records that a job's work was done outside the scheduler, e.g. a manual sync,
+// and pushes its next run back by a full interval
+func (s *Scheduler) MarkRun(name string) error {
+	s.mutex.Lock()
+	j := s.find(name)
+	if j == nil {
+		s.mutex.Unlock()
+		return fmt.Errorf("job not found: %s", name)
+	}
+
+	j.lastRun = time.Now()
+	j.lastError = nil
+	j.reschedule(j.lastRun)
+	status := j.status()
+	s.mutex.Unlock()
+
+	s.notifyChange(status)
+	s.signal()
+	return nil
+}
+
+// Status returns the schedule of a job
+func (s *Scheduler) Status(name string) (JobStatus, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	j := s.find(name)
+	if j == nil {
+		return JobStatus{}, fmt.Errorf("job not found: %s", name)
+	}
+
+	return j.status(), nil
+}
+
+// Statuses returns the schedules of all jobs, in the order they were registered
+func (s *Scheduler) Statuses() []JobStatus {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	statuses := make([]JobStatus, len(s.jobs))
+	for i, j := range s.jobs {
+		statuses[i] = j.status()
+	}
+	return statuses
+}
+
+// Run executes jobs as they fall due until ctx is canceled
+func (s *Scheduler) Run(ctx context.Context) {
+	s.logger.Info("Starting background job scheduler")
+
+	for {
+		if ctx.Err() != nil {
+			s.logger.Info("Stopping background job scheduler")
+			return
+		}
+
+		if j := s.dueJob(time.Now()); j != nil {
+			s.runJob(ctx, j)
+			continue
+		}
+
+		timer := time.NewTimer(s.untilNextRun(time.Now()))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			s.logger.Info("Stopping background job scheduler")
+			return
+		case <-s.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// dueJob returns the most overdue job, or nil if none is due
+func (s *Scheduler) dueJob(now time.Time) *job {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var due *job
+	for _, j := range s.jobs {
+		if j.nextRun.IsZero() || j.nextRun.After(now) {
+			continue
+		}
+		if due == nil || j.nextRun.Before(due.nextRun) {
+			due = j
+		}
+	}
+	return due
+}
+
+// untilNextRun returns how long until the next job falls due
+func (s *Scheduler) untilNextRun(now time.Time) time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	wait := idleWait
+	for _, j := range s.jobs {
+		if j.nextRun.IsZero() {
+			continue
+		}
+		if until := j.nextRun.Sub(now); until < wait {
+			wait = until
+		}
+	}
+
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// runJob runs a job and schedules its next run
+func (s *Scheduler) runJob(ctx context.Context, j *job) {
+	s.mutex.Lock()
+	j.running = true
+	// Clear the due time so the job isn't picked again while it runs
+	j.nextRun = time.Time{}
+	status := j.status()
+	s.mutex.Unlock()
+
+	s.notifyChange(status)
+
+	err := j.run(ctx)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("Background job %s failed: %v", j.name, err))
+	}
+
+	s.mutex.Lock()
+	j.running = false
+	j.lastRun = time.Now()
+	j.lastError = err
+	// A RunNow or MarkRun during the run already set the next run
+	if j.nextRun.IsZero() {
+		j.reschedule(j.lastRun)
+	}
+	status = j.status()
+	s.mutex.Unlock()
+
+	s.notifyChange(status)
+}
+
+// find returns the job with the given name. Must be called with the mutex held.
+func (s *Scheduler) find(name string) *job {
+	for _, j := range s.jobs {
+		if j.name == name {
+			return j
+		}
+	}
+	return nil
+}
+
+// notifyChange reports a job's new status to the change callback, if any
+func (s *Scheduler) notifyChange(status JobStatus) {
+	s.mutex.Lock()
+	onChange := s.onChange
+	s.mutex.Unlock()
+
+	if onChange != nil {
+		onChange(status)
+	}
+}
+
+// signal wakes the run loop so it picks up schedule changes
+func (s *Scheduler) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,245 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startScheduler runs the scheduler until the test ends
+func startScheduler(t *testing.T, s *Scheduler) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+// countingJob returns a job that signals each run on the returned channel
+func countingJob(err error) (JobFunc, chan struct{}) {
+	runs := make(chan struct{}, 100)
+	return func(ctx context.Context) error {
+		runs <- struct{}{}
+		return err
+	}, runs
+}
+
+// waitForRun waits for a job to run once
+func waitForRun(t *testing.T, runs chan struct{}) {
+	t.Helper()
+	select {
+	case <-runs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job did not run")
+	}
+}
+
+func TestScheduler_RunsJobsAtInterval(t *testing.T) {
+	s := NewScheduler()
+	run, runs := countingJob(nil)
+	require.NoError(t, s.Register("sync", 10*time.Millisecond, run))
+
+	startScheduler(t, s)
+
+	waitForRun(t, runs)
+	waitForRun(t, runs)
+
+	status, err := s.Status("sync")
+	require.NoError(t, err)
+	assert.True(t, status.Enabled)
+	assert.False(t, status.LastRun.IsZero())
+	assert.NoError(t, status.LastError)
+}
+
+func TestScheduler_DisabledJob(t *testing.T) {
+	s := NewScheduler()
+	run, runs := countingJob(nil)
+	require.NoError(t, s.Register("sync", 0, run))
+
+	status, err := s.Status("sync")
+	require.NoError(t, err)
+	assert.False(t, status.Enabled)
+	assert.True(t, status.NextRun.IsZero())
+
+	startScheduler(t, s)
+
+	select {
+	case <-runs:
+		t.Fatal("disabled job ran")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Enabling the job schedules it
+	require.NoError(t, s.SetInterval("sync", 10*time.Millisecond))
+	waitForRun(t, runs)
+}
+
+func TestScheduler_SetIntervalDisables(t *testing.T) {
+	s := NewScheduler()
+	run, runs := countingJob(nil)
+	require.NoError(t, s.Register("sync", time.Hour, run))
+
+	before, err := s.Status("sync")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), before.NextRun, time.Second)
+
+	require.NoError(t, s.SetInterval("sync", 0))
+
+	after, err := s.Status("sync")
+	require.NoError(t, err)
+	assert.False(t, after.Enabled)
+	assert.True(t, after.NextRun.IsZero())
+	assert.Empty(t, runs)
+
+	assert.Error(t, s.SetInterval("missing", time.Minute))
+}
+
+func TestScheduler_RunNow(t *testing.T) {
+	s := NewScheduler()
+	run, runs := countingJob(nil)
+	require.NoError(t, s.Register("cleanup", time.Hour, run))
+
+	startScheduler(t, s)
+
+	require.NoError(t, s.RunNow("cleanup"))
+	waitForRun(t, runs)
+
+	// The next run is a full interval after this one
+	require.Eventually(t, func() bool {
+		status, err := s.Status("cleanup")
+		return err == nil && !status.Running && !status.LastRun.IsZero()
+	}, 5*time.Second, 5*time.Millisecond)
+
+	status, err := s.Status("cleanup")
+	require.NoError(t, err)
+	assert.WithinDuration(t, status.LastRun.Add(time.Hour), status.NextRun, time.Millisecond)
+
+	assert.Error(t, s.RunNow("missing"))
+}
+
+func TestScheduler_MarkRun(t *testing.T) {
+	s := NewScheduler()
+	run, _ := countingJob(nil)
+	require.NoError(t, s.Register("sync", 15*time.Minute, run))
+
+	require.NoError(t, s.MarkRun("sync"))
+
+	status, err := s.Status("sync")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), status.LastRun, time.Second)
+	assert.Equal(t, status.LastRun.Add(15*time.Minute), status.NextRun)
+
+	assert.Error(t, s.MarkRun("missing"))
+}
+
+func TestScheduler_RecordsErrors(t *testing.T) {
+	s := NewScheduler()
+	run, runs := countingJob(errors.New("S3 unreachable"))
+	require.NoError(t, s.Register("sync", 10*time.Millisecond, run))
+
+	startScheduler(t, s)
+	waitForRun(t, runs)
+
+	require.Eventually(t, func() bool {
+		status, err := s.Status("sync")
+		return err == nil && status.LastError != nil
+	}, 5*time.Second, 5*time.Millisecond)
+
+	// A failed job keeps its schedule
+	waitForRun(t, runs)
+}
+
+func TestScheduler_OnChange(t *testing.T) {
+	s := NewScheduler()
+
+	var mutex sync.Mutex
+	var changes []JobStatus
+	s.SetOnChange(func(status JobStatus) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		changes = append(changes, status)
+	})
+
+	run, runs := countingJob(nil)
+	require.NoError(t, s.Register("sync", time.Hour, run))
+
+	startScheduler(t, s)
+	require.NoError(t, s.RunNow("sync"))
+	waitForRun(t, runs)
+
+	// Registered, queued, started and finished
+	require.Eventually(t, func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(changes) == 4
+	}, 5*time.Second, 5*time.Millisecond)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.True(t, changes[2].Running)
+	assert.False(t, changes[3].Running)
+	assert.False(t, changes[3].LastRun.IsZero())
+}
+
+func TestScheduler_RegisterValidation(t *testing.T) {
+	s := NewScheduler()
+	run, _ := countingJob(nil)
+
+	assert.Error(t, s.Register("", time.Minute, run))
+	assert.Error(t, s.Register("sync", time.Minute, nil))
+
+	require.NoError(t, s.Register("sync", time.Minute, run))
+	err := s.Register("sync", time.Minute, run)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "already registered")
+
+	_, err = s.Status("missing")
+	assert.Error(t, err)
+
+	statuses := s.Statuses()
+	require.Len(t, statuses, 1)
+	assert.Equal(t, "sync", statuses[0].Name)
+}
+
+func TestScheduler_JobsRunOneAtATime(t *testing.T) {
+	s := NewScheduler()
+
+	var mutex sync.Mutex
+	active, maxActive := 0, 0
+	slowJob := func(ctx context.Context) error {
+		mutex.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		mutex.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mutex.Lock()
+		active--
+		mutex.Unlock()
+		return nil
+	}
+
+	require.NoError(t, s.Register("first", 5*time.Millisecond, slowJob))
+	require.NoError(t, s.Register("second", 5*time.Millisecond, slowJob))
+
+	startScheduler(t, s)
+	time.Sleep(100 * time.Millisecond)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Equal(t, 1, maxActive)
+}
@@ -2,53 +2,83 @@ package storage
 
 import (
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	
+	"file-sharing-app/internal/models"
 	"file-sharing-app/pkg/errors"
 	"file-sharing-app/pkg/logger"
 )
 
-// FileStatus represents the status of a file
-type FileStatus string
+// The file and share records are the domain models; this package maps them to SQL.
+// The aliases keep the storage names working, so a field added to a model only needs a column here.
+type (
+	FileStatus    = models.FileStatus
+	FileMetadata  = models.FileMetadata
+	FileSortField = models.FileSortField
+	FileQuery     = models.FileQuery
+	ShareRecord   = models.ShareRecord
+	ShareOptions  = models.ShareOptions
+)
+
+const (
+	StatusUploading = models.StatusUploading
+	StatusActive    = models.StatusActive
+	StatusExpired   = models.StatusExpired
+	StatusDeleted   = models.StatusDeleted
+	StatusError     = models.StatusError
+	StatusPending   = models.StatusPending
+)
 
 const (
-	StatusUploading FileStatus = "uploading"
-	StatusActive    FileStatus = "active"
-	StatusExpired   FileStatus = "expired"
-	StatusDeleted   FileStatus = "deleted"
-	StatusError     FileStatus = "error"
+	SortByUploadDate = models.SortByUploadDate
+	SortByFileName   = models.SortByFileName
+	SortByFileSize   = models.SortByFileSize
+	SortByExpiration = models.SortByExpiration
+	SortByStatus     = models.SortByStatus
 )
 
-// FileMetadata represents file information stored in the database
-type FileMetadata struct {
-	ID             string    `json:"id"`
-	FileName       string    `json:"filename"`
-	FilePath       string    `json:"filepath"`
-	FileSize       int64     `json:"filesize"`
-	UploadDate     time.Time `json:"upload_date"`
-	ExpirationDate time.Time `json:"expiration_date"`
-	S3Key          string    `json:"s3_key"`
-	Status         FileStatus `json:"status"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
-}
-
-// ShareRecord represents a file sharing record
-type ShareRecord struct {
-	ID            string    `json:"id"`
-	FileID        string    `json:"file_id"`
-	Recipients    []string  `json:"recipients"`
-	Message       string    `json:"message"`
-	SharedDate    time.Time `json:"shared_date"`
-	PresignedURL  string    `json:"presigned_url"`
-	URLExpiration time.Time `json:"url_expiration"`
-	CreatedAt     time.Time `json:"created_at"`
+// DefaultProfile is the profile assigned to files saved without one
+const DefaultProfile = "default"
+
+// DefaultEncryptionMode is the encryption recorded for files saved without one.
+// Uploads always used SSE-S3 before the mode became configurable.
+const DefaultEncryptionMode = "SSE-S3"
+
+// fileSortColumns maps sort fields to the ORDER BY expressions they use.
+// Dates are compared as julian days, as stored timestamps keep the UTC offset they were saved with.
+var fileSortColumns = map[FileSortField]string{
+	SortByUploadDate: "julianday(upload_date)",
+	SortByFileName:   "filename COLLATE NOCASE",
+	SortByFileSize:   "filesize",
+	SortByExpiration: "julianday(expiration_date)",
+	SortByStatus:     "status",
+}
+
+// OutboxEntry is an operation queued while offline, replayed in ID order once S3 is reachable
+type OutboxEntry struct {
+	ID        int64     `json:"id"`
+	Operation string    `json:"operation"`
+	FileID    string    `json:"file_id"`
+	Payload   string    `json:"payload"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NotificationRecord is a desktop notification the app has sent
+type NotificationRecord struct {
+	ID        int64     `json:"id"`
+	Event     string    `json:"event"`
+	FileID    string    `json:"file_id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 // Database interface defines the contract for database operations
@@ -57,18 +87,45 @@ type Database interface {
 	SaveFile(file *FileMetadata) error
 	GetFile(id string) (*FileMetadata, error)
 	ListFiles() ([]*FileMetadata, error)
+	ListFilesByProfile(profile string) ([]*FileMetadata, error)
+	QueryFiles(query FileQuery) ([]*FileMetadata, error)
 	UpdateFileStatus(id string, status FileStatus) error
 	UpdateFileExpiration(id string, expirationDate time.Time) error
+	UpdateFileChecksum(id string, checksum, etag string) error
+	UpdateFileEncryptionMode(id string, encryptionMode string) error
+	UpdateFileContentType(id string, contentType string) error
+	UpdateFileStorage(id string, compression string, storedSize int64) error
+	UpdateFileScan(id string, verdict, engine string) error
 	DeleteFile(id string) error
 
 	// Share operations
 	SaveShare(share *ShareRecord) error
 	GetShareHistory(fileID string) ([]*ShareRecord, error)
+	ListSharesExpiringBefore(before time.Time) ([]*ShareRecord, error)
+	GetShare(id string) (*ShareRecord, error)
+	ListRecentShares(profile string, limit int) ([]*ShareRecord, error)
+	UpdateShareSigning(id string, signedAt, urlExpiration time.Time) error
+	RevokeShare(id string, revokedAt time.Time) error
+
+	// Outbox operations
+	EnqueueOutbox(entry *OutboxEntry) error
+	ListOutbox() ([]*OutboxEntry, error)
+	RecordOutboxAttempt(id int64, lastError string) error
+	DeleteOutboxEntry(id int64) error
+
+	// Notification history operations
+	SaveNotification(notification *NotificationRecord) error
+	ListNotifications(limit int) ([]*NotificationRecord, error)
+	HasNotification(event, fileID string) (bool, error)
+	ClearNotifications() error
 
 	// Configuration operations
 	SaveConfig(key, value string) error
 	GetConfig(key string) (string, error)
 
+	// WithTransaction runs fn with a Database whose operations all commit or roll back together
+	WithTransaction(fn func(tx Database) error) error
+
 	// Database management
 	Close() error
 }
@@ -76,7 +133,20 @@ type Database interface {
 // SQLiteDatabase implements the Database interface using SQLite
 type SQLiteDatabase struct {
 	db     *sql.DB
+	conn   queryer // db, or tx inside WithTransaction
+	tx     *sql.Tx // the transaction this instance is part of, if any
+	path   string
 	logger *logger.Logger
+
+	// Encrypts sensitive fields; nil stores them in plain text
+	cipher *FieldCipher
+}
+
+// queryer runs statements, on the database or in a transaction
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
 }
 
 // NewSQLiteDatabase creates a new SQLite database instance
@@ -95,8 +165,9 @@ func NewSQLiteDatabase(dbPath string) (*SQLiteDatabase, error) {
 			return errors.WrapError(err, errors.ErrDatabaseConnection, "failed to create database directory")
 		}
 
-		// Open database connection
-		db, err := sql.Open("sqlite3", dbPath)
+		// Open database connection. Transactions take the write lock when they begin, and
+		// writers wait for each other rather than failing while a transaction is open.
+		db, err := sql.Open("sqlite3", dbPath+"?_busy_timeout=5000&_txlock=immediate&_secure_delete=on")
 		if err != nil {
 			return errors.WrapError(err, errors.ErrDatabaseConnection, "failed to open database")
 		}
@@ -121,13 +192,15 @@ func NewSQLiteDatabase(dbPath string) (*SQLiteDatabase, error) {
 
 		sqliteDB = &SQLiteDatabase{
 			db:     db,
+			conn:   db,
+			path:   dbPath,
 			logger: log,
 		}
 
-		// Initialize schema
-		if err := sqliteDB.initSchema(); err != nil {
+		// Bring the schema up to date, refusing databases from newer versions of the app
+		if err := sqliteDB.migrate(); err != nil {
 			db.Close()
-			return errors.WrapError(err, errors.ErrDatabaseError, "failed to initialize schema")
+			return errors.WrapError(err, errors.ErrDatabaseError, "failed to migrate database schema")
 		}
 
 		log.Info("Database initialized successfully")
@@ -141,52 +214,6 @@ func NewSQLiteDatabase(dbPath string) (*SQLiteDatabase, error) {
 	return sqliteDB, nil
 }
 
-// initSchema creates the database tables if they don't exist
-func (s *SQLiteDatabase) initSchema() error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS files (
-		id TEXT PRIMARY KEY,
-		filename TEXT NOT NULL,
-		filepath TEXT NOT NULL,
-		filesize INTEGER NOT NULL,
-		upload_date DATETIME NOT NULL,
-		expiration_date DATETIME NOT NULL,
-		s3_key TEXT NOT NULL,
-		status TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_files_upload_date ON files(upload_date);
-	CREATE INDEX IF NOT EXISTS idx_files_expiration_date ON files(expiration_date);
-	CREATE INDEX IF NOT EXISTS idx_files_status ON files(status);
-
-	CREATE TABLE IF NOT EXISTS shares (
-		id TEXT PRIMARY KEY,
-		file_id TEXT NOT NULL,
-		recipients TEXT NOT NULL,
-		message TEXT,
-		shared_date DATETIME NOT NULL,
-		presigned_url TEXT NOT NULL,
-		url_expiration DATETIME NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (file_id) REFERENCES files(id) ON DELETE CASCADE
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_shares_file_id ON shares(file_id);
-	CREATE INDEX IF NOT EXISTS idx_shares_shared_date ON shares(shared_date);
-
-	CREATE TABLE IF NOT EXISTS app_config (
-		key TEXT PRIMARY KEY,
-		value TEXT NOT NULL,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-	`
-
-	_, err := s.db.Exec(schema)
-	return err
-}
-
 // File operations
 
 // SaveFile saves a file metadata record to the database
@@ -202,16 +229,22 @@ func (s *SQLiteDatabase) SaveFile(file *FileMetadata) error {
 		now := time.Now()
 		file.CreatedAt = now
 		file.UpdatedAt = now
+		if file.Profile == "" {
+			file.Profile = DefaultProfile
+		}
+		if file.EncryptionMode == "" {
+			file.EncryptionMode = DefaultEncryptionMode
+		}
 
 		query := `
-			INSERT INTO files (id, filename, filepath, filesize, upload_date, expiration_date, s3_key, status, created_at, updated_at)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			INSERT INTO files (id, filename, filepath, filesize, upload_date, expiration_date, s3_key, status, profile, encryption_mode, checksum_sha256, etag, content_type, compression, stored_size, scan_verdict, scan_engine, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		`
 
-		_, err := s.db.Exec(query,
+		_, err := s.conn.Exec(query,
 			file.ID, file.FileName, file.FilePath, file.FileSize,
 			file.UploadDate, file.ExpirationDate, file.S3Key, string(file.Status),
-			file.CreatedAt, file.UpdatedAt,
+			file.Profile, file.EncryptionMode, file.Checksum, file.ETag, file.ContentType, file.Compression, file.StoredSize, file.ScanVerdict, file.ScanEngine, file.CreatedAt, file.UpdatedAt,
 		)
 
 		if err != nil {
@@ -234,11 +267,11 @@ func (s *SQLiteDatabase) GetFile(id string) (*FileMetadata, error) {
 		})
 
 		query := `
-			SELECT id, filename, filepath, filesize, upload_date, expiration_date, s3_key, status, created_at, updated_at
+			SELECT id, filename, filepath, filesize, upload_date, expiration_date, s3_key, status, profile, encryption_mode, checksum_sha256, etag, content_type, compression, stored_size, scan_verdict, scan_engine, created_at, updated_at
 			FROM files WHERE id = ?
 		`
 
-		row := s.db.QueryRow(query, id)
+		row := s.conn.QueryRow(query, id)
 
 		var fileData FileMetadata
 		var status string
@@ -246,7 +279,7 @@ func (s *SQLiteDatabase) GetFile(id string) (*FileMetadata, error) {
 		err := row.Scan(
 			&fileData.ID, &fileData.FileName, &fileData.FilePath, &fileData.FileSize,
 			&fileData.UploadDate, &fileData.ExpirationDate, &fileData.S3Key, &status,
-			&fileData.CreatedAt, &fileData.UpdatedAt,
+			&fileData.Profile, &fileData.EncryptionMode, &fileData.Checksum, &fileData.ETag, &fileData.ContentType, &fileData.Compression, &fileData.StoredSize, &fileData.ScanVerdict, &fileData.ScanEngine, &fileData.CreatedAt, &fileData.UpdatedAt,
 		)
 
 		if err != nil {
@@ -273,11 +306,190 @@ func (s *SQLiteDatabase) GetFile(id string) (*FileMetadata, error) {
 // ListFiles retrieves all file metadata records
 func (s *SQLiteDatabase) ListFiles() ([]*FileMetadata, error) {
 	query := `
-		SELECT id, filename, filepath, filesize, upload_date, expiration_date, s3_key, status, created_at, updated_at
+		SELECT id, filename, filepath, filesize, upload_date, expiration_date, s3_key, status, profile, encryption_mode, checksum_sha256, etag, content_type, compression, stored_size, scan_verdict, scan_engine, created_at, updated_at
 		FROM files ORDER BY upload_date DESC
 	`
 
-	rows, err := s.db.Query(query)
+	return s.queryFiles(query)
+}
+
+// ListFilesByProfile retrieves the file metadata records belonging to a profile
+func (s *SQLiteDatabase) ListFilesByProfile(profile string) ([]*FileMetadata, error) {
+	if profile == "" {
+		profile = DefaultProfile
+	}
+
+	query := `
+		SELECT id, filename, filepath, filesize, upload_date, expiration_date, s3_key, status, profile, encryption_mode, checksum_sha256, etag, content_type, compression, stored_size, scan_verdict, scan_engine, created_at, updated_at
+		FROM files WHERE profile = ? ORDER BY upload_date DESC
+	`
+
+	return s.queryFiles(query, profile)
+}
+
+// QueryFiles retrieves the file metadata records matching the query's filters, in its sort order
+func (s *SQLiteDatabase) QueryFiles(query FileQuery) ([]*FileMetadata, error) {
+	if query.Limit < 0 || query.Offset < 0 {
+		return nil, fmt.Errorf("limit and offset cannot be negative")
+	}
+
+	var conditions []string
+	var args []interface{}
+
+	if query.Profile != "" {
+		conditions = append(conditions, "profile = ?")
+		args = append(args, query.Profile)
+	}
+
+	if search := strings.TrimSpace(query.Search); search != "" && s.cipher != nil {
+		// Encrypted share fields are searched after decrypting them
+		fileIDs, err := s.searchEncryptedShares(search)
+		if err != nil {
+			return nil, err
+		}
+		condition := `(filename LIKE ? ESCAPE '\'`
+		args = append(args, "%"+escapeLike(search)+"%")
+		if len(fileIDs) > 0 {
+			condition += " OR id IN (" + placeholders(len(fileIDs)) + ")"
+			for _, id := range fileIDs {
+				args = append(args, id)
+			}
+		}
+		conditions = append(conditions, condition+")")
+	} else if search != "" {
+		pattern := "%" + escapeLike(search) + "%"
+		conditions = append(conditions, `(filename LIKE ? ESCAPE '\'
+			OR EXISTS (SELECT 1 FROM shares WHERE shares.file_id = files.id
+				AND (shares.recipients LIKE ? ESCAPE '\' OR shares.message LIKE ? ESCAPE '\')))`)
+		args = append(args, pattern, pattern, pattern)
+	}
+
+	// A range over the case-folded names, so the filename index can be used.
+	// NOCASE only folds ASCII letters, so the prefix is folded the same way.
+	if query.NamePrefix != "" {
+		lower := asciiLower(query.NamePrefix)
+		conditions = append(conditions, "filename COLLATE NOCASE >= ?")
+		args = append(args, lower)
+		if upper, ok := prefixUpperBound(lower); ok {
+			conditions = append(conditions, "filename COLLATE NOCASE < ?")
+			args = append(args, upper)
+		}
+	}
+
+	if query.Checksum != "" {
+		conditions = append(conditions, "checksum_sha256 = ?")
+		args = append(args, query.Checksum)
+	}
+	if query.S3Key != "" {
+		conditions = append(conditions, "s3_key = ?")
+		args = append(args, query.S3Key)
+	}
+
+	if len(query.Statuses) > 0 {
+		conditions = append(conditions, "status IN ("+placeholders(len(query.Statuses))+")")
+		for _, status := range query.Statuses {
+			args = append(args, string(status))
+		}
+	}
+	if len(query.ExcludedStatuses) > 0 {
+		conditions = append(conditions, "status NOT IN ("+placeholders(len(query.ExcludedStatuses))+")")
+		for _, status := range query.ExcludedStatuses {
+			args = append(args, string(status))
+		}
+	}
+
+	if query.MinSize > 0 {
+		conditions = append(conditions, "filesize >= ?")
+		args = append(args, query.MinSize)
+	}
+	if query.MaxSize > 0 {
+		conditions = append(conditions, "filesize <= ?")
+		args = append(args, query.MaxSize)
+	}
+
+	if !query.UploadedAfter.IsZero() {
+		conditions = append(conditions, "julianday(upload_date) >= julianday(?)")
+		args = append(args, query.UploadedAfter)
+	}
+	if !query.UploadedBefore.IsZero() {
+		conditions = append(conditions, "julianday(upload_date) < julianday(?)")
+		args = append(args, query.UploadedBefore)
+	}
+	if !query.ExpiresAfter.IsZero() {
+		conditions = append(conditions, "julianday(expiration_date) >= julianday(?)")
+		args = append(args, query.ExpiresAfter)
+	}
+	if !query.ExpiresBefore.IsZero() {
+		conditions = append(conditions, "julianday(expiration_date) < julianday(?)")
+		args = append(args, query.ExpiresBefore)
+	}
+
+	sortBy := query.SortBy
+	if sortBy == "" {
+		sortBy = SortByUploadDate
+	}
+	column, ok := fileSortColumns[sortBy]
+	if !ok {
+		return nil, fmt.Errorf("cannot sort files by %q", sortBy)
+	}
+	direction := "DESC"
+	if query.Ascending {
+		direction = "ASC"
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	sqlQuery := fmt.Sprintf(`
+		SELECT id, filename, filepath, filesize, upload_date, expiration_date, s3_key, status, profile, encryption_mode, checksum_sha256, etag, content_type, compression, stored_size, scan_verdict, scan_engine, created_at, updated_at
+		FROM files %s ORDER BY %s %s, id %s
+	`, where, column, direction, direction)
+
+	if query.Limit > 0 || query.Offset > 0 {
+		// SQLite needs a LIMIT to use OFFSET; -1 means no limit
+		limit := query.Limit
+		if limit == 0 {
+			limit = -1
+		}
+		sqlQuery += " LIMIT ? OFFSET ?"
+		args = append(args, limit, query.Offset)
+	}
+
+	return s.queryFiles(sqlQuery, args...)
+}
+
+// placeholders returns n comma-separated SQL parameter placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// prefixUpperBound returns the smallest case-folded string greater than every name starting with the folded prefix.
+// Returns false if there is none, when the prefix is all 0xff bytes.
+func prefixUpperBound(prefix string) (string, bool) {
+	bound := []byte(prefix)
+	for i := len(bound) - 1; i >= 0; i-- {
+		if bound[i] < 0xff {
+			bound[i]++
+			// Folded names have no uppercase letters, so after '@' comes '['
+			if bound[i] == 'A' {
+				bound[i] = '['
+			}
+			return string(bound[:i+1]), true
+		}
+	}
+	return "", false
+}
+
+// escapeLike escapes the LIKE wildcards in a search term, for use with ESCAPE '\'
+func escapeLike(term string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(term)
+}
+
+// queryFiles runs a file query and scans the resulting rows
+func (s *SQLiteDatabase) queryFiles(query string, args ...interface{}) ([]*FileMetadata, error) {
+	rows, err := s.conn.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list files: %w", err)
 	}
@@ -292,7 +504,7 @@ func (s *SQLiteDatabase) ListFiles() ([]*FileMetadata, error) {
 		err := rows.Scan(
 			&file.ID, &file.FileName, &file.FilePath, &file.FileSize,
 			&file.UploadDate, &file.ExpirationDate, &file.S3Key, &status,
-			&file.CreatedAt, &file.UpdatedAt,
+			&file.Profile, &file.EncryptionMode, &file.Checksum, &file.ETag, &file.ContentType, &file.Compression, &file.StoredSize, &file.ScanVerdict, &file.ScanEngine, &file.CreatedAt, &file.UpdatedAt,
 		)
 
 		if err != nil {
@@ -314,7 +526,7 @@ func (s *SQLiteDatabase) ListFiles() ([]*FileMetadata, error) {
 func (s *SQLiteDatabase) UpdateFileStatus(id string, status FileStatus) error {
 	query := `UPDATE files SET status = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
 
-	result, err := s.db.Exec(query, string(status), id)
+	result, err := s.conn.Exec(query, string(status), id)
 	if err != nil {
 		return fmt.Errorf("failed to update file status: %w", err)
 	}
@@ -335,7 +547,7 @@ func (s *SQLiteDatabase) UpdateFileStatus(id string, status FileStatus) error {
 func (s *SQLiteDatabase) UpdateFileExpiration(id string, expirationDate time.Time) error {
 	query := `UPDATE files SET expiration_date = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
 
-	result, err := s.db.Exec(query, expirationDate, id)
+	result, err := s.conn.Exec(query, expirationDate, id)
 	if err != nil {
 		return fmt.Errorf("failed to update file expiration: %w", err)
 	}
@@ -352,11 +564,116 @@ func (s *SQLiteDatabase) UpdateFileExpiration(id string, expirationDate time.Tim
 	return nil
 }
 
+// UpdateFileChecksum records the SHA-256 checksum and S3 ETag of an uploaded file
+func (s *SQLiteDatabase) UpdateFileChecksum(id string, checksum, etag string) error {
+	query := `UPDATE files SET checksum_sha256 = ?, etag = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+
+	result, err := s.conn.Exec(query, checksum, etag, id)
+	if err != nil {
+		return fmt.Errorf("failed to update file checksum: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("file not found: %s", id)
+	}
+
+	return nil
+}
+
+// UpdateFileContentType records the content type a file was uploaded with
+func (s *SQLiteDatabase) UpdateFileContentType(id string, contentType string) error {
+	query := `UPDATE files SET content_type = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+
+	result, err := s.conn.Exec(query, contentType, id)
+	if err != nil {
+		return fmt.Errorf("failed to update file content type: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("file not found: %s", id)
+	}
+
+	return nil
+}
+
+// UpdateFileStorage records how an uploaded file is stored in S3: its compression, if any, and its stored size
+func (s *SQLiteDatabase) UpdateFileStorage(id string, compression string, storedSize int64) error {
+	query := `UPDATE files SET compression = ?, stored_size = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+
+	result, err := s.conn.Exec(query, compression, storedSize, id)
+	if err != nil {
+		return fmt.Errorf("failed to update file storage: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("file not found: %s", id)
+	}
+
+	return nil
+}
+
+// UpdateFileScan records the verdict of the virus scan of a file and the scanner that gave it
+func (s *SQLiteDatabase) UpdateFileScan(id string, verdict, engine string) error {
+	query := `UPDATE files SET scan_verdict = ?, scan_engine = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+
+	result, err := s.conn.Exec(query, verdict, engine, id)
+	if err != nil {
+		return fmt.Errorf("failed to update file scan: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("file not found: %s", id)
+	}
+
+	return nil
+}
+
+// UpdateFileEncryptionMode records the encryption used for a file's upload
+func (s *SQLiteDatabase) UpdateFileEncryptionMode(id string, encryptionMode string) error {
+	query := `UPDATE files SET encryption_mode = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+
+	result, err := s.conn.Exec(query, encryptionMode, id)
+	if err != nil {
+		return fmt.Errorf("failed to update file encryption mode: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("file not found: %s", id)
+	}
+
+	return nil
+}
+
 // DeleteFile removes a file metadata record from the database
 func (s *SQLiteDatabase) DeleteFile(id string) error {
 	query := `DELETE FROM files WHERE id = ?`
 
-	result, err := s.db.Exec(query, id)
+	result, err := s.conn.Exec(query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete file: %w", err)
 	}
@@ -380,20 +697,23 @@ func (s *SQLiteDatabase) SaveShare(share *ShareRecord) error {
 	now := time.Now()
 	share.CreatedAt = now
 
-	// Convert recipients slice to JSON
-	recipientsJSON, err := json.Marshal(share.Recipients)
+	// Convert recipients slice to JSON, encrypting the sensitive fields if enabled.
+	// The link isn't stored; it is signed again from signed_at and url_expiration.
+	recipients, message, err := s.cipher.encryptShare(share)
 	if err != nil {
-		return fmt.Errorf("failed to marshal recipients: %w", err)
+		return err
 	}
 
 	query := `
-		INSERT INTO shares (id, file_id, recipients, message, shared_date, presigned_url, url_expiration, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO shares (id, file_id, recipients, message, shared_date, signed_at, url_expiration, short_link,
+			link_lifetime, max_downloads, download_name, inline_preview, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err = s.db.Exec(query,
-		share.ID, share.FileID, string(recipientsJSON), share.Message,
-		share.SharedDate, share.PresignedURL, share.URLExpiration, share.CreatedAt,
+	_, err = s.conn.Exec(query,
+		share.ID, share.FileID, recipients, message,
+		share.SharedDate, share.SignedAt, share.URLExpiration, share.ShortLink,
+		int64(share.LinkLifetime/time.Second), share.MaxDownloads, share.DownloadName, share.InlinePreview, share.CreatedAt,
 	)
 
 	if err != nil {
@@ -406,34 +726,157 @@ func (s *SQLiteDatabase) SaveShare(share *ShareRecord) error {
 // GetShareHistory retrieves all share records for a file
 func (s *SQLiteDatabase) GetShareHistory(fileID string) ([]*ShareRecord, error) {
 	query := `
-		SELECT id, file_id, recipients, message, shared_date, presigned_url, url_expiration, created_at
+		SELECT id, file_id, recipients, message, shared_date, signed_at, url_expiration, short_link, revoked_at, link_lifetime, max_downloads, download_name, inline_preview, created_at
 		FROM shares WHERE file_id = ? ORDER BY shared_date DESC
 	`
 
-	rows, err := s.db.Query(query, fileID)
+	rows, err := s.conn.Query(query, fileID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get share history: %w", err)
 	}
 	defer rows.Close()
 
+	return s.scanShares(rows)
+}
+
+// GetShare retrieves a share record by ID
+func (s *SQLiteDatabase) GetShare(id string) (*ShareRecord, error) {
+	query := `
+		SELECT id, file_id, recipients, message, shared_date, signed_at, url_expiration, short_link, revoked_at, link_lifetime, max_downloads, download_name, inline_preview, created_at
+		FROM shares WHERE id = ?
+	`
+
+	rows, err := s.conn.Query(query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get share: %w", err)
+	}
+	defer rows.Close()
+
+	shares, err := s.scanShares(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(shares) == 0 {
+		return nil, fmt.Errorf("share not found: %s", id)
+	}
+
+	return shares[0], nil
+}
+
+// ListSharesExpiringBefore retrieves share records whose URL expires before the given time, soonest first
+func (s *SQLiteDatabase) ListSharesExpiringBefore(before time.Time) ([]*ShareRecord, error) {
+	query := `
+		SELECT id, file_id, recipients, message, shared_date, signed_at, url_expiration, short_link, revoked_at, link_lifetime, max_downloads, download_name, inline_preview, created_at
+		FROM shares ORDER BY url_expiration ASC
+	`
+
+	rows, err := s.conn.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expiring shares: %w", err)
+	}
+	defer rows.Close()
+
+	shares, err := s.scanShares(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// Compare in Go, as stored timestamps keep the UTC offset they were saved with
+	var expiring []*ShareRecord
+	for _, share := range shares {
+		if share.URLExpiration.Before(before) {
+			expiring = append(expiring, share)
+		}
+	}
+
+	return expiring, nil
+}
+
+// ListRecentShares retrieves the most recent shares of the profile's active files, newest first
+func (s *SQLiteDatabase) ListRecentShares(profile string, limit int) ([]*ShareRecord, error) {
+	query := `
+		SELECT s.id, s.file_id, s.recipients, s.message, s.shared_date, s.signed_at, s.url_expiration, s.short_link, s.revoked_at, s.link_lifetime, s.max_downloads, s.download_name, s.inline_preview, s.created_at
+		FROM shares s JOIN files f ON f.id = s.file_id
+		WHERE f.profile = ? AND f.status = ?
+		ORDER BY s.shared_date DESC LIMIT ?
+	`
+
+	rows, err := s.conn.Query(query, profile, StatusActive, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recent shares: %w", err)
+	}
+	defer rows.Close()
+
+	return s.scanShares(rows)
+}
+
+// UpdateShareSigning records that a share's link is now signed as of signedAt and expires at urlExpiration
+func (s *SQLiteDatabase) UpdateShareSigning(id string, signedAt, urlExpiration time.Time) error {
+	query := `UPDATE shares SET signed_at = ?, url_expiration = ? WHERE id = ?`
+
+	result, err := s.conn.Exec(query, signedAt, urlExpiration, id)
+	if err != nil {
+		return fmt.Errorf("failed to update share signing: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("share not found: %s", id)
+	}
+
+	return nil
+}
+
+// RevokeShare records that a share's link was revoked at revokedAt. A share is only revoked once.
+func (s *SQLiteDatabase) RevokeShare(id string, revokedAt time.Time) error {
+	query := `UPDATE shares SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`
+
+	result, err := s.conn.Exec(query, revokedAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke share: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("share not found or already revoked: %s", id)
+	}
+
+	return nil
+}
+
+// scanShares reads share records from query rows, decrypting their sensitive fields
+func (s *SQLiteDatabase) scanShares(rows *sql.Rows) ([]*ShareRecord, error) {
 	var shares []*ShareRecord
 
 	for rows.Next() {
 		var share ShareRecord
 		var recipientsJSON string
+		var revokedAt sql.NullTime
+		var linkLifetime int64
 
 		err := rows.Scan(
 			&share.ID, &share.FileID, &recipientsJSON, &share.Message,
-			&share.SharedDate, &share.PresignedURL, &share.URLExpiration, &share.CreatedAt,
+			&share.SharedDate, &share.SignedAt, &share.URLExpiration, &share.ShortLink, &revokedAt,
+			&linkLifetime, &share.MaxDownloads, &share.DownloadName, &share.InlinePreview, &share.CreatedAt,
 		)
 
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan share row: %w", err)
 		}
+		share.RevokedAt = revokedAt.Time
+		share.LinkLifetime = time.Duration(linkLifetime) * time.Second
 
 		// Unmarshal recipients JSON
-		if err := json.Unmarshal([]byte(recipientsJSON), &share.Recipients); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal recipients: %w", err)
+		if err := s.cipher.decryptShare(&share, recipientsJSON); err != nil {
+			return nil, err
 		}
 
 		shares = append(shares, &share)
@@ -446,6 +889,205 @@ func (s *SQLiteDatabase) GetShareHistory(fileID string) ([]*ShareRecord, error)
 	return shares, nil
 }
 
+// Outbox operations
+
+// EnqueueOutbox appends an operation to the outbox and sets its ID
+func (s *SQLiteDatabase) EnqueueOutbox(entry *OutboxEntry) error {
+	entry.CreatedAt = time.Now()
+
+	// Queued shares hold recipients and messages
+	payload, err := s.cipher.encrypt("outbox.payload", entry.Payload)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO outbox (operation, file_id, payload, attempts, last_error, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := s.conn.Exec(query,
+		entry.Operation, entry.FileID, payload, entry.Attempts, entry.LastError, entry.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue outbox entry: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get outbox entry ID: %w", err)
+	}
+	entry.ID = id
+
+	return nil
+}
+
+// ListOutbox retrieves all queued operations in the order they were queued
+func (s *SQLiteDatabase) ListOutbox() ([]*OutboxEntry, error) {
+	query := `
+		SELECT id, operation, file_id, payload, attempts, last_error, created_at
+		FROM outbox ORDER BY id ASC
+	`
+
+	rows, err := s.conn.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list outbox: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*OutboxEntry
+
+	for rows.Next() {
+		var entry OutboxEntry
+
+		err := rows.Scan(
+			&entry.ID, &entry.Operation, &entry.FileID, &entry.Payload,
+			&entry.Attempts, &entry.LastError, &entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbox row: %w", err)
+		}
+
+		if entry.Payload, err = s.cipher.decrypt("outbox.payload", entry.Payload); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating outbox rows: %w", err)
+	}
+
+	return entries, nil
+}
+
+// RecordOutboxAttempt counts a failed replay of an outbox entry and keeps its error
+func (s *SQLiteDatabase) RecordOutboxAttempt(id int64, lastError string) error {
+	query := `UPDATE outbox SET attempts = attempts + 1, last_error = ? WHERE id = ?`
+
+	result, err := s.conn.Exec(query, lastError, id)
+	if err != nil {
+		return fmt.Errorf("failed to record outbox attempt: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("outbox entry not found: %d", id)
+	}
+
+	return nil
+}
+
+// DeleteOutboxEntry removes an operation from the outbox
+func (s *SQLiteDatabase) DeleteOutboxEntry(id int64) error {
+	query := `DELETE FROM outbox WHERE id = ?`
+
+	result, err := s.conn.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete outbox entry: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("outbox entry not found: %d", id)
+	}
+
+	return nil
+}
+
+// Notification history operations
+
+// SaveNotification records a sent notification and sets its ID
+func (s *SQLiteDatabase) SaveNotification(notification *NotificationRecord) error {
+	notification.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO notifications (event, file_id, title, content, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	result, err := s.conn.Exec(query,
+		notification.Event, notification.FileID, notification.Title, notification.Content, notification.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save notification: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get notification ID: %w", err)
+	}
+	notification.ID = id
+
+	return nil
+}
+
+// ListNotifications retrieves the most recent notifications, newest first
+func (s *SQLiteDatabase) ListNotifications(limit int) ([]*NotificationRecord, error) {
+	query := `
+		SELECT id, event, file_id, title, content, created_at
+		FROM notifications ORDER BY id DESC LIMIT ?
+	`
+
+	rows, err := s.conn.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var notifications []*NotificationRecord
+
+	for rows.Next() {
+		var notification NotificationRecord
+
+		err := rows.Scan(
+			&notification.ID, &notification.Event, &notification.FileID,
+			&notification.Title, &notification.Content, &notification.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification row: %w", err)
+		}
+
+		notifications = append(notifications, &notification)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notification rows: %w", err)
+	}
+
+	return notifications, nil
+}
+
+// HasNotification reports whether a notification for the event and file has been sent
+func (s *SQLiteDatabase) HasNotification(event, fileID string) (bool, error) {
+	query := `SELECT COUNT(*) FROM notifications WHERE event = ? AND file_id = ?`
+
+	var count int
+	if err := s.conn.QueryRow(query, event, fileID).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check notification history: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+// ClearNotifications removes all notification history
+func (s *SQLiteDatabase) ClearNotifications() error {
+	if _, err := s.conn.Exec(`DELETE FROM notifications`); err != nil {
+		return fmt.Errorf("failed to clear notifications: %w", err)
+	}
+
+	return nil
+}
+
 // Configuration operations
 
 // SaveConfig saves a configuration key-value pair
@@ -455,7 +1097,7 @@ func (s *SQLiteDatabase) SaveConfig(key, value string) error {
 		VALUES (?, ?, CURRENT_TIMESTAMP)
 	`
 
-	_, err := s.db.Exec(query, key, value)
+	_, err := s.conn.Exec(query, key, value)
 	if err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
@@ -468,7 +1110,7 @@ func (s *SQLiteDatabase) GetConfig(key string) (string, error) {
 	query := `SELECT value FROM app_config WHERE key = ?`
 
 	var value string
-	err := s.db.QueryRow(query, key).Scan(&value)
+	err := s.conn.QueryRow(query, key).Scan(&value)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return "", fmt.Errorf("config key not found: %s", key)
@@ -479,8 +1121,35 @@ func (s *SQLiteDatabase) GetConfig(key string) (string, error) {
 	return value, nil
 }
 
+// WithTransaction runs fn with a Database whose operations are all part of one transaction.
+// The transaction commits if fn returns nil and rolls back otherwise. Inside a transaction, fn joins it.
+func (s *SQLiteDatabase) WithTransaction(fn func(tx Database) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return errors.WrapError(err, errors.ErrDatabaseError, "failed to begin transaction")
+	}
+	// Rolls back if fn fails or panics; a no-op once committed
+	defer tx.Rollback()
+
+	if err := fn(&SQLiteDatabase{db: s.db, conn: tx, tx: tx, path: s.path, logger: s.logger, cipher: s.cipher}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.WrapError(err, errors.ErrDatabaseError, "failed to commit transaction")
+	}
+	return nil
+}
+
 // Close closes the database connection
 func (s *SQLiteDatabase) Close() error {
+	if s.tx != nil {
+		return fmt.Errorf("cannot close the database from inside a transaction")
+	}
 	if s.db != nil {
 		return s.db.Close()
 	}
@@ -1,8 +1,11 @@
 package storage
 
 import (
+	"database/sql"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -147,6 +150,112 @@ func TestSQLiteDatabase_ListFiles(t *testing.T) {
 	assert.Equal(t, "test-id-3", retrievedFiles[1].ID)
 }
 
+func TestSQLiteDatabase_ListFilesByProfile(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+
+	files := []*FileMetadata{
+		{ID: "default-file", Profile: ""},
+		{ID: "client-a-file", Profile: "client-a"},
+		{ID: "client-b-file", Profile: "client-b"},
+	}
+	for _, file := range files {
+		file.FileName = file.ID + ".txt"
+		file.FilePath = "/tmp/" + file.FileName
+		file.FileSize = 100
+		file.UploadDate = time.Now()
+		file.ExpirationDate = time.Now().Add(time.Hour)
+		file.S3Key = "uploads/" + file.FileName
+		file.Status = StatusActive
+		require.NoError(t, db.SaveFile(file))
+	}
+
+	// Files saved without a profile belong to the default profile
+	defaultFiles, err := db.ListFilesByProfile(DefaultProfile)
+	require.NoError(t, err)
+	require.Len(t, defaultFiles, 1)
+	assert.Equal(t, "default-file", defaultFiles[0].ID)
+	assert.Equal(t, DefaultProfile, defaultFiles[0].Profile)
+
+	clientFiles, err := db.ListFilesByProfile("client-a")
+	require.NoError(t, err)
+	require.Len(t, clientFiles, 1)
+	assert.Equal(t, "client-a-file", clientFiles[0].ID)
+
+	retrieved, err := db.GetFile("client-b-file")
+	require.NoError(t, err)
+	assert.Equal(t, "client-b", retrieved.Profile)
+}
+
+func TestNewSQLiteDatabase_UpgradesLegacySchema(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "legacy.db")
+
+	// Create a database with the schema used before profiles existed
+	legacy, err := sql.Open("sqlite3", dbPath)
+	require.NoError(t, err)
+	_, err = legacy.Exec(`
+		CREATE TABLE files (
+			id TEXT PRIMARY KEY,
+			filename TEXT NOT NULL,
+			filepath TEXT NOT NULL,
+			filesize INTEGER NOT NULL,
+			upload_date DATETIME NOT NULL,
+			expiration_date DATETIME NOT NULL,
+			s3_key TEXT NOT NULL,
+			status TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		INSERT INTO files (id, filename, filepath, filesize, upload_date, expiration_date, s3_key, status)
+		VALUES ('legacy-file', 'old.txt', '/tmp/old.txt', 10, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, 'uploads/old.txt', 'active');
+	`)
+	require.NoError(t, err)
+	require.NoError(t, legacy.Close())
+
+	db, err := NewSQLiteDatabase(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	files, err := db.ListFilesByProfile(DefaultProfile)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "legacy-file", files[0].ID)
+	assert.Equal(t, DefaultEncryptionMode, files[0].EncryptionMode)
+	assert.Empty(t, files[0].Checksum)
+	assert.Empty(t, files[0].ETag)
+}
+
+func TestSQLiteDatabase_SaveFile_EncryptionMode(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+
+	newFile := func(id, mode string) *FileMetadata {
+		return &FileMetadata{
+			ID:             id,
+			FileName:       id + ".txt",
+			FilePath:       "/tmp/" + id + ".txt",
+			FileSize:       1024,
+			UploadDate:     time.Now(),
+			ExpirationDate: time.Now().Add(24 * time.Hour),
+			S3Key:          "uploads/" + id + ".txt",
+			Status:         StatusActive,
+			EncryptionMode: mode,
+		}
+	}
+
+	require.NoError(t, db.SaveFile(newFile("sse-c-file", "SSE-C")))
+	require.NoError(t, db.SaveFile(newFile("unset-file", "")))
+
+	saved, err := db.GetFile("sse-c-file")
+	require.NoError(t, err)
+	assert.Equal(t, "SSE-C", saved.EncryptionMode)
+
+	// Records without a mode are stored with the default
+	saved, err = db.GetFile("unset-file")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultEncryptionMode, saved.EncryptionMode)
+}
+
 func TestSQLiteDatabase_UpdateFileStatus(t *testing.T) {
 	db, _ := createTempDatabase(t)
 	defer db.Close()
@@ -230,6 +339,189 @@ func TestSQLiteDatabase_UpdateFileExpiration_NotFound(t *testing.T) {
 	assert.Contains(t, err.Error(), "file not found")
 }
 
+func TestSQLiteDatabase_UpdateFileChecksum(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+
+	file := &FileMetadata{
+		ID:             "test-id-checksum",
+		FileName:       "test-checksum.txt",
+		FilePath:       "/tmp/test-checksum.txt",
+		FileSize:       4,
+		UploadDate:     time.Now(),
+		ExpirationDate: time.Now().Add(24 * time.Hour),
+		S3Key:          "uploads/test-checksum.txt",
+		Status:         StatusUploading,
+	}
+	require.NoError(t, db.SaveFile(file))
+
+	checksum := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	err := db.UpdateFileChecksum("test-id-checksum", checksum, "098f6bcd4621d373cade4e832627b4f6")
+	assert.NoError(t, err)
+
+	retrievedFile, err := db.GetFile("test-id-checksum")
+	require.NoError(t, err)
+	assert.Equal(t, checksum, retrievedFile.Checksum)
+	assert.Equal(t, "098f6bcd4621d373cade4e832627b4f6", retrievedFile.ETag)
+
+	files, err := db.ListFiles()
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, checksum, files[0].Checksum)
+}
+
+func TestSQLiteDatabase_UpdateFileChecksum_NotFound(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+
+	err := db.UpdateFileChecksum("non-existent-id", "abc", "def")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "file not found")
+}
+
+func TestSQLiteDatabase_UpdateFileContentType(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+
+	file := &FileMetadata{
+		ID:             "test-id-content-type",
+		FileName:       "notes.md",
+		FilePath:       "/tmp/notes.md",
+		FileSize:       4,
+		UploadDate:     time.Now(),
+		ExpirationDate: time.Now().Add(24 * time.Hour),
+		S3Key:          "uploads/notes.md",
+		Status:         StatusUploading,
+	}
+	require.NoError(t, db.SaveFile(file))
+
+	retrievedFile, err := db.GetFile("test-id-content-type")
+	require.NoError(t, err)
+	assert.Empty(t, retrievedFile.ContentType)
+
+	require.NoError(t, db.UpdateFileContentType("test-id-content-type", "text/markdown"))
+
+	retrievedFile, err = db.GetFile("test-id-content-type")
+	require.NoError(t, err)
+	assert.Equal(t, "text/markdown", retrievedFile.ContentType)
+
+	files, err := db.QueryFiles(FileQuery{})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "text/markdown", files[0].ContentType)
+
+	err = db.UpdateFileContentType("non-existent-id", "text/plain")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "file not found")
+}
+
+func TestSQLiteDatabase_UpdateFileStorage(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+
+	file := &FileMetadata{
+		ID:             "test-id-storage",
+		FileName:       "server.log",
+		FilePath:       "/tmp/server.log",
+		FileSize:       10000,
+		UploadDate:     time.Now(),
+		ExpirationDate: time.Now().Add(24 * time.Hour),
+		S3Key:          "uploads/server.log",
+		Status:         StatusUploading,
+	}
+	require.NoError(t, db.SaveFile(file))
+
+	retrievedFile, err := db.GetFile("test-id-storage")
+	require.NoError(t, err)
+	assert.Empty(t, retrievedFile.Compression)
+	assert.Zero(t, retrievedFile.StoredSize)
+
+	require.NoError(t, db.UpdateFileStorage("test-id-storage", "zstd", 1200))
+
+	retrievedFile, err = db.GetFile("test-id-storage")
+	require.NoError(t, err)
+	assert.Equal(t, "zstd", retrievedFile.Compression)
+	assert.Equal(t, int64(1200), retrievedFile.StoredSize)
+	assert.Equal(t, int64(10000), retrievedFile.FileSize)
+
+	files, err := db.QueryFiles(FileQuery{})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "zstd", files[0].Compression)
+	assert.Equal(t, int64(1200), files[0].StoredSize)
+
+	err = db.UpdateFileStorage("non-existent-id", "", 10)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "file not found")
+}
+
+func TestSQLiteDatabase_UpdateFileScan(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+
+	file := &FileMetadata{
+		ID:             "test-id-scan",
+		FileName:       "report.pdf",
+		FilePath:       "/tmp/report.pdf",
+		FileSize:       2048,
+		UploadDate:     time.Now(),
+		ExpirationDate: time.Now().Add(24 * time.Hour),
+		S3Key:          "uploads/report.pdf",
+		Status:         StatusUploading,
+	}
+	require.NoError(t, db.SaveFile(file))
+
+	retrievedFile, err := db.GetFile("test-id-scan")
+	require.NoError(t, err)
+	assert.Empty(t, retrievedFile.ScanVerdict)
+	assert.Empty(t, retrievedFile.ScanEngine)
+
+	require.NoError(t, db.UpdateFileScan("test-id-scan", "clean", "ClamAV 1.0.3/27100"))
+
+	retrievedFile, err = db.GetFile("test-id-scan")
+	require.NoError(t, err)
+	assert.Equal(t, "clean", retrievedFile.ScanVerdict)
+	assert.Equal(t, "ClamAV 1.0.3/27100", retrievedFile.ScanEngine)
+
+	files, err := db.QueryFiles(FileQuery{})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "clean", files[0].ScanVerdict)
+	assert.Equal(t, "ClamAV 1.0.3/27100", files[0].ScanEngine)
+
+	err = db.UpdateFileScan("non-existent-id", "clean", "ClamAV")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "file not found")
+}
+
+func TestSQLiteDatabase_UpdateFileEncryptionMode(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+
+	file := &FileMetadata{
+		ID:             "test-id-encryption",
+		FileName:       "test-encryption.txt",
+		FilePath:       "/tmp/test-encryption.txt",
+		FileSize:       1024,
+		UploadDate:     time.Now(),
+		ExpirationDate: time.Now().Add(24 * time.Hour),
+		S3Key:          "uploads/test-encryption.txt",
+		Status:         StatusPending,
+	}
+	require.NoError(t, db.SaveFile(file))
+
+	err := db.UpdateFileEncryptionMode("test-id-encryption", "SSE-KMS")
+	assert.NoError(t, err)
+
+	retrievedFile, err := db.GetFile("test-id-encryption")
+	require.NoError(t, err)
+	assert.Equal(t, "SSE-KMS", retrievedFile.EncryptionMode)
+
+	err = db.UpdateFileEncryptionMode("non-existent-id", "SSE-KMS")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "file not found")
+}
+
 func TestSQLiteDatabase_DeleteFile(t *testing.T) {
 	db, _ := createTempDatabase(t)
 	defer db.Close()
@@ -294,8 +586,15 @@ func TestSQLiteDatabase_SaveShare(t *testing.T) {
 		Recipients:    []string{"user1@example.com", "user2@example.com"},
 		Message:       "Please find the attached file",
 		SharedDate:    time.Now(),
+		SignedAt:      time.Now().Truncate(time.Second),
 		PresignedURL:  "https://s3.amazonaws.com/bucket/key?signature=xyz",
 		URLExpiration: time.Now().Add(7 * 24 * time.Hour),
+		ShareOptions: ShareOptions{
+			LinkLifetime:  7 * 24 * time.Hour,
+			MaxDownloads:  2,
+			DownloadName:  "Quarterly report.txt",
+			InlinePreview: true,
+		},
 	}
 
 	err = db.SaveShare(share)
@@ -303,6 +602,17 @@ func TestSQLiteDatabase_SaveShare(t *testing.T) {
 
 	// Verify the share was saved with timestamp
 	assert.False(t, share.CreatedAt.IsZero(), "CreatedAt should be set")
+
+	// The link is signed again when needed, never stored
+	saved, err := db.GetShare("share-id-1")
+	require.NoError(t, err)
+	assert.Empty(t, saved.PresignedURL)
+	assert.True(t, share.SignedAt.Equal(saved.SignedAt))
+	assert.Equal(t, share.Recipients, saved.Recipients)
+	assert.Equal(t, share.ShareOptions, saved.ShareOptions)
+
+	_, err = db.GetShare("missing-share")
+	assert.Error(t, err)
 }
 
 func TestSQLiteDatabase_GetShareHistory(t *testing.T) {
@@ -332,7 +642,7 @@ func TestSQLiteDatabase_GetShareHistory(t *testing.T) {
 			Recipients:    []string{"user1@example.com"},
 			Message:       "First share",
 			SharedDate:    time.Now().Add(-2 * time.Hour),
-			PresignedURL:  "https://s3.amazonaws.com/bucket/key1?signature=abc",
+			SignedAt:      time.Now(),
 			URLExpiration: time.Now().Add(5 * 24 * time.Hour),
 		},
 		{
@@ -341,7 +651,7 @@ func TestSQLiteDatabase_GetShareHistory(t *testing.T) {
 			Recipients:    []string{"user2@example.com", "user3@example.com"},
 			Message:       "Second share",
 			SharedDate:    time.Now().Add(-1 * time.Hour),
-			PresignedURL:  "https://s3.amazonaws.com/bucket/key2?signature=def",
+			SignedAt:      time.Now(),
 			URLExpiration: time.Now().Add(6 * 24 * time.Hour),
 		},
 	}
@@ -365,6 +675,444 @@ func TestSQLiteDatabase_GetShareHistory(t *testing.T) {
 	assert.Equal(t, []string{"user1@example.com"}, retrievedShares[1].Recipients)
 }
 
+func TestSQLiteDatabase_ListSharesExpiringBefore(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+
+	file := &FileMetadata{
+		ID:             "test-file-id-4",
+		FileName:       "test4.txt",
+		FilePath:       "/tmp/test4.txt",
+		FileSize:       1024,
+		UploadDate:     time.Now(),
+		ExpirationDate: time.Now().Add(7 * 24 * time.Hour),
+		S3Key:          "uploads/test-file-id-4/test4.txt",
+		Status:         StatusActive,
+	}
+	require.NoError(t, db.SaveFile(file))
+
+	for id, expiresIn := range map[string]time.Duration{
+		"share-later": 3 * time.Hour,
+		"share-soon":  30 * time.Minute,
+		"share-far":   5 * 24 * time.Hour,
+	} {
+		require.NoError(t, db.SaveShare(&ShareRecord{
+			ID:            id,
+			FileID:        file.ID,
+			Recipients:    []string{"user@example.com"},
+			SharedDate:    time.Now(),
+			PresignedURL:  "https://s3.amazonaws.com/bucket/key?signature=" + id,
+			URLExpiration: time.Now().Add(expiresIn),
+		}))
+	}
+
+	shares, err := db.ListSharesExpiringBefore(time.Now().Add(6 * time.Hour))
+	require.NoError(t, err)
+	require.Len(t, shares, 2)
+	assert.Equal(t, "share-soon", shares[0].ID)
+	assert.Equal(t, "share-later", shares[1].ID)
+}
+
+func TestSQLiteDatabase_QueryFiles(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+
+	now := time.Now()
+	losAngeles := time.FixedZone("PDT", -7*60*60)
+	saveFile := func(id, name string, size int64, uploaded time.Time, status FileStatus) {
+		require.NoError(t, db.SaveFile(&FileMetadata{
+			ID:             id,
+			FileName:       name,
+			FilePath:       "/tmp/" + name,
+			FileSize:       size,
+			UploadDate:     uploaded,
+			ExpirationDate: uploaded.Add(7 * 24 * time.Hour),
+			S3Key:          "uploads/" + id,
+			Status:         status,
+		}))
+	}
+	saveFile("report", "Quarterly Report.pdf", 5*1024*1024, now.Add(-2*time.Hour), StatusActive)
+	saveFile("photo", "holiday_photo.jpg", 200*1024*1024, now.Add(-3*24*time.Hour).In(losAngeles), StatusExpired)
+	saveFile("notes", "notes.txt", 1024, now.Add(-40*24*time.Hour).UTC(), StatusError)
+	saveFile("budget", "budget 100%.xlsx", 20*1024, now.Add(-time.Hour), StatusActive)
+
+	require.NoError(t, db.SaveShare(&ShareRecord{
+		ID:            "share-notes",
+		FileID:        "notes",
+		Recipients:    []string{"alice@example.com"},
+		Message:       "Minutes from the offsite",
+		SharedDate:    now,
+		SignedAt:      time.Now(),
+		URLExpiration: now.Add(time.Hour),
+	}))
+
+	ids := func(query FileQuery) []string {
+		files, err := db.QueryFiles(query)
+		require.NoError(t, err)
+		ids := []string{}
+		for _, file := range files {
+			ids = append(ids, file.ID)
+		}
+		return ids
+	}
+
+	// By default newest uploads come first, whatever offset they were saved with
+	assert.Equal(t, []string{"budget", "report", "photo", "notes"}, ids(FileQuery{}))
+
+	// Search matches file names, recipients and messages, ignoring case
+	assert.Equal(t, []string{"report"}, ids(FileQuery{Search: "quarterly"}))
+	assert.Equal(t, []string{"notes"}, ids(FileQuery{Search: "ALICE@"}))
+	assert.Equal(t, []string{"notes"}, ids(FileQuery{Search: "offsite"}))
+
+	// Wildcards in the search are matched literally
+	assert.Equal(t, []string{"budget"}, ids(FileQuery{Search: "100%"}))
+	assert.Equal(t, []string{"photo"}, ids(FileQuery{Search: "y_p"}))
+
+	assert.Equal(t, []string{"photo", "notes"}, ids(FileQuery{Statuses: []FileStatus{StatusExpired, StatusError}}))
+	assert.Equal(t, []string{"photo"}, ids(FileQuery{MinSize: 100 * 1024 * 1024}))
+	assert.Equal(t, []string{"budget", "notes"}, ids(FileQuery{MaxSize: 1024 * 1024}))
+	assert.Equal(t, []string{"budget", "report", "photo"}, ids(FileQuery{UploadedAfter: now.Add(-7 * 24 * time.Hour)}))
+	assert.Equal(t, []string{"photo", "notes"}, ids(FileQuery{UploadedBefore: now.Add(-24 * time.Hour)}))
+
+	// Filters combine
+	assert.Equal(t, []string{"report"}, ids(FileQuery{Statuses: []FileStatus{StatusActive}, MinSize: 1024 * 1024}))
+
+	assert.Equal(t, []string{"budget", "photo", "notes", "report"}, ids(FileQuery{SortBy: SortByFileName, Ascending: true}))
+	assert.Equal(t, []string{"photo", "report", "budget", "notes"}, ids(FileQuery{SortBy: SortByFileSize}))
+	assert.Equal(t, []string{"notes", "photo", "report", "budget"}, ids(FileQuery{SortBy: SortByExpiration, Ascending: true}))
+
+	// Other profiles' files are not included
+	assert.Empty(t, ids(FileQuery{Profile: "work"}))
+
+	_, err := db.QueryFiles(FileQuery{SortBy: "s3_key; DROP TABLE files"})
+	assert.Error(t, err)
+}
+
+func TestSQLiteDatabase_QueryFiles_UsesIndexes(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+
+	plan := func(query string, args ...interface{}) string {
+		rows, err := db.db.Query("EXPLAIN QUERY PLAN "+query, args...)
+		require.NoError(t, err)
+		defer rows.Close()
+
+		var details []string
+		for rows.Next() {
+			var id, parent, unused int
+			var detail string
+			require.NoError(t, rows.Scan(&id, &parent, &unused, &detail))
+			details = append(details, detail)
+		}
+		return strings.Join(details, "; ")
+	}
+
+	assert.Contains(t, plan(`SELECT id FROM files WHERE profile = ? AND status IN (?, ?)`, DefaultProfile, "active", "error"),
+		"idx_files_profile_status")
+	assert.Contains(t, plan(`SELECT id FROM files WHERE profile = ? AND julianday(upload_date) >= julianday(?)`, DefaultProfile, time.Now()),
+		"idx_files_profile_uploaded")
+	assert.Contains(t, plan(`SELECT id FROM files WHERE profile = ? ORDER BY filesize DESC`, DefaultProfile),
+		"idx_files_profile_filesize")
+	assert.Contains(t, plan(`SELECT id FROM files WHERE profile = ? AND filename COLLATE NOCASE >= ? AND filename COLLATE NOCASE < ?`, DefaultProfile, "rep", "req"),
+		"idx_files_profile_filename")
+	assert.Contains(t, plan(`SELECT id FROM files WHERE julianday(expiration_date) < julianday(?) AND status NOT IN (?, ?)`, time.Now(), "expired", "deleted"),
+		"idx_files_expires")
+	assert.Contains(t, plan(`SELECT id FROM files WHERE profile = ? AND checksum_sha256 = ? AND status IN (?)`, DefaultProfile, "abc", "active"),
+		"idx_files_profile_checksum")
+	assert.Contains(t, plan(`SELECT id FROM files WHERE s3_key = ? AND status NOT IN (?, ?)`, "uploads/a.txt", "expired", "deleted"),
+		"idx_files_s3_key")
+}
+
+func TestSQLiteDatabase_QueryFiles_Paging(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		profile := DefaultProfile
+		if i%2 == 1 {
+			profile = "work"
+		}
+		require.NoError(t, db.SaveFile(&FileMetadata{
+			ID:             fmt.Sprintf("file-%d", i),
+			FileName:       fmt.Sprintf("file-%d.txt", i),
+			FilePath:       "/tmp/file.txt",
+			FileSize:       1024,
+			UploadDate:     now.Add(time.Duration(i) * time.Minute),
+			ExpirationDate: now.Add(time.Duration(i-2) * time.Hour),
+			S3Key:          fmt.Sprintf("uploads/file-%d", i),
+			Status:         StatusActive,
+			Profile:        profile,
+		}))
+	}
+
+	ids := func(query FileQuery) []string {
+		files, err := db.QueryFiles(query)
+		require.NoError(t, err)
+		ids := []string{}
+		for _, file := range files {
+			ids = append(ids, file.ID)
+		}
+		return ids
+	}
+
+	// Without a profile every profile's files are included
+	assert.Equal(t, []string{"file-4", "file-3", "file-2", "file-1", "file-0"}, ids(FileQuery{}))
+	assert.Equal(t, []string{"file-3", "file-1"}, ids(FileQuery{Profile: "work"}))
+
+	assert.Equal(t, []string{"file-4", "file-3"}, ids(FileQuery{Limit: 2}))
+	assert.Equal(t, []string{"file-2", "file-1"}, ids(FileQuery{Limit: 2, Offset: 2}))
+	assert.Equal(t, []string{"file-1", "file-0"}, ids(FileQuery{Offset: 3}))
+	assert.Empty(t, ids(FileQuery{Limit: 2, Offset: 10}))
+
+	assert.Equal(t, []string{"file-1", "file-0"}, ids(FileQuery{ExpiresBefore: now}))
+	assert.Equal(t, []string{"file-4", "file-3", "file-2"}, ids(FileQuery{ExpiresAfter: now}))
+	assert.Equal(t, []string{"file-2", "file-1"}, ids(FileQuery{ExpiresAfter: now.Add(-90 * time.Minute), ExpiresBefore: now.Add(30 * time.Minute)}))
+
+	_, err := db.QueryFiles(FileQuery{Limit: -1})
+	assert.Error(t, err)
+	_, err = db.QueryFiles(FileQuery{Offset: -1})
+	assert.Error(t, err)
+}
+
+func TestSQLiteDatabase_QueryFiles_NamePrefixAndExclusions(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+
+	names := map[string]FileStatus{
+		"Report.pdf":    StatusActive,
+		"report-2.pdf":  StatusExpired,
+		"reports.zip":   StatusDeleted,
+		"repo.tar":      StatusError,
+		"@home.txt":     StatusActive,
+		"[draft].txt":   StatusActive,
+		"Zebra.png":     StatusActive,
+		"zoo.png":       StatusActive,
+		"{braces}.json": StatusActive,
+	}
+	for name, status := range names {
+		require.NoError(t, db.SaveFile(&FileMetadata{
+			ID:             name,
+			FileName:       name,
+			FilePath:       "/tmp/" + name,
+			FileSize:       1024,
+			UploadDate:     time.Now(),
+			ExpirationDate: time.Now().Add(time.Hour),
+			S3Key:          "uploads/" + name,
+			Status:         status,
+		}))
+	}
+
+	ids := func(query FileQuery) []string {
+		query.SortBy = SortByFileName
+		query.Ascending = true
+		files, err := db.QueryFiles(query)
+		require.NoError(t, err)
+		ids := []string{}
+		for _, file := range files {
+			ids = append(ids, file.ID)
+		}
+		return ids
+	}
+
+	// Prefixes ignore case and match only the start of the name
+	assert.Equal(t, []string{"report-2.pdf", "Report.pdf", "reports.zip"}, ids(FileQuery{NamePrefix: "REPORT"}))
+	assert.Equal(t, []string{"repo.tar", "report-2.pdf", "Report.pdf", "reports.zip"}, ids(FileQuery{NamePrefix: "rep"}))
+	assert.Equal(t, []string{"Zebra.png", "zoo.png"}, ids(FileQuery{NamePrefix: "z"}))
+	assert.Equal(t, []string{"@home.txt"}, ids(FileQuery{NamePrefix: "@"}))
+	assert.Empty(t, ids(FileQuery{NamePrefix: "ort"}))
+
+	assert.Equal(t, []string{"repo.tar", "Report.pdf"},
+		ids(FileQuery{NamePrefix: "rep", ExcludedStatuses: []FileStatus{StatusExpired, StatusDeleted}}))
+}
+
+func TestSQLiteDatabase_QueryFiles_ChecksumAndS3Key(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+
+	files := []struct {
+		id, checksum, s3Key string
+	}{
+		{"original", "aaaa", "uploads/shared.bin"},
+		{"reused", "aaaa", "uploads/shared.bin"},
+		{"other", "bbbb", "uploads/other.bin"},
+	}
+	for _, file := range files {
+		require.NoError(t, db.SaveFile(&FileMetadata{
+			ID:             file.id,
+			FileName:       "data.bin",
+			FilePath:       "/tmp/data.bin",
+			FileSize:       1024,
+			UploadDate:     time.Now(),
+			ExpirationDate: time.Now().Add(time.Hour),
+			S3Key:          file.s3Key,
+			Status:         StatusActive,
+		}))
+		require.NoError(t, db.UpdateFileChecksum(file.id, file.checksum, "etag"))
+	}
+
+	ids := func(query FileQuery) []string {
+		query.SortBy = SortByFileName
+		found, err := db.QueryFiles(query)
+		require.NoError(t, err)
+		ids := []string{}
+		for _, file := range found {
+			ids = append(ids, file.ID)
+		}
+		return ids
+	}
+
+	assert.ElementsMatch(t, []string{"original", "reused"}, ids(FileQuery{Checksum: "aaaa"}))
+	assert.ElementsMatch(t, []string{"original", "reused"}, ids(FileQuery{S3Key: "uploads/shared.bin"}))
+	assert.Equal(t, []string{"other"}, ids(FileQuery{Checksum: "bbbb", S3Key: "uploads/other.bin"}))
+	assert.Empty(t, ids(FileQuery{Checksum: "aaaa", S3Key: "uploads/other.bin"}))
+	assert.Empty(t, ids(FileQuery{Checksum: "aaaa", Profile: "work"}))
+}
+
+func TestSQLiteDatabase_ListRecentShares(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+
+	saveFile := func(id, profile string, status FileStatus) {
+		require.NoError(t, db.SaveFile(&FileMetadata{
+			ID:             id,
+			FileName:       id + ".txt",
+			FilePath:       "/tmp/" + id + ".txt",
+			FileSize:       1024,
+			UploadDate:     time.Now(),
+			ExpirationDate: time.Now().Add(7 * 24 * time.Hour),
+			S3Key:          "uploads/" + id,
+			Status:         status,
+			Profile:        profile,
+		}))
+	}
+	saveFile("active-file", DefaultProfile, StatusActive)
+	saveFile("expired-file", DefaultProfile, StatusExpired)
+	saveFile("work-file", "work", StatusActive)
+
+	saveShare := func(id, fileID string, sharedAgo time.Duration) {
+		require.NoError(t, db.SaveShare(&ShareRecord{
+			ID:            id,
+			FileID:        fileID,
+			Recipients:    []string{"user@example.com"},
+			SharedDate:    time.Now().Add(-sharedAgo),
+			PresignedURL:  "https://s3.amazonaws.com/bucket/key?signature=" + id,
+			URLExpiration: time.Now().Add(time.Hour),
+		}))
+	}
+	saveShare("share-old", "active-file", 3*time.Hour)
+	saveShare("share-new", "active-file", time.Minute)
+	saveShare("share-middle", "active-file", time.Hour)
+	saveShare("share-expired-file", "expired-file", time.Minute)
+	saveShare("share-work", "work-file", time.Minute)
+
+	shares, err := db.ListRecentShares(DefaultProfile, 10)
+	require.NoError(t, err)
+	require.Len(t, shares, 3)
+	assert.Equal(t, "share-new", shares[0].ID)
+	assert.Equal(t, "share-middle", shares[1].ID)
+	assert.Equal(t, "share-old", shares[2].ID)
+	assert.Equal(t, []string{"user@example.com"}, shares[0].Recipients)
+
+	shares, err = db.ListRecentShares(DefaultProfile, 2)
+	require.NoError(t, err)
+	assert.Len(t, shares, 2)
+
+	shares, err = db.ListRecentShares("work", 10)
+	require.NoError(t, err)
+	require.Len(t, shares, 1)
+	assert.Equal(t, "share-work", shares[0].ID)
+}
+
+func TestSQLiteDatabase_UpdateShareSigning(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+
+	file := &FileMetadata{
+		ID:             "test-file-id-5",
+		FileName:       "test5.txt",
+		FilePath:       "/tmp/test5.txt",
+		FileSize:       1024,
+		UploadDate:     time.Now(),
+		ExpirationDate: time.Now().Add(7 * 24 * time.Hour),
+		S3Key:          "uploads/test-file-id-5/test5.txt",
+		Status:         StatusActive,
+	}
+	require.NoError(t, db.SaveFile(file))
+
+	require.NoError(t, db.SaveShare(&ShareRecord{
+		ID:            "share-renew",
+		FileID:        file.ID,
+		Recipients:    []string{"user@example.com"},
+		SharedDate:    time.Now(),
+		SignedAt:      time.Now(),
+		URLExpiration: time.Now().Add(time.Hour),
+	}))
+
+	renewedAt := time.Now().Add(30 * time.Minute)
+	renewedUntil := time.Now().Add(24 * time.Hour)
+	err := db.UpdateShareSigning("share-renew", renewedAt, renewedUntil)
+	require.NoError(t, err)
+
+	shares, err := db.GetShareHistory(file.ID)
+	require.NoError(t, err)
+	require.Len(t, shares, 1)
+	assert.WithinDuration(t, renewedAt, shares[0].SignedAt, time.Second)
+	assert.WithinDuration(t, renewedUntil, shares[0].URLExpiration, time.Second)
+
+	err = db.UpdateShareSigning("missing-share", renewedAt, renewedUntil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "share not found")
+}
+
+func TestSQLiteDatabase_RevokeShare(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+
+	file := &FileMetadata{
+		ID:             "test-file-id-6",
+		FileName:       "test6.txt",
+		FilePath:       "/tmp/test6.txt",
+		FileSize:       1024,
+		UploadDate:     time.Now(),
+		ExpirationDate: time.Now().Add(30 * 24 * time.Hour),
+		S3Key:          "uploads/test-file-id-6/test6.txt",
+		Status:         StatusActive,
+	}
+	require.NoError(t, db.SaveFile(file))
+
+	require.NoError(t, db.SaveShare(&ShareRecord{
+		ID:            "share-short",
+		FileID:        file.ID,
+		Recipients:    []string{"user@example.com"},
+		SharedDate:    time.Now(),
+		SignedAt:      time.Now(),
+		URLExpiration: file.ExpirationDate,
+		ShortLink:     true,
+	}))
+
+	share, err := db.GetShare("share-short")
+	require.NoError(t, err)
+	assert.True(t, share.ShortLink)
+	assert.False(t, share.IsRevoked())
+
+	revokedAt := time.Now()
+	require.NoError(t, db.RevokeShare("share-short", revokedAt))
+
+	share, err = db.GetShare("share-short")
+	require.NoError(t, err)
+	assert.True(t, share.IsRevoked())
+	assert.WithinDuration(t, revokedAt, share.RevokedAt, time.Second)
+
+	// The first revocation time is kept
+	err = db.RevokeShare("share-short", revokedAt.Add(time.Hour))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "already revoked")
+
+	err = db.RevokeShare("missing-share", revokedAt)
+	assert.Error(t, err)
+}
+
 func TestSQLiteDatabase_GetShareHistory_NoShares(t *testing.T) {
 	db, _ := createTempDatabase(t)
 	defer db.Close()
@@ -374,6 +1122,61 @@ func TestSQLiteDatabase_GetShareHistory_NoShares(t *testing.T) {
 	assert.Empty(t, shares)
 }
 
+func TestSQLiteDatabase_Outbox(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+
+	upload := &OutboxEntry{Operation: "upload", FileID: "file-1"}
+	share := &OutboxEntry{Operation: "share", FileID: "file-1", Payload: `{"recipients":["a@example.com"]}`}
+	remove := &OutboxEntry{Operation: "delete", FileID: "file-2"}
+
+	for _, entry := range []*OutboxEntry{upload, share, remove} {
+		require.NoError(t, db.EnqueueOutbox(entry))
+		assert.NotZero(t, entry.ID)
+	}
+
+	// Entries come back in the order they were queued
+	entries, err := db.ListOutbox()
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "upload", entries[0].Operation)
+	assert.Equal(t, "share", entries[1].Operation)
+	assert.Equal(t, share.Payload, entries[1].Payload)
+	assert.Equal(t, "delete", entries[2].Operation)
+	assert.Equal(t, "file-2", entries[2].FileID)
+
+	// Failed attempts are counted
+	require.NoError(t, db.RecordOutboxAttempt(upload.ID, "connection refused"))
+	require.NoError(t, db.RecordOutboxAttempt(upload.ID, "connection reset"))
+	entries, err = db.ListOutbox()
+	require.NoError(t, err)
+	assert.Equal(t, 2, entries[0].Attempts)
+	assert.Equal(t, "connection reset", entries[0].LastError)
+
+	require.NoError(t, db.DeleteOutboxEntry(upload.ID))
+	entries, err = db.ListOutbox()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, share.ID, entries[0].ID)
+}
+
+func TestSQLiteDatabase_Outbox_NotFound(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+
+	entries, err := db.ListOutbox()
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	err = db.RecordOutboxAttempt(42, "error")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "outbox entry not found")
+
+	err = db.DeleteOutboxEntry(42)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "outbox entry not found")
+}
+
 func TestSQLiteDatabase_SaveConfig(t *testing.T) {
 	db, _ := createTempDatabase(t)
 	defer db.Close()
@@ -437,7 +1240,7 @@ func TestSQLiteDatabase_ForeignKeyConstraint(t *testing.T) {
 		Recipients:    []string{"user@example.com"},
 		Message:       "This should fail",
 		SharedDate:    time.Now(),
-		PresignedURL:  "https://s3.amazonaws.com/bucket/key?signature=xyz",
+		SignedAt:      time.Now(),
 		URLExpiration: time.Now().Add(24 * time.Hour),
 	}
 
@@ -472,7 +1275,7 @@ func TestSQLiteDatabase_CascadeDelete(t *testing.T) {
 		Recipients:    []string{"user@example.com"},
 		Message:       "Test cascade delete",
 		SharedDate:    time.Now(),
-		PresignedURL:  "https://s3.amazonaws.com/bucket/key?signature=xyz",
+		SignedAt:      time.Now(),
 		URLExpiration: time.Now().Add(24 * time.Hour),
 	}
 
@@ -503,4 +1306,134 @@ func TestSQLiteDatabase_Close(t *testing.T) {
 	// Calling Close again should not error
 	err = db.Close()
 	assert.NoError(t, err)
-}
\ No newline at end of file
+}
+func TestSQLiteDatabase_Notifications(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+
+	first := &NotificationRecord{Event: "upload_complete", FileID: "file-1", Title: "Upload complete", Content: "report.pdf"}
+	require.NoError(t, db.SaveNotification(first))
+	assert.NotZero(t, first.ID)
+	assert.False(t, first.CreatedAt.IsZero())
+
+	second := &NotificationRecord{Event: "missing_files", Title: "Files missing from S3", Content: "2 files"}
+	require.NoError(t, db.SaveNotification(second))
+
+	// Newest first, limited
+	notifications, err := db.ListNotifications(10)
+	require.NoError(t, err)
+	require.Len(t, notifications, 2)
+	assert.Equal(t, second.ID, notifications[0].ID)
+	assert.Equal(t, "file-1", notifications[1].FileID)
+	assert.Equal(t, "report.pdf", notifications[1].Content)
+
+	notifications, err = db.ListNotifications(1)
+	require.NoError(t, err)
+	assert.Len(t, notifications, 1)
+
+	sent, err := db.HasNotification("upload_complete", "file-1")
+	require.NoError(t, err)
+	assert.True(t, sent)
+
+	sent, err = db.HasNotification("upload_complete", "file-2")
+	require.NoError(t, err)
+	assert.False(t, sent)
+
+	require.NoError(t, db.ClearNotifications())
+	notifications, err = db.ListNotifications(10)
+	require.NoError(t, err)
+	assert.Empty(t, notifications)
+}
+
+func TestSQLiteDatabase_WithTransaction(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+
+	newFile := func(id string) *FileMetadata {
+		return &FileMetadata{
+			ID:             id,
+			FileName:       id + ".txt",
+			FilePath:       "/tmp/" + id + ".txt",
+			FileSize:       1024,
+			UploadDate:     time.Now(),
+			ExpirationDate: time.Now().Add(time.Hour),
+			S3Key:          "uploads/" + id,
+			Status:         StatusUploading,
+		}
+	}
+
+	// Committed changes are all kept
+	err := db.WithTransaction(func(tx Database) error {
+		if err := tx.SaveFile(newFile("committed")); err != nil {
+			return err
+		}
+		if err := tx.UpdateFileChecksum("committed", "abc123", "etag-1"); err != nil {
+			return err
+		}
+		return tx.UpdateFileStatus("committed", StatusActive)
+	})
+	require.NoError(t, err)
+
+	file, err := db.GetFile("committed")
+	require.NoError(t, err)
+	assert.Equal(t, StatusActive, file.Status)
+	assert.Equal(t, "abc123", file.Checksum)
+
+	// A failure rolls back every change made in the transaction
+	err = db.WithTransaction(func(tx Database) error {
+		if err := tx.UpdateFileStatus("committed", StatusError); err != nil {
+			return err
+		}
+		if err := tx.SaveFile(newFile("rolled-back")); err != nil {
+			return err
+		}
+		// Nested transactions join the outer one
+		return tx.WithTransaction(func(inner Database) error {
+			if err := inner.UpdateFileChecksum("committed", "changed", "etag-2"); err != nil {
+				return err
+			}
+			return fmt.Errorf("upload failed")
+		})
+	})
+	require.Error(t, err)
+	assert.Equal(t, "upload failed", err.Error())
+
+	file, err = db.GetFile("committed")
+	require.NoError(t, err)
+	assert.Equal(t, StatusActive, file.Status)
+	assert.Equal(t, "abc123", file.Checksum)
+	_, err = db.GetFile("rolled-back")
+	assert.Error(t, err)
+
+	// The database can't be closed from inside a transaction
+	err = db.WithTransaction(func(tx Database) error {
+		return tx.Close()
+	})
+	assert.Error(t, err)
+	_, err = db.GetFile("committed")
+	assert.NoError(t, err)
+}
+
+func TestSQLiteDatabase_WithTransaction_ConcurrentWriters(t *testing.T) {
+	db, _ := createTempDatabase(t)
+	defer db.Close()
+
+	// Writers outside a transaction wait for it to finish instead of failing
+	started := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- db.WithTransaction(func(tx Database) error {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			return tx.SaveConfig("owner", "transaction")
+		})
+	}()
+
+	<-started
+	require.NoError(t, db.SaveConfig("other", "writer"))
+	require.NoError(t, <-done)
+
+	value, err := db.GetConfig("owner")
+	require.NoError(t, err)
+	assert.Equal(t, "transaction", value)
+}
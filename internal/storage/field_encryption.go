@@ -0,0 +1,304 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	// fieldEncryptionConfigKey is set in app_config while sensitive fields are stored encrypted
+	fieldEncryptionConfigKey = "field_encryption"
+
+	// fieldEncryptionAlgorithm is the value of fieldEncryptionConfigKey
+	fieldEncryptionAlgorithm = "aes-256-gcm"
+
+	// encryptedFieldPrefix marks encrypted values, followed by the key ID, ":" and the
+	// base64 nonce and ciphertext. Values without it are stored in plain text.
+	encryptedFieldPrefix = "enc:v1:"
+)
+
+// FieldCipher encrypts the sensitive fields stored in the database with AES-256-GCM:
+// share recipients and messages, and queued shares.
+// It encrypts with its current key, and decrypts with any of its keys.
+type FieldCipher struct {
+	currentKeyID string
+	aeads        map[string]cipher.AEAD
+}
+
+// NewFieldCipher creates a FieldCipher encrypting with the current key. Previous keys
+// decrypt fields that haven't been re-encrypted since a key rotation.
+func NewFieldCipher(current []byte, previous ...[]byte) (*FieldCipher, error) {
+	c := &FieldCipher{aeads: make(map[string]cipher.AEAD)}
+
+	for i, key := range append([][]byte{current}, previous...) {
+		if key == nil && i > 0 {
+			continue
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("database key must be 32 bytes, got %d", len(key))
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid database key: %w", err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cipher: %w", err)
+		}
+
+		id := fieldKeyID(key)
+		c.aeads[id] = aead
+		if i == 0 {
+			c.currentKeyID = id
+		}
+	}
+
+	return c, nil
+}
+
+// fieldKeyID identifies a key in the values encrypted with it, without revealing the key
+func fieldKeyID(key []byte) string {
+	sum := sha256.Sum256(append([]byte("file-sharing-app field key:"), key...))
+	return hex.EncodeToString(sum[:4])
+}
+
+// KeyID returns the ID of the key fields are encrypted with
+func (c *FieldCipher) KeyID() string {
+	if c == nil {
+		return ""
+	}
+	return c.currentKeyID
+}
+
+// encrypt encrypts the value of a column. The column is authenticated with it, so values
+// can't be moved between columns. Without a cipher the value is returned as it is.
+func (c *FieldCipher) encrypt(column, value string) (string, error) {
+	if c == nil {
+		return value, nil
+	}
+
+	aead := c.aeads[c.currentKeyID]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(column))
+	return encryptedFieldPrefix + c.currentKeyID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt decrypts the value of a column encrypted by encrypt. Values stored in plain text,
+// from before encryption was turned on, are returned as they are.
+func (c *FieldCipher) decrypt(column, value string) (string, error) {
+	if !strings.HasPrefix(value, encryptedFieldPrefix) {
+		return value, nil
+	}
+	if c == nil {
+		return "", fmt.Errorf("%s is encrypted, but the database key isn't loaded", column)
+	}
+
+	keyID, encoded, found := strings.Cut(strings.TrimPrefix(value, encryptedFieldPrefix), ":")
+	if !found {
+		return "", fmt.Errorf("%s has a malformed encrypted value", column)
+	}
+	aead, ok := c.aeads[keyID]
+	if !ok {
+		return "", fmt.Errorf("%s is encrypted with key %s, which isn't in the keyring", column, keyID)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("%s has a malformed encrypted value", column)
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(column))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s: %w", column, err)
+	}
+	return string(plaintext), nil
+}
+
+// encryptShare encrypts a share's sensitive fields for storage
+func (c *FieldCipher) encryptShare(share *ShareRecord) (recipients, message string, err error) {
+	recipientsJSON, err := json.Marshal(share.Recipients)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to marshal recipients: %w", err)
+	}
+
+	if recipients, err = c.encrypt("shares.recipients", string(recipientsJSON)); err != nil {
+		return "", "", err
+	}
+	if message, err = c.encrypt("shares.message", share.Message); err != nil {
+		return "", "", err
+	}
+	return recipients, message, nil
+}
+
+// decryptShare decrypts the sensitive fields of a share read from storage
+func (c *FieldCipher) decryptShare(share *ShareRecord, recipients string) error {
+	recipientsJSON, err := c.decrypt("shares.recipients", recipients)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(recipientsJSON), &share.Recipients); err != nil {
+		return fmt.Errorf("failed to unmarshal recipients: %w", err)
+	}
+
+	if share.Message, err = c.decrypt("shares.message", share.Message); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetFieldCipher sets the cipher sensitive fields are encrypted and decrypted with, for a
+// database whose fields were encrypted before it was opened. Use ReencryptFields to turn
+// encryption on or off, or to rotate the key.
+func (s *SQLiteDatabase) SetFieldCipher(c *FieldCipher) {
+	s.cipher = c
+}
+
+// FieldEncryptionEnabled reports whether sensitive fields are stored encrypted
+func (s *SQLiteDatabase) FieldEncryptionEnabled() (bool, error) {
+	var count int
+	err := s.conn.QueryRow("SELECT COUNT(*) FROM app_config WHERE key = ?", fieldEncryptionConfigKey).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to read field encryption setting: %w", err)
+	}
+	return count > 0, nil
+}
+
+// ReencryptFields rewrites every sensitive field encrypted with c, or in plain text if c is nil,
+// and uses c from then on. It turns encryption on, rotates the key or turns encryption off.
+// Existing values must be readable with the current cipher or c. All fields are rewritten in
+// one transaction, so a failure leaves them as they were.
+func (s *SQLiteDatabase) ReencryptFields(c *FieldCipher) error {
+	current := s.cipher
+
+	// Reads fields encrypted with either cipher
+	readable := c
+	if current != nil {
+		readable = &FieldCipher{currentKeyID: current.currentKeyID, aeads: make(map[string]cipher.AEAD)}
+		for id, aead := range current.aeads {
+			readable.aeads[id] = aead
+		}
+		if c != nil {
+			for id, aead := range c.aeads {
+				readable.aeads[id] = aead
+			}
+		}
+	}
+
+	err := s.WithTransaction(func(txDB Database) error {
+		tx := txDB.(*SQLiteDatabase)
+		tx.cipher = readable
+
+		shares, err := tx.listAllShares()
+		if err != nil {
+			return err
+		}
+		for _, share := range shares {
+			recipients, message, err := c.encryptShare(share)
+			if err != nil {
+				return err
+			}
+			_, err = tx.conn.Exec(`UPDATE shares SET recipients = ?, message = ? WHERE id = ?`,
+				recipients, message, share.ID)
+			if err != nil {
+				return fmt.Errorf("failed to re-encrypt share %s: %w", share.ID, err)
+			}
+		}
+
+		entries, err := tx.ListOutbox()
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			payload, err := c.encrypt("outbox.payload", entry.Payload)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.conn.Exec(`UPDATE outbox SET payload = ? WHERE id = ?`, payload, entry.ID); err != nil {
+				return fmt.Errorf("failed to re-encrypt outbox entry %d: %w", entry.ID, err)
+			}
+		}
+
+		if c == nil {
+			_, err = tx.conn.Exec("DELETE FROM app_config WHERE key = ?", fieldEncryptionConfigKey)
+		} else {
+			err = tx.SaveConfig(fieldEncryptionConfigKey, fieldEncryptionAlgorithm)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save field encryption setting: %w", err)
+		}
+
+		s.logger.InfoWithFields("Re-encrypted database fields", map[string]interface{}{
+			"shares":    len(shares),
+			"outbox":    len(entries),
+			"encrypted": c != nil,
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.cipher = c
+	return nil
+}
+
+// listAllShares retrieves every share record
+func (s *SQLiteDatabase) listAllShares() ([]*ShareRecord, error) {
+	rows, err := s.conn.Query(`
+		SELECT id, file_id, recipients, message, shared_date, signed_at, url_expiration, short_link, revoked_at, link_lifetime, max_downloads, download_name, inline_preview, created_at
+		FROM shares
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shares: %w", err)
+	}
+	defer rows.Close()
+
+	return s.scanShares(rows)
+}
+
+// searchEncryptedShares returns the IDs of files with a share whose recipients or message contain
+// the search text, ignoring ASCII case like LIKE does. Encrypted fields can't be searched in SQL.
+func (s *SQLiteDatabase) searchEncryptedShares(search string) ([]string, error) {
+	shares, err := s.listAllShares()
+	if err != nil {
+		return nil, err
+	}
+
+	search = asciiLower(search)
+	seen := make(map[string]bool)
+	var fileIDs []string
+	for _, share := range shares {
+		if seen[share.FileID] {
+			continue
+		}
+		recipients, err := json.Marshal(share.Recipients)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal recipients: %w", err)
+		}
+		if strings.Contains(asciiLower(string(recipients)), search) || strings.Contains(asciiLower(share.Message), search) {
+			seen[share.FileID] = true
+			fileIDs = append(fileIDs, share.FileID)
+		}
+	}
+	return fileIDs, nil
+}
+
+// asciiLower lowers ASCII letters only, the way SQLite's LIKE and NOCASE compare
+func asciiLower(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'A' && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testKey returns a database key filled with b
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func TestFieldCipher(t *testing.T) {
+	c, err := NewFieldCipher(testKey(1))
+	require.NoError(t, err)
+
+	encrypted, err := c.encrypt("shares.message", "see you friday")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encrypted, encryptedFieldPrefix+c.KeyID()+":"))
+	assert.NotContains(t, encrypted, "friday")
+
+	// Each encryption uses a new nonce
+	again, err := c.encrypt("shares.message", "see you friday")
+	require.NoError(t, err)
+	assert.NotEqual(t, encrypted, again)
+
+	decrypted, err := c.decrypt("shares.message", encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "see you friday", decrypted)
+
+	// Values can't be moved to another column
+	_, err = c.decrypt("shares.recipients", encrypted)
+	assert.Error(t, err)
+
+	// Plain text from before encryption was turned on is read as it is
+	plain, err := c.decrypt("shares.message", "not encrypted")
+	require.NoError(t, err)
+	assert.Equal(t, "not encrypted", plain)
+
+	// Without the key, encrypted values can't be read
+	var none *FieldCipher
+	_, err = none.decrypt("shares.message", encrypted)
+	assert.Error(t, err)
+	other, err := NewFieldCipher(testKey(2))
+	require.NoError(t, err)
+	_, err = other.decrypt("shares.message", encrypted)
+	assert.Error(t, err)
+
+	// Previous keys still decrypt
+	rotated, err := NewFieldCipher(testKey(2), testKey(1))
+	require.NoError(t, err)
+	decrypted, err = rotated.decrypt("shares.message", encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "see you friday", decrypted)
+
+	_, err = NewFieldCipher([]byte("too short"))
+	assert.Error(t, err)
+}
+
+func TestSQLiteDatabase_ReencryptFields(t *testing.T) {
+	db, dbPath := createTempDatabase(t)
+
+	require.NoError(t, db.SaveFile(&FileMetadata{
+		ID:             "file-1",
+		FileName:       "contract.pdf",
+		FilePath:       "/tmp/contract.pdf",
+		FileSize:       1024,
+		UploadDate:     time.Now(),
+		ExpirationDate: time.Now().Add(24 * time.Hour),
+		S3Key:          "uploads/contract.pdf",
+		Status:         StatusActive,
+	}))
+	require.NoError(t, db.SaveShare(&ShareRecord{
+		ID:            "share-1",
+		FileID:        "file-1",
+		Recipients:    []string{"alice@example.com"},
+		Message:       "Signed copy attached",
+		SharedDate:    time.Now(),
+		SignedAt:      time.Now(),
+		URLExpiration: time.Now().Add(time.Hour),
+	}))
+	require.NoError(t, db.EnqueueOutbox(&OutboxEntry{
+		Operation: "share",
+		FileID:    "file-1",
+		Payload:   `{"recipients":["bob@example.com"],"message":"queued"}`,
+	}))
+
+	// rawShare returns the stored values of the sensitive fields
+	rawShare := func(db *SQLiteDatabase) string {
+		var values []string
+		rows, err := db.db.Query("SELECT recipients, message FROM shares")
+		require.NoError(t, err)
+		defer rows.Close()
+		for rows.Next() {
+			var recipients, message string
+			require.NoError(t, rows.Scan(&recipients, &message))
+			values = append(values, recipients, message)
+		}
+		var payload string
+		require.NoError(t, db.db.QueryRow("SELECT payload FROM outbox").Scan(&payload))
+		return strings.Join(append(values, payload), "\n")
+	}
+
+	enabled, err := db.FieldEncryptionEnabled()
+	require.NoError(t, err)
+	assert.False(t, enabled)
+
+	// Turning encryption on encrypts what is already stored
+	first, err := NewFieldCipher(testKey(1))
+	require.NoError(t, err)
+	require.NoError(t, db.ReencryptFields(first))
+
+	stored := rawShare(db)
+	for _, secret := range []string{"alice@example.com", "Signed copy", "bob@example.com"} {
+		assert.NotContains(t, stored, secret)
+	}
+	assert.Equal(t, 3, strings.Count(stored, encryptedFieldPrefix+first.KeyID()))
+
+	enabled, err = db.FieldEncryptionEnabled()
+	require.NoError(t, err)
+	assert.True(t, enabled)
+
+	shares, err := db.GetShareHistory("file-1")
+	require.NoError(t, err)
+	require.Len(t, shares, 1)
+	assert.Equal(t, []string{"alice@example.com"}, shares[0].Recipients)
+	assert.Equal(t, "Signed copy attached", shares[0].Message)
+
+	entries, err := db.ListOutbox()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Contains(t, entries[0].Payload, "bob@example.com")
+
+	// Encrypted recipients and messages can still be searched
+	files, err := db.QueryFiles(FileQuery{Search: "ALICE@"})
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+	files, err = db.QueryFiles(FileQuery{Search: "nobody"})
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	// Fields written later are encrypted too
+	require.NoError(t, db.SaveShare(&ShareRecord{
+		ID:            "share-2",
+		FileID:        "file-1",
+		Recipients:    []string{"carol@example.com"},
+		Message:       "Countersigned",
+		SharedDate:    time.Now().Add(time.Minute),
+		SignedAt:      time.Now(),
+		URLExpiration: time.Now().Add(time.Hour),
+	}))
+	assert.NotContains(t, rawShare(db), "Countersigned")
+
+	// Reopened without the key, the fields can't be read
+	require.NoError(t, db.Close())
+	db, err = NewSQLiteDatabase(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.GetShareHistory("file-1")
+	assert.Error(t, err)
+
+	// Re-encrypting needs the key the fields are encrypted with, and changes nothing without it
+	second, err := NewFieldCipher(testKey(2))
+	require.NoError(t, err)
+	assert.Error(t, db.ReencryptFields(second))
+	assert.Contains(t, rawShare(db), first.KeyID())
+
+	// Rotating the key re-encrypts everything with the new one
+	db.SetFieldCipher(first)
+	require.NoError(t, db.ReencryptFields(second))
+	stored = rawShare(db)
+	assert.Equal(t, 5, strings.Count(stored, encryptedFieldPrefix+second.KeyID()))
+	assert.NotContains(t, stored, first.KeyID())
+
+	shares, err = db.GetShareHistory("file-1")
+	require.NoError(t, err)
+	require.Len(t, shares, 2)
+	assert.Equal(t, "Countersigned", shares[0].Message)
+
+	// Turning encryption off stores the fields in plain text again
+	require.NoError(t, db.ReencryptFields(nil))
+	assert.Contains(t, rawShare(db), "alice@example.com")
+	assert.NotContains(t, rawShare(db), encryptedFieldPrefix)
+	enabled, err = db.FieldEncryptionEnabled()
+	require.NoError(t, err)
+	assert.False(t, enabled)
+}
@@ -0,0 +1,523 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migration upgrades the database schema by one version
+type migration struct {
+	version     int
+	description string
+	up          func(tx *sql.Tx) error
+}
+
+// migrations are applied in order, each in its own transaction. Never edit one that has been released;
+// add a new migration instead, so existing databases are upgraded too.
+var migrations = []migration{
+	{
+		// Databases created before schema versions were recorded are at version 0.
+		// Their tables are brought up to date here, so this also works on them.
+		version:     1,
+		description: "create tables",
+		up:          createTables,
+	},
+	{
+		version:     2,
+		description: "index the file list for filtering, sorting and expiration checks",
+		up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				CREATE INDEX IF NOT EXISTS idx_files_profile ON files(profile);
+				CREATE INDEX IF NOT EXISTS idx_files_profile_status ON files(profile, status);
+				CREATE INDEX IF NOT EXISTS idx_files_profile_filename ON files(profile, filename COLLATE NOCASE);
+				CREATE INDEX IF NOT EXISTS idx_files_profile_filesize ON files(profile, filesize);
+				CREATE INDEX IF NOT EXISTS idx_files_profile_uploaded ON files(profile, julianday(upload_date));
+				CREATE INDEX IF NOT EXISTS idx_files_profile_expires ON files(profile, julianday(expiration_date));
+				-- For the expiration checker, which looks at every profile
+				CREATE INDEX IF NOT EXISTS idx_files_expires ON files(julianday(expiration_date));
+			`)
+			return err
+		},
+	},
+	{
+		version:     3,
+		description: "store when share links were signed instead of the links themselves",
+		up:          replaceShareURLs,
+	},
+	{
+		version:     4,
+		description: "record which shares have short links and when they were revoked",
+		up: func(tx *sql.Tx) error {
+			if err := addColumnIfMissing(tx, "shares", "short_link", "BOOLEAN NOT NULL DEFAULT 0"); err != nil {
+				return err
+			}
+			return addColumnIfMissing(tx, "shares", "revoked_at", "DATETIME")
+		},
+	},
+	{
+		version:     5,
+		description: "record each share's link lifetime, download limit and download name",
+		up: func(tx *sql.Tx) error {
+			// The lifetime is in seconds; zero is the default lifetime
+			if err := addColumnIfMissing(tx, "shares", "link_lifetime", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+				return err
+			}
+			if err := addColumnIfMissing(tx, "shares", "max_downloads", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+				return err
+			}
+			return addColumnIfMissing(tx, "shares", "download_name", "TEXT NOT NULL DEFAULT ''")
+		},
+	},
+	{
+		version:     6,
+		description: "record which shares open in the browser instead of downloading",
+		up: func(tx *sql.Tx) error {
+			return addColumnIfMissing(tx, "shares", "inline_preview", "BOOLEAN NOT NULL DEFAULT 0")
+		},
+	},
+	{
+		version:     7,
+		description: "record the content type each file was uploaded with",
+		up: func(tx *sql.Tx) error {
+			// Empty for files uploaded before it was recorded
+			return addColumnIfMissing(tx, "files", "content_type", "TEXT NOT NULL DEFAULT ''")
+		},
+	},
+	{
+		version:     8,
+		description: "record how each file is compressed and how much is stored",
+		up: func(tx *sql.Tx) error {
+			if err := addColumnIfMissing(tx, "files", "compression", "TEXT NOT NULL DEFAULT ''"); err != nil {
+				return err
+			}
+			// Zero for files uploaded before it was recorded
+			return addColumnIfMissing(tx, "files", "stored_size", "INTEGER NOT NULL DEFAULT 0")
+		},
+	},
+	{
+		version:     9,
+		description: "index files by content and by S3 object, for reusing identical uploads",
+		up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				CREATE INDEX IF NOT EXISTS idx_files_profile_checksum ON files(profile, checksum_sha256);
+				CREATE INDEX IF NOT EXISTS idx_files_s3_key ON files(s3_key);
+			`)
+			return err
+		},
+	},
+	{
+		version:     10,
+		description: "record the virus scan verdict of each file and the scanner that gave it",
+		up: func(tx *sql.Tx) error {
+			// Empty for files uploaded without a scan
+			if err := addColumnIfMissing(tx, "files", "scan_verdict", "TEXT NOT NULL DEFAULT ''"); err != nil {
+				return err
+			}
+			return addColumnIfMissing(tx, "files", "scan_engine", "TEXT NOT NULL DEFAULT ''")
+		},
+	},
+}
+
+// latestSchemaVersion returns the schema version this build of the app migrates databases to
+func latestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// createTables creates the tables, and adds the columns that older databases are missing
+func createTables(tx *sql.Tx) error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS files (
+		id TEXT PRIMARY KEY,
+		filename TEXT NOT NULL,
+		filepath TEXT NOT NULL,
+		filesize INTEGER NOT NULL,
+		upload_date DATETIME NOT NULL,
+		expiration_date DATETIME NOT NULL,
+		s3_key TEXT NOT NULL,
+		status TEXT NOT NULL,
+		profile TEXT NOT NULL DEFAULT 'default',
+		encryption_mode TEXT NOT NULL DEFAULT 'SSE-S3',
+		checksum_sha256 TEXT NOT NULL DEFAULT '',
+		etag TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_files_upload_date ON files(upload_date);
+	CREATE INDEX IF NOT EXISTS idx_files_expiration_date ON files(expiration_date);
+	CREATE INDEX IF NOT EXISTS idx_files_status ON files(status);
+
+	CREATE TABLE IF NOT EXISTS shares (
+		id TEXT PRIMARY KEY,
+		file_id TEXT NOT NULL,
+		recipients TEXT NOT NULL,
+		message TEXT,
+		shared_date DATETIME NOT NULL,
+		presigned_url TEXT NOT NULL,
+		url_expiration DATETIME NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (file_id) REFERENCES files(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_shares_file_id ON shares(file_id);
+	CREATE INDEX IF NOT EXISTS idx_shares_shared_date ON shares(shared_date);
+
+	CREATE TABLE IF NOT EXISTS outbox (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		operation TEXT NOT NULL,
+		file_id TEXT NOT NULL,
+		payload TEXT NOT NULL DEFAULT '',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_outbox_file_id ON outbox(file_id);
+
+	CREATE TABLE IF NOT EXISTS notifications (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		event TEXT NOT NULL,
+		file_id TEXT NOT NULL DEFAULT '',
+		title TEXT NOT NULL,
+		content TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_notifications_event_file ON notifications(event, file_id);
+
+	CREATE TABLE IF NOT EXISTS app_config (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+
+	if _, err := tx.Exec(schema); err != nil {
+		return err
+	}
+
+	// Columns added to the files table before schema versions were recorded
+	for _, column := range []struct{ name, definition string }{
+		{"profile", "TEXT NOT NULL DEFAULT 'default'"},
+		{"encryption_mode", "TEXT NOT NULL DEFAULT 'SSE-S3'"},
+		{"checksum_sha256", "TEXT NOT NULL DEFAULT ''"},
+		{"etag", "TEXT NOT NULL DEFAULT ''"},
+	} {
+		if err := addColumnIfMissing(tx, "files", column.name, column.definition); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// replaceShareURLs records when each share's link was signed, so it can be signed again identically,
+// and drops the stored links. Anyone who could read the database could otherwise use them.
+func replaceShareURLs(tx *sql.Tx) error {
+	if err := addColumnIfMissing(tx, "shares", "signed_at", "DATETIME"); err != nil {
+		return err
+	}
+
+	type signing struct {
+		id                      string
+		signedAt, urlExpiration time.Time
+	}
+	var signings []signing
+
+	rows, err := tx.Query("SELECT id, presigned_url, shared_date, url_expiration FROM shares")
+	if err != nil {
+		return fmt.Errorf("failed to read share links: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			share        signing
+			presignedURL string
+			sharedDate   time.Time
+		)
+		if err := rows.Scan(&share.id, &presignedURL, &sharedDate, &share.urlExpiration); err != nil {
+			return fmt.Errorf("failed to scan share link: %w", err)
+		}
+
+		// Links stored encrypted can't be read here. They are signed again as of the share date,
+		// so copying them gives a new link, while the one already sent keeps working.
+		share.signedAt = sharedDate.Truncate(time.Second)
+		if signedAt, expiration, ok := parseSigning(presignedURL); ok {
+			share.signedAt = signedAt
+			share.urlExpiration = signedAt.Add(expiration)
+		}
+		signings = append(signings, share)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating share links: %w", err)
+	}
+	rows.Close()
+
+	for _, share := range signings {
+		_, err := tx.Exec("UPDATE shares SET signed_at = ?, url_expiration = ? WHERE id = ?",
+			share.signedAt, share.urlExpiration, share.id)
+		if err != nil {
+			return fmt.Errorf("failed to record when share %s was signed: %w", share.id, err)
+		}
+	}
+
+	if _, err := tx.Exec("ALTER TABLE shares DROP COLUMN presigned_url"); err != nil {
+		return fmt.Errorf("failed to drop share links: %w", err)
+	}
+	return nil
+}
+
+// parseSigning reads the signing time and expiration from a presigned URL
+func parseSigning(presignedURL string) (time.Time, time.Duration, bool) {
+	parsed, err := url.Parse(presignedURL)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+
+	query := parsed.Query()
+	signedAt, err := time.Parse("20060102T150405Z", query.Get("X-Amz-Date"))
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	seconds, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil || seconds <= 0 {
+		return time.Time{}, 0, false
+	}
+
+	return signedAt, time.Duration(seconds) * time.Second, true
+}
+
+// addColumnIfMissing adds a column to an existing table when upgrading older databases
+func addColumnIfMissing(tx *sql.Tx, table, column, definition string) error {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			columnType string
+			notNull    int
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultVal, &primaryKey); err != nil {
+			return fmt.Errorf("failed to scan table info for %s: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating table info for %s: %w", table, err)
+	}
+	rows.Close()
+
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	if err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
+// SchemaVersion returns the version of the database schema; 0 if no migrations have been applied
+func (s *SQLiteDatabase) SchemaVersion() (int, error) {
+	exists, err := s.tableExists("schema_version")
+	if err != nil || !exists {
+		return 0, err
+	}
+
+	var version int
+	if err := s.db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// migrate brings the schema up to the latest version. An existing database is backed up first.
+// A database migrated by a newer version of the app is refused, as this build can't know its schema.
+func (s *SQLiteDatabase) migrate() error {
+	current, err := s.SchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	latest := latestSchemaVersion()
+	if current > latest {
+		return fmt.Errorf("database schema version %d is newer than this version of the app supports (%d); update the app to open it", current, latest)
+	}
+	if current == latest {
+		return nil
+	}
+
+	// Databases with no tables yet are new and have nothing to back up
+	existing, err := s.tableExists("files")
+	if err != nil {
+		return err
+	}
+	if existing {
+		if err := s.backup(current); err != nil {
+			return err
+		}
+	}
+
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_version (
+			version INTEGER PRIMARY KEY,
+			description TEXT NOT NULL,
+			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema version table: %w", err)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := s.applyMigration(m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyMigration runs a migration and records its version in one transaction,
+// so a failed migration leaves the database at the previous version
+func (s *SQLiteDatabase) applyMigration(m migration) error {
+	s.logger.InfoWithFields("Migrating database schema", map[string]interface{}{
+		"version":     m.version,
+		"description": m.description,
+	})
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %d: %w", m.version, err)
+	}
+	defer tx.Rollback()
+
+	if err := m.up(tx); err != nil {
+		return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.description, err)
+	}
+
+	_, err = tx.Exec("INSERT INTO schema_version (version, description) VALUES (?, ?)", m.version, m.description)
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", m.version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", m.version, err)
+	}
+	return nil
+}
+
+// backup copies the database next to it before it is migrated, named after its current schema version.
+// Earlier backups are removed once it is taken, so only the copy from before the latest upgrade is kept.
+func (s *SQLiteDatabase) backup(version int) error {
+	backupPath := fmt.Sprintf("%s.v%d-%s.bak", s.path, version, time.Now().Format("20060102-150405"))
+
+	s.logger.InfoWithFields("Backing up database before migrating", map[string]interface{}{
+		"schema_version": version,
+	})
+
+	if _, err := s.db.Exec("VACUUM INTO ?", backupPath); err != nil {
+		return fmt.Errorf("failed to back up database before migrating: %w", err)
+	}
+	if err := os.Chmod(backupPath, 0600); err != nil {
+		return fmt.Errorf("failed to set backup file permissions: %w", err)
+	}
+	if err := clearShareURLs(backupPath); err != nil {
+		os.Remove(backupPath)
+		return fmt.Errorf("failed to clear share links from backup: %w", err)
+	}
+
+	s.removeBackupsExcept(backupPath)
+	return nil
+}
+
+// clearShareURLs empties the share links a database from before schema version 3 stored, so a backup of it
+// can't be used to download the shared files. The column is kept, as older versions of the app require it.
+func clearShareURLs(path string) error {
+	db, err := sql.Open("sqlite3", path+"?_secure_delete=on")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var columns int
+	if err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('shares') WHERE name = 'presigned_url'").Scan(&columns); err != nil {
+		return err
+	}
+	if columns == 0 {
+		return nil
+	}
+
+	if _, err := db.Exec("UPDATE shares SET presigned_url = ''"); err != nil {
+		return err
+	}
+	// Rewrite the file, so the links aren't left in its free pages or journal
+	_, err = db.Exec("VACUUM")
+	return err
+}
+
+// backups returns the paths of the backups taken of the database before migrating it
+func (s *SQLiteDatabase) backups() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Dir(s.path))
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := filepath.Base(s.path) + ".v"
+	var paths []string
+	for _, entry := range entries {
+		if name := entry.Name(); !entry.IsDir() && strings.HasPrefix(name, prefix) && strings.HasSuffix(name, ".bak") {
+			paths = append(paths, filepath.Join(filepath.Dir(s.path), name))
+		}
+	}
+	return paths, nil
+}
+
+// removeBackupsExcept deletes the backups taken of the database before earlier migrations. Failing to
+// is logged rather than returned, as the migration can go ahead.
+func (s *SQLiteDatabase) removeBackupsExcept(keep string) {
+	paths, err := s.backups()
+	if err != nil {
+		s.logger.WarnWithError("Failed to look for database backups", err)
+		return
+	}
+
+	for _, path := range paths {
+		if path == keep {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			s.logger.WarnWithError("Failed to remove database backup", err)
+			continue
+		}
+		s.logger.InfoWithFields("Removed database backup", map[string]interface{}{
+			"backup": filepath.Base(path),
+		})
+	}
+}
+
+// tableExists reports whether the database has a table
+func (s *SQLiteDatabase) tableExists(name string) (bool, error) {
+	var count int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect table %s: %w", name, err)
+	}
+	return count > 0, nil
+}
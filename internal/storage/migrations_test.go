@@ -0,0 +1,313 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"file-sharing-app/pkg/logger"
+)
+
+// backups returns the backups taken of a database before migrating it
+func backups(t *testing.T, dbPath string) []string {
+	matches, err := filepath.Glob(dbPath + ".v*.bak")
+	require.NoError(t, err)
+	return matches
+}
+
+// execRaw runs statements on a database file without migrating it
+func execRaw(t *testing.T, dbPath string, statements ...string) {
+	raw, err := sql.Open("sqlite3", dbPath)
+	require.NoError(t, err)
+	defer raw.Close()
+
+	for _, statement := range statements {
+		_, err := raw.Exec(statement)
+		require.NoError(t, err)
+	}
+}
+
+func TestMigrations_AreOrdered(t *testing.T) {
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.version, "migration versions must be consecutive")
+		assert.NotEmpty(t, m.description)
+		assert.NotNil(t, m.up)
+	}
+}
+
+func TestMigrate_NewDatabase(t *testing.T) {
+	db, dbPath := createTempDatabase(t)
+	defer db.Close()
+
+	version, err := db.SchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, latestSchemaVersion(), version)
+
+	// A new database has nothing to back up
+	assert.Empty(t, backups(t, dbPath))
+
+	var applied int
+	require.NoError(t, db.db.QueryRow("SELECT COUNT(*) FROM schema_version").Scan(&applied))
+	assert.Equal(t, len(migrations), applied)
+
+	// Reopening an up to date database changes nothing
+	require.NoError(t, db.Close())
+	db, err = NewSQLiteDatabase(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+	assert.Empty(t, backups(t, dbPath))
+}
+
+func TestMigrate_UnversionedDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "legacy.db")
+
+	// A database from before files had a profile, encryption mode or checksum, or schema versions were recorded
+	execRaw(t, dbPath,
+		`CREATE TABLE files (
+			id TEXT PRIMARY KEY,
+			filename TEXT NOT NULL,
+			filepath TEXT NOT NULL,
+			filesize INTEGER NOT NULL,
+			upload_date DATETIME NOT NULL,
+			expiration_date DATETIME NOT NULL,
+			s3_key TEXT NOT NULL,
+			status TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`INSERT INTO files (id, filename, filepath, filesize, upload_date, expiration_date, s3_key, status)
+			VALUES ('legacy-file', 'old.txt', '/tmp/old.txt', 1024, '2023-01-01 10:00:00', '2023-01-08 10:00:00', 'uploads/old.txt', 'active')`,
+	)
+
+	db, err := NewSQLiteDatabase(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	version, err := db.SchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, latestSchemaVersion(), version)
+
+	file, err := db.GetFile("legacy-file")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultProfile, file.Profile)
+	assert.Equal(t, "SSE-S3", file.EncryptionMode)
+
+	// The database was backed up as it was before migrating
+	found := backups(t, dbPath)
+	require.Len(t, found, 1)
+	assert.Contains(t, filepath.Base(found[0]), "legacy.db.v0-")
+
+	backup, err := sql.Open("sqlite3", found[0])
+	require.NoError(t, err)
+	defer backup.Close()
+
+	var name string
+	require.NoError(t, backup.QueryRow("SELECT filename FROM files WHERE id = 'legacy-file'").Scan(&name))
+	assert.Equal(t, "old.txt", name)
+	var columns int
+	require.NoError(t, backup.QueryRow("SELECT COUNT(*) FROM pragma_table_info('files') WHERE name = 'profile'").Scan(&columns))
+	assert.Zero(t, columns)
+
+	// Opening the upgraded database again keeps the backup, and files that only look like one
+	kept := dbPath + ".v0-notes.bak"
+	require.NoError(t, os.WriteFile(kept, []byte("kept by the user"), 0600))
+	require.NoError(t, db.Close())
+	db, err = NewSQLiteDatabase(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+	assert.ElementsMatch(t, append(found, kept), backups(t, dbPath))
+}
+
+func TestMigrate_ReplacesShareURLs(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "shares.db")
+
+	// Shares stored their signed links before schema version 3
+	execRaw(t, dbPath,
+		`CREATE TABLE files (
+			id TEXT PRIMARY KEY,
+			filename TEXT NOT NULL,
+			filepath TEXT NOT NULL,
+			filesize INTEGER NOT NULL,
+			upload_date DATETIME NOT NULL,
+			expiration_date DATETIME NOT NULL,
+			s3_key TEXT NOT NULL,
+			status TEXT NOT NULL
+		)`,
+		`CREATE TABLE shares (
+			id TEXT PRIMARY KEY,
+			file_id TEXT NOT NULL,
+			recipients TEXT NOT NULL,
+			message TEXT,
+			shared_date DATETIME NOT NULL,
+			presigned_url TEXT NOT NULL,
+			url_expiration DATETIME NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`INSERT INTO files (id, filename, filepath, filesize, upload_date, expiration_date, s3_key, status)
+			VALUES ('file-1', 'report.pdf', '/tmp/report.pdf', 1024, '2024-03-01 09:00:00', '2024-03-08 09:00:00', 'uploads/report.pdf', 'active')`,
+		`INSERT INTO shares (id, file_id, recipients, message, shared_date, presigned_url, url_expiration)
+			VALUES ('signed', 'file-1', '["alice@example.com"]', '', '2024-03-01 09:30:00',
+				'https://bucket.s3.amazonaws.com/uploads/report.pdf?X-Amz-Algorithm=AWS4-HMAC-SHA256&X-Amz-Date=20240301T093001Z&X-Amz-Expires=86400&X-Amz-Signature=abc',
+				'2024-03-02 09:30:00')`,
+		`INSERT INTO shares (id, file_id, recipients, message, shared_date, presigned_url, url_expiration)
+			VALUES ('encrypted', 'file-1', '["bob@example.com"]', '', '2024-03-01 10:15:00.5', 'enc:v1:0a1b2c3d:c2VjcmV0', '2024-03-02 10:15:00')`,
+	)
+
+	// A backup an earlier version of the app left holding the links
+	content, err := os.ReadFile(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dbPath+".v2-20240301-100000.bak", content, 0600))
+
+	db, err := NewSQLiteDatabase(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// The links are gone
+	var columns int
+	require.NoError(t, db.db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('shares') WHERE name = 'presigned_url'").Scan(&columns))
+	assert.Zero(t, columns)
+
+	// Links that can be read keep their signing time and expiration, so they are signed again identically
+	share, err := db.GetShare("signed")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 1, 9, 30, 1, 0, time.UTC), share.SignedAt.UTC())
+	assert.Equal(t, time.Date(2024, 3, 2, 9, 30, 1, 0, time.UTC), share.URLExpiration.UTC())
+
+	// Others are signed as of the share date
+	share, err = db.GetShare("encrypted")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 1, 10, 15, 0, 0, time.UTC), share.SignedAt.UTC())
+	assert.Equal(t, time.Date(2024, 3, 2, 10, 15, 0, 0, time.UTC), share.URLExpiration.UTC())
+
+	// Only the backup taken before migrating is kept, and no file is left holding the links
+	found := backups(t, dbPath)
+	require.Len(t, found, 1)
+	assert.Contains(t, filepath.Base(found[0]), "shares.db.v0-")
+	entries, err := os.ReadDir(filepath.Dir(dbPath))
+	require.NoError(t, err)
+	for _, entry := range entries {
+		if entry.Name() == filepath.Base(dbPath) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(filepath.Dir(dbPath), entry.Name()))
+		require.NoError(t, err)
+		assert.NotContains(t, string(content), "X-Amz-Signature", entry.Name())
+	}
+}
+
+func TestBackup_ClearsShareURLs(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "shares.db")
+	execRaw(t, dbPath,
+		`CREATE TABLE files (id TEXT PRIMARY KEY, filename TEXT NOT NULL)`,
+		`CREATE TABLE shares (id TEXT PRIMARY KEY, file_id TEXT NOT NULL, presigned_url TEXT NOT NULL)`,
+		`INSERT INTO shares (id, file_id, presigned_url)
+			VALUES ('signed', 'file-1', 'https://bucket.s3.amazonaws.com/uploads/report.pdf?X-Amz-Signature=abc')`,
+	)
+
+	raw, err := sql.Open("sqlite3", dbPath)
+	require.NoError(t, err)
+	defer raw.Close()
+	db := &SQLiteDatabase{db: raw, conn: raw, path: dbPath, logger: logger.NewWithComponent("database")}
+	require.NoError(t, db.backup(2))
+
+	// The backup keeps the shares, but not their links
+	found := backups(t, dbPath)
+	require.Len(t, found, 1)
+	content, err := os.ReadFile(found[0])
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "X-Amz-Signature")
+
+	backup, err := sql.Open("sqlite3", found[0])
+	require.NoError(t, err)
+	defer backup.Close()
+	var presignedURL string
+	require.NoError(t, backup.QueryRow("SELECT presigned_url FROM shares WHERE id = 'signed'").Scan(&presignedURL))
+	assert.Empty(t, presignedURL)
+}
+
+func TestMigrate_RefusesNewerDatabase(t *testing.T) {
+	db, dbPath := createTempDatabase(t)
+	require.NoError(t, db.Close())
+
+	newer := latestSchemaVersion() + 1
+	execRaw(t, dbPath, fmt.Sprintf("INSERT INTO schema_version (version, description) VALUES (%d, 'from the future')", newer))
+
+	_, err := NewSQLiteDatabase(dbPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf("schema version %d is newer", newer))
+	assert.Empty(t, backups(t, dbPath))
+}
+
+func TestMigrate_FailedMigrationRollsBack(t *testing.T) {
+	db, dbPath := createTempDatabase(t)
+	require.NoError(t, db.SaveFile(&FileMetadata{
+		ID:             "file-1",
+		FileName:       "report.pdf",
+		FilePath:       "/tmp/report.pdf",
+		FileSize:       1024,
+		UploadDate:     time.Now(),
+		ExpirationDate: time.Now().Add(time.Hour),
+		S3Key:          "uploads/report.pdf",
+		Status:         StatusActive,
+	}))
+	require.NoError(t, db.Close())
+
+	// A backup left by an earlier upgrade
+	stale := dbPath + ".v1-20240101-090000.bak"
+	require.NoError(t, os.WriteFile(stale, []byte("old backup"), 0600))
+
+	latest := latestSchemaVersion()
+	original := migrations
+	t.Cleanup(func() { migrations = original })
+	migrations = append(append([]migration{}, original...), migration{
+		version:     latest + 1,
+		description: "add a column, then fail",
+		up: func(tx *sql.Tx) error {
+			if _, err := tx.Exec("ALTER TABLE files ADD COLUMN owner TEXT NOT NULL DEFAULT ''"); err != nil {
+				return err
+			}
+			return fmt.Errorf("disk full")
+		},
+	})
+
+	_, err := NewSQLiteDatabase(dbPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "disk full")
+
+	// The database was backed up, replacing the earlier backup, and left at the previous version without the partial change
+	found := backups(t, dbPath)
+	require.Len(t, found, 1)
+	assert.Contains(t, filepath.Base(found[0]), fmt.Sprintf(".v%d-", latest))
+
+	backup, err := sql.Open("sqlite3", found[0])
+	require.NoError(t, err)
+	var name string
+	require.NoError(t, backup.QueryRow("SELECT filename FROM files WHERE id = 'file-1'").Scan(&name))
+	assert.Equal(t, "report.pdf", name)
+	require.NoError(t, backup.Close())
+
+	migrations = original
+	db, err = NewSQLiteDatabase(dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	version, err := db.SchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, latest, version)
+
+	var columns int
+	require.NoError(t, db.db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('files') WHERE name = 'owner'").Scan(&columns))
+	assert.Zero(t, columns)
+
+	_, err = db.GetFile("file-1")
+	assert.NoError(t, err)
+
+	// The backup is kept after the upgrade succeeds, so the previous version can be restored
+	assert.Equal(t, found, backups(t, dbPath))
+}
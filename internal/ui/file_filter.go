@@ -0,0 +1,222 @@
+package ui
+
+import (
+	"strings"
+	"time"
+
+	"file-sharing-app/internal/models"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// filterOption is a choice in one of the file list filters, with the label shown for it
+type filterOption struct {
+	label string
+	apply func(query *models.FileQuery, now time.Time)
+}
+
+const megabyte = 1024 * 1024
+
+// statusFilters are the choices of the status filter; the first shows every file
+var statusFilters = []filterOption{
+	{"All statuses", func(query *models.FileQuery, now time.Time) {}},
+	{"Active", filterStatus(models.StatusActive)},
+	{"Expired", filterStatus(models.StatusExpired)},
+	{"Error", filterStatus(models.StatusError)},
+}
+
+// sizeFilters are the choices of the size filter
+var sizeFilters = []filterOption{
+	{"Any size", func(query *models.FileQuery, now time.Time) {}},
+	{"Under 1 MB", func(query *models.FileQuery, now time.Time) {
+		query.MaxSize = megabyte - 1
+	}},
+	{"1 MB – 100 MB", func(query *models.FileQuery, now time.Time) {
+		query.MinSize, query.MaxSize = megabyte, 100*megabyte
+	}},
+	{"Over 100 MB", func(query *models.FileQuery, now time.Time) {
+		query.MinSize = 100*megabyte + 1
+	}},
+}
+
+// dateFilters are the choices of the upload date filter
+var dateFilters = []filterOption{
+	{"Any time", func(query *models.FileQuery, now time.Time) {}},
+	{"Today", func(query *models.FileQuery, now time.Time) {
+		query.UploadedAfter = startOfDay(now)
+	}},
+	{"Last 7 days", func(query *models.FileQuery, now time.Time) {
+		query.UploadedAfter = startOfDay(now).AddDate(0, 0, -6)
+	}},
+	{"Last 30 days", func(query *models.FileQuery, now time.Time) {
+		query.UploadedAfter = startOfDay(now).AddDate(0, 0, -29)
+	}},
+	{"Older than 30 days", func(query *models.FileQuery, now time.Time) {
+		query.UploadedBefore = startOfDay(now).AddDate(0, 0, -29)
+	}},
+}
+
+// sortFields are the columns the file list can be sorted by
+var sortFields = []struct {
+	label string
+	field models.FileSortField
+}{
+	{"Upload date", models.SortByUploadDate},
+	{"Name", models.SortByFileName},
+	{"Size", models.SortByFileSize},
+	{"Expiration", models.SortByExpiration},
+	{"Status", models.SortByStatus},
+}
+
+// filterStatus returns a filter that keeps only files with the status
+func filterStatus(status models.FileStatus) func(query *models.FileQuery, now time.Time) {
+	return func(query *models.FileQuery, now time.Time) {
+		query.Statuses = []models.FileStatus{status}
+	}
+}
+
+// startOfDay returns midnight of the day of t, in its location
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// filterLabels returns the labels of the options, in order
+func filterLabels(options []filterOption) []string {
+	labels := make([]string, len(options))
+	for i, option := range options {
+		labels[i] = option.label
+	}
+	return labels
+}
+
+// buildFileQuery builds the file list query from the search text and the labels chosen in the filters.
+// Unknown labels leave the query unfiltered.
+func buildFileQuery(search, status, size, date, sortBy string, ascending bool, now time.Time) models.FileQuery {
+	query := models.FileQuery{
+		Search:    strings.TrimSpace(search),
+		Ascending: ascending,
+	}
+
+	for _, filter := range []struct {
+		options []filterOption
+		label   string
+	}{
+		{statusFilters, status},
+		{sizeFilters, size},
+		{dateFilters, date},
+	} {
+		for _, option := range filter.options {
+			if option.label == filter.label {
+				option.apply(&query, now)
+				break
+			}
+		}
+	}
+
+	for _, sortField := range sortFields {
+		if sortField.label == sortBy {
+			query.SortBy = sortField.field
+			break
+		}
+	}
+
+	return query
+}
+
+// createFilterBar creates the search box, filters and sort order shown above the file list
+func (mw *MainWindow) createFilterBar() fyne.CanvasObject {
+	mw.searchEntry = widget.NewEntry()
+	mw.searchEntry.SetPlaceHolder("Search by name, recipient or message")
+	mw.searchEntry.ActionItem = widget.NewIcon(theme.SearchIcon())
+
+	mw.statusFilter = widget.NewSelect(filterLabels(statusFilters), nil)
+	mw.statusFilter.SetSelectedIndex(0)
+	mw.sizeFilter = widget.NewSelect(filterLabels(sizeFilters), nil)
+	mw.sizeFilter.SetSelectedIndex(0)
+	mw.dateFilter = widget.NewSelect(filterLabels(dateFilters), nil)
+	mw.dateFilter.SetSelectedIndex(0)
+
+	sortLabels := make([]string, len(sortFields))
+	for i, sortField := range sortFields {
+		sortLabels[i] = sortField.label
+	}
+	mw.sortSelect = widget.NewSelect(sortLabels, nil)
+	mw.sortSelect.SetSelectedIndex(0)
+
+	mw.sortOrderBtn = widget.NewButtonWithIcon("", theme.MoveDownIcon(), mw.toggleSortOrder)
+
+	// Callbacks are set once the widgets have their initial selection, so building the bar doesn't filter
+	mw.searchEntry.OnChanged = func(string) { mw.filterChanged() }
+	for _, filter := range []*widget.Select{mw.statusFilter, mw.sizeFilter, mw.dateFilter, mw.sortSelect} {
+		filter.OnChanged = func(string) { mw.filterChanged() }
+	}
+
+	return container.NewBorder(nil, nil, nil,
+		container.NewHBox(
+			mw.statusFilter,
+			mw.sizeFilter,
+			mw.dateFilter,
+			widget.NewLabel("Sort:"),
+			mw.sortSelect,
+			mw.sortOrderBtn,
+		),
+		mw.searchEntry,
+	)
+}
+
+// toggleSortOrder switches the file list between ascending and descending order
+func (mw *MainWindow) toggleSortOrder() {
+	mw.sortAscending = !mw.sortAscending
+	if mw.sortAscending {
+		mw.sortOrderBtn.SetIcon(theme.MoveUpIcon())
+	} else {
+		mw.sortOrderBtn.SetIcon(theme.MoveDownIcon())
+	}
+	mw.filterChanged()
+}
+
+// filterChanged shows the first page of files matching the new search, filters or sort order
+func (mw *MainWindow) filterChanged() {
+	mw.fileLimit = models.DefaultFilePageSize
+	mw.applyFileFilter()
+}
+
+// showMoreFiles loads another page of files into the list
+func (mw *MainWindow) showMoreFiles() {
+	mw.fileLimit += models.DefaultFilePageSize
+	mw.applyFileFilter()
+}
+
+// fileQuery returns the query chosen in the filter bar, for the pages of files shown
+func (mw *MainWindow) fileQuery() models.FileQuery {
+	query := buildFileQuery(
+		mw.searchEntry.Text,
+		mw.statusFilter.Selected,
+		mw.sizeFilter.Selected,
+		mw.dateFilter.Selected,
+		mw.sortSelect.Selected,
+		mw.sortAscending,
+		time.Now(),
+	)
+	query.Limit = mw.fileLimit
+	return query
+}
+
+// applyFileFilter shows the files matching the search, filters and sort order
+func (mw *MainWindow) applyFileFilter() {
+	if mw.OnFilterFiles == nil {
+		return
+	}
+
+	files, err := mw.OnFilterFiles(mw.fileQuery())
+	if err != nil {
+		mw.SetStatus("Error filtering files: " + err.Error())
+		return
+	}
+
+	mw.UpdateFiles(files)
+}
@@ -0,0 +1,140 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"file-sharing-app/internal/models"
+
+	"fyne.io/fyne/v2/test"
+)
+
+func TestBuildFileQuery(t *testing.T) {
+	now := time.Date(2024, 3, 15, 14, 30, 0, 0, time.UTC)
+	midnight := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+
+	query := buildFileQuery("  report ", "All statuses", "Any size", "Any time", "Upload date", false, now)
+	if query.Search != "report" {
+		t.Errorf("Expected search 'report', got '%s'", query.Search)
+	}
+	if len(query.Statuses) != 0 || query.MinSize != 0 || query.MaxSize != 0 {
+		t.Error("Expected no status or size filter")
+	}
+	if !query.UploadedAfter.IsZero() || !query.UploadedBefore.IsZero() {
+		t.Error("Expected no date filter")
+	}
+	if query.SortBy != models.SortByUploadDate || query.Ascending {
+		t.Errorf("Expected newest uploads first, got %s ascending=%v", query.SortBy, query.Ascending)
+	}
+
+	query = buildFileQuery("", "Expired", "1 MB – 100 MB", "Last 7 days", "Name", true, now)
+	if len(query.Statuses) != 1 || query.Statuses[0] != models.StatusExpired {
+		t.Errorf("Expected only expired files, got %v", query.Statuses)
+	}
+	if query.MinSize != megabyte || query.MaxSize != 100*megabyte {
+		t.Errorf("Expected sizes between 1 MB and 100 MB, got %d-%d", query.MinSize, query.MaxSize)
+	}
+	if !query.UploadedAfter.Equal(midnight.AddDate(0, 0, -6)) {
+		t.Errorf("Expected uploads since %v, got %v", midnight.AddDate(0, 0, -6), query.UploadedAfter)
+	}
+	if query.SortBy != models.SortByFileName || !query.Ascending {
+		t.Errorf("Expected names in ascending order, got %s ascending=%v", query.SortBy, query.Ascending)
+	}
+
+	query = buildFileQuery("", "", "Over 100 MB", "Older than 30 days", "", false, now)
+	if query.MinSize != 100*megabyte+1 || query.MaxSize != 0 {
+		t.Errorf("Expected sizes over 100 MB, got %d-%d", query.MinSize, query.MaxSize)
+	}
+	if !query.UploadedBefore.Equal(midnight.AddDate(0, 0, -29)) || !query.UploadedAfter.IsZero() {
+		t.Errorf("Expected uploads before %v, got %v", midnight.AddDate(0, 0, -29), query.UploadedBefore)
+	}
+	if query.SortBy != "" {
+		t.Errorf("Expected the default sort order, got %s", query.SortBy)
+	}
+
+	query = buildFileQuery("", "", "", "Today", "", false, now)
+	if !query.UploadedAfter.Equal(midnight) {
+		t.Errorf("Expected uploads since midnight, got %v", query.UploadedAfter)
+	}
+}
+
+func TestMainWindow_FilterFiles(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	mainWindow := NewMainWindow(testApp)
+
+	var queries []models.FileQuery
+	mainWindow.SetOnFilterFiles(func(query models.FileQuery) ([]models.FileMetadata, error) {
+		queries = append(queries, query)
+		return []models.FileMetadata{{ID: "file-1", FileName: "report.pdf"}}, nil
+	})
+
+	test.Type(mainWindow.searchEntry, "rep")
+	if len(queries) != 3 {
+		t.Fatalf("Expected the list to be filtered as the search is typed, got %d queries", len(queries))
+	}
+	if queries[2].Search != "rep" {
+		t.Errorf("Expected search 'rep', got '%s'", queries[2].Search)
+	}
+	if len(mainWindow.files) != 1 || mainWindow.files[0].ID != "file-1" {
+		t.Error("Expected the filtered files to be shown")
+	}
+
+	mainWindow.statusFilter.SetSelected("Active")
+	last := queries[len(queries)-1]
+	if len(last.Statuses) != 1 || last.Statuses[0] != models.StatusActive {
+		t.Errorf("Expected only active files, got %v", last.Statuses)
+	}
+
+	mainWindow.sortSelect.SetSelected("Size")
+	test.Tap(mainWindow.sortOrderBtn)
+	last = queries[len(queries)-1]
+	if last.SortBy != models.SortByFileSize || !last.Ascending {
+		t.Errorf("Expected sizes in ascending order, got %s ascending=%v", last.SortBy, last.Ascending)
+	}
+	if last.Search != "rep" || len(last.Statuses) != 1 {
+		t.Error("Expected the search and status filter to be kept")
+	}
+}
+
+func TestMainWindow_ShowMoreFiles(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	mainWindow := NewMainWindow(testApp)
+
+	// Serve files from a history larger than a page
+	history := make([]models.FileMetadata, models.DefaultFilePageSize+50)
+	var limits []int
+	mainWindow.SetOnFilterFiles(func(query models.FileQuery) ([]models.FileMetadata, error) {
+		limits = append(limits, query.Limit)
+		if query.Limit < len(history) {
+			return history[:query.Limit], nil
+		}
+		return history, nil
+	})
+
+	if mainWindow.showMoreBtn.Visible() {
+		t.Error("Expected no Show More button before files are loaded")
+	}
+
+	mainWindow.statusFilter.SetSelected("Active")
+	if len(mainWindow.files) != models.DefaultFilePageSize || !mainWindow.showMoreBtn.Visible() {
+		t.Fatalf("Expected a full page with a Show More button, got %d files", len(mainWindow.files))
+	}
+
+	test.Tap(mainWindow.showMoreBtn)
+	if limits[len(limits)-1] != 2*models.DefaultFilePageSize {
+		t.Errorf("Expected a second page to be requested, got limit %d", limits[len(limits)-1])
+	}
+	if len(mainWindow.files) != len(history) || mainWindow.showMoreBtn.Visible() {
+		t.Errorf("Expected every file without a Show More button, got %d files", len(mainWindow.files))
+	}
+
+	// Changing the filters starts again from the first page
+	mainWindow.statusFilter.SetSelected("Expired")
+	if limits[len(limits)-1] != models.DefaultFilePageSize {
+		t.Errorf("Expected the first page after a filter change, got limit %d", limits[len(limits)-1])
+	}
+}
@@ -2,6 +2,8 @@ package ui
 
 import (
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"file-sharing-app/internal/models"
@@ -9,6 +11,7 @@ import (
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 )
@@ -21,21 +24,70 @@ type MainWindow struct {
 	
 	// UI components
 	statusLabel   *widget.Label
+	scheduleLabel *widget.Label
 	uploadBtn     *widget.Button
 	settingsBtn   *widget.Button
 	refreshBtn    *widget.Button
+	profileSelect *widget.Select
+	editProfileBtn *widget.Button
+	newProfileBtn  *widget.Button
+	provisionBtn   *widget.Button
+	notificationsBtn *widget.Button
+	bandwidthSelect  *widget.Select
+	
+	// Search, filters and sort order of the file list
+	searchEntry   *widget.Entry
+	statusFilter  *widget.Select
+	sizeFilter    *widget.Select
+	dateFilter    *widget.Select
+	sortSelect    *widget.Select
+	sortOrderBtn  *widget.Button
+	sortAscending bool
+	
+	// The file list shows one page of files at first; showMoreBtn loads another
+	fileLimit   int
+	showMoreBtn *widget.Button
 	
 	// Data
 	files []models.FileMetadata
 	
+	// Set while the profile list is updated programmatically so the switch callback is not fired
+	updatingProfiles bool
+	
+	// Set while the upload speed is shown programmatically so the change callback is not fired
+	updatingBandwidth bool
+	
+	// System tray; nil when the desktop has none
+	tray           desktop.App
+	minimizeToTray bool
+	hiddenToTray   bool
+	recentShares   []models.RecentShare
+	
 	// Callbacks for business logic integration (will be set by main app)
-	OnUploadFile func(filePath string, expiration time.Duration) error
-	OnShareFile  func(fileID string, recipients []string, message string) error
+	OnUploadFile func(filePath string, expiration time.Duration, opts models.UploadOptions) error
+	OnChangeBandwidthLimit       func(kbps int) error
+	OnChangeUploadBandwidthLimit func(fileID string, kbps int) error
+	OnUploadClipboard func(text string) error
+	OnShareFile  func(fileID string, recipients []string, message string, opts models.ShareOptions) error
 	OnDeleteFile func(fileID string) error
+	OnRetryUpload  func(fileID string) error
 	OnRefreshFiles func() ([]models.FileMetadata, error)
+	OnFilterFiles  func(query models.FileQuery) ([]models.FileMetadata, error)
 	OnGeneratePresignedURL func(fileID string, expiration time.Duration) (string, error)
+	OnCopyShareLink func(shareID string) (string, error)
+	OnRevokeShare   func(shareID string) error
+	OnDownloadFile func(fileID string, destPath string) error
 	OnSaveSettings func(settings *models.ApplicationSettings) error
 	OnLoadSettings func() (*models.ApplicationSettings, error)
+	OnSwitchProfile func(name string) error
+	OnLoadProfile   func(name string) (*models.Profile, error)
+	OnSaveProfile   func(profile *models.Profile, accessKey, secretKey string) error
+	OnDeleteProfile func(name string) error
+	OnProvisionBucket func(req *models.ProvisionRequest) (*models.ProvisionResult, error)
+	OnCheckDrift      func(req *models.ProvisionRequest) (*models.StackDriftReport, error)
+	OnCheckBucketHealth func() (*models.HealthReport, error)
+	OnLoadNotifications  func() ([]*models.Notification, error)
+	OnClearNotifications func() error
 }
 
 // NewMainWindow creates a new main window
@@ -45,12 +97,14 @@ func NewMainWindow(app fyne.App) *MainWindow {
 	window.SetIcon(theme.DocumentIcon())
 
 	mw := &MainWindow{
-		app:    app,
-		window: window,
-		files:  []models.FileMetadata{},
+		app:       app,
+		window:    window,
+		files:     []models.FileMetadata{},
+		fileLimit: models.DefaultFilePageSize,
 	}
 
 	mw.setupUI()
+	mw.setupTray()
 	return mw
 }
 
@@ -65,11 +119,36 @@ func (mw *MainWindow) GetWindow() fyne.Window {
 }
 
 // Callback setters for interface compliance
-func (mw *MainWindow) SetOnUploadFile(callback func(filePath string, expiration time.Duration) error) {
+func (mw *MainWindow) SetOnUploadFile(callback func(filePath string, expiration time.Duration, opts models.UploadOptions) error) {
 	mw.OnUploadFile = callback
 }
 
-func (mw *MainWindow) SetOnShareFile(callback func(fileID string, recipients []string, message string) error) {
+// SetOnChangeBandwidthLimit sets the callback that changes the upload speed limit shared by all uploads
+func (mw *MainWindow) SetOnChangeBandwidthLimit(callback func(kbps int) error) {
+	mw.OnChangeBandwidthLimit = callback
+}
+
+// SetOnChangeUploadBandwidthLimit sets the callback that changes the speed limit of a single running upload
+func (mw *MainWindow) SetOnChangeUploadBandwidthLimit(callback func(fileID string, kbps int) error) {
+	mw.OnChangeUploadBandwidthLimit = callback
+}
+
+// SetOnUploadClipboard sets the callback for uploading the clipboard text from the tray
+func (mw *MainWindow) SetOnUploadClipboard(callback func(text string) error) {
+	mw.OnUploadClipboard = callback
+}
+
+// SetOnCopyShareLink sets the callback that signs a recent share's link when it is copied from the tray
+func (mw *MainWindow) SetOnCopyShareLink(callback func(shareID string) (string, error)) {
+	mw.OnCopyShareLink = callback
+}
+
+// SetOnRevokeShare sets the callback that revokes a recent share's short link from the tray
+func (mw *MainWindow) SetOnRevokeShare(callback func(shareID string) error) {
+	mw.OnRevokeShare = callback
+}
+
+func (mw *MainWindow) SetOnShareFile(callback func(fileID string, recipients []string, message string, opts models.ShareOptions) error) {
 	mw.OnShareFile = callback
 }
 
@@ -77,14 +156,28 @@ func (mw *MainWindow) SetOnDeleteFile(callback func(fileID string) error) {
 	mw.OnDeleteFile = callback
 }
 
+// SetOnRetryUpload sets the callback for uploading a file again after its upload failed
+func (mw *MainWindow) SetOnRetryUpload(callback func(fileID string) error) {
+	mw.OnRetryUpload = callback
+}
+
 func (mw *MainWindow) SetOnRefreshFiles(callback func() ([]models.FileMetadata, error)) {
 	mw.OnRefreshFiles = callback
 }
 
+// SetOnFilterFiles sets the callback for searching, filtering and sorting the file list
+func (mw *MainWindow) SetOnFilterFiles(callback func(query models.FileQuery) ([]models.FileMetadata, error)) {
+	mw.OnFilterFiles = callback
+}
+
 func (mw *MainWindow) SetOnGeneratePresignedURL(callback func(fileID string, expiration time.Duration) (string, error)) {
 	mw.OnGeneratePresignedURL = callback
 }
 
+func (mw *MainWindow) SetOnDownloadFile(callback func(fileID string, destPath string) error) {
+	mw.OnDownloadFile = callback
+}
+
 func (mw *MainWindow) SetOnSaveSettings(callback func(settings *models.ApplicationSettings) error) {
 	mw.OnSaveSettings = callback
 }
@@ -93,11 +186,80 @@ func (mw *MainWindow) SetOnLoadSettings(callback func() (*models.ApplicationSett
 	mw.OnLoadSettings = callback
 }
 
+func (mw *MainWindow) SetOnSwitchProfile(callback func(name string) error) {
+	mw.OnSwitchProfile = callback
+}
+
+func (mw *MainWindow) SetOnLoadProfile(callback func(name string) (*models.Profile, error)) {
+	mw.OnLoadProfile = callback
+}
+
+func (mw *MainWindow) SetOnSaveProfile(callback func(profile *models.Profile, accessKey, secretKey string) error) {
+	mw.OnSaveProfile = callback
+}
+
+func (mw *MainWindow) SetOnDeleteProfile(callback func(name string) error) {
+	mw.OnDeleteProfile = callback
+}
+
+// SetOnProvisionBucket sets the callback for provisioning a bucket from the app
+func (mw *MainWindow) SetOnProvisionBucket(callback func(req *models.ProvisionRequest) (*models.ProvisionResult, error)) {
+	mw.OnProvisionBucket = callback
+}
+
+// SetOnCheckDrift sets the callback for checking a provisioned stack for drift
+func (mw *MainWindow) SetOnCheckDrift(callback func(req *models.ProvisionRequest) (*models.StackDriftReport, error)) {
+	mw.OnCheckDrift = callback
+}
+
+// SetOnCheckBucketHealth sets the callback for checking the active bucket's configuration
+func (mw *MainWindow) SetOnCheckBucketHealth(callback func() (*models.HealthReport, error)) {
+	mw.OnCheckBucketHealth = callback
+}
+
+// SetOnLoadNotifications sets the callback for loading the notification history
+func (mw *MainWindow) SetOnLoadNotifications(callback func() ([]*models.Notification, error)) {
+	mw.OnLoadNotifications = callback
+}
+
+// SetOnClearNotifications sets the callback for clearing the notification history
+func (mw *MainWindow) SetOnClearNotifications(callback func() error) {
+	mw.OnClearNotifications = callback
+}
+
+// SetProfiles updates the profile switcher with the available profiles and the active one
+func (mw *MainWindow) SetProfiles(names []string, active string) {
+	mw.updatingProfiles = true
+	defer func() { mw.updatingProfiles = false }()
+	
+	mw.profileSelect.Options = names
+	mw.profileSelect.SetSelected(active)
+	mw.profileSelect.Refresh()
+}
+
+// ShowBandwidthLimit shows the upload speed limit shared by all uploads in the toolbar
+func (mw *MainWindow) ShowBandwidthLimit(kbps int) {
+	mw.updatingBandwidth = true
+	defer func() { mw.updatingBandwidth = false }()
+	
+	options, selected := bandwidthLabel(kbps, bandwidthLabels())
+	mw.bandwidthSelect.Options = options
+	mw.bandwidthSelect.SetSelected(selected)
+	mw.bandwidthSelect.Refresh()
+}
+
 // UpdateFiles updates the file list display
 func (mw *MainWindow) UpdateFiles(files []models.FileMetadata) {
 	mw.files = files
 	mw.fileList.Refresh()
 	
+	// A full page means there may be more files to load
+	if len(files) >= mw.fileLimit {
+		mw.showMoreBtn.Show()
+	} else {
+		mw.showMoreBtn.Hide()
+	}
+	
 	// Update empty state visibility
 	mw.updateEmptyState()
 }
@@ -109,11 +271,60 @@ func (mw *MainWindow) updateEmptyState() {
 	mw.fileList.Refresh()
 }
 
+// ShowShareEmail shows the email to send recipients of a share, with options to copy it
+// or open it in the default mail client. Safe to call from any goroutine.
+func (mw *MainWindow) ShowShareEmail(email *models.ShareEmail) {
+	fyne.Do(func() {
+		body := widget.NewMultiLineEntry()
+		body.SetText(email.Body)
+		body.Wrapping = fyne.TextWrapWord
+		body.SetMinRowsVisible(12)
+		
+		copyBtn := widget.NewButtonWithIcon("Copy", theme.ContentCopyIcon(), func() {
+			mw.window.Clipboard().SetContent(email.Body)
+		})
+		mailBtn := widget.NewButtonWithIcon("Open in Mail App", theme.MailComposeIcon(), func() {
+			mailto, err := url.Parse(email.MailtoURL())
+			if err == nil {
+				err = mw.app.OpenURL(mailto)
+			}
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to open mail app: %v", err), mw.window)
+			}
+		})
+		
+		content := container.NewBorder(
+			widget.NewLabel(fmt.Sprintf("To: %s\nSubject: %s", strings.Join(email.Recipients, ", "), email.Subject)),
+			container.NewHBox(copyBtn, mailBtn),
+			nil, nil,
+			body,
+		)
+		
+		emailDialog := dialog.NewCustom("Email Recipients", "Close", content, mw.window)
+		emailDialog.Resize(fyne.NewSize(600, 450))
+		emailDialog.Show()
+	})
+}
+
 // SetStatus updates the status label
 func (mw *MainWindow) SetStatus(status string) {
 	mw.statusLabel.SetText(status)
 }
 
+// SetSyncSchedule shows when the file list was last synced with S3 and when the next automatic sync runs.
+// A zero nextSync means automatic sync is off. Safe to call from any goroutine.
+func (mw *MainWindow) SetSyncSchedule(lastSynced, nextSync time.Time) {
+	text := formatSyncSchedule(lastSynced, nextSync, time.Now())
+	fyne.Do(func() {
+		mw.scheduleLabel.SetText(text)
+	})
+}
+
+// SendNotification shows a native desktop notification. Safe to call from any goroutine.
+func (mw *MainWindow) SendNotification(title, content string) {
+	mw.app.SendNotification(fyne.NewNotification(title, content))
+}
+
 // EnableActions enables/disables action buttons
 func (mw *MainWindow) EnableActions(enabled bool) {
 	if enabled {
@@ -138,6 +349,10 @@ func (mw *MainWindow) createComponents() {
 	// Status label
 	mw.statusLabel = widget.NewLabel("Ready - Configure AWS credentials to begin")
 	mw.statusLabel.TextStyle = fyne.TextStyle{Italic: true}
+	
+	// Sync schedule, shown next to the status
+	mw.scheduleLabel = widget.NewLabel(formatSyncSchedule(time.Time{}, time.Time{}, time.Now()))
+	mw.scheduleLabel.TextStyle = fyne.TextStyle{Italic: true}
 
 	// Action buttons
 	mw.uploadBtn = widget.NewButton("Upload Files", mw.showUploadDialog)
@@ -151,12 +366,37 @@ func (mw *MainWindow) createComponents() {
 	mw.refreshBtn.Icon = theme.ViewRefreshIcon()
 	mw.refreshBtn.Disable()
 
+	mw.notificationsBtn = widget.NewButton("Notifications", mw.showNotificationHistory)
+	mw.notificationsBtn.Icon = theme.InfoIcon()
+
+	// Upload speed limit shared by all uploads, applied to running uploads too
+	mw.bandwidthSelect = widget.NewSelect(bandwidthLabels(), mw.changeBandwidthLimit)
+	mw.updatingBandwidth = true
+	mw.bandwidthSelect.SetSelected(models.FormatBandwidth(0))
+	mw.updatingBandwidth = false
+
+	// Profile switcher
+	mw.profileSelect = widget.NewSelect([]string{}, mw.switchProfile)
+	mw.profileSelect.PlaceHolder = "Profile"
+
+	mw.editProfileBtn = widget.NewButton("Edit Profile", mw.showEditProfileDialog)
+	mw.editProfileBtn.Icon = theme.DocumentCreateIcon()
+
+	mw.newProfileBtn = widget.NewButton("New Profile", mw.showNewProfileDialog)
+	mw.newProfileBtn.Icon = theme.ContentAddIcon()
+
+	mw.provisionBtn = widget.NewButton("Provision Bucket", mw.showProvisionDialog)
+	mw.provisionBtn.Icon = theme.StorageIcon()
+
 	// File list
 	mw.fileList = widget.NewList(
 		func() int { return len(mw.files) },
 		func() fyne.CanvasObject { return mw.createFileListItem() },
 		func(id widget.ListItemID, obj fyne.CanvasObject) { mw.updateFileListItem(id, obj) },
 	)
+	
+	mw.showMoreBtn = widget.NewButton("Show More Files", mw.showMoreFiles)
+	mw.showMoreBtn.Hide()
 }
 
 func (mw *MainWindow) createLayout() *fyne.Container {
@@ -172,6 +412,16 @@ func (mw *MainWindow) createLayout() *fyne.Container {
 		mw.refreshBtn,
 		widget.NewSeparator(),
 		mw.settingsBtn,
+		mw.notificationsBtn,
+		widget.NewSeparator(),
+		widget.NewLabel("Upload speed:"),
+		mw.bandwidthSelect,
+		widget.NewSeparator(),
+		widget.NewLabel("Profile:"),
+		mw.profileSelect,
+		mw.editProfileBtn,
+		mw.newProfileBtn,
+		mw.provisionBtn,
 	)
 
 	// Files section header
@@ -205,13 +455,14 @@ func (mw *MainWindow) createLayout() *fyne.Container {
 			toolbar,
 			widget.NewSeparator(),
 			filesHeader,
+			mw.createFilterBar(),
 		),
 		// Bottom
-		mw.statusLabel,
+		container.NewBorder(nil, nil, nil, mw.scheduleLabel, mw.statusLabel),
 		// Left, Right
 		nil, nil,
 		// Center
-		fileContainer,
+		container.NewBorder(nil, mw.showMoreBtn, nil, nil, fileContainer),
 	)
 
 	return content
@@ -239,6 +490,19 @@ func (mw *MainWindow) createFileListItem() fyne.CanvasObject {
 	shareBtn := widget.NewButton("Share", nil)
 	shareBtn.Icon = theme.MailSendIcon()
 
+	downloadBtn := widget.NewButton("Download", nil)
+	downloadBtn.Icon = theme.DownloadIcon()
+
+	// Only shown for files whose upload failed
+	retryBtn := widget.NewButton("Retry", nil)
+	retryBtn.Icon = theme.ViewRefreshIcon()
+	retryBtn.Hide()
+
+	// Only shown for files being uploaded
+	speedBtn := widget.NewButton("Speed", nil)
+	speedBtn.Icon = theme.MediaFastForwardIcon()
+	speedBtn.Hide()
+
 	deleteBtn := widget.NewButton("Delete", nil)
 	deleteBtn.Icon = theme.DeleteIcon()
 	deleteBtn.Importance = widget.DangerImportance
@@ -258,6 +522,9 @@ func (mw *MainWindow) createFileListItem() fyne.CanvasObject {
 	actionContainer := container.NewHBox(
 		copyLinkBtn,
 		shareBtn,
+		downloadBtn,
+		retryBtn,
+		speedBtn,
 		deleteBtn,
 	)
 
@@ -291,14 +558,14 @@ func (mw *MainWindow) updateFileListItem(id widget.ListItemID, obj fyne.CanvasOb
 	sizeInfoContainer := infoContainer.Objects[1].(*fyne.Container)
 	sizeLabel := sizeInfoContainer.Objects[0].(*widget.Label)
 	dateLabel := sizeInfoContainer.Objects[2].(*widget.Label)
-	sizeLabel.SetText(formatFileSize(file.FileSize))
+	sizeLabel.SetText(formatStoredSize(file))
 	dateLabel.SetText(formatRelativeTime(file.UploadDate))
 
 	expirationInfoContainer := infoContainer.Objects[2].(*fyne.Container)
 	expirationLabel := expirationInfoContainer.Objects[0].(*widget.Label)
 	statusLabel := expirationInfoContainer.Objects[2].(*widget.Label)
 	expirationLabel.SetText(formatExpiration(file.ExpirationDate))
-	statusLabel.SetText(formatStatus(file.Status))
+	statusLabel.SetText(formatFileStatus(file))
 
 	// Update progress bar visibility
 	progressBar := infoContainer.Objects[3].(*widget.ProgressBar)
@@ -312,21 +579,48 @@ func (mw *MainWindow) updateFileListItem(id widget.ListItemID, obj fyne.CanvasOb
 	// Update action buttons
 	copyLinkBtn := actionContainer.Objects[0].(*widget.Button)
 	shareBtn := actionContainer.Objects[1].(*widget.Button)
-	deleteBtn := actionContainer.Objects[2].(*widget.Button)
+	downloadBtn := actionContainer.Objects[2].(*widget.Button)
+	retryBtn := actionContainer.Objects[3].(*widget.Button)
+	speedBtn := actionContainer.Objects[4].(*widget.Button)
+	deleteBtn := actionContainer.Objects[5].(*widget.Button)
 
 	// Set button callbacks
 	copyLinkBtn.OnTapped = func() { mw.copyFileLink(file.ID) }
 	shareBtn.OnTapped = func() { mw.showSharingDialog(file) }
+	downloadBtn.OnTapped = func() { mw.downloadFile(file) }
+	retryBtn.OnTapped = func() { mw.retryUpload(file) }
+	speedBtn.OnTapped = func() { mw.showUploadSpeedDialog(file) }
 	deleteBtn.OnTapped = func() { mw.confirmDeleteFile(file) }
 
 	// Enable/disable buttons based on file status
-	canShare := file.Status == models.StatusActive
-	copyLinkBtn.Enable()
+	// SSE-C files can't be opened from a link, only downloaded with the local key
+	// Files waiting to upload can be shared; the share is queued behind the upload
+	canShare := (file.Status == models.StatusActive || file.Status == models.StatusPending) && file.EncryptionMode != models.EncryptionSSEC
+	if file.EncryptionMode == models.EncryptionSSEC || file.Status == models.StatusPending {
+		copyLinkBtn.Disable()
+	} else {
+		copyLinkBtn.Enable()
+	}
 	if canShare {
 		shareBtn.Enable()
 	} else {
 		shareBtn.Disable()
 	}
+	if file.Status == models.StatusActive {
+		downloadBtn.Enable()
+	} else {
+		downloadBtn.Disable()
+	}
+	if file.Status == models.StatusError && mw.OnRetryUpload != nil {
+		retryBtn.Show()
+	} else {
+		retryBtn.Hide()
+	}
+	if file.Status == models.StatusUploading && mw.OnChangeUploadBandwidthLimit != nil {
+		speedBtn.Show()
+	} else {
+		speedBtn.Hide()
+	}
 	deleteBtn.Enable()
 
 	// Apply status-based styling
@@ -350,10 +644,139 @@ func (mw *MainWindow) showSettingsDialog() {
 	if mw.OnLoadSettings != nil && mw.OnSaveSettings != nil {
 		settingsDialog.SetCallbacks(mw.OnSaveSettings, mw.OnLoadSettings)
 	}
+	settingsDialog.SetHealthCheckCallback(mw.OnCheckBucketHealth)
 	
 	settingsDialog.Show()
 }
 
+func (mw *MainWindow) showNotificationHistory() {
+	if mw.OnLoadNotifications == nil {
+		dialog.ShowInformation("Notifications", "Notifications are not available", mw.window)
+		return
+	}
+	
+	notifications, err := mw.OnLoadNotifications()
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to load notifications: %v", err), mw.window)
+		return
+	}
+	
+	now := time.Now()
+	list := widget.NewList(
+		func() int { return len(notifications) },
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Wrapping = fyne.TextWrapWord
+			return label
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			obj.(*widget.Label).SetText(formatNotification(notifications[id], now))
+		},
+	)
+	
+	var content fyne.CanvasObject = list
+	if len(notifications) == 0 {
+		content = container.NewCenter(widget.NewLabel("No notifications yet"))
+	}
+	
+	var historyDialog dialog.Dialog
+	clearBtn := widget.NewButtonWithIcon("Clear History", theme.DeleteIcon(), func() {
+		if mw.OnClearNotifications == nil {
+			return
+		}
+		if err := mw.OnClearNotifications(); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to clear notifications: %v", err), mw.window)
+			return
+		}
+		historyDialog.Hide()
+	})
+	if len(notifications) == 0 {
+		clearBtn.Disable()
+	}
+	
+	historyDialog = dialog.NewCustom("Notifications", "Close", container.NewBorder(nil, clearBtn, nil, nil, content), mw.window)
+	historyDialog.Resize(fyne.NewSize(600, 450))
+	historyDialog.Show()
+}
+
+// changeBandwidthLimit applies the upload speed chosen in the toolbar
+func (mw *MainWindow) changeBandwidthLimit(selected string) {
+	if mw.updatingBandwidth || mw.OnChangeBandwidthLimit == nil {
+		return
+	}
+	
+	if err := mw.OnChangeBandwidthLimit(parseBandwidth(selected)); err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to change upload speed: %v", err), mw.window)
+	}
+}
+
+// showUploadSpeedDialog lets the speed limit of a running upload be changed while it runs
+func (mw *MainWindow) showUploadSpeedDialog(file models.FileMetadata) {
+	speedSelect := widget.NewSelect(bandwidthLabels(), func(selected string) {
+		if err := mw.OnChangeUploadBandwidthLimit(file.ID, parseBandwidth(selected)); err != nil {
+			dialog.ShowError(err, mw.window)
+		}
+	})
+	speedSelect.PlaceHolder = "Choose a speed limit"
+	
+	content := container.NewVBox(
+		widget.NewLabel(fmt.Sprintf("Speed limit for uploading %s:", file.FileName)),
+		speedSelect,
+		widget.NewLabel("The upload speed in the toolbar still applies to all uploads."),
+	)
+	dialog.ShowCustom("Upload Speed", "Close", content, mw.window)
+}
+
+func (mw *MainWindow) switchProfile(name string) {
+	if mw.updatingProfiles || mw.OnSwitchProfile == nil {
+		return
+	}
+	
+	mw.SetStatus(fmt.Sprintf("Switching to profile %s...", name))
+	go func() {
+		if err := mw.OnSwitchProfile(name); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to switch profile: %v", err), mw.window)
+		}
+	}()
+}
+
+func (mw *MainWindow) showEditProfileDialog() {
+	if mw.OnLoadProfile == nil || mw.profileSelect.Selected == "" {
+		dialog.ShowInformation("Edit Profile", "No profile selected", mw.window)
+		return
+	}
+	
+	profile, err := mw.OnLoadProfile(mw.profileSelect.Selected)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to load profile: %v", err), mw.window)
+		return
+	}
+	
+	profileDialog := NewProfileDialog(mw.window, profile)
+	profileDialog.SetCallbacks(mw.OnSaveProfile, mw.OnDeleteProfile)
+	profileDialog.Show()
+}
+
+func (mw *MainWindow) showNewProfileDialog() {
+	profileDialog := NewProfileDialog(mw.window, nil)
+	profileDialog.SetCallbacks(mw.OnSaveProfile, mw.OnDeleteProfile)
+	profileDialog.Show()
+}
+
+func (mw *MainWindow) showProvisionDialog() {
+	// Pre-fill from the selected profile so its stack can be checked for drift or updated
+	var profile *models.Profile
+	if mw.OnLoadProfile != nil && mw.profileSelect.Selected != "" {
+		if loaded, err := mw.OnLoadProfile(mw.profileSelect.Selected); err == nil {
+			profile = loaded
+		}
+	}
+	
+	provisionDialog := NewProvisionDialog(mw.window, profile)
+	provisionDialog.SetCallbacks(mw.OnProvisionBucket, mw.OnCheckDrift)
+	provisionDialog.Show()
+}
+
 func (mw *MainWindow) refreshFiles() {
 	if mw.OnRefreshFiles != nil {
 		mw.SetStatus("Refreshing files...")
@@ -367,23 +790,70 @@ func (mw *MainWindow) refreshFiles() {
 }
 
 func (mw *MainWindow) copyFileLink(fileID string) {
-	// Generate presigned URL and copy to clipboard
-	if mw.OnGeneratePresignedURL != nil {
-		go func() {
-			// Generate URL with 24 hour expiration
-			url, err := mw.OnGeneratePresignedURL(fileID, 24*time.Hour)
-			if err != nil {
-				dialog.ShowError(fmt.Errorf("Failed to generate sharing link: %v", err), mw.window)
-				return
-			}
-			
-			// Copy to clipboard
-			mw.window.Clipboard().SetContent(url)
-			dialog.ShowInformation("Link Copied", "Sharing link has been copied to clipboard", mw.window)
-		}()
-	} else {
+	if mw.OnGeneratePresignedURL == nil {
 		dialog.ShowInformation("Copy Link", "Link generation not available - AWS not configured", mw.window)
+		return
+	}
+	
+	// Presigned links work for at most a week
+	lifetimeSelect := widget.NewSelect([]string{"1 hour", "1 day", "1 week"}, nil)
+	lifetimeSelect.SetSelected("1 day")
+	
+	dialog.ShowForm("Copy Link", "Copy", "Cancel",
+		[]*widget.FormItem{widget.NewFormItem("Link valid for", lifetimeSelect)},
+		func(confirmed bool) {
+			if confirmed {
+				mw.copyPresignedLink(fileID, parseLinkLifetime(lifetimeSelect.Selected))
+			}
+		}, mw.window)
+}
+
+// copyPresignedLink generates a presigned URL valid for the lifetime and copies it to the clipboard
+func (mw *MainWindow) copyPresignedLink(fileID string, lifetime time.Duration) {
+	go func() {
+		url, err := mw.OnGeneratePresignedURL(fileID, lifetime)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to generate sharing link: %v", err), mw.window)
+			return
+		}
+		
+		// Copy to clipboard
+		mw.window.Clipboard().SetContent(url)
+		dialog.ShowInformation("Link Copied", "Sharing link has been copied to clipboard", mw.window)
+	}()
+}
+
+func (mw *MainWindow) downloadFile(file models.FileMetadata) {
+	if mw.OnDownloadFile == nil {
+		dialog.ShowInformation("Download", "Download not available - AWS not configured", mw.window)
+		return
 	}
+
+	saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, mw.window)
+			return
+		}
+		if writer == nil {
+			return // cancelled
+		}
+		destPath := writer.URI().Path()
+		// The download writes the file itself; release the handle the dialog created
+		writer.Close()
+
+		go func() {
+			err := mw.OnDownloadFile(file.ID, destPath)
+			fyne.Do(func() {
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("Failed to download file: %v", err), mw.window)
+					return
+				}
+				dialog.ShowInformation("Download Complete", fmt.Sprintf("Saved '%s' to %s", file.FileName, destPath), mw.window)
+			})
+		}()
+	}, mw.window)
+	saveDialog.SetFileName(file.FileName)
+	saveDialog.Show()
 }
 
 func (mw *MainWindow) confirmDeleteFile(file models.FileMetadata) {
@@ -403,6 +873,19 @@ func (mw *MainWindow) confirmDeleteFile(file models.FileMetadata) {
 	)
 }
 
+// retryUpload uploads a file again after its upload failed
+func (mw *MainWindow) retryUpload(file models.FileMetadata) {
+	if mw.OnRetryUpload == nil {
+		return
+	}
+	
+	if err := mw.OnRetryUpload(file.ID); err != nil {
+		dialog.ShowError(err, mw.window)
+		return
+	}
+	mw.refreshFiles()
+}
+
 func (mw *MainWindow) applyStatusStyling(obj fyne.CanvasObject, status models.FileStatus) {
 	// Apply visual styling based on file status
 	// This is a simplified approach - in a real app you might use custom themes
@@ -421,6 +904,9 @@ func (mw *MainWindow) applyStatusStyling(obj fyne.CanvasObject, status models.Fi
 	case models.StatusError:
 		// Red tint for error files
 		border.Objects[0].(*fyne.Container).Objects[0].(*widget.Icon).SetResource(theme.ErrorIcon())
+	case models.StatusPending:
+		// Clock for files waiting to be uploaded
+		border.Objects[0].(*fyne.Container).Objects[0].(*widget.Icon).SetResource(theme.HistoryIcon())
 	default:
 		border.Objects[0].(*fyne.Container).Objects[0].(*widget.Icon).SetResource(theme.DocumentIcon())
 	}
@@ -440,6 +926,15 @@ func formatFileSize(bytes int64) string {
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
 
+// formatStoredSize formats a file's size, with the size stored in S3 and the ratio when it was compressed
+func formatStoredSize(file models.FileMetadata) string {
+	ratio := file.CompressionRatio()
+	if file.Compression == "" || ratio == 0 {
+		return formatFileSize(file.FileSize)
+	}
+	return fmt.Sprintf("%s (%s stored with %s, %.0f%%)", formatFileSize(file.FileSize), formatFileSize(file.StoredSize), file.Compression, ratio*100)
+}
+
 func formatRelativeTime(t time.Time) string {
 	now := time.Now()
 	diff := now.Sub(t)
@@ -471,6 +966,39 @@ func formatExpiration(t time.Time) string {
 	}
 }
 
+// formatSyncSchedule describes the last and next sync as clock times, which stay correct without refreshing
+func formatSyncSchedule(lastSynced, nextSync, now time.Time) string {
+	last := "Never synced"
+	if !lastSynced.IsZero() {
+		last = "Last synced " + formatClockTime(lastSynced, now)
+	}
+	
+	next := "Auto sync off"
+	if !nextSync.IsZero() {
+		next = "Next sync " + formatClockTime(nextSync, now)
+	}
+	
+	return last + " • " + next
+}
+
+// formatClockTime formats a time of day, adding the date when it isn't today
+func formatClockTime(t, now time.Time) string {
+	t = t.In(now.Location())
+	if t.Year() == now.Year() && t.YearDay() == now.YearDay() {
+		return t.Format("15:04")
+	}
+	return t.Format("Jan 2 15:04")
+}
+
+// formatNotification formats a notification for the history, with the time it was sent
+func formatNotification(notification *models.Notification, now time.Time) string {
+	text := formatClockTime(notification.CreatedAt, now) + "  " + notification.Title
+	if notification.Content != "" {
+		text += "\n" + notification.Content
+	}
+	return text
+}
+
 func formatStatus(status models.FileStatus) string {
 	switch status {
 	case models.StatusUploading:
@@ -483,7 +1011,24 @@ func formatStatus(status models.FileStatus) string {
 		return "Deleted"
 	case models.StatusError:
 		return "Error"
+	case models.StatusPending:
+		return "Pending"
 	default:
 		return string(status)
 	}
-}
\ No newline at end of file
+}
+
+// formatFileStatus describes a file's status, preferring when a scheduled upload starts and then any
+// operation queued for it while offline. Uploads blocked by the virus scanner say so.
+func formatFileStatus(file models.FileMetadata) string {
+	if !file.ScheduledAt.IsZero() {
+		return "Upload scheduled for " + formatClockTime(file.ScheduledAt, time.Now())
+	}
+	if file.PendingOperation != "" {
+		return file.PendingOperation.Description()
+	}
+	if file.Status == models.StatusError && file.ScanVerdict == models.ScanInfected {
+		return "Blocked: virus found"
+	}
+	return formatStatus(file.Status)
+}
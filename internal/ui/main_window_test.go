@@ -6,7 +6,9 @@ import (
 
 	"file-sharing-app/internal/models"
 
+	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
 )
 
 func TestMainWindow_Creation(t *testing.T) {
@@ -86,6 +88,88 @@ func TestMainWindow_SetStatus(t *testing.T) {
 	}
 }
 
+func TestMainWindow_SetSyncSchedule(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	mainWindow := NewMainWindow(testApp)
+
+	if mainWindow.scheduleLabel.Text != "Never synced • Auto sync off" {
+		t.Errorf("Unexpected initial schedule '%s'", mainWindow.scheduleLabel.Text)
+	}
+
+	now := time.Now()
+	mainWindow.SetSyncSchedule(now, now.Add(time.Minute))
+
+	expected := formatSyncSchedule(now, now.Add(time.Minute), now)
+	if mainWindow.scheduleLabel.Text != expected {
+		t.Errorf("Expected schedule '%s', got '%s'", expected, mainWindow.scheduleLabel.Text)
+	}
+}
+
+func TestFormatSyncSchedule(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name       string
+		lastSynced time.Time
+		nextSync   time.Time
+		expected   string
+	}{
+		{"never synced, auto sync off", time.Time{}, time.Time{}, "Never synced • Auto sync off"},
+		{"never synced, sync scheduled", time.Time{}, now.Add(15 * time.Minute), "Never synced • Next sync 12:15"},
+		{"synced today", now.Add(-5 * time.Minute), now.Add(10 * time.Minute), "Last synced 11:55 • Next sync 12:10"},
+		{"synced yesterday", now.Add(-24 * time.Hour), time.Time{}, "Last synced Mar 14 12:00 • Auto sync off"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := formatSyncSchedule(tt.lastSynced, tt.nextSync, now)
+			if result != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestFormatNotification(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.Local)
+
+	notification := &models.Notification{
+		Title:     "Upload complete",
+		Content:   "report.pdf was uploaded",
+		CreatedAt: now.Add(-30 * time.Minute),
+	}
+	expected := "11:30  Upload complete\nreport.pdf was uploaded"
+	if result := formatNotification(notification, now); result != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, result)
+	}
+
+	notification = &models.Notification{Title: "Files missing from S3", CreatedAt: now.Add(-24 * time.Hour)}
+	expected = "Mar 14 12:00  Files missing from S3"
+	if result := formatNotification(notification, now); result != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, result)
+	}
+}
+
+func TestMainWindow_NotificationHistory(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	mainWindow := NewMainWindow(testApp)
+
+	loaded := false
+	mainWindow.SetOnLoadNotifications(func() ([]*models.Notification, error) {
+		loaded = true
+		return []*models.Notification{{Title: "Upload complete", CreatedAt: time.Now()}}, nil
+	})
+
+	mainWindow.showNotificationHistory()
+	if !loaded {
+		t.Error("Notification history should be loaded when shown")
+	}
+}
+
 func TestMainWindow_EnableActions(t *testing.T) {
 	// Create test app
 	testApp := test.NewApp()
@@ -107,6 +191,45 @@ func TestMainWindow_EnableActions(t *testing.T) {
 	}
 }
 
+func TestMainWindow_RetryUpload(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	mainWindow := NewMainWindow(testApp)
+
+	var retried []string
+	mainWindow.SetOnRetryUpload(func(fileID string) error {
+		retried = append(retried, fileID)
+		return nil
+	})
+
+	mainWindow.UpdateFiles([]models.FileMetadata{
+		{ID: "failed", FileName: "failed.txt", Status: models.StatusError},
+		{ID: "active", FileName: "active.txt", Status: models.StatusActive},
+	})
+
+	// retryButton returns the Retry button of the file list row showing a file
+	retryButton := func(id int) *widget.Button {
+		item := mainWindow.createFileListItem()
+		mainWindow.updateFileListItem(id, item)
+		actions := item.(*fyne.Container).Objects[1].(*fyne.Container)
+		return actions.Objects[3].(*widget.Button)
+	}
+
+	retryBtn := retryButton(0)
+	if !retryBtn.Visible() {
+		t.Fatal("Expected a Retry button for a failed upload")
+	}
+	test.Tap(retryBtn)
+	if len(retried) != 1 || retried[0] != "failed" {
+		t.Errorf("Expected the failed upload to be retried, got %v", retried)
+	}
+
+	if retryButton(1).Visible() {
+		t.Error("Expected no Retry button for an active file")
+	}
+}
+
 func TestFormatFileSize(t *testing.T) {
 	tests := []struct {
 		bytes    int64
@@ -127,6 +250,26 @@ func TestFormatFileSize(t *testing.T) {
 	}
 }
 
+func TestFormatStoredSize(t *testing.T) {
+	tests := []struct {
+		file     models.FileMetadata
+		expected string
+	}{
+		{models.FileMetadata{FileSize: 2048}, "2.0 KB"},
+		{models.FileMetadata{FileSize: 2048, StoredSize: 2048}, "2.0 KB"},
+		{models.FileMetadata{FileSize: 4096, StoredSize: 1024, Compression: "gzip"}, "4.0 KB (1.0 KB stored with gzip, 25%)"},
+		// Files uploaded before the stored size was recorded
+		{models.FileMetadata{FileSize: 4096, Compression: "zstd"}, "4.0 KB"},
+	}
+
+	for _, test := range tests {
+		result := formatStoredSize(test.file)
+		if result != test.expected {
+			t.Errorf("formatStoredSize(%d, %d, %q) = %s, expected %s", test.file.FileSize, test.file.StoredSize, test.file.Compression, result, test.expected)
+		}
+	}
+}
+
 func TestFormatStatus(t *testing.T) {
 	tests := []struct {
 		status   models.FileStatus
@@ -137,6 +280,7 @@ func TestFormatStatus(t *testing.T) {
 		{models.StatusExpired, "Expired"},
 		{models.StatusDeleted, "Deleted"},
 		{models.StatusError, "Error"},
+		{models.StatusPending, "Pending"},
 	}
 
 	for _, test := range tests {
@@ -145,4 +289,27 @@ func TestFormatStatus(t *testing.T) {
 			t.Errorf("formatStatus(%s) = %s, expected %s", test.status, result, test.expected)
 		}
 	}
+}
+
+func TestFormatFileStatus(t *testing.T) {
+	tests := []struct {
+		file     models.FileMetadata
+		expected string
+	}{
+		{models.FileMetadata{Status: models.StatusActive}, "Ready"},
+		{models.FileMetadata{Status: models.StatusPending, PendingOperation: models.OutboxUpload}, "Upload pending"},
+		{models.FileMetadata{Status: models.StatusActive, PendingOperation: models.OutboxShare}, "Share pending"},
+		{models.FileMetadata{Status: models.StatusActive, PendingOperation: models.OutboxDelete}, "Delete pending"},
+		{models.FileMetadata{Status: models.StatusPending, PendingOperation: models.OutboxUpload, ScheduledAt: time.Now().Add(time.Hour).Truncate(time.Minute)},
+			"Upload scheduled for " + formatClockTime(time.Now().Add(time.Hour).Truncate(time.Minute), time.Now())},
+		{models.FileMetadata{Status: models.StatusError, ScanVerdict: models.ScanInfected}, "Blocked: virus found"},
+		{models.FileMetadata{Status: models.StatusActive, ScanVerdict: models.ScanClean}, "Ready"},
+	}
+
+	for _, test := range tests {
+		result := formatFileStatus(test.file)
+		if result != test.expected {
+			t.Errorf("formatFileStatus(%s, %s) = %s, expected %s", test.file.Status, test.file.PendingOperation, result, test.expected)
+		}
+	}
 }
\ No newline at end of file
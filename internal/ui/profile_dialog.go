@@ -0,0 +1,301 @@
+package ui
+
+import (
+	"fmt"
+
+	"file-sharing-app/internal/models"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ProfileDialog represents the dialog for creating and editing profiles
+type ProfileDialog struct {
+	parent  fyne.Window
+	dialog  *dialog.CustomDialog
+	profile *models.Profile
+	editing bool
+
+	// Form widgets
+	nameEntry               *widget.Entry
+	awsRegionEntry          *widget.Entry
+	s3BucketEntry           *widget.Entry
+	defaultExpirationSelect *widget.Select
+	maxFileSizeEntry        *widget.Entry
+	encryptionModeSelect    *widget.Select
+	kmsKeyIDEntry           *widget.Entry
+	shareLinkURLEntry       *widget.Entry
+	accessKeyEntry          *widget.Entry
+	secretKeyEntry          *widget.Entry
+	deleteBtn               *widget.Button
+
+	// Callbacks
+	OnSaveProfile   func(profile *models.Profile, accessKey, secretKey string) error
+	OnDeleteProfile func(name string) error
+}
+
+// NewProfileDialog creates a dialog for a new profile, or for editing profile if it is not nil
+func NewProfileDialog(parent fyne.Window, profile *models.Profile) *ProfileDialog {
+	pd := &ProfileDialog{
+		parent:  parent,
+		profile: profile,
+		editing: profile != nil,
+	}
+
+	if pd.profile == nil {
+		pd.profile = models.NewProfile("")
+	}
+
+	pd.createDialog()
+	pd.populateForm()
+	return pd
+}
+
+// SetCallbacks sets the callback functions for profile operations
+func (pd *ProfileDialog) SetCallbacks(
+	onSave func(profile *models.Profile, accessKey, secretKey string) error,
+	onDelete func(name string) error,
+) {
+	pd.OnSaveProfile = onSave
+	pd.OnDeleteProfile = onDelete
+}
+
+// Show displays the profile dialog
+func (pd *ProfileDialog) Show() {
+	pd.dialog.Show()
+}
+
+// Hide closes the profile dialog
+func (pd *ProfileDialog) Hide() {
+	pd.dialog.Hide()
+}
+
+func (pd *ProfileDialog) createDialog() {
+	pd.createFormWidgets()
+
+	content := container.NewVBox(
+		pd.createFormLayout(),
+		widget.NewSeparator(),
+		pd.createActionButtons(),
+	)
+
+	title := "New Profile"
+	if pd.editing {
+		title = "Edit Profile"
+	}
+
+	pd.dialog = dialog.NewCustom(title, "Close", content, pd.parent)
+	pd.dialog.Resize(fyne.NewSize(500, 550))
+}
+
+func (pd *ProfileDialog) createFormWidgets() {
+	pd.nameEntry = widget.NewEntry()
+	pd.nameEntry.SetPlaceHolder("e.g., client-acme")
+
+	pd.awsRegionEntry = widget.NewEntry()
+	pd.awsRegionEntry.SetPlaceHolder("e.g., us-west-2")
+
+	pd.s3BucketEntry = widget.NewEntry()
+	pd.s3BucketEntry.SetPlaceHolder("e.g., acme-file-sharing-bucket")
+
+	pd.defaultExpirationSelect = widget.NewSelect(
+		[]string{"1h", "1d", "1w", "1m"},
+		nil,
+	)
+
+	pd.maxFileSizeEntry = widget.NewEntry()
+	pd.maxFileSizeEntry.SetPlaceHolder("100")
+
+	pd.kmsKeyIDEntry = widget.NewEntry()
+	pd.kmsKeyIDEntry.SetPlaceHolder("KMS key ID, ARN or alias/name")
+	pd.encryptionModeSelect = widget.NewSelect(models.EncryptionModes, pd.onEncryptionModeChanged)
+
+	pd.shareLinkURLEntry = widget.NewEntry()
+	pd.shareLinkURLEntry.SetPlaceHolder("Filled in when provisioning; empty for presigned links")
+
+	pd.accessKeyEntry = widget.NewEntry()
+	pd.secretKeyEntry = widget.NewPasswordEntry()
+	if pd.editing {
+		pd.accessKeyEntry.SetPlaceHolder("Leave empty to keep stored credentials")
+		pd.secretKeyEntry.SetPlaceHolder("Leave empty to keep stored credentials")
+	} else {
+		pd.accessKeyEntry.SetPlaceHolder("AKIA...")
+	}
+}
+
+func (pd *ProfileDialog) createFormLayout() *fyne.Container {
+	profileSection := widget.NewCard("Profile", "",
+		container.NewVBox(
+			widget.NewFormItem("Name", pd.nameEntry).Widget,
+		),
+	)
+
+	awsSection := widget.NewCard("AWS Configuration", "",
+		container.NewVBox(
+			widget.NewFormItem("AWS Region", pd.awsRegionEntry).Widget,
+			widget.NewFormItem("S3 Bucket", pd.s3BucketEntry).Widget,
+			widget.NewFormItem("Access Key ID", pd.accessKeyEntry).Widget,
+			widget.NewFormItem("Secret Access Key", pd.secretKeyEntry).Widget,
+			widget.NewFormItem("Short Link Address", pd.shareLinkURLEntry).Widget,
+		),
+	)
+
+	defaultsSection := widget.NewCard("Defaults", "",
+		container.NewVBox(
+			widget.NewFormItem("Default Expiration", pd.defaultExpirationSelect).Widget,
+			widget.NewFormItem("Max File Size (MB)", pd.maxFileSizeEntry).Widget,
+			widget.NewFormItem("Encryption", pd.encryptionModeSelect).Widget,
+			widget.NewFormItem("KMS Key", pd.kmsKeyIDEntry).Widget,
+		),
+	)
+
+	return container.NewVBox(
+		profileSection,
+		awsSection,
+		defaultsSection,
+	)
+}
+
+func (pd *ProfileDialog) createActionButtons() *fyne.Container {
+	saveBtn := widget.NewButton("Save Profile", pd.saveProfile)
+	saveBtn.Importance = widget.HighImportance
+	saveBtn.Icon = theme.DocumentSaveIcon()
+
+	pd.deleteBtn = widget.NewButton("Delete Profile", pd.confirmDeleteProfile)
+	pd.deleteBtn.Importance = widget.DangerImportance
+	pd.deleteBtn.Icon = theme.DeleteIcon()
+	if !pd.editing {
+		pd.deleteBtn.Hide()
+	}
+
+	cancelBtn := widget.NewButton("Cancel", func() {
+		pd.Hide()
+	})
+
+	return container.NewHBox(
+		pd.deleteBtn,
+		widget.NewSeparator(),
+		cancelBtn,
+		saveBtn,
+	)
+}
+
+func (pd *ProfileDialog) populateForm() {
+	pd.nameEntry.SetText(pd.profile.Name)
+	if pd.editing {
+		// Renaming would orphan the profile's keyring items and files
+		pd.nameEntry.Disable()
+	}
+
+	pd.awsRegionEntry.SetText(pd.profile.AWSRegion)
+	pd.s3BucketEntry.SetText(pd.profile.S3Bucket)
+	pd.defaultExpirationSelect.SetSelected(pd.profile.DefaultExpiration)
+	pd.maxFileSizeEntry.SetText(fmt.Sprintf("%.0f", float64(pd.profile.MaxFileSize)/(1024*1024)))
+	pd.kmsKeyIDEntry.SetText(pd.profile.KMSKeyID)
+	pd.shareLinkURLEntry.SetText(pd.profile.ShareLinkURL)
+	pd.encryptionModeSelect.SetSelected(pd.profile.GetEncryptionMode())
+}
+
+func (pd *ProfileDialog) saveProfile() {
+	if err := pd.validateForm(); err != nil {
+		dialog.ShowError(err, pd.parent)
+		return
+	}
+
+	pd.updateProfileFromForm()
+
+	if pd.OnSaveProfile != nil {
+		if err := pd.OnSaveProfile(pd.profile, pd.accessKeyEntry.Text, pd.secretKeyEntry.Text); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to save profile: %v", err), pd.parent)
+			return
+		}
+	}
+
+	pd.Hide()
+}
+
+func (pd *ProfileDialog) confirmDeleteProfile() {
+	dialog.ShowConfirm("Delete Profile",
+		fmt.Sprintf("Are you sure you want to delete profile '%s' and its stored credentials?", pd.profile.Name),
+		func(confirmed bool) {
+			if !confirmed || pd.OnDeleteProfile == nil {
+				return
+			}
+			if err := pd.OnDeleteProfile(pd.profile.Name); err != nil {
+				dialog.ShowError(fmt.Errorf("Failed to delete profile: %v", err), pd.parent)
+				return
+			}
+			pd.Hide()
+		}, pd.parent)
+}
+
+func (pd *ProfileDialog) validateForm() error {
+	if !models.IsValidProfileName(pd.nameEntry.Text) {
+		return fmt.Errorf("Profile name must be 1-32 letters, digits, '-' or '_'")
+	}
+
+	if pd.awsRegionEntry.Text == "" {
+		return fmt.Errorf("AWS region cannot be empty")
+	}
+
+	if pd.s3BucketEntry.Text == "" {
+		return fmt.Errorf("S3 bucket name cannot be empty")
+	}
+
+	if pd.defaultExpirationSelect.Selected == "" {
+		return fmt.Errorf("Please select a default expiration period")
+	}
+
+	if pd.maxFileSizeEntry.Text == "" {
+		return fmt.Errorf("Max file size cannot be empty")
+	}
+
+	if err := models.ValidateEncryption(pd.encryptionModeSelect.Selected, pd.kmsKeyIDEntry.Text); err != nil {
+		return err
+	}
+
+	if err := models.ValidateShareLinkURL(pd.shareLinkURLEntry.Text); err != nil {
+		return err
+	}
+
+	// Credentials are optional when editing, but must be given as a pair
+	if (pd.accessKeyEntry.Text == "") != (pd.secretKeyEntry.Text == "") {
+		return fmt.Errorf("Access key ID and secret access key must be provided together")
+	}
+
+	if !pd.editing && pd.accessKeyEntry.Text == "" {
+		return fmt.Errorf("AWS credentials are required for a new profile")
+	}
+
+	return nil
+}
+
+func (pd *ProfileDialog) updateProfileFromForm() {
+	pd.profile.Name = pd.nameEntry.Text
+	pd.profile.AWSRegion = pd.awsRegionEntry.Text
+	pd.profile.S3Bucket = pd.s3BucketEntry.Text
+	pd.profile.ShareLinkURL = pd.shareLinkURLEntry.Text
+	pd.profile.DefaultExpiration = pd.defaultExpirationSelect.Selected
+	pd.profile.EncryptionMode = pd.encryptionModeSelect.Selected
+	pd.profile.KMSKeyID = ""
+	if pd.profile.EncryptionMode == models.EncryptionSSEKMS {
+		pd.profile.KMSKeyID = pd.kmsKeyIDEntry.Text
+	}
+
+	var maxFileSizeMB float64
+	if _, err := fmt.Sscanf(pd.maxFileSizeEntry.Text, "%f", &maxFileSizeMB); err == nil {
+		pd.profile.MaxFileSize = int64(maxFileSizeMB * 1024 * 1024)
+	}
+}
+
+// onEncryptionModeChanged enables the KMS key entry only when SSE-KMS is selected
+func (pd *ProfileDialog) onEncryptionModeChanged(mode string) {
+	if mode == models.EncryptionSSEKMS {
+		pd.kmsKeyIDEntry.Enable()
+	} else {
+		pd.kmsKeyIDEntry.Disable()
+	}
+}
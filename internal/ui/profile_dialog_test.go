@@ -0,0 +1,205 @@
+package ui
+
+import (
+	"testing"
+
+	"file-sharing-app/internal/models"
+
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProfileDialog_NewProfile(t *testing.T) {
+	app := test.NewApp()
+	defer app.Quit()
+	window := app.NewWindow("Test")
+
+	dialog := NewProfileDialog(window, nil)
+
+	require.NotNil(t, dialog)
+	assert.False(t, dialog.editing)
+	assert.False(t, dialog.nameEntry.Disabled())
+	assert.False(t, dialog.deleteBtn.Visible())
+	assert.Equal(t, "", dialog.nameEntry.Text)
+	assert.Equal(t, "1d", dialog.defaultExpirationSelect.Selected)
+}
+
+func TestNewProfileDialog_EditProfile(t *testing.T) {
+	app := test.NewApp()
+	defer app.Quit()
+	window := app.NewWindow("Test")
+
+	profile := &models.Profile{
+		Name:              "client-a",
+		AWSRegion:         "eu-west-1",
+		S3Bucket:          "client-a-bucket",
+		DefaultExpiration: "1w",
+		MaxFileSize:       50 * 1024 * 1024,
+		ShareLinkURL:      "https://abc123.lambda-url.eu-west-1.on.aws/",
+	}
+
+	dialog := NewProfileDialog(window, profile)
+
+	assert.True(t, dialog.editing)
+	assert.True(t, dialog.nameEntry.Disabled())
+	assert.True(t, dialog.deleteBtn.Visible())
+	assert.Equal(t, "client-a", dialog.nameEntry.Text)
+	assert.Equal(t, "eu-west-1", dialog.awsRegionEntry.Text)
+	assert.Equal(t, "client-a-bucket", dialog.s3BucketEntry.Text)
+	assert.Equal(t, "1w", dialog.defaultExpirationSelect.Selected)
+	assert.Equal(t, "50", dialog.maxFileSizeEntry.Text)
+	assert.Equal(t, models.EncryptionSSES3, dialog.encryptionModeSelect.Selected)
+	assert.True(t, dialog.kmsKeyIDEntry.Disabled())
+	assert.Equal(t, "https://abc123.lambda-url.eu-west-1.on.aws/", dialog.shareLinkURLEntry.Text)
+}
+
+func TestProfileDialog_ValidateForm(t *testing.T) {
+	app := test.NewApp()
+	defer app.Quit()
+	window := app.NewWindow("Test")
+
+	tests := []struct {
+		name        string
+		editing     bool
+		setup       func(pd *ProfileDialog)
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name: "valid new profile",
+			setup: func(pd *ProfileDialog) {
+				pd.nameEntry.SetText("client-a")
+				pd.s3BucketEntry.SetText("client-a-bucket")
+				pd.accessKeyEntry.SetText("AKIATEST")
+				pd.secretKeyEntry.SetText("secret")
+			},
+		},
+		{
+			name: "invalid name",
+			setup: func(pd *ProfileDialog) {
+				pd.nameEntry.SetText("client a")
+				pd.s3BucketEntry.SetText("client-a-bucket")
+			},
+			expectError: true,
+			errorMsg:    "Profile name",
+		},
+		{
+			name: "missing bucket",
+			setup: func(pd *ProfileDialog) {
+				pd.nameEntry.SetText("client-a")
+				pd.s3BucketEntry.SetText("")
+			},
+			expectError: true,
+			errorMsg:    "S3 bucket",
+		},
+		{
+			name: "short link address without https",
+			setup: func(pd *ProfileDialog) {
+				pd.nameEntry.SetText("client-a")
+				pd.s3BucketEntry.SetText("client-a-bucket")
+				pd.accessKeyEntry.SetText("AKIATEST")
+				pd.secretKeyEntry.SetText("secret")
+				pd.shareLinkURLEntry.SetText("http://links.example.com/")
+			},
+			expectError: true,
+			errorMsg:    "Short link address",
+		},
+		{
+			name: "new profile without credentials",
+			setup: func(pd *ProfileDialog) {
+				pd.nameEntry.SetText("client-a")
+				pd.s3BucketEntry.SetText("client-a-bucket")
+			},
+			expectError: true,
+			errorMsg:    "credentials are required",
+		},
+		{
+			name: "access key without secret",
+			setup: func(pd *ProfileDialog) {
+				pd.nameEntry.SetText("client-a")
+				pd.s3BucketEntry.SetText("client-a-bucket")
+				pd.accessKeyEntry.SetText("AKIATEST")
+			},
+			expectError: true,
+			errorMsg:    "provided together",
+		},
+		{
+			name:    "SSE-KMS without key",
+			editing: true,
+			setup: func(pd *ProfileDialog) {
+				pd.s3BucketEntry.SetText("client-a-bucket")
+				pd.encryptionModeSelect.SetSelected(models.EncryptionSSEKMS)
+			},
+			expectError: true,
+			errorMsg:    "KMS key ID is required",
+		},
+		{
+			name:    "SSE-KMS with key",
+			editing: true,
+			setup: func(pd *ProfileDialog) {
+				pd.s3BucketEntry.SetText("client-a-bucket")
+				pd.encryptionModeSelect.SetSelected(models.EncryptionSSEKMS)
+				pd.kmsKeyIDEntry.SetText("alias/client-a")
+			},
+		},
+		{
+			name:    "edited profile keeps stored credentials",
+			editing: true,
+			setup: func(pd *ProfileDialog) {
+				pd.s3BucketEntry.SetText("client-a-bucket")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var profile *models.Profile
+			if tt.editing {
+				profile = models.NewProfile("client-a")
+			}
+
+			dialog := NewProfileDialog(window, profile)
+			tt.setup(dialog)
+
+			err := dialog.validateForm()
+			if tt.expectError {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestProfileDialog_SaveProfile(t *testing.T) {
+	app := test.NewApp()
+	defer app.Quit()
+	window := app.NewWindow("Test")
+
+	var savedProfile *models.Profile
+	var savedAccessKey string
+	dialog := NewProfileDialog(window, nil)
+	dialog.SetCallbacks(func(profile *models.Profile, accessKey, secretKey string) error {
+		savedProfile = profile
+		savedAccessKey = accessKey
+		return nil
+	}, nil)
+
+	dialog.nameEntry.SetText("client-a")
+	dialog.awsRegionEntry.SetText("eu-west-1")
+	dialog.s3BucketEntry.SetText("client-a-bucket")
+	dialog.maxFileSizeEntry.SetText("25")
+	dialog.accessKeyEntry.SetText("AKIATEST")
+	dialog.secretKeyEntry.SetText("secret")
+
+	dialog.saveProfile()
+
+	require.NotNil(t, savedProfile)
+	assert.Equal(t, "client-a", savedProfile.Name)
+	assert.Equal(t, "eu-west-1", savedProfile.AWSRegion)
+	assert.Equal(t, "client-a-bucket", savedProfile.S3Bucket)
+	assert.Equal(t, int64(25*1024*1024), savedProfile.MaxFileSize)
+	assert.Equal(t, "AKIATEST", savedAccessKey)
+}
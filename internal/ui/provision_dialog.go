@@ -0,0 +1,291 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"file-sharing-app/internal/models"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ProvisionDialog is the wizard for creating a bucket and IAM user with CloudFormation
+type ProvisionDialog struct {
+	parent fyne.Window
+	dialog *dialog.CustomDialog
+
+	// Form widgets
+	profileNameEntry *widget.Entry
+	stackNameEntry   *widget.Entry
+	bucketNameEntry  *widget.Entry
+	iamUserNameEntry *widget.Entry
+	regionEntry      *widget.Entry
+	adminKeyEntry    *widget.Entry
+	adminSecretEntry *widget.Entry
+	endpointEntry    *widget.Entry
+	provisionBtn     *widget.Button
+	checkDriftBtn    *widget.Button
+	resultLabel      *widget.Label
+
+	// Callbacks
+	OnProvisionBucket func(req *models.ProvisionRequest) (*models.ProvisionResult, error)
+	OnCheckDrift      func(req *models.ProvisionRequest) (*models.StackDriftReport, error)
+}
+
+// NewProvisionDialog creates the provisioning wizard, pre-filled from profile if it is not nil
+func NewProvisionDialog(parent fyne.Window, profile *models.Profile) *ProvisionDialog {
+	pd := &ProvisionDialog{
+		parent: parent,
+	}
+
+	pd.createDialog()
+	pd.populateForm(profile)
+	return pd
+}
+
+// SetCallbacks sets the callback functions for provisioning operations
+func (pd *ProvisionDialog) SetCallbacks(
+	onProvision func(req *models.ProvisionRequest) (*models.ProvisionResult, error),
+	onCheckDrift func(req *models.ProvisionRequest) (*models.StackDriftReport, error),
+) {
+	pd.OnProvisionBucket = onProvision
+	pd.OnCheckDrift = onCheckDrift
+}
+
+// Show displays the provisioning dialog
+func (pd *ProvisionDialog) Show() {
+	pd.dialog.Show()
+}
+
+// Hide closes the provisioning dialog
+func (pd *ProvisionDialog) Hide() {
+	pd.dialog.Hide()
+}
+
+func (pd *ProvisionDialog) createDialog() {
+	pd.createFormWidgets()
+
+	content := container.NewVBox(
+		pd.createFormLayout(),
+		widget.NewSeparator(),
+		pd.resultLabel,
+		pd.createActionButtons(),
+	)
+
+	pd.dialog = dialog.NewCustom("Provision Bucket", "Close", container.NewVScroll(content), pd.parent)
+	pd.dialog.Resize(fyne.NewSize(550, 650))
+}
+
+func (pd *ProvisionDialog) createFormWidgets() {
+	pd.profileNameEntry = widget.NewEntry()
+	pd.profileNameEntry.SetPlaceHolder("e.g., client-acme")
+
+	pd.stackNameEntry = widget.NewEntry()
+	pd.stackNameEntry.SetPlaceHolder(models.DefaultStackName)
+
+	pd.bucketNameEntry = widget.NewEntry()
+	pd.bucketNameEntry.SetPlaceHolder("e.g., acme-file-sharing-bucket")
+
+	pd.iamUserNameEntry = widget.NewEntry()
+	pd.iamUserNameEntry.SetPlaceHolder("e.g., acme-file-sharing-user")
+
+	pd.regionEntry = widget.NewEntry()
+	pd.regionEntry.SetPlaceHolder("e.g., us-west-2")
+
+	pd.adminKeyEntry = widget.NewEntry()
+	pd.adminKeyEntry.SetPlaceHolder("Leave empty to use the default AWS credentials")
+
+	pd.adminSecretEntry = widget.NewPasswordEntry()
+	pd.adminSecretEntry.SetPlaceHolder("Leave empty to use the default AWS credentials")
+
+	pd.endpointEntry = widget.NewEntry()
+	pd.endpointEntry.SetPlaceHolder("e.g., http://localhost:4566 (optional)")
+
+	pd.resultLabel = widget.NewLabel("")
+	pd.resultLabel.Wrapping = fyne.TextWrapWord
+}
+
+func (pd *ProvisionDialog) createFormLayout() *fyne.Container {
+	stackSection := widget.NewCard("Stack", "Resources are created from file-sharing-app.yaml",
+		container.NewVBox(
+			widget.NewFormItem("Profile Name", pd.profileNameEntry).Widget,
+			widget.NewFormItem("Stack Name", pd.stackNameEntry).Widget,
+			widget.NewFormItem("Bucket Name", pd.bucketNameEntry).Widget,
+			widget.NewFormItem("IAM User Name", pd.iamUserNameEntry).Widget,
+			widget.NewFormItem("AWS Region", pd.regionEntry).Widget,
+		),
+	)
+
+	adminSection := widget.NewCard("Administrator Credentials", "Used only for provisioning and never stored",
+		container.NewVBox(
+			widget.NewFormItem("Access Key ID", pd.adminKeyEntry).Widget,
+			widget.NewFormItem("Secret Access Key", pd.adminSecretEntry).Widget,
+			widget.NewFormItem("Endpoint", pd.endpointEntry).Widget,
+		),
+	)
+
+	return container.NewVBox(
+		stackSection,
+		adminSection,
+	)
+}
+
+func (pd *ProvisionDialog) createActionButtons() *fyne.Container {
+	pd.provisionBtn = widget.NewButton("Provision", pd.provisionBucket)
+	pd.provisionBtn.Importance = widget.HighImportance
+	pd.provisionBtn.Icon = theme.StorageIcon()
+
+	pd.checkDriftBtn = widget.NewButton("Check Drift", pd.checkDrift)
+	pd.checkDriftBtn.Icon = theme.SearchIcon()
+
+	return container.NewHBox(
+		pd.checkDriftBtn,
+		widget.NewSeparator(),
+		pd.provisionBtn,
+	)
+}
+
+func (pd *ProvisionDialog) populateForm(profile *models.Profile) {
+	pd.stackNameEntry.SetText(models.DefaultStackName)
+	if profile == nil {
+		return
+	}
+
+	pd.profileNameEntry.SetText(profile.Name)
+	pd.bucketNameEntry.SetText(profile.S3Bucket)
+	pd.regionEntry.SetText(profile.AWSRegion)
+	if profile.StackName != "" {
+		pd.stackNameEntry.SetText(profile.StackName)
+	}
+}
+
+func (pd *ProvisionDialog) provisionBucket() {
+	if err := pd.validateForm(); err != nil {
+		dialog.ShowError(err, pd.parent)
+		return
+	}
+
+	req := pd.buildRequest()
+	pd.setBusy(true)
+	pd.resultLabel.SetText("Provisioning... this can take a few minutes.")
+
+	// Stack creation takes minutes; keep the UI responsive while it runs
+	go func() {
+		text, err := pd.runProvision(req)
+		fyne.Do(func() {
+			pd.setBusy(false)
+			pd.showOutcome(text, err)
+		})
+	}()
+}
+
+func (pd *ProvisionDialog) checkDrift() {
+	if pd.stackNameEntry.Text == "" {
+		dialog.ShowError(fmt.Errorf("Stack name cannot be empty"), pd.parent)
+		return
+	}
+
+	req := pd.buildRequest()
+	pd.setBusy(true)
+	pd.resultLabel.SetText("Checking stack drift...")
+
+	go func() {
+		text, err := pd.runCheckDrift(req)
+		fyne.Do(func() {
+			pd.setBusy(false)
+			pd.showOutcome(text, err)
+		})
+	}()
+}
+
+// runProvision calls the provisioning callback and describes the result
+func (pd *ProvisionDialog) runProvision(req *models.ProvisionRequest) (string, error) {
+	if pd.OnProvisionBucket == nil {
+		return "", fmt.Errorf("Provisioning is not available")
+	}
+
+	result, err := pd.OnProvisionBucket(req)
+	if err != nil {
+		return "", fmt.Errorf("Failed to provision bucket: %v", err)
+	}
+
+	return fmt.Sprintf("Stack %s is %s.\nBucket %s in %s is ready and profile %s has been configured with the credentials of IAM user %s.",
+		result.StackName, result.StackStatus, result.BucketName, result.Region, req.ProfileName, result.IAMUserName), nil
+}
+
+// runCheckDrift calls the drift callback and describes the report
+func (pd *ProvisionDialog) runCheckDrift(req *models.ProvisionRequest) (string, error) {
+	if pd.OnCheckDrift == nil {
+		return "", fmt.Errorf("Drift detection is not available")
+	}
+
+	report, err := pd.OnCheckDrift(req)
+	if err != nil {
+		return "", fmt.Errorf("Failed to check stack drift: %v", err)
+	}
+
+	return formatDriftReport(report), nil
+}
+
+func (pd *ProvisionDialog) showOutcome(text string, err error) {
+	if err != nil {
+		pd.resultLabel.SetText(err.Error())
+		dialog.ShowError(err, pd.parent)
+		return
+	}
+	pd.resultLabel.SetText(text)
+}
+
+func (pd *ProvisionDialog) setBusy(busy bool) {
+	if busy {
+		pd.provisionBtn.Disable()
+		pd.checkDriftBtn.Disable()
+	} else {
+		pd.provisionBtn.Enable()
+		pd.checkDriftBtn.Enable()
+	}
+}
+
+func (pd *ProvisionDialog) validateForm() error {
+	if err := pd.buildRequest().Validate(); err != nil {
+		if validationErr, ok := err.(*models.ValidationError); ok {
+			return fmt.Errorf("%s", validationErr.Message)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (pd *ProvisionDialog) buildRequest() *models.ProvisionRequest {
+	return &models.ProvisionRequest{
+		ProfileName:    strings.TrimSpace(pd.profileNameEntry.Text),
+		StackName:      strings.TrimSpace(pd.stackNameEntry.Text),
+		BucketName:     strings.TrimSpace(pd.bucketNameEntry.Text),
+		IAMUserName:    strings.TrimSpace(pd.iamUserNameEntry.Text),
+		Region:         strings.TrimSpace(pd.regionEntry.Text),
+		AdminAccessKey: strings.TrimSpace(pd.adminKeyEntry.Text),
+		AdminSecretKey: pd.adminSecretEntry.Text,
+		Endpoint:       strings.TrimSpace(pd.endpointEntry.Text),
+	}
+}
+
+// formatDriftReport describes a drift report for display
+func formatDriftReport(report *models.StackDriftReport) string {
+	if !report.IsDrifted() {
+		return fmt.Sprintf("Stack %s is in sync with the template (checked %s).",
+			report.StackName, report.CheckedAt.Format("2006-01-02 15:04"))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Stack %s has drifted from the template:", report.StackName)
+	for _, drift := range report.DriftedResources {
+		fmt.Fprintf(&b, "\n  %s (%s): %s", drift.LogicalResourceID, drift.ResourceType, drift.DriftStatus)
+	}
+	b.WriteString("\nRun Provision again to restore the expected configuration.")
+	return b.String()
+}
@@ -0,0 +1,126 @@
+package ui
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"file-sharing-app/internal/models"
+
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProvisionDialog(t *testing.T) {
+	app := test.NewApp()
+	defer app.Quit()
+	window := app.NewWindow("Test")
+
+	dialog := NewProvisionDialog(window, nil)
+	require.NotNil(t, dialog)
+	assert.Equal(t, models.DefaultStackName, dialog.stackNameEntry.Text)
+	assert.Equal(t, "", dialog.profileNameEntry.Text)
+
+	profile := &models.Profile{
+		Name:      "client-a",
+		AWSRegion: "eu-west-1",
+		S3Bucket:  "client-a-bucket",
+		StackName: "client-a-stack",
+	}
+	dialog = NewProvisionDialog(window, profile)
+	assert.Equal(t, "client-a", dialog.profileNameEntry.Text)
+	assert.Equal(t, "client-a-stack", dialog.stackNameEntry.Text)
+	assert.Equal(t, "client-a-bucket", dialog.bucketNameEntry.Text)
+	assert.Equal(t, "eu-west-1", dialog.regionEntry.Text)
+}
+
+func TestProvisionDialog_ValidateForm(t *testing.T) {
+	app := test.NewApp()
+	defer app.Quit()
+	window := app.NewWindow("Test")
+
+	dialog := NewProvisionDialog(window, nil)
+	dialog.profileNameEntry.SetText("client-a")
+	dialog.bucketNameEntry.SetText("client-a-bucket")
+	dialog.iamUserNameEntry.SetText("client-a-user")
+	dialog.regionEntry.SetText("eu-west-1")
+	assert.NoError(t, dialog.validateForm())
+
+	dialog.bucketNameEntry.SetText("Client_Bucket")
+	err := dialog.validateForm()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Bucket name")
+
+	dialog.bucketNameEntry.SetText("client-a-bucket")
+	dialog.adminKeyEntry.SetText("AKIATEST")
+	err = dialog.validateForm()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "provided together")
+}
+
+func TestProvisionDialog_RunProvision(t *testing.T) {
+	app := test.NewApp()
+	defer app.Quit()
+	window := app.NewWindow("Test")
+
+	var received *models.ProvisionRequest
+	dialog := NewProvisionDialog(window, nil)
+	dialog.SetCallbacks(func(req *models.ProvisionRequest) (*models.ProvisionResult, error) {
+		received = req
+		return &models.ProvisionResult{
+			StackName:   req.StackName,
+			StackStatus: "CREATE_COMPLETE",
+			BucketName:  req.BucketName,
+			Region:      req.Region,
+			IAMUserName: req.IAMUserName,
+		}, nil
+	}, nil)
+
+	dialog.profileNameEntry.SetText("client-a")
+	dialog.bucketNameEntry.SetText(" client-a-bucket ")
+	dialog.iamUserNameEntry.SetText("client-a-user")
+	dialog.regionEntry.SetText("eu-west-1")
+	dialog.endpointEntry.SetText("http://localhost:4566")
+
+	text, err := dialog.runProvision(dialog.buildRequest())
+	require.NoError(t, err)
+	require.NotNil(t, received)
+	assert.Equal(t, "client-a-bucket", received.BucketName)
+	assert.Equal(t, "http://localhost:4566", received.Endpoint)
+	assert.Contains(t, text, "CREATE_COMPLETE")
+	assert.Contains(t, text, "client-a-bucket")
+
+	dialog.SetCallbacks(func(req *models.ProvisionRequest) (*models.ProvisionResult, error) {
+		return nil, fmt.Errorf("stack rolled back")
+	}, nil)
+	_, err = dialog.runProvision(dialog.buildRequest())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stack rolled back")
+}
+
+func TestProvisionDialog_RunCheckDrift(t *testing.T) {
+	app := test.NewApp()
+	defer app.Quit()
+	window := app.NewWindow("Test")
+
+	dialog := NewProvisionDialog(window, nil)
+	_, err := dialog.runCheckDrift(dialog.buildRequest())
+	assert.Error(t, err)
+
+	dialog.SetCallbacks(nil, func(req *models.ProvisionRequest) (*models.StackDriftReport, error) {
+		return &models.StackDriftReport{
+			StackName:   req.StackName,
+			DriftStatus: "DRIFTED",
+			DriftedResources: []models.ResourceDrift{
+				{LogicalResourceID: "FileStorageBucket", ResourceType: "AWS::S3::Bucket", DriftStatus: "MODIFIED"},
+			},
+			CheckedAt: time.Now(),
+		}, nil
+	})
+
+	text, err := dialog.runCheckDrift(dialog.buildRequest())
+	require.NoError(t, err)
+	assert.Contains(t, text, "has drifted")
+	assert.Contains(t, text, "FileStorageBucket (AWS::S3::Bucket): MODIFIED")
+}
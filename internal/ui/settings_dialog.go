@@ -2,6 +2,10 @@ package ui
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"file-sharing-app/internal/models"
 
@@ -23,13 +27,31 @@ type SettingsDialog struct {
 	s3BucketEntry       *widget.Entry
 	defaultExpirationSelect *widget.Select
 	maxFileSizeEntry    *widget.Entry
+	encryptionModeSelect *widget.Select
+	kmsKeyIDEntry        *widget.Entry
+	contentTypesEntry    *widget.Entry
+	compressionSelect    *widget.Select
+	compressionSavingsEntry *widget.Entry
+	virusScannerSelect   *widget.Select
+	clamdAddressEntry    *widget.Entry
+	scanCommandEntry     *widget.Entry
 	uiThemeSelect       *widget.Select
 	autoRefreshCheck    *widget.Check
 	showNotificationsCheck *widget.Check
+	minimizeToTrayCheck    *widget.Check
+	notificationChecks     map[models.NotificationEvent]*widget.Check
+	expiryWarningEntry     *widget.Entry
+	syncIntervalEntry            *widget.Entry
+	expirationCheckIntervalEntry *widget.Entry
+	metadataCleanupIntervalEntry *widget.Entry
+	urlRenewalIntervalEntry      *widget.Entry
+	healthCheckBtn      *widget.Button
+	healthReportLabel   *widget.Label
 	
 	// Callbacks
 	OnSaveSettings func(settings *models.ApplicationSettings) error
 	OnLoadSettings func() (*models.ApplicationSettings, error)
+	OnCheckBucketHealth func() (*models.HealthReport, error)
 }
 
 // NewSettingsDialog creates a new settings dialog
@@ -51,6 +73,11 @@ func (sd *SettingsDialog) SetCallbacks(
 	sd.OnLoadSettings = onLoad
 }
 
+// SetHealthCheckCallback sets the callback that runs the bucket health check
+func (sd *SettingsDialog) SetHealthCheckCallback(onCheck func() (*models.HealthReport, error)) {
+	sd.OnCheckBucketHealth = onCheck
+}
+
 // Show displays the settings dialog
 func (sd *SettingsDialog) Show() {
 	// Load current settings
@@ -117,6 +144,28 @@ func (sd *SettingsDialog) createFormWidgets() {
 	sd.maxFileSizeEntry = widget.NewEntry()
 	sd.maxFileSizeEntry.SetPlaceHolder("100")
 	
+	// Server-side encryption; the KMS key only applies to SSE-KMS
+	sd.kmsKeyIDEntry = widget.NewEntry()
+	sd.kmsKeyIDEntry.SetPlaceHolder("KMS key ID, ARN or alias/name")
+	sd.encryptionModeSelect = widget.NewSelect(models.EncryptionModes, sd.onEncryptionModeChanged)
+	
+	// Upload compression; the threshold only applies when it is on
+	sd.compressionSelect = widget.NewSelect(append([]string{compressionOff}, models.CompressionAlgorithms...), sd.onCompressionChanged)
+	sd.compressionSavingsEntry = widget.NewEntry()
+	sd.compressionSavingsEntry.SetPlaceHolder(strconv.Itoa(models.DefaultCompressionMinSavingsPercent))
+	
+	// Virus scanning; the address and the command only apply to their scanner
+	sd.clamdAddressEntry = widget.NewEntry()
+	sd.clamdAddressEntry.SetPlaceHolder(models.DefaultClamdAddress)
+	sd.scanCommandEntry = widget.NewEntry()
+	sd.scanCommandEntry.SetPlaceHolder("clamscan --no-summary " + models.ScanFilePlaceholder)
+	sd.virusScannerSelect = widget.NewSelect([]string{scannerOff, scannerClamdLabel, scannerCommandLabel}, sd.onVirusScannerChanged)
+	
+	// Content types by extension, one ".ext type/subtype" per line
+	sd.contentTypesEntry = widget.NewMultiLineEntry()
+	sd.contentTypesEntry.SetPlaceHolder(".md text/markdown")
+	sd.contentTypesEntry.SetMinRowsVisible(3)
+	
 	// UI Theme
 	sd.uiThemeSelect = widget.NewSelect(
 		[]string{"light", "dark", "auto"},
@@ -124,8 +173,33 @@ func (sd *SettingsDialog) createFormWidgets() {
 	)
 	
 	// Boolean settings
-	sd.autoRefreshCheck = widget.NewCheck("Automatically refresh file list", nil)
-	sd.showNotificationsCheck = widget.NewCheck("Show system notifications", nil)
+	sd.autoRefreshCheck = widget.NewCheck("Automatically sync file list with S3", sd.onAutoRefreshChanged)
+	sd.showNotificationsCheck = widget.NewCheck("Show system notifications", sd.onShowNotificationsChanged)
+	sd.minimizeToTrayCheck = widget.NewCheck("Keep running in the system tray when the window is closed", nil)
+	
+	// Notifications for each event
+	sd.notificationChecks = make(map[models.NotificationEvent]*widget.Check)
+	for _, event := range models.NotificationEvents {
+		sd.notificationChecks[event] = widget.NewCheck(event.Description(), nil)
+	}
+	sd.expiryWarningEntry = widget.NewEntry()
+	sd.expiryWarningEntry.SetPlaceHolder(strconv.Itoa(models.DefaultExpiryWarningHours))
+	
+	// Background job intervals (in minutes)
+	sd.syncIntervalEntry = widget.NewEntry()
+	sd.syncIntervalEntry.SetPlaceHolder(strconv.Itoa(models.DefaultSyncIntervalMinutes))
+	sd.expirationCheckIntervalEntry = widget.NewEntry()
+	sd.expirationCheckIntervalEntry.SetPlaceHolder(strconv.Itoa(models.DefaultExpirationCheckIntervalMinutes))
+	sd.metadataCleanupIntervalEntry = widget.NewEntry()
+	sd.metadataCleanupIntervalEntry.SetPlaceHolder(strconv.Itoa(models.DefaultMetadataCleanupIntervalMinutes))
+	sd.urlRenewalIntervalEntry = widget.NewEntry()
+	sd.urlRenewalIntervalEntry.SetPlaceHolder(strconv.Itoa(models.DefaultURLRenewalIntervalMinutes))
+	
+	// Bucket health check
+	sd.healthCheckBtn = widget.NewButton("Check Bucket Health", sd.checkBucketHealth)
+	sd.healthCheckBtn.Icon = theme.InfoIcon()
+	sd.healthReportLabel = widget.NewLabel("")
+	sd.healthReportLabel.Wrapping = fyne.TextWrapWord
 }
 
 func (sd *SettingsDialog) createFormLayout() *fyne.Container {
@@ -134,6 +208,8 @@ func (sd *SettingsDialog) createFormLayout() *fyne.Container {
 		container.NewVBox(
 			widget.NewFormItem("AWS Region", sd.awsRegionEntry).Widget,
 			widget.NewFormItem("S3 Bucket", sd.s3BucketEntry).Widget,
+			sd.healthCheckBtn,
+			sd.healthReportLabel,
 		),
 	)
 	
@@ -145,6 +221,20 @@ func (sd *SettingsDialog) createFormLayout() *fyne.Container {
 				widget.NewFormItem("Max File Size (MB)", sd.maxFileSizeEntry).Widget,
 				widget.NewLabel("Maximum file size for uploads"),
 			),
+			widget.NewFormItem("Encryption", sd.encryptionModeSelect).Widget,
+			widget.NewFormItem("KMS Key", sd.kmsKeyIDEntry).Widget,
+			widget.NewFormItem("Compression", sd.compressionSelect).Widget,
+			widget.NewFormItem("Compress if it saves (%)", sd.compressionSavingsEntry).Widget,
+			widget.NewFormItem("Content Types", sd.contentTypesEntry).Widget,
+		),
+	)
+	
+	// Virus Scanning section
+	scanSection := widget.NewCard("Virus Scanning", "Files are scanned before they are uploaded",
+		container.NewVBox(
+			widget.NewFormItem("Scanner", sd.virusScannerSelect).Widget,
+			widget.NewFormItem("clamd Socket or Address", sd.clamdAddressEntry).Widget,
+			widget.NewFormItem("Scan Command", sd.scanCommandEntry).Widget,
 		),
 	)
 	
@@ -152,8 +242,26 @@ func (sd *SettingsDialog) createFormLayout() *fyne.Container {
 	uiSection := widget.NewCard("User Interface", "",
 		container.NewVBox(
 			widget.NewFormItem("Theme", sd.uiThemeSelect).Widget,
+			sd.minimizeToTrayCheck,
+		),
+	)
+	
+	// Notifications section
+	notificationItems := container.NewVBox(sd.showNotificationsCheck)
+	for _, event := range models.NotificationEvents {
+		notificationItems.Add(sd.notificationChecks[event])
+	}
+	notificationItems.Add(widget.NewFormItem("Warn before expiry (hours)", sd.expiryWarningEntry).Widget)
+	notificationSection := widget.NewCard("Notifications", "", notificationItems)
+	
+	// Background Jobs section
+	jobsSection := widget.NewCard("Background Jobs", "Intervals in minutes",
+		container.NewVBox(
 			sd.autoRefreshCheck,
-			sd.showNotificationsCheck,
+			widget.NewFormItem("Sync with S3", sd.syncIntervalEntry).Widget,
+			widget.NewFormItem("Check expirations", sd.expirationCheckIntervalEntry).Widget,
+			widget.NewFormItem("Clean up expired records", sd.metadataCleanupIntervalEntry).Widget,
+			widget.NewFormItem("Renew share links", sd.urlRenewalIntervalEntry).Widget,
 		),
 	)
 	
@@ -166,6 +274,24 @@ func (sd *SettingsDialog) createFormLayout() *fyne.Container {
 **File Settings Help:**
 - Default Expiration: How long files remain accessible by default
 - Max File Size: Maximum size limit for file uploads (in MB)
+- Encryption: SSE-S3 uses S3-managed keys, SSE-KMS uses the KMS key you enter, and SSE-C uses a key kept in your OS keychain. SSE-C files can only be downloaded from this app, not shared by link.
+- Compression: Compresses text-heavy uploads such as logs, CSVs and JSON with gzip or zstd, when it makes them smaller by more than the percentage given. Browsers decompress them as they download. gzip works everywhere; zstd needs a recent browser.
+- Content Types: Uploads are stored with the type detected from their extension and content. Add a line such as ".md text/markdown" to choose the type for an extension instead.
+
+**Virus Scanning Help:**
+- Scanner: Scans each file before it is uploaded. Files the scanner finds something in, or can't scan, are not uploaded. Shares of scanned files tell recipients what scanned them.
+- clamd Socket or Address: The ClamAV daemon's socket, such as /var/run/clamav/clamd.ctl, or its host:port if it listens on TCP. Files are streamed to it, so its StreamMaxLength must allow your largest uploads.
+- Scan Command: Run on each file, with {file} standing for its path; without it the path is added to the end. It must exit with 0 for clean files and 1 for infected ones, as clamscan does.
+
+**Notifications Help:**
+- Choose which events show a desktop notification; past notifications are listed under Notifications in the main window
+- Warn before expiry: How many hours before a file expires to send the "about to expire" notification
+
+**Background Jobs Help:**
+- Sync with S3: How often the file list is checked against S3, when automatic sync is on
+- Check expirations: How often files past their expiration date are marked expired
+- Clean up expired records: How often records of files expired for over 30 days are removed
+- Renew share links: How often share links about to expire are re-signed, up to the file's expiration
 
 **Note:** Changes require application restart to take full effect.
 	`)
@@ -176,7 +302,10 @@ func (sd *SettingsDialog) createFormLayout() *fyne.Container {
 	return container.NewVBox(
 		awsSection,
 		fileSection,
+		scanSection,
 		uiSection,
+		notificationSection,
+		jobsSection,
 		helpSection,
 	)
 }
@@ -204,6 +333,39 @@ func (sd *SettingsDialog) createActionButtons() *fyne.Container {
 	)
 }
 
+func (sd *SettingsDialog) checkBucketHealth() {
+	sd.healthCheckBtn.Disable()
+	sd.healthReportLabel.SetText("Checking bucket configuration...")
+	
+	// The check makes several AWS calls; keep the dialog responsive while it runs
+	go func() {
+		text, err := sd.runHealthCheck()
+		fyne.Do(func() {
+			sd.healthCheckBtn.Enable()
+			if err != nil {
+				sd.healthReportLabel.SetText("")
+				dialog.ShowError(err, sd.parent)
+				return
+			}
+			sd.healthReportLabel.SetText(text)
+		})
+	}()
+}
+
+// runHealthCheck calls the health check callback and formats the report
+func (sd *SettingsDialog) runHealthCheck() (string, error) {
+	if sd.OnCheckBucketHealth == nil {
+		return "", fmt.Errorf("Bucket health check is not available")
+	}
+	
+	report, err := sd.OnCheckBucketHealth()
+	if err != nil {
+		return "", fmt.Errorf("Failed to check bucket health: %v", err)
+	}
+	
+	return report.String(), nil
+}
+
 func (sd *SettingsDialog) populateForm() {
 	if sd.settings == nil {
 		return
@@ -216,11 +378,34 @@ func (sd *SettingsDialog) populateForm() {
 	// Populate file settings
 	sd.defaultExpirationSelect.SetSelected(sd.settings.DefaultExpiration)
 	sd.maxFileSizeEntry.SetText(fmt.Sprintf("%.0f", float64(sd.settings.MaxFileSize)/(1024*1024)))
+	sd.kmsKeyIDEntry.SetText(sd.settings.KMSKeyID)
+	sd.encryptionModeSelect.SetSelected(sd.settings.GetEncryptionMode())
+	sd.contentTypesEntry.SetText(formatContentTypeOverrides(sd.settings.ContentTypeOverrides))
+	sd.compressionSavingsEntry.SetText(strconv.Itoa(sd.settings.GetCompressionMinSavings()))
+	sd.compressionSelect.SetSelected(formatCompression(sd.settings.UploadCompression))
+	sd.clamdAddressEntry.SetText(sd.settings.ClamdAddress)
+	sd.scanCommandEntry.SetText(sd.settings.ScanCommand)
+	sd.virusScannerSelect.SetSelected(formatVirusScanner(sd.settings.VirusScanner))
 	
 	// Populate UI settings
 	sd.uiThemeSelect.SetSelected(sd.settings.UITheme)
 	sd.autoRefreshCheck.SetChecked(sd.settings.AutoRefresh)
 	sd.showNotificationsCheck.SetChecked(sd.settings.ShowNotifications)
+	sd.minimizeToTrayCheck.SetChecked(sd.settings.MinimizeToTray)
+	
+	// Populate notification settings
+	for _, event := range models.NotificationEvents {
+		sd.notificationChecks[event].SetChecked(!sd.settings.NotificationDisabled(event))
+	}
+	sd.expiryWarningEntry.SetText(strconv.Itoa(int(sd.settings.GetExpiryWarningWindow() / time.Hour)))
+	sd.onShowNotificationsChanged(sd.settings.ShowNotifications)
+	
+	// Populate background job intervals, showing the default for settings saved before they existed
+	sd.syncIntervalEntry.SetText(formatMinutes(sd.settings.GetSyncInterval()))
+	sd.expirationCheckIntervalEntry.SetText(formatMinutes(sd.settings.GetExpirationCheckInterval()))
+	sd.metadataCleanupIntervalEntry.SetText(formatMinutes(sd.settings.GetMetadataCleanupInterval()))
+	sd.urlRenewalIntervalEntry.SetText(formatMinutes(sd.settings.GetURLRenewalInterval()))
+	sd.onAutoRefreshChanged(sd.settings.AutoRefresh)
 }
 
 func (sd *SettingsDialog) saveSettings() {
@@ -285,9 +470,241 @@ func (sd *SettingsDialog) validateForm() error {
 		return fmt.Errorf("Please select a UI theme")
 	}
 	
+	// Validate encryption; SSE-KMS needs a key
+	if err := models.ValidateEncryption(sd.encryptionModeSelect.Selected, sd.kmsKeyIDEntry.Text); err != nil {
+		return err
+	}
+	
+	// Validate compression
+	if _, err := parseCompressionSavings(sd.compressionSavingsEntry.Text); err != nil {
+		return err
+	}
+	
+	// Validate virus scanning; each scanner needs its address or command
+	if err := models.ValidateVirusScanner(parseVirusScanner(sd.virusScannerSelect.Selected), sd.clamdAddressEntry.Text, sd.scanCommandEntry.Text); err != nil {
+		return err
+	}
+	
+	// Validate content types
+	if _, err := parseContentTypeOverrides(sd.contentTypesEntry.Text); err != nil {
+		return err
+	}
+	
+	// Validate expiry warning
+	if _, err := parseExpiryWarningHours(sd.expiryWarningEntry.Text); err != nil {
+		return err
+	}
+	
+	// Validate background job intervals
+	intervals := []struct {
+		name  string
+		entry *widget.Entry
+	}{
+		{"Sync interval", sd.syncIntervalEntry},
+		{"Expiration check interval", sd.expirationCheckIntervalEntry},
+		{"Cleanup interval", sd.metadataCleanupIntervalEntry},
+		{"Link renewal interval", sd.urlRenewalIntervalEntry},
+	}
+	for _, interval := range intervals {
+		if _, err := parseIntervalMinutes(interval.name, interval.entry.Text); err != nil {
+			return err
+		}
+	}
+	
 	return nil
 }
 
+// parseIntervalMinutes parses a background job interval entered in minutes. An empty entry means the default.
+func parseIntervalMinutes(name, text string) (int, error) {
+	if text == "" {
+		return 0, nil
+	}
+	
+	minutes, err := strconv.Atoi(text)
+	if err != nil || minutes < 1 || minutes > models.MaxJobIntervalMinutes {
+		return 0, fmt.Errorf("%s must be a whole number of minutes between 1 and %d", name, models.MaxJobIntervalMinutes)
+	}
+	return minutes, nil
+}
+
+// parseExpiryWarningHours parses how many hours before expiry to warn. An empty entry means the default.
+func parseExpiryWarningHours(text string) (int, error) {
+	if text == "" {
+		return 0, nil
+	}
+	
+	hours, err := strconv.Atoi(text)
+	if err != nil || hours < 1 || hours > models.MaxExpiryWarningHours {
+		return 0, fmt.Errorf("Expiry warning must be a whole number of hours between 1 and %d", models.MaxExpiryWarningHours)
+	}
+	return hours, nil
+}
+
+// compressionOff is the compression choice that uploads files as they are
+const compressionOff = "Off"
+
+// parseCompression converts the compression choice to the setting, which is empty when compression is off
+func parseCompression(selected string) string {
+	if selected == compressionOff {
+		return ""
+	}
+	return selected
+}
+
+// formatCompression converts the compression setting to the choice shown
+func formatCompression(compression string) string {
+	if compression == "" {
+		return compressionOff
+	}
+	return compression
+}
+
+// Virus scanner choices
+const (
+	scannerOff          = "Off"
+	scannerClamdLabel   = "ClamAV daemon (clamd)"
+	scannerCommandLabel = "Command"
+)
+
+// parseVirusScanner converts the virus scanner choice to the setting, which is empty when scanning is off
+func parseVirusScanner(selected string) string {
+	switch selected {
+	case scannerClamdLabel:
+		return models.ScannerClamd
+	case scannerCommandLabel:
+		return models.ScannerCommand
+	default:
+		return ""
+	}
+}
+
+// formatVirusScanner converts the virus scanner setting to the choice shown
+func formatVirusScanner(scanner string) string {
+	switch scanner {
+	case models.ScannerClamd:
+		return scannerClamdLabel
+	case models.ScannerCommand:
+		return scannerCommandLabel
+	default:
+		return scannerOff
+	}
+}
+
+// parseCompressionSavings parses how much compression must save, in percent. An empty entry means the default.
+func parseCompressionSavings(text string) (int, error) {
+	if text == "" {
+		return 0, nil
+	}
+	
+	percent, err := strconv.Atoi(text)
+	if err != nil || percent < 1 || percent > models.MaxCompressionMinSavingsPercent {
+		return 0, fmt.Errorf("Compression savings must be a whole number of percent between 1 and %d", models.MaxCompressionMinSavingsPercent)
+	}
+	return percent, nil
+}
+
+// parseContentTypeOverrides parses content types entered by extension, one ".ext type/subtype" per line.
+// Extensions are matched ignoring case; blank lines are skipped.
+func parseContentTypeOverrides(text string) (map[string]string, error) {
+	var overrides map[string]string
+	for _, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("Content types must be entered as an extension and a type, such as .md text/markdown")
+		}
+		
+		ext := strings.ToLower(fields[0])
+		if err := models.ValidateContentTypeOverride(ext, fields[1]); err != nil {
+			return nil, fmt.Errorf("Invalid content type: %v", err)
+		}
+		if overrides == nil {
+			overrides = make(map[string]string)
+		}
+		overrides[ext] = fields[1]
+	}
+	return overrides, nil
+}
+
+// formatContentTypeOverrides lists content types by extension, one per line, sorted by extension
+func formatContentTypeOverrides(overrides map[string]string) string {
+	exts := make([]string, 0, len(overrides))
+	for ext := range overrides {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	
+	lines := make([]string, 0, len(exts))
+	for _, ext := range exts {
+		lines = append(lines, ext+" "+overrides[ext])
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatMinutes formats a duration as a whole number of minutes
+func formatMinutes(d time.Duration) string {
+	return strconv.Itoa(int(d / time.Minute))
+}
+
+// onAutoRefreshChanged enables the sync interval entry only when automatic sync is on
+func (sd *SettingsDialog) onAutoRefreshChanged(enabled bool) {
+	if enabled {
+		sd.syncIntervalEntry.Enable()
+	} else {
+		sd.syncIntervalEntry.Disable()
+	}
+}
+
+// onShowNotificationsChanged enables the per-event notification settings only when notifications are on
+func (sd *SettingsDialog) onShowNotificationsChanged(enabled bool) {
+	for _, check := range sd.notificationChecks {
+		if enabled {
+			check.Enable()
+		} else {
+			check.Disable()
+		}
+	}
+	if enabled {
+		sd.expiryWarningEntry.Enable()
+	} else {
+		sd.expiryWarningEntry.Disable()
+	}
+}
+
+// onCompressionChanged enables the savings threshold only when compression is on
+func (sd *SettingsDialog) onCompressionChanged(selected string) {
+	if selected == compressionOff {
+		sd.compressionSavingsEntry.Disable()
+	} else {
+		sd.compressionSavingsEntry.Enable()
+	}
+}
+
+// onVirusScannerChanged enables the clamd address or the scan command only when its scanner is chosen
+func (sd *SettingsDialog) onVirusScannerChanged(selected string) {
+	if selected == scannerClamdLabel {
+		sd.clamdAddressEntry.Enable()
+	} else {
+		sd.clamdAddressEntry.Disable()
+	}
+	if selected == scannerCommandLabel {
+		sd.scanCommandEntry.Enable()
+	} else {
+		sd.scanCommandEntry.Disable()
+	}
+}
+
+// onEncryptionModeChanged enables the KMS key entry only when SSE-KMS is selected
+func (sd *SettingsDialog) onEncryptionModeChanged(mode string) {
+	if mode == models.EncryptionSSEKMS {
+		sd.kmsKeyIDEntry.Enable()
+	} else {
+		sd.kmsKeyIDEntry.Disable()
+	}
+}
+
 func (sd *SettingsDialog) updateSettingsFromForm() {
 	if sd.settings == nil {
 		sd.settings = models.DefaultApplicationSettings()
@@ -306,8 +723,36 @@ func (sd *SettingsDialog) updateSettingsFromForm() {
 		sd.settings.MaxFileSize = int64(maxFileSizeMB * 1024 * 1024)
 	}
 	
+	// Update encryption settings; the KMS key is only kept for SSE-KMS
+	sd.settings.EncryptionMode = sd.encryptionModeSelect.Selected
+	sd.settings.KMSKeyID = ""
+	if sd.settings.EncryptionMode == models.EncryptionSSEKMS {
+		sd.settings.KMSKeyID = sd.kmsKeyIDEntry.Text
+	}
+	sd.settings.ContentTypeOverrides, _ = parseContentTypeOverrides(sd.contentTypesEntry.Text)
+	sd.settings.UploadCompression = parseCompression(sd.compressionSelect.Selected)
+	sd.settings.CompressionMinSavingsPercent, _ = parseCompressionSavings(sd.compressionSavingsEntry.Text)
+	
+	// Update virus scanning; the address and command are kept while another scanner is chosen
+	sd.settings.VirusScanner = parseVirusScanner(sd.virusScannerSelect.Selected)
+	sd.settings.ClamdAddress = strings.TrimSpace(sd.clamdAddressEntry.Text)
+	sd.settings.ScanCommand = strings.TrimSpace(sd.scanCommandEntry.Text)
+	
 	// Update UI settings
 	sd.settings.UITheme = sd.uiThemeSelect.Selected
 	sd.settings.AutoRefresh = sd.autoRefreshCheck.Checked
 	sd.settings.ShowNotifications = sd.showNotificationsCheck.Checked
+	sd.settings.MinimizeToTray = sd.minimizeToTrayCheck.Checked
+	
+	// Update notification settings
+	for _, event := range models.NotificationEvents {
+		sd.settings.SetNotificationEnabled(event, sd.notificationChecks[event].Checked)
+	}
+	sd.settings.ExpiryWarningHours, _ = parseExpiryWarningHours(sd.expiryWarningEntry.Text)
+	
+	// Update background job intervals; validateForm has already checked them
+	sd.settings.SyncIntervalMinutes, _ = parseIntervalMinutes("", sd.syncIntervalEntry.Text)
+	sd.settings.ExpirationCheckIntervalMinutes, _ = parseIntervalMinutes("", sd.expirationCheckIntervalEntry.Text)
+	sd.settings.MetadataCleanupIntervalMinutes, _ = parseIntervalMinutes("", sd.metadataCleanupIntervalEntry.Text)
+	sd.settings.URLRenewalIntervalMinutes, _ = parseIntervalMinutes("", sd.urlRenewalIntervalEntry.Text)
 }
\ No newline at end of file
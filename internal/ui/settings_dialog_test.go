@@ -77,6 +77,7 @@ func TestSettingsDialog_PopulateForm(t *testing.T) {
 	assert.Equal(t, "dark", dialog.uiThemeSelect.Selected)
 	assert.False(t, dialog.autoRefreshCheck.Checked)
 	assert.True(t, dialog.showNotificationsCheck.Checked)
+	assert.False(t, dialog.minimizeToTrayCheck.Checked)
 }
 
 func TestSettingsDialog_ValidateForm(t *testing.T) {
@@ -194,6 +195,7 @@ func TestSettingsDialog_UpdateSettingsFromForm(t *testing.T) {
 	dialog.uiThemeSelect.SetSelected("dark")
 	dialog.autoRefreshCheck.SetChecked(false)
 	dialog.showNotificationsCheck.SetChecked(true)
+	dialog.minimizeToTrayCheck.SetChecked(false)
 	
 	// Initialize settings
 	dialog.settings = models.DefaultApplicationSettings()
@@ -209,6 +211,200 @@ func TestSettingsDialog_UpdateSettingsFromForm(t *testing.T) {
 	assert.Equal(t, "dark", dialog.settings.UITheme)
 	assert.False(t, dialog.settings.AutoRefresh)
 	assert.True(t, dialog.settings.ShowNotifications)
+	assert.False(t, dialog.settings.MinimizeToTray)
+}
+
+func TestSettingsDialog_JobIntervals(t *testing.T) {
+	app := test.NewApp()
+	window := app.NewWindow("Test")
+	dialog := NewSettingsDialog(window)
+	
+	// Settings saved before the intervals existed show the defaults
+	dialog.settings = models.DefaultApplicationSettings()
+	dialog.settings.S3Bucket = "test-bucket"
+	dialog.settings.SyncIntervalMinutes = 0
+	dialog.settings.URLRenewalIntervalMinutes = 30
+	dialog.settings.AutoRefresh = false
+	dialog.populateForm()
+	
+	assert.Equal(t, "15", dialog.syncIntervalEntry.Text)
+	assert.Equal(t, "30", dialog.urlRenewalIntervalEntry.Text)
+	assert.True(t, dialog.syncIntervalEntry.Disabled())
+	
+	dialog.autoRefreshCheck.SetChecked(true)
+	assert.False(t, dialog.syncIntervalEntry.Disabled())
+	
+	dialog.syncIntervalEntry.SetText("45")
+	dialog.expirationCheckIntervalEntry.SetText("")
+	require.NoError(t, dialog.validateForm())
+	
+	dialog.updateSettingsFromForm()
+	assert.Equal(t, 45, dialog.settings.SyncIntervalMinutes)
+	assert.Equal(t, 0, dialog.settings.ExpirationCheckIntervalMinutes)
+	assert.Equal(t, 5*time.Minute, dialog.settings.GetExpirationCheckInterval())
+	assert.Equal(t, 30, dialog.settings.URLRenewalIntervalMinutes)
+	
+	for _, invalid := range []string{"0", "-5", "abc", "1.5", "20000"} {
+		dialog.metadataCleanupIntervalEntry.SetText(invalid)
+		err := dialog.validateForm()
+		assert.Error(t, err, invalid)
+		assert.Contains(t, err.Error(), "Cleanup interval must be a whole number of minutes")
+	}
+}
+
+func TestSettingsDialog_ContentTypes(t *testing.T) {
+	app := test.NewApp()
+	window := app.NewWindow("Test")
+	dialog := NewSettingsDialog(window)
+	
+	dialog.settings = models.DefaultApplicationSettings()
+	dialog.settings.S3Bucket = "test-bucket"
+	dialog.settings.ContentTypeOverrides = map[string]string{".md": "text/markdown", ".heic": "image/heic"}
+	dialog.populateForm()
+	assert.Equal(t, ".heic image/heic\n.md text/markdown", dialog.contentTypesEntry.Text)
+	
+	// Extensions are matched ignoring case, and blank lines are skipped
+	dialog.contentTypesEntry.SetText(".LOG text/plain\n\n  .md   text/x-markdown  ")
+	require.NoError(t, dialog.validateForm())
+	dialog.updateSettingsFromForm()
+	assert.Equal(t, map[string]string{".log": "text/plain", ".md": "text/x-markdown"}, dialog.settings.ContentTypeOverrides)
+	
+	dialog.contentTypesEntry.SetText("")
+	dialog.updateSettingsFromForm()
+	assert.Nil(t, dialog.settings.ContentTypeOverrides)
+	
+	for _, invalid := range []string{".md", "md text/markdown", ".md markdown", ".md text/markdown extra"} {
+		dialog.contentTypesEntry.SetText(invalid)
+		assert.Error(t, dialog.validateForm(), invalid)
+	}
+}
+
+func TestSettingsDialog_Compression(t *testing.T) {
+	app := test.NewApp()
+	window := app.NewWindow("Test")
+	dialog := NewSettingsDialog(window)
+	
+	dialog.settings = models.DefaultApplicationSettings()
+	dialog.settings.S3Bucket = "test-bucket"
+	dialog.populateForm()
+	assert.Equal(t, compressionOff, dialog.compressionSelect.Selected)
+	assert.Equal(t, "10", dialog.compressionSavingsEntry.Text)
+	assert.True(t, dialog.compressionSavingsEntry.Disabled())
+	
+	// Choosing an algorithm enables the threshold
+	dialog.compressionSelect.SetSelected(models.CompressionZstd)
+	assert.False(t, dialog.compressionSavingsEntry.Disabled())
+	dialog.compressionSavingsEntry.SetText("25")
+	require.NoError(t, dialog.validateForm())
+	dialog.updateSettingsFromForm()
+	assert.Equal(t, models.CompressionZstd, dialog.settings.UploadCompression)
+	assert.Equal(t, 25, dialog.settings.CompressionMinSavingsPercent)
+	
+	// An empty threshold means the default
+	dialog.compressionSavingsEntry.SetText("")
+	require.NoError(t, dialog.validateForm())
+	dialog.updateSettingsFromForm()
+	assert.Equal(t, models.DefaultCompressionMinSavingsPercent, dialog.settings.GetCompressionMinSavings())
+	
+	dialog.compressionSelect.SetSelected(compressionOff)
+	dialog.updateSettingsFromForm()
+	assert.Empty(t, dialog.settings.UploadCompression)
+	
+	for _, invalid := range []string{"0", "-5", "abc", "91"} {
+		dialog.compressionSavingsEntry.SetText(invalid)
+		err := dialog.validateForm()
+		assert.Error(t, err, invalid)
+		assert.Contains(t, err.Error(), "Compression savings must be a whole number of percent")
+	}
+}
+
+func TestSettingsDialog_VirusScanning(t *testing.T) {
+	app := test.NewApp()
+	window := app.NewWindow("Test")
+	dialog := NewSettingsDialog(window)
+	
+	dialog.settings = models.DefaultApplicationSettings()
+	dialog.settings.S3Bucket = "test-bucket"
+	dialog.populateForm()
+	assert.Equal(t, scannerOff, dialog.virusScannerSelect.Selected)
+	assert.True(t, dialog.clamdAddressEntry.Disabled())
+	assert.True(t, dialog.scanCommandEntry.Disabled())
+	
+	// Choosing clamd enables its address, which defaults to the local socket
+	dialog.virusScannerSelect.SetSelected(scannerClamdLabel)
+	assert.False(t, dialog.clamdAddressEntry.Disabled())
+	assert.True(t, dialog.scanCommandEntry.Disabled())
+	require.NoError(t, dialog.validateForm())
+	dialog.updateSettingsFromForm()
+	assert.Equal(t, models.ScannerClamd, dialog.settings.VirusScanner)
+	assert.Equal(t, models.DefaultClamdAddress, dialog.settings.GetClamdAddress())
+	
+	dialog.clamdAddressEntry.SetText("clamd.sock")
+	assert.Error(t, dialog.validateForm())
+	dialog.clamdAddressEntry.SetText("localhost:3310")
+	require.NoError(t, dialog.validateForm())
+	
+	// A command scanner needs a command
+	dialog.virusScannerSelect.SetSelected(scannerCommandLabel)
+	assert.False(t, dialog.scanCommandEntry.Disabled())
+	err := dialog.validateForm()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Scan command is required")
+	
+	dialog.scanCommandEntry.SetText("clamscan --no-summary {file}")
+	require.NoError(t, dialog.validateForm())
+	dialog.updateSettingsFromForm()
+	assert.Equal(t, models.ScannerCommand, dialog.settings.VirusScanner)
+	assert.Equal(t, "clamscan --no-summary {file}", dialog.settings.ScanCommand)
+	assert.Equal(t, "localhost:3310", dialog.settings.ClamdAddress)
+	
+	// Saved settings show again as they were chosen
+	dialog.populateForm()
+	assert.Equal(t, scannerCommandLabel, dialog.virusScannerSelect.Selected)
+	
+	dialog.virusScannerSelect.SetSelected(scannerOff)
+	dialog.updateSettingsFromForm()
+	assert.Empty(t, dialog.settings.VirusScanner)
+}
+
+func TestSettingsDialog_Notifications(t *testing.T) {
+	app := test.NewApp()
+	window := app.NewWindow("Test")
+	dialog := NewSettingsDialog(window)
+	
+	dialog.settings = models.DefaultApplicationSettings()
+	dialog.settings.S3Bucket = "test-bucket"
+	dialog.settings.ExpiryWarningHours = 0
+	dialog.settings.SetNotificationEnabled(models.NotifyMissingFiles, false)
+	dialog.populateForm()
+	
+	assert.Len(t, dialog.notificationChecks, len(models.NotificationEvents))
+	assert.True(t, dialog.notificationChecks[models.NotifyUploadComplete].Checked)
+	assert.False(t, dialog.notificationChecks[models.NotifyMissingFiles].Checked)
+	assert.Equal(t, "24", dialog.expiryWarningEntry.Text)
+	
+	// Turning notifications off disables the per-event settings
+	dialog.showNotificationsCheck.SetChecked(false)
+	assert.True(t, dialog.notificationChecks[models.NotifyUploadFailed].Disabled())
+	assert.True(t, dialog.expiryWarningEntry.Disabled())
+	dialog.showNotificationsCheck.SetChecked(true)
+	assert.False(t, dialog.notificationChecks[models.NotifyUploadFailed].Disabled())
+	
+	dialog.notificationChecks[models.NotifyUploadComplete].SetChecked(false)
+	dialog.notificationChecks[models.NotifyMissingFiles].SetChecked(true)
+	dialog.expiryWarningEntry.SetText("6")
+	require.NoError(t, dialog.validateForm())
+	
+	dialog.updateSettingsFromForm()
+	assert.Equal(t, []models.NotificationEvent{models.NotifyUploadComplete}, dialog.settings.DisabledNotifications)
+	assert.Equal(t, 6, dialog.settings.ExpiryWarningHours)
+	
+	for _, invalid := range []string{"0", "abc", "1000"} {
+		dialog.expiryWarningEntry.SetText(invalid)
+		err := dialog.validateForm()
+		assert.Error(t, err, invalid)
+		assert.Contains(t, err.Error(), "Expiry warning must be a whole number of hours")
+	}
 }
 
 func TestSettingsDialog_UpdateSettingsFromForm_NilSettings(t *testing.T) {
@@ -405,4 +601,36 @@ func TestSettingsDialog_SaveSettings_SaveError(t *testing.T) {
 	dialog.saveSettings()
 	
 	// Test passes if no panic occurs
-}
\ No newline at end of file
+}
+func TestSettingsDialog_RunHealthCheck(t *testing.T) {
+	app := test.NewApp()
+	window := app.NewWindow("Test")
+	dialog := NewSettingsDialog(window)
+	
+	// No callback configured
+	_, err := dialog.runHealthCheck()
+	assert.Error(t, err)
+	
+	dialog.SetHealthCheckCallback(func() (*models.HealthReport, error) {
+		return &models.HealthReport{
+			Bucket: "test-bucket",
+			Region: "us-west-2",
+			Checks: []models.HealthCheck{
+				{Name: "Versioning", Status: models.HealthCheckFail, Message: "Old versions are never expired", Remediation: "Add a noncurrent version expiration"},
+			},
+		}, nil
+	})
+	
+	text, err := dialog.runHealthCheck()
+	assert.NoError(t, err)
+	assert.Contains(t, text, "[FAIL] Versioning")
+	assert.Contains(t, text, "Fix: Add a noncurrent version expiration")
+	
+	dialog.SetHealthCheckCallback(func() (*models.HealthReport, error) {
+		return nil, errors.New("access denied")
+	})
+	
+	_, err = dialog.runHealthCheck()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "access denied")
+}
@@ -3,7 +3,9 @@ package ui
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"file-sharing-app/internal/models"
 
@@ -21,21 +23,29 @@ type SharingDialog struct {
 	file   models.FileMetadata
 	
 	// UI components
-	fileInfoLabel    *widget.Label
-	emailEntry       *widget.Entry
-	addEmailBtn      *widget.Button
-	recipientList    *widget.List
-	messageEntry     *widget.Entry
-	shareBtn         *widget.Button
-	cancelBtn        *widget.Button
+	fileInfoLabel     *widget.Label
+	checksumLabel     *widget.Label
+	emailEntry        *widget.Entry
+	addEmailBtn       *widget.Button
+	recipientList     *widget.List
+	messageEntry      *widget.Entry
+	lifetimeSelect    *widget.Select
+	maxDownloadsEntry *widget.Entry
+	downloadNameEntry *widget.Entry
+	inlineCheck       *widget.Check
+	shareBtn          *widget.Button
+	cancelBtn         *widget.Button
 	
 	// Data
 	recipients []string
-	onShare    func(fileID string, recipients []string, message string) error
+	onShare    func(fileID string, recipients []string, message string, opts models.ShareOptions) error
 }
 
+// defaultLinkLifetime is the link lifetime option that leaves the lifetime to the app
+const defaultLinkLifetime = "Default"
+
 // NewSharingDialog creates a new sharing dialog
-func NewSharingDialog(parent fyne.Window, file models.FileMetadata, onShare func(string, []string, string) error) *SharingDialog {
+func NewSharingDialog(parent fyne.Window, file models.FileMetadata, onShare func(string, []string, string, models.ShareOptions) error) *SharingDialog {
 	d := &SharingDialog{
 		window:     parent,
 		file:       file,
@@ -63,10 +73,15 @@ func (d *SharingDialog) setupDialog() {
 	d.fileInfoLabel.TextStyle = fyne.TextStyle{Bold: true}
 	
 	fileDetails := widget.NewLabel(fmt.Sprintf("Size: %s • Expires: %s", 
-		formatFileSize(d.file.FileSize), 
+		formatStoredSize(d.file), 
 		formatExpiration(d.file.ExpirationDate)))
 	fileDetails.TextStyle = fyne.TextStyle{Italic: true}
 	
+	// Checksum recipients can use to verify their download
+	d.checksumLabel = widget.NewLabel(formatChecksum(d.file.Checksum))
+	d.checksumLabel.TextStyle = fyne.TextStyle{Monospace: true}
+	d.checksumLabel.Wrapping = fyne.TextWrapBreak
+	
 	// Email input section
 	emailLabel := widget.NewLabel("Add Recipients:")
 	emailLabel.TextStyle = fyne.TextStyle{Bold: true}
@@ -99,6 +114,41 @@ func (d *SharingDialog) setupDialog() {
 	d.messageEntry.SetPlaceHolder("Add a personal message for recipients...")
 	d.messageEntry.Resize(fyne.NewSize(400, 80))
 	
+	// Link options section
+	optionsLabel := widget.NewLabel("Link Options:")
+	optionsLabel.TextStyle = fyne.TextStyle{Bold: true}
+	
+	d.lifetimeSelect = widget.NewSelect(
+		[]string{
+			defaultLinkLifetime,
+			"1 hour",
+			"1 day",
+			"1 week",
+			"1 month",
+		},
+		nil,
+	)
+	d.lifetimeSelect.SetSelected(defaultLinkLifetime)
+	
+	d.maxDownloadsEntry = widget.NewEntry()
+	d.maxDownloadsEntry.SetPlaceHolder("No limit")
+	
+	d.downloadNameEntry = widget.NewEntry()
+	d.downloadNameEntry.SetPlaceHolder(d.file.FileName)
+	
+	// Only PDFs and images can be shown by browsers
+	d.inlineCheck = widget.NewCheck("Open in the browser instead of downloading", nil)
+	if _, ok := d.file.InlineContentType(); !ok {
+		d.inlineCheck.Disable()
+	}
+	
+	optionsForm := widget.NewForm(
+		widget.NewFormItem("Link valid for", d.lifetimeSelect),
+		widget.NewFormItem("Downloads", d.maxDownloadsEntry),
+		widget.NewFormItem("Save as", d.downloadNameEntry),
+		widget.NewFormItem("Preview", d.inlineCheck),
+	)
+	
 	// Action buttons
 	d.shareBtn = widget.NewButton("Share File", d.shareFile)
 	d.shareBtn.Icon = theme.MailSendIcon()
@@ -113,6 +163,7 @@ func (d *SharingDialog) setupDialog() {
 	fileSection := container.NewVBox(
 		d.fileInfoLabel,
 		fileDetails,
+		d.checksumLabel,
 	)
 	
 	emailSection := container.NewVBox(
@@ -130,6 +181,12 @@ func (d *SharingDialog) setupDialog() {
 		container.NewScroll(d.messageEntry),
 	)
 	
+	optionsSection := container.NewVBox(
+		optionsLabel,
+		optionsForm,
+		widget.NewLabel("The link never outlives the file. Recipients are asked to keep to the downloads; it isn't enforced."),
+	)
+	
 	buttonSection := container.NewHBox(
 		d.cancelBtn,
 		widget.NewSeparator(),
@@ -145,11 +202,13 @@ func (d *SharingDialog) setupDialog() {
 		widget.NewSeparator(),
 		messageSection,
 		widget.NewSeparator(),
+		optionsSection,
+		widget.NewSeparator(),
 		buttonSection,
 	)
 	
 	d.dialog = dialog.NewCustom("Share File", "", content, d.window)
-	d.dialog.Resize(fyne.NewSize(550, 600))
+	d.dialog.Resize(fyne.NewSize(550, 720))
 }
 
 func (d *SharingDialog) createRecipientItem() fyne.CanvasObject {
@@ -234,6 +293,12 @@ func (d *SharingDialog) shareFile() {
 	
 	message := strings.TrimSpace(d.messageEntry.Text)
 	
+	opts, err := d.shareOptions()
+	if err != nil {
+		dialog.ShowError(err, d.window)
+		return
+	}
+	
 	// Disable buttons during sharing
 	d.shareBtn.SetText("Sharing...")
 	d.shareBtn.Disable()
@@ -241,7 +306,7 @@ func (d *SharingDialog) shareFile() {
 	
 	// Perform sharing in goroutine
 	go func() {
-		if err := d.onShare(d.file.ID, d.recipients, message); err != nil {
+		if err := d.onShare(d.file.ID, d.recipients, message, opts); err != nil {
 			// Show error dialog
 			dialog.ShowError(err, d.window)
 			
@@ -259,8 +324,56 @@ func (d *SharingDialog) shareFile() {
 	}()
 }
 
+// shareOptions reads the link options. Empty fields keep the defaults.
+func (d *SharingDialog) shareOptions() (models.ShareOptions, error) {
+	opts := models.ShareOptions{
+		LinkLifetime:  parseLinkLifetime(d.lifetimeSelect.Selected),
+		DownloadName:  strings.TrimSpace(d.downloadNameEntry.Text),
+		InlinePreview: d.inlineCheck.Checked && !d.inlineCheck.Disabled(),
+	}
+	
+	if downloads := strings.TrimSpace(d.maxDownloadsEntry.Text); downloads != "" {
+		maxDownloads, err := strconv.Atoi(downloads)
+		if err != nil || maxDownloads <= 0 {
+			return opts, fmt.Errorf("downloads must be a whole number above zero, or empty for no limit")
+		}
+		opts.MaxDownloads = maxDownloads
+	}
+	
+	// The file's own name needs no override
+	if opts.DownloadName == d.file.FileName {
+		opts.DownloadName = ""
+	}
+	
+	return opts, opts.Validate()
+}
+
+// parseLinkLifetime converts a link lifetime option to a duration; zero is the default lifetime
+func parseLinkLifetime(selected string) time.Duration {
+	switch selected {
+	case "1 hour":
+		return time.Hour
+	case "1 day":
+		return 24 * time.Hour
+	case "1 week":
+		return 7 * 24 * time.Hour
+	case "1 month":
+		return 30 * 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
 func (d *SharingDialog) isValidEmail(email string) bool {
 	// Simple email validation regex
 	emailRegex := regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
 	return emailRegex.MatchString(email)
-}
\ No newline at end of file
+}
+
+// formatChecksum describes a file's SHA-256 for display
+func formatChecksum(checksum string) string {
+	if checksum == "" {
+		return "SHA-256: not recorded (uploaded before checksums were kept)"
+	}
+	return "SHA-256: " + checksum
+}
@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -28,7 +29,7 @@ func TestSharingDialog_Creation(t *testing.T) {
 	}
 
 	// Create sharing dialog
-	sharingDialog := NewSharingDialog(testWindow, testFile, func(fileID string, recipients []string, message string) error {
+	sharingDialog := NewSharingDialog(testWindow, testFile, func(fileID string, recipients []string, message string, opts models.ShareOptions) error {
 		return nil
 	})
 
@@ -124,4 +125,115 @@ func TestSharingDialog_AddRecipient(t *testing.T) {
 	if sharingDialog.shareBtn.Disabled() {
 		t.Error("Share button should be enabled after adding recipient")
 	}
-}
\ No newline at end of file
+}
+func TestSharingDialog_ChecksumLabel(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	testWindow := testApp.NewWindow("Test")
+
+	checksum := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	sharingDialog := NewSharingDialog(testWindow, models.FileMetadata{ID: "test-1", FileName: "test.txt", Checksum: checksum}, nil)
+
+	if sharingDialog.checksumLabel.Text != "SHA-256: "+checksum {
+		t.Errorf("Expected checksum label to show the checksum, got '%s'", sharingDialog.checksumLabel.Text)
+	}
+
+	// Files uploaded before checksums were recorded say so rather than showing a blank
+	legacyDialog := NewSharingDialog(testWindow, models.FileMetadata{ID: "test-2", FileName: "old.txt"}, nil)
+
+	if !strings.Contains(legacyDialog.checksumLabel.Text, "not recorded") {
+		t.Errorf("Expected checksum label to explain the missing checksum, got '%s'", legacyDialog.checksumLabel.Text)
+	}
+}
+
+func TestSharingDialog_ShareOptions(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	testWindow := testApp.NewWindow("Test")
+	sharingDialog := NewSharingDialog(testWindow, models.FileMetadata{ID: "test-1", FileName: "report-final.pdf"}, nil)
+
+	// Nothing chosen keeps the defaults
+	opts, err := sharingDialog.shareOptions()
+	if err != nil {
+		t.Fatalf("Expected default options to be valid, got %v", err)
+	}
+	if opts != (models.ShareOptions{}) {
+		t.Errorf("Expected default options, got %+v", opts)
+	}
+
+	sharingDialog.lifetimeSelect.SetSelected("1 week")
+	sharingDialog.maxDownloadsEntry.SetText(" 3 ")
+	sharingDialog.downloadNameEntry.SetText("Q3 report.pdf")
+
+	opts, err = sharingDialog.shareOptions()
+	if err != nil {
+		t.Fatalf("Expected options to be valid, got %v", err)
+	}
+	expected := models.ShareOptions{LinkLifetime: 7 * 24 * time.Hour, MaxDownloads: 3, DownloadName: "Q3 report.pdf"}
+	if opts != expected {
+		t.Errorf("Expected %+v, got %+v", expected, opts)
+	}
+
+	// The file's own name isn't an override
+	sharingDialog.downloadNameEntry.SetText("report-final.pdf")
+	if opts, _ := sharingDialog.shareOptions(); opts.DownloadName != "" {
+		t.Errorf("Expected no download name override, got %q", opts.DownloadName)
+	}
+
+	for _, downloads := range []string{"0", "-1", "many"} {
+		sharingDialog.maxDownloadsEntry.SetText(downloads)
+		if _, err := sharingDialog.shareOptions(); err == nil {
+			t.Errorf("Expected downloads %q to be rejected", downloads)
+		}
+	}
+
+	sharingDialog.maxDownloadsEntry.SetText("")
+	sharingDialog.downloadNameEntry.SetText("reports/q3.pdf")
+	if _, err := sharingDialog.shareOptions(); err == nil {
+		t.Error("Expected a download name with a path to be rejected")
+	}
+}
+
+func TestParseLinkLifetime(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+	}{
+		{"Default", 0},
+		{"1 hour", time.Hour},
+		{"1 day", 24 * time.Hour},
+		{"1 week", 7 * 24 * time.Hour},
+		{"1 month", 30 * 24 * time.Hour},
+		{"", 0},
+	}
+
+	for _, test := range tests {
+		if result := parseLinkLifetime(test.input); result != test.expected {
+			t.Errorf("parseLinkLifetime(%s) = %v, expected %v", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestSharingDialog_InlinePreview(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	testWindow := testApp.NewWindow("Test")
+
+	pdfDialog := NewSharingDialog(testWindow, models.FileMetadata{ID: "test-1", FileName: "scan.pdf"}, nil)
+	if pdfDialog.inlineCheck.Disabled() {
+		t.Fatal("Expected PDFs to offer an inline preview")
+	}
+	pdfDialog.inlineCheck.SetChecked(true)
+	if opts, err := pdfDialog.shareOptions(); err != nil || !opts.InlinePreview {
+		t.Errorf("Expected an inline preview, got %+v, %v", opts, err)
+	}
+
+	// Browsers can't show other files
+	textDialog := NewSharingDialog(testWindow, models.FileMetadata{ID: "test-2", FileName: "notes.txt"}, nil)
+	if !textDialog.inlineCheck.Disabled() {
+		t.Error("Expected the inline preview to be unavailable for text files")
+	}
+}
@@ -0,0 +1,183 @@
+package ui
+
+import (
+	"fmt"
+
+	"file-sharing-app/internal/models"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+)
+
+// setupTray adds the system tray menu, on desktops that have a tray.
+// Closing the window then hides it to the tray when MinimizeToTray is on, so background jobs keep running.
+func (mw *MainWindow) setupTray() {
+	tray, ok := mw.app.(desktop.App)
+	if !ok {
+		return
+	}
+
+	mw.tray = tray
+	mw.tray.SetSystemTrayMenu(mw.buildTrayMenu())
+	mw.window.SetCloseIntercept(mw.closeWindow)
+}
+
+// SetMinimizeToTray sets whether closing the window hides it to the system tray instead of quitting
+func (mw *MainWindow) SetMinimizeToTray(enabled bool) {
+	mw.minimizeToTray = enabled
+}
+
+// SetRecentShares updates the share links offered in the tray menu. Safe to call from any goroutine.
+func (mw *MainWindow) SetRecentShares(shares []models.RecentShare) {
+	fyne.Do(func() {
+		mw.recentShares = shares
+		if mw.tray != nil {
+			mw.tray.SetSystemTrayMenu(mw.buildTrayMenu())
+		}
+	})
+}
+
+// buildTrayMenu creates the tray menu with quick actions and the recent share links.
+// Fyne adds a Quit item at the end.
+func (mw *MainWindow) buildTrayMenu() *fyne.Menu {
+	var shareItems, revokeItems []*fyne.MenuItem
+	for _, share := range mw.recentShares {
+		shareItems = append(shareItems, fyne.NewMenuItem(formatRecentShare(share), func() {
+			mw.copyShareLink(share)
+		}))
+		// Presigned links can't be revoked, so only short links are offered
+		if share.ShortLink {
+			revokeItems = append(revokeItems, fyne.NewMenuItem(formatRecentShare(share), func() {
+				mw.confirmRevokeShare(share)
+			}))
+		}
+	}
+
+	recentShares := fyne.NewMenuItem("Copy Share Link", nil)
+	recentShares.ChildMenu = fyne.NewMenu("", withPlaceholder(shareItems, "No recent shares")...)
+
+	revokeShares := fyne.NewMenuItem("Revoke Share Link", nil)
+	revokeShares.ChildMenu = fyne.NewMenu("", withPlaceholder(revokeItems, "No revocable shares")...)
+
+	return fyne.NewMenu("File Sharing App",
+		fyne.NewMenuItem("Open Window", mw.showWindow),
+		fyne.NewMenuItem("Upload File…", mw.uploadFromTray),
+		fyne.NewMenuItem("Upload Clipboard", mw.uploadClipboard),
+		fyne.NewMenuItemSeparator(),
+		recentShares,
+		revokeShares,
+	)
+}
+
+// withPlaceholder returns items, or a single disabled item with the given label when there are none
+func withPlaceholder(items []*fyne.MenuItem, label string) []*fyne.MenuItem {
+	if len(items) > 0 {
+		return items
+	}
+
+	none := fyne.NewMenuItem(label, nil)
+	none.Disabled = true
+	return []*fyne.MenuItem{none}
+}
+
+// closeWindow hides the window to the tray, or quits when minimizing to the tray is off
+func (mw *MainWindow) closeWindow() {
+	if mw.tray == nil || !mw.minimizeToTray {
+		mw.window.Close()
+		return
+	}
+
+	mw.window.Hide()
+	if !mw.hiddenToTray {
+		mw.hiddenToTray = true
+		mw.SendNotification("Still running", "File Sharing App keeps running in the system tray. Use Quit in the tray menu to exit.")
+	}
+}
+
+// showWindow brings the window back from the tray
+func (mw *MainWindow) showWindow() {
+	mw.window.Show()
+	mw.window.RequestFocus()
+}
+
+// uploadFromTray opens the window with the upload dialog
+func (mw *MainWindow) uploadFromTray() {
+	mw.showWindow()
+	if mw.uploadBtn.Disabled() {
+		return
+	}
+	mw.showUploadDialog()
+}
+
+// uploadClipboard uploads the text on the clipboard as a file, with the default expiration
+func (mw *MainWindow) uploadClipboard() {
+	if mw.OnUploadClipboard == nil || mw.uploadBtn.Disabled() {
+		mw.SendNotification("Upload unavailable", "Uploads aren't available right now. Open the window for details.")
+		return
+	}
+
+	text := mw.app.Clipboard().Content()
+	go func() {
+		if err := mw.OnUploadClipboard(text); err != nil {
+			mw.SendNotification("Upload failed", fmt.Sprintf("The clipboard could not be uploaded: %v", err))
+		}
+	}()
+}
+
+// copyShareLink puts a share's link on the clipboard. Links aren't kept, so it is signed again first.
+func (mw *MainWindow) copyShareLink(share models.RecentShare) {
+	if mw.OnCopyShareLink == nil {
+		mw.SendNotification("Link unavailable", "Share links can't be copied right now. Open the window for details.")
+		return
+	}
+
+	url, err := mw.OnCopyShareLink(share.ShareID)
+	if err != nil {
+		mw.SendNotification("Link unavailable", fmt.Sprintf("The link to %s could not be copied: %v", share.FileName, err))
+		return
+	}
+
+	mw.app.Clipboard().SetContent(url)
+	mw.SendNotification("Link copied", fmt.Sprintf("The link to %s is on the clipboard", share.FileName))
+}
+
+// confirmRevokeShare opens the window and asks before revoking a share's short link, which can't be undone
+func (mw *MainWindow) confirmRevokeShare(share models.RecentShare) {
+	if mw.OnRevokeShare == nil {
+		mw.SendNotification("Revoke unavailable", "Share links can't be revoked right now. Open the window for details.")
+		return
+	}
+
+	mw.showWindow()
+	dialog.ShowConfirm("Revoke Share Link",
+		fmt.Sprintf("Revoke the link to %s? Anyone who has it will no longer be able to download the file. This can't be undone.", formatRecentShare(share)),
+		func(confirmed bool) {
+			if confirmed {
+				mw.revokeShare(share)
+			}
+		}, mw.window)
+}
+
+// revokeShare revokes a share's short link and reports the outcome
+func (mw *MainWindow) revokeShare(share models.RecentShare) {
+	if err := mw.OnRevokeShare(share.ShareID); err != nil {
+		dialog.ShowError(fmt.Errorf("The link to %s could not be revoked: %v", share.FileName, err), mw.window)
+		return
+	}
+
+	mw.SendNotification("Link revoked", fmt.Sprintf("The link to %s no longer works", share.FileName))
+}
+
+// formatRecentShare labels a share in the tray menu with the file and who it was shared with
+func formatRecentShare(share models.RecentShare) string {
+	label := share.FileName
+	switch len(share.Recipients) {
+	case 0:
+		return label
+	case 1:
+		return label + " – " + share.Recipients[0]
+	default:
+		return fmt.Sprintf("%s – %s +%d", label, share.Recipients[0], len(share.Recipients)-1)
+	}
+}
@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"file-sharing-app/internal/models"
+
+	"fyne.io/fyne/v2/test"
+)
+
+func TestMainWindow_TrayMenu(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	mainWindow := NewMainWindow(testApp)
+
+	menu := mainWindow.buildTrayMenu()
+	labels := []string{"Open Window", "Upload File…", "Upload Clipboard", "", "Copy Share Link", "Revoke Share Link"}
+	if len(menu.Items) != len(labels) {
+		t.Fatalf("Expected %d tray menu items, got %d", len(labels), len(menu.Items))
+	}
+	for i, label := range labels {
+		if menu.Items[i].Label != label {
+			t.Errorf("Expected item %d to be '%s', got '%s'", i, label, menu.Items[i].Label)
+		}
+	}
+
+	// Without shares the submenu says so
+	shares := menu.Items[4].ChildMenu.Items
+	if len(shares) != 1 || !shares[0].Disabled {
+		t.Fatal("Expected a single disabled item when there are no recent shares")
+	}
+
+	mainWindow.recentShares = []models.RecentShare{{
+		ShareID:       "share-1",
+		FileName:      "report.pdf",
+		Recipients:    []string{"alice@example.com"},
+		URLExpiration: time.Now().Add(time.Hour),
+	}}
+	shares = mainWindow.buildTrayMenu().Items[4].ChildMenu.Items
+	if len(shares) != 1 || shares[0].Label != "report.pdf – alice@example.com" {
+		t.Fatalf("Expected the recent share in the tray menu, got %v", shares)
+	}
+
+	// Without a way to sign the link nothing is copied
+	shares[0].Action()
+	if content := testApp.Clipboard().Content(); content != "" {
+		t.Errorf("Expected nothing on the clipboard, got '%s'", content)
+	}
+
+	// Choosing a share signs its link and copies it
+	mainWindow.SetOnCopyShareLink(func(shareID string) (string, error) {
+		return "https://bucket.s3.amazonaws.com/report.pdf?signature=" + shareID, nil
+	})
+	shares[0].Action()
+	if content := testApp.Clipboard().Content(); content != "https://bucket.s3.amazonaws.com/report.pdf?signature=share-1" {
+		t.Errorf("Expected the share link on the clipboard, got '%s'", content)
+	}
+}
+
+func TestMainWindow_TrayRevokeShare(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	mainWindow := NewMainWindow(testApp)
+	mainWindow.recentShares = []models.RecentShare{
+		{ShareID: "presigned", FileName: "report.pdf", Recipients: []string{"alice@example.com"}},
+		{ShareID: "short", FileName: "slides.pdf", Recipients: []string{"bob@example.com"}, ShortLink: true},
+	}
+
+	// Presigned links can't be revoked, so only the short link is offered
+	revocable := mainWindow.buildTrayMenu().Items[5].ChildMenu.Items
+	if len(revocable) != 1 || revocable[0].Label != "slides.pdf – bob@example.com" {
+		t.Fatalf("Expected only the short link share to be revocable, got %v", revocable)
+	}
+
+	var revoked string
+	mainWindow.SetOnRevokeShare(func(shareID string) error {
+		revoked = shareID
+		return nil
+	})
+	mainWindow.revokeShare(mainWindow.recentShares[1])
+	if revoked != "short" {
+		t.Errorf("Expected share 'short' to be revoked, got '%s'", revoked)
+	}
+
+	// Without short links there is nothing to revoke
+	mainWindow.recentShares = mainWindow.recentShares[:1]
+	revocable = mainWindow.buildTrayMenu().Items[5].ChildMenu.Items
+	if len(revocable) != 1 || !revocable[0].Disabled {
+		t.Fatal("Expected a single disabled item when no shares can be revoked")
+	}
+}
+
+func TestMainWindow_UploadClipboard(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	mainWindow := NewMainWindow(testApp)
+	mainWindow.EnableActions(true)
+
+	uploaded := make(chan string, 1)
+	mainWindow.SetOnUploadClipboard(func(text string) error {
+		uploaded <- text
+		return nil
+	})
+
+	testApp.Clipboard().SetContent("meeting notes")
+	mainWindow.uploadClipboard()
+
+	select {
+	case text := <-uploaded:
+		if text != "meeting notes" {
+			t.Errorf("Expected the clipboard text to be uploaded, got '%s'", text)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Clipboard was not uploaded")
+	}
+}
+
+func TestFormatRecentShare(t *testing.T) {
+	tests := []struct {
+		recipients []string
+		expected   string
+	}{
+		{nil, "report.pdf"},
+		{[]string{"alice@example.com"}, "report.pdf – alice@example.com"},
+		{[]string{"alice@example.com", "bob@example.com", "carol@example.com"}, "report.pdf – alice@example.com +2"},
+	}
+
+	for _, tt := range tests {
+		result := formatRecentShare(models.RecentShare{FileName: "report.pdf", Recipients: tt.recipients})
+		if result != tt.expected {
+			t.Errorf("Expected '%s', got '%s'", tt.expected, result)
+		}
+	}
+}
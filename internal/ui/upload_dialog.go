@@ -3,8 +3,11 @@ package ui
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"file-sharing-app/internal/models"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
@@ -21,21 +24,29 @@ type FileUploadDialog struct {
 	// UI components
 	fileLabel      *widget.Label
 	fileSizeLabel  *widget.Label
+	riskLabel      *widget.Label
 	expirationSelect *widget.Select
+	speedSelect    *widget.Select
+	startAtEntry   *widget.Entry
 	progressBar    *widget.ProgressBar
 	uploadBtn      *widget.Button
 	cancelBtn      *widget.Button
 	
 	// Data
-	selectedFile string
-	onUpload     func(filePath string, expiration time.Duration) error
+	selectedFile   string
+	bandwidthLimit *models.BandwidthLimit // this upload's own limit; the speed select changes it while the dialog is open
+	onUpload       func(filePath string, expiration time.Duration, opts models.UploadOptions) error
 }
 
+// bandwidthChoices are the upload speed limits offered, in KB/s; zero is unlimited
+var bandwidthChoices = []int{0, 256, 512, 1024, 2048, 5 * 1024, 10 * 1024}
+
 // NewFileUploadDialog creates a new file upload dialog
-func NewFileUploadDialog(parent fyne.Window, onUpload func(string, time.Duration) error) *FileUploadDialog {
+func NewFileUploadDialog(parent fyne.Window, onUpload func(string, time.Duration, models.UploadOptions) error) *FileUploadDialog {
 	d := &FileUploadDialog{
-		window:   parent,
-		onUpload: onUpload,
+		window:         parent,
+		onUpload:       onUpload,
+		bandwidthLimit: models.NewBandwidthLimit(0),
 	}
 	
 	d.setupDialog()
@@ -73,6 +84,11 @@ func (d *FileUploadDialog) setupDialog() {
 	d.fileSizeLabel = widget.NewLabel("")
 	d.fileSizeLabel.Hide()
 	
+	d.riskLabel = widget.NewLabel("")
+	d.riskLabel.Wrapping = fyne.TextWrapWord
+	d.riskLabel.Importance = widget.WarningImportance
+	d.riskLabel.Hide()
+	
 	selectFileBtn := widget.NewButton("Select File", d.selectFile)
 	selectFileBtn.Icon = theme.FolderOpenIcon()
 	selectFileBtn.Importance = widget.MediumImportance
@@ -92,6 +108,15 @@ func (d *FileUploadDialog) setupDialog() {
 	)
 	d.expirationSelect.SetSelected("1 day") // Default selection
 	
+	// Upload speed and start time
+	d.speedSelect = widget.NewSelect(bandwidthLabels(), func(selected string) {
+		d.bandwidthLimit.Set(parseBandwidth(selected))
+	})
+	d.speedSelect.SetSelected(models.FormatBandwidth(0))
+	
+	d.startAtEntry = widget.NewEntry()
+	d.startAtEntry.SetPlaceHolder("Now, or e.g. 22:00 or 2024-06-01 22:00")
+	
 	// Progress section
 	d.progressBar = widget.NewProgressBar()
 	d.progressBar.Hide()
@@ -111,6 +136,7 @@ func (d *FileUploadDialog) setupDialog() {
 		widget.NewLabel("Select File to Upload"),
 		container.NewBorder(nil, nil, nil, selectFileBtn, d.fileLabel),
 		d.fileSizeLabel,
+		d.riskLabel,
 	)
 	
 	expirationSection := container.NewVBox(
@@ -119,6 +145,18 @@ func (d *FileUploadDialog) setupDialog() {
 		widget.NewLabel("Files will be automatically deleted after the expiration time."),
 	)
 	
+	optionsLabel := widget.NewLabel("Upload Speed and Start Time:")
+	optionsLabel.TextStyle = fyne.TextStyle{Bold: true}
+	
+	optionsSection := container.NewVBox(
+		optionsLabel,
+		widget.NewForm(
+			widget.NewFormItem("Speed limit", d.speedSelect),
+			widget.NewFormItem("Start at", d.startAtEntry),
+		),
+		widget.NewLabel("The speed limit applies on top of the one in the toolbar. Scheduled uploads wait in the upload queue."),
+	)
+	
 	progressSection := container.NewVBox(
 		widget.NewLabel("Upload Progress:"),
 		d.progressBar,
@@ -135,13 +173,15 @@ func (d *FileUploadDialog) setupDialog() {
 		widget.NewSeparator(),
 		expirationSection,
 		widget.NewSeparator(),
+		optionsSection,
+		widget.NewSeparator(),
 		progressSection,
 		widget.NewSeparator(),
 		buttonSection,
 	)
 	
 	d.dialog = dialog.NewCustom("Upload File", "", content, d.window)
-	d.dialog.Resize(fyne.NewSize(500, 400))
+	d.dialog.Resize(fyne.NewSize(500, 520))
 }
 
 func (d *FileUploadDialog) selectFile() {
@@ -161,7 +201,9 @@ func (d *FileUploadDialog) selectFile() {
 		d.fileLabel.Refresh()
 		
 		// Show file size
+		d.showRisk(filename, nil)
 		if info, err := storage.LoadResourceFromURI(uri); err == nil {
+			d.showRisk(filename, info.Content())
 			size := len(info.Content())
 			d.fileSizeLabel.SetText(fmt.Sprintf("Size: %s", formatFileSize(int64(size))))
 			d.fileSizeLabel.Show()
@@ -191,6 +233,26 @@ func (d *FileUploadDialog) selectFile() {
 	fileDialog.Show()
 }
 
+// showRisk warns when the selected file could run code on recipients' computers or in their browsers
+func (d *FileUploadDialog) showRisk(fileName string, content []byte) {
+	head := content
+	if len(head) > models.ContentSniffLength {
+		head = head[:models.ContentSniffLength]
+	}
+	
+	contentType := ""
+	if len(head) > 0 {
+		contentType = models.DetectContentType(fileName, head, nil)
+	}
+	if !models.IsRiskyFile(fileName, contentType) {
+		d.riskLabel.Hide()
+		return
+	}
+	
+	d.riskLabel.SetText("This file can run programs or scripts. Only share it with people who expect it; recipients always download it rather than opening it in the browser.")
+	d.riskLabel.Show()
+}
+
 func (d *FileUploadDialog) uploadFile() {
 	if d.selectedFile == "" || d.onUpload == nil {
 		return
@@ -199,6 +261,13 @@ func (d *FileUploadDialog) uploadFile() {
 	// Parse expiration duration
 	expiration := d.parseExpiration(d.expirationSelect.Selected)
 	
+	startAt, err := parseStartAt(d.startAtEntry.Text, time.Now())
+	if err != nil {
+		dialog.ShowError(err, d.window)
+		return
+	}
+	opts := models.UploadOptions{BandwidthLimit: d.bandwidthLimit, StartAt: startAt}
+	
 	// Show progress and disable upload button
 	d.progressBar.Show()
 	d.progressBar.SetValue(0)
@@ -209,7 +278,7 @@ func (d *FileUploadDialog) uploadFile() {
 	// Start upload in goroutine
 	go func() {
 		// Call the upload callback
-		if err := d.onUpload(d.selectedFile, expiration); err != nil {
+		if err := d.onUpload(d.selectedFile, expiration, opts); err != nil {
 			// Show error dialog
 			dialog.ShowError(err, d.window)
 			
@@ -218,6 +287,10 @@ func (d *FileUploadDialog) uploadFile() {
 			d.uploadBtn.SetText("Upload File")
 			d.uploadBtn.Enable()
 			d.cancelBtn.Enable()
+		} else if !startAt.IsZero() {
+			d.progressBar.Hide()
+			d.uploadBtn.SetText("Upload Scheduled")
+			time.AfterFunc(2*time.Second, d.Hide)
 		} else {
 			// Success - show completion
 			d.SetProgress(1.0)
@@ -225,6 +298,70 @@ func (d *FileUploadDialog) uploadFile() {
 	}()
 }
 
+// parseStartAt parses when to start an upload: empty for now, a time of day such as 22:00 for
+// its next occurrence, or a date and time such as 2024-06-01 22:00, in local time
+func parseStartAt(text string, now time.Time) (time.Time, error) {
+	text = strings.TrimSpace(text)
+	if text == "" || strings.EqualFold(text, "now") {
+		return time.Time{}, nil
+	}
+	
+	if clock, err := time.ParseInLocation("15:04", text, now.Location()); err == nil {
+		startAt := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+		if !startAt.After(now) {
+			startAt = startAt.AddDate(0, 0, 1)
+		}
+		return startAt, nil
+	}
+	
+	startAt, err := time.ParseInLocation("2006-01-02 15:04", text, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("start time must be a time such as 22:00, or a date and time such as 2024-06-01 22:00")
+	}
+	if !startAt.After(now) {
+		return time.Time{}, fmt.Errorf("start time %s has already passed", text)
+	}
+	return startAt, nil
+}
+
+// bandwidthLabels returns the upload speed limits offered, as shown in selects
+func bandwidthLabels() []string {
+	labels := make([]string, len(bandwidthChoices))
+	for i, kbps := range bandwidthChoices {
+		labels[i] = models.FormatBandwidth(kbps)
+	}
+	return labels
+}
+
+// parseBandwidth returns the speed limit in KB/s a label made by models.FormatBandwidth stands for,
+// or zero for unlimited
+func parseBandwidth(label string) int {
+	var value int
+	var unit string
+	if _, err := fmt.Sscanf(label, "%d %s", &value, &unit); err != nil || value <= 0 {
+		return 0
+	}
+	switch unit {
+	case "KB/s":
+		return value
+	case "MB/s":
+		return value * 1024
+	default:
+		return 0
+	}
+}
+
+// bandwidthLabel returns the label for a speed limit, adding it to the choices if it isn't one of them
+func bandwidthLabel(kbps int, labels []string) ([]string, string) {
+	label := models.FormatBandwidth(kbps)
+	for _, existing := range labels {
+		if existing == label {
+			return labels, label
+		}
+	}
+	return append(labels, label), label
+}
+
 func (d *FileUploadDialog) parseExpiration(selected string) time.Duration {
 	switch selected {
 	case "1 hour":
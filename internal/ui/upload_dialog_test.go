@@ -4,6 +4,8 @@ import (
 	"testing"
 	"time"
 
+	"file-sharing-app/internal/models"
+
 	"fyne.io/fyne/v2/test"
 )
 
@@ -16,7 +18,7 @@ func TestFileUploadDialog_Creation(t *testing.T) {
 	testWindow := testApp.NewWindow("Test")
 
 	// Create upload dialog
-	uploadDialog := NewFileUploadDialog(testWindow, func(filePath string, expiration time.Duration) error {
+	uploadDialog := NewFileUploadDialog(testWindow, func(filePath string, expiration time.Duration, opts models.UploadOptions) error {
 		return nil
 	})
 
@@ -93,4 +95,112 @@ func TestFileUploadDialog_SetProgress(t *testing.T) {
 	if uploadDialog.progressBar.Value != 1.0 {
 		t.Errorf("Expected progress 1.0, got %f", uploadDialog.progressBar.Value)
 	}
-}
\ No newline at end of file
+}
+func TestFileUploadDialog_ShowRisk(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	uploadDialog := NewFileUploadDialog(testApp.NewWindow("Test"), nil)
+
+	tests := []struct {
+		fileName string
+		content  string
+		warned   bool
+	}{
+		{"setup.exe", "MZ", true},
+		{"page.html", "<html></html>", true},
+		{"invoice.pdf", "<!DOCTYPE html><script>", true},
+		{"invoice.pdf", "%PDF-1.7", false},
+		{"notes.txt", "plain notes", false},
+	}
+
+	for _, test := range tests {
+		uploadDialog.showRisk(test.fileName, []byte(test.content))
+		if uploadDialog.riskLabel.Visible() != test.warned {
+			t.Errorf("showRisk(%s, %q) warned = %v, expected %v", test.fileName, test.content, uploadDialog.riskLabel.Visible(), test.warned)
+		}
+	}
+}
+
+func TestParseStartAt(t *testing.T) {
+	now := time.Date(2024, 6, 1, 18, 30, 0, 0, time.Local)
+
+	tests := []struct {
+		input    string
+		expected time.Time
+	}{
+		{"", time.Time{}},
+		{"now", time.Time{}},
+		{"22:00", time.Date(2024, 6, 1, 22, 0, 0, 0, time.Local)},
+		{" 06:15 ", time.Date(2024, 6, 2, 6, 15, 0, 0, time.Local)}, // already passed today
+		{"2024-06-03 01:00", time.Date(2024, 6, 3, 1, 0, 0, 0, time.Local)},
+	}
+
+	for _, test := range tests {
+		result, err := parseStartAt(test.input, now)
+		if err != nil {
+			t.Errorf("parseStartAt(%q) returned error: %v", test.input, err)
+			continue
+		}
+		if !result.Equal(test.expected) {
+			t.Errorf("parseStartAt(%q) = %v, expected %v", test.input, result, test.expected)
+		}
+	}
+
+	for _, input := range []string{"tonight", "25:00", "2024-05-31 22:00"} {
+		if _, err := parseStartAt(input, now); err == nil {
+			t.Errorf("parseStartAt(%q) should have failed", input)
+		}
+	}
+}
+
+func TestParseBandwidth(t *testing.T) {
+	for _, kbps := range append(bandwidthChoices, 1500, 300) {
+		if result := parseBandwidth(models.FormatBandwidth(kbps)); result != kbps {
+			t.Errorf("parseBandwidth(%q) = %d, expected %d", models.FormatBandwidth(kbps), result, kbps)
+		}
+	}
+
+	if result := parseBandwidth("fast"); result != 0 {
+		t.Errorf("parseBandwidth(\"fast\") = %d, expected 0", result)
+	}
+
+	// Limits set outside the choices are offered too
+	labels, label := bandwidthLabel(300, bandwidthLabels())
+	if label != "300 KB/s" || len(labels) != len(bandwidthChoices)+1 {
+		t.Errorf("bandwidthLabel(300) = %v, %s", labels, label)
+	}
+	labels, _ = bandwidthLabel(1024, bandwidthLabels())
+	if len(labels) != len(bandwidthChoices) {
+		t.Errorf("bandwidthLabel(1024) added an existing choice: %v", labels)
+	}
+}
+
+func TestFileUploadDialog_SpeedLimit(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	received := make(chan models.UploadOptions, 1)
+	uploadDialog := NewFileUploadDialog(testApp.NewWindow("Test"), func(filePath string, expiration time.Duration, opts models.UploadOptions) error {
+		received <- opts
+		return nil
+	})
+
+	uploadDialog.speedSelect.SetSelected("512 KB/s")
+	uploadDialog.selectedFile = "/tmp/video.mp4"
+	uploadDialog.uploadFile()
+
+	opts := <-received
+	if opts.BandwidthLimit.KBps() != 512 {
+		t.Errorf("Expected 512 KB/s, got %d", opts.BandwidthLimit.KBps())
+	}
+	if !opts.StartAt.IsZero() {
+		t.Errorf("Expected the upload to start now, got %v", opts.StartAt)
+	}
+
+	// The limit handed to the upload follows the select while the dialog is open
+	uploadDialog.speedSelect.SetSelected("2 MB/s")
+	if opts.BandwidthLimit.KBps() != 2048 {
+		t.Errorf("Expected 2048 KB/s after changing the speed, got %d", opts.BandwidthLimit.KBps())
+	}
+}
@@ -68,7 +68,7 @@ func TestAWSIntegration(t *testing.T) {
 			"test":       "integration",
 		}
 
-		err := s3Service.UploadFile(ctx, testKey, testFile, metadata, progressCh)
+		_, err := s3Service.UploadFile(ctx, testKey, testFile, metadata, progressCh)
 		close(progressCh)
 		require.NoError(t, err, "File upload should succeed")
 
@@ -121,7 +121,7 @@ func TestAWSIntegration(t *testing.T) {
 			}
 		}()
 
-		err := s3Service.UploadFile(ctx, testKey, largeTestFile, nil, progressCh)
+		_, err := s3Service.UploadFile(ctx, testKey, largeTestFile, nil, progressCh)
 		close(progressCh)
 		require.NoError(t, err, "Large file upload should succeed")
 		assert.True(t, progressReceived, "Progress updates should be received")
@@ -154,7 +154,7 @@ func TestAWSIntegration(t *testing.T) {
 				}
 			}()
 
-			err := s3Service.UploadFile(ctx, testKeys[i], testFiles[i], nil, progressCh)
+			_, err := s3Service.UploadFile(ctx, testKeys[i], testFiles[i], nil, progressCh)
 			close(progressCh)
 			require.NoError(t, err, "File %d upload should succeed", i)
 		}
@@ -182,7 +182,7 @@ func TestAWSIntegration(t *testing.T) {
 
 	t.Run("Error Handling", func(t *testing.T) {
 		// Test upload to non-existent file
-		err := s3Service.UploadFile(ctx, "test-key", "/non/existent/file", nil, nil)
+		_, err := s3Service.UploadFile(ctx, "test-key", "/non/existent/file", nil, nil)
 		assert.Error(t, err, "Upload of non-existent file should fail")
 
 		// Test head object for non-existent key
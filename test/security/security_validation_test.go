@@ -166,7 +166,7 @@ func TestPresignedURLSecurity(t *testing.T) {
 		// Upload file and check generated key
 		testKey := "security-test/" + generateSecureTestKey()
 		
-		err := s3Service.UploadFile(ctx, testKey, testFile, nil, nil)
+		_, err := s3Service.UploadFile(ctx, testKey, testFile, nil, nil)
 		require.NoError(t, err)
 		defer s3Service.DeleteObject(ctx, testKey)
 
@@ -191,7 +191,7 @@ func TestPresignedURLSecurity(t *testing.T) {
 		defer os.Remove(testFile)
 
 		testKey := "security-test/http-" + generateSecureTestKey()
-		err := s3Service.UploadFile(ctx, testKey, testFile, nil, nil)
+		_, err := s3Service.UploadFile(ctx, testKey, testFile, nil, nil)
 		require.NoError(t, err)
 		defer s3Service.DeleteObject(ctx, testKey)
 
@@ -278,7 +278,7 @@ func TestS3BucketSecurity(t *testing.T) {
 			"test":       "security",
 		}
 		
-		err := s3Service.UploadFile(ctx, testKey, testFile, metadata, nil)
+		_, err := s3Service.UploadFile(ctx, testKey, testFile, metadata, nil)
 		require.NoError(t, err)
 		defer s3Service.DeleteObject(ctx, testKey)
 
@@ -345,10 +345,10 @@ func TestErrorHandlingSecurity(t *testing.T) {
 		ctx := context.Background()
 
 		// Test upload with invalid file path
-		err = s3Service.UploadFile(ctx, "test-key", "/etc/passwd", nil, nil)
+		_, err = s3Service.UploadFile(ctx, "test-key", "/etc/passwd", nil, nil)
 		assert.Error(t, err, "Upload of system file should fail")
 
-		err = s3Service.UploadFile(ctx, "test-key", "../../../etc/passwd", nil, nil)
+		_, err = s3Service.UploadFile(ctx, "test-key", "../../../etc/passwd", nil, nil)
 		assert.Error(t, err, "Upload with path traversal should fail")
 	})
 }